@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetConfigArrayOfInts retrieves the int array configuration with the given name from the repository
+func GetConfigArrayOfInts(name string, defaultValue []int) ([]int, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigArrayOfInts(name, defaultValue)
+}
+
+// GetConfigArrayOfFloats retrieves the float array configuration with the given name from the repository
+func GetConfigArrayOfFloats(name string, defaultValue []float64) ([]float64, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigArrayOfFloats(name, defaultValue)
+}
+
+// GetConfigArrayOfInts retrieves the int array configuration with the given
+// name from the repository. Elements are converted like GetConfigInt does.
+func (c *Client) GetConfigArrayOfInts(name string, defaultValue []int) ([]int, error) {
+	return c.GetConfigArrayOfIntsContext(context.Background(), name, defaultValue)
+}
+
+// GetConfigArrayOfFloats retrieves the float array configuration with the
+// given name from the repository. Integer elements of any type are converted
+// to floats.
+func (c *Client) GetConfigArrayOfFloats(name string, defaultValue []float64) ([]float64, error) {
+	return c.GetConfigArrayOfFloatsContext(context.Background(), name, defaultValue)
+}
+
+// GetConfigArrayOfIntsContext retrieves the int array configuration with the
+// given name for the tenant of ctx, falling back to the shared value.
+// It returns the error of ctx if ctx is done.
+func (c *Client) GetConfigArrayOfIntsContext(ctx context.Context, name string, defaultValue []int) (_ []int, err error) {
+	defer c.observe("GetConfigArrayOfInts", name, &err)
+	config, ok, err := c.tenantData(ctx, name)
+	if err != nil {
+		return defaultValue, err
+	}
+	if !ok {
+		return defaultValue, c.notFound(name)
+	}
+	if config == nil {
+		return defaultValue, nil
+	}
+	configArray, ok := config.([]interface{})
+	if !ok {
+		return defaultValue, typeMismatch(name, "an array of ints")
+	}
+	output := []int{}
+	for i, v := range configArray {
+		configInt, err := toInt(v)
+		if err != nil {
+			return defaultValue, keyError(name, fmt.Errorf("not an array of ints: element %d: %w", i, err))
+		}
+		output = append(output, configInt)
+	}
+	return output, nil
+}
+
+// GetConfigArrayOfFloatsContext retrieves the float array configuration with
+// the given name for the tenant of ctx, falling back to the shared value.
+// It returns the error of ctx if ctx is done.
+func (c *Client) GetConfigArrayOfFloatsContext(ctx context.Context, name string, defaultValue []float64) (_ []float64, err error) {
+	defer c.observe("GetConfigArrayOfFloats", name, &err)
+	config, ok, err := c.tenantData(ctx, name)
+	if err != nil {
+		return defaultValue, err
+	}
+	if !ok {
+		return defaultValue, c.notFound(name)
+	}
+	if config == nil {
+		return defaultValue, nil
+	}
+	configArray, ok := config.([]interface{})
+	if !ok {
+		return defaultValue, typeMismatch(name, "an array of floats")
+	}
+	output := []float64{}
+	for i, v := range configArray {
+		configFloat, err := toFloat(v)
+		if err != nil {
+			return defaultValue, keyError(name, fmt.Errorf("%w: not an array of floats: element %d is a %T", ErrTypeMismatch, i, v))
+		}
+		output = append(output, configFloat)
+	}
+	return output, nil
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetConfigArrayOfInts(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"ports":   []interface{}{80, int64(443), 8080.0, uint16(9090)},
+		"mixed":   []interface{}{80, "443"},
+		"partial": []interface{}{80, 1.5},
+		"name":    "John",
+	})
+
+	ports, err := client.GetConfigArrayOfInts("ports", nil)
+	expected := []int{80, 443, 8080, 9090}
+	if err != nil || !reflect.DeepEqual(ports, expected) {
+		t.Errorf("Expected ports to be %v, got %v (%v)", expected, ports, err)
+	}
+	defaultValue := []int{1}
+	for _, name := range []string{"mixed", "partial", "name", "missing"} {
+		if values, err := client.GetConfigArrayOfInts(name, defaultValue); err == nil || !reflect.DeepEqual(values, defaultValue) {
+			t.Errorf("Expected the default and an error for %s, got %v (%v)", name, values, err)
+		}
+	}
+}
+
+func TestGetConfigArrayOfFloats(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"weights": []interface{}{0.5, 1, int64(2), float32(0.25)},
+		"mixed":   []interface{}{0.5, true},
+		"name":    "John",
+	})
+
+	weights, err := client.GetConfigArrayOfFloats("weights", nil)
+	expected := []float64{0.5, 1, 2, 0.25}
+	if err != nil || !reflect.DeepEqual(weights, expected) {
+		t.Errorf("Expected weights to be %v, got %v (%v)", expected, weights, err)
+	}
+	defaultValue := []float64{1}
+	for _, name := range []string{"mixed", "name", "missing"} {
+		if values, err := client.GetConfigArrayOfFloats(name, defaultValue); err == nil || !reflect.DeepEqual(values, defaultValue) {
+			t.Errorf("Expected the default and an error for %s, got %v (%v)", name, values, err)
+		}
+	}
+}
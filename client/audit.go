@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"errors"
+)
+
+// redactedValue replaces the values of sensitive configurations in logs.
+const redactedValue = "REDACTED"
+
+// AuditHook is called with every read of a configuration by a getter, with
+// the name the getter was called with and whether it found the configuration,
+// that is whether it returned the value of the configuration rather than its
+// default. It is called on the goroutine of the getter, so it must be fast
+// and safe for concurrent use.
+type AuditHook func(name string, found bool)
+
+// auditListener returns the ReadListener calling hook.
+func auditListener(hook AuditHook) ReadListener {
+	return func(event ReadEvent) {
+		hook(event.Key, event.Fallback == FallbackNone)
+	}
+}
+
+// isSensitive reports whether the configuration with the given name, or the
+// configuration a dotted path with that name reads into, was marked
+// sensitive with WithSensitiveKeys.
+func (c *Client) isSensitive(name string) bool {
+	if len(c.sensitiveKeys) == 0 {
+		return false
+	}
+	if c.sensitiveKeys[name] {
+		return true
+	}
+	if key, ok := c.sourceKey(name); ok && c.sensitiveKeys[key] {
+		return true
+	}
+	for i := range name {
+		if name[i] == '.' && c.sensitiveKeys[name[:i]] {
+			return true
+		}
+	}
+	return false
+}
+
+// redactKinds are the errors whose message redactError keeps, as they never
+// carry values.
+var redactKinds = []error{
+	ErrConfigNotFound, ErrTypeMismatch, ErrSchemaMismatch, ErrStaleConfig, ErrClientClosed,
+	ErrNotReady, ErrInvalidData, context.Canceled, context.DeadlineExceeded,
+}
+
+// redactError returns err with its message, which may quote the value of a
+// sensitive configuration, such as the error of a codec, replaced by the kind
+// of the error. errors.Is still matches the errors it wraps.
+func redactError(err error) error {
+	message := "details of a sensitive config redacted"
+	for _, kind := range redactKinds {
+		if errors.Is(err, kind) {
+			message = kind.Error() + ": " + message
+			break
+		}
+	}
+	return &obfuscatedError{message: message, err: err}
+}
+
+// redactConfigError redacts the error *err about the configuration with the
+// given name if the configuration is sensitive, keeping the name and getter
+// of a ConfigError.
+func (c *Client) redactConfigError(name string, err *error) {
+	if *err == nil || !c.isSensitive(name) {
+		return
+	}
+	var configErr *ConfigError
+	if errors.As(*err, &configErr) {
+		*err = &ConfigError{Key: configErr.Key, Op: configErr.Op, Err: redactError(configErr.Err)}
+		return
+	}
+	*err = redactError(*err)
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithAuditHook(t *testing.T) {
+	type read struct {
+		name  string
+		found bool
+	}
+	var reads []read
+	repository := &mapRepository{data: map[string]interface{}{"name": "John", "database": map[string]interface{}{"host": "db"}}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithAuditHook(func(name string, found bool) {
+		reads = append(reads, read{name, found})
+	}))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	_, _ = client.GetConfigString("name", "")
+	_, _ = client.GetConfigString("database.host", "")
+	_, _ = client.GetConfigString("missing", "default")
+	_, _ = client.GetConfigInt("name", 0)
+	expected := []read{{"name", true}, {"database.host", true}, {"missing", false}, {"name", false}}
+	if fmt.Sprint(reads) != fmt.Sprint(expected) {
+		t.Errorf("Expected the reads %v, got %v", expected, reads)
+	}
+}
+
+func TestWithSensitiveKeys(t *testing.T) {
+	var fields []LogFields
+	logger := &fieldsLogger{fields: &fields}
+	repository := &mapRepository{data: map[string]interface{}{
+		"db_password": "hunter2",
+		"pool_size":   500,
+		"secrets":     map[string]interface{}{"token": "s3cr3t"},
+		"name":        "John",
+	}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second,
+		WithSensitiveKeys("db_password", "pool_size"), WithSensitiveKeys("secrets"), WithClampLogging(), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// The errors of getters do not quote sensitive values, but still match.
+	var port int
+	err = client.GetConfig("db_password", &port, nil)
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected ErrTypeMismatch, got %v", err)
+	}
+	if err == nil || strings.Contains(err.Error(), "hunter2") || !strings.Contains(err.Error(), "db_password") {
+		t.Errorf("Expected the error to name the key without its value, got %v", err)
+	}
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Op != "GetConfig" {
+		t.Errorf("Expected a ConfigError of GetConfig, got %v", err)
+	}
+	err = client.GetConfig("secrets.token", &port, nil)
+	if err == nil || strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("Expected a path into a sensitive config to be redacted, got %v", err)
+	}
+	err = client.GetConfig("name", &port, nil)
+	if err == nil || !strings.Contains(err.Error(), "John") {
+		t.Errorf("Expected other configs to keep their errors, got %v", err)
+	}
+
+	// Clamping a sensitive value logs it redacted.
+	size, _ := client.GetConfigIntClamped("pool_size", 10, 1, 100)
+	if size != 100 {
+		t.Errorf("Expected the clamped size 100, got %d", size)
+	}
+	if len(fields) == 0 {
+		t.Fatalf("Expected the clamping to be logged")
+	}
+	for _, entry := range fields {
+		if logged := fmt.Sprint(entry); strings.Contains(logged, "500") || strings.Contains(logged, "hunter2") {
+			t.Errorf("Expected sensitive values to stay out of the logs, got %s", logged)
+		}
+	}
+}
+
+func TestWithSensitiveKeysPrefetch(t *testing.T) {
+	var fields []LogFields
+	logger := &fieldsLogger{fields: &fields}
+	repository := &mapRepository{data: map[string]interface{}{"db_password": "hunter2", "name": "John"}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithSensitiveKeys("db_password"), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// Prefetch errors name sensitive configs without quoting their values.
+	err = client.Prefetch(map[string]interface{}{"db_password": new(int), "name": new(int)})
+	if err == nil || strings.Contains(err.Error(), "hunter2") || !strings.Contains(err.Error(), "db_password") {
+		t.Errorf("Expected the error to name the key without its value, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "John") {
+		t.Errorf("Expected other configs to keep their errors, got %v", err)
+	}
+
+	// So do the errors logged by the refreshes.
+	if err := client.ForceRefresh(context.Background()); err != nil {
+		t.Fatalf("Error refreshing client: %s", err.Error())
+	}
+	if len(fields) == 0 {
+		t.Fatalf("Expected the prefetch errors to be logged")
+	}
+	for _, entry := range fields {
+		if logged := fmt.Sprint(entry); strings.Contains(logged, "hunter2") {
+			t.Errorf("Expected sensitive values to stay out of the logs, got %s", logged)
+		}
+	}
+}
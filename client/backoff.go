@@ -0,0 +1,71 @@
+package client
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Defaults of the backoff of the refresh loop after failed refreshes.
+const (
+	DefaultMaxRefreshBackoff        = 5 * time.Minute
+	DefaultRefreshBackoffMultiplier = 2.0
+)
+
+// refreshDelay returns how long the refresh loop waits before the next
+// refresh after the given number of consecutive failed refreshes. Without
+// failures it waits RefreshInterval, moved by up to the interval jitter. Each failure multiplies the wait by the
+// backoff multiplier up to the max backoff, and the wait is jittered between
+// half and all of that.
+func (c *Client) refreshDelay(failures int) time.Duration {
+	multiplier := c.backoffMultiplier
+	if multiplier == 0 {
+		multiplier = DefaultRefreshBackoffMultiplier
+	}
+	if failures == 0 || multiplier <= 1 {
+		return c.jitterInterval()
+	}
+	maxBackoff := c.maxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = DefaultMaxRefreshBackoff
+	}
+	if maxBackoff < c.RefreshInterval {
+		maxBackoff = c.RefreshInterval
+	}
+	backoff := float64(c.RefreshInterval)
+	for i := 0; i < failures && backoff < float64(maxBackoff); i++ {
+		backoff *= multiplier
+	}
+	if backoff > float64(maxBackoff) {
+		backoff = float64(maxBackoff)
+	}
+	return time.Duration(backoff/2 + c.randomFloat()*backoff/2)
+}
+
+// jitterInterval returns RefreshInterval moved earlier or later by a random
+// amount of up to the interval jitter, so that the refreshes of clients
+// started together drift apart.
+func (c *Client) jitterInterval() time.Duration {
+	if c.intervalJitter <= 0 {
+		return c.RefreshInterval
+	}
+	shift := (2*c.randomFloat() - 1) * c.intervalJitter * float64(c.RefreshInterval)
+	return c.RefreshInterval + time.Duration(shift)
+}
+
+// startupDelay returns the random delay added to the first wait of the
+// refresh loop, below the startup jitter.
+func (c *Client) startupDelay() time.Duration {
+	if c.startupJitter <= 0 {
+		return 0
+	}
+	return time.Duration(c.randomFloat() * float64(c.startupJitter))
+}
+
+// randomFloat returns a random number in [0, 1) from the random source of
+// the refresh loop.
+func (c *Client) randomFloat() float64 {
+	if c.random == nil {
+		return rand.Float64()
+	}
+	return c.random.Float64()
+}
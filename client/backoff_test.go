@@ -0,0 +1,258 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeTimerClock is a fakeClock whose waits are reported on waits and end
+// when the test sends on fire.
+type fakeTimerClock struct {
+	fakeClock
+	waits chan time.Duration
+	fire  chan time.Time
+}
+
+func (f *fakeTimerClock) After(d time.Duration) <-chan time.Time {
+	f.waits <- d
+	return f.fire
+}
+
+// recoveringRepository is a mapRepository whose next failures refreshes fail.
+type recoveringRepository struct {
+	mapRepository
+	failures atomic.Int32
+}
+
+func (f *recoveringRepository) Refresh() error {
+	if f.failures.Add(-1) >= 0 {
+		return errors.New("backend unavailable")
+	}
+	return nil
+}
+
+func TestRefreshBackoff(t *testing.T) {
+	clock := &fakeTimerClock{waits: make(chan time.Duration, 1), fire: make(chan time.Time)}
+	repository := &recoveringRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}}
+	client, err := NewClient(context.Background(), repository, time.Second, WithClock(clock), WithRefreshBackoff(5*time.Second, 2))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	repository.failures.Store(3)
+
+	// Each wait is checked before it is ended to run the next refresh.
+	expect := func(min time.Duration, max time.Duration) {
+		t.Helper()
+		select {
+		case wait := <-clock.waits:
+			if wait < min || wait > max {
+				t.Errorf("Expected a wait between %s and %s, got %s", min, max, wait)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the refresh loop to wait, timed out")
+		}
+	}
+	expect(time.Second, time.Second) // Refresh interval, the refresh fails
+	clock.fire <- time.Time{}
+	expect(time.Second, 2*time.Second) // Backoff doubled, the refresh fails
+	clock.fire <- time.Time{}
+	expect(2*time.Second, 4*time.Second) // Backoff doubled, the refresh fails
+	clock.fire <- time.Time{}
+	expect(5*time.Second/2, 5*time.Second) // Backoff capped, the refresh succeeds
+	clock.fire <- time.Time{}
+	expect(time.Second, time.Second) // Back to the refresh interval
+}
+
+func TestRefreshDelay(t *testing.T) {
+	client := &Client{RefreshInterval: 10 * time.Second}
+	if delay := client.refreshDelay(0); delay != 10*time.Second {
+		t.Errorf("Expected the refresh interval without failures, got %s", delay)
+	}
+	if delay := client.refreshDelay(100); delay < DefaultMaxRefreshBackoff/2 || delay > DefaultMaxRefreshBackoff {
+		t.Errorf("Expected the default max backoff to bound the wait, got %s", delay)
+	}
+
+	// A multiplier of 1 disables the backoff.
+	WithRefreshBackoff(time.Minute, 1)(client)
+	if delay := client.refreshDelay(3); delay != 10*time.Second {
+		t.Errorf("Expected no backoff with a multiplier of 1, got %s", delay)
+	}
+
+	// The max backoff is never below the refresh interval.
+	WithRefreshBackoff(time.Second, 3)(client)
+	if delay := client.refreshDelay(3); delay < 5*time.Second || delay > 10*time.Second {
+		t.Errorf("Expected the refresh interval to bound the wait, got %s", delay)
+	}
+}
+
+func TestRefreshRetryAfter(t *testing.T) {
+	var throttled atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if throttled.Load() {
+			w.Header().Set("Retry-After", "42")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("name: John\n"))
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Error parsing url: %s", err.Error())
+	}
+	clock := &fakeTimerClock{waits: make(chan time.Duration, 1), fire: make(chan time.Time)}
+	repository := &source.WebRepository{Name: "web", URL: u}
+	client, err := NewClient(context.Background(), repository, time.Second, WithClock(clock), WithObfuscatedErrors(false))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	throttled.Store(true)
+
+	expect := func(expected time.Duration) {
+		t.Helper()
+		select {
+		case wait := <-clock.waits:
+			if wait != expected {
+				t.Errorf("Expected a wait of %s, got %s", expected, wait)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the refresh loop to wait, timed out")
+		}
+	}
+	expect(time.Second) // Refresh interval, the refresh is throttled
+	clock.fire <- time.Time{}
+	expect(42 * time.Second) // Retry-After of the response, the refresh succeeds
+	throttled.Store(false)
+	clock.fire <- time.Time{}
+	expect(time.Second) // Back to the refresh interval
+}
+
+func TestForceRefreshRestartsWait(t *testing.T) {
+	clock := &fakeTimerClock{waits: make(chan time.Duration, 1), fire: make(chan time.Time)}
+	repository := &recoveringRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}}
+	client, err := NewClient(context.Background(), repository, time.Minute, WithClock(clock), WithLogger(NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	expect := func(min time.Duration, max time.Duration) {
+		t.Helper()
+		select {
+		case wait := <-clock.waits:
+			if wait < min || wait > max {
+				t.Errorf("Expected a wait between %s and %s, got %s", min, max, wait)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the refresh loop to wait again, timed out")
+		}
+	}
+	expect(time.Minute, time.Minute)
+
+	// A forced refresh restarts the wait without waiting for it to end.
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error forcing refresh: %s", err.Error())
+	}
+	expect(time.Minute, time.Minute)
+
+	// A failed forced refresh backs off like a failed background one.
+	repository.failures.Store(1)
+	if client.ForceRefresh(context.Background()) == nil {
+		t.Fatalf("Expected the forced refresh to fail")
+	}
+	expect(time.Minute, 2*time.Minute)
+}
+
+// fixedSource is a rand.Source that always returns the same value.
+type fixedSource int64
+
+func (f fixedSource) Int63() int64 {
+	return int64(f)
+}
+
+func (f fixedSource) Seed(int64) {}
+
+func TestRefreshJitter(t *testing.T) {
+	clock := &fakeTimerClock{waits: make(chan time.Duration, 1), fire: make(chan time.Time)}
+	repository := &mapRepository{data: map[string]interface{}{"name": "John"}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithClock(clock),
+		WithRefreshJitter(5*time.Second, 0.1), WithRandSource(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	wait := func() time.Duration {
+		t.Helper()
+		select {
+		case wait := <-clock.waits:
+			return wait
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the refresh loop to wait, timed out")
+		}
+		return 0
+	}
+	// The first wait is delayed by up to the startup jitter.
+	if first := wait(); first < 9*time.Second || first >= 16*time.Second {
+		t.Errorf("Expected a first wait between 9s and 16s, got %s", first)
+	}
+	// Later waits stay within the interval jitter, and vary.
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 20; i++ {
+		clock.fire <- time.Time{}
+		next := wait()
+		if next < 9*time.Second || next > 11*time.Second {
+			t.Errorf("Expected a wait between 9s and 11s, got %s", next)
+		}
+		seen[next] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("Expected the waits to be jittered, got %v", seen)
+	}
+}
+
+func TestRefreshJitterBounds(t *testing.T) {
+	for _, test := range []struct {
+		source  rand.Source
+		startup time.Duration
+		wait    time.Duration
+	}{
+		{source: fixedSource(0), startup: 0, wait: 9 * time.Second},
+		{source: fixedSource(1 << 62), startup: 2500 * time.Millisecond, wait: 10 * time.Second},
+	} {
+		client := &Client{RefreshInterval: 10 * time.Second}
+		WithRefreshJitter(5*time.Second, 0.1)(client)
+		WithRandSource(test.source)(client)
+		if delay := client.startupDelay(); delay != test.startup {
+			t.Errorf("Expected a startup delay of %s, got %s", test.startup, delay)
+		}
+		if delay := client.refreshDelay(0); delay != test.wait {
+			t.Errorf("Expected a wait of %s, got %s", test.wait, delay)
+		}
+	}
+
+	// Without jitter the refresh interval is kept.
+	client := &Client{RefreshInterval: 10 * time.Second}
+	if delay := client.startupDelay(); delay != 0 {
+		t.Errorf("Expected no startup delay, got %s", delay)
+	}
+	if delay := client.refreshDelay(0); delay != 10*time.Second {
+		t.Errorf("Expected the refresh interval, got %s", delay)
+	}
+	// The interval jitter is capped at half the interval.
+	WithRefreshJitter(0, 3)(client)
+	WithRandSource(fixedSource(0))(client)
+	if delay := client.refreshDelay(0); delay != 5*time.Second {
+		t.Errorf("Expected the wait to be at least half the interval, got %s", delay)
+	}
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ConfigsError is returned by GetConfigs when some configurations could not
+// be read. errors.Is matches the error of any configuration.
+type ConfigsError struct {
+	Errors map[string]error // Error of every configuration that could not be read, by name
+}
+
+func (e *ConfigsError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	messages := make([]string, len(names))
+	for i, name := range names {
+		messages[i] = name + ": " + e.Errors[name].Error()
+	}
+	return "error getting configs: " + strings.Join(messages, "; ")
+}
+
+func (e *ConfigsError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetConfigs reads every configuration named in requests into the pointer it
+// maps to, the way GetConfig does. All of them are read between the same two
+// refreshes, so they are consistent with each other, and a refresh started
+// meanwhile waits for them. A configuration that is missing or cannot be
+// decoded leaves its pointer unchanged, so it can be set to the default
+// beforehand, and the errors of all such configurations are returned
+// together as a *ConfigsError.
+func (c *Client) GetConfigs(requests map[string]interface{}) error {
+	ctx := context.Background()
+	errs := map[string]error{}
+	if err := c.awaitReadiness(ctx); err != nil {
+		for name := range requests {
+			errs[name] = err
+		}
+		return &ConfigsError{Errors: errs}
+	}
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	for name, data := range requests {
+		if err := c.GetConfigContext(ctx, name, data, nil); err != nil {
+			errs[name] = err
+		}
+	}
+	if len(errs) > 0 {
+		return &ConfigsError{Errors: errs}
+	}
+	return nil
+}
+
+func GetConfigs(requests map[string]interface{}) error {
+	client := DefaultClient()
+	if client == nil {
+		return ErrNoDefaultClient
+	}
+	return client.GetConfigs(requests)
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetConfigs(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"name":    "John",
+		"retries": 3,
+		"db":      map[string]interface{}{"host": "localhost", "port": 5432},
+	})
+	var db struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	}
+	var name string
+	var retries int
+	err := client.GetConfigs(map[string]interface{}{
+		"name":    &name,
+		"retries": &retries,
+		"db":      &db,
+	})
+	if err != nil {
+		t.Fatalf("Error getting configs: %s", err.Error())
+	}
+	if name != "John" || retries != 3 || db.Host != "localhost" || db.Port != 5432 {
+		t.Errorf("Expected every config to be read, got %s, %d and %+v", name, retries, db)
+	}
+
+	// Every failure is reported, and the other configurations are still read.
+	timeout := 30
+	var port string
+	var count int
+	err = client.GetConfigs(map[string]interface{}{
+		"name":    &name,
+		"timeout": &timeout,
+		"db":      &port,
+		"retries": &count,
+	})
+	var configsErr *ConfigsError
+	if !errors.As(err, &configsErr) {
+		t.Fatalf("Expected a ConfigsError, got %v", err)
+	}
+	if len(configsErr.Errors) != 2 || configsErr.Errors["timeout"] == nil || configsErr.Errors["db"] == nil {
+		t.Errorf("Expected errors for timeout and db, got %v", configsErr.Errors)
+	}
+	if timeout != 30 {
+		t.Errorf("Expected a missing config to leave its value unchanged, got %d", timeout)
+	}
+	if count != 3 {
+		t.Errorf("Expected the other configs to be read, got %d", count)
+	}
+
+	// The errors of a closed Client match ErrClientClosed.
+	client.Close()
+	err = client.GetConfigs(map[string]interface{}{"name": &name})
+	if !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"sync"
+)
+
+// Cache stores the encoded configurations GetConfig decodes into the
+// caller's value, so that repeated reads of a key skip encoding the
+// repository data. The Client invalidates the cache after every refresh that
+// changed the data. Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the cached value for key, if there is one.
+	Get(key string) (interface{}, bool)
+	// Set caches value for key.
+	Set(key string, value interface{})
+	// Invalidate drops every cached value.
+	Invalidate()
+}
+
+// mapCache is the default Cache, a map that is replaced on every invalidation.
+type mapCache struct {
+	mu      sync.RWMutex
+	entries map[string]interface{}
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{entries: map[string]interface{}{}}
+}
+
+func (m *mapCache) Get(key string) (interface{}, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := m.entries[key]
+	return value, ok
+}
+
+func (m *mapCache) Set(key string, value interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = value
+}
+
+func (m *mapCache) Invalidate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = map[string]interface{}{}
+}
+
+// cachedConfig returns the cached encoding of the configuration with the given name.
+func (c *Client) cachedConfig(name string) ([]byte, bool) {
+	if c.cache == nil {
+		return nil, false
+	}
+	value, ok := c.cache.Get(name)
+	if !ok {
+		return nil, false
+	}
+	encoded, ok := value.([]byte)
+	return encoded, ok
+}
+
+// cacheConfig caches the encoding of the configuration with the given name,
+// unless the cache has been invalidated since generation was read.
+func (c *Client) cacheConfig(name string, generation uint64, encoded []byte) {
+	if c.cache == nil {
+		return
+	}
+	c.cacheMu.RLock()
+	defer c.cacheMu.RUnlock()
+	if c.cacheGeneration.Load() == generation {
+		c.cache.Set(name, encoded)
+	}
+}
+
+// invalidateCache invalidates the cache and starts a new generation, so that
+// encodings computed from the previous data are not cached anymore.
+func (c *Client) invalidateCache() {
+	if c.cache == nil {
+		return
+	}
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	c.cacheGeneration.Add(1)
+	c.cache.Invalidate()
+}
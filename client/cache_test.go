@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingCache is a Cache that records its calls.
+type recordingCache struct {
+	sync.Mutex
+	entries       map[string]interface{}
+	hits          int
+	invalidations int
+}
+
+func (r *recordingCache) Get(key string) (interface{}, bool) {
+	r.Lock()
+	defer r.Unlock()
+	value, ok := r.entries[key]
+	if ok {
+		r.hits++
+	}
+	return value, ok
+}
+
+func (r *recordingCache) Set(key string, value interface{}) {
+	r.Lock()
+	defer r.Unlock()
+	r.entries[key] = value
+}
+
+func (r *recordingCache) Invalidate() {
+	r.Lock()
+	defer r.Unlock()
+	r.entries = map[string]interface{}{}
+	r.invalidations++
+}
+
+func TestWithCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("name: John\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	cache := &recordingCache{entries: map[string]interface{}{}}
+	repository := &source.FileRepository{Name: "file", Path: path}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithCache(cache))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	if cache.invalidations != 1 {
+		t.Errorf("Expected the first refresh to invalidate the cache, got %d invalidations", cache.invalidations)
+	}
+
+	var name string
+	for i := 0; i < 2; i++ {
+		err = client.GetConfig("name", &name, "")
+		if err != nil || name != "John" {
+			t.Errorf("Expected name to be John, got %s (%v)", name, err)
+		}
+	}
+	if cache.hits != 1 {
+		t.Errorf("Expected the second read to hit the cache, got %d hits", cache.hits)
+	}
+
+	// A refresh invalidates the cache, so the new value is read.
+	err = os.WriteFile(path, []byte("name: Jane\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error refreshing client: %s", err.Error())
+	}
+	if cache.invalidations != 2 {
+		t.Errorf("Expected the refresh to invalidate the cache, got %d invalidations", cache.invalidations)
+	}
+	err = client.GetConfig("name", &name, "")
+	if err != nil || name != "Jane" {
+		t.Errorf("Expected name to be Jane, got %s (%v)", name, err)
+	}
+}
+
+func TestWithCacheNil(t *testing.T) {
+	repository := &mapRepository{data: map[string]interface{}{"name": "John"}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithCache(nil))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	var name string
+	_ = client.GetConfig("name", &name, "")
+	// Without a cache, changes are visible without a refresh.
+	repository.set("name", "Jane")
+	err = client.GetConfig("name", &name, "")
+	if err != nil || name != "Jane" {
+		t.Errorf("Expected name to be Jane, got %s (%v)", name, err)
+	}
+}
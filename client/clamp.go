@@ -0,0 +1,67 @@
+package client
+
+import (
+	"errors"
+)
+
+// ErrInvalidRange is returned by the clamped getters when min is greater than max.
+var ErrInvalidRange = errors.New("min is greater than max")
+
+// GetConfigIntClamped retrieves the int configuration with the given name and
+// clamps it to [min, max]. If the configuration cannot be read, defaultValue is
+// returned unclamped along with the error.
+func (c *Client) GetConfigIntClamped(name string, defaultValue int, min int, max int) (int, error) {
+	if min > max {
+		return defaultValue, ErrInvalidRange
+	}
+	value, err := c.GetConfigInt(name, defaultValue)
+	if err != nil {
+		return value, err
+	}
+	switch {
+	case value < min:
+		c.logClamp(name, value, min)
+		return min, nil
+	case value > max:
+		c.logClamp(name, value, max)
+		return max, nil
+	}
+	return value, nil
+}
+
+// GetConfigFloatClamped retrieves the float configuration with the given name
+// and clamps it to [min, max]. If the configuration cannot be read,
+// defaultValue is returned unclamped along with the error.
+func (c *Client) GetConfigFloatClamped(name string, defaultValue float64, min float64, max float64) (float64, error) {
+	if min > max {
+		return defaultValue, ErrInvalidRange
+	}
+	value, err := c.GetConfigFloat(name, defaultValue)
+	if err != nil {
+		return value, err
+	}
+	switch {
+	case value < min:
+		c.logClamp(name, value, min)
+		return min, nil
+	case value > max:
+		c.logClamp(name, value, max)
+		return max, nil
+	}
+	return value, nil
+}
+
+// logClamp logs that a configuration was clamped, if WithClampLogging is set.
+func (c *Client) logClamp(name string, value interface{}, clamped interface{}) {
+	if !c.logClamping {
+		return
+	}
+	if c.isSensitive(name) {
+		value, clamped = redactedValue, redactedValue
+	}
+	c.log().Warn("config value out of range, clamping", LogFields{
+		"config":  name,
+		"value":   value,
+		"clamped": clamped,
+	})
+}
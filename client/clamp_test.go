@@ -0,0 +1,87 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"github.com/sirupsen/logrus"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetConfigIntClamped(t *testing.T) {
+	client, repository := newMapClient(t, map[string]interface{}{"workers": 5})
+	tests := []struct {
+		name     string
+		value    int
+		expected int
+	}{
+		{name: "in range", value: 5, expected: 5},
+		{name: "below min", value: -3, expected: 1},
+		{name: "above max", value: 100, expected: 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository.set("workers", test.value)
+			value, err := client.GetConfigIntClamped("workers", 4, 1, 10)
+			if err != nil {
+				t.Errorf("Expected no error, got %s", err.Error())
+			}
+			if value != test.expected {
+				t.Errorf("Expected %d, got %d", test.expected, value)
+			}
+		})
+	}
+
+	value, err := client.GetConfigIntClamped("missing", 4, 1, 10)
+	if err == nil || value != 4 {
+		t.Errorf("Expected the default and an error for a missing key, got %d (%v)", value, err)
+	}
+	value, err = client.GetConfigIntClamped("workers", 4, 10, 1)
+	if err != ErrInvalidRange || value != 4 {
+		t.Errorf("Expected ErrInvalidRange, got %d (%v)", value, err)
+	}
+}
+
+func TestGetConfigFloatClamped(t *testing.T) {
+	client, repository := newMapClient(t, map[string]interface{}{"ratio": 0.5})
+	tests := []struct {
+		name     string
+		value    float64
+		expected float64
+	}{
+		{name: "in range", value: 0.5, expected: 0.5},
+		{name: "below min", value: -0.1, expected: 0},
+		{name: "above max", value: 1.5, expected: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository.set("ratio", test.value)
+			value, err := client.GetConfigFloatClamped("ratio", 0.25, 0, 1)
+			if err != nil {
+				t.Errorf("Expected no error, got %s", err.Error())
+			}
+			if value != test.expected {
+				t.Errorf("Expected %f, got %f", test.expected, value)
+			}
+		})
+	}
+}
+
+func TestWithClampLogging(t *testing.T) {
+	var logs bytes.Buffer
+	logrus.SetOutput(&logs)
+	defer logrus.SetOutput(os.Stderr)
+
+	repository := &mapRepository{data: map[string]interface{}{"workers": 100}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithClampLogging())
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	_, _ = client.GetConfigIntClamped("workers", 4, 1, 10)
+	if !strings.Contains(logs.String(), "clamping") {
+		t.Errorf("Expected clamping to be logged, got %q", logs.String())
+	}
+}
@@ -4,214 +4,759 @@ import (
 	"context"
 	"errors"
 	"github.com/divakarmanoj/go-remote-config/source"
-	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
+	"io"
+	"math/rand"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Client struct {
-	Repository      source.Repository
-	RefreshInterval time.Duration
-	isClosed        bool
-	cancel          context.CancelFunc
+	Repository          source.Repository
+	RefreshInterval     time.Duration
+	isClosed            atomic.Bool // set once Close has been called
+	cancel              context.CancelFunc
+	deploymentColor     string
+	mu                  sync.RWMutex
+	prefetched          map[string]prefetchEntry
+	refreshMu           sync.Mutex // serializes refreshes of the repository
+	flightMu            sync.Mutex
+	flight              *refreshCall // refresh of ForceRefresh in flight, nil if there is none; guarded by flightMu
+	obfuscateErrors     bool
+	obfuscateHosts      bool
+	ready               chan struct{} // closed after the first successful refresh
+	readyOnce           sync.Once
+	readinessGate       bool
+	readyTimeout        time.Duration
+	version             string // version marker of the data the derived state was computed from
+	derived             bool   // whether the derived state has been computed at least once
+	transformers        []ValueTransformer
+	transformed         atomic.Pointer[map[string]interface{}] // transformed values of the last accepted refresh
+	events              eventBus                               // fans refresh results out to listeners
+	values              map[string]interface{}                 // values recorded to find the keys a refresh changed
+	viewValues          atomic.Pointer[map[string]interface{}] // values of the last refresh, for a View
+	sources             map[string]string                      // repository each recorded value came from, for a source.SourceResolver
+	optionalKeys        map[string]bool                        // keys whose absence is not an error
+	transformKeyErrors  func(error) error                      // maps refresh errors to user-facing errors
+	cache               Cache                                  // cache of encoded configurations, nil disables caching
+	cacheMu             sync.RWMutex                           // orders cache writes against invalidations
+	cacheGeneration     atomic.Uint64                          // incremented on every invalidation of the cache
+	logClamping         bool                                   // log when a clamped getter clamps a value
+	shutdownHooks       []func()                               // run by Close once the refresh goroutine has stopped
+	closeOnce           sync.Once
+	stopped             chan struct{}          // closed when the refresh goroutine returns
+	forced              chan error             // outcome of the last ForceRefresh, for the refresh goroutine to restart its wait
+	notified            chan struct{}          // signaled when the repository loads a change on its own
+	validateOnStartOnly bool                   // skip validation on periodic refreshes
+	clock               Clock                  // tells the time, nil means the system clock
+	maxConcurrentGets   int                    // bound of concurrent GetData calls, 0 for the default
+	getSemaphore        chan struct{}          // bounds concurrent GetData calls, nil when unbounded
+	tenantResolver      TenantResolver         // resolves the tenant of the context getters, nil for none
+	snapshotValidators  []SnapshotValidator    // validators of the full snapshot of every refresh
+	snapshotTransforms  []SnapshotTransform    // transforms of the full snapshot of every refresh
+	overrides           map[string][]*override // values pinned with Override, latest last
+	overridesMu         sync.RWMutex
+	compactLog          *compactLog         // logs refreshes compactly, nil logs every failure
+	maxBackoff          time.Duration       // bound of the wait after failed refreshes, 0 for the default
+	backoffMultiplier   float64             // growth of the wait per failed refresh, 0 for the default
+	startupJitter       time.Duration       // bound of the random delay added to the first wait of the refresh loop
+	intervalJitter      float64             // fraction of the refresh interval each wait is randomly moved by
+	random              *rand.Rand          // random source of the refresh loop, nil for the global source
+	logger              Logger              // receives the log entries, nil for the standard logrus logger
+	codec               source.MarshalCodec // converts configuration values for getters, nil for YAML
+	timeLayouts         []string            // layouts of the strings GetConfigTime parses, nil for RFC 3339
+	statusMu            sync.Mutex
+	lastSuccess         time.Time                         // end of the last successful refresh, zero before the first
+	lastErr             error                             // error of the last refresh, nil if it succeeded
+	maxStaleness        time.Duration                     // age of the last success after which Healthy fails, 0 for no limit
+	skipInitialRefresh  bool                              // NewClient returns without refreshing the repository
+	maxStaleAge         time.Duration                     // age of the last success after which getters fail while refreshes fail, 0 for no limit
+	refreshTimeout      time.Duration                     // bound of every refresh of the repository, 0 for none
+	stuckRefresh        chan struct{}                     // closed when an abandoned refresh returns, nil if there is none; guarded by refreshMu
+	coerceScalars       bool                              // GetConfig coerces scalars to the types of the target before failing
+	paused              atomic.Bool                       // the refresh loop skips its refreshes; set under refreshMu
+	initialAttempts     int                               // attempts of the refresh NewClient blocks on, 0 for one
+	initialBackoff      time.Duration                     // wait after the first failed attempt of that refresh, doubled after each further one
+	degradedStart       bool                              // NewClient succeeds even if that refresh fails
+	lastGoodPath        string                            // file the data of successful refreshes is persisted to, empty for none
+	persisted           bool                              // the last good file was written; guarded by refreshMu
+	lastGoodSeeded      source.Repository                 // the repository the last good file was seeded beneath, nil if it was not or once a refresh succeeded
+	repositoryMu        sync.RWMutex                      // guards Repository against getters while the last good file is dropped
+	keyNormalizer       KeyNormalizer                     // maps names to their canonical form, nil to look names up exactly
+	readListeners       []ReadListener                    // called with every read of a getter
+	sensitiveKeys       map[string]bool                   // keys whose values are kept out of logs and errors
+	keyIndex            atomic.Pointer[map[string]string] // key of the repository of each canonical form, nil before the first refresh
 }
 
-var defaultClient *Client
+// defaultClient is the Client the package-level functions use.
+var defaultClient atomic.Pointer[Client]
+
+// DefaultClient returns the Client the package-level functions such as
+// GetConfig use, which is the last one created with NewClient or set with
+// SetDefaultClient, or nil if there is none.
+func DefaultClient() *Client {
+	return defaultClient.Load()
+}
+
+// SetDefaultClient makes client, which may be nil, the Client the
+// package-level functions use, for example to swap in a test Client.
+func SetDefaultClient(client *Client) {
+	defaultClient.Store(client)
+}
 
 // NewClient creates a new Client with the provided context, repository,
 // and refresh interval. It starts a background goroutine to periodically
 // refresh the configuration data from the repository based on the given
-// refresh interval. The function returns the created Client.
-func NewClient(ctx context.Context, repository source.Repository, refreshInterval time.Duration) (*Client, error) {
+// refresh interval. Options can be passed to customize the Client. The
+// repository may only be nil when data is seeded with WithInitialData or
+// WithSeedFromEnv.
+// The function returns the created Client, or ErrInvalidRefreshInterval
+// without refreshing the repository if the refresh interval is not positive.
+func NewClient(ctx context.Context, repository source.Repository, refreshInterval time.Duration, opts ...Option) (*Client, error) {
+	// A refresh interval that is not positive would make the background
+	// goroutine refresh the repository in a busy loop.
+	if refreshInterval <= 0 {
+		return nil, ErrInvalidRefreshInterval
+	}
+
 	// Create a new context and its corresponding cancel function
 	// for the Client. This allows us to control the lifetime of the
 	// background refresh goroutine.
 	ctx, cancel := context.WithCancel(ctx)
 
 	// Create the Client instance with the provided repository and refresh interval.
-	client := &Client{
-		Repository:      repository,
-		RefreshInterval: refreshInterval,
-		cancel:          cancel, // Store the cancel function in the Client struct for later use.
-	}
+	client := newClient(repository, opts)
+	client.RefreshInterval = refreshInterval
+	client.cancel = cancel // Store the cancel function in the Client struct for later use.
+	client.stopped = make(chan struct{})
+	client.forced = make(chan error, 1)
+	client.watchRepository(ctx)
 
-	// Refresh the configuration data for the first time to ensure the
-	// Client is initialized with the latest data before it is used.
-	err := client.Repository.Refresh()
-	if err != nil {
-		logrus.WithError(err).Error("error refreshing repository")
-		return nil, err
+	if client.readinessGate {
+		// With a readiness gate the first refresh runs in the background and
+		// getters wait for it instead of NewClient blocking on it.
+		go func() {
+			client.logRefresh(client.refreshRepository())
+		}()
+	} else if !client.skipInitialRefresh {
+		// Refresh the configuration data for the first time to ensure the
+		// Client is initialized with the latest data before it is used.
+		if err := client.initialRefresh(ctx); err != nil {
+			cancel()
+			return nil, err
+		}
 	}
 
 	// Start the background refresh goroutine by calling the refresh function
 	// with the newly created context and the client as arguments.
 	go refresh(ctx, client)
-	defaultClient = client
+	SetDefaultClient(client)
 	// Return the created Client instance, which is now ready to use.
 	return client, nil
 }
 
+// newClient creates a Client of the repository configured with opts, without
+// a refresh goroutine.
+func newClient(repository source.Repository, opts []Option) *Client {
+	client := &Client{
+		Repository: repository,
+		cancel:     func() {},
+		ready:      make(chan struct{}),
+		cache:      newMapCache(),
+	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	client.initGetLimit()
+	return client
+}
+
 // refresh is a goroutine that periodically refreshes the configuration data
-// from the repository based on the provided refresh interval. It stops
-// refreshing when the given context is canceled.
+// from the repository based on the provided refresh interval. After failed
+// refreshes it backs off exponentially, returning to the refresh interval
+// once a refresh succeeds. When the source asks to retry later, as with the
+// Retry-After header of an HTTP 429 or 503 response, the next refresh waits
+// for that delay instead. It stops refreshing when the given context is
+// canceled. A refresh forced with ForceRefresh restarts the wait, so the
+// next refresh does not follow it right away.
 func refresh(ctx context.Context, client *Client) {
-	ticker := time.NewTicker(client.RefreshInterval) // Create a new ticker with the given refresh interval
+	if client.stopped != nil {
+		defer close(client.stopped) // Let Close know the refresh routine has stopped
+	}
+	var failures int             // Number of consecutive failed refreshes
+	var retryAfter time.Duration // Delay the source asked for before the next refresh, 0 for none
+	first := true                // Whether the loop waits for its first refresh
 	for {
+		delay := client.refreshDelay(failures)
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+		if first {
+			delay += client.startupDelay()
+			first = false
+		}
+		wait, stop := client.after(delay)
 		select {
-		case <-ticker.C:
-			// The ticker has ticked, indicating it's time to refresh the data
-			err := client.Repository.Refresh() // Call the Refresh method of the repository to update the configuration data
-			if err != nil {
-				logrus.WithError(err).Error("error refreshing repository")
+		case <-wait:
+			// The wait is over, indicating it's time to refresh the data
+			err := client.refreshPeriodic() // Call the Refresh method of the repository to update the configuration data
+			if errors.Is(err, errRefreshPaused) {
+				continue
+			}
+			client.logRefresh(err)
+			retryAfter = source.RetryAfter(err)
+			// A partial refresh reached the source, so it does not back off.
+			if err != nil && !isPartial(err) {
+				failures++
+			} else {
+				failures = 0
+			}
+		case err := <-client.forced:
+			// The repository was just refreshed, wait again from now
+			stop()
+			retryAfter = source.RetryAfter(err)
+			if err != nil && !isPartial(err) {
+				failures++
+			} else {
+				failures = 0
 			}
 		case <-ctx.Done():
 			// The context is canceled, indicating the refresh routine should stop
+			stop()
 			return
 		}
 	}
 }
 
+// ForceRefresh refreshes the repository immediately instead of waiting for
+// the next tick of the background refresh goroutine, which then waits a full
+// refresh interval again. It returns ErrClientClosed without touching the
+// repository once the Client is closed. Calls made while another call is
+// refreshing do not refresh the repository again: they wait for that refresh
+// and return its error.
+func (c *Client) ForceRefresh(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.coalesce(ctx, func() error {
+		c.refreshMu.Lock()
+		defer c.refreshMu.Unlock()
+		err := c.doRefresh(true)
+		if c.forced != nil && !errors.Is(err, ErrClientClosed) {
+			// Replace the outcome the refresh goroutine has not taken yet, if any.
+			select {
+			case <-c.forced:
+			default:
+			}
+			c.forced <- err
+		}
+		return err
+	})
+}
+
+// refreshRepository refreshes the repository, serialized with any other
+// refresh of the Client.
+func (c *Client) refreshRepository() error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	return c.doRefresh(true)
+}
+
+// refreshPeriodic refreshes the repository from the refresh loop, which
+// skips validation when the Client validates on start only. It returns
+// errRefreshPaused without refreshing while the Client is paused.
+func (c *Client) refreshPeriodic() error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if c.paused.Load() {
+		return errRefreshPaused
+	}
+	return c.doRefresh(!c.validateOnStartOnly)
+}
+
+// doRefresh refreshes the repository and then updates everything the Client
+// derives from the repository data, validating it if validate is true. It
+// returns ErrClientClosed without touching the repository once the Client is
+// closed. The caller must hold refreshMu.
+func (c *Client) doRefresh(validate bool) error {
+	return c.update(validate, c.refreshWithTimeout)
+}
+
+// update is doRefresh with the repository refreshed by load, which does
+// nothing for data the repository has already loaded on its own.
+func (c *Client) update(validate bool, load func() error) error {
+	if c.isClosed.Load() {
+		return ErrClientClosed
+	}
+	startedAt := c.now()
+	changed, previous, sources, err := c.refreshData(validate, load)
+	if err == nil {
+		c.persistLastGood(changed)
+	}
+	result := RefreshResult{
+		StartedAt:   startedAt,
+		Duration:    c.now().Sub(startedAt),
+		Err:         err,
+		ChangedKeys: changed,
+		Source:      c.Repository.GetName(),
+	}
+	if len(changed) > 0 {
+		result.ChangeSources = sources
+		result.previous = previous
+		result.current = c.values
+	}
+	c.recordRefresh(err)
+	c.events.publish(result)
+	return err
+}
+
+// recordRefresh records the outcome of a refresh for LastRefresh.
+func (c *Client) recordRefresh(err error) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	c.lastErr = err
+	if err == nil || isPartial(err) {
+		c.lastSuccess = c.now()
+	}
+}
+
+// LastRefresh returns the time the last successful refresh of the repository
+// ended, on the Client's clock, and the error of the most recent refresh,
+// which is nil if it succeeded. The time is zero until a refresh succeeds. A
+// recent time and a nil error mean the configuration is current, while a
+// stale time or an error mean the Client is serving old data, which a health
+// endpoint can report. A repository that tolerates key errors, such as with
+// source.WithEtcdTolerateKeyErrors, applies the keys that parse: the refresh
+// then counts as successful, and its error unwraps to the source.KeyErrors
+// of the keys that keep their previous values.
+func (c *Client) LastRefresh() (time.Time, error) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+	return c.lastSuccess, c.lastErr
+}
+
+// refreshData refreshes the repository with load and then the derived state,
+// returning the keys that changed, the values recorded before the refresh and
+// the repositories the changes came from.
+func (c *Client) refreshData(validate bool, load func() error) ([]string, map[string]interface{}, map[string]string, error) {
+	err := load()
+	if err != nil {
+		partial := isPartial(err)
+		if c.transformKeyErrors != nil {
+			err = c.transformKeyErrors(err)
+		}
+		if c.obfuscateErrors {
+			err = obfuscateError(err, c.obfuscateHosts)
+		}
+		if !partial {
+			return nil, nil, nil, err
+		}
+		// Apply the keys that parsed, and report the others.
+		err = &partialError{err: err}
+	}
+	c.dropLastGood()
+	previousVersion := c.version
+	// Skip recomputing derived state when the repository reports an unchanged version.
+	if versioned, ok := c.Repository.(source.Versioned); ok {
+		version := versioned.Version()
+		if c.derived && version != "" && version == c.version {
+			c.log().Debug("version unchanged, skipping derived state", nil)
+			c.markReady()
+			return nil, nil, nil, err
+		}
+		c.version = version
+	}
+	// Transform and check the values before anything decodes them.
+	if err := c.loadTransformed(validate); err != nil {
+		// Check the rejected version again on the next refresh.
+		c.version = previousVersion
+		c.log().Error("rejected repository snapshot, keeping the last accepted data", LogFields{"error": err})
+		return nil, nil, nil, err
+	}
+	c.indexKeys()
+	c.invalidateCache()
+	changed, previous, sources := c.trackChanges()
+	if prefetchErr := c.prefetchAll(validate); prefetchErr != nil {
+		c.log().Error("error prefetching configs", LogFields{"error": prefetchErr})
+	}
+	c.derived = true
+	c.markReady()
+	return changed, previous, sources, err
+}
+
+// markReady records that the repository has been refreshed successfully at least once.
+func (c *Client) markReady() {
+	if c.ready == nil {
+		return
+	}
+	c.readyOnce.Do(func() {
+		close(c.ready)
+	})
+}
+
+// WaitReady blocks until the first successful refresh of the repository or
+// until the context is done, in which case it returns the context's error.
+func (c *Client) WaitReady(ctx context.Context) error {
+	if c.ready == nil {
+		return nil
+	}
+	select {
+	case <-c.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// awaitReadiness waits for the first successful refresh when the Client has
+// a readiness gate, returning ErrNotReady if it does not happen in time, or
+// the error of ctx if ctx is done first.
+func (c *Client) awaitReadiness(ctx context.Context) error {
+	if !c.readinessGate {
+		return nil
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, c.readyTimeout)
+	defer cancel()
+	if c.WaitReady(waitCtx) != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return ErrNotReady
+	}
+	return nil
+}
+
 func GetConfig(name string, data interface{}, defaultValue interface{}) error {
-	return defaultClient.GetConfig(name, data, defaultValue)
+	client := DefaultClient()
+	if client == nil {
+		new(Client).assignDefault(data, defaultValue)
+		return ErrNoDefaultClient
+	}
+	return client.GetConfig(name, data, defaultValue)
 }
 
 func GetConfigArrayOfStrings(name string, defaultValue []string) ([]string, error) {
-	return defaultClient.GetConfigArrayOfStrings(name, defaultValue)
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigArrayOfStrings(name, defaultValue)
 }
 
 func GetConfigString(name string, defaultValue string) (string, error) {
-	return defaultClient.GetConfigString(name, defaultValue)
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigString(name, defaultValue)
 }
 
 func GetConfigInt(name string, defaultValue int) (int, error) {
-	return defaultClient.GetConfigInt(name, defaultValue)
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigInt(name, defaultValue)
 }
 
 func GetConfigFloat(name string, defaultValue float64) (float64, error) {
-	return defaultClient.GetConfigFloat(name, defaultValue)
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigFloat(name, defaultValue)
+}
+
+func GetConfigBool(name string, defaultValue bool) (bool, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigBool(name, defaultValue)
+}
+
+// Done returns a channel that is closed once the background refresh goroutine
+// has returned after Close, so tests can assert that closing a Client leaks no
+// goroutine. The channel of a Client created with NewStaticClient is closed
+// from the start, and that of a Client created otherwise is never closed.
+func (c *Client) Done() <-chan struct{} {
+	return c.stopped
 }
 
 // Close stops the background refresh goroutine of the Client by canceling
-// its associated context. This function allows graceful termination of the
-// background routine and prevents potential goroutine leaks. It should be
+// its associated context, and waits for it to return and for a refresh in
+// flight, such as one of ForceRefresh, to finish, so that no refresh uses the
+// repository once Close returns. This function allows graceful termination of
+// the background routine and prevents potential goroutine leaks. It should be
 // called when the Client is no longer needed to release resources properly.
+// Calling Close more than once has no further effect. Use Shutdown to bound
+// the wait for a slow refresh.
 func (c *Client) Close() {
+	_ = c.Shutdown(context.Background())
+}
+
+// Shutdown is Close with the wait for the refresh goroutine and a refresh in
+// flight bounded by ctx. Once ctx is done it stops waiting and returns its
+// error, and the refresh keeps running in the background. The repository is
+// closed and the shutdown hooks run after the wait either way, so a repository
+// whose Close aborts its requests, such as a source.StreamRepository, still
+// ends a refresh that is blocked.
+func (c *Client) Shutdown(ctx context.Context) error {
+	// Mark the Client closed first so no new refresh starts.
+	c.isClosed.Store(true)
 	// Call the Cancel function associated with the Client's context.
 	// This cancels the context, causing the background refresh goroutine
 	// (started by NewClient) to return and terminate gracefully.
 	c.cancel()
-	c.isClosed = true
+	err := c.waitRefreshes(ctx)
+	c.closeOnce.Do(func() {
+		// Stop the background work of repositories that have any, such as
+		// the reconnect loop of a source.StreamRepository.
+		if closer, ok := c.Repository.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				c.log().Error("error closing repository", LogFields{"error": err})
+			}
+		}
+		for _, hook := range c.shutdownHooks {
+			hook()
+		}
+	})
+	return err
 }
 
-// GetConfig retrieves the configuration with the given name from the repository
-// and stores it in the provided data pointer. It returns an error if the
-// configuration is not found, the data argument is not a non-nil pointer, or
-// the type of the data is not compatible with the type in the repository.
-func (c *Client) GetConfig(name string, data interface{}, defaultValue interface{}) error {
-	if c.isClosed {
-		data = defaultValue
-		return errors.New("client is closed")
-	}
-	// Get the configuration data from the repository
-	config, ok := c.Repository.GetData(name)
-	if !ok {
-		data = defaultValue
-		return errors.New("config not found")
-	}
-	//
-	marshal, err := yaml.Marshal(config)
-	if err != nil {
-		data = defaultValue
-		return err
+// waitRefreshes waits until the refresh goroutine has returned and no refresh
+// holds refreshMu, or until ctx is done.
+func (c *Client) waitRefreshes(ctx context.Context) error {
+	if c.stopped != nil {
+		select {
+		case <-c.stopped:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	// Unmarshal the configuration data into the provided data pointer
-	err = yaml.Unmarshal(marshal, data)
-	if err != nil {
-		data = defaultValue
-		return err
+	idle := make(chan struct{})
+	go func() {
+		c.refreshMu.Lock()
+		c.refreshMu.Unlock()
+		close(idle)
+	}()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	return nil
+// getData looks up the configuration with the given name in the repository,
+// preferring the variant for the Client's deployment color when one is set.
+// A name that is not a top-level configuration is read as a dotted path, such
+// as database.primary.host, into one.
+func (c *Client) getData(name string) (interface{}, bool) {
+	config, ok, _ := c.getScheduled(name)
+	return config, ok
 }
 
-// GetConfigArrayOfStrings retrieves the configuration with the given name from the repository
-func (c *Client) GetConfigArrayOfStrings(name string, defaultValue []string) ([]string, error) {
-	if c.isClosed {
-		return defaultValue, errors.New("client is closed")
+// getScheduled is getData, also reporting whether the value of the
+// configuration must not be cached, because it depends on the time as it is
+// scheduled or expiring, or because it is pinned with Override.
+func (c *Client) getScheduled(name string) (interface{}, bool, bool) {
+	if value, ok := c.overridden(name); ok {
+		return value, true, true
 	}
-	// Get the configuration data from the repository
-	config, ok := c.Repository.GetData(name)
+	config, ok := c.lookupColor(name)
+	var path []string
 	if !ok {
-		return defaultValue, errors.New("config not found")
+		// Fall back to a dotted path into a top-level configuration.
+		name, path = splitPath(name)
+		if path != nil {
+			config, ok = c.lookupColor(name)
+		}
 	}
-
-	configArray, ok := config.([]interface{})
 	if !ok {
-		return defaultValue, errors.New("config is not an array of strings")
+		return nil, false, false
 	}
-	output := []string{}
-	for _, v := range configArray {
-		str, ok := v.(string)
-		if !ok {
-			return defaultValue, errors.New("config is not an array of strings")
-		}
-		output = append(output, str)
+	refreshedAt, _ := c.LastRefresh()
+	config, ok, expiring := resolveExpiry(config, refreshedAt, c.now())
+	if !ok {
+		return nil, false, true
 	}
-
-	return output, nil
+	config, ok, scheduled := resolveSchedule(config, c.now())
+	if ok && path != nil {
+		config, ok = descend(config, path)
+	}
+	return config, ok, scheduled || expiring
 }
 
-// GetConfigString retrieves the configuration with the given name from the repository
-func (c *Client) GetConfigString(name string, defaultValue string) (string, error) {
-	if c.isClosed {
-		return defaultValue, errors.New("client is closed")
+// lookupColor looks up the configuration with the given name, preferring the
+// variant for the Client's deployment color when one is set.
+func (c *Client) lookupColor(name string) (interface{}, bool) {
+	if c.deploymentColor != "" {
+		if config, ok := c.lookup(name + "@" + c.deploymentColor); ok {
+			return config, true
+		}
 	}
-	// Get the configuration data from the repository
-	config, ok := c.Repository.GetData(name)
-	if !ok {
-		return defaultValue, errors.New("config not found")
+	return c.lookup(name)
+}
+
+// notFound returns the error getters report when the configuration with the
+// given name is missing, which is nil for optional keys.
+func (c *Client) notFound(name string) error {
+	if c.optionalKeys[name] {
+		return nil
 	}
+	return keyError(name, ErrConfigNotFound)
+}
 
-	configString, ok := config.(string)
-	if !ok {
-		return defaultValue, errors.New("config is not a string")
+// GetConfig retrieves the configuration with the given name from the repository
+// and stores it in the provided data pointer. It returns an error if the
+// configuration is not found, the data argument is not a non-nil pointer, or
+// the type of the data is not compatible with the type in the repository.
+// The configuration is converted into data through YAML, or the codec set
+// with WithCodec. When data points to a struct and defaultValue is that struct or a pointer to
+// it, the configuration is decoded over a copy of defaultValue, so fields the
+// configuration does not set take their default values. On an error, a copy of
+// defaultValue is stored in data instead, converted the same way if it is of
+// another type; a nil defaultValue leaves data untouched. A configuration
+// that is present but empty, such as `key: ~` in YAML, is not decoded over
+// data either: data is left as defaultValue and no error is returned.
+func (c *Client) GetConfig(name string, data interface{}, defaultValue interface{}) error {
+	return c.GetConfigContext(context.Background(), name, data, defaultValue)
+}
+
+// checkData returns ErrInvalidData unless data, the argument of GetConfig for
+// the configuration with the given name, is a non-nil pointer.
+func checkData(name string, data interface{}) error {
+	target := reflect.ValueOf(data)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return keyError(name, ErrInvalidData)
 	}
+	return nil
+}
 
-	return configString, nil
+// GetConfigContext is GetConfig for the tenant of ctx, falling back to the
+// shared value. It returns the error of ctx without looking the configuration
+// up if ctx is done, and stops waiting for a readiness gate once it is.
+func (c *Client) GetConfigContext(ctx context.Context, name string, data interface{}, defaultValue interface{}) (err error) {
+	defer c.observe("GetConfig", name, &err)
+	return c.getConfig(ctx, name, data, defaultValue)
 }
 
-// GetConfigInt retrieves the configuration with the given name from the repository
-func (c *Client) GetConfigInt(name string, defaultValue int) (int, error) {
-	if c.isClosed {
-		return defaultValue, errors.New("client is closed")
+// getConfig is GetConfigContext for getters built on it, which report the
+// read themselves.
+func (c *Client) getConfig(ctx context.Context, name string, data interface{}, defaultValue interface{}) (err error) {
+	if err := checkData(name, data); err != nil {
+		return err
 	}
-	// Get the configuration data from the repository
-	config, ok := c.Repository.GetData(name)
-	if !ok {
-		return defaultValue, errors.New("config not found")
+	if c.isClosed.Load() {
+		c.assignDefault(data, defaultValue)
+		return ErrClientClosed
+	}
+	if err := ctx.Err(); err != nil {
+		c.assignDefault(data, defaultValue)
+		return err
+	}
+	if err := c.awaitReadiness(ctx); err != nil {
+		c.assignDefault(data, defaultValue)
+		return err
+	}
+	if err := c.checkStale(name); err != nil {
+		c.assignDefault(data, defaultValue)
+		return err
+	}
+	// Decode over a copy of the default struct, so absent fields keep their defaults
+	mergeDefaults(data, defaultValue)
+	// Tenant overrides are decoded on every lookup, as the caches are shared by tenants
+	if config, ok := c.tenantOverride(ctx, name); ok {
+		if err := valueError(config); err != nil {
+			c.assignDefault(data, defaultValue)
+			return err
+		}
+		if config == nil {
+			c.assignDefault(data, defaultValue)
+			return nil
+		}
+		marshal, err := c.marshal(config)
+		if err == nil {
+			if err = c.unmarshalConfig(marshal, data); err != nil {
+				err = keyError(name, &decodeError{err: err})
+			}
+		}
+		if err != nil {
+			c.assignDefault(data, defaultValue)
+		}
+		return err
+	}
+	// Serve the decoding computed by Prefetch, if there is one for this type
+	if c.loadPrefetched(name, data) {
+		return nil
 	}
-	configInt, ok := config.(int)
+	marshal, ok := c.cachedConfig(name)
 	if !ok {
-		return defaultValue, errors.New("config is not an int64")
+		generation := c.cacheGeneration.Load()
+		// Get the configuration data from the repository
+		config, ok, scheduled := c.getScheduled(name)
+		if !ok {
+			c.assignDefault(data, defaultValue)
+			return c.notFound(name)
+		}
+		// An empty value stands for the default rather than a null to decode.
+		if config == nil {
+			c.assignDefault(data, defaultValue)
+			return nil
+		}
+		if err := valueError(config); err != nil {
+			c.assignDefault(data, defaultValue)
+			return err
+		}
+		//
+		var err error
+		marshal, err = c.marshal(config)
+		if err != nil {
+			c.assignDefault(data, defaultValue)
+			return err
+		}
+		// Scheduled, expiring and overridden values change without a refresh, so they are not cached.
+		if !scheduled {
+			c.cacheConfig(name, generation, marshal)
+		}
+	}
+	// Unmarshal the configuration data into the provided data pointer
+	err = c.unmarshalConfig(marshal, data)
+	if err != nil {
+		c.assignDefault(data, defaultValue)
+		return keyError(name, &decodeError{err: err})
 	}
 
-	return configInt, nil
+	return nil
+}
+
+// GetConfigArrayOfStrings retrieves the configuration with the given name from the repository
+func (c *Client) GetConfigArrayOfStrings(name string, defaultValue []string) ([]string, error) {
+	return c.GetConfigArrayOfStringsContext(context.Background(), name, defaultValue)
+}
+
+// GetConfigString retrieves the configuration with the given name from the repository
+func (c *Client) GetConfigString(name string, defaultValue string) (string, error) {
+	return c.GetConfigStringContext(context.Background(), name, defaultValue)
+}
+
+// GetConfigInt retrieves the configuration with the given name from the repository.
+// Integers of any type and floats with no fractional part are converted to an
+// int, as long as they fit in one.
+func (c *Client) GetConfigInt(name string, defaultValue int) (int, error) {
+	return c.GetConfigIntContext(context.Background(), name, defaultValue)
 }
 
 // GetConfigFloat retrieves the configuration with the given name from the repository
 func (c *Client) GetConfigFloat(name string, defaultValue float64) (float64, error) {
-	if c.isClosed {
-		return defaultValue, errors.New("client is closed")
-	}
-	// Get the configuration data from the repository
-	config, ok := c.Repository.GetData(name)
-	if !ok {
-		return defaultValue, errors.New("config not found")
-	}
-	configInt, ok := config.(float64)
-	if !ok {
-		return defaultValue, errors.New("config is not an int64")
-	}
+	return c.GetConfigFloatContext(context.Background(), name, defaultValue)
+}
 
-	return configInt, nil
+// GetConfigBool retrieves the configuration with the given name from the repository.
+// Besides a bool, it accepts the string encodings of a bool, such as "true",
+// "false", "1" and "0", that some sources produce.
+func (c *Client) GetConfigBool(name string, defaultValue bool) (bool, error) {
+	return c.GetConfigBoolContext(context.Background(), name, defaultValue)
 }
@@ -9,7 +9,11 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -204,6 +208,47 @@ func (t *test) GetName() string {
 	return "test"
 }
 
+// mapRepository is an in-memory Repository used to drive the client in tests.
+type mapRepository struct {
+	sync.RWMutex
+	data map[string]interface{}
+}
+
+func (m *mapRepository) GetData(name string) (config interface{}, isPresent bool) {
+	m.RLock()
+	defer m.RUnlock()
+	config, isPresent = m.data[name]
+	return config, isPresent
+}
+
+func (m *mapRepository) GetRawData() []byte {
+	return nil
+}
+
+func (m *mapRepository) Refresh() error {
+	return nil
+}
+
+func (m *mapRepository) GetName() string {
+	return "map"
+}
+
+func (m *mapRepository) set(name string, value interface{}) {
+	m.Lock()
+	defer m.Unlock()
+	m.data[name] = value
+}
+
+func newMapClient(t *testing.T, data map[string]interface{}) (*Client, *mapRepository) {
+	repository := &mapRepository{data: data}
+	client, err := NewClient(context.Background(), repository, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	t.Cleanup(client.Close)
+	return client, repository
+}
+
 func TestRefresh(t *testing.T) {
 	// should throw Err
 	_, err := NewClient(context.Background(), &test{ShouldError: true}, 1*time.Second)
@@ -222,7 +267,8 @@ func TestRefresh(t *testing.T) {
 	if count != 0 {
 		t.Errorf("Expected count to be 0, got %d", count)
 	}
-	ctx, _ := context.WithTimeout(context.Background(), 1*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
 	refresh(ctx, client)
 	if client.GetConfig("test", &count, nil) != nil {
 		t.Errorf("Expected error, got nil")
@@ -329,3 +375,332 @@ func TestRefresh(t *testing.T) {
 //		})
 //	}
 //}
+
+func TestForceRefresh(t *testing.T) {
+	repository := &test{}
+	client, err := NewClient(context.Background(), repository, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Errorf("Error forcing refresh: %s", err.Error())
+	}
+	if repository.GetRefeshCount != 2 {
+		t.Errorf("Expected 2 refreshes, got %d", repository.GetRefeshCount)
+	}
+
+	client.Close()
+	err = client.ForceRefresh(context.Background())
+	if !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed, got %v", err)
+	}
+	if repository.GetRefeshCount != 2 {
+		t.Errorf("Expected repository not to be refreshed after Close, got %d refreshes", repository.GetRefeshCount)
+	}
+}
+
+func TestGettersAfterClose(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{"name": "John", "age": 30})
+	client.Close()
+
+	var name string
+	if err := client.GetConfig("name", &name, nil); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed from GetConfig, got %v", err)
+	}
+	if _, err := client.GetConfigString("name", ""); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed from GetConfigString, got %v", err)
+	}
+	if _, err := client.GetConfigInt("age", 0); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed from GetConfigInt, got %v", err)
+	}
+	if _, err := client.GetConfigFloat("age", 0); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed from GetConfigFloat, got %v", err)
+	}
+	if _, err := client.GetConfigArrayOfStrings("name", nil); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed from GetConfigArrayOfStrings, got %v", err)
+	}
+	if _, err := client.GetConfigBool("name", false); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed from GetConfigBool, got %v", err)
+	}
+}
+
+func TestGetConfigBool(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"enabled":  true,
+		"disabled": false,
+		"on":       "true",
+		"off":      "0",
+		"one":      "1",
+		"word":     "maybe",
+		"number":   1,
+	})
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"enabled", true},
+		{"disabled", false},
+		{"on", true},
+		{"off", false},
+		{"one", true},
+	}
+	for _, test := range tests {
+		value, err := client.GetConfigBool(test.name, !test.expected)
+		if err != nil || value != test.expected {
+			t.Errorf("Expected %s to be %t, got %t (%v)", test.name, test.expected, value, err)
+		}
+	}
+	for _, name := range []string{"word", "number", "missing"} {
+		value, err := client.GetConfigBool(name, true)
+		if err == nil || !value {
+			t.Errorf("Expected an error and the default for %s, got %t (%v)", name, value, err)
+		}
+	}
+}
+
+// closableRepository is a mapRepository that counts calls to Close.
+type closableRepository struct {
+	mapRepository
+	closed int
+}
+
+func (c *closableRepository) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestCloseClosesRepository(t *testing.T) {
+	repository := &closableRepository{mapRepository: mapRepository{data: map[string]interface{}{}}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	client.Close()
+	client.Close()
+	if repository.closed != 1 {
+		t.Errorf("Expected the repository to be closed once, got %d", repository.closed)
+	}
+}
+
+func TestNewClientInvalidRefreshInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		repository := &mapRepository{data: map[string]interface{}{}}
+		client, err := NewClient(context.Background(), repository, interval)
+		if !errors.Is(err, ErrInvalidRefreshInterval) || client != nil {
+			t.Errorf("Expected ErrInvalidRefreshInterval for %s, got %v", interval, err)
+		}
+	}
+}
+
+func TestDefaultClient(t *testing.T) {
+	previous := DefaultClient()
+	t.Cleanup(func() {
+		SetDefaultClient(previous)
+	})
+
+	// Without a default Client the package-level functions return the default.
+	SetDefaultClient(nil)
+	name, err := GetConfigString("name", "default")
+	if !errors.Is(err, ErrNoDefaultClient) || name != "default" {
+		t.Errorf("Expected the default and ErrNoDefaultClient, got %s (%v)", name, err)
+	}
+	var retries int
+	err = GetConfig("retries", &retries, 3)
+	if !errors.Is(err, ErrNoDefaultClient) || retries != 3 {
+		t.Errorf("Expected the default and ErrNoDefaultClient, got %d (%v)", retries, err)
+	}
+	if IsFeatureEnabled("features", "beta") {
+		t.Errorf("Expected no feature to be enabled without a default Client")
+	}
+
+	// NewClient sets the default Client, and SetDefaultClient replaces it.
+	created, _ := newMapClient(t, map[string]interface{}{"name": "John"})
+	if DefaultClient() != created {
+		t.Errorf("Expected NewClient to set the default Client")
+	}
+	swapped, _ := newMapClient(t, map[string]interface{}{"name": "Jane"})
+	SetDefaultClient(created)
+	if DefaultClient() != created {
+		t.Errorf("Expected SetDefaultClient to set the default Client")
+	}
+	name, err = GetConfigString("name", "default")
+	if err != nil || name != "John" {
+		t.Errorf("Expected John from the default Client, got %s (%v)", name, err)
+	}
+	SetDefaultClient(swapped)
+	name, _ = GetConfigString("name", "default")
+	if name != "Jane" {
+		t.Errorf("Expected Jane from the swapped default Client, got %s", name)
+	}
+}
+
+func TestCloseConcurrent(t *testing.T) {
+	repository := &closableRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}}
+	client, err := NewClient(context.Background(), repository, time.Millisecond)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			client.Close()
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				name, err := client.GetConfigString("name", "default")
+				closed := errors.Is(err, ErrClientClosed) && name == "default"
+				if !closed && (err != nil || name != "John") {
+					t.Errorf("Expected John or the default with ErrClientClosed, got %s (%v)", name, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	<-client.Done()
+	if repository.closed != 1 {
+		t.Errorf("Expected the repository to be closed once, got %d", repository.closed)
+	}
+	_, err = client.GetConfigString("name", "default")
+	if !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed after Close, got %v", err)
+	}
+}
+
+func TestJSON5Int(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json5")
+	err := os.WriteFile(path, []byte("{\n  // retry budget\n  retries: 3,\n}\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path, Codec: source.JSON5Codec}, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	retries, err := client.GetConfigInt("retries", 0)
+	if err != nil {
+		t.Errorf("Error getting retries: %s", err.Error())
+	}
+	if retries != 3 {
+		t.Errorf("Expected retries to be 3, got %d", retries)
+	}
+}
+
+// refreshGoroutines counts the goroutines running the refresh loop.
+func refreshGoroutines() int {
+	buf := make([]byte, 1<<20)
+	stacks := string(buf[:runtime.Stack(buf, true)])
+	return strings.Count(stacks, "go-remote-config/client.refresh(")
+}
+
+func TestDone(t *testing.T) {
+	before := refreshGoroutines()
+	client, err := NewClient(context.Background(), &mapRepository{data: map[string]interface{}{}}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	deadline := time.Now().Add(time.Second)
+	for refreshGoroutines() != before+1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if refreshGoroutines() != before+1 {
+		t.Errorf("Expected a refresh goroutine to be running")
+	}
+	select {
+	case <-client.Done():
+		t.Fatalf("Expected Done not to be closed before Close")
+	default:
+	}
+
+	client.Close()
+	select {
+	case <-client.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("Expected Done to be closed after Close")
+	}
+	if after := refreshGoroutines(); after > before {
+		t.Errorf("Expected the refresh goroutine to have exited, got %d running, %d before", after, before)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	repository := &gatedRepository{
+		mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}},
+		started:       make(chan struct{}, 1),
+		release:       make(chan struct{}),
+	}
+	client, err := NewClient(context.Background(), repository, time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	repository.blocking.Store(true)
+	go func() {
+		_ = client.ForceRefresh(context.Background())
+	}()
+	<-repository.started
+
+	// The wait for the refresh in flight is bounded by the context.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := client.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded while the refresh is in flight, got %v", err)
+	}
+
+	// Once the refresh finishes, Shutdown returns with the goroutine gone.
+	close(repository.release)
+	if err := client.Shutdown(context.Background()); err != nil {
+		t.Errorf("Error shutting down: %s", err.Error())
+	}
+	select {
+	case <-client.Done():
+	default:
+		t.Errorf("Expected the refresh goroutine to have returned when Shutdown returns")
+	}
+	if !client.refreshMu.TryLock() {
+		t.Errorf("Expected no refresh in flight when Shutdown returns")
+	}
+}
+
+func TestLastRefresh(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	repository := &flakyRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}}
+	repository.failing.Store(true)
+	client, err := NewClient(context.Background(), repository, time.Hour, WithClock(clock), WithReadinessGate(time.Second), WithLogger(NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	refresh := func() {
+		t.Helper()
+		_ = client.ForceRefresh(context.Background())
+	}
+
+	// Before the first successful refresh the time is zero.
+	refresh()
+	at, err := client.LastRefresh()
+	if !at.IsZero() || err == nil {
+		t.Errorf("Expected a zero time and an error before a success, got %s (%v)", at, err)
+	}
+
+	repository.failing.Store(false)
+	clock.advance(time.Minute)
+	refresh()
+	at, err = client.LastRefresh()
+	if !at.Equal(clock.Now()) || err != nil {
+		t.Errorf("Expected the time of the success and no error, got %s (%v)", at, err)
+	}
+
+	// A failure keeps the time of the last success.
+	success := at
+	repository.failing.Store(true)
+	clock.advance(time.Minute)
+	refresh()
+	at, err = client.LastRefresh()
+	if !at.Equal(success) || err == nil || err.Error() != "backend unavailable" {
+		t.Errorf("Expected the time of the last success and the error, got %s (%v)", at, err)
+	}
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"time"
+)
+
+// Clock tells the Client the current time. Replace it with WithClock, for
+// example to control time in tests with a configtest.FakeClock.
+type Clock interface {
+	Now() time.Time
+}
+
+// TimerClock is a Clock that also waits. When the Clock of a Client is a
+// TimerClock, the refresh loop waits for the next refresh and OnExpire for
+// configurations to lapse with After.
+type TimerClock interface {
+	Clock
+	// After returns a channel that receives the time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the TimerClock of the system, which a Client uses unless
+// WithClock replaces it.
+type SystemClock struct{}
+
+// Now returns the current time.
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After returns a channel that receives the time once d has elapsed.
+func (SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// now returns the current time of the Client's clock.
+func (c *Client) now() time.Time {
+	if c.clock == nil {
+		return SystemClock{}.Now()
+	}
+	return c.clock.Now()
+}
+
+// after returns a channel that receives the time once d has elapsed on the
+// Client's clock, and a function releasing the timer behind it. The system
+// clock waits on a timer of its own, which can be released early.
+func (c *Client) after(d time.Duration) (<-chan time.Time, func()) {
+	if clock, ok := c.clock.(TimerClock); ok && clock != (SystemClock{}) {
+		return clock.After(d), func() {}
+	}
+	timer := time.NewTimer(d)
+	return timer.C, func() { timer.Stop() }
+}
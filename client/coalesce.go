@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+)
+
+// refreshCall is a refresh of the repository requested with ForceRefresh
+// that later requests share while it runs.
+type refreshCall struct {
+	done chan struct{} // closed once the refresh returned
+	err  error
+}
+
+// coalesce runs refresh unless another coalesced refresh is in flight, in
+// which case it waits for that one and returns its error instead, so that a
+// burst of requests refreshes the repository once. A caller that waits gives
+// up with the error of ctx once it is done, leaving the refresh running.
+func (c *Client) coalesce(ctx context.Context, refresh func() error) error {
+	c.flightMu.Lock()
+	if call := c.flight; call != nil {
+		c.flightMu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	call := &refreshCall{done: make(chan struct{})}
+	c.flight = call
+	c.flightMu.Unlock()
+	defer func() {
+		c.flightMu.Lock()
+		c.flight = nil
+		c.flightMu.Unlock()
+		close(call.done)
+	}()
+	call.err = refresh()
+	return call.err
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gatedRepository is a Repository whose Refresh, once blocking, signals
+// started and waits for release.
+type gatedRepository struct {
+	mapRepository
+	refreshes atomic.Int32
+	blocking  atomic.Bool
+	started   chan struct{}
+	release   chan struct{}
+}
+
+func (b *gatedRepository) Refresh() error {
+	b.refreshes.Add(1)
+	if b.blocking.Load() {
+		b.started <- struct{}{}
+		<-b.release
+	}
+	return nil
+}
+
+func TestForceRefreshCoalesces(t *testing.T) {
+	repository := &gatedRepository{
+		mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}},
+		started:       make(chan struct{}, 1),
+		release:       make(chan struct{}),
+	}
+	client, err := NewClient(context.Background(), repository, time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	repository.refreshes.Store(0)
+	repository.blocking.Store(true)
+
+	errs := make(chan error, 20)
+	go func() { errs <- client.ForceRefresh(context.Background()) }()
+	<-repository.started
+	var wg sync.WaitGroup
+	for i := 1; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			wg.Done()
+			errs <- client.ForceRefresh(context.Background())
+		}()
+	}
+	wg.Wait()
+	// Give the callers time to join the refresh in flight.
+	time.Sleep(50 * time.Millisecond)
+	close(repository.release)
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Expected the shared refresh to succeed, got %s", err.Error())
+		}
+	}
+	if refreshes := repository.refreshes.Load(); refreshes != 1 {
+		t.Errorf("Expected a single refresh, got %d", refreshes)
+	}
+
+	// A caller that gives up leaves the refresh running.
+	repository.release = make(chan struct{})
+	go func() { errs <- client.ForceRefresh(context.Background()) }()
+	<-repository.started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := client.ForceRefresh(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected the waiting caller to stop with its context, got %v", err)
+	}
+	close(repository.release)
+	if err := <-errs; err != nil {
+		t.Errorf("Expected the refresh to succeed, got %s", err.Error())
+	}
+}
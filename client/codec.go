@@ -0,0 +1,55 @@
+package client
+
+import (
+	"fmt"
+	"github.com/divakarmanoj/go-remote-config/source"
+)
+
+// conversionCodec returns the codec the Client converts values with.
+func (c *Client) conversionCodec() source.MarshalCodec {
+	if c.codec == nil {
+		return source.YAMLCodec
+	}
+	return c.codec
+}
+
+// marshal encodes a configuration value with the codec of the Client.
+func (c *Client) marshal(v interface{}) ([]byte, error) {
+	return c.conversionCodec().Marshal(v)
+}
+
+// unmarshal decodes an encoded configuration value into v with the codec of the Client.
+func (c *Client) unmarshal(data []byte, v interface{}) error {
+	return c.conversionCodec().Unmarshal(data, v)
+}
+
+// decodeConfig decodes the configuration value config into data with the
+// round trip through the codec of the Client that GetConfig uses.
+func (c *Client) decodeConfig(config interface{}, data interface{}) error {
+	marshal, err := c.marshal(config)
+	if err != nil {
+		return err
+	}
+	return c.unmarshalConfig(marshal, data)
+}
+
+// keyCodecTransformer returns a ValueTransformer that parses the string value
+// of each configuration named in codecs with its codec. When it cannot be
+// parsed, the configuration is replaced by the error.
+func keyCodecTransformer(codecs map[string]source.Codec) ValueTransformer {
+	return func(key string, value interface{}) interface{} {
+		codec, ok := codecs[key]
+		if !ok {
+			return value
+		}
+		raw, ok := value.(string)
+		if !ok {
+			return value
+		}
+		var parsed interface{}
+		if err := codec.Unmarshal([]byte(raw), &parsed); err != nil {
+			return &valueFailure{err: fmt.Errorf("%w: not parsed by its codec: %v", ErrTypeMismatch, err)}
+		}
+		return parsed
+	}
+}
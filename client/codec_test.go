@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithCodec(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{"db": {"max_conns": 20, "ratio": 0.5, "id": 9007199254740993, "timeout": null}}`), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	repository := &source.FileRepository{Name: "file", Path: path, Codec: source.WithDecoderOptions(source.JSONCodec, source.UseNumber())}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithCodec(source.JSONCodec))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// Structs are decoded by their json tags, with the numeric types of their fields.
+	type database struct {
+		MaxConns int     `json:"max_conns"`
+		Ratio    float64 `json:"ratio"`
+		ID       int64   `json:"id"`
+		Timeout  *int    `json:"timeout"`
+	}
+	var db database
+	err = client.GetConfig("db", &db, nil)
+	if err != nil {
+		t.Fatalf("Error getting db: %s", err.Error())
+	}
+	if db.MaxConns != 20 || db.Ratio != 0.5 || db.ID != 9007199254740993 || db.Timeout != nil {
+		t.Errorf("Expected {20 0.5 9007199254740993 <nil>}, got %+v", db)
+	}
+	got, err := Get(client, "db", database{})
+	if err != nil || got != db {
+		t.Errorf("Expected Get to decode the same way, got %+v (%v)", got, err)
+	}
+
+	// Defaults of another type are converted with the codec too.
+	var fallback database
+	err = client.GetConfig("missing", &fallback, map[string]interface{}{"max_conns": 5})
+	if err == nil || fallback.MaxConns != 5 {
+		t.Errorf("Expected the default to be converted with its json tags, got %+v (%v)", fallback, err)
+	}
+}
+
+func TestWithCodecPerKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "name: John\n" +
+		"rules: '{\"limit\": 10, \"paths\": [\"/api\", \"/admin\"]}'\n" +
+		"broken: '{\"limit\": '\n" +
+		"raw: '{\"limit\": 10}'\n"
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	codecs := map[string]source.Codec{"rules": source.JSONCodec, "broken": source.JSONCodec, "name": source.JSONCodec}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second, WithCodecPerKey(codecs))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// The JSON document held by rules is decoded like a YAML map.
+	type rules struct {
+		Limit int      `yaml:"limit"`
+		Paths []string `yaml:"paths"`
+	}
+	var value rules
+	err = client.GetConfig("rules", &value, nil)
+	if err != nil || value.Limit != 10 || !reflect.DeepEqual(value.Paths, []string{"/api", "/admin"}) {
+		t.Errorf("Expected the rules decoded from JSON, got %+v (%v)", value, err)
+	}
+	if limit, err := client.GetConfigInt("rules.limit", 0); err != nil || limit != 10 {
+		t.Errorf("Expected the nested limit, got %d (%v)", limit, err)
+	}
+
+	// Unlisted keys keep their string, and a listed string that is not JSON fails.
+	if raw, err := client.GetConfigString("raw", ""); err != nil || raw != `{"limit": 10}` {
+		t.Errorf("Expected the unlisted key to stay a string, got %s (%v)", raw, err)
+	}
+	if broken, err := client.GetConfigMap("broken", nil); !errors.Is(err, ErrTypeMismatch) || broken != nil {
+		t.Errorf("Expected a value that does not parse to fail, got %v (%v)", broken, err)
+	}
+	if name, err := client.GetConfigString("name", "default"); !errors.Is(err, ErrTypeMismatch) || name != "default" {
+		t.Errorf("Expected a plain string listed as JSON to fail, got %s (%v)", name, err)
+	}
+}
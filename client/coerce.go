@@ -0,0 +1,178 @@
+package client
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// unmarshalConfig decodes an encoded configuration value into data. With
+// scalar coercion, a value that fails to decode is decoded again after its
+// scalars are coerced to the types of data, and the original error is
+// returned if that fails too.
+func (c *Client) unmarshalConfig(marshal []byte, data interface{}) error {
+	err := c.unmarshal(marshal, data)
+	if err == nil || !c.coerceScalars {
+		return err
+	}
+	var config interface{}
+	if c.unmarshal(marshal, &config) != nil {
+		return err
+	}
+	coerced, coerceErr := c.marshal(coerceScalars(config, reflect.TypeOf(data)))
+	if coerceErr != nil || c.unmarshal(coerced, data) != nil {
+		return err
+	}
+	return nil
+}
+
+// Interfaces of the types that decode themselves, whose values are left as
+// they are.
+var (
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// coerceScalars returns config with the scalars converted to the kinds
+// target expects where the conversion is lossless: strings to numbers and
+// bools, numbers and bools to strings, and the numbers 0 and 1 to bools. It
+// follows target into pointers, slices, maps and struct fields, matched by
+// their yaml or json tag or, ignoring case, their name. Values that cannot be
+// converted are left for the codec to reject.
+func coerceScalars(config interface{}, target reflect.Type) interface{} {
+	for target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+	pointer := reflect.PtrTo(target)
+	if pointer.Implements(yamlUnmarshalerType) || pointer.Implements(jsonUnmarshalerType) || pointer.Implements(textUnmarshalerType) {
+		return config
+	}
+	switch target.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value, ok := config.(string); ok {
+			if number, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+				return number
+			}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value, ok := config.(string); ok {
+			if number, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64); err == nil {
+				return number
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		if value, ok := config.(string); ok {
+			if number, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				return number
+			}
+		}
+	case reflect.Bool:
+		switch value := config.(type) {
+		case string:
+			if flag, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil {
+				return flag
+			}
+		case int, int64, uint64, float64:
+			switch fmt.Sprint(value) {
+			case "0":
+				return false
+			case "1":
+				return true
+			}
+		}
+	case reflect.String:
+		switch value := config.(type) {
+		case int, int64, uint64, bool:
+			return fmt.Sprint(value)
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	case reflect.Slice, reflect.Array:
+		if values, ok := config.([]interface{}); ok {
+			coerced := make([]interface{}, len(values))
+			for i, value := range values {
+				coerced[i] = coerceScalars(value, target.Elem())
+			}
+			return coerced
+		}
+	case reflect.Map:
+		if values, ok := config.(map[string]interface{}); ok {
+			coerced := make(map[string]interface{}, len(values))
+			for key, value := range values {
+				coerced[key] = coerceScalars(value, target.Elem())
+			}
+			return coerced
+		}
+	case reflect.Struct:
+		if values, ok := config.(map[string]interface{}); ok {
+			coerced := make(map[string]interface{}, len(values))
+			for key, value := range values {
+				if field, ok := structField(target, key); ok {
+					value = coerceScalars(value, field.Type)
+				}
+				coerced[key] = value
+			}
+			return coerced
+		}
+	}
+	return config
+}
+
+// structField returns the field of the struct type target that the key of a
+// configuration decodes into, looking into embedded structs.
+func structField(target reflect.Type, key string) (reflect.StructField, bool) {
+	var byName *reflect.StructField
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		name, tagged := fieldTag(field)
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && !tagged {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if found, ok := structField(embedded, key); ok {
+					return found, true
+				}
+				continue
+			}
+		}
+		if tagged && name == key {
+			return field, true
+		}
+		if !tagged && byName == nil && strings.EqualFold(field.Name, key) {
+			byName = &field
+		}
+	}
+	if byName != nil {
+		return *byName, true
+	}
+	return reflect.StructField{}, false
+}
+
+// fieldTag returns the name the yaml or json tag of field gives it, and
+// whether a tag names it.
+func fieldTag(field reflect.StructField) (string, bool) {
+	for _, key := range []string{"yaml", "json"} {
+		tag, ok := field.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name != "" {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type coercedServer struct {
+	Port    int     `yaml:"port"`
+	Weight  float64 `yaml:"weight"`
+	Enabled bool    `yaml:"enabled"`
+	Debug   bool    `yaml:"debug"`
+	Name    string  `yaml:"name"`
+	Version string  `yaml:"version"`
+	Limits  []uint  `yaml:"limits"`
+	Labels  map[string]string
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func TestWithScalarCoercion(t *testing.T) {
+	data := map[string]interface{}{
+		"server": map[string]interface{}{
+			"port":    "8080",
+			"weight":  " 0.5 ",
+			"enabled": "true",
+			"debug":   1,
+			"name":    true,
+			"version": 1.5,
+			"limits":  []interface{}{"10", 20},
+			"labels":  map[string]interface{}{"tier": 2},
+			"timeout": "5s",
+		},
+		"port":  "8080",
+		"ports": []interface{}{"80", "443"},
+	}
+	repository := &mapRepository{data: data}
+	strict := newClient(repository, nil)
+	var server coercedServer
+	if err := strict.GetConfig("server", &server, nil); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected strict decoding to fail without coercion, got %v", err)
+	}
+
+	client := newClient(repository, []Option{WithScalarCoercion()})
+	server = coercedServer{}
+	err := client.GetConfig("server", &server, nil)
+	if err != nil {
+		t.Fatalf("Error getting server: %s", err.Error())
+	}
+	expected := coercedServer{
+		Port:    8080,
+		Weight:  0.5,
+		Enabled: true,
+		Debug:   true,
+		Name:    "true",
+		Version: "1.5",
+		Limits:  []uint{10, 20},
+		Labels:  map[string]string{"tier": "2"},
+		Timeout: 5 * time.Second,
+	}
+	if !reflect.DeepEqual(server, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, server)
+	}
+
+	port, err := Get(client, "port", 0)
+	if err != nil || port != 8080 {
+		t.Errorf("Expected 8080, got %d (%v)", port, err)
+	}
+	ports, err := Get[[]int](client, "ports", nil)
+	if err != nil || !reflect.DeepEqual(ports, []int{80, 443}) {
+		t.Errorf("Expected [80 443], got %v (%v)", ports, err)
+	}
+}
+
+func TestWithScalarCoercionFailures(t *testing.T) {
+	client := newClient(&mapRepository{data: map[string]interface{}{
+		"broken": map[string]interface{}{"port": "eighty"},
+		"flag":   2,
+		"float":  "1.5",
+	}}, []Option{WithScalarCoercion()})
+
+	server := coercedServer{Port: 1}
+	err := client.GetConfig("broken", &server, coercedServer{Port: 1})
+	if !errors.Is(err, ErrTypeMismatch) || server.Port != 1 {
+		t.Errorf("Expected a string that is not a number to fail, got %v (%d)", err, server.Port)
+	}
+	flag, err := Get(client, "flag", false)
+	if err == nil || flag {
+		t.Errorf("Expected a number other than 0 and 1 not to be a bool, got %t (%v)", flag, err)
+	}
+	number, err := Get(client, "float", 0)
+	if err == nil || number != 0 {
+		t.Errorf("Expected a fractional string not to be an int, got %d (%v)", number, err)
+	}
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// compactLog tracks the outcome of refreshes for WithCompactLogging.
+type compactLog struct {
+	sync.Mutex
+	summaryInterval time.Duration // interval between summaries, 0 for none
+	succeeded       bool          // whether the last refresh succeeded
+	started         bool          // whether any refresh has been logged
+	failuresInRow   int           // failures since the last success
+	failingSince    time.Time     // time of the first of those failures
+	successes       int           // successes since the last summary
+	failures        int           // failures since the last summary
+	since           time.Time     // start of the current summary period
+}
+
+// logRefresh logs the outcome of a refresh of the repository. By default every
+// failed refresh is logged; with WithCompactLogging only state transitions are,
+// along with periodic summaries.
+func (c *Client) logRefresh(err error) {
+	if c.compactLog == nil {
+		if err != nil {
+			c.log().Error("error refreshing repository", LogFields{"error": err})
+		}
+		return
+	}
+	c.compactLog.record(c.log(), err, c.now())
+}
+
+// record logs the refresh that ended with err at now to logger if it changed
+// the state, and the summary of the period if it is over, which is a reminder
+// of the outage while refreshes fail.
+func (l *compactLog) record(logger Logger, err error, now time.Time) {
+	l.Lock()
+	defer l.Unlock()
+	if !l.started {
+		l.since = now
+	}
+	switch {
+	case err != nil && (l.succeeded || !l.started):
+		logger.Error("error refreshing repository", LogFields{"error": err})
+	case err == nil && !l.started:
+		logger.Info("repository refreshed", nil)
+	case err == nil && !l.succeeded:
+		logger.Info("repository refreshed after failures", LogFields{
+			"failures":    l.failuresInRow,
+			"failing_for": now.Sub(l.failingSince).String(),
+		})
+	}
+	l.started = true
+	l.succeeded = err == nil
+	if err != nil {
+		if l.failuresInRow == 0 {
+			l.failingSince = now
+		}
+		l.failures++
+		l.failuresInRow++
+	} else {
+		l.successes++
+		l.failuresInRow = 0
+	}
+
+	if l.summaryInterval > 0 && now.Sub(l.since) >= l.summaryInterval {
+		if err != nil {
+			// During an outage the summary is a reminder that it goes on.
+			logger.Warn("repository refresh still failing", LogFields{
+				"error":       err,
+				"attempts":    l.failuresInRow,
+				"failing_for": now.Sub(l.failingSince).String(),
+			})
+			l.successes, l.failures = 0, 0
+			l.since = now
+			return
+		}
+		logger.Info("repository refresh summary", LogFields{
+			"successes": l.successes,
+			"failures":  l.failures,
+			"period":    now.Sub(l.since).String(),
+		})
+		l.successes, l.failures = 0, 0
+		l.since = now
+	}
+}
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyRepository is a mapRepository whose refreshes fail while failing is set.
+type flakyRepository struct {
+	mapRepository
+	failing atomic.Bool
+}
+
+func (f *flakyRepository) Refresh() error {
+	if f.failing.Load() {
+		return errors.New("backend unavailable")
+	}
+	return nil
+}
+
+func TestWithCompactLogging(t *testing.T) {
+	var logs bytes.Buffer
+	logrus.SetOutput(&logs)
+	defer logrus.SetOutput(os.Stderr)
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	repository := &flakyRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}}
+	client, err := NewClient(context.Background(), repository, time.Hour, WithCompactLogging(time.Minute), WithClock(clock))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	tick := func(times int) {
+		for i := 0; i < times; i++ {
+			clock.advance(time.Second)
+			client.logRefresh(client.refreshPeriodic())
+		}
+	}
+	lines := func() []string {
+		output := strings.TrimSpace(logs.String())
+		logs.Reset()
+		if output == "" {
+			return nil
+		}
+		return strings.Split(output, "\n")
+	}
+
+	// Only the first success is logged, not the steady state after it.
+	tick(10)
+	logged := lines()
+	if len(logged) != 1 || !strings.Contains(logged[0], "repository refreshed") {
+		t.Errorf("Expected only the first success to be logged, got %q", logged)
+	}
+
+	// Only the first of consecutive failures is logged.
+	repository.failing.Store(true)
+	tick(5)
+	logged = lines()
+	if len(logged) != 1 || !strings.Contains(logged[0], "error refreshing repository") {
+		t.Errorf("Expected only the first failure to be logged, got %q", logged)
+	}
+
+	// The recovery is logged with the number of failures.
+	repository.failing.Store(false)
+	tick(5)
+	logged = lines()
+	if len(logged) != 1 || !strings.Contains(logged[0], "refreshed after failures") || !strings.Contains(logged[0], "failures=5") {
+		t.Errorf("Expected the recovery to be logged, got %q", logged)
+	}
+
+	// A summary is logged once per interval.
+	tick(60)
+	logged = lines()
+	if len(logged) != 1 || !strings.Contains(logged[0], "refresh summary") {
+		t.Errorf("Expected a single summary, got %q", logged)
+	}
+}
+
+func TestRefreshLoggingDefault(t *testing.T) {
+	var logs bytes.Buffer
+	logrus.SetOutput(&logs)
+	defer logrus.SetOutput(os.Stderr)
+
+	repository := &flakyRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}}
+	client, err := NewClient(context.Background(), repository, time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// Without compact logging every failure is logged.
+	repository.failing.Store(true)
+	for i := 0; i < 3; i++ {
+		client.logRefresh(client.refreshPeriodic())
+	}
+	if count := strings.Count(logs.String(), "error refreshing repository"); count != 3 {
+		t.Errorf("Expected every failure to be logged, got %d lines", count)
+	}
+}
+
+func TestWithCompactLoggingOutage(t *testing.T) {
+	logger := &recordingLogger{}
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	repository := &flakyRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}}
+	client, err := NewClient(context.Background(), repository, time.Hour, WithCompactLogging(time.Minute), WithClock(clock), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	tick := func(times int) {
+		for i := 0; i < times; i++ {
+			clock.advance(time.Second)
+			client.logRefresh(client.refreshPeriodic())
+		}
+	}
+	entries := func() []string {
+		logger.Lock()
+		defer logger.Unlock()
+		entries := logger.entries
+		logger.entries = nil
+		return entries
+	}
+	entries()
+
+	// 130 failed refreshes log the first failure and a reminder per minute.
+	repository.failing.Store(true)
+	tick(130)
+	expected := []string{
+		"error error refreshing repository: backend unavailable",
+		"warn repository refresh still failing: backend unavailable",
+		"warn repository refresh still failing: backend unavailable",
+	}
+	if logged := entries(); !reflect.DeepEqual(logged, expected) {
+		t.Errorf("Expected %q, got %q", expected, logged)
+	}
+
+	// The recovery is logged once, then nothing until the next summary.
+	repository.failing.Store(false)
+	tick(5)
+	if logged := entries(); !reflect.DeepEqual(logged, []string{"info repository refreshed after failures"}) {
+		t.Errorf("Expected a single recovery entry, got %q", logged)
+	}
+}
+
+func TestCompactLogFields(t *testing.T) {
+	var fields []LogFields
+	logger := &fieldsLogger{fields: &fields}
+	log := &compactLog{summaryInterval: time.Minute}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	log.record(logger, nil, start)
+	for i := 1; i <= 42; i++ {
+		log.record(logger, errors.New("backend unavailable"), start.Add(time.Duration(i)*time.Second*2))
+	}
+	log.record(logger, nil, start.Add(5*time.Minute))
+
+	reminder := fields[2]
+	if reminder["attempts"] != 30 || reminder["failing_for"] != "58s" {
+		t.Errorf("Expected the attempts and duration of the outage, got %v", reminder)
+	}
+	// The recovery is followed by the summary of the period.
+	recovery := fields[3]
+	if recovery["failures"] != 42 || recovery["failing_for"] != "4m58s" {
+		t.Errorf("Expected the recovery to report the outage, got %v", recovery)
+	}
+}
+
+// fieldsLogger is a Logger recording the fields of its entries.
+type fieldsLogger struct {
+	fields *[]LogFields
+}
+
+func (f *fieldsLogger) Debug(message string, fields LogFields) { *f.fields = append(*f.fields, fields) }
+
+func (f *fieldsLogger) Info(message string, fields LogFields) { *f.fields = append(*f.fields, fields) }
+
+func (f *fieldsLogger) Warn(message string, fields LogFields) { *f.fields = append(*f.fields, fields) }
+
+func (f *fieldsLogger) Error(message string, fields LogFields) { *f.fields = append(*f.fields, fields) }
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultEncryptedPrefix marks the encrypted string values of the
+// configurations when WithDecryptor is given no prefix, as in
+// `password: enc:BASE64...`.
+const DefaultEncryptedPrefix = "enc:"
+
+// ErrDecrypt is returned by the getters of a configuration holding an
+// encrypted value that cannot be decrypted.
+var ErrDecrypt = errors.New("config value cannot be decrypted")
+
+// Decryptor decrypts the encrypted values of the configurations, set with
+// WithDecryptor.
+type Decryptor interface {
+	// Decrypt returns the plaintext of ciphertext, the part of an encrypted
+	// value after its prefix.
+	Decrypt(ciphertext string) (string, error)
+}
+
+// decryptTransformer returns a ValueTransformer that decrypts every string
+// starting with prefix in a configuration, including those nested in maps
+// and lists. When one of them cannot be decrypted, the whole configuration
+// is replaced by the error.
+func decryptTransformer(prefix string, decryptor Decryptor) ValueTransformer {
+	return func(key string, value interface{}) interface{} {
+		decrypted, err := decryptValue(prefix, decryptor, value)
+		if err != nil {
+			// The error of the decryptor is left out, as it may quote the ciphertext.
+			return &valueFailure{err: fmt.Errorf("%w: %s", ErrDecrypt, key)}
+		}
+		return decrypted
+	}
+}
+
+// decryptValue returns a copy of value with its encrypted strings decrypted.
+func decryptValue(prefix string, decryptor Decryptor, value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		if !strings.HasPrefix(value, prefix) {
+			return value, nil
+		}
+		return decryptor.Decrypt(strings.TrimPrefix(value, prefix))
+	case map[string]interface{}:
+		output := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			decrypted, err := decryptValue(prefix, decryptor, item)
+			if err != nil {
+				return nil, err
+			}
+			output[key] = decrypted
+		}
+		return output, nil
+	case []interface{}:
+		output := make([]interface{}, len(value))
+		for i, item := range value {
+			decrypted, err := decryptValue(prefix, decryptor, item)
+			if err != nil {
+				return nil, err
+			}
+			output[i] = decrypted
+		}
+		return output, nil
+	}
+	return value, nil
+}
+
+// AESGCMDecryptor is a Decryptor of values encrypted with AES-GCM, encoded
+// as the standard base64 of the nonce followed by the sealed plaintext.
+type AESGCMDecryptor struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCMDecryptor creates an AESGCMDecryptor with key, which must be 16,
+// 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+func NewAESGCMDecryptor(key []byte) (*AESGCMDecryptor, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCMDecryptor{aead: aead}, nil
+}
+
+// Decrypt returns the plaintext of ciphertext.
+func (a *AESGCMDecryptor) Decrypt(ciphertext string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < a.aead.NonceSize() {
+		return "", errors.New("ciphertext is too short")
+	}
+	nonce, sealed := sealed[:a.aead.NonceSize()], sealed[a.aead.NonceSize():]
+	plaintext, err := a.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Encrypt returns the ciphertext of plaintext with a random nonce, for
+// storing it in a configuration after the prefix.
+func (a *AESGCMDecryptor) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, a.aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	sealed := a.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithDecryptor(t *testing.T) {
+	decryptor, err := NewAESGCMDecryptor([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("Error creating decryptor: %s", err.Error())
+	}
+	encrypt := func(plaintext string) string {
+		ciphertext, err := decryptor.Encrypt(plaintext)
+		if err != nil {
+			t.Fatalf("Error encrypting: %s", err.Error())
+		}
+		return DefaultEncryptedPrefix + ciphertext
+	}
+	other, _ := NewAESGCMDecryptor([]byte("fedcba9876543210"))
+	foreign, _ := other.Encrypt("hunter2")
+
+	repository := &mapRepository{data: map[string]interface{}{
+		"password": encrypt("hunter2"),
+		"name":     "John",
+		"database": map[string]interface{}{
+			"host":     "db1",
+			"password": encrypt("correct-horse"),
+			"tokens":   []interface{}{encrypt("a"), "plain"},
+		},
+		"broken":  DefaultEncryptedPrefix + "not base64!",
+		"foreign": DefaultEncryptedPrefix + foreign,
+	}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithDecryptor("", decryptor))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	if password, err := client.GetConfigString("password", ""); err != nil || password != "hunter2" {
+		t.Errorf("Expected password to be decrypted, got %s (%v)", password, err)
+	}
+	if name, err := client.GetConfigString("name", ""); err != nil || name != "John" {
+		t.Errorf("Expected plaintext name to be kept, got %s (%v)", name, err)
+	}
+	var database map[string]interface{}
+	err = client.GetConfig("database", &database, nil)
+	expected := map[string]interface{}{"host": "db1", "password": "correct-horse", "tokens": []interface{}{"a", "plain"}}
+	if err != nil || !reflect.DeepEqual(database, expected) {
+		t.Errorf("Expected database to be %v, got %v (%v)", expected, database, err)
+	}
+
+	for _, name := range []string{"broken", "foreign"} {
+		value, err := client.GetConfigString(name, "default")
+		if !errors.Is(err, ErrDecrypt) || value != "default" {
+			t.Errorf("Expected ErrDecrypt and the default for %s, got %s (%v)", name, value, err)
+		}
+		if err != nil && strings.Contains(err.Error(), foreign) {
+			t.Errorf("Expected the error not to quote the ciphertext, got %s", err.Error())
+		}
+		var data string
+		if err := client.GetConfig(name, &data, "default"); !errors.Is(err, ErrDecrypt) || data != "default" {
+			t.Errorf("Expected GetConfig to report ErrDecrypt for %s, got %s (%v)", name, data, err)
+		}
+	}
+}
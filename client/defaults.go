@@ -0,0 +1,113 @@
+package client
+
+import (
+	"reflect"
+)
+
+// defaultFor returns defaultValue, or the value it points to, if it can be
+// stored in the value target points to.
+func defaultFor(target reflect.Value, defaultValue interface{}) (reflect.Value, bool) {
+	defaults := reflect.ValueOf(defaultValue)
+	if defaults.Kind() == reflect.Ptr && !defaults.Type().AssignableTo(target.Elem().Type()) {
+		if defaults.IsNil() {
+			return reflect.Value{}, false
+		}
+		defaults = defaults.Elem()
+	}
+	if !defaults.IsValid() || !defaults.Type().AssignableTo(target.Elem().Type()) {
+		return reflect.Value{}, false
+	}
+	return defaults, true
+}
+
+// mergeDefaults copies defaultValue into the struct data points to, so that
+// the fields the configuration does not set keep their default values when the
+// configuration is decoded over it. defaultValue may be the struct or a
+// pointer to it; anything else leaves data untouched. Maps, slices and
+// pointers are copied too, so decoding never modifies defaultValue.
+func mergeDefaults(data interface{}, defaultValue interface{}) {
+	target := reflect.ValueOf(data)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return
+	}
+	defaults, ok := defaultFor(target, defaultValue)
+	if !ok {
+		return
+	}
+	target.Elem().Set(deepCopy(defaults))
+}
+
+// assignDefault stores a copy of defaultValue in the value data points to,
+// replacing whatever a failed decoding left there. A defaultValue of another
+// type, such as a map for a struct, is converted through the codec of the
+// Client. A nil defaultValue leaves data untouched.
+func (c *Client) assignDefault(data interface{}, defaultValue interface{}) {
+	target := reflect.ValueOf(data)
+	if target.Kind() != reflect.Ptr || target.IsNil() || defaultValue == nil {
+		return
+	}
+	if defaults, ok := defaultFor(target, defaultValue); ok {
+		target.Elem().Set(deepCopy(defaults))
+		return
+	}
+	marshal, err := c.marshal(defaultValue)
+	if err != nil {
+		c.log().Debug("error marshalling default value", LogFields{"error": err})
+		return
+	}
+	target.Elem().Set(reflect.Zero(target.Elem().Type()))
+	err = c.unmarshal(marshal, data)
+	if err != nil {
+		c.log().Debug("error unmarshalling default value", LogFields{"error": err})
+	}
+}
+
+// deepCopy returns a copy of value that shares no map, slice or pointer with it.
+// Unexported fields of structs are copied shallowly.
+func deepCopy(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.New(value.Type().Elem())
+		copied.Elem().Set(deepCopy(value.Elem()))
+		return copied
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return copied
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			copied.Index(i).Set(deepCopy(value.Index(i)))
+		}
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(value)
+		for i := 0; i < value.NumField(); i++ {
+			if copied.Field(i).CanSet() {
+				copied.Field(i).Set(deepCopy(value.Field(i)))
+			}
+		}
+		return copied
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(deepCopy(value.Elem()))
+		return copied
+	}
+	return value
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type serverConfig struct {
+	Host    string            `yaml:"host"`
+	Port    int               `yaml:"port"`
+	Tags    []string          `yaml:"tags"`
+	Labels  map[string]string `yaml:"labels"`
+	Timeout *int              `yaml:"timeout"`
+}
+
+func TestGetConfigMergesDefaults(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"server": map[string]interface{}{"host": "db.internal", "labels": map[string]interface{}{"env": "prod"}},
+	})
+	timeout := 30
+	defaults := serverConfig{Host: "localhost", Port: 5432, Tags: []string{"a"}, Labels: map[string]string{"team": "core"}, Timeout: &timeout}
+
+	var server serverConfig
+	err := client.GetConfig("server", &server, defaults)
+	if err != nil {
+		t.Errorf("Error getting server: %s", err.Error())
+	}
+	expected := serverConfig{
+		Host:    "db.internal",
+		Port:    5432,
+		Tags:    []string{"a"},
+		Labels:  map[string]string{"team": "core", "env": "prod"},
+		Timeout: &timeout,
+	}
+	if !reflect.DeepEqual(server, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, server)
+	}
+	if len(defaults.Labels) != 1 || server.Timeout == defaults.Timeout {
+		t.Errorf("Expected the defaults not to be shared or modified, got %+v", defaults)
+	}
+
+	// A pointer to the defaults works the same way.
+	var fromPointer serverConfig
+	err = client.GetConfig("server", &fromPointer, &defaults)
+	if err != nil || fromPointer.Port != 5432 || fromPointer.Host != "db.internal" {
+		t.Errorf("Expected defaults from a pointer to be merged, got %+v (%v)", fromPointer, err)
+	}
+
+	// Defaults of another type are ignored.
+	var other serverConfig
+	err = client.GetConfig("server", &other, 1)
+	if err != nil || other.Port != 0 {
+		t.Errorf("Expected defaults of another type to be ignored, got %+v (%v)", other, err)
+	}
+}
+
+func TestGetConfigAssignsDefaultOnError(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"server": map[string]interface{}{"host": "db.internal", "port": "not a port"},
+	})
+	defaults := serverConfig{Host: "localhost", Port: 5432, Labels: map[string]string{"team": "core"}}
+
+	var missing serverConfig
+	err := client.GetConfig("missing", &missing, defaults)
+	if err == nil || !reflect.DeepEqual(missing, defaults) {
+		t.Errorf("Expected an error and the default for a missing config, got %+v (%v)", missing, err)
+	}
+	missing.Labels["team"] = "changed"
+	if defaults.Labels["team"] != "core" {
+		t.Errorf("Expected the default to be copied, got %v", defaults.Labels)
+	}
+
+	// A failed decoding does not leave a partially decoded value behind.
+	var invalid serverConfig
+	err = client.GetConfig("server", &invalid, &defaults)
+	if err == nil || !reflect.DeepEqual(invalid, defaults) {
+		t.Errorf("Expected an error and the default for an invalid config, got %+v (%v)", invalid, err)
+	}
+
+	// A default of another type is converted.
+	var converted serverConfig
+	err = client.GetConfig("missing", &converted, map[string]interface{}{"host": "fallback"})
+	if err == nil || converted.Host != "fallback" {
+		t.Errorf("Expected the default map to be converted, got %+v (%v)", converted, err)
+	}
+	count := 7
+	err = client.GetConfig("missing", &count, 3)
+	if err == nil || count != 3 {
+		t.Errorf("Expected count to be the default 3, got %d (%v)", count, err)
+	}
+
+	client.Close()
+	var closed serverConfig
+	err = client.GetConfig("server", &closed, defaults)
+	if !errors.Is(err, ErrClientClosed) || !reflect.DeepEqual(closed, defaults) {
+		t.Errorf("Expected ErrClientClosed and the default, got %+v (%v)", closed, err)
+	}
+}
+
+func TestEmptyValuesUseDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("empty:\ntilde: ~\nexplicit: null\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing config: %s", err.Error())
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	for _, name := range []string{"empty", "tilde", "explicit"} {
+		if value, err := client.GetConfigString(name, "fallback"); err != nil || value != "fallback" {
+			t.Errorf("Expected the default string for %s, got %q (%v)", name, value, err)
+		}
+		if value, err := client.GetConfigInt(name, 7); err != nil || value != 7 {
+			t.Errorf("Expected the default int for %s, got %d (%v)", name, value, err)
+		}
+		if value, err := client.GetConfigFloat(name, 0.5); err != nil || value != 0.5 {
+			t.Errorf("Expected the default float for %s, got %v (%v)", name, value, err)
+		}
+		if value, err := client.GetConfigBool(name, true); err != nil || !value {
+			t.Errorf("Expected the default bool for %s, got %v (%v)", name, value, err)
+		}
+		defaults := serverConfig{Host: "localhost", Port: 5432}
+		var server serverConfig
+		if err := client.GetConfig(name, &server, defaults); err != nil || !reflect.DeepEqual(server, defaults) {
+			t.Errorf("Expected the default struct for %s, got %+v (%v)", name, server, err)
+		}
+		var port int
+		if err := client.GetConfig(name, &port, 5432); err != nil || port != 5432 {
+			t.Errorf("Expected the default port for %s, got %d (%v)", name, port, err)
+		}
+	}
+}
+
+func TestDeclaredDefaultsPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("_defaults:\n  timeout: 30\n  retries: 3\ntimeout: 10\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing config: %s", err.Error())
+	}
+	repository := source.NewDefaultsRepository(&source.FileRepository{Name: "file", Path: path})
+	client, err := NewClient(context.Background(), repository, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// Live value, then the default declared in the source, then the caller's.
+	if value, err := client.GetConfigInt("timeout", 60); err != nil || value != 10 {
+		t.Errorf("Expected the live timeout, got %d (%v)", value, err)
+	}
+	if value, err := client.GetConfigInt("retries", 1); err != nil || value != 3 {
+		t.Errorf("Expected the declared retries, got %d (%v)", value, err)
+	}
+	if value, err := client.GetConfigInt("workers", 4); !errors.Is(err, ErrConfigNotFound) || value != 4 {
+		t.Errorf("Expected the caller's workers and ErrConfigNotFound, got %d (%v)", value, err)
+	}
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"time"
+)
+
+// ErrConfigNotFound is returned, wrapped with the name of the configuration,
+// by getters when the configuration is missing, unless it was declared
+// optional with WithOptionalKeys.
+var ErrConfigNotFound = errors.New("config not found")
+
+// ErrTypeMismatch is returned, wrapped with the name of the configuration, by
+// getters when the configuration cannot be converted to the type they
+// return, and by the refresh of a Client with WithEagerTypeCheck when a
+// configuration does not have its registered type.
+var ErrTypeMismatch = errors.New("config has an unexpected type")
+
+// ErrClientClosed is returned when a Client is used after Close was called.
+var ErrClientClosed = errors.New("client is closed")
+
+// ErrNotReady is returned by getters of a Client with a readiness gate when
+// the first refresh has not succeeded within the readiness timeout.
+var ErrNotReady = errors.New("config is not ready")
+
+// ErrNoDefaultClient is returned by the package-level functions while there
+// is no default Client, as neither NewClient nor SetDefaultClient was called.
+var ErrNoDefaultClient = errors.New("no default client configured")
+
+// ErrInvalidRefreshInterval is returned by NewClient when the refresh
+// interval is not positive.
+var ErrInvalidRefreshInterval = errors.New("refresh interval must be positive")
+
+// ErrInvalidData is returned, wrapped with the name of the configuration, by
+// GetConfig when its data argument is not a non-nil pointer.
+var ErrInvalidData = errors.New("data must be a non-nil pointer")
+
+// ErrUnhealthy is returned, wrapped with the reason, by Healthy when the
+// configuration of the Client is not usable.
+var ErrUnhealthy = errors.New("config is unhealthy")
+
+// ErrStaleConfig is returned, wrapped with the name of the configuration, by
+// getters of a Client with WithMaxStaleAge while refreshes fail and the last
+// successful one is older than the maximum stale age.
+var ErrStaleConfig = errors.New("config is stale")
+
+// ErrRefreshTimeout is returned, wrapped, by a refresh of a Client with
+// WithRefreshTimeout when the repository does not refresh in time.
+var ErrRefreshTimeout = errors.New("refresh timed out")
+
+// ConfigError is the error getters return when they cannot return a
+// configuration, identifying the configuration and the getter that failed:
+//
+//	GetConfigInt max_conns: config has an unexpected type: not an int
+//
+// It unwraps to the cause, such as ErrConfigNotFound, ErrTypeMismatch,
+// ErrStaleConfig, ErrClientClosed or the error of the context, so that
+// errors.Is and errors.As see through it.
+type ConfigError struct {
+	Key string // Name of the configuration
+	Op  string // Getter that failed, such as GetConfigInt, empty outside getters
+	Err error  // Cause of the failure
+}
+
+func (e *ConfigError) Error() string {
+	if e.Op == "" {
+		return e.Key + ": " + e.Err.Error()
+	}
+	return e.Op + " " + e.Key + ": " + e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// keyError returns err as the ConfigError of the configuration with the
+// given name, leaving the getter to be set by annotate.
+func keyError(name string, err error) error {
+	return &ConfigError{Key: name, Err: err}
+}
+
+// annotate sets the getter op on the error *err of the configuration with the
+// given name, wrapping it in a ConfigError unless it already is the
+// ConfigError of that configuration. Getters defer it, through observe, with
+// their named error, so that a getter built on another one reports itself.
+func annotate(op string, name string, err *error) {
+	if *err == nil {
+		return
+	}
+	if configErr, ok := (*err).(*ConfigError); ok && configErr.Key == name {
+		*err = &ConfigError{Key: name, Op: op, Err: configErr.Err}
+		return
+	}
+	*err = &ConfigError{Key: name, Op: op, Err: *err}
+}
+
+// typeMismatch returns the error getters report when the configuration with
+// the given name is not of the given kind.
+func typeMismatch(name string, kind string) error {
+	return keyError(name, fmt.Errorf("%w: not %s", ErrTypeMismatch, kind))
+}
+
+// decodeError is an error of GetConfig decoding a configuration into the
+// caller's type. It unwraps to the error of the codec, and also matches
+// ErrTypeMismatch.
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string {
+	return ErrTypeMismatch.Error() + ": " + e.err.Error()
+}
+
+func (e *decodeError) Is(target error) bool {
+	return target == ErrTypeMismatch
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.err
+}
+
+// obfuscatedError is an error whose message has been sanitized. It does not
+// unwrap to the original error, whose fields may hold the credentials, so
+// errors.As cannot reach it, but errors.Is still matches the errors it wraps.
+type obfuscatedError struct {
+	message string
+	err     error
+}
+
+func (e *obfuscatedError) Error() string {
+	return e.message
+}
+
+func (e *obfuscatedError) Is(target error) bool {
+	return errors.Is(e.err, target)
+}
+
+// RetryAfter returns the delay the source asked to wait before retrying, which
+// carries no credentials, so that the refresh loop still honors it.
+func (e *obfuscatedError) RetryAfter() time.Duration {
+	return source.RetryAfter(e.err)
+}
+
+// obfuscateError returns err with the credentials, query parameters and
+// optionally the host of every URL in its message redacted.
+func obfuscateError(err error, hideHosts bool) error {
+	return &obfuscatedError{message: source.RedactURLs(err.Error(), hideHosts), err: err}
+}
+
+// partialError is the error of a refresh that applied the keys of the
+// repository that parsed, reported by the repository in a source.KeyErrors,
+// and failed for the others.
+type partialError struct {
+	err error
+}
+
+func (e *partialError) Error() string {
+	return e.err.Error()
+}
+
+func (e *partialError) Unwrap() error {
+	return e.err
+}
+
+// isPartial reports whether err is the error of a refresh that applied the
+// keys that parsed.
+func isPartial(err error) bool {
+	var partialErr *partialError
+	var keyErrors *source.KeyErrors
+	return errors.As(err, &partialErr) || errors.As(err, &keyErrors)
+}
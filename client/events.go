@@ -0,0 +1,59 @@
+package client
+
+import (
+	"sync"
+)
+
+// eventBus fans the result of every refresh out to its subscribers. Every
+// producer of changes, the refresh loop, ForceRefresh and RefreshHandler,
+// publishes through doRefresh, so subscribers are notified the same way
+// whatever triggered the refresh. The zero value is ready to use.
+type eventBus struct {
+	mu          sync.RWMutex
+	subscribers map[uint64]RefreshListener
+	order       []uint64 // subscriber ids in subscription order
+	next        uint64
+}
+
+// subscribe adds a subscriber called with every published result, and returns
+// a function that removes it again.
+func (b *eventBus) subscribe(subscriber RefreshListener) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers == nil {
+		b.subscribers = map[uint64]RefreshListener{}
+	}
+	id := b.next
+	b.next++
+	b.subscribers[id] = subscriber
+	b.order = append(b.order, id)
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[id]; !ok {
+			return
+		}
+		delete(b.subscribers, id)
+		for i, subscribed := range b.order {
+			if subscribed == id {
+				b.order = append(b.order[:i:i], b.order[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// publish calls every subscriber with result, in subscription order. The
+// subscribers are called without holding the lock, so they may subscribe or
+// unsubscribe.
+func (b *eventBus) publish(result RefreshResult) {
+	b.mu.RLock()
+	subscribers := make([]RefreshListener, 0, len(b.order))
+	for _, id := range b.order {
+		subscribers = append(subscribers, b.subscribers[id])
+	}
+	b.mu.RUnlock()
+	for _, subscriber := range subscribers {
+		subscriber(result)
+	}
+}
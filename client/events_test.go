@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEventBusChangeSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("name: John\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	var mu sync.Mutex
+	var results []RefreshResult
+	listener := func(result RefreshResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, result)
+	}
+	// lastChanged waits for a result changing the given key and returns its changed keys.
+	lastChanged := func(key string) []string {
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			mu.Lock()
+			for _, result := range results {
+				for _, changed := range result.ChangedKeys {
+					if changed == key {
+						mu.Unlock()
+						return result.ChangedKeys
+					}
+				}
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+		}
+		return nil
+	}
+	reset := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		results = nil
+	}
+
+	repository := &source.FileRepository{Name: "file", Path: path}
+	client, err := NewClient(context.Background(), repository, 50*time.Millisecond, WithRefreshListener(listener))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	if changed := lastChanged("name"); !reflect.DeepEqual(changed, []string{"name"}) {
+		t.Errorf("Expected the initial refresh to report name, got %v", changed)
+	}
+
+	producers := []struct {
+		name    string
+		key     string
+		trigger func()
+	}{
+		{name: "refresh loop", key: "loop", trigger: func() {}},
+		{name: "ForceRefresh", key: "forced", trigger: func() {
+			if err := client.ForceRefresh(context.Background()); err != nil {
+				t.Errorf("Error refreshing client: %s", err.Error())
+			}
+		}},
+		{name: "RefreshHandler", key: "handler", trigger: func() {
+			request := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+			request.Header.Set("X-API-KEY", "key")
+			recorder := httptest.NewRecorder()
+			client.RefreshHandler("key").ServeHTTP(recorder, request)
+			if recorder.Code != http.StatusNoContent {
+				t.Errorf("Expected status 204, got %d", recorder.Code)
+			}
+		}},
+	}
+	for _, producer := range producers {
+		reset()
+		err = os.WriteFile(path, []byte("name: John\n"+producer.key+": true\n"), 0o600)
+		if err != nil {
+			t.Fatalf("Error writing file: %s", err.Error())
+		}
+		producer.trigger()
+		if changed := lastChanged(producer.key); changed == nil {
+			t.Errorf("Expected the %s to publish %s as changed", producer.name, producer.key)
+		}
+		// Reset the file so that the next source changes a single key.
+		err = os.WriteFile(path, []byte("name: John\n"), 0o600)
+		if err != nil {
+			t.Fatalf("Error writing file: %s", err.Error())
+		}
+		_ = client.ForceRefresh(context.Background())
+	}
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	var bus eventBus
+	var first, second int
+	unsubscribe := bus.subscribe(func(RefreshResult) { first++ })
+	bus.subscribe(func(RefreshResult) {
+		second++
+		// Subscribers may unsubscribe while being notified.
+		unsubscribe()
+	})
+	bus.publish(RefreshResult{})
+	bus.publish(RefreshResult{})
+	if first != 1 {
+		t.Errorf("Expected the unsubscribed listener to be called once, got %d", first)
+	}
+	if second != 2 {
+		t.Errorf("Expected the remaining listener to be called twice, got %d", second)
+	}
+}
@@ -0,0 +1,393 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+func EvaluateBoolExpr(name string, attrs map[string]interface{}, defaultValue bool) (bool, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.EvaluateBoolExpr(name, attrs, defaultValue)
+}
+
+// EvaluateBoolExpr retrieves the expression string stored under the given
+// name and evaluates it against attrs. Expressions support number, string and
+// boolean literals, attribute names, the comparison operators ==, !=, <, <=,
+// >, >=, the boolean operators &&, || and !, unary minus and parentheses, for
+// example "version >= 2 && region == 'us'". && and || short-circuit, so the
+// right operand is not evaluated when the left one decides the result. It
+// returns the default value and an error if the expression is missing,
+// malformed, refers to an undefined attribute or does not evaluate to a boolean.
+func (c *Client) EvaluateBoolExpr(name string, attrs map[string]interface{}, defaultValue bool) (bool, error) {
+	expression, err := c.GetConfigString(name, "")
+	if err != nil {
+		return defaultValue, err
+	}
+	result, err := evaluateExpr(expression, attrs)
+	if err != nil {
+		return defaultValue, err
+	}
+	return result, nil
+}
+
+// evaluateExpr parses and evaluates a boolean expression against attrs.
+func evaluateExpr(expression string, attrs map[string]interface{}) (bool, error) {
+	tokens, err := tokenizeExpr(expression)
+	if err != nil {
+		return false, err
+	}
+	p := &exprParser{tokens: tokens, attrs: attrs}
+	value, err := p.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if p.pos != len(p.tokens) {
+		return false, fmt.Errorf("unexpected token %q in expression", p.tokens[p.pos].text)
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression does not evaluate to a boolean")
+	}
+	return result, nil
+}
+
+type exprTokenKind int
+
+const (
+	tokenIdent exprTokenKind = iota
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+}
+
+// tokenizeExpr splits an expression into identifiers, literals and operators.
+func tokenizeExpr(expression string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'' || r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated string in expression")
+			}
+			tokens = append(tokens, exprToken{kind: tokenString, text: string(runes[i+1 : end])})
+			i = end + 1
+		case unicode.IsDigit(r):
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: string(runes[i:end])})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: string(runes[i:end])})
+			i = end
+		default:
+			operator := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", ">=", "<=", ">", "<", "!", "-", "(", ")"} {
+				if strings.HasPrefix(string(runes[i:]), candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected character %q in expression", r)
+			}
+			tokens = append(tokens, exprToken{kind: tokenOperator, text: operator})
+			i += len(operator)
+		}
+	}
+	return tokens, nil
+}
+
+// exprParser is a recursive descent parser that evaluates while parsing.
+// While skipping, it only parses the operand of a short-circuited operator:
+// attributes are not looked up and operators are not type checked.
+type exprParser struct {
+	tokens   []exprToken
+	pos      int
+	attrs    map[string]interface{}
+	skipping bool
+}
+
+// parseSkipped parses an operand with parse without evaluating it when skip is true.
+func (p *exprParser) parseSkipped(skip bool, parse func() (interface{}, error)) (interface{}, error) {
+	if !skip || p.skipping {
+		return parse()
+	}
+	p.skipping = true
+	defer func() { p.skipping = false }()
+	return parse()
+}
+
+func (p *exprParser) peekOperator(operators ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOperator {
+		return "", false
+	}
+	for _, operator := range operators {
+		if p.tokens[p.pos].text == operator {
+			return operator, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseOr() (interface{}, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peekOperator("||"); !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseSkipped(left == true, p.parseAnd)
+		if err != nil {
+			return nil, err
+		}
+		if p.skipping || left == true {
+			left = true
+			continue
+		}
+		l, r, err := boolOperands(left, right, "||")
+		if err != nil {
+			return nil, err
+		}
+		left = l || r
+	}
+}
+
+func (p *exprParser) parseAnd() (interface{}, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.peekOperator("&&"); !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseSkipped(left == false, p.parseUnary)
+		if err != nil {
+			return nil, err
+		}
+		if p.skipping || left == false {
+			left = false
+			continue
+		}
+		l, r, err := boolOperands(left, right, "&&")
+		if err != nil {
+			return nil, err
+		}
+		left = l && r
+	}
+}
+
+func (p *exprParser) parseUnary() (interface{}, error) {
+	if _, ok := p.peekOperator("!"); ok {
+		p.pos++
+		value, err := p.parseUnary()
+		if err != nil || p.skipping {
+			return value, err
+		}
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator ! requires a boolean")
+		}
+		return !b, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (interface{}, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	operator, ok := p.peekOperator("==", "!=", ">=", "<=", ">", "<")
+	if !ok {
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parseOperand()
+	if err != nil || p.skipping {
+		return nil, err
+	}
+	return compareValues(left, right, operator)
+}
+
+func (p *exprParser) parseOperand() (interface{}, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	switch token.kind {
+	case tokenNumber:
+		number, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q in expression", token.text)
+		}
+		return number, nil
+	case tokenString:
+		return token.text, nil
+	case tokenIdent:
+		switch token.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		if p.skipping {
+			return nil, nil
+		}
+		value, ok := p.attrs[token.text]
+		if !ok {
+			return nil, fmt.Errorf("undefined variable %s in expression", token.text)
+		}
+		return normalizeExprValue(value)
+	}
+	if token.text == "-" {
+		value, err := p.parseOperand()
+		if err != nil || p.skipping {
+			return nil, err
+		}
+		number, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("operator - requires a number")
+		}
+		return -number, nil
+	}
+	if token.text == "(" {
+		value, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.peekOperator(")"); !ok {
+			return nil, fmt.Errorf("missing closing parenthesis in expression")
+		}
+		p.pos++
+		return value, nil
+	}
+	return nil, fmt.Errorf("unexpected token %q in expression", token.text)
+}
+
+// normalizeExprValue converts attribute values to the float64, string and
+// bool types the evaluator works with.
+func normalizeExprValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool, string, float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int8:
+		return float64(v), nil
+	case int16:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint8:
+		return float64(v), nil
+	case uint16:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	}
+	return nil, fmt.Errorf("unsupported attribute type %T in expression", value)
+}
+
+func boolOperands(left, right interface{}, operator string) (bool, bool, error) {
+	l, lok := left.(bool)
+	r, rok := right.(bool)
+	if !lok || !rok {
+		return false, false, fmt.Errorf("operator %s requires booleans", operator)
+	}
+	return l, r, nil
+}
+
+// compareValues applies a comparison operator to two values of the same type.
+func compareValues(left, right interface{}, operator string) (bool, error) {
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false, fmt.Errorf("cannot compare number with %T", right)
+		}
+		switch operator {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot compare string with %T", right)
+		}
+		switch operator {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		}
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return false, fmt.Errorf("cannot compare boolean with %T", right)
+		}
+		switch operator {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		}
+		return false, fmt.Errorf("operator %s is not supported for booleans", operator)
+	}
+	return false, fmt.Errorf("unsupported operator %s", operator)
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestEvaluateBoolExpr(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"comparison": "version >= 2",
+		"logic":      "version >= 2 && region == 'us' || beta",
+		"negation":   "!(region != \"us\")",
+		"undefined":  "tier == 'gold'",
+		"malformed":  "version >=",
+		"number":     "version",
+		"mismatch":   "region > 2",
+		"negative":   "offset > -5 && -offset == 3",
+		"orShort":    "region == 'us' || tier == 'gold'",
+		"andShort":   "beta && tier == 'gold'",
+		"strictOr":   "region == 'eu' || tier == 'gold'",
+	})
+	attrs := map[string]interface{}{"version": 3, "region": "us", "beta": false}
+
+	testCases := []struct {
+		name    string
+		attrs   map[string]interface{}
+		want    bool
+		wantErr bool
+	}{
+		{name: "comparison", attrs: attrs, want: true},
+		{name: "comparison", attrs: map[string]interface{}{"version": 1.5}, want: false},
+		{name: "logic", attrs: attrs, want: true},
+		{name: "logic", attrs: map[string]interface{}{"version": 1, "region": "us", "beta": true}, want: true},
+		{name: "logic", attrs: map[string]interface{}{"version": 3, "region": "eu", "beta": false}, want: false},
+		{name: "negation", attrs: attrs, want: true},
+		{name: "undefined", attrs: attrs, wantErr: true},
+		{name: "malformed", attrs: attrs, wantErr: true},
+		{name: "number", attrs: attrs, wantErr: true},
+		{name: "mismatch", attrs: attrs, wantErr: true},
+		{name: "missing", attrs: attrs, wantErr: true},
+		{name: "negative", attrs: map[string]interface{}{"offset": -3}, want: true},
+		{name: "orShort", attrs: attrs, want: true},
+		{name: "andShort", attrs: attrs, want: false},
+		{name: "strictOr", attrs: attrs, wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := client.EvaluateBoolExpr(tc.name, tc.attrs, true)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("Expected error for %s, got nil", tc.name)
+			}
+			if !got {
+				t.Errorf("Expected default value for %s on error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Error evaluating %s: %s", tc.name, err.Error())
+		}
+		if got != tc.want {
+			t.Errorf("Expected %s with %v to be %t, got %t", tc.name, tc.attrs, tc.want, got)
+		}
+	}
+}
+
+func TestEvaluateBoolExprDefaultClient(t *testing.T) {
+	newMapClient(t, map[string]interface{}{"comparison": "version >= 2"})
+	got, err := EvaluateBoolExpr("comparison", map[string]interface{}{"version": 3}, false)
+	if err != nil {
+		t.Errorf("Error evaluating comparison: %s", err.Error())
+	}
+	if !got {
+		t.Errorf("Expected comparison to be true, got false")
+	}
+}
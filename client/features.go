@@ -0,0 +1,46 @@
+package client
+
+// GetConfigEnabledSet retrieves the string array configuration with the given name from the repository as a set
+func GetConfigEnabledSet(name string, defaultValue map[string]struct{}) (map[string]struct{}, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigEnabledSet(name, defaultValue)
+}
+
+// IsFeatureEnabled reports whether feature is in the set configuration with the given name
+func IsFeatureEnabled(setName string, feature string) bool {
+	client := DefaultClient()
+	if client == nil {
+		return false
+	}
+	return client.IsFeatureEnabled(setName, feature)
+}
+
+// GetConfigEnabledSet retrieves the string array configuration with the given
+// name from the repository as a set, such as a list of enabled features, so
+// that callers holding it check membership without scanning the array.
+func (c *Client) GetConfigEnabledSet(name string, defaultValue map[string]struct{}) (map[string]struct{}, error) {
+	names, err := c.GetConfigArrayOfStrings(name, nil)
+	if err != nil {
+		return defaultValue, err
+	}
+	set := make(map[string]struct{}, len(names))
+	for _, feature := range names {
+		set[feature] = struct{}{}
+	}
+	return set, nil
+}
+
+// IsFeatureEnabled reports whether feature is in the string array
+// configuration named setName. It returns false when the configuration is
+// missing or is not an array of strings.
+func (c *Client) IsFeatureEnabled(setName string, feature string) bool {
+	set, err := c.GetConfigEnabledSet(setName, nil)
+	if err != nil {
+		return false
+	}
+	_, enabled := set[feature]
+	return enabled
+}
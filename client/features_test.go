@@ -0,0 +1,60 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestGetConfigEnabledSet(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"features":  []interface{}{"search", "export", "search"},
+		"empty":     []interface{}{},
+		"not_list":  "search",
+		"not_names": []interface{}{"search", 42},
+	})
+	defaults := map[string]struct{}{"default": {}}
+
+	set, err := client.GetConfigEnabledSet("features", defaults)
+	if err != nil {
+		t.Fatalf("Error getting set: %s", err.Error())
+	}
+	if len(set) != 2 {
+		t.Errorf("Expected 2 features, got %v", set)
+	}
+	for _, feature := range []string{"search", "export"} {
+		if _, ok := set[feature]; !ok {
+			t.Errorf("Expected %s to be in the set, got %v", feature, set)
+		}
+	}
+
+	set, err = client.GetConfigEnabledSet("empty", defaults)
+	if err != nil || set == nil || len(set) != 0 {
+		t.Errorf("Expected an empty set, got %v (%v)", set, err)
+	}
+
+	for _, name := range []string{"missing", "not_list", "not_names"} {
+		set, err = client.GetConfigEnabledSet(name, defaults)
+		if _, ok := set["default"]; err == nil || !ok || len(set) != 1 {
+			t.Errorf("Expected an error and the default for %s, got %v (%v)", name, set, err)
+		}
+	}
+}
+
+func TestIsFeatureEnabled(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"features": []interface{}{"search", "export"},
+		"not_list": "search",
+	})
+
+	if !client.IsFeatureEnabled("features", "search") {
+		t.Errorf("Expected search to be enabled")
+	}
+	if client.IsFeatureEnabled("features", "billing") {
+		t.Errorf("Expected billing not to be enabled")
+	}
+	if client.IsFeatureEnabled("missing", "search") {
+		t.Errorf("Expected no feature of a missing set to be enabled")
+	}
+	if client.IsFeatureEnabled("not_list", "search") {
+		t.Errorf("Expected no feature of a set of the wrong type to be enabled")
+	}
+}
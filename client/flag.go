@@ -0,0 +1,173 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// flagBuckets is the number of buckets users are hashed into for percentage
+// rollouts, so that percentages have a resolution of 0.01.
+const flagBuckets = 10000
+
+// EvalContext is the user a feature flag is evaluated for with IsEnabled.
+type EvalContext struct {
+	UserID     string                 // Identifier of the user, hashed to place them in percentage rollouts
+	Attributes map[string]interface{} // Attributes of the user matched by the targeting rules, such as country
+}
+
+// flagConfig is the shape of a feature flag in the repository.
+type flagConfig struct {
+	Enabled    *bool      `yaml:"enabled"`    // False turns the flag off for everyone
+	Rules      []flagRule `yaml:"rules"`      // Targeting rules, the first matching one decides
+	Percentage *float64   `yaml:"percentage"` // Rollout of the users no rule matches
+}
+
+// flagRule is a targeting rule of a feature flag.
+type flagRule struct {
+	Attributes map[string]interface{} `yaml:"attributes"` // Values the attributes must equal, a list matching any of its values
+	Percentage *float64               `yaml:"percentage"` // Rollout of the users the rule matches, 100 when absent
+}
+
+// IsEnabled reports whether the feature flag with the given name is enabled for the user of eval
+func IsEnabled(flag string, eval EvalContext) bool {
+	client := DefaultClient()
+	if client == nil {
+		return false
+	}
+	return client.IsEnabled(flag, eval)
+}
+
+// IsEnabled reports whether the feature flag with the given name is enabled
+// for the user of eval. A flag is either a bool, which applies to everyone,
+// or a map such as:
+//
+//	new_checkout:
+//	  enabled: true      # false turns the flag off for everyone, true when absent
+//	  rules:             # the first rule whose attributes all match decides
+//	    - attributes:
+//	        country: [US, CA] # a list matches any of its values
+//	        plan: pro
+//	      percentage: 50 # share of the matching users enabled, 100 when absent
+//	  percentage: 10     # share of the users no rule matches, 100 when there are no rules and 0 otherwise
+//
+// Users are placed in percentage rollouts by hashing their UserID with the
+// name of the flag, so the result is stable for a user across refreshes and
+// processes, a user within a 10% rollout stays in it when it grows to 20%,
+// and different flags enable different users. A user without a UserID is
+// only enabled by a 100% rollout. IsEnabled returns false when the flag is
+// missing, and logs and returns false when it is malformed.
+func (c *Client) IsEnabled(flag string, eval EvalContext) bool {
+	config, err := c.getFlag(flag)
+	if err != nil {
+		if errors.Is(err, ErrTypeMismatch) {
+			c.redactConfigError(flag, &err)
+			c.log().Warn("invalid feature flag, disabling it", LogFields{"flag": flag, "error": err})
+		}
+		return false
+	}
+	enabled, err := config.evaluate(flag, eval)
+	if err != nil {
+		c.redactConfigError(flag, &err)
+		c.log().Warn("invalid feature flag, disabling it", LogFields{"flag": flag, "error": err})
+		return false
+	}
+	return enabled
+}
+
+// getFlag reads the feature flag with the given name, converting the bool
+// form to a map enabling or disabling it for everyone.
+func (c *Client) getFlag(flag string) (flagConfig, error) {
+	enabled, err := c.GetConfigBool(flag, false)
+	if err == nil {
+		return flagConfig{Enabled: &enabled}, nil
+	}
+	if !errors.Is(err, ErrTypeMismatch) {
+		return flagConfig{}, err
+	}
+	var config flagConfig
+	err = c.GetConfig(flag, &config, nil)
+	return config, err
+}
+
+// evaluate reports whether the flag is enabled for the user of eval.
+func (f flagConfig) evaluate(flag string, eval EvalContext) (bool, error) {
+	if f.Enabled != nil && !*f.Enabled {
+		return false, nil
+	}
+	for i, rule := range f.Rules {
+		matched, err := rule.matches(eval.Attributes)
+		if err != nil {
+			return false, fmt.Errorf("rule %d: %w", i, err)
+		}
+		if matched {
+			return inRollout(flag, eval.UserID, rule.Percentage, 100)
+		}
+	}
+	fallback := 100.0
+	if len(f.Rules) > 0 {
+		fallback = 0
+	}
+	return inRollout(flag, eval.UserID, f.Percentage, fallback)
+}
+
+// matches reports whether attributes hold every value of the rule.
+func (r flagRule) matches(attributes map[string]interface{}) (bool, error) {
+	for name, expected := range r.Attributes {
+		actual, ok := attributes[name]
+		if !ok {
+			return false, nil
+		}
+		actual, err := normalizeExprValue(actual)
+		if err != nil {
+			return false, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		values, ok := expected.([]interface{})
+		if !ok {
+			values = []interface{}{expected}
+		}
+		matched := false
+		for _, value := range values {
+			value, err := normalizeExprValue(value)
+			if err != nil {
+				return false, fmt.Errorf("attribute %s: %w", name, err)
+			}
+			if value == actual {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// inRollout reports whether the user with the given ID falls within the
+// percentage of users the flag is rolled out to, fallback when percentage is
+// nil.
+func inRollout(flag string, userID string, percentage *float64, fallback float64) (bool, error) {
+	share := fallback
+	if percentage != nil {
+		share = *percentage
+	}
+	if share < 0 || share > 100 {
+		return false, fmt.Errorf("percentage %v is not between 0 and 100", share)
+	}
+	if share == 100 {
+		return true, nil
+	}
+	if share == 0 || userID == "" {
+		return false, nil
+	}
+	return float64(flagBucket(flag, userID)) < share*flagBuckets/100, nil
+}
+
+// flagBucket hashes the user with the given ID into one of flagBuckets
+// buckets, salted with the name of the flag.
+func flagBucket(flag string, userID string) uint32 {
+	sum := sha256.Sum256([]byte(flag + "\x00" + userID))
+	return binary.BigEndian.Uint32(sum[:4]) % flagBuckets
+}
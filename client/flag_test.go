@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"testing"
+)
+
+func TestIsEnabledPercentage(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"checkout": map[string]interface{}{"percentage": 30},
+		"search":   map[string]interface{}{"percentage": 30},
+		"all":      map[string]interface{}{"percentage": 100},
+		"none":     map[string]interface{}{"percentage": 0},
+	})
+
+	users := 10000
+	enabled, both := 0, 0
+	for i := 0; i < users; i++ {
+		eval := EvalContext{UserID: "user-" + strconv.Itoa(i)}
+		checkout := client.IsEnabled("checkout", eval)
+		if checkout != client.IsEnabled("checkout", eval) {
+			t.Fatalf("Expected the result for %s to be stable", eval.UserID)
+		}
+		if checkout {
+			enabled++
+			if client.IsEnabled("search", eval) {
+				both++
+			}
+		}
+		if !client.IsEnabled("all", eval) || client.IsEnabled("none", eval) {
+			t.Fatalf("Expected 100%% and 0%% rollouts to enable everyone and no one for %s", eval.UserID)
+		}
+	}
+	if enabled < users*28/100 || enabled > users*32/100 {
+		t.Errorf("Expected about 30%% of users to be enabled, got %d of %d", enabled, users)
+	}
+	// The rollouts of different flags are independent, so about 30% of the
+	// users in one are in the other.
+	if both < enabled*25/100 || both > enabled*35/100 {
+		t.Errorf("Expected about 30%% of enabled users to be in the other rollout, got %d of %d", both, enabled)
+	}
+	if client.IsEnabled("checkout", EvalContext{}) {
+		t.Errorf("Expected a user without an ID not to be in a partial rollout")
+	}
+}
+
+func TestIsEnabledRolloutGrows(t *testing.T) {
+	client, repository := newMapClient(t, map[string]interface{}{
+		"checkout": map[string]interface{}{"percentage": 10},
+	})
+	var enabled []EvalContext
+	for i := 0; i < 1000; i++ {
+		eval := EvalContext{UserID: "user-" + strconv.Itoa(i)}
+		if client.IsEnabled("checkout", eval) {
+			enabled = append(enabled, eval)
+		}
+	}
+
+	repository.set("checkout", map[string]interface{}{"percentage": 20.5})
+	if err := client.ForceRefresh(context.Background()); err != nil {
+		t.Fatalf("Error refreshing: %s", err.Error())
+	}
+	for _, eval := range enabled {
+		if !client.IsEnabled("checkout", eval) {
+			t.Errorf("Expected %s to stay enabled when the rollout grows", eval.UserID)
+		}
+	}
+}
+
+func TestIsEnabledRules(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"checkout": map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{
+					"attributes": map[string]interface{}{"country": []interface{}{"US", "CA"}, "plan": "pro"},
+				},
+				map[string]interface{}{
+					"attributes": map[string]interface{}{"beta": true, "version": 2},
+				},
+				map[string]interface{}{
+					"attributes": map[string]interface{}{"country": "DE"},
+					"percentage": 0,
+				},
+			},
+		},
+		"disabled": map[string]interface{}{
+			"enabled": false,
+			"rules":   []interface{}{map[string]interface{}{"attributes": map[string]interface{}{"plan": "pro"}}},
+		},
+		"fallback": map[string]interface{}{
+			"rules":      []interface{}{map[string]interface{}{"attributes": map[string]interface{}{"plan": "free"}, "percentage": 0}},
+			"percentage": 100,
+		},
+		"on":      true,
+		"off":     false,
+		"invalid": map[string]interface{}{"percentage": 150},
+		"name":    "John",
+	})
+
+	tests := []struct {
+		flag       string
+		attributes map[string]interface{}
+		expected   bool
+	}{
+		{"checkout", map[string]interface{}{"country": "CA", "plan": "pro"}, true},
+		{"checkout", map[string]interface{}{"country": "FR", "plan": "pro"}, false},
+		{"checkout", map[string]interface{}{"country": "US"}, false},
+		{"checkout", map[string]interface{}{"beta": true, "version": int64(2)}, true},
+		{"checkout", map[string]interface{}{"beta": true, "version": 2.0}, true},
+		{"checkout", map[string]interface{}{"country": "DE", "plan": "pro"}, false},
+		{"checkout", nil, false},
+		{"disabled", map[string]interface{}{"plan": "pro"}, false},
+		{"fallback", map[string]interface{}{"plan": "free"}, false},
+		{"fallback", map[string]interface{}{"plan": "pro"}, true},
+		{"on", nil, true},
+		{"off", nil, false},
+		{"invalid", nil, false},
+		{"name", nil, false},
+		{"missing", nil, false},
+	}
+	for _, test := range tests {
+		eval := EvalContext{UserID: "user-1", Attributes: test.attributes}
+		if enabled := client.IsEnabled(test.flag, eval); enabled != test.expected {
+			t.Errorf("Expected %s to be %t for %v, got %t", test.flag, test.expected, test.attributes, enabled)
+		}
+	}
+}
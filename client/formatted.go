@@ -0,0 +1,47 @@
+package client
+
+import (
+	"fmt"
+)
+
+func GetConfigStringf(defaultValue string, format string, args ...interface{}) (string, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigStringf(defaultValue, format, args...)
+}
+
+func GetConfigIntf(defaultValue int, format string, args ...interface{}) (int, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigIntf(defaultValue, format, args...)
+}
+
+func GetConfigFloatf(defaultValue float64, format string, args ...interface{}) (float64, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigFloatf(defaultValue, format, args...)
+}
+
+// GetConfigStringf builds the configuration name with fmt.Sprintf from the
+// given format and arguments, then retrieves it like GetConfigString.
+func (c *Client) GetConfigStringf(defaultValue string, format string, args ...interface{}) (string, error) {
+	return c.GetConfigString(fmt.Sprintf(format, args...), defaultValue)
+}
+
+// GetConfigIntf builds the configuration name with fmt.Sprintf from the
+// given format and arguments, then retrieves it like GetConfigInt.
+func (c *Client) GetConfigIntf(defaultValue int, format string, args ...interface{}) (int, error) {
+	return c.GetConfigInt(fmt.Sprintf(format, args...), defaultValue)
+}
+
+// GetConfigFloatf builds the configuration name with fmt.Sprintf from the
+// given format and arguments, then retrieves it like GetConfigFloat.
+func (c *Client) GetConfigFloatf(defaultValue float64, format string, args ...interface{}) (float64, error) {
+	return c.GetConfigFloat(fmt.Sprintf(format, args...), defaultValue)
+}
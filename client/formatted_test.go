@@ -0,0 +1,45 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestGetConfigFormatted(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"tenant.42.plan":  "gold",
+		"tenant.42.quota": 100,
+		"tenant.42.ratio": 0.5,
+	})
+
+	plan, err := client.GetConfigStringf("free", "tenant.%d.plan", 42)
+	if err != nil {
+		t.Errorf("Error getting plan: %s", err.Error())
+	}
+	if plan != "gold" {
+		t.Errorf("Expected plan to be gold, got %s", plan)
+	}
+
+	quota, err := client.GetConfigIntf(0, "tenant.%s.%s", "42", "quota")
+	if err != nil {
+		t.Errorf("Error getting quota: %s", err.Error())
+	}
+	if quota != 100 {
+		t.Errorf("Expected quota to be 100, got %d", quota)
+	}
+
+	ratio, err := client.GetConfigFloatf(0, "tenant.%d.ratio", 42)
+	if err != nil {
+		t.Errorf("Error getting ratio: %s", err.Error())
+	}
+	if ratio != 0.5 {
+		t.Errorf("Expected ratio to be 0.5, got %f", ratio)
+	}
+
+	plan, err = client.GetConfigStringf("free", "tenant.%d.plan", 7)
+	if err == nil {
+		t.Errorf("Expected error, got nil")
+	}
+	if plan != "free" {
+		t.Errorf("Expected plan to be free, got %s", plan)
+	}
+}
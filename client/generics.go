@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetConfigEnumT retrieves the string configuration with the given name and
+// returns it as the caller's own enum type. It returns the default value and
+// an error if the configuration is missing or is not one of the allowed values.
+func GetConfigEnumT[T ~string](c *Client, name string, allowed []T, defaultValue T) (_ T, err error) {
+	defer c.observe("GetConfigEnumT", name, &err)
+	value, err := c.getConfigString(context.Background(), name, string(defaultValue))
+	if err != nil {
+		return defaultValue, err
+	}
+	for _, a := range allowed {
+		if T(value) == a {
+			return a, nil
+		}
+	}
+	return defaultValue, keyError(name, fmt.Errorf("not an allowed enum value: %q", value))
+}
+
+// GetConfigTransform retrieves the configuration with the given name decoded
+// as T and returns the result of transform applied to it. It returns the
+// default value and the error if the configuration cannot be read as T or
+// transform fails, and the default value alone for a missing optional key.
+func GetConfigTransform[T, R any](c *Client, name string, defaultValue R, transform func(T) (R, error)) (_ R, err error) {
+	defer c.observe("GetConfigTransform", name, &err)
+	var value T
+	err = c.getConfig(context.Background(), name, &value, nil)
+	if err != nil {
+		return defaultValue, err
+	}
+	if _, ok := c.getData(name); !ok {
+		return defaultValue, nil
+	}
+	result, err := transform(value)
+	if err != nil {
+		return defaultValue, err
+	}
+	return result, nil
+}
+
+// Get retrieves the configuration with the given name decoded as T, through
+// the same round trip as GetConfig, so that maps, slices of structs and
+// custom types can be read like the built-in types:
+//
+//	service, err := client.Get[Service](c, "service", Service{})
+//	ports, err := client.Get[[]int](c, "ports", nil)
+//
+// When T is a struct, fields the configuration does not set take their values
+// from defaultValue. It returns defaultValue and the error if the
+// configuration is missing or cannot be decoded as T, and defaultValue alone
+// for a missing optional key.
+func Get[T any](c *Client, name string, defaultValue T) (_ T, err error) {
+	defer c.observe("Get", name, &err)
+	var value T
+	err = c.getConfig(context.Background(), name, &value, defaultValue)
+	if err != nil {
+		return defaultValue, err
+	}
+	if _, ok := c.getData(name); !ok {
+		return defaultValue, nil
+	}
+	return value, nil
+}
+
+// GetSlice retrieves the array configuration with the given name and decodes
+// every element as T, such as a list of upstream servers:
+//
+//	upstreams, err := client.GetSlice[Upstream](c, "upstreams", nil)
+//
+// An empty array is an empty slice rather than defaultValue. It returns
+// defaultValue and an error if the configuration is missing, is not an array
+// or has an element that cannot be decoded as T.
+func GetSlice[T any](c *Client, name string, defaultValue []T) (_ []T, err error) {
+	defer c.observe("GetSlice", name, &err)
+	config, ok, err := c.tenantData(context.Background(), name)
+	if err != nil {
+		return defaultValue, err
+	}
+	if !ok {
+		return defaultValue, c.notFound(name)
+	}
+	if config == nil {
+		return defaultValue, nil
+	}
+	if _, ok := config.([]interface{}); !ok {
+		return defaultValue, typeMismatch(name, "an array")
+	}
+	output := []T{}
+	if err := c.decodeConfig(config, &output); err != nil {
+		return defaultValue, keyError(name, &decodeError{err: err})
+	}
+	return output, nil
+}
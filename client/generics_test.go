@@ -0,0 +1,185 @@
+package client
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type logLevel string
+
+const (
+	logLevelDebug logLevel = "debug"
+	logLevelInfo  logLevel = "info"
+	logLevelError logLevel = "error"
+)
+
+func TestGetConfigEnumT(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"level":   "error",
+		"invalid": "verbose",
+		"number":  3,
+	})
+	allowed := []logLevel{logLevelDebug, logLevelInfo, logLevelError}
+
+	level, err := GetConfigEnumT(client, "level", allowed, logLevelInfo)
+	if err != nil {
+		t.Errorf("Error getting level: %s", err.Error())
+	}
+	if level != logLevelError {
+		t.Errorf("Expected level to be error, got %s", level)
+	}
+
+	for _, name := range []string{"invalid", "number", "missing"} {
+		level, err = GetConfigEnumT(client, name, allowed, logLevelInfo)
+		if err == nil {
+			t.Errorf("Expected error for %s, got nil", name)
+		}
+		if level != logLevelInfo {
+			t.Errorf("Expected default level info for %s, got %s", name, level)
+		}
+	}
+}
+
+// endpoint is a domain type parsed from a "host:port" string.
+type endpoint struct {
+	host string
+	port int
+}
+
+func parseEndpoint(value string) (endpoint, error) {
+	host, port, ok := strings.Cut(value, ":")
+	if !ok {
+		return endpoint{}, errors.New("endpoint is missing a port")
+	}
+	number, err := strconv.Atoi(port)
+	if err != nil {
+		return endpoint{}, err
+	}
+	return endpoint{host: host, port: number}, nil
+}
+
+func TestGetConfigTransform(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"db":      "localhost:5432",
+		"invalid": "localhost",
+		"number":  []interface{}{1},
+	})
+	fallback := endpoint{host: "fallback", port: 1}
+
+	db, err := GetConfigTransform(client, "db", fallback, parseEndpoint)
+	if err != nil {
+		t.Errorf("Error getting db: %s", err.Error())
+	}
+	if db != (endpoint{host: "localhost", port: 5432}) {
+		t.Errorf("Expected db to be localhost:5432, got %v", db)
+	}
+
+	for _, name := range []string{"invalid", "number", "missing"} {
+		value, err := GetConfigTransform(client, name, fallback, parseEndpoint)
+		if err == nil {
+			t.Errorf("Expected error for %s, got nil", name)
+		}
+		if value != fallback {
+			t.Errorf("Expected the default for %s, got %v", name, value)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"retries": 3,
+		"name":    "John",
+		"ports":   []interface{}{80, 443},
+		"labels":  map[string]interface{}{"team": "core", "tier": "1"},
+		"level":   "info",
+		"service": map[string]interface{}{
+			"name": "api",
+			"upstreams": []interface{}{
+				map[string]interface{}{"host": "a", "port": 1},
+				map[string]interface{}{"host": "b", "port": 2},
+			},
+		},
+	})
+	type upstream struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	}
+	type service struct {
+		Name      string     `yaml:"name"`
+		Timeout   string     `yaml:"timeout"`
+		Upstreams []upstream `yaml:"upstreams"`
+	}
+
+	if retries, err := Get(client, "retries", 0); err != nil || retries != 3 {
+		t.Errorf("Expected retries to be 3, got %d (%v)", retries, err)
+	}
+	if name, err := Get(client, "name", ""); err != nil || name != "John" {
+		t.Errorf("Expected name to be John, got %s (%v)", name, err)
+	}
+	if level, err := Get(client, "level", logLevelError); err != nil || level != logLevelInfo {
+		t.Errorf("Expected level to be info, got %s (%v)", level, err)
+	}
+	if ports, err := Get[[]int](client, "ports", nil); err != nil || !reflect.DeepEqual(ports, []int{80, 443}) {
+		t.Errorf("Expected ports to be [80 443], got %v (%v)", ports, err)
+	}
+	labels, err := Get(client, "labels", map[string]string{})
+	if err != nil || !reflect.DeepEqual(labels, map[string]string{"team": "core", "tier": "1"}) {
+		t.Errorf("Expected the labels, got %v (%v)", labels, err)
+	}
+
+	// Fields the configuration does not set keep their default values.
+	got, err := Get(client, "service", service{Timeout: "5s"})
+	expected := service{Name: "api", Timeout: "5s", Upstreams: []upstream{{Host: "a", Port: 1}, {Host: "b", Port: 2}}}
+	if err != nil || !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %+v, got %+v (%v)", expected, got, err)
+	}
+
+	// Missing and mistyped configurations return the default value.
+	if ports, err := Get(client, "name", []int{1}); err == nil || !reflect.DeepEqual(ports, []int{1}) {
+		t.Errorf("Expected the default and an error for a mistyped config, got %v (%v)", ports, err)
+	}
+	if retries, err := Get(client, "missing", 5); err == nil || retries != 5 {
+		t.Errorf("Expected the default and an error for a missing config, got %d (%v)", retries, err)
+	}
+}
+
+type upstream struct {
+	Host   string `yaml:"host"`
+	Weight int    `yaml:"weight"`
+}
+
+func TestGetSlice(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"upstreams": []interface{}{
+			map[string]interface{}{"host": "a.internal", "weight": 3},
+			map[string]interface{}{"host": "b.internal", "weight": 1},
+		},
+		"empty":  []interface{}{},
+		"name":   "John",
+		"broken": []interface{}{"a.internal"},
+	})
+	defaultValue := []upstream{{Host: "localhost", Weight: 1}}
+
+	upstreams, err := GetSlice(client, "upstreams", defaultValue)
+	expected := []upstream{{Host: "a.internal", Weight: 3}, {Host: "b.internal", Weight: 1}}
+	if err != nil || !reflect.DeepEqual(upstreams, expected) {
+		t.Errorf("Expected %v, got %v (%v)", expected, upstreams, err)
+	}
+	empty, err := GetSlice(client, "empty", defaultValue)
+	if err != nil || empty == nil || len(empty) != 0 {
+		t.Errorf("Expected an empty slice, got %v (%v)", empty, err)
+	}
+	for _, name := range []string{"name", "broken"} {
+		value, err := GetSlice(client, name, defaultValue)
+		if !errors.Is(err, ErrTypeMismatch) || !reflect.DeepEqual(value, defaultValue) {
+			t.Errorf("Expected the default and a type mismatch for %s, got %v (%v)", name, value, err)
+		}
+	}
+	value, err := GetSlice(client, "missing", defaultValue)
+	if err == nil || !reflect.DeepEqual(value, defaultValue) {
+		t.Errorf("Expected the default and an error for a missing key, got %v (%v)", value, err)
+	}
+}
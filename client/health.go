@@ -0,0 +1,59 @@
+package client
+
+import (
+	"fmt"
+)
+
+// Healthy reports whether the configuration of the Client is usable, for
+// example to answer a readiness probe. It returns nil once a refresh has
+// succeeded and the last refresh did not fail, only failed some keys of a
+// repository that tolerates key errors, or failed within the window set with
+// WithMaxStaleness. Otherwise it returns an error wrapping
+// ErrUnhealthy that says why: no refresh has succeeded yet, the last refresh
+// failed, or the data is older than the maximum staleness. A closed Client
+// returns ErrClientClosed.
+func (c *Client) Healthy() error {
+	if c.isClosed.Load() {
+		return ErrClientClosed
+	}
+	lastSuccess, lastErr := c.LastRefresh()
+	if lastSuccess.IsZero() {
+		if lastErr != nil {
+			return fmt.Errorf("%w: no successful refresh yet: %v", ErrUnhealthy, lastErr)
+		}
+		return fmt.Errorf("%w: no successful refresh yet", ErrUnhealthy)
+	}
+	if c.maxStaleness > 0 {
+		age := c.now().Sub(lastSuccess)
+		if age <= c.maxStaleness {
+			return nil
+		}
+		if lastErr != nil {
+			return fmt.Errorf("%w: last successful refresh %s ago: %v", ErrUnhealthy, age, lastErr)
+		}
+		return fmt.Errorf("%w: last successful refresh %s ago", ErrUnhealthy, age)
+	}
+	// A partial refresh reached the source and applied the keys that parsed.
+	if lastErr != nil && !isPartial(lastErr) {
+		return fmt.Errorf("%w: last refresh failed: %v", ErrUnhealthy, lastErr)
+	}
+	return nil
+}
+
+// checkStale returns an error wrapping ErrStaleConfig for the configuration
+// with the given name when the Client has a maximum stale age, the last
+// refresh failed and the last successful one is older than the maximum.
+func (c *Client) checkStale(name string) error {
+	if c.maxStaleAge <= 0 {
+		return nil
+	}
+	lastSuccess, lastErr := c.LastRefresh()
+	if lastErr == nil || lastSuccess.IsZero() {
+		return nil
+	}
+	age := c.now().Sub(lastSuccess)
+	if age <= c.maxStaleAge {
+		return nil
+	}
+	return keyError(name, fmt.Errorf("%w: last successful refresh %s ago: %v", ErrStaleConfig, age, lastErr))
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealthy(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	repository := &flakyRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}}
+	repository.failing.Store(true)
+	client, err := NewClient(context.Background(), repository, time.Hour, WithClock(clock), WithReadinessGate(time.Second), WithLogger(NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	_ = client.ForceRefresh(context.Background())
+
+	err = client.Healthy()
+	if !errors.Is(err, ErrUnhealthy) || !strings.Contains(err.Error(), "no successful refresh") {
+		t.Errorf("Expected an unhealthy client before the first success, got %v", err)
+	}
+
+	repository.failing.Store(false)
+	_ = client.ForceRefresh(context.Background())
+	if err := client.Healthy(); err != nil {
+		t.Errorf("Expected a healthy client after a success, got %v", err)
+	}
+
+	repository.failing.Store(true)
+	_ = client.ForceRefresh(context.Background())
+	err = client.Healthy()
+	if !errors.Is(err, ErrUnhealthy) || !strings.Contains(err.Error(), "backend unavailable") {
+		t.Errorf("Expected an unhealthy client after a failed refresh, got %v", err)
+	}
+
+	client.Close()
+	if err := client.Healthy(); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed after Close, got %v", err)
+	}
+}
+
+func TestHealthyPartialRefresh(t *testing.T) {
+	keyErrors := &source.KeyErrors{Errors: map[string]error{"limits": errors.New("yaml: line 1: did not find expected node content")}}
+	repository := &partialRepository{
+		mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}},
+		keyErrors:     keyErrors,
+	}
+	client, err := NewClient(context.Background(), repository, time.Hour, WithLogger(NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// A refresh that only failed some keys keeps the client healthy.
+	if _, lastErr := client.LastRefresh(); !errors.Is(lastErr, keyErrors) {
+		t.Fatalf("Expected the last refresh to report the key errors, got %v", lastErr)
+	}
+	if err := client.Healthy(); err != nil {
+		t.Errorf("Expected a healthy client after a partial refresh, got %v", err)
+	}
+}
+
+func TestHealthyMaxStaleness(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	repository := &flakyRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}}
+	client, err := NewClient(context.Background(), repository, time.Hour, WithClock(clock), WithMaxStaleness(5*time.Minute), WithLogger(NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// A failure within the staleness window is tolerated.
+	repository.failing.Store(true)
+	clock.advance(time.Minute)
+	_ = client.ForceRefresh(context.Background())
+	if err := client.Healthy(); err != nil {
+		t.Errorf("Expected a healthy client within the staleness window, got %v", err)
+	}
+
+	clock.advance(5 * time.Minute)
+	err = client.Healthy()
+	if !errors.Is(err, ErrUnhealthy) || !strings.Contains(err.Error(), "backend unavailable") {
+		t.Errorf("Expected an unhealthy client past the staleness window, got %v", err)
+	}
+
+	// Data that is too old is stale even if no refresh failed.
+	repository.failing.Store(false)
+	_ = client.ForceRefresh(context.Background())
+	clock.advance(10 * time.Minute)
+	err = client.Healthy()
+	if !errors.Is(err, ErrUnhealthy) || !strings.Contains(err.Error(), "10m0s ago") {
+		t.Errorf("Expected a stale client, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"github.com/divakarmanoj/go-remote-config/source"
+	"sort"
+)
+
+// initialRepository is the repository of the data seeded with
+// WithInitialData, which never changes.
+type initialRepository struct {
+	data map[string]interface{}
+}
+
+// GetName returns the name of the seeded data.
+func (r *initialRepository) GetName() string {
+	return "initial"
+}
+
+// GetData returns the seeded configuration with the given name.
+func (r *initialRepository) GetData(configName string) (interface{}, bool) {
+	config, isPresent := r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, as the data was never encoded.
+func (r *initialRepository) GetRawData() []byte {
+	return nil
+}
+
+// Refresh does nothing, as the seeded data never changes.
+func (r *initialRepository) Refresh() error {
+	return nil
+}
+
+// Keys returns the names of the seeded configurations.
+func (r *initialRepository) Keys() []string {
+	keys := make([]string, 0, len(r.data))
+	for key := range r.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// seed layers the initial data beneath the repository of the Client, or makes
+// it the repository when NewClient was given none.
+func (c *Client) seed(data map[string]interface{}) {
+	initial := &initialRepository{data: data}
+	if c.Repository == nil {
+		c.Repository = initial
+		return
+	}
+	c.Repository = source.NewChainRepository(c.Repository.GetName(), []source.Repository{c.Repository, initial})
+}
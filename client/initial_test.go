@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithInitialData(t *testing.T) {
+	client, err := NewClient(context.Background(), nil, time.Hour, WithInitialData(map[string]interface{}{
+		"name":    "John",
+		"age":     30,
+		"ratio":   0.5,
+		"enabled": true,
+		"tags":    []interface{}{"a", "b"},
+		"server":  map[string]interface{}{"host": "db.internal", "port": 5432},
+	}))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	if value, err := client.GetConfigString("name", ""); err != nil || value != "John" {
+		t.Errorf("Expected John, got %s (%v)", value, err)
+	}
+	if value, err := client.GetConfigInt("age", 0); err != nil || value != 30 {
+		t.Errorf("Expected 30, got %d (%v)", value, err)
+	}
+	if value, err := client.GetConfigFloat("ratio", 0); err != nil || value != 0.5 {
+		t.Errorf("Expected 0.5, got %f (%v)", value, err)
+	}
+	if value, err := client.GetConfigBool("enabled", false); err != nil || !value {
+		t.Errorf("Expected true, got %t (%v)", value, err)
+	}
+	if value, err := client.GetConfigArrayOfStrings("tags", nil); err != nil || !reflect.DeepEqual(value, []string{"a", "b"}) {
+		t.Errorf("Expected [a b], got %v (%v)", value, err)
+	}
+	var server serverConfig
+	if err := client.GetConfig("server", &server, nil); err != nil || server.Host != "db.internal" || server.Port != 5432 {
+		t.Errorf("Expected the seeded server, got %+v (%v)", server, err)
+	}
+}
+
+func TestWithoutInitialRefresh(t *testing.T) {
+	repository := &flakyRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "Jane"}}}
+	repository.failing.Store(true)
+	client, err := NewClient(context.Background(), repository, time.Hour, WithInitialData(map[string]interface{}{
+		"name": "John",
+		"age":  30,
+	}), WithoutInitialRefresh(), WithLogger(NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Expected NewClient not to refresh the failing repository, got %s", err.Error())
+	}
+	defer client.Close()
+	if at, _ := client.LastRefresh(); !at.IsZero() {
+		t.Errorf("Expected no refresh, got one at %s", at)
+	}
+
+	// The repository overrides the seeded data once it loads.
+	repository.failing.Store(false)
+	if err := client.ForceRefresh(context.Background()); err != nil {
+		t.Fatalf("Error refreshing client: %s", err.Error())
+	}
+	if value, err := client.GetConfigString("name", ""); err != nil || value != "Jane" {
+		t.Errorf("Expected the repository's Jane, got %s (%v)", value, err)
+	}
+	if value, err := client.GetConfigInt("age", 0); err != nil || value != 30 {
+		t.Errorf("Expected the seeded age, got %d (%v)", value, err)
+	}
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// SnapshotTransform rewrites the configurations of a refresh as a whole, such
+// as to expand references between them. It receives every configuration as
+// the value transformers left it and returns the configurations getters
+// should see, or an error to reject the refresh. Like a ValueTransformer, it
+// must return new maps and slices rather than modify those of the snapshot in
+// place, because those are shared with the repository.
+type SnapshotTransform func(snapshot map[string]interface{}) (map[string]interface{}, error)
+
+// transformSnapshot runs the snapshot transforms on snapshot in the order
+// they were added, returning the first error.
+func (c *Client) transformSnapshot(snapshot map[string]interface{}) (map[string]interface{}, error) {
+	for _, transform := range c.snapshotTransforms {
+		var err error
+		snapshot, err = transform(snapshot)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// referencePattern matches the ${name} references in string values.
+var referencePattern = regexp.MustCompile(`\$\{([^{}]+)\}`)
+
+// envNamePattern matches the names of environment variables.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExpandEnv is a SnapshotTransform that replaces the ${NAME} references in
+// string values, at any depth, with the value of the environment variable
+// NAME. References to variables that are not set are left as they are, so
+// that ExpandReferences, added after it, can expand them.
+func ExpandEnv(snapshot map[string]interface{}) (map[string]interface{}, error) {
+	expand := func(value string) (interface{}, error) {
+		return referencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+			name := reference[2 : len(reference)-1]
+			if !envNamePattern.MatchString(name) {
+				return reference
+			}
+			if env, ok := os.LookupEnv(name); ok {
+				return env
+			}
+			return reference
+		}), nil
+	}
+	expanded := make(map[string]interface{}, len(snapshot))
+	for key, value := range snapshot {
+		value, err := rewriteStrings(value, expand)
+		if err != nil {
+			return nil, err
+		}
+		expanded[key] = value
+	}
+	return expanded, nil
+}
+
+// ExpandReferences is a SnapshotTransform that replaces the ${name}
+// references in string values, at any depth, with the value of the
+// configuration name of the snapshot, which may be a dotted path such as
+// ${database.host}. A string that is a single reference takes the referenced
+// value with its type, so `port: ${defaults.port}` stays a number, while a
+// reference within a longer string is formatted into it and must be to a
+// scalar. References in referenced values are expanded too. A reference to a
+// missing configuration or a cycle of references rejects the refresh.
+func ExpandReferences(snapshot map[string]interface{}) (map[string]interface{}, error) {
+	expander := &referenceExpander{snapshot: snapshot, resolving: map[string]bool{}}
+	expanded := make(map[string]interface{}, len(snapshot))
+	for key, value := range snapshot {
+		value, err := rewriteStrings(value, expander.expand)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		expanded[key] = value
+	}
+	return expanded, nil
+}
+
+// referenceExpander expands the references of ExpandReferences.
+type referenceExpander struct {
+	snapshot  map[string]interface{}
+	resolving map[string]bool // references being resolved, to detect cycles
+}
+
+// expand returns value with its references replaced.
+func (e *referenceExpander) expand(value string) (interface{}, error) {
+	if match := referencePattern.FindStringSubmatch(value); match != nil && match[0] == value {
+		return e.resolve(match[1])
+	}
+	var err error
+	expanded := referencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		resolved, resolveErr := e.resolve(reference[2 : len(reference)-1])
+		if resolveErr != nil {
+			err = resolveErr
+			return reference
+		}
+		switch resolved.(type) {
+		case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+			err = fmt.Errorf("reference %s is not a scalar", reference)
+			return reference
+		}
+		return fmt.Sprint(resolved)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
+
+// resolve returns the expanded value of the configuration a reference names.
+func (e *referenceExpander) resolve(name string) (interface{}, error) {
+	if e.resolving[name] {
+		return nil, fmt.Errorf("cycle of references through ${%s}", name)
+	}
+	config, ok := e.snapshot[name]
+	if !ok {
+		key, path := splitPath(name)
+		if path != nil {
+			config, ok = e.snapshot[key]
+			if ok {
+				config, ok = descend(config, path)
+			}
+		}
+	}
+	if !ok || valueError(config) != nil {
+		return nil, fmt.Errorf("reference to missing configuration ${%s}", name)
+	}
+	e.resolving[name] = true
+	defer delete(e.resolving, name)
+	return rewriteStrings(config, e.expand)
+}
+
+// rewriteStrings returns a copy of value with every string, at any depth,
+// replaced by the result of rewrite.
+func rewriteStrings(value interface{}, rewrite func(string) (interface{}, error)) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		return rewrite(value)
+	case map[string]interface{}:
+		rewritten := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			item, err := rewriteStrings(item, rewrite)
+			if err != nil {
+				return nil, err
+			}
+			rewritten[key] = item
+		}
+		return rewritten, nil
+	case map[interface{}]interface{}:
+		rewritten := make(map[interface{}]interface{}, len(value))
+		for key, item := range value {
+			item, err := rewriteStrings(item, rewrite)
+			if err != nil {
+				return nil, err
+			}
+			rewritten[key] = item
+		}
+		return rewritten, nil
+	case []interface{}:
+		rewritten := make([]interface{}, len(value))
+		for i, item := range value {
+			item, err := rewriteStrings(item, rewrite)
+			if err != nil {
+				return nil, err
+			}
+			rewritten[i] = item
+		}
+		return rewritten, nil
+	}
+	return value, nil
+}
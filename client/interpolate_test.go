@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithTransform(t *testing.T) {
+	t.Setenv("INTERPOLATE_TEST_HOST", "db.internal")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("host: ${INTERPOLATE_TEST_HOST}\nport: 5432\nurl: postgres://${host}:${port}/app\nreplica_port: ${port}\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	errRejected := errors.New("rejected")
+	reject := false
+	check := func(snapshot map[string]interface{}) (map[string]interface{}, error) {
+		if reject {
+			return nil, errRejected
+		}
+		return snapshot, nil
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second,
+		WithTransform(ExpandEnv), WithTransform(check), WithTransform(ExpandReferences))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// Environment variables and references to other keys are expanded.
+	host, _ := client.GetConfigString("host", "")
+	if host != "db.internal" {
+		t.Errorf("Expected host to be db.internal, got %q", host)
+	}
+	url, _ := client.GetConfigString("url", "")
+	if url != "postgres://db.internal:5432/app" {
+		t.Errorf("Expected the expanded url, got %q", url)
+	}
+	replicaPort, err := client.GetConfigInt("replica_port", 0)
+	if err != nil || replicaPort != 5432 {
+		t.Errorf("Expected a reference alone to keep its int type, got %d, %v", replicaPort, err)
+	}
+
+	// A transform error rejects the refresh and keeps the last accepted values.
+	reject = true
+	err = os.WriteFile(path, []byte("host: other\nport: 1\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	err = client.ForceRefresh(context.Background())
+	if !errors.Is(err, errRejected) {
+		t.Errorf("Expected the refresh to be rejected, got %v", err)
+	}
+	host, _ = client.GetConfigString("host", "")
+	if host != "db.internal" {
+		t.Errorf("Expected the last accepted host, got %q", host)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("INTERPOLATE_TEST_USER", "admin")
+	expanded, err := ExpandEnv(map[string]interface{}{
+		"user":  "${INTERPOLATE_TEST_USER}",
+		"unset": "${INTERPOLATE_TEST_UNSET}",
+		"path":  "${database.host}",
+		"list":  []interface{}{"${INTERPOLATE_TEST_USER}@host", 1},
+	})
+	if err != nil {
+		t.Fatalf("Error expanding environment variables: %s", err.Error())
+	}
+	if expanded["user"] != "admin" {
+		t.Errorf("Expected user to be admin, got %v", expanded["user"])
+	}
+	if expanded["unset"] != "${INTERPOLATE_TEST_UNSET}" || expanded["path"] != "${database.host}" {
+		t.Errorf("Expected unset variables and references to be left as they are, got %v and %v", expanded["unset"], expanded["path"])
+	}
+	list, _ := expanded["list"].([]interface{})
+	if len(list) != 2 || list[0] != "admin@host" || list[1] != 1 {
+		t.Errorf("Expected nested strings to be expanded, got %v", expanded["list"])
+	}
+}
+
+func TestExpandReferences(t *testing.T) {
+	expanded, err := ExpandReferences(map[string]interface{}{
+		"database": map[string]interface{}{"host": "db", "port": 5432},
+		"dsn":      "${database.host}:${database.port}",
+		"hosts":    []interface{}{"${database.host}", "${alias}"},
+		"alias":    "${database.host}",
+	})
+	if err != nil {
+		t.Fatalf("Error expanding references: %s", err.Error())
+	}
+	if expanded["dsn"] != "db:5432" {
+		t.Errorf("Expected dsn to be db:5432, got %v", expanded["dsn"])
+	}
+	hosts, _ := expanded["hosts"].([]interface{})
+	if len(hosts) != 2 || hosts[0] != "db" || hosts[1] != "db" {
+		t.Errorf("Expected nested and chained references to be expanded, got %v", expanded["hosts"])
+	}
+
+	_, err = ExpandReferences(map[string]interface{}{"a": "${missing}"})
+	if err == nil {
+		t.Error("Expected an error for a missing reference")
+	}
+	_, err = ExpandReferences(map[string]interface{}{"a": "${b}", "b": "x${a}"})
+	if err == nil {
+		t.Error("Expected an error for a cycle of references")
+	}
+	_, err = ExpandReferences(map[string]interface{}{"a": "x${b}", "b": map[string]interface{}{"c": 1}})
+	if err == nil {
+		t.Error("Expected an error for a map formatted into a string")
+	}
+}
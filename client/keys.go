@@ -0,0 +1,72 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Keys returns the sorted names of the top-level configurations of the last
+// refresh, such as for debugging or an admin page. It is safe to call while
+// the Client refreshes. Like View, it returns nil for repositories that do
+// not implement source.KeyLister.
+func (c *Client) Keys() []string {
+	return c.View().Keys()
+}
+
+// KeysFlattened returns the sorted dotted paths of every value of the last
+// refresh that is not itself a map, such as database.primary.host, which
+// getters accept in place of a configuration name. Arrays are not expanded.
+func (c *Client) KeysFlattened() []string {
+	return c.View().KeysFlattened()
+}
+
+// Keys returns the sorted names of the top-level configurations of the View.
+func (v *View) Keys() []string {
+	if v.values == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// KeysFlattened returns the sorted dotted paths of every value of the View
+// that is not itself a non-empty map.
+func (v *View) KeysFlattened() []string {
+	if v.values == nil {
+		return nil
+	}
+	keys := []string{}
+	for key, value := range v.values {
+		keys = appendPaths(keys, key, value)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// appendPaths appends to keys the dotted paths below prefix of the values
+// nested in config, or prefix itself if config is not a map or is empty.
+func appendPaths(keys []string, prefix string, config interface{}) []string {
+	switch configMap := config.(type) {
+	case map[string]interface{}:
+		if len(configMap) == 0 {
+			break
+		}
+		for key, value := range configMap {
+			keys = appendPaths(keys, prefix+PathSeparator+key, value)
+		}
+		return keys
+	case map[interface{}]interface{}:
+		if len(configMap) == 0 {
+			break
+		}
+		for key, value := range configMap {
+			keys = appendPaths(keys, prefix+PathSeparator+fmt.Sprint(key), value)
+		}
+		return keys
+	}
+	return append(keys, prefix)
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newFileClient(t *testing.T, content string) (*Client, string) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	t.Cleanup(client.Close)
+	return client, path
+}
+
+func TestKeys(t *testing.T) {
+	client, _ := newFileClient(t, "name: John\nage: 30\ntags: [a, b]\n")
+	expected := []string{"age", "name", "tags"}
+	if keys := client.Keys(); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+	if keys := client.KeysFlattened(); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected flat configurations not to be expanded, got %v", keys)
+	}
+}
+
+func TestKeysFlattened(t *testing.T) {
+	client, _ := newFileClient(t, "database:\n  primary:\n    host: db1\n    port: 5432\n  replicas: [db2, db3]\nempty: {}\nname: John\n")
+	if keys := client.Keys(); !reflect.DeepEqual(keys, []string{"database", "empty", "name"}) {
+		t.Errorf("Expected the top-level keys, got %v", keys)
+	}
+	expected := []string{"database.primary.host", "database.primary.port", "database.replicas", "empty", "name"}
+	keys := client.KeysFlattened()
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+	if host, err := client.GetConfigString(keys[0], ""); err != nil || host != "db1" {
+		t.Errorf("Expected the path to be readable by getters, got %s (%v)", host, err)
+	}
+}
+
+func TestKeysDuringRefresh(t *testing.T) {
+	client, path := newFileClient(t, "a: 1\n")
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			_ = os.WriteFile(path, []byte("a: 1\nb: 2\n"), 0o600)
+			_ = client.ForceRefresh(context.Background())
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		keys := client.Keys()
+		if len(keys) == 0 || keys[0] != "a" {
+			t.Errorf("Expected a in every listing, got %v", keys)
+		}
+		client.KeysFlattened()
+	}
+	wg.Wait()
+	if keys := client.Keys(); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Errorf("Expected the keys of the last refresh, got %v", keys)
+	}
+}
+
+func TestKeysWithoutKeyLister(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{"name": "John"})
+	if keys := client.Keys(); keys != nil {
+		t.Errorf("Expected no keys for a repository that cannot list them, got %v", keys)
+	}
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"github.com/divakarmanoj/go-remote-config/source"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// RefreshResult describes a refresh of the repository of a Client.
+type RefreshResult struct {
+	StartedAt     time.Time              // Time the refresh started
+	Duration      time.Duration          // Time the refresh took
+	Err           error                  // Error of the refresh, nil if it succeeded
+	ChangedKeys   []string               // Sorted keys whose values were added, changed or removed by the refresh
+	Source        string                 // Name of the repository that was refreshed
+	ChangeSources map[string]string      // Name of the repository each changed key came from, the layer of a source.SourceResolver such as a ChainRepository
+	previous      map[string]interface{} // values before the refresh, when keys changed
+	current       map[string]interface{} // values after the refresh, when keys changed
+}
+
+// RefreshListener is called after every refresh of the repository of a
+// Client. It runs on the refreshing goroutine, so it must not block or
+// refresh the Client itself.
+type RefreshListener func(RefreshResult)
+
+// currentValues returns every configuration of the repository as seen by
+// getters, or nil if the repository does not implement source.KeyLister.
+func (c *Client) currentValues() map[string]interface{} {
+	if values := c.transformed.Load(); values != nil {
+		return *values
+	}
+	lister, ok := c.Repository.(source.KeyLister)
+	if !ok {
+		return nil
+	}
+	values := map[string]interface{}{}
+	for _, key := range lister.Keys() {
+		if value, ok := c.lookup(key); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// trackChanges records the current values of the repository and returns the
+// keys that changed since they were last recorded, with the values recorded
+// before and the repositories the changes came from. It is the only place
+// that diffs the repository data, so every subscriber sees the same changed keys.
+func (c *Client) trackChanges() ([]string, map[string]interface{}, map[string]string) {
+	previous := c.values
+	values := c.currentValues()
+	changed := changedKeys(previous, values)
+	c.values = values
+	c.viewValues.Store(&values)
+
+	resolver, ok := c.Repository.(source.SourceResolver)
+	if !ok {
+		if len(changed) == 0 {
+			return changed, previous, nil
+		}
+		sources := make(map[string]string, len(changed))
+		for _, key := range changed {
+			sources[key] = c.Repository.GetName()
+		}
+		return changed, previous, sources
+	}
+	// Record the source of every key, so that a removed key is attributed to
+	// the repository it came from.
+	previousSources := c.sources
+	c.sources = make(map[string]string, len(values))
+	for key := range values {
+		if name, ok := resolver.SourceOf(key); ok {
+			c.sources[key] = name
+		}
+	}
+	if len(changed) == 0 {
+		return changed, previous, nil
+	}
+	sources := make(map[string]string, len(changed))
+	for _, key := range changed {
+		name, ok := c.sources[key]
+		if !ok {
+			name, ok = previousSources[key]
+		}
+		if ok {
+			sources[key] = name
+		}
+	}
+	return changed, previous, sources
+}
+
+// changedKeys returns the sorted keys that were added, changed or removed between before and after.
+func changedKeys(before, after map[string]interface{}) []string {
+	var changed []string
+	for key, value := range after {
+		previous, ok := before[key]
+		if !ok || !reflect.DeepEqual(previous, value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithRefreshListener(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("name: John\nage: 30\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	var mu sync.Mutex
+	var results []RefreshResult
+	listener := func(result RefreshResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, result)
+	}
+	before := time.Now()
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second, WithRefreshListener(listener))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	err = os.WriteFile(path, []byte("name: Jane\nage: 30\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Errorf("Error forcing refresh: %s", err.Error())
+	}
+	err = os.Remove(path)
+	if err != nil {
+		t.Fatalf("Error removing file: %s", err.Error())
+	}
+	if client.ForceRefresh(context.Background()) == nil {
+		t.Errorf("Expected error refreshing a missing file, got nil")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 refresh results, got %d", len(results))
+	}
+	expectedChanges := [][]string{{"age", "name"}, {"name"}, nil}
+	for i, result := range results {
+		if result.Source != "file" {
+			t.Errorf("Expected source to be file, got %s", result.Source)
+		}
+		if result.StartedAt.Before(before) || result.Duration < 0 {
+			t.Errorf("Expected timing to be populated, got %v and %v", result.StartedAt, result.Duration)
+		}
+		if !reflect.DeepEqual(result.ChangedKeys, expectedChanges[i]) {
+			t.Errorf("Expected changed keys of refresh %d to be %v, got %v", i, expectedChanges[i], result.ChangedKeys)
+		}
+	}
+	if results[0].Err != nil || results[1].Err != nil {
+		t.Errorf("Expected successful refreshes to have no error")
+	}
+	if results[2].Err == nil {
+		t.Errorf("Expected failed refresh to report its error")
+	}
+}
+
+func TestRefreshListenerChangeSources(t *testing.T) {
+	dir := t.TempDir()
+	overridePath := filepath.Join(dir, "override.yaml")
+	basePath := filepath.Join(dir, "base.yaml")
+	write := func(path string, content string) {
+		t.Helper()
+		err := os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Error writing file: %s", err.Error())
+		}
+	}
+	write(overridePath, "name: Jane\n")
+	write(basePath, "name: John\nport: 1\nregion: eu\n")
+	chain := source.NewChainRepository("chain", []source.Repository{
+		&source.FileRepository{Name: "override", Path: overridePath},
+		&source.FileRepository{Name: "base", Path: basePath},
+	})
+	results := make(chan RefreshResult, 10)
+	client, err := NewClient(context.Background(), chain, 10*time.Second, WithRefreshListener(func(result RefreshResult) {
+		results <- result
+	}))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	initial := <-results
+	expected := map[string]string{"name": "override", "port": "base", "region": "base"}
+	if !reflect.DeepEqual(initial.ChangeSources, expected) {
+		t.Errorf("Expected the sources %v, got %v", expected, initial.ChangeSources)
+	}
+
+	// Changes from different layers carry the layer they came from, and a
+	// removed key the layer it used to come from.
+	write(overridePath, "name: Jack\nport: 2\n")
+	write(basePath, "name: John\nport: 1\n")
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error forcing refresh: %s", err.Error())
+	}
+	result := <-results
+	expected = map[string]string{"name": "override", "port": "override", "region": "base"}
+	if !reflect.DeepEqual(result.ChangedKeys, []string{"name", "port", "region"}) || !reflect.DeepEqual(result.ChangeSources, expected) {
+		t.Errorf("Expected the sources %v, got %v for %v", expected, result.ChangeSources, result.ChangedKeys)
+	}
+
+	// Repositories that do not combine others are the source of every change.
+	plain, err := NewClient(context.Background(), &source.FileRepository{Name: "base", Path: basePath}, 10*time.Second, WithRefreshListener(func(result RefreshResult) {
+		results <- result
+	}))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer plain.Close()
+	result = <-results
+	expected = map[string]string{"name": "base", "port": "base"}
+	if !reflect.DeepEqual(result.ChangeSources, expected) {
+		t.Errorf("Expected the sources %v, got %v", expected, result.ChangeSources)
+	}
+}
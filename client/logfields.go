@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+)
+
+// GetConfigLogFields retrieves the log fields configuration with the given name from the repository
+func GetConfigLogFields(name string, defaultValue logrus.Fields) (logrus.Fields, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigLogFields(name, defaultValue)
+}
+
+// GetConfigLogFields retrieves the map configuration with the given name from
+// the repository as logrus.Fields, for use with logrus.WithFields. Keys that
+// are not strings, such as the numbers or booleans of a YAML map, are
+// converted to their string form.
+func (c *Client) GetConfigLogFields(name string, defaultValue logrus.Fields) (logrus.Fields, error) {
+	if c.isClosed.Load() {
+		return defaultValue, ErrClientClosed
+	}
+	if err := c.awaitReadiness(context.Background()); err != nil {
+		return defaultValue, err
+	}
+	// Get the configuration data from the repository
+	config, ok := c.getData(name)
+	if !ok {
+		return defaultValue, c.notFound(name)
+	}
+
+	switch configMap := config.(type) {
+	case map[string]interface{}:
+		fields := make(logrus.Fields, len(configMap))
+		for key, value := range configMap {
+			fields[key] = value
+		}
+		return fields, nil
+	case map[interface{}]interface{}:
+		fields := make(logrus.Fields, len(configMap))
+		for key, value := range configMap {
+			fields[fmt.Sprint(key)] = value
+		}
+		return fields, nil
+	}
+	return defaultValue, typeMismatch(name, "a map")
+}
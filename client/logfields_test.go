@@ -0,0 +1,36 @@
+package client
+
+import (
+	"github.com/sirupsen/logrus"
+	"reflect"
+	"testing"
+)
+
+func TestGetConfigLogFields(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"fields":   map[string]interface{}{"service": "billing", "shard": 3},
+		"mixed":    map[interface{}]interface{}{"region": "eu", 42: "answer", true: "yes"},
+		"not_map":  "service",
+		"not_list": []interface{}{"service"},
+	})
+	defaults := logrus.Fields{"service": "unknown"}
+
+	fields, err := client.GetConfigLogFields("fields", defaults)
+	expected := logrus.Fields{"service": "billing", "shard": 3}
+	if err != nil || !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected %v, got %v (%v)", expected, fields, err)
+	}
+
+	fields, err = client.GetConfigLogFields("mixed", defaults)
+	expected = logrus.Fields{"region": "eu", "42": "answer", "true": "yes"}
+	if err != nil || !reflect.DeepEqual(fields, expected) {
+		t.Errorf("Expected interface keys to be normalized to %v, got %v (%v)", expected, fields, err)
+	}
+
+	for _, name := range []string{"not_map", "not_list", "missing"} {
+		fields, err = client.GetConfigLogFields(name, defaults)
+		if err == nil || !reflect.DeepEqual(fields, defaults) {
+			t.Errorf("Expected an error and the default for %s, got %v (%v)", name, fields, err)
+		}
+	}
+}
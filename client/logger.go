@@ -0,0 +1,73 @@
+package client
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// LogFields hold the structured context of a log entry. The error an entry
+// reports, if any, is under "error".
+type LogFields map[string]interface{}
+
+// Logger receives the log entries of a Client. Replace it with WithLogger, for
+// example to route the entries into another logging library or to silence
+// them in tests.
+type Logger interface {
+	Debug(message string, fields LogFields)
+	Info(message string, fields LogFields)
+	Warn(message string, fields LogFields)
+	Error(message string, fields LogFields)
+}
+
+// NewLogrusLogger returns a Logger writing to the given logrus logger, such as
+// logrus.StandardLogger(), which Clients write to by default.
+func NewLogrusLogger(logger logrus.FieldLogger) Logger {
+	return logrusLogger{logger: logger}
+}
+
+// NewNopLogger returns a Logger discarding every entry.
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+// log returns the Logger of the Client.
+func (c *Client) log() Logger {
+	if c.logger == nil {
+		return defaultLogger
+	}
+	return c.logger
+}
+
+// defaultLogger writes to the standard logrus logger.
+var defaultLogger = NewLogrusLogger(logrus.StandardLogger())
+
+// logrusLogger implements Logger with logrus.
+type logrusLogger struct {
+	logger logrus.FieldLogger
+}
+
+func (l logrusLogger) Debug(message string, fields LogFields) {
+	l.logger.WithFields(logrus.Fields(fields)).Debug(message)
+}
+
+func (l logrusLogger) Info(message string, fields LogFields) {
+	l.logger.WithFields(logrus.Fields(fields)).Info(message)
+}
+
+func (l logrusLogger) Warn(message string, fields LogFields) {
+	l.logger.WithFields(logrus.Fields(fields)).Warn(message)
+}
+
+func (l logrusLogger) Error(message string, fields LogFields) {
+	l.logger.WithFields(logrus.Fields(fields)).Error(message)
+}
+
+// nopLogger implements Logger by discarding every entry.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, LogFields) {}
+
+func (nopLogger) Info(string, LogFields) {}
+
+func (nopLogger) Warn(string, LogFields) {}
+
+func (nopLogger) Error(string, LogFields) {}
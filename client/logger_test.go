@@ -0,0 +1,86 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"github.com/sirupsen/logrus"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingLogger is a Logger recording the messages of its entries by level.
+type recordingLogger struct {
+	sync.Mutex
+	entries []string
+}
+
+func (r *recordingLogger) record(level string, message string, fields LogFields) {
+	r.Lock()
+	defer r.Unlock()
+	if err, ok := fields["error"].(error); ok {
+		message += ": " + err.Error()
+	}
+	r.entries = append(r.entries, level+" "+message)
+}
+
+func (r *recordingLogger) Debug(message string, fields LogFields) { r.record("debug", message, fields) }
+
+func (r *recordingLogger) Info(message string, fields LogFields) { r.record("info", message, fields) }
+
+func (r *recordingLogger) Warn(message string, fields LogFields) { r.record("warn", message, fields) }
+
+func (r *recordingLogger) Error(message string, fields LogFields) { r.record("error", message, fields) }
+
+func TestWithLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logrus.SetOutput(&logs)
+	defer logrus.SetOutput(os.Stderr)
+
+	logger := &recordingLogger{}
+	repository := &flakyRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}}
+	client, err := NewClient(context.Background(), repository, time.Hour, WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	repository.failing.Store(true)
+	client.logRefresh(client.refreshPeriodic())
+
+	logger.Lock()
+	entries := logger.entries
+	logger.Unlock()
+	if len(entries) != 1 || entries[0] != "error error refreshing repository: backend unavailable" {
+		t.Errorf("Expected the refresh error to be logged to the logger, got %v", entries)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected nothing to be logged to logrus, got %s", logs.String())
+	}
+
+	// The nop logger discards every entry.
+	WithLogger(NewNopLogger())(client)
+	client.logRefresh(client.refreshPeriodic())
+	if logs.Len() != 0 {
+		t.Errorf("Expected nothing to be logged to logrus, got %s", logs.String())
+	}
+}
+
+func TestNewLogrusLogger(t *testing.T) {
+	var logs bytes.Buffer
+	base := logrus.New()
+	base.SetOutput(&logs)
+	base.SetLevel(logrus.InfoLevel)
+	logger := NewLogrusLogger(base)
+
+	logger.Debug("hidden", nil)
+	logger.Warn("config value out of range, clamping", LogFields{"config": "timeout"})
+	output := logs.String()
+	if strings.Contains(output, "hidden") {
+		t.Errorf("Expected debug entries to be filtered by the logrus level, got %s", output)
+	}
+	if !strings.Contains(output, "level=warning") || !strings.Contains(output, "config=timeout") {
+		t.Errorf("Expected the entry with its fields, got %s", output)
+	}
+}
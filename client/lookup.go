@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"reflect"
+)
+
+// lookupValue returns the configuration with the given name, or false if the
+// Client is closed, not ready or does not have it.
+func (c *Client) lookupValue(name string) (interface{}, bool) {
+	if c.isClosed.Load() {
+		return nil, false
+	}
+	if err := c.awaitReadiness(context.Background()); err != nil {
+		return nil, false
+	}
+	return c.getData(name)
+}
+
+// GetRaw returns a copy of the untyped value of the configuration with the
+// given name, as the typed getters see it, and whether it is present. The
+// copy shares no map or slice with the Client, so it can be modified freely,
+// and it is safe to call while the Client refreshes.
+func (c *Client) GetRaw(name string) (interface{}, bool) {
+	config, ok := c.lookupValue(name)
+	if !ok || config == nil {
+		return nil, ok
+	}
+	return deepCopy(reflect.ValueOf(config)).Interface(), true
+}
+
+// LookupString returns the string configuration with the given name and
+// whether it is present and a string.
+func (c *Client) LookupString(name string) (string, bool) {
+	config, _ := c.lookupValue(name)
+	value, ok := config.(string)
+	return value, ok
+}
+
+// LookupInt returns the int configuration with the given name and whether it
+// is present and an int.
+func (c *Client) LookupInt(name string) (int, bool) {
+	config, ok := c.lookupValue(name)
+	if !ok {
+		return 0, false
+	}
+	value, err := toInt(config)
+	return value, err == nil
+}
+
+// LookupFloat returns the float configuration with the given name and whether
+// it is present and a float.
+func (c *Client) LookupFloat(name string) (float64, bool) {
+	config, _ := c.lookupValue(name)
+	value, ok := config.(float64)
+	return value, ok
+}
+
+// LookupBool returns the bool configuration with the given name and whether
+// it is present and a bool.
+func (c *Client) LookupBool(name string) (bool, bool) {
+	config, _ := c.lookupValue(name)
+	value, ok := config.(bool)
+	return value, ok
+}
+
+// LookupStrings returns the string array configuration with the given name
+// and whether it is present and an array of strings.
+func (c *Client) LookupStrings(name string) ([]string, bool) {
+	config, _ := c.lookupValue(name)
+	items, ok := config.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		value, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		values = append(values, value)
+	}
+	return values, true
+}
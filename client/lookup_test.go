@@ -0,0 +1,91 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"name":    "John",
+		"age":     30,
+		"ratio":   0.5,
+		"enabled": true,
+		"tags":    []interface{}{"a", "b"},
+		"mixed":   []interface{}{"a", 1},
+	})
+
+	// Present with the right type.
+	if value, ok := client.LookupString("name"); !ok || value != "John" {
+		t.Errorf("Expected John, got %s (%t)", value, ok)
+	}
+	if value, ok := client.LookupInt("age"); !ok || value != 30 {
+		t.Errorf("Expected 30, got %d (%t)", value, ok)
+	}
+	if value, ok := client.LookupFloat("ratio"); !ok || value != 0.5 {
+		t.Errorf("Expected 0.5, got %f (%t)", value, ok)
+	}
+	if value, ok := client.LookupBool("enabled"); !ok || !value {
+		t.Errorf("Expected true, got %t (%t)", value, ok)
+	}
+	if value, ok := client.LookupStrings("tags"); !ok || !reflect.DeepEqual(value, []string{"a", "b"}) {
+		t.Errorf("Expected [a b], got %v (%t)", value, ok)
+	}
+
+	// Present with the wrong type, and absent.
+	for _, name := range []string{"missing", "mixed"} {
+		if _, ok := client.LookupString(name); ok {
+			t.Errorf("Expected LookupString(%s) not to be found", name)
+		}
+		if _, ok := client.LookupInt(name); ok {
+			t.Errorf("Expected LookupInt(%s) not to be found", name)
+		}
+		if _, ok := client.LookupFloat(name); ok {
+			t.Errorf("Expected LookupFloat(%s) not to be found", name)
+		}
+		if _, ok := client.LookupBool(name); ok {
+			t.Errorf("Expected LookupBool(%s) not to be found", name)
+		}
+		if _, ok := client.LookupStrings(name); ok {
+			t.Errorf("Expected LookupStrings(%s) not to be found", name)
+		}
+	}
+	if _, ok := client.LookupInt("name"); ok {
+		t.Errorf("Expected a string not to be found as an int")
+	}
+
+	client.Close()
+	if _, ok := client.LookupString("name"); ok {
+		t.Errorf("Expected nothing to be found after Close")
+	}
+}
+
+func TestGetRaw(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"name":     "John",
+		"database": map[string]interface{}{"host": "localhost", "ports": []interface{}{5432}},
+	})
+
+	if value, ok := client.GetRaw("name"); !ok || value != "John" {
+		t.Errorf("Expected John, got %v (%t)", value, ok)
+	}
+	if value, ok := client.GetRaw("database.host"); !ok || value != "localhost" {
+		t.Errorf("Expected the dotted path to resolve, got %v (%t)", value, ok)
+	}
+	if value, ok := client.GetRaw("missing"); ok || value != nil {
+		t.Errorf("Expected missing not to be found, got %v (%t)", value, ok)
+	}
+
+	// Modifying the copy leaves the Client's data untouched.
+	value, _ := client.GetRaw("database")
+	database := value.(map[string]interface{})
+	database["host"] = "remote"
+	database["ports"].([]interface{})[0] = 0
+	if host, _ := client.GetConfigString("database.host", ""); host != "localhost" {
+		t.Errorf("Expected the internal map to be unchanged, got %s", host)
+	}
+	value, _ = client.GetRaw("database.ports")
+	if !reflect.DeepEqual(value, []interface{}{5432}) {
+		t.Errorf("Expected the internal slice to be unchanged, got %v", value)
+	}
+}
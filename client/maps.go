@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetConfigMap retrieves the map configuration with the given name from the repository
+func GetConfigMap(name string, defaultValue map[string]interface{}) (map[string]interface{}, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigMap(name, defaultValue)
+}
+
+// GetConfigStringMap retrieves the map of strings configuration with the given name from the repository
+func GetConfigStringMap(name string, defaultValue map[string]string) (map[string]string, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigStringMap(name, defaultValue)
+}
+
+// GetConfigIntMap retrieves the map of ints configuration with the given name from the repository
+func GetConfigIntMap(name string, defaultValue map[string]int) (map[string]int, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigIntMap(name, defaultValue)
+}
+
+// GetConfigMap retrieves the map configuration with the given name from the
+// repository. Nested maps and lists are returned as decoded, and keys that
+// are not strings, such as the numbers or booleans of a YAML map, are
+// converted to their string form. The map is a copy, so callers may modify it.
+func (c *Client) GetConfigMap(name string, defaultValue map[string]interface{}) (_ map[string]interface{}, err error) {
+	defer c.observe("GetConfigMap", name, &err)
+	return c.getConfigMap(name, defaultValue)
+}
+
+// getConfigMap is GetConfigMap for getters built on it, which report the
+// read themselves.
+func (c *Client) getConfigMap(name string, defaultValue map[string]interface{}) (map[string]interface{}, error) {
+	config, ok, err := c.tenantData(context.Background(), name)
+	if err != nil {
+		return defaultValue, err
+	}
+	if !ok {
+		return defaultValue, c.notFound(name)
+	}
+	if config == nil {
+		return defaultValue, nil
+	}
+	configMap, ok := toStringKeyMap(config)
+	if !ok {
+		return defaultValue, typeMismatch(name, "a map")
+	}
+	return configMap, nil
+}
+
+// GetConfigStringMap retrieves the map configuration with the given name from
+// the repository as a map of strings. Numbers and booleans are converted to
+// their string form, while a nested map or list is a mismatch that returns
+// defaultValue.
+func (c *Client) GetConfigStringMap(name string, defaultValue map[string]string) (_ map[string]string, err error) {
+	defer c.observe("GetConfigStringMap", name, &err)
+	configMap, err := c.getConfigMap(name, nil)
+	if err != nil {
+		return defaultValue, err
+	}
+	output := make(map[string]string, len(configMap))
+	for key, value := range configMap {
+		switch value := value.(type) {
+		case string:
+			output[key] = value
+		case map[string]interface{}, map[interface{}]interface{}, []interface{}, nil:
+			return defaultValue, keyError(name, fmt.Errorf("%w: not a map of strings: %s is a %T", ErrTypeMismatch, key, value))
+		default:
+			output[key] = fmt.Sprint(value)
+		}
+	}
+	return output, nil
+}
+
+// GetConfigIntMap retrieves the map configuration with the given name from
+// the repository as a map of ints. Values are converted like GetConfigInt
+// does, and a value that is not an int is a mismatch that returns
+// defaultValue.
+func (c *Client) GetConfigIntMap(name string, defaultValue map[string]int) (_ map[string]int, err error) {
+	defer c.observe("GetConfigIntMap", name, &err)
+	configMap, err := c.getConfigMap(name, nil)
+	if err != nil {
+		return defaultValue, err
+	}
+	output := make(map[string]int, len(configMap))
+	for key, value := range configMap {
+		configInt, err := toInt(value)
+		if err != nil {
+			return defaultValue, keyError(name, fmt.Errorf("not a map of ints: %s: %w", key, err))
+		}
+		output[key] = configInt
+	}
+	return output, nil
+}
+
+// toStringKeyMap returns a copy of config with string keys, if config is a map.
+func toStringKeyMap(config interface{}) (map[string]interface{}, bool) {
+	switch configMap := config.(type) {
+	case map[string]interface{}:
+		output := make(map[string]interface{}, len(configMap))
+		for key, value := range configMap {
+			output[key] = value
+		}
+		return output, true
+	case map[interface{}]interface{}:
+		output := make(map[string]interface{}, len(configMap))
+		for key, value := range configMap {
+			output[fmt.Sprint(key)] = value
+		}
+		return output, true
+	}
+	return nil, false
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetConfigMap(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"limits": map[string]interface{}{"free": 10, "pro": int64(100), "enterprise": 1000.0},
+		"labels": map[interface{}]interface{}{"team": "payments", 7: true},
+		"database": map[string]interface{}{
+			"primary":  map[string]interface{}{"host": "db1", "port": 5432},
+			"replicas": []interface{}{"db2", "db3"},
+		},
+		"name": "John",
+	})
+
+	database, err := client.GetConfigMap("database", nil)
+	if err != nil {
+		t.Fatalf("Error getting map: %s", err.Error())
+	}
+	primary, ok := database["primary"].(map[string]interface{})
+	if !ok || primary["host"] != "db1" || primary["port"] != 5432 {
+		t.Errorf("Expected the nested map to be kept, got %v", database["primary"])
+	}
+	if replicas, ok := database["replicas"].([]interface{}); !ok || len(replicas) != 2 {
+		t.Errorf("Expected the nested list to be kept, got %v", database["replicas"])
+	}
+	// The map is a copy of the repository data.
+	database["primary"] = nil
+	if again, _ := client.GetConfigMap("database", nil); again["primary"] == nil {
+		t.Errorf("Expected changes to the returned map not to reach the repository")
+	}
+
+	labels, err := client.GetConfigStringMap("labels", nil)
+	expected := map[string]string{"team": "payments", "7": "true"}
+	if err != nil || !reflect.DeepEqual(labels, expected) {
+		t.Errorf("Expected labels to be %v, got %v (%v)", expected, labels, err)
+	}
+	limits, err := client.GetConfigIntMap("limits", nil)
+	expectedLimits := map[string]int{"free": 10, "pro": 100, "enterprise": 1000}
+	if err != nil || !reflect.DeepEqual(limits, expectedLimits) {
+		t.Errorf("Expected limits to be %v, got %v (%v)", expectedLimits, limits, err)
+	}
+}
+
+func TestGetConfigMapWrongType(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"name":     "John",
+		"database": map[string]interface{}{"primary": map[string]interface{}{"host": "db1"}},
+		"labels":   map[string]interface{}{"team": "payments"},
+	})
+	defaultMap := map[string]interface{}{"default": true}
+
+	if config, err := client.GetConfigMap("name", defaultMap); err == nil || !reflect.DeepEqual(config, defaultMap) {
+		t.Errorf("Expected the default for a string, got %v (%v)", config, err)
+	}
+	if config, err := client.GetConfigMap("missing", defaultMap); err == nil || !reflect.DeepEqual(config, defaultMap) {
+		t.Errorf("Expected the default for a missing config, got %v (%v)", config, err)
+	}
+	defaultStrings := map[string]string{"default": "yes"}
+	if config, err := client.GetConfigStringMap("database", defaultStrings); err == nil || !reflect.DeepEqual(config, defaultStrings) {
+		t.Errorf("Expected the default for a nested map, got %v (%v)", config, err)
+	}
+	defaultInts := map[string]int{"default": 1}
+	if config, err := client.GetConfigIntMap("labels", defaultInts); err == nil || !reflect.DeepEqual(config, defaultInts) {
+		t.Errorf("Expected the default for strings, got %v (%v)", config, err)
+	}
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"fmt"
+)
+
+// mustValue returns value, or panics with the error of the getter named
+// getter that read the configuration with the given name.
+func mustValue[T any](getter string, name string, value T, err error) T {
+	if err != nil {
+		panic(fmt.Sprintf("client: %s(%q): %s", getter, name, err.Error()))
+	}
+	return value
+}
+
+// MustGetConfigString is Client.MustGetConfigString on the default Client.
+func MustGetConfigString(name string) string {
+	value, err := GetConfigString(name, "")
+	return mustValue("MustGetConfigString", name, value, err)
+}
+
+// MustGetConfigInt is Client.MustGetConfigInt on the default Client.
+func MustGetConfigInt(name string) int {
+	value, err := GetConfigInt(name, 0)
+	return mustValue("MustGetConfigInt", name, value, err)
+}
+
+// MustGetConfigFloat is Client.MustGetConfigFloat on the default Client.
+func MustGetConfigFloat(name string) float64 {
+	value, err := GetConfigFloat(name, 0)
+	return mustValue("MustGetConfigFloat", name, value, err)
+}
+
+// MustGetConfigBool is Client.MustGetConfigBool on the default Client.
+func MustGetConfigBool(name string) bool {
+	value, err := GetConfigBool(name, false)
+	return mustValue("MustGetConfigBool", name, value, err)
+}
+
+// MustGetConfigArrayOfStrings is Client.MustGetConfigArrayOfStrings on the default Client.
+func MustGetConfigArrayOfStrings(name string) []string {
+	value, err := GetConfigArrayOfStrings(name, nil)
+	return mustValue("MustGetConfigArrayOfStrings", name, value, err)
+}
+
+// MustGetConfigString retrieves the string configuration with the given name
+// and panics if it is missing or is not a string. It is meant strictly for
+// initialization code, such as init functions, that cannot go on without the
+// configuration; use GetConfigString anywhere else.
+func (c *Client) MustGetConfigString(name string) string {
+	value, err := c.GetConfigString(name, "")
+	return mustValue("MustGetConfigString", name, value, err)
+}
+
+// MustGetConfigInt retrieves an int configuration with the given name
+// like GetConfigInt, and panics if it is missing or of another type.
+func (c *Client) MustGetConfigInt(name string) int {
+	value, err := c.GetConfigInt(name, 0)
+	return mustValue("MustGetConfigInt", name, value, err)
+}
+
+// MustGetConfigFloat retrieves a float configuration with the given name
+// like GetConfigFloat, and panics if it is missing or of another type.
+func (c *Client) MustGetConfigFloat(name string) float64 {
+	value, err := c.GetConfigFloat(name, 0)
+	return mustValue("MustGetConfigFloat", name, value, err)
+}
+
+// MustGetConfigBool retrieves a bool configuration with the given name
+// like GetConfigBool, and panics if it is missing or of another type.
+func (c *Client) MustGetConfigBool(name string) bool {
+	value, err := c.GetConfigBool(name, false)
+	return mustValue("MustGetConfigBool", name, value, err)
+}
+
+// MustGetConfigArrayOfStrings retrieves a string array configuration with the given name
+// like GetConfigArrayOfStrings, and panics if it is missing or of another type.
+func (c *Client) MustGetConfigArrayOfStrings(name string) []string {
+	value, err := c.GetConfigArrayOfStrings(name, nil)
+	return mustValue("MustGetConfigArrayOfStrings", name, value, err)
+}
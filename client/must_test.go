@@ -0,0 +1,74 @@
+package client
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// expectPanic calls fn and fails the test unless it panics with a message
+// containing every one of parts.
+func expectPanic(t *testing.T, fn func(), parts ...string) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		recovered := recover()
+		message, ok := recovered.(string)
+		if !ok {
+			t.Errorf("Expected a panic with a message, got %v", recovered)
+			return
+		}
+		for _, part := range parts {
+			if !strings.Contains(message, part) {
+				t.Errorf("Expected the panic message %q to contain %q", message, part)
+			}
+		}
+	}()
+	fn()
+}
+
+func TestMustGetters(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"name":    "John",
+		"age":     30,
+		"ratio":   0.5,
+		"enabled": true,
+		"tags":    []interface{}{"a", "b"},
+	})
+
+	if value := client.MustGetConfigString("name"); value != "John" {
+		t.Errorf("Expected John, got %s", value)
+	}
+	if value := client.MustGetConfigInt("age"); value != 30 {
+		t.Errorf("Expected 30, got %d", value)
+	}
+	if value := client.MustGetConfigFloat("ratio"); value != 0.5 {
+		t.Errorf("Expected 0.5, got %f", value)
+	}
+	if value := client.MustGetConfigBool("enabled"); !value {
+		t.Errorf("Expected true, got false")
+	}
+	if value := client.MustGetConfigArrayOfStrings("tags"); !reflect.DeepEqual(value, []string{"a", "b"}) {
+		t.Errorf("Expected [a b], got %v", value)
+	}
+
+	expectPanic(t, func() { client.MustGetConfigString("missing") }, "MustGetConfigString", `"missing"`, "config not found")
+	expectPanic(t, func() { client.MustGetConfigInt("name") }, "MustGetConfigInt", `"name"`)
+	expectPanic(t, func() { client.MustGetConfigFloat("name") }, "MustGetConfigFloat", "unexpected type")
+	expectPanic(t, func() { client.MustGetConfigBool("age") }, "MustGetConfigBool", "unexpected type")
+	expectPanic(t, func() { client.MustGetConfigArrayOfStrings("name") }, "MustGetConfigArrayOfStrings", "unexpected type")
+}
+
+func TestMustGettersDefaultClient(t *testing.T) {
+	previous := DefaultClient()
+	defer SetDefaultClient(previous)
+
+	SetDefaultClient(nil)
+	expectPanic(t, func() { MustGetConfigString("name") }, "no default client")
+
+	client, _ := newMapClient(t, map[string]interface{}{"name": "John"})
+	SetDefaultClient(client)
+	if value := MustGetConfigString("name"); value != "John" {
+		t.Errorf("Expected John, got %s", value)
+	}
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"github.com/divakarmanoj/go-remote-config/source"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// KeyNormalizer maps the name of a configuration to its canonical form, so
+// that names written in different styles find the same configuration.
+type KeyNormalizer func(key string) string
+
+// DefaultKeyNormalizer lower-cases key, separates camelCase words and turns
+// the separators ".", "-", "/" and spaces into "_", so that DATABASE_HOST,
+// database.host, database-host and databaseHost are all database_host.
+func DefaultKeyNormalizer(key string) string {
+	runes := []rune(key)
+	var builder strings.Builder
+	for i, r := range runes {
+		switch {
+		case r == '.' || r == '-' || r == '/' || r == ' ':
+			builder.WriteByte('_')
+		case unicode.IsUpper(r):
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				builder.WriteByte('_')
+			}
+			builder.WriteRune(unicode.ToLower(r))
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// indexKeys maps the canonical form of every key of the repository to the
+// key, for lookups by names in another style. When keys collide, the key
+// that sorts first wins and the others are only found by their exact name.
+// Repositories that do not implement source.KeyLister cannot be listed, so
+// their keys are only found by their exact name.
+func (c *Client) indexKeys() {
+	if c.keyNormalizer == nil {
+		return
+	}
+	lister, ok := c.Repository.(source.KeyLister)
+	if !ok {
+		c.log().Warn("repository cannot be listed, skipping key normalization", nil)
+		return
+	}
+	keys := lister.Keys()
+	sort.Strings(keys)
+	index := make(map[string]string, len(keys))
+	for _, key := range keys {
+		canonical := c.keyNormalizer(key)
+		if kept, ok := index[canonical]; ok {
+			c.log().Warn("keys collide after normalization, keeping the first", LogFields{"key": canonical, "kept": kept, "ignored": key})
+			continue
+		}
+		index[canonical] = key
+	}
+	c.keyIndex.Store(&index)
+}
+
+// sourceKey returns the key of the repository whose canonical form is that of
+// name, and whether there is one other than name itself.
+func (c *Client) sourceKey(name string) (string, bool) {
+	if c.keyNormalizer == nil {
+		return "", false
+	}
+	index := c.keyIndex.Load()
+	if index == nil {
+		return "", false
+	}
+	key, ok := (*index)[c.keyNormalizer(name)]
+	return key, ok && key != name
+}
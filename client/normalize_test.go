@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultKeyNormalizer(t *testing.T) {
+	for _, key := range []string{"DATABASE_HOST", "database.host", "database-host", "databaseHost", "Database/Host"} {
+		if canonical := DefaultKeyNormalizer(key); canonical != "database_host" {
+			t.Errorf("Expected %s to normalize to database_host, got %s", key, canonical)
+		}
+	}
+}
+
+func TestWithKeyNormalizer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "DATABASE_HOST: localhost\nserverPort: 8080\nlog-level: debug\nretry:\n  count: 3\nTIMEOUT: 5\ntimeout: 10\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	logger := &recordingLogger{}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, time.Hour, WithKeyNormalizer(nil), WithLogger(logger))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	if value, _ := client.GetConfigString("database.host", ""); value != "localhost" {
+		t.Errorf("Expected database.host to find DATABASE_HOST, got %q", value)
+	}
+	if value, _ := client.GetConfigInt("server_port", 0); value != 8080 {
+		t.Errorf("Expected server_port to find serverPort, got %d", value)
+	}
+	if value, _ := client.View().GetConfigString("LOG_LEVEL", ""); value != "debug" {
+		t.Errorf("Expected LOG_LEVEL to find log-level in a View, got %q", value)
+	}
+	// Dotted paths into nested configurations still resolve.
+	if value, _ := client.GetConfigInt("retry.count", 0); value != 3 {
+		t.Errorf("Expected the dotted path to resolve, got %d", value)
+	}
+
+	// Of colliding keys the one that sorts first wins, and exact names still
+	// find the others.
+	if value, _ := client.GetConfigInt("Timeout", 0); value != 5 {
+		t.Errorf("Expected TIMEOUT to win the collision, got %d", value)
+	}
+	if value, _ := client.GetConfigInt("timeout", 0); value != 10 {
+		t.Errorf("Expected the exact name to be found, got %d", value)
+	}
+	collisions := 0
+	for _, entry := range logger.entries {
+		if strings.Contains(entry, "keys collide after normalization") {
+			collisions++
+		}
+	}
+	if collisions != 1 {
+		t.Errorf("Expected the collision to be logged once, got %v", logger.entries)
+	}
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/divakarmanoj/go-remote-config/source"
+)
+
+// watchRepository registers the Client with the repository when it is a
+// source.Notifier, and applies the changes it notifies until ctx is
+// canceled, so that changes loaded by a watch or a stream between refreshes
+// reach the cache, the snapshot transforms, the subscribers and the prefetched
+// configurations as a refresh would.
+func (c *Client) watchRepository(ctx context.Context) {
+	notifier, ok := c.Repository.(source.Notifier)
+	if !ok {
+		return
+	}
+	c.notified = make(chan struct{}, 1)
+	notifier.NotifyChanges(c.notifyChange)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.notified:
+				err := c.applyNotified()
+				if errors.Is(err, errRefreshPaused) || errors.Is(err, ErrClientClosed) {
+					continue
+				}
+				if err != nil {
+					c.log().Error("error applying change of repository", LogFields{"error": err})
+				}
+			}
+		}
+	}()
+}
+
+// notifyChange signals that the repository loaded a change on its own.
+// Changes notified while one is applied are applied together, and the
+// repository is never held up.
+func (c *Client) notifyChange() {
+	select {
+	case c.notified <- struct{}{}:
+	default:
+	}
+}
+
+// applyNotified updates the state the Client derives from the repository
+// data, which the repository has already loaded, without refreshing the
+// repository. It returns errRefreshPaused without updating while the Client
+// is paused, as the refresh of Resume picks the change up.
+func (c *Client) applyNotified() error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if c.paused.Load() {
+		return errRefreshPaused
+	}
+	return c.update(!c.validateOnStartOnly, func() error {
+		return nil
+	})
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// notifyingRepository is a mapRepository that loads changes on its own and
+// notifies them like a watch would.
+type notifyingRepository struct {
+	mapRepository
+	notify func()
+}
+
+func (n *notifyingRepository) NotifyChanges(fn func()) {
+	n.notify = fn
+}
+
+func (n *notifyingRepository) Keys() []string {
+	n.RLock()
+	defer n.RUnlock()
+	keys := make([]string, 0, len(n.data))
+	for key := range n.data {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestRepositoryNotifier(t *testing.T) {
+	repository := &notifyingRepository{mapRepository: mapRepository{data: map[string]interface{}{
+		"db": map[string]interface{}{"host": "primary"},
+	}}}
+	client, err := NewClient(context.Background(), repository, time.Hour, WithTransform(ExpandEnv))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	var db struct {
+		Host string `yaml:"host"`
+	}
+	if err := client.GetConfig("db", &db, nil); err != nil || db.Host != "primary" {
+		t.Fatalf("Expected the host primary, got %q (%v)", db.Host, err)
+	}
+	changes := make(chan interface{}, 1)
+	client.Subscribe("db", func(oldVal, newVal interface{}) {
+		changes <- newVal
+	})
+
+	// A change the repository loads on its own reaches the cache, the
+	// snapshot and the subscribers without a refresh of the Client.
+	repository.set("db", map[string]interface{}{"host": "replica"})
+	repository.notify()
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected the notified change to reach the subscribers")
+	}
+	if err := client.GetConfig("db", &db, nil); err != nil || db.Host != "replica" {
+		t.Errorf("Expected the notified host replica, got %q (%v)", db.Host, err)
+	}
+}
+
+func TestRepositoryNotifierFileWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("db:\n  host: primary\n"), 0o600); err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	repository := &source.FileRepository{Name: "file", Path: path, Watch: true}
+	client, err := NewClient(context.Background(), repository, time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	var db struct {
+		Host string `yaml:"host"`
+	}
+	if err := client.GetConfig("db", &db, nil); err != nil || db.Host != "primary" {
+		t.Fatalf("Expected the host primary, got %q (%v)", db.Host, err)
+	}
+
+	// An edit picked up by the watch replaces the decoded value.
+	if err := os.WriteFile(path, []byte("db:\n  host: replica\n"), 0o600); err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if err := client.GetConfig("db", &db, nil); err == nil && db.Host == "replica" {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Errorf("Expected the watched host replica, got %q", db.Host)
+}
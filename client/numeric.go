@@ -0,0 +1,104 @@
+package client
+
+import (
+	"fmt"
+	"math"
+)
+
+// errNotInt is returned by toInt for values that are not integers.
+var errNotInt = fmt.Errorf("%w: config is not an integer", ErrTypeMismatch)
+
+// errIntOutOfRange is returned by toInt for integers that do not fit in an int.
+var errIntOutOfRange = fmt.Errorf("%w: config is out of the range of an int", ErrTypeMismatch)
+
+// toInt converts a configuration value of any integer type, or a float with
+// no fractional part, to an int, as sources decode integers into different
+// types.
+func toInt(config interface{}) (int, error) {
+	switch value := config.(type) {
+	case int:
+		return value, nil
+	case int8:
+		return int(value), nil
+	case int16:
+		return int(value), nil
+	case int32:
+		return int(value), nil
+	case int64:
+		if value < math.MinInt || value > math.MaxInt {
+			return 0, errIntOutOfRange
+		}
+		return int(value), nil
+	case uint:
+		if value > math.MaxInt {
+			return 0, errIntOutOfRange
+		}
+		return int(value), nil
+	case uint8:
+		return int(value), nil
+	case uint16:
+		return int(value), nil
+	case uint32:
+		if uint64(value) > math.MaxInt {
+			return 0, errIntOutOfRange
+		}
+		return int(value), nil
+	case uint64:
+		if value > math.MaxInt {
+			return 0, errIntOutOfRange
+		}
+		return int(value), nil
+	case float32:
+		return floatToInt(float64(value))
+	case float64:
+		return floatToInt(value)
+	}
+	return 0, errNotInt
+}
+
+// floatToInt converts a float with no fractional part to an int.
+func floatToInt(value float64) (int, error) {
+	if math.Trunc(value) != value {
+		return 0, errNotInt
+	}
+	// float64(math.MaxInt) rounds up to 2^63, which no longer fits.
+	if value < math.MinInt || value >= math.MaxInt {
+		return 0, errIntOutOfRange
+	}
+	return int(value), nil
+}
+
+// errNotFloat is returned by toFloat for values that are not numbers.
+var errNotFloat = fmt.Errorf("%w: config is not a float", ErrTypeMismatch)
+
+// toFloat converts a configuration value of any integer or float type to a
+// float64, as sources decode numbers into different types.
+func toFloat(config interface{}) (float64, error) {
+	switch value := config.(type) {
+	case float64:
+		return value, nil
+	case float32:
+		return float64(value), nil
+	case int:
+		return float64(value), nil
+	case int8:
+		return float64(value), nil
+	case int16:
+		return float64(value), nil
+	case int32:
+		return float64(value), nil
+	case int64:
+		return float64(value), nil
+	case uint:
+		return float64(value), nil
+	case uint8:
+		return float64(value), nil
+	case uint16:
+		return float64(value), nil
+	case uint32:
+		return float64(value), nil
+	case uint64:
+		return float64(value), nil
+	}
+	return 0, errNotFloat
+}
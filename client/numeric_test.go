@@ -0,0 +1,55 @@
+package client
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestGetConfigIntNumericTypes(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"int":      int(1),
+		"int8":     int8(-8),
+		"int16":    int16(16),
+		"int32":    int32(-32),
+		"int64":    int64(64),
+		"uint":     uint(7),
+		"uint8":    uint8(8),
+		"uint16":   uint16(16),
+		"uint32":   uint32(32),
+		"uint64":   uint64(64),
+		"float32":  float32(3),
+		"float64":  float64(-4),
+		"fraction": 1.5,
+		"huge":     uint64(math.MaxUint64),
+		"big":      1e30,
+		"nan":      math.NaN(),
+		"string":   "5",
+	})
+	tests := map[string]int{
+		"int": 1, "int8": -8, "int16": 16, "int32": -32, "int64": 64,
+		"uint": 7, "uint8": 8, "uint16": 16, "uint32": 32, "uint64": 64,
+		"float32": 3, "float64": -4,
+	}
+	for name, expected := range tests {
+		value, err := client.GetConfigInt(name, 0)
+		if err != nil || value != expected {
+			t.Errorf("Expected %s to be %d, got %d (%v)", name, expected, value, err)
+		}
+	}
+	for _, name := range []string{"fraction", "nan", "string"} {
+		value, err := client.GetConfigInt(name, 9)
+		if !errors.Is(err, errNotInt) || value != 9 {
+			t.Errorf("Expected errNotInt and the default for %s, got %d (%v)", name, value, err)
+		}
+	}
+	for _, name := range []string{"huge", "big"} {
+		value, err := client.GetConfigInt(name, 9)
+		if !errors.Is(err, errIntOutOfRange) || value != 9 {
+			t.Errorf("Expected errIntOutOfRange and the default for %s, got %d (%v)", name, value, err)
+		}
+	}
+	if value, ok := client.LookupInt("uint16"); !ok || value != 16 {
+		t.Errorf("Expected LookupInt to convert uint16, got %d (%t)", value, ok)
+	}
+}
@@ -0,0 +1,518 @@
+package client
+
+import (
+	"github.com/divakarmanoj/go-remote-config/source"
+	"math/rand"
+	"time"
+)
+
+// Option configures a Client created with NewClient.
+type Option func(*Client)
+
+// WithDeploymentColor makes the Client prefer keys suffixed with the given
+// deployment color, so `db_host@green` overrides `db_host` when the color is
+// "green". Keys without a variant for the color fall back to the base key.
+func WithDeploymentColor(color string) Option {
+	return func(c *Client) {
+		c.deploymentColor = color
+	}
+}
+
+// WithObfuscatedErrors sanitizes refresh errors before they are logged or
+// returned, stripping credentials and query parameters from any URL they
+// mention. When hideHosts is true the host of each URL is redacted as well.
+func WithObfuscatedErrors(hideHosts bool) Option {
+	return func(c *Client) {
+		c.obfuscateErrors = true
+		c.obfuscateHosts = hideHosts
+	}
+}
+
+// WithReadinessGate makes NewClient return without waiting for the first
+// refresh, which then runs in the background. Until it succeeds, getters block
+// for up to timeout and then return their default value with ErrNotReady.
+func WithReadinessGate(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.readinessGate = true
+		c.readyTimeout = timeout
+	}
+}
+
+// WithValueTransformer adds a transformer applied to the value of every
+// configuration when the repository is loaded, before the values are decoded
+// or validated. Transformers run in the order they were added and also see
+// deployment color variants under their full key, such as `db_host@green`.
+func WithValueTransformer(transformer ValueTransformer) Option {
+	return func(c *Client) {
+		c.transformers = append(c.transformers, transformer)
+	}
+}
+
+// WithTransform adds a transform run on all configurations after each
+// refresh, once the value transformers have run and before the snapshot
+// validators and anything else see the new values. Unlike a
+// ValueTransformer, it sees every configuration at once, so it can expand
+// references between them. Transforms run in the order they were added; when
+// one returns an error the refresh fails with that error and getters keep
+// serving the last accepted values. ExpandEnv and ExpandReferences are
+// built in; add ExpandEnv first, so that references to environment variables
+// are expanded before ExpandReferences looks them up as configurations.
+// Transforms only run for repositories that implement source.KeyLister.
+func WithTransform(transform SnapshotTransform) Option {
+	return func(c *Client) {
+		c.snapshotTransforms = append(c.snapshotTransforms, transform)
+	}
+}
+
+// WithRefreshListener adds a listener called after every refresh, successful
+// or not, with the timing, error and changed keys of the refresh. Changed keys
+// are only reported for repositories that implement source.KeyLister.
+func WithRefreshListener(listener RefreshListener) Option {
+	return func(c *Client) {
+		c.events.subscribe(listener)
+	}
+}
+
+// WithReadListener adds a listener called with every read of a configuration
+// by a getter, with the name of the configuration and whether and why the
+// getter fell back to its default value, for example to count the keys read
+// most or the reads that miss. Getters built on other getters, such as
+// GetConfigIntClamped, report the read of the getter they are built on. A
+// Client without read listeners pays nothing for them.
+func WithReadListener(listener ReadListener) Option {
+	return func(c *Client) {
+		c.readListeners = append(c.readListeners, listener)
+	}
+}
+
+// WithAuditHook adds a hook called with every read of a configuration by a
+// getter, with its name and whether the getter found it, for example to keep
+// an access log of secret-bearing keys. It is a ReadListener reduced to what
+// an access log needs, and like read listeners it costs nothing when no hook
+// is added.
+func WithAuditHook(hook AuditHook) Option {
+	return func(c *Client) {
+		c.readListeners = append(c.readListeners, auditListener(hook))
+	}
+}
+
+// WithSensitiveKeys marks the configurations with the given names as
+// sensitive, such as those holding secrets, so that their values are never
+// logged by the Client: the errors getters and Prefetch return for them, and
+// the errors and values the Client logs about them, say what went wrong
+// without quoting the value. Dotted paths into a sensitive configuration are
+// sensitive too. It can be given more than once.
+func WithSensitiveKeys(keys ...string) Option {
+	return func(c *Client) {
+		if c.sensitiveKeys == nil {
+			c.sensitiveKeys = map[string]bool{}
+		}
+		for _, key := range keys {
+			c.sensitiveKeys[key] = true
+		}
+	}
+}
+
+// WithChangeSummary calls handler after every successful refresh with the
+// keys it added, removed and changed, found by comparing the values before
+// and after the refresh, for example to write a single audit log line. Unlike
+// a RefreshListener, handler runs off the refreshing goroutine, so it may
+// block, and it is called once per refresh, in order. Keys are only reported
+// for repositories that implement source.KeyLister.
+func WithChangeSummary(handler ChangeSummaryHandler) Option {
+	return func(c *Client) {
+		summary := &changeSummary{handler: handler}
+		c.events.subscribe(summary.listen)
+	}
+}
+
+// WithOptionalKeys marks configurations that may legitimately be missing.
+// Getters return the default value without an error for a missing optional
+// key, and Prefetch does not report it, while missing required keys still
+// produce an error.
+func WithOptionalKeys(keys ...string) Option {
+	return func(c *Client) {
+		if c.optionalKeys == nil {
+			c.optionalKeys = map[string]bool{}
+		}
+		for _, key := range keys {
+			c.optionalKeys[key] = true
+		}
+	}
+}
+
+// WithTransformKeyErrors maps every refresh error with transform before it is
+// logged or returned. Pass source.NormalizeError to tag backend errors with
+// the source error kinds, so callers can write
+// errors.Is(err, source.ErrSourceNotFound) whatever the repository.
+func WithTransformKeyErrors(transform func(error) error) Option {
+	return func(c *Client) {
+		c.transformKeyErrors = transform
+	}
+}
+
+// WithCache replaces the map the Client caches encoded configurations in, for
+// example with a size-bounded LRU. Passing nil disables caching.
+func WithCache(cache Cache) Option {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// WithClampLogging logs a warning whenever GetConfigIntClamped or
+// GetConfigFloatClamped clamps an out-of-range value.
+func WithClampLogging() Option {
+	return func(c *Client) {
+		c.logClamping = true
+	}
+}
+
+// WithShutdownHook adds a hook that Close runs once the refresh goroutine has
+// stopped, for example to flush a cache file or emit a final metric. Hooks run
+// in the order they were added, and only on the first call to Close or
+// Shutdown, after the repository is closed.
+func WithShutdownHook(hook func()) Option {
+	return func(c *Client) {
+		c.shutdownHooks = append(c.shutdownHooks, hook)
+	}
+}
+
+// WithValidateOnStartOnly validates the repository data, such as the decoding
+// of Prefetch targets, only on the initial load and on explicit refreshes
+// through ForceRefresh or RefreshHandler, not on every periodic refresh. This
+// saves the cost of validating large configurations that rarely change, at
+// the price of safety: invalid data loaded by a periodic refresh is not
+// reported by the refresh, and only surfaces as an error when it is read.
+func WithValidateOnStartOnly() Option {
+	return func(c *Client) {
+		c.validateOnStartOnly = true
+	}
+}
+
+// WithSeedFromEnv imports the environment variables whose name starts with
+// prefix as configurations beneath the repository, which overrides them. The
+// prefix is stripped, and names are lowercased and nested by separator, so
+// with the prefix APP_ and the separator _, APP_DB_HOST sets the host field
+// of the db configuration. When NewClient is given a nil repository the
+// environment is the repository. See source.EnvRepository.
+func WithSeedFromEnv(prefix string, separator string) Option {
+	return func(c *Client) {
+		env := &source.EnvRepository{Name: "env", Prefix: prefix, Separator: separator}
+		if c.Repository == nil {
+			c.Repository = env
+			return
+		}
+		c.Repository = source.NewChainRepository(c.Repository.GetName(), []source.Repository{c.Repository, env})
+	}
+}
+
+// WithInitialData seeds the Client with data, a map of configuration names to
+// their values, beneath the repository, which overrides them once it has
+// loaded. When NewClient is given a nil repository the data is all the Client
+// serves, which lets tests and air-gapped deployments inject fixtures without
+// any source. The map must not be modified afterwards.
+func WithInitialData(data map[string]interface{}) Option {
+	return func(c *Client) {
+		c.seed(data)
+	}
+}
+
+// WithoutInitialRefresh makes NewClient return without refreshing the
+// repository, so that it neither blocks on nor fails because of an
+// unreachable source, and serves the data seeded with WithInitialData until
+// the first refresh of the background goroutine, one refresh interval later.
+// WaitReady and Healthy still wait for that refresh.
+func WithoutInitialRefresh() Option {
+	return func(c *Client) {
+		c.skipInitialRefresh = true
+	}
+}
+
+// WithInitialRefreshRetries makes the refresh NewClient blocks on try up to
+// attempts times before NewClient fails, so that a source that is briefly
+// unavailable at boot does not fail the start. The wait between attempts is
+// backoff after the first failure and doubles after each further one.
+// NewClient stops retrying with the error of its context once that is done.
+func WithInitialRefreshRetries(attempts int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.initialAttempts = attempts
+		c.initialBackoff = backoff
+	}
+}
+
+// WithDegradedStart makes NewClient succeed even if its refresh fails, after
+// any retries of WithInitialRefreshRetries. The failure is logged, getters
+// return their defaults and Healthy reports the Client unhealthy until the
+// background goroutine refreshes the repository successfully.
+func WithDegradedStart() Option {
+	return func(c *Client) {
+		c.degradedStart = true
+	}
+}
+
+// WithLastGoodFile persists the configuration to the file at path after
+// every successful refresh that changes it, so that the Client can start from
+// it when the source is unavailable. The file is written to a temporary file
+// renamed over it, so a crash never leaves it partially written. When the
+// refresh NewClient blocks on fails, after any retries of
+// WithInitialRefreshRetries, the Client seeds the file beneath the repository
+// like WithInitialData and NewClient succeeds. Healthy reports the Client
+// unhealthy until a refresh succeeds, which also removes the file from
+// beneath the repository, so that keys deleted at the source are no longer
+// served. Only repositories that implement source.KeyLister can be persisted.
+//
+// The file holds the configuration as the repository serves it, before any
+// WithDecryptor or other value transformers run, so values encrypted at the
+// source stay encrypted on disk but every other secret is written in plain
+// text. The file is only readable by its owner; keep it on a volume no other
+// workload can read, and do not use this option for configurations whose
+// secrets must never reach the disk.
+func WithLastGoodFile(path string) Option {
+	return func(c *Client) {
+		c.lastGoodPath = path
+	}
+}
+
+// WithKeyNormalizer makes getters find configurations by names in another
+// style than the repository's, such as database.host for DATABASE_HOST. Every
+// key of the repository is mapped to its canonical form by normalizer on each
+// refresh, and a name that is not a key itself finds the key with the same
+// canonical form. A nil normalizer uses DefaultKeyNormalizer. When several
+// keys have the same canonical form, the one that sorts first, byte-wise,
+// wins and a warning is logged; the others are still found by their exact
+// name. Only repositories that implement source.KeyLister are normalized.
+func WithKeyNormalizer(normalizer KeyNormalizer) Option {
+	return func(c *Client) {
+		if normalizer == nil {
+			normalizer = DefaultKeyNormalizer
+		}
+		c.keyNormalizer = normalizer
+	}
+}
+
+// WithClock replaces the clock the Client tells the time with, which decides
+// for instance when scheduled configurations become effective and how stale
+// the data is. When clock is a TimerClock, the refresh loop and the startup
+// retries also wait on it, so a configtest.FakeClock drives refreshes.
+func WithClock(clock Clock) Option {
+	return func(c *Client) {
+		c.clock = clock
+	}
+}
+
+// WithMaxConcurrentGets bounds the number of GetData calls the Client makes
+// on the repository at once to limit, queueing the excess reads, so that a
+// burst of reads of uncached keys does not overload a read-through backend.
+// It defaults to DefaultMaxConcurrentGets for source.ReadThrough repositories
+// and to no limit otherwise. A negative limit disables the bound.
+func WithMaxConcurrentGets(limit int) Option {
+	return func(c *Client) {
+		c.maxConcurrentGets = limit
+	}
+}
+
+// WithTenantResolver resolves the tenant of the context passed to the context
+// getters, such as GetConfigStringContext, which then prefer the tenant's
+// value under `tenants.<id>.<name>` and fall back to the shared value.
+func WithTenantResolver(resolver TenantResolver) Option {
+	return func(c *Client) {
+		c.tenantResolver = resolver
+	}
+}
+
+// WithSnapshotValidator adds a validator run on every configuration after each
+// refresh, before anything else sees the new values. When it returns an error
+// the refresh fails with that error and getters keep serving the last accepted
+// values. Validators only run for repositories that implement
+// source.KeyLister, and are skipped on periodic refreshes with
+// WithValidateOnStartOnly.
+func WithSnapshotValidator(validator SnapshotValidator) Option {
+	return func(c *Client) {
+		c.snapshotValidators = append(c.snapshotValidators, validator)
+	}
+}
+
+// WithEagerTypeCheck checks on every refresh that the given configurations,
+// registered with RegisterInt, RegisterString and the like, have their
+// expected type, so type drift surfaces when it is loaded rather than when a
+// getter is called. A refresh that changes the type of a registered
+// configuration fails with ErrTypeMismatch and is rejected like one rejected
+// by WithSnapshotValidator, with the same conditions.
+func WithEagerTypeCheck(checks ...TypeCheck) Option {
+	return func(c *Client) {
+		c.snapshotValidators = append(c.snapshotValidators, c.typeCheckValidator(checks))
+	}
+}
+
+// WithSchema checks on every refresh that the configuration with the given
+// name decodes into prototype, a struct or a pointer to one, the way
+// GetConfig decodes it, that the fields tagged `validate:"required"` are set,
+// and that the decoded value passes its Validate method if it implements
+// SchemaValidator. A refresh with a configuration that is missing, unless it
+// is optional, or that does not match fails with ErrSchemaMismatch, which
+// LastRefresh reports, and is rejected like one rejected by
+// WithSnapshotValidator, with the same conditions.
+func WithSchema(name string, prototype interface{}) Option {
+	return func(c *Client) {
+		c.snapshotValidators = append(c.snapshotValidators, c.schemaValidator(name, prototype))
+	}
+}
+
+// WithDecryptor decrypts the string values starting with prefix, which
+// defaults to DefaultEncryptedPrefix, with decryptor when they are loaded, so
+// that getters return the plaintext. Values nested in maps and lists are
+// decrypted too. The getters of a configuration with a value that cannot be
+// decrypted return their default and an error wrapping ErrDecrypt, never the
+// ciphertext. Decryption runs like a ValueTransformer added at this point.
+func WithDecryptor(prefix string, decryptor Decryptor) Option {
+	return func(c *Client) {
+		if prefix == "" {
+			prefix = DefaultEncryptedPrefix
+		}
+		c.transformers = append(c.transformers, decryptTransformer(prefix, decryptor))
+	}
+}
+
+// WithCompactLogging logs refreshes only when their outcome changes, that is
+// on the first success, the first failure after a success and the first
+// success after failures, instead of logging every failed refresh, so that an
+// unreachable source does not flood the logs. When summaryInterval is
+// positive, a summary of the successes and failures is also logged on the
+// first refresh after each summaryInterval; while refreshes keep failing it
+// is instead a warning that the refresh is still failing, with the latest
+// error, the number of attempts and how long the outage has lasted, which is
+// also logged on recovery.
+func WithCompactLogging(summaryInterval time.Duration) Option {
+	return func(c *Client) {
+		c.compactLog = &compactLog{summaryInterval: summaryInterval}
+	}
+}
+
+// WithRefreshBackoff sets how the refresh loop backs off after failed
+// refreshes: each consecutive failure multiplies the wait before the next
+// refresh by multiplier, up to maxBackoff, and the wait is jittered so that
+// clients do not retry in lockstep. A successful refresh returns to the
+// refresh interval. Zero values select DefaultMaxRefreshBackoff and
+// DefaultRefreshBackoffMultiplier, and any other multiplier of at most 1
+// disables the backoff.
+func WithRefreshBackoff(maxBackoff time.Duration, multiplier float64) Option {
+	return func(c *Client) {
+		c.maxBackoff = maxBackoff
+		c.backoffMultiplier = multiplier
+	}
+}
+
+// WithRefreshJitter desynchronizes the refreshes of instances started at the
+// same time, so that they do not all reach the backend at once. The first
+// wait of the refresh loop is extended by a random delay of up to
+// startupJitter, and every wait of a refresh interval is moved earlier or
+// later by a random fraction of the interval of up to intervalJitter, such as
+// 0.1 for 10%. The refresh of NewClient is not delayed. intervalJitter is
+// capped at 0.5, so that no wait is shorter than half the interval.
+func WithRefreshJitter(startupJitter time.Duration, intervalJitter float64) Option {
+	return func(c *Client) {
+		if intervalJitter > 0.5 {
+			intervalJitter = 0.5
+		}
+		c.startupJitter = startupJitter
+		c.intervalJitter = intervalJitter
+	}
+}
+
+// WithRandSource sets the random source of the jitter of the refresh loop,
+// for example a seeded one in tests. It is only used by the refresh loop, so
+// it need not be safe for concurrent use.
+func WithRandSource(source rand.Source) Option {
+	return func(c *Client) {
+		c.random = rand.New(source)
+	}
+}
+
+// WithLogger sends the log entries of the Client to logger instead of the
+// standard logrus logger.
+func WithLogger(logger Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithCodec converts configuration values to the types getters such as
+// GetConfig decode them into with codec instead of source.YAMLCodec. With
+// source.JSONCodec, values are decoded with encoding/json, so structs are
+// matched by their json tags and numbers keep the types of the fields they
+// are decoded into.
+func WithCodec(codec source.MarshalCodec) Option {
+	return func(c *Client) {
+		c.codec = codec
+	}
+}
+
+// WithCodecPerKey parses the string values of the configurations named in
+// codecs with their codec when they are loaded, so that a source holding
+// YAML can carry a few keys whose values are JSON documents, such as
+// `rules: '{"limit": 10}'`, that getters then read like any other map. Values
+// that are not strings and unlisted keys are left as they are. The getters
+// of a configuration whose value cannot be parsed return their default and
+// an error wrapping ErrTypeMismatch. Parsing runs like a ValueTransformer
+// added at this point. The map must not be modified afterwards.
+func WithCodecPerKey(codecs map[string]source.Codec) Option {
+	return func(c *Client) {
+		c.transformers = append(c.transformers, keyCodecTransformer(codecs))
+	}
+}
+
+// WithScalarCoercion makes GetConfig, and the getters built on it such as
+// Get, retry a value that fails to decode after coercing its scalars to the
+// types they are decoded into, so that "8080" fills an int field, 8080 a
+// string field and "true" or 1 a bool field. Only conversions that parse
+// exactly are made, so "eighty" still fails to decode into an int. Without
+// it values are decoded strictly.
+func WithScalarCoercion() Option {
+	return func(c *Client) {
+		c.coerceScalars = true
+	}
+}
+
+// WithTimeLayouts parses the strings GetConfigTime reads with the given
+// layouts, tried in order, instead of time.RFC3339 alone. Include
+// time.RFC3339 to keep accepting it.
+func WithTimeLayouts(layouts ...string) Option {
+	return func(c *Client) {
+		c.timeLayouts = layouts
+	}
+}
+
+// WithMaxStaleness sets how long after the last successful refresh Healthy
+// keeps reporting the Client healthy. Failed refreshes within that window are
+// tolerated, while a refresh loop that has not succeeded for longer makes the
+// Client unhealthy even without an error. Without it, Healthy fails as soon
+// as the last refresh failed and never reports the data as stale.
+func WithMaxStaleness(maxStaleness time.Duration) Option {
+	return func(c *Client) {
+		c.maxStaleness = maxStaleness
+	}
+}
+
+// WithMaxStaleAge stops getters from serving the data of the last successful
+// refresh once it is older than maxStaleAge while the refreshes since then
+// fail. They return their default value and an error wrapping ErrStaleConfig
+// instead, until a refresh succeeds again. Data is never stale while the
+// refreshes succeed, however old it is, and until the first one succeeds.
+func WithMaxStaleAge(maxStaleAge time.Duration) Option {
+	return func(c *Client) {
+		c.maxStaleAge = maxStaleAge
+	}
+}
+
+// WithRefreshTimeout bounds the time every refresh of the repository may take,
+// so that a source that hangs does not freeze the refresh loop. Repositories
+// that implement source.ContextRefresher refresh with a context that is done
+// after timeout. The refresh of other repositories is abandoned after timeout
+// and logged as stuck, and no other refresh starts until it returns. Either
+// way the refresh fails with an error wrapping ErrRefreshTimeout, or the error
+// of the context, and the current data is kept.
+func WithRefreshTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.refreshTimeout = timeout
+	}
+}
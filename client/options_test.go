@@ -0,0 +1,221 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithDeploymentColor(t *testing.T) {
+	repository := &mapRepository{data: map[string]interface{}{
+		"db_host":       "db.internal",
+		"db_host@green": "db-green.internal",
+		"db_host@blue":  "db-blue.internal",
+		"timeout":       30,
+	}}
+	testCases := []struct {
+		color string
+		want  string
+	}{
+		{color: "green", want: "db-green.internal"},
+		{color: "blue", want: "db-blue.internal"},
+		{color: "red", want: "db.internal"},
+		{color: "", want: "db.internal"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.color, func(t *testing.T) {
+			client, err := NewClient(context.Background(), repository, 10*time.Second, WithDeploymentColor(tc.color))
+			if err != nil {
+				t.Fatalf("Error creating client: %s", err.Error())
+			}
+			defer client.Close()
+			host, err := client.GetConfigString("db_host", "")
+			if err != nil {
+				t.Errorf("Error getting db_host: %s", err.Error())
+			}
+			if host != tc.want {
+				t.Errorf("Expected db_host to be %s, got %s", tc.want, host)
+			}
+			timeout, err := client.GetConfigInt("timeout", 0)
+			if err != nil {
+				t.Errorf("Error getting timeout: %s", err.Error())
+			}
+			if timeout != 30 {
+				t.Errorf("Expected timeout to be 30, got %d", timeout)
+			}
+		})
+	}
+}
+
+// blockingRepository is a Repository whose Refresh blocks until release is closed.
+type blockingRepository struct {
+	mapRepository
+	release chan struct{}
+}
+
+func (b *blockingRepository) Refresh() error {
+	<-b.release
+	return nil
+}
+
+func TestWithReadinessGate(t *testing.T) {
+	repository := &blockingRepository{
+		mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}},
+		release:       make(chan struct{}),
+	}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithReadinessGate(5*time.Second))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if client.WaitReady(ctx) == nil {
+		t.Errorf("Expected WaitReady to time out before the first refresh")
+	}
+
+	result := make(chan string)
+	go func() {
+		name, _ := client.GetConfigString("name", "default")
+		result <- name
+	}()
+	select {
+	case name := <-result:
+		t.Fatalf("Expected getter to block until ready, got %s", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(repository.release)
+	if name := <-result; name != "John" {
+		t.Errorf("Expected name to be John, got %s", name)
+	}
+	if client.WaitReady(context.Background()) != nil {
+		t.Errorf("Expected client to be ready")
+	}
+}
+
+func TestWithReadinessGateTimeout(t *testing.T) {
+	repository := &blockingRepository{
+		mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}},
+		release:       make(chan struct{}),
+	}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithReadinessGate(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	// Release the blocked first refresh before closing the client.
+	defer client.Close()
+	defer close(repository.release)
+	name, err := client.GetConfigString("name", "default")
+	if !errors.Is(err, ErrNotReady) {
+		t.Errorf("Expected ErrNotReady, got %v", err)
+	}
+	if name != "default" {
+		t.Errorf("Expected name to be default, got %s", name)
+	}
+}
+
+func TestWithOptionalKeys(t *testing.T) {
+	repository := &mapRepository{data: map[string]interface{}{"name": "John"}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithOptionalKeys("nickname", "retries"))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	nickname, err := client.GetConfigString("nickname", "Johnny")
+	if err != nil {
+		t.Errorf("Expected no error for a missing optional key, got %s", err.Error())
+	}
+	if nickname != "Johnny" {
+		t.Errorf("Expected default nickname, got %s", nickname)
+	}
+	retries, err := client.GetConfigInt("retries", 3)
+	if err != nil || retries != 3 {
+		t.Errorf("Expected default retries without error, got %d and %v", retries, err)
+	}
+	err = client.Prefetch(map[string]interface{}{"nickname": &nickname})
+	if err != nil {
+		t.Errorf("Expected no prefetch error for a missing optional key, got %s", err.Error())
+	}
+
+	_, err = client.GetConfigString("email", "")
+	if err == nil {
+		t.Errorf("Expected error for a missing required key, got nil")
+	}
+	var email string
+	if client.GetConfig("email", &email, nil) == nil {
+		t.Errorf("Expected error from GetConfig for a missing required key, got nil")
+	}
+}
+
+func TestWithShutdownHook(t *testing.T) {
+	repository := &mapRepository{data: map[string]interface{}{"name": "John"}}
+	var client *Client
+	var calls []string
+	first := func() {
+		select {
+		case <-client.stopped:
+		default:
+			t.Errorf("Expected the hook to run after the refresh goroutine stopped")
+		}
+		calls = append(calls, "first")
+	}
+	second := func() { calls = append(calls, "second") }
+	client, err := NewClient(context.Background(), repository, 10*time.Millisecond, WithShutdownHook(first), WithShutdownHook(second))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	client.Close()
+	client.Close()
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Errorf("Expected each hook to run once in order, got %v", calls)
+	}
+}
+
+func TestWithSeedFromEnv(t *testing.T) {
+	t.Setenv("APP_DB_HOST", "db.env")
+	t.Setenv("APP_DB_PORT", "5432")
+	t.Setenv("APP_NAME", "env")
+	repository := &mapRepository{data: map[string]interface{}{"name": "remote"}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithSeedFromEnv("APP_", "_"))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	var db struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	}
+	err = client.GetConfig("db", &db, nil)
+	if err != nil {
+		t.Errorf("Error getting db: %s", err.Error())
+	}
+	if db.Host != "db.env" || db.Port != 5432 {
+		t.Errorf("Expected db.host to be imported from the environment, got %v", db)
+	}
+	// Remote sources override the environment.
+	if name, _ := client.GetConfigString("name", ""); name != "remote" {
+		t.Errorf("Expected name to be remote, got %s", name)
+	}
+}
+
+func TestWithSeedFromEnvWithoutRepository(t *testing.T) {
+	t.Setenv("APP_NAME", "env")
+	client, err := NewClient(context.Background(), nil, 10*time.Second,
+		WithSeedFromEnv("APP_", "_"), WithInitialData(map[string]interface{}{"name": "initial", "age": 30}))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// The initial data is layered beneath the environment.
+	if name, _ := client.GetConfigString("name", ""); name != "env" {
+		t.Errorf("Expected name to be env, got %s", name)
+	}
+	if age, _ := client.GetConfigInt("age", 0); age != 30 {
+		t.Errorf("Expected age to be 30, got %d", age)
+	}
+}
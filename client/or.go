@@ -0,0 +1,64 @@
+package client
+
+// GetConfigOr retrieves the configuration with the given name decoded as T,
+// or returns the default value on any failure. Use GetConfig when the reason
+// of a failure matters.
+func GetConfigOr[T any](c *Client, name string, defaultValue T) T {
+	value, err := GetConfigTransform(c, name, defaultValue, func(value T) (T, error) {
+		return value, nil
+	})
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// StringOr returns the string configuration with the given name, or the
+// default value on any failure.
+func (c *Client) StringOr(name string, defaultValue string) string {
+	value, err := c.GetConfigString(name, defaultValue)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// IntOr returns the int configuration with the given name, or the default
+// value on any failure.
+func (c *Client) IntOr(name string, defaultValue int) int {
+	value, err := c.GetConfigInt(name, defaultValue)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// FloatOr returns the float configuration with the given name, or the default
+// value on any failure.
+func (c *Client) FloatOr(name string, defaultValue float64) float64 {
+	value, err := c.GetConfigFloat(name, defaultValue)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// BoolOr returns the bool configuration with the given name, or the default
+// value on any failure.
+func (c *Client) BoolOr(name string, defaultValue bool) bool {
+	value, err := c.GetConfigBool(name, defaultValue)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// StringsOr returns the string array configuration with the given name, or
+// the default value on any failure.
+func (c *Client) StringsOr(name string, defaultValue []string) []string {
+	value, err := c.GetConfigArrayOfStrings(name, defaultValue)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
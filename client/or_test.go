@@ -0,0 +1,58 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrGetters(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"name":    "John",
+		"age":     30,
+		"ratio":   0.5,
+		"enabled": true,
+		"tags":    []interface{}{"a", "b"},
+		"map":     map[string]interface{}{"key": "value"},
+	})
+
+	if value := client.StringOr("name", "default"); value != "John" {
+		t.Errorf("Expected John, got %s", value)
+	}
+	if value := client.IntOr("age", 1); value != 30 {
+		t.Errorf("Expected 30, got %d", value)
+	}
+	if value := client.FloatOr("ratio", 1); value != 0.5 {
+		t.Errorf("Expected 0.5, got %f", value)
+	}
+	if value := client.BoolOr("enabled", false); !value {
+		t.Errorf("Expected true, got false")
+	}
+	if value := client.StringsOr("tags", nil); !reflect.DeepEqual(value, []string{"a", "b"}) {
+		t.Errorf("Expected [a b], got %v", value)
+	}
+	if value := GetConfigOr(client, "age", 1); value != 30 {
+		t.Errorf("Expected 30, got %d", value)
+	}
+
+	// Missing and wrongly typed configurations return the default.
+	for _, name := range []string{"missing", "map"} {
+		if value := client.StringOr(name, "default"); value != "default" {
+			t.Errorf("Expected the default for %s, got %s", name, value)
+		}
+		if value := client.IntOr(name, 1); value != 1 {
+			t.Errorf("Expected the default for %s, got %d", name, value)
+		}
+		if value := client.FloatOr(name, 1.5); value != 1.5 {
+			t.Errorf("Expected the default for %s, got %f", name, value)
+		}
+		if value := client.BoolOr(name, true); !value {
+			t.Errorf("Expected the default for %s, got false", name)
+		}
+		if value := client.StringsOr(name, []string{"x"}); !reflect.DeepEqual(value, []string{"x"}) {
+			t.Errorf("Expected the default for %s, got %v", name, value)
+		}
+	}
+	if value := GetConfigOr(client, "name", 1); value != 1 {
+		t.Errorf("Expected the default for a string read as an int, got %d", value)
+	}
+}
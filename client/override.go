@@ -0,0 +1,69 @@
+package client
+
+// override is a value pinned with Override.
+type override struct {
+	value interface{}
+}
+
+// Override pins the configuration with the given name to value in this
+// process, for tests or to mitigate an incident, whatever the repository
+// holds. Getters return value, as is and for every deployment color, until
+// the returned restore function is called, and refreshes in between do not
+// change it. Overrides of the same name stack: restoring the latest one
+// brings back the previous one. Calling restore more than once has no
+// further effect.
+func (c *Client) Override(name string, value interface{}) (restore func()) {
+	pinned := &override{value: value}
+	c.overridesMu.Lock()
+	if c.overrides == nil {
+		c.overrides = map[string][]*override{}
+	}
+	c.overrides[name] = append(c.overrides[name], pinned)
+	c.overridesMu.Unlock()
+	c.resetDecoded()
+
+	restored := false
+	return func() {
+		c.overridesMu.Lock()
+		if restored {
+			c.overridesMu.Unlock()
+			return
+		}
+		restored = true
+		stack := c.overrides[name]
+		for i, entry := range stack {
+			if entry == pinned {
+				stack = append(stack[:i:i], stack[i+1:]...)
+				break
+			}
+		}
+		if len(stack) == 0 {
+			delete(c.overrides, name)
+		} else {
+			c.overrides[name] = stack
+		}
+		c.overridesMu.Unlock()
+		c.resetDecoded()
+	}
+}
+
+// overridden returns the value the configuration with the given name is
+// pinned to with Override, if any.
+func (c *Client) overridden(name string) (interface{}, bool) {
+	c.overridesMu.RLock()
+	defer c.overridesMu.RUnlock()
+	stack := c.overrides[name]
+	if len(stack) == 0 {
+		return nil, false
+	}
+	return stack[len(stack)-1].value, true
+}
+
+// resetDecoded drops the cached and prefetched decodings, so that GetConfig
+// sees an override as soon as it is added or restored.
+func (c *Client) resetDecoded() {
+	c.invalidateCache()
+	if err := c.prefetchAll(false); err != nil {
+		c.log().Debug("error prefetching configs", LogFields{"error": err})
+	}
+}
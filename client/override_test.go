@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOverride(t *testing.T) {
+	client, repository := newMapClient(t, map[string]interface{}{"limit": 10, "name": "John"})
+	err := client.Prefetch(map[string]interface{}{"name": new(string)})
+	if err != nil {
+		t.Fatalf("Error prefetching: %s", err.Error())
+	}
+
+	restoreLimit := client.Override("limit", 1)
+	restoreName := client.Override("name", "Jane")
+
+	// The override wins over refreshed data.
+	repository.set("limit", 20)
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error forcing refresh: %s", err.Error())
+	}
+	limit, err := client.GetConfigInt("limit", 0)
+	if err != nil || limit != 1 {
+		t.Errorf("Expected the overridden limit 1, got %d (%v)", limit, err)
+	}
+	var name string
+	err = client.GetConfig("name", &name, "")
+	if err != nil || name != "Jane" {
+		t.Errorf("Expected the overridden name Jane, got %s (%v)", name, err)
+	}
+
+	// Overrides stack, and restoring brings back the previous value.
+	restoreAgain := client.Override("limit", 2)
+	if limit, _ := client.GetConfigInt("limit", 0); limit != 2 {
+		t.Errorf("Expected the latest override 2, got %d", limit)
+	}
+	restoreAgain()
+	if limit, _ := client.GetConfigInt("limit", 0); limit != 1 {
+		t.Errorf("Expected the previous override 1, got %d", limit)
+	}
+
+	restoreLimit()
+	restoreLimit()
+	restoreName()
+	if limit, _ := client.GetConfigInt("limit", 0); limit != 20 {
+		t.Errorf("Expected the refreshed limit 20 after restore, got %d", limit)
+	}
+	err = client.GetConfig("name", &name, "")
+	if err != nil || name != "John" {
+		t.Errorf("Expected the repository name John after restore, got %s (%v)", name, err)
+	}
+
+	// A missing configuration can be overridden too.
+	restore := client.Override("missing", "pinned")
+	if value, err := client.GetConfigString("missing", ""); err != nil || value != "pinned" {
+		t.Errorf("Expected the overridden missing config, got %s (%v)", value, err)
+	}
+	restore()
+	if _, err := client.GetConfigString("missing", ""); err == nil {
+		t.Errorf("Expected an error for a restored missing config, got nil")
+	}
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"testing"
+	"time"
+)
+
+// partialRepository is a Repository whose Refresh reports the keys that did
+// not parse, while its data holds the keys that did.
+type partialRepository struct {
+	mapRepository
+	keyErrors error
+}
+
+func (p *partialRepository) Refresh() error {
+	return p.keyErrors
+}
+
+func TestPartialRefresh(t *testing.T) {
+	keyErrors := &source.KeyErrors{Errors: map[string]error{"limits": errors.New("yaml: line 1: did not find expected node content")}}
+	repository := &partialRepository{
+		mapRepository: mapRepository{data: map[string]interface{}{"name": "John", "retries": 3}},
+		keyErrors:     keyErrors,
+	}
+	client, err := NewClient(context.Background(), repository, time.Hour, WithLogger(NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Expected the keys that parsed to be served, got %s", err.Error())
+	}
+	defer client.Close()
+
+	if value, _ := client.GetConfigString("name", ""); value != "John" {
+		t.Errorf("Expected the valid key to be served, got %q", value)
+	}
+	if value, err := client.GetConfigInt("limits", 10); value != 10 || !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected the broken key to fall back to its default, got %d (%v)", value, err)
+	}
+	lastSuccess, lastErr := client.LastRefresh()
+	if lastSuccess.IsZero() {
+		t.Errorf("Expected a partial refresh to count as successful")
+	}
+	var reported *source.KeyErrors
+	if !errors.As(lastErr, &reported) || reported.Errors["limits"] == nil {
+		t.Errorf("Expected LastRefresh to report the key errors, got %v", lastErr)
+	}
+
+	// A later refresh that parses every key clears the error.
+	repository.Lock()
+	repository.keyErrors = nil
+	repository.data = map[string]interface{}{"name": "Jane", "limits": 5}
+	repository.Unlock()
+	if err := client.ForceRefresh(context.Background()); err != nil {
+		t.Fatalf("Error refreshing: %s", err.Error())
+	}
+	if value, _ := client.GetConfigInt("limits", 10); value != 5 {
+		t.Errorf("Expected the fixed key to be served, got %d", value)
+	}
+	if _, lastErr := client.LastRefresh(); lastErr != nil {
+		t.Errorf("Expected the error to be cleared, got %v", lastErr)
+	}
+}
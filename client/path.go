@@ -0,0 +1,53 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathSeparator separates the segments of a dotted path, such as
+// database.primary.host, which getters accept in place of a configuration
+// name to read a value nested in a top-level configuration. A top-level
+// configuration whose name contains the separator takes precedence over the
+// path.
+const PathSeparator = "."
+
+// splitPath splits name into the name of the top-level configuration and the
+// segments of the path below it. The path is nil when name has no separator.
+func splitPath(name string) (string, []string) {
+	index := strings.Index(name, PathSeparator)
+	if index <= 0 {
+		return name, nil
+	}
+	return name[:index], strings.Split(name[index+len(PathSeparator):], PathSeparator)
+}
+
+// descend returns the value at path in config, and whether every segment of
+// the path but the last names a map holding the next one.
+func descend(config interface{}, path []string) (interface{}, bool) {
+	for _, segment := range path {
+		switch configMap := config.(type) {
+		case map[string]interface{}:
+			value, ok := configMap[segment]
+			if !ok {
+				return nil, false
+			}
+			config = value
+		case map[interface{}]interface{}:
+			// Keys such as 1 or true are decoded as numbers and bools.
+			found := false
+			for key, value := range configMap {
+				if fmt.Sprint(key) == segment {
+					config, found = value, true
+					break
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return config, true
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestDottedPath(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"database": map[string]interface{}{
+			"primary": map[string]interface{}{
+				"host": "db.internal",
+				"port": 5432,
+			},
+			"replicas": map[interface{}]interface{}{1: "replica-1"},
+			"name":     "orders",
+		},
+		"app.name": "literal",
+		"app": map[string]interface{}{
+			"name": "nested",
+		},
+	})
+
+	// Deep paths return the leaf.
+	host, err := client.GetConfigString("database.primary.host", "localhost")
+	if err != nil || host != "db.internal" {
+		t.Errorf("Expected db.internal, got %s (%v)", host, err)
+	}
+	port, err := client.GetConfigInt("database.primary.port", 0)
+	if err != nil || port != 5432 {
+		t.Errorf("Expected 5432, got %d (%v)", port, err)
+	}
+	replica, err := client.GetConfigString("database.replicas.1", "")
+	if err != nil || replica != "replica-1" {
+		t.Errorf("Expected replica-1, got %s (%v)", replica, err)
+	}
+
+	// A missing intermediate segment returns the default.
+	host, err = client.GetConfigString("database.secondary.host", "localhost")
+	if err == nil || host != "localhost" {
+		t.Errorf("Expected the default and an error for a missing segment, got %s (%v)", host, err)
+	}
+	host, err = client.GetConfigString("cache.primary.host", "localhost")
+	if err == nil || host != "localhost" {
+		t.Errorf("Expected the default and an error for a missing configuration, got %s (%v)", host, err)
+	}
+
+	// A segment that is a scalar rather than a map returns the default.
+	host, err = client.GetConfigString("database.name.host", "localhost")
+	if err == nil || host != "localhost" {
+		t.Errorf("Expected the default and an error for a scalar segment, got %s (%v)", host, err)
+	}
+
+	// A top-level configuration named with dots takes precedence.
+	name, _ := client.GetConfigString("app.name", "")
+	if name != "literal" {
+		t.Errorf("Expected literal, got %s", name)
+	}
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"errors"
+)
+
+// errRefreshPaused is returned by refreshPeriodic instead of refreshing
+// while the Client is paused.
+var errRefreshPaused = errors.New("refresh paused")
+
+// Pause stops the background goroutine from refreshing the repository, for
+// example to freeze the configuration during a rollout or a maintenance
+// window, until Resume is called. The goroutine keeps running and skips its
+// refreshes. Pause waits for a refresh in progress, so no periodic refresh
+// changes the configuration once it returns. Refreshes requested explicitly,
+// with ForceRefresh, still run.
+func (c *Client) Pause() {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	c.paused.Store(true)
+}
+
+// Resume restarts the refreshes stopped by Pause and refreshes the
+// repository right away to catch up with the changes made while paused,
+// returning the error of that refresh. It does nothing if the Client is not
+// paused.
+func (c *Client) Resume() error {
+	c.refreshMu.Lock()
+	paused := c.paused.Swap(false)
+	c.refreshMu.Unlock()
+	if !paused {
+		return nil
+	}
+	return c.ForceRefresh(context.Background())
+}
+
+// Paused reports whether the background refreshes are stopped by Pause.
+func (c *Client) Paused() bool {
+	return c.paused.Load()
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPauseResume(t *testing.T) {
+	clock := &fakeTimerClock{waits: make(chan time.Duration, 1), fire: make(chan time.Time)}
+	repository := &swappingRepository{}
+	client, err := NewClient(context.Background(), repository, time.Second, WithClock(clock))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	waitForLoop := func() {
+		t.Helper()
+		select {
+		case <-clock.waits:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the refresh loop to wait")
+		}
+	}
+	waitForLoop()
+
+	// The loop keeps running but skips its refreshes while paused.
+	client.Pause()
+	if !client.Paused() {
+		t.Errorf("Expected the client to be paused")
+	}
+	for i := 0; i < 3; i++ {
+		clock.fire <- time.Now()
+		waitForLoop()
+	}
+	if generation := repository.generation.Load(); generation != 1 {
+		t.Errorf("Expected no refresh while paused, got %d refreshes", generation)
+	}
+	if min, _ := client.GetConfigInt("min", 0); min != 1 {
+		t.Errorf("Expected the configuration to be frozen, got %d", min)
+	}
+
+	// Resume catches up right away, then the loop refreshes again.
+	if err := client.Resume(); err != nil {
+		t.Fatalf("Error resuming client: %s", err.Error())
+	}
+	if client.Paused() {
+		t.Errorf("Expected the client to be resumed")
+	}
+	if min, _ := client.GetConfigInt("min", 0); min != 2 {
+		t.Errorf("Expected Resume to refresh, got %d", min)
+	}
+	waitForLoop()
+	clock.fire <- time.Now()
+	waitForLoop()
+	if generation := repository.generation.Load(); generation != 3 {
+		t.Errorf("Expected the loop to refresh after Resume, got %d refreshes", generation)
+	}
+	if err := client.Resume(); err != nil || repository.generation.Load() != 3 {
+		t.Errorf("Expected Resume of a running client to do nothing, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+)
+
+// persistLastGood writes the data of the repository to the last good file
+// after a successful refresh that changed it. The data is written as the
+// repository holds it, before value transformers such as decryption run. It
+// is written to a temporary file renamed over the last good file, so a crash
+// never leaves a partial file behind. The caller must hold refreshMu.
+func (c *Client) persistLastGood(changed []string) {
+	if c.lastGoodPath == "" || (c.persisted && len(changed) == 0) {
+		return
+	}
+	repository := c.Repository
+	if c.lastGoodSeeded != nil {
+		// Leave out the data seeded from the file itself.
+		repository = c.lastGoodSeeded
+	}
+	lister, ok := repository.(source.KeyLister)
+	if !ok {
+		c.log().Warn("repository cannot be listed, not persisting the last good configuration", nil)
+		return
+	}
+	data := map[string]interface{}{}
+	for _, key := range lister.Keys() {
+		if value, ok := repository.GetData(key); ok {
+			data[key] = value
+		}
+	}
+	encoded, err := source.YAMLCodec.Marshal(data)
+	if err == nil {
+		err = writeFileAtomic(c.lastGoodPath, encoded)
+	}
+	if err != nil {
+		c.log().Error("error persisting the last good configuration", LogFields{"error": err, "path": c.lastGoodPath})
+		return
+	}
+	c.persisted = true
+}
+
+// seedLastGood seeds the Client with the configuration persisted to the last
+// good file, beneath the repository, when the refresh NewClient blocks on
+// failed with err. It reports whether the file could be loaded.
+func (c *Client) seedLastGood(err error) bool {
+	if c.lastGoodPath == "" {
+		return false
+	}
+	data, loadErr := c.loadLastGood()
+	if loadErr != nil {
+		c.log().Error("error loading the last good configuration", LogFields{"error": loadErr, "path": c.lastGoodPath})
+		return false
+	}
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	c.repositoryMu.Lock()
+	c.lastGoodSeeded = c.Repository
+	c.seed(data)
+	c.repositoryMu.Unlock()
+	c.log().Warn("starting from the last good configuration until a refresh succeeds", LogFields{"error": err, "path": c.lastGoodPath})
+	return true
+}
+
+// dropLastGood removes the last good file seeded beneath the repository once
+// a refresh of the repository succeeds, so that keys the source no longer has
+// are not served from the file. The caller must hold refreshMu.
+func (c *Client) dropLastGood() {
+	if c.lastGoodSeeded == nil {
+		return
+	}
+	c.repositoryMu.Lock()
+	c.Repository = c.lastGoodSeeded
+	c.repositoryMu.Unlock()
+	c.lastGoodSeeded = nil
+	c.log().Info("refresh succeeded, no longer serving the last good configuration", LogFields{"path": c.lastGoodPath})
+}
+
+// loadLastGood reads the configuration persisted to the last good file.
+func (c *Client) loadLastGood() (map[string]interface{}, error) {
+	encoded, err := os.ReadFile(c.lastGoodPath)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{}
+	if err := source.YAMLCodec.Unmarshal(encoded, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the directory of path,
+// readable by its owner only, and renames it to path.
+func writeFileAtomic(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Nothing is left to remove once the file has been renamed.
+		_ = os.Remove(file.Name())
+	}()
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
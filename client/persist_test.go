@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithLastGoodFile(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("name: John\nage: 30\n"), 0o600); err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	lastGood := filepath.Join(dir, "last-good.yaml")
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: configPath}, time.Hour, WithLastGoodFile(lastGood))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	info, err := os.Stat(lastGood)
+	if err != nil {
+		t.Fatalf("Expected the last good file to be written, got %s", err.Error())
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("Expected the last good file to be readable by its owner only, got %v", mode)
+	}
+
+	// A successful refresh replaces the file.
+	if err := os.WriteFile(configPath, []byte("name: Jane\n"), 0o600); err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	if err := client.ForceRefresh(context.Background()); err != nil {
+		t.Fatalf("Error refreshing: %s", err.Error())
+	}
+	persisted := &source.FileRepository{Name: "last-good", Path: lastGood}
+	if err := persisted.Refresh(); err != nil {
+		t.Fatalf("Error reading the last good file: %s", err.Error())
+	}
+	if value, _ := persisted.GetData("name"); value != "Jane" {
+		t.Errorf("Expected the last good file to hold the refreshed value, got %v", value)
+	}
+	if _, ok := persisted.GetData("age"); ok {
+		t.Errorf("Expected the removed configuration to be left out of the last good file")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("Expected no temporary file to be left behind, got %d files", len(entries))
+	}
+}
+
+func TestWithLastGoodFileFallback(t *testing.T) {
+	lastGood := filepath.Join(t.TempDir(), "last-good.yaml")
+	if err := os.WriteFile(lastGood, []byte("name: John\nregion: eu\n"), 0o600); err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	repository := &failingRepository{err: errors.New("backend unavailable")}
+	client, err := NewClient(context.Background(), repository, time.Hour, WithLastGoodFile(lastGood), WithLogger(NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Expected the client to start from the last good file, got %s", err.Error())
+	}
+	defer client.Close()
+	if value, _ := client.GetConfigString("name", "default"); value != "John" {
+		t.Errorf("Expected the persisted value, got %s", value)
+	}
+	if err := client.Healthy(); !errors.Is(err, ErrUnhealthy) {
+		t.Errorf("Expected the client to be unhealthy, got %v", err)
+	}
+
+	// Once a refresh succeeds, keys removed at the source are no longer served from the file.
+	repository.data = map[string]interface{}{"name": "Jane"}
+	repository.err = nil
+	if err := client.ForceRefresh(context.Background()); err != nil {
+		t.Fatalf("Error refreshing: %s", err.Error())
+	}
+	if value, _ := client.GetConfigString("name", "default"); value != "Jane" {
+		t.Errorf("Expected the refreshed value, got %s", value)
+	}
+	if value, err := client.GetConfigString("region", "default"); err == nil || value != "default" {
+		t.Errorf("Expected the removed configuration to be missing, got %s (%v)", value, err)
+	}
+
+	// Without a last good file the start fails.
+	_, err = NewClient(context.Background(), &failingRepository{err: errors.New("backend unavailable")}, time.Hour, WithLastGoodFile(filepath.Join(t.TempDir(), "missing.yaml")), WithLogger(NewNopLogger()))
+	if err == nil {
+		t.Errorf("Expected an error without a last good file")
+	}
+}
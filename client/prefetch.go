@@ -0,0 +1,108 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// prefetchEntry is a configuration registered with Prefetch.
+type prefetchEntry struct {
+	targetType reflect.Type // pointer type the configuration is read into
+	data       []byte       // marshalled configuration, nil until it decoded successfully
+}
+
+// Prefetch registers configurations whose decodings are checked eagerly, now
+// and after every refresh, so that GetConfig for them is faster and decode
+// errors surface early. targets maps configuration names to pointers of the
+// type the configuration will be read into, for example
+// map[string]interface{}{"address": &Address{}}.
+// No target is registered if any of them is not a non-nil pointer.
+// It returns an error listing every configuration that failed to decode.
+func (c *Client) Prefetch(targets map[string]interface{}) error {
+	for name, target := range targets {
+		value := reflect.ValueOf(target)
+		if value.Kind() != reflect.Ptr || value.IsNil() {
+			return fmt.Errorf("prefetch target for %s must be a non-nil pointer", name)
+		}
+	}
+	c.mu.Lock()
+	if c.prefetched == nil {
+		c.prefetched = map[string]prefetchEntry{}
+	}
+	for name, target := range targets {
+		c.prefetched[name] = prefetchEntry{targetType: reflect.TypeOf(target)}
+	}
+	c.mu.Unlock()
+	return c.prefetchAll(true)
+}
+
+// prefetchAll decodes every registered prefetch target from the current
+// repository data and caches the marshalled data of those that succeed. When
+// validate is false the data is cached without checking that it decodes, and
+// a decode error only surfaces when GetConfig reads the configuration.
+func (c *Client) prefetchAll(validate bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []string
+	for name, entry := range c.prefetched {
+		// Drop the stale data until the configuration decodes again.
+		entry.data = nil
+		config, ok, scheduled := c.getScheduled(name)
+		if !ok && c.optionalKeys[name] || scheduled || ok && config == nil {
+			// Scheduled, expiring and overridden values change without a refresh, so they are read on every call,
+			// as are empty values, which GetConfig leaves as the default.
+			c.prefetched[name] = entry
+			continue
+		}
+		data, err := c.marshalConfig(name)
+		if err == nil && validate {
+			err = c.unmarshal(data, reflect.New(entry.targetType.Elem()).Interface())
+		}
+		if err != nil {
+			c.prefetched[name] = entry
+			c.redactConfigError(name, &err)
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err.Error()))
+			continue
+		}
+		entry.data = data
+		c.prefetched[name] = entry
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return errors.New("error decoding prefetched configs: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// marshalConfig marshals the configuration with the given name.
+func (c *Client) marshalConfig(name string) ([]byte, error) {
+	config, ok := c.getData(name)
+	if !ok {
+		return nil, keyError(name, ErrConfigNotFound)
+	}
+	if err := valueError(config); err != nil {
+		return nil, err
+	}
+	return c.marshal(config)
+}
+
+// loadPrefetched unmarshals the prefetched data of the configuration into
+// data and reports whether data of the matching type was available. Like
+// GetConfig, it leaves fields of data that are absent from the configuration
+// untouched, and every call gets its own copy of slices and maps.
+func (c *Client) loadPrefetched(name string, data interface{}) bool {
+	c.mu.RLock()
+	entry, ok := c.prefetched[name]
+	c.mu.RUnlock()
+	if !ok || entry.data == nil {
+		return false
+	}
+	dataValue := reflect.ValueOf(data)
+	if dataValue.Type() != entry.targetType || dataValue.IsNil() {
+		return false
+	}
+	return c.unmarshal(entry.data, data) == nil
+}
@@ -0,0 +1,221 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type prefetchAddress struct {
+	Street string `yaml:"street"`
+	City   string `yaml:"city"`
+}
+
+func TestPrefetch(t *testing.T) {
+	client, repository := newMapClient(t, map[string]interface{}{
+		"address": map[string]interface{}{"street": "123 Main St", "city": "New York"},
+		"broken":  "not a struct",
+	})
+
+	err := client.Prefetch(map[string]interface{}{"address": &prefetchAddress{}})
+	if err != nil {
+		t.Errorf("Error prefetching address: %s", err.Error())
+	}
+	client.mu.RLock()
+	cached := client.prefetched["address"]
+	client.mu.RUnlock()
+	if cached.data == nil {
+		t.Errorf("Expected prefetch cache to hold the address")
+	}
+
+	var address prefetchAddress
+	err = client.GetConfig("address", &address, nil)
+	if err != nil {
+		t.Errorf("Error getting address: %s", err.Error())
+	}
+	if address.Street != "123 Main St" {
+		t.Errorf("Expected street to be 123 Main St, got %s", address.Street)
+	}
+
+	// Fields absent from the configuration are left untouched, as without Prefetch.
+	type partialAddress struct {
+		City string `yaml:"city"`
+		Zip  string `yaml:"zip"`
+	}
+	err = client.Prefetch(map[string]interface{}{"address": &partialAddress{}})
+	if err != nil {
+		t.Errorf("Error prefetching address: %s", err.Error())
+	}
+	partial := partialAddress{Zip: "10001"}
+	err = client.GetConfig("address", &partial, nil)
+	if err != nil {
+		t.Errorf("Error getting address: %s", err.Error())
+	}
+	if partial.Zip != "10001" || partial.City != "New York" {
+		t.Errorf("Expected zip to be kept and city to be New York, got %v", partial)
+	}
+
+	// A refresh recomputes the cached decoding.
+	repository.set("address", map[string]interface{}{"street": "1 Side St", "city": "Boston"})
+	err = client.refreshRepository()
+	if err != nil {
+		t.Errorf("Error refreshing: %s", err.Error())
+	}
+	err = client.GetConfig("address", &address, nil)
+	if err != nil {
+		t.Errorf("Error getting address: %s", err.Error())
+	}
+	if address.City != "Boston" {
+		t.Errorf("Expected city to be Boston, got %s", address.City)
+	}
+}
+
+func TestPrefetchReportsDecodeErrors(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"broken": "not a struct",
+	})
+	err := client.Prefetch(map[string]interface{}{
+		"broken":  &prefetchAddress{},
+		"missing": &prefetchAddress{},
+	})
+	if err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+	expected := "error decoding prefetched configs: broken: "
+	if len(err.Error()) < len(expected) || err.Error()[:len(expected)] != expected {
+		t.Errorf("Expected error to start with %q, got %q", expected, err.Error())
+	}
+
+	err = client.Prefetch(map[string]interface{}{"address": &prefetchAddress{}, "invalid": prefetchAddress{}})
+	if err == nil {
+		t.Errorf("Expected error for a non-pointer target, got nil")
+	}
+	client.mu.RLock()
+	_, registered := client.prefetched["address"]
+	client.mu.RUnlock()
+	if registered {
+		t.Errorf("Expected no target to be registered when one is invalid")
+	}
+}
+
+func TestPrefetchDoesNotShareValues(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"tags": map[string]interface{}{"names": []interface{}{"a", "b"}},
+	})
+	type tags struct {
+		Names []string `yaml:"names"`
+	}
+	err := client.Prefetch(map[string]interface{}{"tags": &tags{}})
+	if err != nil {
+		t.Errorf("Error prefetching tags: %s", err.Error())
+	}
+	var first, second tags
+	_ = client.GetConfig("tags", &first, nil)
+	first.Names[0] = "modified"
+	_ = client.GetConfig("tags", &second, nil)
+	if second.Names[0] != "a" {
+		t.Errorf("Expected prefetched slices not to be shared, got %v", second.Names)
+	}
+}
+
+// versionedRepository is a mapRepository that reports a fixed version and
+// counts lookups of its data.
+type versionedRepository struct {
+	mapRepository
+	version string
+	lookups int
+}
+
+func (v *versionedRepository) GetData(name string) (interface{}, bool) {
+	v.lookups++
+	return v.mapRepository.GetData(name)
+}
+
+func (v *versionedRepository) Version() string {
+	return v.version
+}
+
+func TestPrefetchSkippedWhenVersionUnchanged(t *testing.T) {
+	repository := &versionedRepository{
+		mapRepository: mapRepository{data: map[string]interface{}{
+			"address": map[string]interface{}{"street": "123 Main St", "city": "New York"},
+		}},
+		version: "1",
+	}
+	client, err := NewClient(context.Background(), repository, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	err = client.Prefetch(map[string]interface{}{"address": &prefetchAddress{}})
+	if err != nil {
+		t.Errorf("Error prefetching address: %s", err.Error())
+	}
+
+	lookups := repository.lookups
+	err = client.refreshRepository()
+	if err != nil {
+		t.Errorf("Error refreshing: %s", err.Error())
+	}
+	if repository.lookups != lookups {
+		t.Errorf("Expected no prefetch with an unchanged version, got %d lookups", repository.lookups-lookups)
+	}
+
+	repository.version = "2"
+	err = client.refreshRepository()
+	if err != nil {
+		t.Errorf("Error refreshing: %s", err.Error())
+	}
+	if repository.lookups == lookups {
+		t.Errorf("Expected prefetch after the version changed")
+	}
+}
+
+func TestWithValidateOnStartOnly(t *testing.T) {
+	for _, startOnly := range []bool{false, true} {
+		repository := &mapRepository{data: map[string]interface{}{
+			"address": map[string]interface{}{"street": "123 Main St", "city": "New York"},
+		}}
+		var opts []Option
+		if startOnly {
+			opts = append(opts, WithValidateOnStartOnly())
+		}
+		client, err := NewClient(context.Background(), repository, 10*time.Second, opts...)
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err.Error())
+		}
+		defer client.Close()
+		err = client.Prefetch(map[string]interface{}{"address": &prefetchAddress{}})
+		if err != nil {
+			t.Errorf("Error prefetching address: %s", err.Error())
+		}
+		// validated reports whether the last refresh caught the invalid address.
+		validated := func() bool {
+			client.mu.RLock()
+			defer client.mu.RUnlock()
+			return client.prefetched["address"].data == nil
+		}
+
+		repository.set("address", "not a struct")
+		err = client.refreshPeriodic()
+		if err != nil {
+			t.Errorf("Error refreshing: %s", err.Error())
+		}
+		if validated() == startOnly {
+			t.Errorf("Expected periodic validation to be %t with validate on start only %t", !startOnly, startOnly)
+		}
+		var address prefetchAddress
+		if client.GetConfig("address", &address, nil) == nil {
+			t.Errorf("Expected the invalid address to fail when read")
+		}
+
+		// An explicit refresh always validates.
+		err = client.ForceRefresh(context.Background())
+		if err != nil {
+			t.Errorf("Error refreshing: %s", err.Error())
+		}
+		if !validated() {
+			t.Errorf("Expected ForceRefresh to validate with validate on start only %t", startOnly)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"errors"
+)
+
+// FallbackReason is the reason a getter returned its default value instead
+// of the configuration.
+type FallbackReason string
+
+// Reasons a getter falls back to its default value, reported in a ReadEvent.
+const (
+	FallbackNone         FallbackReason = ""              // the getter returned the configuration
+	FallbackNotFound     FallbackReason = "not_found"     // the configuration is missing
+	FallbackTypeMismatch FallbackReason = "type_mismatch" // the configuration is not of the type the getter reads
+	FallbackClosed       FallbackReason = "closed"        // the Client is closed
+	FallbackError        FallbackReason = "error"         // any other error, such as a stale configuration or a done context
+)
+
+// ReadEvent describes a read of a configuration by a getter, reported to the
+// listeners added with WithReadListener.
+type ReadEvent struct {
+	Key      string         // Name of the configuration
+	Op       string         // Getter that read it, such as GetConfigString
+	Fallback FallbackReason // Why the getter returned its default value, FallbackNone if it did not
+	Err      error          // Error the getter returned
+}
+
+// ReadListener is called with every read of a configuration by a getter, on
+// the goroutine of the getter, so it must be fast and safe for concurrent
+// use.
+type ReadListener func(event ReadEvent)
+
+// fallbackReason returns the reason a getter that returned err fell back to
+// its default value.
+func fallbackReason(err error) FallbackReason {
+	switch {
+	case err == nil:
+		return FallbackNone
+	case errors.Is(err, ErrConfigNotFound):
+		return FallbackNotFound
+	case errors.Is(err, ErrTypeMismatch):
+		return FallbackTypeMismatch
+	case errors.Is(err, ErrClientClosed):
+		return FallbackClosed
+	}
+	return FallbackError
+}
+
+// observe annotates the error *err of the getter op with the configuration
+// name like annotate, redacts it if the configuration is sensitive, and
+// reports the read to the read listeners, including audit hooks. Getters
+// defer it with their named error; getters built on another one call an
+// unexported variant of it, so that every read is reported once.
+func (c *Client) observe(op string, name string, err *error) {
+	annotate(op, name, err)
+	c.redactConfigError(name, err)
+	if len(c.readListeners) == 0 {
+		return
+	}
+	event := ReadEvent{Key: name, Op: op, Fallback: fallbackReason(*err), Err: *err}
+	for _, listener := range c.readListeners {
+		listener(event)
+	}
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithReadListener(t *testing.T) {
+	var mu sync.Mutex
+	var events []ReadEvent
+	listener := func(event ReadEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+	repository := &mapRepository{data: map[string]interface{}{"name": "John", "age": "thirty", "limits": map[string]interface{}{"max": 3}}}
+	client, err := NewClient(context.Background(), repository, time.Hour, WithReadListener(listener))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+
+	_, _ = client.GetConfigString("name", "")
+	_, _ = client.GetConfigInt("age", 0)
+	_, _ = client.GetConfigBool("missing", false)
+	// Getters built on other getters report a single read.
+	_, _ = client.GetConfigIntMap("limits", nil)
+	_, _ = Get(client, "name", "")
+	client.Close()
+	_, _ = client.GetConfigString("name", "")
+
+	expected := []ReadEvent{
+		{Key: "name", Op: "GetConfigString", Fallback: FallbackNone},
+		{Key: "age", Op: "GetConfigInt", Fallback: FallbackTypeMismatch},
+		{Key: "missing", Op: "GetConfigBool", Fallback: FallbackNotFound},
+		{Key: "limits", Op: "GetConfigIntMap", Fallback: FallbackNone},
+		{Key: "name", Op: "Get", Fallback: FallbackNone},
+		{Key: "name", Op: "GetConfigString", Fallback: FallbackClosed},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d reads, got %+v", len(expected), events)
+	}
+	for i, event := range events {
+		if event.Key != expected[i].Key || event.Op != expected[i].Op || event.Fallback != expected[i].Fallback {
+			t.Errorf("Expected read %d to be %+v, got %+v", i, expected[i], event)
+		}
+		if (event.Fallback == FallbackNone) != (event.Err == nil) {
+			t.Errorf("Expected read %d to carry the error of its fallback, got %v", i, event.Err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+)
+
+// RefreshHandler returns an HTTP handler that forces a refresh of the Client
+// when it receives a POST request, for example from a CI/CD pipeline after a
+// deploy. When authKey is not empty, requests must carry it in the X-API-KEY
+// header, like requests to the config server. The handler responds with
+// 204 No Content once the refresh succeeded.
+func (c *Client) RefreshHandler(authKey string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if authKey != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("X-API-KEY")), []byte(authKey)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		err := c.ForceRefresh(r.Context())
+		if errors.Is(err, ErrClientClosed) {
+			http.Error(w, "Client is closed", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			// The refresh error may mention backend URLs, so it is only logged.
+			c.log().Error("error refreshing repository", LogFields{"error": err})
+			http.Error(w, "Refresh failed", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
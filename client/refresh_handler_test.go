@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRefreshHandler(t *testing.T) {
+	repository := &test{}
+	client, err := NewClient(context.Background(), repository, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	handler := client.RefreshHandler("s3cret")
+
+	testCases := []struct {
+		name       string
+		method     string
+		key        string
+		wantStatus int
+		refreshes  int
+	}{
+		{name: "authorized", method: http.MethodPost, key: "s3cret", wantStatus: http.StatusNoContent, refreshes: 2},
+		{name: "wrong key", method: http.MethodPost, key: "guess", wantStatus: http.StatusUnauthorized, refreshes: 2},
+		{name: "missing key", method: http.MethodPost, wantStatus: http.StatusUnauthorized, refreshes: 2},
+		{name: "wrong method", method: http.MethodGet, key: "s3cret", wantStatus: http.StatusMethodNotAllowed, refreshes: 2},
+	}
+	for _, tc := range testCases {
+		request := httptest.NewRequest(tc.method, "/refresh", nil)
+		if tc.key != "" {
+			request.Header.Set("X-API-KEY", tc.key)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != tc.wantStatus {
+			t.Errorf("Expected status %d for %s, got %d", tc.wantStatus, tc.name, recorder.Code)
+		}
+		if repository.GetRefeshCount != tc.refreshes {
+			t.Errorf("Expected %d refreshes after %s, got %d", tc.refreshes, tc.name, repository.GetRefeshCount)
+		}
+	}
+
+	failing := &test{ShouldError: true}
+	client.Repository = failing
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+	request.Header.Set("X-API-KEY", "s3cret")
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500 for a failing refresh, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidRetryPolicy is returned by GetConfigRetryPolicy when the
+// configuration is not a valid retry policy.
+var ErrInvalidRetryPolicy = errors.New("invalid retry policy")
+
+// RetryPolicy is a retry configuration, stored in the repository as
+// `{max_attempts: 3, base_delay: "100ms", max_delay: "2s"}`.
+type RetryPolicy struct {
+	MaxAttempts int           // Maximum number of attempts, at least 1
+	BaseDelay   time.Duration // Delay before the first retry
+	MaxDelay    time.Duration // Upper bound of the delay between retries
+}
+
+// retryPolicyConfig is the shape of a RetryPolicy in the repository. Absent
+// fields are nil, so that they can be taken from the default policy.
+type retryPolicyConfig struct {
+	MaxAttempts *int    `yaml:"max_attempts"`
+	BaseDelay   *string `yaml:"base_delay"`
+	MaxDelay    *string `yaml:"max_delay"`
+}
+
+// GetConfigRetryPolicy retrieves the retry policy with the given name from the repository
+func GetConfigRetryPolicy(name string, defaultValue RetryPolicy) (RetryPolicy, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigRetryPolicy(name, defaultValue)
+}
+
+// GetConfigRetryPolicy retrieves the retry policy with the given name from the
+// repository. Fields the configuration does not set are taken from
+// defaultValue. It returns defaultValue and an error wrapping
+// ErrInvalidRetryPolicy if a delay is not a duration, a delay is negative, the
+// base delay exceeds the max delay or there are fewer than one attempts.
+func (c *Client) GetConfigRetryPolicy(name string, defaultValue RetryPolicy) (RetryPolicy, error) {
+	var config retryPolicyConfig
+	err := c.GetConfig(name, &config, nil)
+	if err != nil {
+		return defaultValue, err
+	}
+	if config == (retryPolicyConfig{}) && c.optionalKeys[name] {
+		return defaultValue, nil
+	}
+
+	policy := defaultValue
+	if config.MaxAttempts != nil {
+		policy.MaxAttempts = *config.MaxAttempts
+	}
+	if config.BaseDelay != nil {
+		policy.BaseDelay, err = time.ParseDuration(*config.BaseDelay)
+		if err != nil {
+			return defaultValue, fmt.Errorf("%w: base_delay: %s", ErrInvalidRetryPolicy, err.Error())
+		}
+	}
+	if config.MaxDelay != nil {
+		policy.MaxDelay, err = time.ParseDuration(*config.MaxDelay)
+		if err != nil {
+			return defaultValue, fmt.Errorf("%w: max_delay: %s", ErrInvalidRetryPolicy, err.Error())
+		}
+	}
+
+	switch {
+	case policy.MaxAttempts < 1:
+		return defaultValue, fmt.Errorf("%w: max_attempts must be at least 1, got %d", ErrInvalidRetryPolicy, policy.MaxAttempts)
+	case policy.BaseDelay < 0 || policy.MaxDelay < 0:
+		return defaultValue, fmt.Errorf("%w: delays must not be negative", ErrInvalidRetryPolicy)
+	case policy.BaseDelay > policy.MaxDelay:
+		return defaultValue, fmt.Errorf("%w: base_delay %s exceeds max_delay %s", ErrInvalidRetryPolicy, policy.BaseDelay, policy.MaxDelay)
+	}
+	return policy, nil
+}
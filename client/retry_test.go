@@ -0,0 +1,47 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetConfigRetryPolicy(t *testing.T) {
+	client, _ := newMapClient(t, map[string]interface{}{
+		"full":      map[string]interface{}{"max_attempts": 5, "base_delay": "100ms", "max_delay": "2s"},
+		"partial":   map[string]interface{}{"max_attempts": 2},
+		"bad_delay": map[string]interface{}{"base_delay": "soon"},
+		"no_tries":  map[string]interface{}{"max_attempts": 0},
+		"inverted":  map[string]interface{}{"base_delay": "5s", "max_delay": "1s"},
+		"not_a_map": "retry",
+	})
+	defaults := RetryPolicy{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: time.Second}
+
+	policy, err := client.GetConfigRetryPolicy("full", defaults)
+	expected := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}
+	if err != nil || policy != expected {
+		t.Errorf("Expected %+v, got %+v (%v)", expected, policy, err)
+	}
+
+	policy, err = client.GetConfigRetryPolicy("partial", defaults)
+	expected = RetryPolicy{MaxAttempts: 2, BaseDelay: 50 * time.Millisecond, MaxDelay: time.Second}
+	if err != nil || policy != expected {
+		t.Errorf("Expected %+v, got %+v (%v)", expected, policy, err)
+	}
+
+	for _, name := range []string{"bad_delay", "no_tries", "inverted"} {
+		policy, err = client.GetConfigRetryPolicy(name, defaults)
+		if !errors.Is(err, ErrInvalidRetryPolicy) || policy != defaults {
+			t.Errorf("Expected ErrInvalidRetryPolicy and the default for %s, got %+v (%v)", name, policy, err)
+		}
+	}
+
+	policy, err = client.GetConfigRetryPolicy("not_a_map", defaults)
+	if err == nil || policy != defaults {
+		t.Errorf("Expected an error and the default for a string, got %+v (%v)", policy, err)
+	}
+	policy, err = client.GetConfigRetryPolicy("missing", defaults)
+	if err == nil || policy != defaults {
+		t.Errorf("Expected an error and the default for a missing key, got %+v (%v)", policy, err)
+	}
+}
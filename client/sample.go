@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+)
+
+// ErrInvalidRate is returned by ShouldSample when the configured rate is not between 0 and 1.
+var ErrInvalidRate = errors.New("sample rate must be between 0 and 1")
+
+// ShouldSample reads the sample rate, between 0 and 1, of the configuration
+// with the given name and returns true with that probability. Every call
+// draws independently, so it suits decisions such as logging a fraction of
+// requests but not stable per-user decisions. If the rate cannot be read or
+// is out of range, defaultRate is used and the error is returned.
+func (c *Client) ShouldSample(name string, defaultRate float64) (bool, error) {
+	rate, err := c.sampleRate(name, defaultRate)
+	return sample(rate), err
+}
+
+// sampleRate returns the sample rate of the configuration with the given
+// name, which may be written as an int or a float.
+func (c *Client) sampleRate(name string, defaultRate float64) (float64, error) {
+	if c.isClosed.Load() {
+		return defaultRate, ErrClientClosed
+	}
+	if err := c.awaitReadiness(context.Background()); err != nil {
+		return defaultRate, err
+	}
+	config, ok := c.getData(name)
+	if !ok {
+		return defaultRate, c.notFound(name)
+	}
+	var rate float64
+	switch config := config.(type) {
+	case int:
+		rate = float64(config)
+	case float64:
+		rate = config
+	default:
+		return defaultRate, typeMismatch(name, "a number")
+	}
+	if rate < 0 || rate > 1 {
+		return defaultRate, ErrInvalidRate
+	}
+	return rate, nil
+}
+
+// sample returns true with probability rate.
+func sample(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if rate >= 1 {
+		return true
+	}
+	return rand.Float64() < rate
+}
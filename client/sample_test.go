@@ -0,0 +1,44 @@
+package client
+
+import (
+	"math"
+	"testing"
+)
+
+func TestShouldSample(t *testing.T) {
+	client, repository := newMapClient(t, map[string]interface{}{"rate": 0.25})
+	const calls = 20000
+	count := func(defaultRate float64) int {
+		sampled := 0
+		for i := 0; i < calls; i++ {
+			ok, _ := client.ShouldSample("rate", defaultRate)
+			if ok {
+				sampled++
+			}
+		}
+		return sampled
+	}
+
+	if rate := float64(count(0)) / calls; math.Abs(rate-0.25) > 0.02 {
+		t.Errorf("Expected an empirical rate close to 0.25, got %f", rate)
+	}
+	repository.set("rate", 0)
+	if sampled := count(1); sampled != 0 {
+		t.Errorf("Expected a rate of 0 to never sample, got %d", sampled)
+	}
+	repository.set("rate", 1)
+	if sampled := count(0); sampled != calls {
+		t.Errorf("Expected a rate of 1 to always sample, got %d", sampled)
+	}
+
+	// Out-of-range and missing rates fall back to the default rate.
+	repository.set("rate", 1.5)
+	ok, err := client.ShouldSample("rate", 0)
+	if err != ErrInvalidRate || ok {
+		t.Errorf("Expected ErrInvalidRate and the default rate, got %t (%v)", ok, err)
+	}
+	ok, err = client.ShouldSample("missing", 1)
+	if err == nil || !ok {
+		t.Errorf("Expected an error and the default rate, got %t (%v)", ok, err)
+	}
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"time"
+)
+
+// Keys of a scheduled configuration, a value that changes at a set time:
+//
+//	rate_limit:
+//	  _value: 100
+//	  _next: 200
+//	  _effective_at: 2024-11-01T00:00:00Z
+//
+// Getters return _value until _effective_at, an RFC 3339 timestamp, has
+// passed on the Client's Clock, and _next from then on. Without _value the
+// configuration is missing until it becomes effective. Only top-level
+// configurations can be scheduled.
+const (
+	ScheduledValueKey = "_value"
+	ScheduledNextKey  = "_next"
+	EffectiveAtKey    = "_effective_at"
+)
+
+// resolveSchedule returns the value of config at now if it is a scheduled
+// configuration, and config itself otherwise. It reports whether the value is
+// present and whether it depends on the time.
+func resolveSchedule(config interface{}, now time.Time) (interface{}, bool, bool) {
+	schedule, ok := config.(map[string]interface{})
+	if !ok {
+		return config, true, false
+	}
+	next, hasNext := schedule[ScheduledNextKey]
+	effectiveAt, ok := effectiveTime(schedule[EffectiveAtKey])
+	if !hasNext || !ok {
+		return config, true, false
+	}
+	for key := range schedule {
+		if key != ScheduledValueKey && key != ScheduledNextKey && key != EffectiveAtKey {
+			return config, true, false
+		}
+	}
+	if !now.Before(effectiveAt) {
+		return next, true, true
+	}
+	value, ok := schedule[ScheduledValueKey]
+	return value, ok, true
+}
+
+// effectiveTime parses the effective-at timestamp of a scheduled configuration.
+func effectiveTime(value interface{}) (time.Time, bool) {
+	switch value := value.(type) {
+	case time.Time:
+		return value, true
+	case string:
+		effectiveAt, err := time.Parse(time.RFC3339, value)
+		return effectiveAt, err == nil
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when advanced.
+type fakeClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.Lock()
+	defer f.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) advance(d time.Duration) {
+	f.Lock()
+	defer f.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func TestScheduledConfig(t *testing.T) {
+	effectiveAt := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: effectiveAt.Add(-time.Hour)}
+	repository := &mapRepository{data: map[string]interface{}{
+		"rate_limit": map[string]interface{}{
+			ScheduledValueKey: 100,
+			ScheduledNextKey:  200,
+			EffectiveAtKey:    effectiveAt.Format(time.RFC3339),
+		},
+		"banner": map[string]interface{}{
+			ScheduledNextKey: "Sale!",
+			EffectiveAtKey:   effectiveAt,
+		},
+		// Maps with other keys are ordinary configurations.
+		"plain": map[string]interface{}{ScheduledNextKey: 1, EffectiveAtKey: "2024-11-01T00:00:00Z", "other": 2},
+	}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithClock(clock))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	err = client.Prefetch(map[string]interface{}{"rate_limit": new(int)})
+	if err != nil {
+		t.Errorf("Error prefetching: %s", err.Error())
+	}
+
+	if limit, _ := client.GetConfigInt("rate_limit", 0); limit != 100 {
+		t.Errorf("Expected the current value 100 before the effective time, got %d", limit)
+	}
+	var limit int
+	if err := client.GetConfig("rate_limit", &limit, nil); err != nil || limit != 100 {
+		t.Errorf("Expected GetConfig to read 100, got %d (%v)", limit, err)
+	}
+	if _, err := client.GetConfigString("banner", ""); err == nil {
+		t.Errorf("Expected banner to be missing before the effective time")
+	}
+
+	clock.advance(time.Hour)
+	if limit, _ := client.GetConfigInt("rate_limit", 0); limit != 200 {
+		t.Errorf("Expected the next value 200 at the effective time, got %d", limit)
+	}
+	if err := client.GetConfig("rate_limit", &limit, nil); err != nil || limit != 200 {
+		t.Errorf("Expected GetConfig not to serve the cached old value, got %d (%v)", limit, err)
+	}
+	if banner, _ := client.GetConfigString("banner", ""); banner != "Sale!" {
+		t.Errorf("Expected banner to be Sale!, got %s", banner)
+	}
+
+	var plain map[string]interface{}
+	if err := client.GetConfig("plain", &plain, nil); err != nil || plain["other"] != 2 {
+		t.Errorf("Expected plain to be read as a map, got %v (%v)", plain, err)
+	}
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrSchemaMismatch is returned by the refresh of a Client with WithSchema
+// when a configuration does not match its registered prototype.
+var ErrSchemaMismatch = errors.New("config does not match its schema")
+
+// ValidateTag is the struct tag of the fields of a schema prototype that
+// declares their constraints. The only constraint is `validate:"required"`,
+// which rejects a configuration that leaves the field at its zero value.
+const ValidateTag = "validate"
+
+// SchemaValidator is implemented by schema prototypes that check themselves
+// after decoding, for constraints struct tags cannot express.
+type SchemaValidator interface {
+	Validate() error
+}
+
+// schemaValidator returns a SnapshotValidator that decodes the configuration
+// with the given name of a snapshot, and its deployment color variant, into a
+// new value of the type of prototype, as GetConfig would, and checks its
+// required fields and its Validate method. A missing configuration is
+// rejected unless it is optional.
+func (c *Client) schemaValidator(name string, prototype interface{}) SnapshotValidator {
+	prototypeType := reflect.TypeOf(prototype)
+	for prototypeType.Kind() == reflect.Ptr {
+		prototypeType = prototypeType.Elem()
+	}
+	return func(snapshot map[string]interface{}) error {
+		keys := []string{name}
+		if c.deploymentColor != "" {
+			keys = append(keys, name+"@"+c.deploymentColor)
+		}
+		for i, key := range keys {
+			config, ok := snapshot[key]
+			if ok {
+				// The snapshot is about to be loaded, so TTLs count from now.
+				config, ok, _ = resolveExpiry(config, c.now(), c.now())
+			}
+			if ok {
+				config, ok, _ = resolveSchedule(config, c.now())
+			}
+			if !ok {
+				if i == 0 && !c.optionalKeys[name] {
+					return fmt.Errorf("%w: %s is missing", ErrSchemaMismatch, key)
+				}
+				continue
+			}
+			err := c.checkSchema(config, prototypeType)
+			if err != nil {
+				c.redactConfigError(name, &err)
+				return fmt.Errorf("%w: %s: %s", ErrSchemaMismatch, key, err.Error())
+			}
+		}
+		return nil
+	}
+}
+
+// checkSchema decodes config into a new value of prototypeType and checks it.
+func (c *Client) checkSchema(config interface{}, prototypeType reflect.Type) error {
+	marshal, err := c.marshal(config)
+	if err != nil {
+		return err
+	}
+	decoded := reflect.New(prototypeType)
+	err = c.unmarshal(marshal, decoded.Interface())
+	if err != nil {
+		return err
+	}
+	err = checkRequired(decoded.Elem(), "")
+	if err != nil {
+		return err
+	}
+	if validator, ok := decoded.Interface().(SchemaValidator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
+
+// checkRequired returns an error naming the first field of value, a struct
+// or anything else, tagged `validate:"required"` that is zero, looking into
+// nested structs.
+func checkRequired(value reflect.Value, path string) error {
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldPath := field.Name
+		if path != "" {
+			fieldPath = path + "." + field.Name
+		}
+		fieldValue := value.Field(i)
+		for _, constraint := range strings.Split(field.Tag.Get(ValidateTag), ",") {
+			if strings.TrimSpace(constraint) == "required" && fieldValue.IsZero() {
+				return fmt.Errorf("%s is required", fieldPath)
+			}
+		}
+		if fieldValue.Kind() == reflect.Ptr && !fieldValue.IsNil() {
+			fieldValue = fieldValue.Elem()
+		}
+		err := checkRequired(fieldValue, fieldPath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
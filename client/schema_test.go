@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type databaseSchema struct {
+	Host    string `yaml:"host" validate:"required"`
+	Port    int    `yaml:"port"`
+	Replica *struct {
+		Host string `yaml:"host" validate:"required"`
+	} `yaml:"replica"`
+}
+
+func (d databaseSchema) Validate() error {
+	if d.Port < 0 || d.Port > 65535 {
+		return errors.New("port is out of range")
+	}
+	return nil
+}
+
+func TestWithSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("database:\n  host: db1\n  port: 5432\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second, WithSchema("database", &databaseSchema{}))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// A good payload is accepted.
+	err = os.WriteFile(path, []byte("database:\n  host: db2\n  port: 5433\n  replica:\n    host: db3\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error forcing refresh: %s", err.Error())
+	}
+
+	// Bad payloads are rejected and the last good data is kept.
+	for _, content := range []string{
+		"database:\n  host: db4\n  port: fifty\n",
+		"database:\n  port: 5434\n",
+		"database:\n  host: db4\n  replica:\n    port: 1\n",
+		"database:\n  host: db4\n  port: 70000\n",
+		"database: db4\n",
+		"other: 1\n",
+	} {
+		err = os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Error writing file: %s", err.Error())
+		}
+		err = client.ForceRefresh(context.Background())
+		if !errors.Is(err, ErrSchemaMismatch) {
+			t.Errorf("Expected ErrSchemaMismatch for %q, got %v", content, err)
+		}
+		if _, lastErr := client.LastRefresh(); !errors.Is(lastErr, ErrSchemaMismatch) {
+			t.Errorf("Expected LastRefresh to report ErrSchemaMismatch, got %v", lastErr)
+		}
+		var database databaseSchema
+		err = client.GetConfig("database", &database, nil)
+		if err != nil || database.Host != "db2" || database.Port != 5433 {
+			t.Errorf("Expected the last good database db2:5433, got %+v (%v)", database, err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReloadOnSignal refreshes the repository with ForceRefresh whenever the
+// process receives one of the given signals, SIGHUP when none are given,
+// until ctx is canceled or the Client is closed. It returns right away and
+// listens in the background. It complements the refresh interval rather than
+// replacing it: the refresh loop keeps polling, and waits a full interval
+// again after each reload. The outcome of each reload is logged like that of
+// a periodic refresh. Call it once per Client; every call listens on its own.
+func (c *Client) ReloadOnSignal(ctx context.Context, sig ...os.Signal) {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sig...)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case received := <-signals:
+				c.log().Info("reloading configuration on signal", LogFields{"signal": received.String()})
+				err := c.ForceRefresh(ctx)
+				if errors.Is(err, ErrClientClosed) || ctx.Err() != nil {
+					return
+				}
+				c.logRefresh(err)
+			case <-ctx.Done():
+				return
+			case <-c.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReloadOnSignal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("name: John\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("Error finding process: %s", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client.ReloadOnSignal(ctx, syscall.SIGUSR1)
+	err = os.WriteFile(path, []byte("name: Jane\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	err = process.Signal(syscall.SIGUSR1)
+	if err != nil {
+		t.Skipf("Cannot send signals on this platform: %s", err.Error())
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for name, _ := client.GetConfigString("name", ""); name != "Jane"; name, _ = client.GetConfigString("name", "") {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the signal to reload the configuration, got %s", name)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// No reload happens once ctx is canceled. The signal must still be
+	// caught, or it would terminate the test process.
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	caught := make(chan os.Signal, 1)
+	signal.Notify(caught, syscall.SIGUSR1)
+	defer signal.Stop(caught)
+	err = os.WriteFile(path, []byte("name: Jack\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	_ = process.Signal(syscall.SIGUSR1)
+	<-caught
+	time.Sleep(20 * time.Millisecond)
+	if name, _ := client.GetConfigString("name", ""); name != "Jane" {
+		t.Errorf("Expected no reload after ctx is canceled, got %s", name)
+	}
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"reflect"
+)
+
+// SnapshotValidator checks invariants across the configurations of a refresh,
+// such as `min` being at most `max`. It receives every configuration as seen
+// by getters and returns an error to reject the refresh. The snapshot must
+// not be modified.
+type SnapshotValidator func(snapshot map[string]interface{}) error
+
+// validateSnapshot runs the snapshot validators on snapshot in the order they
+// were added, returning the first error.
+func (c *Client) validateSnapshot(snapshot map[string]interface{}) error {
+	for _, validator := range c.snapshotValidators {
+		if err := validator(snapshot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot returns a deep copy of every configuration as seen by getters, as
+// of the last refresh, which callers may modify freely, for example to write
+// it to a local fallback file. It waits for a refresh in progress, so the
+// snapshot is never half applied. Values are returned before scheduled and expiring
+// configurations are resolved, and after decryption, so the snapshot holds
+// plaintext secrets; configurations that could not be decrypted or parsed are left
+// out. It returns nil for repositories that do not implement source.KeyLister.
+func (c *Client) Snapshot() map[string]interface{} {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if c.values == nil {
+		return nil
+	}
+	snapshot := make(map[string]interface{}, len(c.values))
+	for key, value := range c.values {
+		if valueError(value) != nil {
+			continue
+		}
+		if value == nil {
+			snapshot[key] = nil
+			continue
+		}
+		snapshot[key] = deepCopy(reflect.ValueOf(value)).Interface()
+	}
+	return snapshot
+}
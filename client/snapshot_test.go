@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithSnapshotValidator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("min: 1\nmax: 10\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	errInverted := errors.New("min is greater than max")
+	validator := func(snapshot map[string]interface{}) error {
+		min, _ := snapshot["min"].(int)
+		max, _ := snapshot["max"].(int)
+		if min > max {
+			return errInverted
+		}
+		return nil
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second, WithSnapshotValidator(validator))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// A refresh that makes min greater than max is rejected.
+	err = os.WriteFile(path, []byte("min: 20\nmax: 10\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	err = client.ForceRefresh(context.Background())
+	if !errors.Is(err, errInverted) {
+		t.Errorf("Expected the refresh to be rejected, got %v", err)
+	}
+	min, _ := client.GetConfigInt("min", 0)
+	max, _ := client.GetConfigInt("max", 0)
+	if min != 1 || max != 10 {
+		t.Errorf("Expected the last accepted values 1 and 10, got %d and %d", min, max)
+	}
+
+	// A valid refresh is accepted.
+	err = os.WriteFile(path, []byte("min: 5\nmax: 10\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Errorf("Error forcing refresh: %s", err.Error())
+	}
+	min, _ = client.GetConfigInt("min", 0)
+	if min != 5 {
+		t.Errorf("Expected min to be 5, got %d", min)
+	}
+
+	// An invalid initial load fails NewClient.
+	err = os.WriteFile(path, []byte("min: 20\nmax: 10\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	_, err = NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second, WithSnapshotValidator(validator))
+	if !errors.Is(err, errInverted) {
+		t.Errorf("Expected NewClient to fail on an invalid snapshot, got %v", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("name: John\ndatabase:\n  hosts: [db1, db2]\n  port: 5432\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	snapshot := client.Snapshot()
+	if snapshot["name"] != "John" {
+		t.Errorf("Expected name to be John, got %v", snapshot["name"])
+	}
+	database, ok := snapshot["database"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected database to be a map, got %v", snapshot["database"])
+	}
+	hosts, ok := database["hosts"].([]interface{})
+	if !ok || len(hosts) != 2 {
+		t.Fatalf("Expected two hosts, got %v", database["hosts"])
+	}
+
+	// Changes to the snapshot, however deep, do not reach the client.
+	snapshot["name"] = "Jane"
+	database["port"] = 1
+	hosts[0] = "evil"
+	delete(snapshot, "database")
+	if name, _ := client.GetConfigString("name", ""); name != "John" {
+		t.Errorf("Expected name to stay John, got %s", name)
+	}
+	var current struct {
+		Hosts []string `yaml:"hosts"`
+		Port  int      `yaml:"port"`
+	}
+	err = client.GetConfig("database", &current, nil)
+	if err != nil || current.Port != 5432 || current.Hosts[0] != "db1" {
+		t.Errorf("Expected database to be unaffected, got %+v (%v)", current, err)
+	}
+	again := client.Snapshot()
+	if again["name"] != "John" || again["database"].(map[string]interface{})["hosts"].([]interface{})[0] != "db1" {
+		t.Errorf("Expected a fresh snapshot to be unaffected, got %v", again)
+	}
+}
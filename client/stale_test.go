@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithMaxStaleAge(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	repository := &flakyRepository{mapRepository: mapRepository{data: map[string]interface{}{
+		"name":   "John",
+		"server": map[string]interface{}{"host": "db.internal"},
+	}}}
+	client, err := NewClient(context.Background(), repository, time.Hour, WithClock(clock), WithMaxStaleAge(10*time.Minute), WithLogger(NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// Old data is not stale while refreshes succeed.
+	clock.advance(time.Hour)
+	if value, err := client.GetConfigString("name", "default"); err != nil || value != "John" {
+		t.Errorf("Expected John without failed refreshes, got %s (%v)", value, err)
+	}
+
+	_ = client.ForceRefresh(context.Background())
+	repository.failing.Store(true)
+	_ = client.ForceRefresh(context.Background())
+
+	// Up to the maximum stale age the last good value is served.
+	clock.advance(10 * time.Minute)
+	if value, err := client.GetConfigString("name", "default"); err != nil || value != "John" {
+		t.Errorf("Expected John at the maximum stale age, got %s (%v)", value, err)
+	}
+
+	clock.advance(time.Nanosecond)
+	if value, err := client.GetConfigString("name", "default"); !errors.Is(err, ErrStaleConfig) || value != "default" {
+		t.Errorf("Expected the default and ErrStaleConfig past the maximum stale age, got %s (%v)", value, err)
+	}
+	if value, err := client.GetConfigInt("name", 7); !errors.Is(err, ErrStaleConfig) || value != 7 {
+		t.Errorf("Expected the default int and ErrStaleConfig, got %d (%v)", value, err)
+	}
+	defaults := serverConfig{Host: "localhost"}
+	var server serverConfig
+	if err := client.GetConfig("server", &server, defaults); !errors.Is(err, ErrStaleConfig) || server.Host != "localhost" {
+		t.Errorf("Expected the default struct and ErrStaleConfig, got %+v (%v)", server, err)
+	}
+
+	// A successful refresh makes the data fresh again.
+	repository.failing.Store(false)
+	_ = client.ForceRefresh(context.Background())
+	if value, err := client.GetConfigString("name", "default"); err != nil || value != "John" {
+		t.Errorf("Expected John after a successful refresh, got %s (%v)", value, err)
+	}
+}
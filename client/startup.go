@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+)
+
+// initialRefresh runs the refresh NewClient blocks on. It makes up to
+// initialAttempts attempts, doubling the wait between them from
+// initialBackoff, and gives up early with the error of ctx when ctx is done.
+// On failure the Client falls back to the last good file, if any, and with a
+// degraded start a failure is only logged, and the Client serves defaults
+// until a later refresh succeeds.
+func (c *Client) initialRefresh(ctx context.Context) error {
+	attempts := c.initialAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := c.initialBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = c.refreshRepository()
+		c.logRefresh(err)
+		if err == nil || isPartial(err) || attempt == attempts {
+			break
+		}
+		wait, stop := c.after(backoff)
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			stop()
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+	if isPartial(err) {
+		// The keys that parsed are served, and LastRefresh reports the others.
+		return nil
+	}
+	if err != nil && c.seedLastGood(err) {
+		return nil
+	}
+	if err != nil && c.degradedStart {
+		c.log().Warn("starting degraded, serving defaults until a refresh succeeds", LogFields{"error": err})
+		return nil
+	}
+	return err
+}
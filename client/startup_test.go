@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithInitialRefreshRetries(t *testing.T) {
+	repository := &recoveringRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}}
+	repository.failures.Store(2)
+	client, err := NewClient(context.Background(), repository, time.Hour, WithInitialRefreshRetries(3, time.Millisecond))
+	if err != nil {
+		t.Fatalf("Expected the third attempt to succeed, got %s", err.Error())
+	}
+	defer client.Close()
+	if err := client.Healthy(); err != nil {
+		t.Errorf("Expected the client to be healthy, got %v", err)
+	}
+
+	// The attempts run out before the source recovers.
+	repository.failures.Store(5)
+	_, err = NewClient(context.Background(), repository, time.Hour, WithInitialRefreshRetries(3, time.Millisecond))
+	if err == nil {
+		t.Errorf("Expected an error once every attempt failed")
+	}
+	if remaining := repository.failures.Load(); remaining != 2 {
+		t.Errorf("Expected 3 attempts, got %d", 5-remaining)
+	}
+}
+
+func TestWithInitialRefreshRetriesContext(t *testing.T) {
+	repository := &failingRepository{err: errors.New("backend unavailable")}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := NewClient(ctx, repository, time.Hour, WithInitialRefreshRetries(5, time.Hour))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the retries to stop with the context, got %v", err)
+	}
+}
+
+func TestWithDegradedStart(t *testing.T) {
+	repository := &recoveringRepository{}
+	repository.failures.Store(3)
+	client, err := NewClient(context.Background(), repository, 10*time.Millisecond, WithInitialRefreshRetries(2, time.Millisecond), WithDegradedStart(), WithLogger(NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Expected a degraded start, got %s", err.Error())
+	}
+	defer client.Close()
+	if value, _ := client.GetConfigString("name", "default"); value != "default" {
+		t.Errorf("Expected the default while degraded, got %s", value)
+	}
+	if err := client.Healthy(); !errors.Is(err, ErrUnhealthy) {
+		t.Errorf("Expected the client to be unhealthy, got %v", err)
+	}
+
+	// The background refresh recovers once the source is back.
+	repository.Lock()
+	repository.data = map[string]interface{}{"name": "John"}
+	repository.Unlock()
+	deadline := time.Now().Add(2 * time.Second)
+	for client.Healthy() != nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := client.Healthy(); err != nil {
+		t.Errorf("Expected a later refresh to succeed, got %v", err)
+	}
+	if value, _ := client.GetConfigString("name", "default"); value != "John" {
+		t.Errorf("Expected the refreshed value, got %s", value)
+	}
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/source"
+)
+
+// NewStaticClient creates a Client that refreshes the repository once, before
+// it returns, and never again, for scripts and short-lived jobs that read
+// their configuration once. It starts no goroutine, so forgetting to Close it
+// leaks nothing, and Close only releases the repository. ForceRefresh still
+// refreshes it on demand. Options apply as with NewClient, except that the
+// refresh is never moved to the background by WithReadinessGate. It returns
+// the error of ctx if ctx is done, and the error of the refresh if it fails.
+func NewStaticClient(ctx context.Context, repository source.Repository, opts ...Option) (*Client, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client := newClient(repository, opts)
+	// There is no refresh goroutine to wait for.
+	client.stopped = make(chan struct{})
+	close(client.stopped)
+	if !client.skipInitialRefresh {
+		if err := client.initialRefresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+	SetDefaultClient(client)
+	return client, nil
+}
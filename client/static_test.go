@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingRepository is a mapRepository counting its refreshes.
+type countingRepository struct {
+	mapRepository
+	refreshes atomic.Int32
+}
+
+func (c *countingRepository) Refresh() error {
+	c.refreshes.Add(1)
+	return nil
+}
+
+func TestNewStaticClient(t *testing.T) {
+	before := refreshGoroutines()
+	repository := &countingRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John", "age": 30}}}
+	client, err := NewStaticClient(context.Background(), repository, WithRefreshBackoff(time.Millisecond, 2))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+
+	if value, err := client.GetConfigString("name", ""); err != nil || value != "John" {
+		t.Errorf("Expected John, got %s (%v)", value, err)
+	}
+	if value, err := client.GetConfigInt("age", 0); err != nil || value != 30 {
+		t.Errorf("Expected 30, got %d (%v)", value, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if refreshes := repository.refreshes.Load(); refreshes != 1 {
+		t.Errorf("Expected a single refresh, got %d", refreshes)
+	}
+	if after := refreshGoroutines(); after > before {
+		t.Errorf("Expected no refresh goroutine, got %d running, %d before", after, before)
+	}
+	select {
+	case <-client.Done():
+	default:
+		t.Errorf("Expected Done to be closed from the start")
+	}
+
+	client.Close()
+	if _, err := client.GetConfigString("name", ""); !errors.Is(err, ErrClientClosed) {
+		t.Errorf("Expected ErrClientClosed after Close, got %v", err)
+	}
+}
+
+func TestNewStaticClientErrors(t *testing.T) {
+	repository := &flakyRepository{mapRepository: mapRepository{data: map[string]interface{}{}}}
+	repository.failing.Store(true)
+	if _, err := NewStaticClient(context.Background(), repository, WithLogger(NewNopLogger())); err == nil {
+		t.Errorf("Expected the error of the refresh")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewStaticClient(ctx, repository); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the error of the context, got %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// ChangeCallback is called with the previous and the new value of a
+// configuration that a refresh changed. The previous value is nil for an
+// added configuration and the new value is nil for a removed one.
+type ChangeCallback func(oldVal, newVal interface{})
+
+// SubscribeOption configures a subscription made with Subscribe.
+type SubscribeOption func(*subscription)
+
+// subscription holds the options of a subscription.
+type subscription struct {
+	initial bool // deliver the current value when subscribing
+}
+
+// WithInitialValue delivers the current value of the configuration when
+// subscribing, before any change, as a change from nil. A subscriber that
+// starts after the configuration has changed thus starts from its current
+// value, without a race between reading the value and subscribing, as no
+// refresh runs in between. Nothing is delivered while the configuration is
+// missing. Such subscriptions wait for a refresh in progress, so they must
+// not be made from a RefreshListener.
+func WithInitialValue() SubscribeOption {
+	return func(s *subscription) {
+		s.initial = true
+	}
+}
+
+// subscribe subscribes listener to the refreshes of the Client and, with
+// WithInitialValue, first calls initial with the current value of the
+// configuration with the given name, if there is one.
+func (c *Client) subscribe(name string, opts []SubscribeOption, listener RefreshListener, initial func(value interface{})) (unsubscribe func()) {
+	var options subscription
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if !options.initial {
+		return c.events.subscribe(listener)
+	}
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if value, ok := c.values[name]; ok {
+		initial(value)
+	}
+	return c.events.subscribe(listener)
+}
+
+// Subscribe calls fn whenever a refresh changes the value of the
+// configuration with the given name, as seen by getters, compared with
+// reflect.DeepEqual. Each call runs in its own goroutine, so a slow callback
+// does not hold up the refresh, and calls for successive changes may run
+// concurrently. Changes are only detected for repositories that implement
+// source.KeyLister. The returned function unsubscribes and may be called
+// concurrently and more than once; a callback already started still runs.
+func (c *Client) Subscribe(name string, fn ChangeCallback, opts ...SubscribeOption) (unsubscribe func()) {
+	return c.subscribe(name, opts, func(result RefreshResult) {
+		if !result.changed(name) {
+			return
+		}
+		go fn(result.previous[name], result.current[name])
+	}, func(value interface{}) {
+		go fn(nil, value)
+	})
+}
+
+// Subscribe delivers the configuration with the given name decoded as T, the
+// way GetConfig decodes it, each time a refresh changes it, as detected by
+// Client.Subscribe. Updates arrive in the order of the refreshes. The channel
+// holds the latest value not received yet: a receiver that falls behind gets
+// the newest value, never a stale one, and the refresh never waits for it.
+// Values that do not decode as T are logged and skipped, and so is the
+// removal of the configuration. The returned function unsubscribes and closes
+// the channel, and may be called more than once.
+func Subscribe[T any](c *Client, name string, opts ...SubscribeOption) (<-chan T, func()) {
+	updates := make(chan T, 1)
+	var mu sync.Mutex
+	closed := false
+	deliver := func(config interface{}) {
+		var value T
+		err := c.decodeConfig(config, &value)
+		if err != nil {
+			c.redactConfigError(name, &err)
+			c.log().Error("error decoding config update, skipping it", LogFields{"config": name, "error": err})
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		// Replace the value the receiver has not taken yet, if any.
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- value
+	}
+	unsubscribe := c.subscribe(name, opts, func(result RefreshResult) {
+		if !result.changed(name) {
+			return
+		}
+		config, ok := result.current[name]
+		if !ok {
+			c.log().Debug("config removed, skipping update", LogFields{"config": name})
+			return
+		}
+		deliver(config)
+	}, deliver)
+	return updates, func() {
+		unsubscribe()
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			closed = true
+			close(updates)
+		}
+	}
+}
+
+// WatchKey delivers the value of the configuration with the given name, as
+// seen by getters, each time a refresh changes it, as detected by Subscribe,
+// and nil when a refresh removes it. Like with the generic Subscribe, the
+// channel holds the latest value not received yet, so a receiver that falls
+// behind gets the newest value and the refresh never waits for it. The
+// channel is closed when ctx is canceled or the Client is closed.
+func (c *Client) WatchKey(ctx context.Context, name string) <-chan interface{} {
+	updates := make(chan interface{}, 1)
+	var mu sync.Mutex
+	closed := false
+	unsubscribe := c.events.subscribe(func(result RefreshResult) {
+		if !result.changed(name) {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		// Replace the value the receiver has not taken yet, if any.
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- result.current[name]
+	})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.Done():
+		}
+		unsubscribe()
+		mu.Lock()
+		defer mu.Unlock()
+		closed = true
+		close(updates)
+	}()
+	return updates
+}
+
+// changed reports whether the refresh changed the configuration with the given name.
+func (r RefreshResult) changed(name string) bool {
+	i := sort.SearchStrings(r.ChangedKeys, name)
+	return i < len(r.ChangedKeys) && r.ChangedKeys[i] == name
+}
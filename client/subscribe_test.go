@@ -0,0 +1,330 @@
+package client
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type change struct {
+	oldVal interface{}
+	newVal interface{}
+}
+
+func TestSubscribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		t.Helper()
+		err := os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Error writing file: %s", err.Error())
+		}
+	}
+	write("dsn: postgres://a\nname: John\n")
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	changes := make(chan change, 10)
+	unsubscribe := client.Subscribe("dsn", func(oldVal, newVal interface{}) {
+		changes <- change{oldVal, newVal}
+	})
+	// A slow callback does not hold up the refresh.
+	release := make(chan struct{})
+	var slow sync.WaitGroup
+	slow.Add(1)
+	unsubscribeSlow := client.Subscribe("dsn", func(oldVal, newVal interface{}) {
+		defer slow.Done()
+		<-release
+	})
+
+	// Only changes of the subscribed key are reported.
+	write("dsn: postgres://a\nname: Jane\n")
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error forcing refresh: %s", err.Error())
+	}
+	write("dsn: postgres://b\nname: Jane\n")
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error forcing refresh: %s", err.Error())
+	}
+	select {
+	case got := <-changes:
+		if got.oldVal != "postgres://a" || got.newVal != "postgres://b" {
+			t.Errorf("Expected a change from postgres://a to postgres://b, got %v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the callback to be called")
+	}
+	select {
+	case got := <-changes:
+		t.Errorf("Expected a single change, got another one %v", got)
+	default:
+	}
+	close(release)
+	slow.Wait()
+	unsubscribeSlow()
+
+	// A removed key reports a nil new value.
+	write("name: Jane\n")
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error forcing refresh: %s", err.Error())
+	}
+	select {
+	case got := <-changes:
+		if got.oldVal != "postgres://b" || got.newVal != nil {
+			t.Errorf("Expected a removal of postgres://b, got %v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the callback to be called for a removal")
+	}
+
+	// Unsubscribing is safe concurrently and stops the callbacks.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unsubscribe()
+		}()
+	}
+	wg.Wait()
+	write("dsn: postgres://c\n")
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error forcing refresh: %s", err.Error())
+	}
+	select {
+	case got := <-changes:
+		t.Errorf("Expected no change after unsubscribing, got %v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscribeTyped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	type database struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	}
+	err := os.WriteFile(path, []byte("db:\n  host: a\n  port: 1\nname: John\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	write := func(content string) {
+		t.Helper()
+		err := os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Error writing file: %s", err.Error())
+		}
+		err = client.ForceRefresh(context.Background())
+		if err != nil {
+			t.Fatalf("Error forcing refresh: %s", err.Error())
+		}
+	}
+	updates, unsubscribe := Subscribe[database](client, "db")
+	expect := func(expected database) {
+		t.Helper()
+		select {
+		case got := <-updates:
+			if got != expected {
+				t.Errorf("Expected %+v, got %+v", expected, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected an update to %+v", expected)
+		}
+	}
+
+	// Changes of the key are delivered decoded, others are not delivered.
+	write("db:\n  host: a\n  port: 1\nname: Jane\n")
+	write("db:\n  host: b\n  port: 2\nname: Jane\n")
+	expect(database{Host: "b", Port: 2})
+
+	// Values that do not decode are skipped.
+	write("db:\n  host: b\n  port: not-a-port\nname: Jane\n")
+	write("db:\n  host: c\n  port: 3\nname: Jane\n")
+	expect(database{Host: "c", Port: 3})
+
+	// A receiver that falls behind gets the newest value.
+	write("db:\n  host: d\n  port: 4\nname: Jane\n")
+	write("db:\n  host: e\n  port: 5\nname: Jane\n")
+	expect(database{Host: "e", Port: 5})
+	select {
+	case got := <-updates:
+		t.Errorf("Expected no stale update, got %+v", got)
+	default:
+	}
+
+	// Unsubscribing closes the channel.
+	unsubscribe()
+	unsubscribe()
+	write("db:\n  host: f\n  port: 6\nname: Jane\n")
+	if _, ok := <-updates; ok {
+		t.Errorf("Expected the channel to be closed")
+	}
+}
+
+func TestSubscribeInitialValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("port: 1\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	write := func(content string) {
+		t.Helper()
+		err := os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Error writing file: %s", err.Error())
+		}
+		err = client.ForceRefresh(context.Background())
+		if err != nil {
+			t.Fatalf("Error forcing refresh: %s", err.Error())
+		}
+	}
+
+	// Subscribers registered after a change receive the current value first.
+	write("port: 2\n")
+	changes := make(chan change, 10)
+	unsubscribe := client.Subscribe("port", func(oldVal, newVal interface{}) {
+		changes <- change{oldVal, newVal}
+	}, WithInitialValue())
+	defer unsubscribe()
+	updates, unsubscribeTyped := Subscribe[int](client, "port", WithInitialValue())
+	defer unsubscribeTyped()
+	select {
+	case got := <-changes:
+		if got.oldVal != nil || got.newVal != 2 {
+			t.Errorf("Expected an initial change from nil to 2, got %v to %v", got.oldVal, got.newVal)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the initial value, timed out")
+	}
+	select {
+	case got := <-updates:
+		if got != 2 {
+			t.Errorf("Expected the initial value 2, got %d", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the initial value, timed out")
+	}
+
+	// Later changes follow.
+	write("port: 3\n")
+	select {
+	case got := <-changes:
+		if got.oldVal != 2 || got.newVal != 3 {
+			t.Errorf("Expected a change from 2 to 3, got %v to %v", got.oldVal, got.newVal)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected a change, timed out")
+	}
+	if got := <-updates; got != 3 {
+		t.Errorf("Expected the update 3, got %d", got)
+	}
+
+	// Without the option, or for a missing configuration, nothing is delivered on subscribing.
+	plain, unsubscribePlain := Subscribe[int](client, "port")
+	defer unsubscribePlain()
+	missing, unsubscribeMissing := Subscribe[int](client, "missing", WithInitialValue())
+	defer unsubscribeMissing()
+	select {
+	case got := <-plain:
+		t.Errorf("Expected no initial value without the option, got %d", got)
+	case got := <-missing:
+		t.Errorf("Expected no initial value for a missing config, got %d", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestWatchKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		t.Helper()
+		err := os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Error writing file: %s", err.Error())
+		}
+	}
+	write("dsn: postgres://a\nname: John\n")
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := client.WatchKey(ctx, "dsn")
+
+	// A refresh that leaves the key unchanged emits nothing.
+	write("dsn: postgres://a\nname: Jane\n")
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error forcing refresh: %s", err.Error())
+	}
+	select {
+	case value := <-updates:
+		t.Errorf("Expected no update for an unchanged key, got %v", value)
+	default:
+	}
+
+	// A change is emitted, and a receiver that falls behind gets the newest value.
+	write("dsn: postgres://b\nname: Jane\n")
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error forcing refresh: %s", err.Error())
+	}
+	write("dsn: postgres://c\nname: Jane\n")
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error forcing refresh: %s", err.Error())
+	}
+	select {
+	case value := <-updates:
+		if value != "postgres://c" {
+			t.Errorf("Expected postgres://c, got %v", value)
+		}
+	default:
+		t.Errorf("Expected an update for a changed key, got none")
+	}
+
+	// The channel is closed when ctx is canceled.
+	cancel()
+	select {
+	case value, ok := <-updates:
+		if ok {
+			t.Errorf("Expected the channel to be closed, got %v", value)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected the channel to be closed on cancel")
+	}
+
+	// And when the Client is closed.
+	updates = client.WatchKey(context.Background(), "dsn")
+	client.Close()
+	select {
+	case value, ok := <-updates:
+		if ok {
+			t.Errorf("Expected the channel to be closed, got %v", value)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected the channel to be closed on Close")
+	}
+}
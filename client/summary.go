@@ -0,0 +1,68 @@
+package client
+
+import (
+	"sync"
+)
+
+// ChangeSummaryHandler receives the sorted keys a successful refresh added,
+// removed and changed, any of which may be empty.
+type ChangeSummaryHandler func(added, removed, changed []string)
+
+// changeSummary calls a ChangeSummaryHandler off the refreshing goroutine, one
+// summary at a time and in the order of the refreshes.
+type changeSummary struct {
+	handler ChangeSummaryHandler
+	mu      sync.Mutex
+	pending []RefreshResult // results waiting for the handler
+	running bool            // whether a goroutine is draining pending
+}
+
+// listen queues the summary of a successful refresh, starting a goroutine to
+// call the handler unless one is already draining the queue.
+func (s *changeSummary) listen(result RefreshResult) {
+	if result.Err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = append(s.pending, result)
+	if !s.running {
+		s.running = true
+		go s.drain()
+	}
+}
+
+// drain calls the handler with the queued summaries until none is left.
+func (s *changeSummary) drain() {
+	for {
+		s.mu.Lock()
+		if len(s.pending) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		result := s.pending[0]
+		s.pending = s.pending[1:]
+		s.mu.Unlock()
+		s.handler(summarize(result))
+	}
+}
+
+// summarize splits the changed keys of result into those it added, removed
+// and changed.
+func summarize(result RefreshResult) (added, removed, changed []string) {
+	added, removed, changed = []string{}, []string{}, []string{}
+	for _, key := range result.ChangedKeys {
+		_, before := result.previous[key]
+		_, after := result.current[key]
+		switch {
+		case !before:
+			added = append(added, key)
+		case !after:
+			removed = append(removed, key)
+		default:
+			changed = append(changed, key)
+		}
+	}
+	return added, removed, changed
+}
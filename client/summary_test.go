@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type changeSummaryCall struct {
+	added, removed, changed []string
+}
+
+func TestWithChangeSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Error writing file: %s", err.Error())
+		}
+	}
+	write("name: John\nage: 30\n")
+	calls := make(chan changeSummaryCall, 10)
+	handler := func(added, removed, changed []string) {
+		calls <- changeSummaryCall{added, removed, changed}
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, time.Hour, WithChangeSummary(handler), WithLogger(NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	refresh := func(content string) {
+		t.Helper()
+		write(content)
+		if err := client.ForceRefresh(context.Background()); err != nil {
+			t.Fatalf("Error refreshing client: %s", err.Error())
+		}
+	}
+	refresh("name: Jane\nage: 30\nrole: admin\n")
+	refresh("name: Jane\nrole: admin\n")
+	refresh("name: Jane\nrole: admin\n")
+	// A failed refresh is not summarized.
+	write("name: [unterminated")
+	if client.ForceRefresh(context.Background()) == nil {
+		t.Fatalf("Expected an error refreshing an invalid file")
+	}
+
+	expected := []changeSummaryCall{
+		{added: []string{"age", "name"}, removed: []string{}, changed: []string{}},
+		{added: []string{"role"}, removed: []string{}, changed: []string{"name"}},
+		{added: []string{}, removed: []string{"age"}, changed: []string{}},
+		{added: []string{}, removed: []string{}, changed: []string{}},
+	}
+	for i, want := range expected {
+		select {
+		case got := <-calls:
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected summary %d to be %+v, got %+v", i, want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected summary %d, got none", i)
+		}
+	}
+	select {
+	case got := <-calls:
+		t.Errorf("Expected no summary of the failed refresh, got %+v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWithChangeSummaryDoesNotBlockRefresh(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	handler := func(added, removed, changed []string) {
+		<-release
+	}
+	client, _ := newMapClient(t, map[string]interface{}{"name": "John"})
+	client.events.subscribe((&changeSummary{handler: handler}).listen)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = client.ForceRefresh(context.Background())
+		_ = client.ForceRefresh(context.Background())
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected refreshes not to wait for a blocked handler")
+	}
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// GetConfigTemplate retrieves the string configuration with the given name,
+// parses it as a text/template and renders it with data. When data is nil the
+// template is rendered against the configurations of the repository, if it
+// implements source.KeyLister. Templates can also read any configuration with
+// the config function, as in `Hello {{ config "name" }}`. Parse and execute
+// errors are returned.
+func (c *Client) GetConfigTemplate(name string, data interface{}) (string, error) {
+	text, err := c.GetConfigString(name, "")
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"config": func(key string) (interface{}, error) {
+			value, ok := c.getData(key)
+			if !ok {
+				return nil, fmt.Errorf("config %s not found", key)
+			}
+			return value, nil
+		},
+	}).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	if data == nil {
+		data = c.currentValues()
+	}
+	var rendered strings.Builder
+	err = tmpl.Execute(&rendered, data)
+	if err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetConfigTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `name: John
+greeting: "Hello {{ .name }}"
+farewell: "Bye {{ config \"name\" }}, see you in {{ .city }}"
+broken: "Hello {{ .name"
+missing: "Hello {{ config \"nobody\" }}"
+`
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// Without data, the template is rendered against the config.
+	greeting, err := client.GetConfigTemplate("greeting", nil)
+	if err != nil {
+		t.Errorf("Error rendering greeting: %s", err.Error())
+	}
+	if greeting != "Hello John" {
+		t.Errorf("Expected Hello John, got %s", greeting)
+	}
+
+	// With data, the config is still reachable through the config function.
+	farewell, err := client.GetConfigTemplate("farewell", map[string]string{"city": "Paris"})
+	if err != nil {
+		t.Errorf("Error rendering farewell: %s", err.Error())
+	}
+	if farewell != "Bye John, see you in Paris" {
+		t.Errorf("Expected Bye John, see you in Paris, got %s", farewell)
+	}
+	greeting, err = client.GetConfigTemplate("greeting", struct{ Name string }{Name: "Jane"})
+	if err == nil {
+		t.Errorf("Expected error for a field missing from the data, got %s", greeting)
+	}
+
+	for _, name := range []string{"broken", "missing", "nothing"} {
+		if _, err := client.GetConfigTemplate(name, nil); err == nil {
+			t.Errorf("Expected error rendering %s, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// TenantsKey is the configuration holding the per-tenant overrides, as a map
+// of tenant IDs to maps of configuration names to their values.
+const TenantsKey = "tenants"
+
+// TenantResolver returns the ID of the tenant a context belongs to, or an
+// empty string when the context has no tenant.
+type TenantResolver func(ctx context.Context) string
+
+// tenantData looks up the configuration with the given name for the tenant
+// of ctx under `tenants.<id>.<name>`, falling back to the shared value. It
+// returns the error of ctx if ctx is done, an error wrapping ErrStaleConfig
+// if the data is too stale, and the decryption error of a configuration that
+// could not be decrypted. A configuration that is present but empty, such as
+// `key:`, `key: ~` or `key: null` in YAML, is returned as nil, for which the
+// typed getters return their default value and no error.
+func (c *Client) tenantData(ctx context.Context, name string) (interface{}, bool, error) {
+	if c.isClosed.Load() {
+		return nil, false, ErrClientClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	if err := c.awaitReadiness(ctx); err != nil {
+		return nil, false, err
+	}
+	if err := c.checkStale(name); err != nil {
+		return nil, false, err
+	}
+	config, ok := c.tenantOverride(ctx, name)
+	if !ok {
+		config, ok = c.getData(name)
+	}
+	if err := valueError(config); err != nil {
+		return nil, false, err
+	}
+	return config, ok, nil
+}
+
+// tenantOverride returns the override of the configuration with the given
+// name for the tenant of ctx, if it has one.
+func (c *Client) tenantOverride(ctx context.Context, name string) (interface{}, bool) {
+	if c.tenantResolver == nil {
+		return nil, false
+	}
+	tenant := c.tenantResolver(ctx)
+	if tenant == "" {
+		return nil, false
+	}
+	tenants, _ := c.getData(TenantsKey)
+	tenantsMap, _ := tenants.(map[string]interface{})
+	overrides, _ := tenantsMap[tenant].(map[string]interface{})
+	config, ok := overrides[name]
+	return config, ok
+}
+
+// GetConfigStringContext retrieves the string configuration with the given
+// name for the tenant of ctx, falling back to the shared value.
+// It returns the error of ctx if ctx is done.
+func (c *Client) GetConfigStringContext(ctx context.Context, name string, defaultValue string) (_ string, err error) {
+	defer c.observe("GetConfigString", name, &err)
+	return c.getConfigString(ctx, name, defaultValue)
+}
+
+// getConfigString is GetConfigStringContext for getters built on it, which
+// report the read themselves.
+func (c *Client) getConfigString(ctx context.Context, name string, defaultValue string) (string, error) {
+	config, ok, err := c.tenantData(ctx, name)
+	if err != nil {
+		return defaultValue, err
+	}
+	if !ok {
+		return defaultValue, c.notFound(name)
+	}
+	if config == nil {
+		return defaultValue, nil
+	}
+	configString, ok := config.(string)
+	if !ok {
+		return defaultValue, typeMismatch(name, "a string")
+	}
+	return configString, nil
+}
+
+// GetConfigIntContext retrieves the int configuration with the given name
+// for the tenant of ctx, falling back to the shared value.
+// It returns the error of ctx if ctx is done.
+func (c *Client) GetConfigIntContext(ctx context.Context, name string, defaultValue int) (_ int, err error) {
+	defer c.observe("GetConfigInt", name, &err)
+	config, ok, err := c.tenantData(ctx, name)
+	if err != nil {
+		return defaultValue, err
+	}
+	if !ok {
+		return defaultValue, c.notFound(name)
+	}
+	if config == nil {
+		return defaultValue, nil
+	}
+	configInt, err := toInt(config)
+	if err != nil {
+		return defaultValue, keyError(name, err)
+	}
+	return configInt, nil
+}
+
+// GetConfigFloatContext retrieves the float configuration with the given
+// name for the tenant of ctx, falling back to the shared value.
+// It returns the error of ctx if ctx is done.
+func (c *Client) GetConfigFloatContext(ctx context.Context, name string, defaultValue float64) (_ float64, err error) {
+	defer c.observe("GetConfigFloat", name, &err)
+	config, ok, err := c.tenantData(ctx, name)
+	if err != nil {
+		return defaultValue, err
+	}
+	if !ok {
+		return defaultValue, c.notFound(name)
+	}
+	if config == nil {
+		return defaultValue, nil
+	}
+	configFloat, ok := config.(float64)
+	if !ok {
+		return defaultValue, typeMismatch(name, "a float")
+	}
+	return configFloat, nil
+}
+
+// GetConfigArrayOfStringsContext retrieves the string array configuration
+// with the given name for the tenant of ctx, falling back to the shared value.
+// It returns the error of ctx if ctx is done.
+func (c *Client) GetConfigArrayOfStringsContext(ctx context.Context, name string, defaultValue []string) (_ []string, err error) {
+	defer c.observe("GetConfigArrayOfStrings", name, &err)
+	config, ok, err := c.tenantData(ctx, name)
+	if err != nil {
+		return defaultValue, err
+	}
+	if !ok {
+		return defaultValue, c.notFound(name)
+	}
+	if config == nil {
+		return defaultValue, nil
+	}
+	configArray, ok := config.([]interface{})
+	if !ok {
+		return defaultValue, typeMismatch(name, "an array of strings")
+	}
+	output := []string{}
+	for _, v := range configArray {
+		str, ok := v.(string)
+		if !ok {
+			return defaultValue, typeMismatch(name, "an array of strings")
+		}
+		output = append(output, str)
+	}
+	return output, nil
+}
+
+// GetConfigBoolContext retrieves the bool configuration with the given name
+// for the tenant of ctx, falling back to the shared value. Like GetConfigBool,
+// it accepts the string encodings of a bool. It returns the error of ctx if
+// ctx is done.
+func (c *Client) GetConfigBoolContext(ctx context.Context, name string, defaultValue bool) (_ bool, err error) {
+	defer c.observe("GetConfigBool", name, &err)
+	config, ok, err := c.tenantData(ctx, name)
+	if err != nil {
+		return defaultValue, err
+	}
+	if !ok {
+		return defaultValue, c.notFound(name)
+	}
+	if config == nil {
+		return defaultValue, nil
+	}
+	switch value := config.(type) {
+	case bool:
+		return value, nil
+	case string:
+		configBool, err := strconv.ParseBool(value)
+		if err != nil {
+			return defaultValue, keyError(name, fmt.Errorf("%w: not a bool: %q", ErrTypeMismatch, value))
+		}
+		return configBool, nil
+	}
+	return defaultValue, keyError(name, fmt.Errorf("%w: not a bool: %T", ErrTypeMismatch, config))
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type tenantKey struct{}
+
+func TestWithTenantResolver(t *testing.T) {
+	repository := &mapRepository{data: map[string]interface{}{
+		"quota":   10,
+		"plan":    "free",
+		"regions": []interface{}{"us"},
+		"tenants": map[string]interface{}{
+			"acme":   map[string]interface{}{"quota": 100, "regions": []interface{}{"us", "eu"}},
+			"globex": map[string]interface{}{"plan": "enterprise"},
+		},
+	}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithTenantResolver(func(ctx context.Context) string {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return tenant
+	}))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	acme := context.WithValue(context.Background(), tenantKey{}, "acme")
+	globex := context.WithValue(context.Background(), tenantKey{}, "globex")
+	other := context.WithValue(context.Background(), tenantKey{}, "initech")
+
+	tests := []struct {
+		ctx      context.Context
+		quota    int
+		plan     string
+		regions  int
+		describe string
+	}{
+		{acme, 100, "free", 2, "acme"},
+		{globex, 10, "enterprise", 1, "globex"},
+		{other, 10, "free", 1, "an unknown tenant"},
+		{context.Background(), 10, "free", 1, "no tenant"},
+	}
+	for _, test := range tests {
+		quota, err := client.GetConfigIntContext(test.ctx, "quota", 0)
+		if err != nil || quota != test.quota {
+			t.Errorf("Expected quota %d for %s, got %d (%v)", test.quota, test.describe, quota, err)
+		}
+		plan, err := client.GetConfigStringContext(test.ctx, "plan", "")
+		if err != nil || plan != test.plan {
+			t.Errorf("Expected plan %s for %s, got %s (%v)", test.plan, test.describe, plan, err)
+		}
+		regions, err := client.GetConfigArrayOfStringsContext(test.ctx, "regions", nil)
+		if err != nil || len(regions) != test.regions {
+			t.Errorf("Expected %d regions for %s, got %v (%v)", test.regions, test.describe, regions, err)
+		}
+	}
+
+	ratio, err := client.GetConfigFloatContext(acme, "ratio", 0.5)
+	if err == nil || ratio != 0.5 {
+		t.Errorf("Expected the default and an error for a missing key, got %f (%v)", ratio, err)
+	}
+}
+
+func TestGetConfigContext(t *testing.T) {
+	repository := &mapRepository{data: map[string]interface{}{
+		"limits":  map[string]interface{}{"quota": 10},
+		"enabled": "true",
+		"tenants": map[string]interface{}{
+			"acme": map[string]interface{}{"limits": map[string]interface{}{"quota": 100}},
+		},
+	}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithTenantResolver(func(ctx context.Context) string {
+		tenant, _ := ctx.Value(tenantKey{}).(string)
+		return tenant
+	}))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	type limits struct {
+		Quota int `yaml:"quota"`
+	}
+
+	// Tenant overrides are decoded without sharing the cache of the shared value.
+	acme := context.WithValue(context.Background(), tenantKey{}, "acme")
+	for _, test := range []struct {
+		ctx   context.Context
+		quota int
+	}{{context.Background(), 10}, {acme, 100}, {context.Background(), 10}} {
+		var got limits
+		err := client.GetConfigContext(test.ctx, "limits", &got, nil)
+		if err != nil || got.Quota != test.quota {
+			t.Errorf("Expected quota %d, got %d (%v)", test.quota, got.Quota, err)
+		}
+	}
+	enabled, err := client.GetConfigBoolContext(acme, "enabled", false)
+	if err != nil || !enabled {
+		t.Errorf("Expected enabled to be true, got %t (%v)", enabled, err)
+	}
+
+	// A done context returns its error and the default.
+	canceled, cancel := context.WithCancel(acme)
+	cancel()
+	got := limits{Quota: 1}
+	err = client.GetConfigContext(canceled, "limits", &got, limits{Quota: 5})
+	if !errors.Is(err, context.Canceled) || got.Quota != 5 {
+		t.Errorf("Expected context.Canceled and the default, got %d (%v)", got.Quota, err)
+	}
+	if quota, err := client.GetConfigIntContext(canceled, "quota", 7); !errors.Is(err, context.Canceled) || quota != 7 {
+		t.Errorf("Expected context.Canceled and the default, got %d (%v)", quota, err)
+	}
+	if enabled, err := client.GetConfigBoolContext(canceled, "enabled", false); !errors.Is(err, context.Canceled) || enabled {
+		t.Errorf("Expected context.Canceled and the default, got %t (%v)", enabled, err)
+	}
+}
+
+func TestGetConfigContextDeadline(t *testing.T) {
+	repository := &blockingRepository{
+		mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}},
+		release:       make(chan struct{}),
+	}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithReadinessGate(5*time.Second))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	// Release the blocked first refresh before closing the client.
+	defer client.Close()
+	defer close(repository.release)
+
+	// The deadline of the context bounds the wait for the readiness gate.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	name, err := client.GetConfigStringContext(ctx, "name", "default")
+	if !errors.Is(err, context.DeadlineExceeded) || name != "default" {
+		t.Errorf("Expected context.DeadlineExceeded and the default, got %s (%v)", name, err)
+	}
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"github.com/divakarmanoj/go-remote-config/source"
+)
+
+// DefaultMaxConcurrentGets is the number of concurrent GetData calls the
+// Client allows on a source.ReadThrough repository without WithMaxConcurrentGets.
+const DefaultMaxConcurrentGets = 16
+
+// initGetLimit creates the semaphore bounding concurrent GetData calls, when
+// a limit was set with WithMaxConcurrentGets or the repository reads through.
+func (c *Client) initGetLimit() {
+	limit := c.maxConcurrentGets
+	if limit == 0 {
+		readThrough, ok := c.Repository.(source.ReadThrough)
+		if !ok || !readThrough.ReadsThrough() {
+			return
+		}
+		limit = DefaultMaxConcurrentGets
+	}
+	if limit > 0 {
+		c.getSemaphore = make(chan struct{}, limit)
+	}
+}
+
+// repositoryData calls GetData on the repository, waiting for a free slot
+// first when concurrent calls are bounded.
+func (c *Client) repositoryData(name string) (interface{}, bool) {
+	if c.getSemaphore != nil {
+		c.getSemaphore <- struct{}{}
+		defer func() { <-c.getSemaphore }()
+	}
+	c.repositoryMu.RLock()
+	repository := c.Repository
+	c.repositoryMu.RUnlock()
+	return repository.GetData(name)
+}
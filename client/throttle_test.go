@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// readThroughRepository is a slow source.ReadThrough repository that records
+// how many GetData calls are in flight at once.
+type readThroughRepository struct {
+	mapRepository
+	inFlight    int32
+	maxInFlight int32
+}
+
+func (r *readThroughRepository) ReadsThrough() bool {
+	return true
+}
+
+func (r *readThroughRepository) GetData(name string) (interface{}, bool) {
+	current := atomic.AddInt32(&r.inFlight, 1)
+	defer atomic.AddInt32(&r.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&r.maxInFlight)
+		if current <= max || atomic.CompareAndSwapInt32(&r.maxInFlight, max, current) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return r.mapRepository.GetData(name)
+}
+
+func TestWithMaxConcurrentGets(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected int32
+	}{
+		{name: "configured", opts: []Option{WithMaxConcurrentGets(3)}, expected: 3},
+		{name: "default", expected: DefaultMaxConcurrentGets},
+		{name: "seeded", opts: []Option{WithSeedFromEnv("APP_", "_"), WithInitialData(map[string]interface{}{"age": 30})}, expected: DefaultMaxConcurrentGets},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := &readThroughRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}}
+			client, err := NewClient(context.Background(), repository, 10*time.Second, test.opts...)
+			if err != nil {
+				t.Fatalf("Error creating client: %s", err.Error())
+			}
+			defer client.Close()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 4*DefaultMaxConcurrentGets; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if name, _ := client.GetConfigString("name", ""); name != "John" {
+						t.Errorf("Expected name to be John, got %s", name)
+					}
+				}()
+			}
+			wg.Wait()
+			if max := atomic.LoadInt32(&repository.maxInFlight); max > test.expected {
+				t.Errorf("Expected at most %d concurrent fetches, got %d", test.expected, max)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// GetConfigTime retrieves the time configuration with the given name from the repository
+func GetConfigTime(name string, defaultValue time.Time) (time.Time, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultValue, ErrNoDefaultClient
+	}
+	return client.GetConfigTime(name, defaultValue)
+}
+
+// GetConfigTime retrieves the time configuration with the given name from the
+// repository, such as the start of a maintenance window. It accepts a time
+// the source already decoded, and strings in RFC 3339 or in the layouts set
+// with WithTimeLayouts.
+func (c *Client) GetConfigTime(name string, defaultValue time.Time) (time.Time, error) {
+	return c.GetConfigTimeContext(context.Background(), name, defaultValue)
+}
+
+// GetConfigTimeContext retrieves the time configuration with the given name
+// for the tenant of ctx, falling back to the shared value.
+// It returns the error of ctx if ctx is done.
+func (c *Client) GetConfigTimeContext(ctx context.Context, name string, defaultValue time.Time) (_ time.Time, err error) {
+	defer c.observe("GetConfigTime", name, &err)
+	config, ok, err := c.tenantData(ctx, name)
+	if err != nil {
+		return defaultValue, err
+	}
+	if !ok {
+		return defaultValue, c.notFound(name)
+	}
+	if config == nil {
+		return defaultValue, nil
+	}
+	switch value := config.(type) {
+	case time.Time:
+		return value, nil
+	case string:
+		return c.parseTime(value, defaultValue)
+	}
+	return defaultValue, typeMismatch(name, "a time")
+}
+
+// parseTime parses value with the first of the Client's time layouts that
+// matches it.
+func (c *Client) parseTime(value string, defaultValue time.Time) (time.Time, error) {
+	layouts := c.timeLayouts
+	if len(layouts) == 0 {
+		layouts = []string{time.RFC3339}
+	}
+	var err error
+	for _, layout := range layouts {
+		var parsed time.Time
+		parsed, err = time.Parse(layout, value)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+	return defaultValue, err
+}
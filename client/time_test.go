@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetConfigTime(t *testing.T) {
+	decoded := time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+	client, _ := newMapClient(t, map[string]interface{}{
+		"window.start": "2024-03-01T02:00:00+01:00",
+		"decoded":      decoded,
+		"invalid":      "tomorrow",
+		"port":         8080,
+	})
+
+	start, err := client.GetConfigTime("window.start", time.Time{})
+	expected := time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC)
+	if err != nil || !start.Equal(expected) {
+		t.Errorf("Expected window.start to be %v, got %v (%v)", expected, start, err)
+	}
+	value, err := client.GetConfigTime("decoded", time.Time{})
+	if err != nil || !value.Equal(decoded) {
+		t.Errorf("Expected decoded to be %v, got %v (%v)", decoded, value, err)
+	}
+	defaultValue := time.Unix(0, 0)
+	for _, name := range []string{"invalid", "port", "missing"} {
+		if value, err := client.GetConfigTime(name, defaultValue); err == nil || !value.Equal(defaultValue) {
+			t.Errorf("Expected the default and an error for %s, got %v (%v)", name, value, err)
+		}
+	}
+}
+
+func TestGetConfigTimeLayouts(t *testing.T) {
+	repository := &mapRepository{data: map[string]interface{}{
+		"release": "2024-03-01",
+		"start":   "2024-03-01T02:00:00Z",
+	}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithTimeLayouts(time.RFC3339, "2006-01-02"))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	release, err := client.GetConfigTime("release", time.Time{})
+	expected := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	if err != nil || !release.Equal(expected) {
+		t.Errorf("Expected release to be %v, got %v (%v)", expected, release, err)
+	}
+	start, err := client.GetConfigTime("start", time.Time{})
+	expected = time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+	if err != nil || !start.Equal(expected) {
+		t.Errorf("Expected start to be %v, got %v (%v)", expected, start, err)
+	}
+}
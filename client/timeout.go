@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/divakarmanoj/go-remote-config/source"
+)
+
+// refreshWithTimeout refreshes the repository, within the refresh timeout if
+// the Client has one. The caller must hold refreshMu.
+func (c *Client) refreshWithTimeout() error {
+	if c.refreshTimeout <= 0 {
+		return c.Repository.Refresh()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.refreshTimeout)
+	defer cancel()
+	if refresher, ok := c.Repository.(source.ContextRefresher); ok {
+		return refresher.RefreshContext(ctx)
+	}
+
+	// Refreshes that cannot be canceled are abandoned, but not overlapped.
+	if c.stuckRefresh != nil {
+		select {
+		case <-c.stuckRefresh:
+			c.stuckRefresh = nil
+		default:
+			return fmt.Errorf("%w: the abandoned refresh has not returned yet", ErrRefreshTimeout)
+		}
+	}
+	done := make(chan struct{})
+	var err error
+	go func() {
+		defer close(done)
+		err = c.Repository.Refresh()
+	}()
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		c.stuckRefresh = done
+		c.log().Warn("repository refresh is stuck, abandoning it", LogFields{"timeout": c.refreshTimeout.String()})
+		return fmt.Errorf("%w after %s", ErrRefreshTimeout, c.refreshTimeout)
+	}
+}
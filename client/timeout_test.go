@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowRepository is a mapRepository whose second refresh takes delay.
+type slowRepository struct {
+	mapRepository
+	refreshes atomic.Int32
+	delay     time.Duration
+}
+
+func (s *slowRepository) Refresh() error {
+	if s.refreshes.Add(1) == 2 {
+		time.Sleep(s.delay)
+	}
+	return nil
+}
+
+// contextRepository is a mapRepository whose RefreshContext waits for its
+// context to be done.
+type contextRepository struct {
+	mapRepository
+}
+
+func (c *contextRepository) RefreshContext(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestWithRefreshTimeout(t *testing.T) {
+	repository := &slowRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}, delay: 200 * time.Millisecond}
+	var mu sync.Mutex
+	var errs []error
+	listener := func(result RefreshResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, result.Err)
+	}
+	client, err := NewClient(context.Background(), repository, 20*time.Millisecond, WithRefreshTimeout(50*time.Millisecond),
+		WithRefreshBackoff(20*time.Millisecond, 1), WithRefreshListener(listener), WithLogger(NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// The stuck refresh times out, and the loop refreshes again once it returns.
+	deadline := time.Now().Add(2 * time.Second)
+	for repository.refreshes.Load() < 4 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if value, err := client.GetConfigString("name", ""); err != nil || value != "John" {
+		t.Errorf("Expected the data to be kept, got %s (%v)", value, err)
+	}
+	client.Close()
+	<-client.Done()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) < 3 || !errors.Is(errs[1], ErrRefreshTimeout) {
+		t.Fatalf("Expected the second refresh to time out, got %v", errs)
+	}
+	if errs[len(errs)-1] != nil {
+		t.Errorf("Expected the loop to recover, got %v", errs)
+	}
+}
+
+func TestWithRefreshTimeoutContextRefresher(t *testing.T) {
+	repository := &contextRepository{mapRepository: mapRepository{data: map[string]interface{}{"name": "John"}}}
+	start := time.Now()
+	_, err := NewClient(context.Background(), repository, time.Hour, WithRefreshTimeout(20*time.Millisecond), WithLogger(NewNopLogger()))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the refresh to be canceled by the timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the refresh to return at the timeout, took %s", elapsed)
+	}
+}
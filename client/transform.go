@@ -0,0 +1,114 @@
+package client
+
+import (
+	"github.com/divakarmanoj/go-remote-config/source"
+)
+
+// ValueTransformer post-processes the value of the configuration with the
+// given key and returns the value getters should see. It must return a new
+// value rather than modify maps or slices of the value in place, because
+// those are shared with the repository.
+type ValueTransformer func(key string, value interface{}) interface{}
+
+// valueFailure replaces a configuration whose value a transformer could not
+// produce, such as a value that cannot be decrypted, so that getters report
+// the error instead of the original value.
+type valueFailure struct {
+	err error
+}
+
+// valueError returns the error of config if a transformer could not produce it.
+func valueError(config interface{}) error {
+	if failure, ok := config.(*valueFailure); ok {
+		return failure.err
+	}
+	return nil
+}
+
+// transform applies the Client's value transformers to value in the order they were added.
+func (c *Client) transform(key string, value interface{}) interface{} {
+	for _, transformer := range c.transformers {
+		value = transformer(key, value)
+	}
+	return value
+}
+
+// loadTransformed applies the value transformers to every configuration of
+// the repository, then the snapshot transforms to all of them, and stores the
+// results for lookups, after checking them with the snapshot validators if
+// validate is true. A snapshot that a transform fails on or that is rejected
+// is not stored, so lookups keep seeing the last accepted one. Repositories
+// that do not implement source.KeyLister cannot be listed, so their values
+// are transformed on each lookup instead, without the snapshot transforms,
+// and never validated as a snapshot.
+func (c *Client) loadTransformed(validate bool) error {
+	if !c.pinsSnapshot() {
+		return nil
+	}
+	lister, ok := c.Repository.(source.KeyLister)
+	if !ok {
+		if len(c.snapshotValidators) > 0 {
+			c.log().Warn("repository cannot be listed, skipping snapshot validation", nil)
+		}
+		if len(c.snapshotTransforms) > 0 {
+			c.log().Warn("repository cannot be listed, skipping snapshot transforms", nil)
+		}
+		return nil
+	}
+	values := map[string]interface{}{}
+	for _, key := range lister.Keys() {
+		value, ok := c.repositoryData(key)
+		if ok {
+			values[key] = c.transform(key, value)
+		}
+	}
+	values, err := c.transformSnapshot(values)
+	if err != nil {
+		return err
+	}
+	if validate {
+		err = c.validateSnapshot(values)
+		if err != nil {
+			return err
+		}
+	}
+	c.transformed.Store(&values)
+	return nil
+}
+
+// pinsSnapshot reports whether lookups are served from the values stored by
+// loadTransformed rather than read from the repository.
+func (c *Client) pinsSnapshot() bool {
+	return len(c.transformers) > 0 || len(c.snapshotTransforms) > 0 || len(c.snapshotValidators) > 0
+}
+
+// lookup returns the configuration with the given name as seen by getters,
+// that is after the value transformers have been applied. With a key
+// normalizer, a name missing from the repository finds the key with the same
+// canonical form.
+func (c *Client) lookup(name string) (interface{}, bool) {
+	value, ok := c.lookupKey(name)
+	if ok {
+		return value, true
+	}
+	if key, ok := c.sourceKey(name); ok {
+		return c.lookupKey(key)
+	}
+	return nil, false
+}
+
+// lookupKey is lookup for the exact key of the repository.
+func (c *Client) lookupKey(name string) (interface{}, bool) {
+	if !c.pinsSnapshot() {
+		return c.repositoryData(name)
+	}
+	if values := c.transformed.Load(); values != nil {
+		value, ok := (*values)[name]
+		return value, ok
+	}
+	value, ok := c.repositoryData(name)
+	if !ok {
+		return nil, false
+	}
+	return c.transform(name, value), true
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func trimStrings(key string, value interface{}) interface{} {
+	if str, ok := value.(string); ok {
+		return strings.TrimSpace(str)
+	}
+	return value
+}
+
+func TestWithValueTransformer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("name: \"  John \"\nage: 30\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second, WithValueTransformer(trimStrings))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	name, err := client.GetConfigString("name", "")
+	if err != nil {
+		t.Errorf("Error getting name: %s", err.Error())
+	}
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %q", name)
+	}
+	var decoded string
+	err = client.GetConfig("name", &decoded, nil)
+	if err != nil {
+		t.Errorf("Error getting name: %s", err.Error())
+	}
+	if decoded != "John" {
+		t.Errorf("Expected name to be John, got %q", decoded)
+	}
+	age, err := client.GetConfigInt("age", 0)
+	if err != nil || age != 30 {
+		t.Errorf("Expected age to be 30, got %d", age)
+	}
+
+	// Prefetch decodes the transformed values.
+	err = client.Prefetch(map[string]interface{}{"name": &decoded})
+	if err != nil {
+		t.Errorf("Error prefetching name: %s", err.Error())
+	}
+	decoded = ""
+	_ = client.GetConfig("name", &decoded, nil)
+	if decoded != "John" {
+		t.Errorf("Expected prefetched name to be John, got %q", decoded)
+	}
+}
+
+func TestWithValueTransformerUnlistedRepository(t *testing.T) {
+	repository := &mapRepository{data: map[string]interface{}{"name": " John "}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithValueTransformer(trimStrings))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	name, err := client.GetConfigString("name", "")
+	if err != nil {
+		t.Errorf("Error getting name: %s", err.Error())
+	}
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %q", name)
+	}
+}
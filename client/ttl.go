@@ -0,0 +1,182 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// Keys of an expiring configuration, a value that goes stale on its own:
+//
+//	promo_banner:
+//	  _value: true
+//	  _ttl: 90s
+//
+// Getters return _value until _ttl, a duration such as 90s or a number of
+// seconds, has passed on the Client's Clock since the last successful
+// refresh, and report the configuration missing from then on, so that the
+// default is used until a refresh succeeds again. OnExpire reports the lapse.
+// _expires_at, an RFC 3339 timestamp, sets a fixed expiry instead, and with
+// both the earlier one applies. Only top-level configurations can expire, and
+// _value may itself be a scheduled configuration.
+const (
+	TTLKey       = "_ttl"
+	ExpiresAtKey = "_expires_at"
+)
+
+// resolveExpiry returns the value of config at now if it is an expiring
+// configuration, and config itself otherwise. refreshedAt is the time the
+// TTL counts from. It reports whether the value is present and whether it
+// depends on the time.
+func resolveExpiry(config interface{}, refreshedAt time.Time, now time.Time) (interface{}, bool, bool) {
+	value, expiresAt, ok := expiry(config, refreshedAt)
+	if !ok {
+		return config, true, false
+	}
+	if !now.Before(expiresAt) {
+		return nil, false, true
+	}
+	return value, true, true
+}
+
+// expiry returns the value of config and the time it expires at if it is an
+// expiring configuration, with its TTL counting from refreshedAt, and reports
+// whether it is one.
+func expiry(config interface{}, refreshedAt time.Time) (interface{}, time.Time, bool) {
+	expiring, ok := config.(map[string]interface{})
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	value, hasValue := expiring[ScheduledValueKey]
+	if !hasValue {
+		return nil, time.Time{}, false
+	}
+	var expiresAt time.Time
+	expires := false
+	for key, setting := range expiring {
+		var at time.Time
+		switch key {
+		case ScheduledValueKey:
+			continue
+		case TTLKey:
+			ttl, ok := ttlDuration(setting)
+			if !ok {
+				return nil, time.Time{}, false
+			}
+			at = refreshedAt.Add(ttl)
+		case ExpiresAtKey:
+			at, ok = effectiveTime(setting)
+			if !ok {
+				return nil, time.Time{}, false
+			}
+		default:
+			return nil, time.Time{}, false
+		}
+		if !expires || at.Before(expiresAt) {
+			expiresAt = at
+			expires = true
+		}
+	}
+	return value, expiresAt, expires
+}
+
+// OnExpire calls fn once each time the expiring configuration with the given
+// name lapses, that is when its _ttl or _expires_at passes on the Client's
+// Clock before a refresh renews it. A configuration that has already lapsed
+// when OnExpire is called is reported at once. Each call runs in its own
+// goroutine. The returned function stops the notifications and may be called
+// concurrently and more than once.
+func (c *Client) OnExpire(name string, fn func()) (cancel func()) {
+	watch := &expiryWatch{client: c, name: name, fn: fn}
+	unsubscribe := c.events.subscribe(func(RefreshResult) {
+		watch.schedule()
+	})
+	watch.schedule()
+	return func() {
+		unsubscribe()
+		watch.stop()
+	}
+}
+
+// expiryWatch waits for the lapse of a configuration watched with OnExpire.
+type expiryWatch struct {
+	client    *Client
+	name      string
+	fn        func()
+	mu        sync.Mutex
+	expiresAt time.Time     // lapse the pending wait, or the last call of fn, is for
+	abandon   chan struct{} // closed to abandon the pending wait, nil without one
+	stopped   bool          // whether the watch has been canceled
+}
+
+// schedule waits for the lapse of the configuration as of the last refresh,
+// unless it is already waited for or was reported.
+func (w *expiryWatch) schedule() {
+	refreshedAt, _ := w.client.LastRefresh()
+	config, ok := w.client.lookupColor(w.name)
+	var expiresAt time.Time
+	if ok {
+		_, expiresAt, ok = expiry(config, refreshedAt)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped || ok && expiresAt.Equal(w.expiresAt) {
+		return
+	}
+	w.release()
+	w.expiresAt = expiresAt
+	if !ok {
+		return
+	}
+	abandon := make(chan struct{})
+	w.abandon = abandon
+	wait, release := w.client.after(expiresAt.Sub(w.client.now()))
+	go func() {
+		defer release()
+		select {
+		case <-wait:
+		case <-abandon:
+			return
+		case <-w.client.Done():
+			return
+		}
+		w.mu.Lock()
+		current := w.abandon == abandon
+		w.abandon = nil
+		w.mu.Unlock()
+		if current {
+			w.fn()
+		}
+	}()
+}
+
+// stop abandons the pending wait, if any, and any later ones.
+func (w *expiryWatch) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	w.release()
+}
+
+// release abandons the pending wait, if any. It must be called with the lock
+// held.
+func (w *expiryWatch) release() {
+	if w.abandon != nil {
+		close(w.abandon)
+		w.abandon = nil
+	}
+}
+
+// ttlDuration parses the TTL of an expiring configuration.
+func ttlDuration(value interface{}) (time.Duration, bool) {
+	switch value := value.(type) {
+	case string:
+		ttl, err := time.ParseDuration(value)
+		return ttl, err == nil
+	case int:
+		return time.Duration(value) * time.Second, true
+	case float64:
+		return time.Duration(value * float64(time.Second)), true
+	}
+	return 0, false
+}
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAlarmClock is a fakeClock whose waits end once it is advanced past
+// them.
+type fakeAlarmClock struct {
+	fakeClock
+	alarmsMu sync.Mutex
+	alarms   []fakeAlarm
+}
+
+// fakeAlarm is a wait of a fakeAlarmClock.
+type fakeAlarm struct {
+	at   time.Time
+	fire chan time.Time
+}
+
+func (f *fakeAlarmClock) After(d time.Duration) <-chan time.Time {
+	f.alarmsMu.Lock()
+	defer f.alarmsMu.Unlock()
+	alarm := fakeAlarm{at: f.Now().Add(d), fire: make(chan time.Time, 1)}
+	if d <= 0 {
+		alarm.fire <- alarm.at
+		return alarm.fire
+	}
+	f.alarms = append(f.alarms, alarm)
+	return alarm.fire
+}
+
+func (f *fakeAlarmClock) advance(d time.Duration) {
+	f.fakeClock.advance(d)
+	now := f.Now()
+	f.alarmsMu.Lock()
+	defer f.alarmsMu.Unlock()
+	pending := f.alarms[:0]
+	for _, alarm := range f.alarms {
+		if alarm.at.After(now) {
+			pending = append(pending, alarm)
+			continue
+		}
+		alarm.fire <- now
+	}
+	f.alarms = pending
+}
+
+func TestExpiringConfig(t *testing.T) {
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	repository := &mapRepository{data: map[string]interface{}{
+		"promo": map[string]interface{}{
+			ScheduledValueKey: true,
+			TTLKey:            "90s",
+		},
+		"banner": map[string]interface{}{
+			ScheduledValueKey: "Sale!",
+			TTLKey:            3600,
+			ExpiresAtKey:      start.Add(time.Minute).Format(time.RFC3339),
+		},
+		// Maps with other keys are ordinary configurations.
+		"plain": map[string]interface{}{ScheduledValueKey: 1, TTLKey: "1s", "other": 2},
+	}}
+	client, err := NewClient(context.Background(), repository, 10*time.Second, WithClock(clock))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	if promo, err := client.GetConfigBool("promo", false); err != nil || !promo {
+		t.Errorf("Expected promo to be true before its TTL, got %v (%v)", promo, err)
+	}
+	if banner, _ := client.GetConfigString("banner", ""); banner != "Sale!" {
+		t.Errorf("Expected banner to be Sale! before it expires, got %s", banner)
+	}
+
+	// The earlier of the TTL and the expiry applies.
+	clock.advance(time.Minute)
+	if banner, err := client.GetConfigString("banner", "none"); err == nil || banner != "none" {
+		t.Errorf("Expected banner to be missing once expired, got %s (%v)", banner, err)
+	}
+	if promo, _ := client.GetConfigBool("promo", false); !promo {
+		t.Errorf("Expected promo to be true before its TTL")
+	}
+
+	// The value is served from the cache no longer than its TTL.
+	clock.advance(30 * time.Second)
+	if promo, err := client.GetConfigBool("promo", false); err == nil || promo {
+		t.Errorf("Expected promo to be missing after its TTL, got %v (%v)", promo, err)
+	}
+
+	// A successful refresh renews the TTL.
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error refreshing: %s", err.Error())
+	}
+	if promo, _ := client.GetConfigBool("promo", false); !promo {
+		t.Errorf("Expected promo to be true again after a refresh")
+	}
+
+	var plain map[string]interface{}
+	if err := client.GetConfig("plain", &plain, nil); err != nil || plain["other"] != 2 {
+		t.Errorf("Expected plain to be read as a map, got %v (%v)", plain, err)
+	}
+}
+
+func TestOnExpire(t *testing.T) {
+	clock := &fakeAlarmClock{fakeClock: fakeClock{now: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)}}
+	repository := &mapRepository{data: map[string]interface{}{
+		"token": map[string]interface{}{ScheduledValueKey: "abc", TTLKey: "90s"},
+		"plain": "abc",
+	}}
+	client, err := NewClient(context.Background(), repository, time.Hour, WithClock(clock))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+	expired := make(chan string, 10)
+	cancel := client.OnExpire("token", func() {
+		expired <- "token"
+	})
+	defer cancel()
+	// Configurations without a TTL never lapse.
+	cancelPlain := client.OnExpire("plain", func() {
+		expired <- "plain"
+	})
+	defer cancelPlain()
+	expectExpiry := func(fired bool) {
+		t.Helper()
+		select {
+		case name := <-expired:
+			if !fired || name != "token" {
+				t.Errorf("Expected no expiry notification, got one for %s", name)
+			}
+		case <-time.After(100 * time.Millisecond):
+			if fired {
+				t.Errorf("Expected an expiry notification")
+			}
+		}
+	}
+
+	clock.advance(time.Minute)
+	expectExpiry(false)
+	clock.advance(30 * time.Second)
+	expectExpiry(true)
+
+	// The lapse is reported once, until a refresh renews the TTL.
+	clock.advance(time.Minute)
+	expectExpiry(false)
+	if err := client.ForceRefresh(context.Background()); err != nil {
+		t.Fatalf("Error refreshing: %s", err.Error())
+	}
+	clock.advance(90 * time.Second)
+	expectExpiry(true)
+
+	// Nothing is reported once the notifications are canceled.
+	cancel()
+	if err := client.ForceRefresh(context.Background()); err != nil {
+		t.Fatalf("Error refreshing: %s", err.Error())
+	}
+	clock.advance(90 * time.Second)
+	expectExpiry(false)
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"fmt"
+)
+
+// TypeCheck is the expected type of a configuration, registered with
+// WithEagerTypeCheck.
+type TypeCheck struct {
+	Name  string                 // Name of the configuration
+	Type  string                 // Name of the expected type, used in errors
+	check func(interface{}) bool // Reports whether a value has the expected type
+}
+
+// RegisterInt expects the configuration with the given name to be an int, as read by GetConfigInt.
+func RegisterInt(name string) TypeCheck {
+	return TypeCheck{Name: name, Type: "int", check: func(value interface{}) bool {
+		_, err := toInt(value)
+		return err == nil
+	}}
+}
+
+// RegisterFloat expects the configuration with the given name to be a float, as read by GetConfigFloat.
+func RegisterFloat(name string) TypeCheck {
+	return TypeCheck{Name: name, Type: "float", check: func(value interface{}) bool {
+		_, ok := value.(float64)
+		return ok
+	}}
+}
+
+// RegisterString expects the configuration with the given name to be a string, as read by GetConfigString.
+func RegisterString(name string) TypeCheck {
+	return TypeCheck{Name: name, Type: "string", check: func(value interface{}) bool {
+		_, ok := value.(string)
+		return ok
+	}}
+}
+
+// RegisterBool expects the configuration with the given name to be a bool.
+func RegisterBool(name string) TypeCheck {
+	return TypeCheck{Name: name, Type: "bool", check: func(value interface{}) bool {
+		_, ok := value.(bool)
+		return ok
+	}}
+}
+
+// RegisterStrings expects the configuration with the given name to be an
+// array of strings, as read by GetConfigArrayOfStrings.
+func RegisterStrings(name string) TypeCheck {
+	return TypeCheck{Name: name, Type: "array of strings", check: func(value interface{}) bool {
+		items, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range items {
+			if _, ok := item.(string); !ok {
+				return false
+			}
+		}
+		return true
+	}}
+}
+
+// typeCheckValidator returns a SnapshotValidator that checks the registered
+// types of the configurations of a snapshot, including their deployment
+// color variants and the current value of scheduled and expiring ones. Missing
+// configurations are not checked.
+func (c *Client) typeCheckValidator(checks []TypeCheck) SnapshotValidator {
+	return func(snapshot map[string]interface{}) error {
+		for _, check := range checks {
+			keys := []string{check.Name}
+			if c.deploymentColor != "" {
+				keys = append(keys, check.Name+"@"+c.deploymentColor)
+			}
+			for _, key := range keys {
+				config, ok := snapshot[key]
+				if !ok {
+					continue
+				}
+				// The snapshot is about to be loaded, so TTLs count from now.
+				value, ok, _ := resolveExpiry(config, c.now(), c.now())
+				if ok {
+					value, ok, _ = resolveSchedule(value, c.now())
+				}
+				if ok && !check.check(value) {
+					return fmt.Errorf("%w: %s is not a %s", ErrTypeMismatch, key, check.Type)
+				}
+			}
+		}
+		return nil
+	}
+}
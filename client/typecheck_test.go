@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithEagerTypeCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("count: 3\nname: John\nhosts: [a, b]\nratio: 0.5\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	client, err := NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, 10*time.Second, WithEagerTypeCheck(
+		RegisterInt("count"),
+		RegisterString("name"),
+		RegisterStrings("hosts"),
+		RegisterFloat("ratio"),
+		RegisterBool("enabled"),
+	))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	// A registered int becoming a string flags the refresh before any getter runs.
+	err = os.WriteFile(path, []byte("count: three\nname: John\nhosts: [a, b]\nratio: 0.5\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	err = client.ForceRefresh(context.Background())
+	if !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("Expected ErrTypeMismatch, got %v", err)
+	}
+	count, err := client.GetConfigInt("count", 0)
+	if err != nil || count != 3 {
+		t.Errorf("Expected the last accepted count 3, got %d (%v)", count, err)
+	}
+
+	// Registered keys that are missing are not flagged.
+	err = os.WriteFile(path, []byte("count: 4\n"), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+	err = client.ForceRefresh(context.Background())
+	if err != nil {
+		t.Errorf("Error forcing refresh: %s", err.Error())
+	}
+
+	for _, content := range []string{"hosts: [a, 1]\n", "ratio: 1\n", "enabled: yes please\n", "name: [John]\n"} {
+		err = os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatalf("Error writing file: %s", err.Error())
+		}
+		err = client.ForceRefresh(context.Background())
+		if !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("Expected ErrTypeMismatch for %q, got %v", content, err)
+		}
+	}
+}
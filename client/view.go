@@ -0,0 +1,186 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// View is a consistent, read-only view of the configurations of a Client as
+// of one refresh, returned by Client.View. Reads of several related keys from
+// one View never mix values of different refreshes. A View shares the
+// immutable values of the refresh, so it is cheap to create and unaffected
+// by later refreshes, and it is safe for concurrent use. Like getters, it
+// prefers the variant of the Client's deployment color and accepts dotted
+// paths, but it ignores tenant overrides, values pinned with Override and
+// the schedules and expiry of configurations.
+type View struct {
+	client *Client
+	values map[string]interface{}
+}
+
+// View returns a View of the configurations of the last refresh. The View of
+// a repository that does not implement source.KeyLister has no
+// configurations, as its values cannot be listed.
+func (c *Client) View() *View {
+	view := &View{client: c}
+	if values := c.viewValues.Load(); values != nil {
+		view.values = *values
+	}
+	return view
+}
+
+// Get returns the configuration with the given name and whether the View has it.
+func (v *View) Get(name string) (interface{}, bool) {
+	config, ok := v.lookupColor(name)
+	if ok {
+		return config, true
+	}
+	name, path := splitPath(name)
+	if path == nil {
+		return nil, false
+	}
+	config, ok = v.lookupColor(name)
+	if !ok {
+		return nil, false
+	}
+	return descend(config, path)
+}
+
+// lookupColor looks up the configuration with the given name, preferring the
+// variant for the deployment color of the Client when one is set.
+func (v *View) lookupColor(name string) (interface{}, bool) {
+	if color := v.client.deploymentColor; color != "" {
+		if config, ok := v.values[name+"@"+color]; ok {
+			return config, true
+		}
+	}
+	config, ok := v.values[name]
+	if !ok {
+		if key, found := v.client.sourceKey(name); found {
+			config, ok = v.values[key]
+		}
+	}
+	return config, ok
+}
+
+// getData returns the configuration with the given name, or the error the
+// typed getters return if it is missing or could not be decrypted.
+func (v *View) getData(name string) (interface{}, bool, error) {
+	config, ok := v.Get(name)
+	if !ok {
+		return nil, false, v.client.notFound(name)
+	}
+	if err := valueError(config); err != nil {
+		return nil, false, err
+	}
+	return config, config != nil, nil
+}
+
+// GetConfig decodes the configuration with the given name into data like
+// Client.GetConfig does.
+func (v *View) GetConfig(name string, data interface{}, defaultValue interface{}) (err error) {
+	defer v.client.observe("View.GetConfig", name, &err)
+	if err := checkData(name, data); err != nil {
+		return err
+	}
+	mergeDefaults(data, defaultValue)
+	config, ok, err := v.getData(name)
+	if !ok {
+		v.client.assignDefault(data, defaultValue)
+		return err
+	}
+	if err := v.client.decodeConfig(config, data); err != nil {
+		v.client.assignDefault(data, defaultValue)
+		return keyError(name, &decodeError{err: err})
+	}
+	return nil
+}
+
+// GetConfigString returns the string configuration with the given name like
+// Client.GetConfigString does.
+func (v *View) GetConfigString(name string, defaultValue string) (_ string, err error) {
+	defer v.client.observe("View.GetConfigString", name, &err)
+	config, ok, err := v.getData(name)
+	if !ok {
+		return defaultValue, err
+	}
+	configString, ok := config.(string)
+	if !ok {
+		return defaultValue, typeMismatch(name, "a string")
+	}
+	return configString, nil
+}
+
+// GetConfigInt returns the int configuration with the given name like
+// Client.GetConfigInt does.
+func (v *View) GetConfigInt(name string, defaultValue int) (_ int, err error) {
+	defer v.client.observe("View.GetConfigInt", name, &err)
+	config, ok, err := v.getData(name)
+	if !ok {
+		return defaultValue, err
+	}
+	configInt, err := toInt(config)
+	if err != nil {
+		return defaultValue, keyError(name, err)
+	}
+	return configInt, nil
+}
+
+// GetConfigFloat returns the float configuration with the given name like
+// Client.GetConfigFloat does.
+func (v *View) GetConfigFloat(name string, defaultValue float64) (_ float64, err error) {
+	defer v.client.observe("View.GetConfigFloat", name, &err)
+	config, ok, err := v.getData(name)
+	if !ok {
+		return defaultValue, err
+	}
+	configFloat, ok := config.(float64)
+	if !ok {
+		return defaultValue, typeMismatch(name, "a float")
+	}
+	return configFloat, nil
+}
+
+// GetConfigBool returns the bool configuration with the given name like
+// Client.GetConfigBool does.
+func (v *View) GetConfigBool(name string, defaultValue bool) (_ bool, err error) {
+	defer v.client.observe("View.GetConfigBool", name, &err)
+	config, ok, err := v.getData(name)
+	if !ok {
+		return defaultValue, err
+	}
+	switch value := config.(type) {
+	case bool:
+		return value, nil
+	case string:
+		configBool, err := strconv.ParseBool(value)
+		if err != nil {
+			return defaultValue, keyError(name, fmt.Errorf("%w: not a bool: %q", ErrTypeMismatch, value))
+		}
+		return configBool, nil
+	}
+	return defaultValue, keyError(name, fmt.Errorf("%w: not a bool: %T", ErrTypeMismatch, config))
+}
+
+// GetConfigArrayOfStrings returns the string array configuration with the
+// given name like Client.GetConfigArrayOfStrings does.
+func (v *View) GetConfigArrayOfStrings(name string, defaultValue []string) (_ []string, err error) {
+	defer v.client.observe("View.GetConfigArrayOfStrings", name, &err)
+	config, ok, err := v.getData(name)
+	if !ok {
+		return defaultValue, err
+	}
+	configArray, ok := config.([]interface{})
+	if !ok {
+		return defaultValue, typeMismatch(name, "an array of strings")
+	}
+	output := []string{}
+	for _, value := range configArray {
+		str, ok := value.(string)
+		if !ok {
+			return defaultValue, typeMismatch(name, "an array of strings")
+		}
+		output = append(output, str)
+	}
+	return output, nil
+}
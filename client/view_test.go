@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// swappingRepository is a Repository whose refresh swaps in the data of the
+// next generation, in which every key holds the generation number.
+type swappingRepository struct {
+	generation atomic.Int64
+	data       atomic.Pointer[map[string]interface{}]
+}
+
+func (s *swappingRepository) GetName() string {
+	return "swapping"
+}
+
+func (s *swappingRepository) GetData(name string) (interface{}, bool) {
+	value, ok := (*s.data.Load())[name]
+	return value, ok
+}
+
+func (s *swappingRepository) GetRawData() []byte {
+	return nil
+}
+
+func (s *swappingRepository) Refresh() error {
+	generation := int(s.generation.Add(1))
+	data := map[string]interface{}{"min": generation, "max": generation, "name": "John"}
+	s.data.Store(&data)
+	return nil
+}
+
+func (s *swappingRepository) Keys() []string {
+	var keys []string
+	for key := range *s.data.Load() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestView(t *testing.T) {
+	repository := &swappingRepository{}
+	client, err := NewClient(context.Background(), repository, time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	view := client.View()
+	if err := client.ForceRefresh(context.Background()); err != nil {
+		t.Fatalf("Error refreshing client: %s", err.Error())
+	}
+
+	// The view keeps the values of the refresh it was taken at.
+	min, err := view.GetConfigInt("min", 0)
+	if err != nil || min != 1 {
+		t.Errorf("Expected min of the first refresh, got %d (%v)", min, err)
+	}
+	max, err := view.GetConfigInt("max", 0)
+	if err != nil || max != 1 {
+		t.Errorf("Expected max of the first refresh, got %d (%v)", max, err)
+	}
+	if value, err := view.GetConfigString("name", ""); err != nil || value != "John" {
+		t.Errorf("Expected John, got %s (%v)", value, err)
+	}
+	if value, err := view.GetConfigString("missing", "default"); err == nil || value != "default" {
+		t.Errorf("Expected the default and an error for a missing key, got %s (%v)", value, err)
+	}
+	if min, _ := client.View().GetConfigInt("min", 0); min != 2 {
+		t.Errorf("Expected a new view to see the second refresh, got %d", min)
+	}
+}
+
+func TestViewConsistentDuringRefresh(t *testing.T) {
+	repository := &swappingRepository{}
+	client, err := NewClient(context.Background(), repository, time.Hour)
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer client.Close()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = client.ForceRefresh(context.Background())
+			}
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		view := client.View()
+		min, _ := view.GetConfigInt("min", 0)
+		max, _ := view.GetConfigInt("max", -1)
+		if min != max {
+			t.Errorf("Expected min and max of the same refresh, got %d and %d", min, max)
+			break
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// ErrInvalidWeights is returned by WeightedChoice when the configuration is
+// not a map of options to non-negative weights with a positive total.
+var ErrInvalidWeights = errors.New("invalid weights")
+
+// WeightedChoice picks an option of the weighted configuration with the given name
+func WeightedChoice(name string, defaultChoice string) (string, error) {
+	client := DefaultClient()
+	if client == nil {
+		return defaultChoice, ErrNoDefaultClient
+	}
+	return client.WeightedChoice(name, defaultChoice)
+}
+
+// WeightedChoice reads the configuration with the given name as a map of
+// options to weights, such as `{primary: 3, secondary: 1}`, and returns an
+// option picked at random with a probability proportional to its weight.
+// Like ShouldSample, every call draws independently. Weights may be ints or
+// floats. If the configuration cannot be read, defaultChoice is returned
+// with the error, which wraps ErrInvalidWeights when a weight is negative or
+// not a number or the weights do not add up to more than 0.
+func (c *Client) WeightedChoice(name string, defaultChoice string) (string, error) {
+	if c.isClosed.Load() {
+		return defaultChoice, ErrClientClosed
+	}
+	if err := c.awaitReadiness(context.Background()); err != nil {
+		return defaultChoice, err
+	}
+	config, ok := c.getData(name)
+	if !ok {
+		return defaultChoice, c.notFound(name)
+	}
+	weights, ok := config.(map[string]interface{})
+	if !ok {
+		return defaultChoice, fmt.Errorf("%w: config is not a map", ErrInvalidWeights)
+	}
+
+	// Sort the options so that the same draw always picks the same option.
+	options := make([]string, 0, len(weights))
+	for option := range weights {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+	cumulative := make([]float64, len(options))
+	var total float64
+	for i, option := range options {
+		var weight float64
+		switch value := weights[option].(type) {
+		case int:
+			weight = float64(value)
+		case float64:
+			weight = value
+		default:
+			return defaultChoice, fmt.Errorf("%w: weight of %s is not a number", ErrInvalidWeights, option)
+		}
+		if weight < 0 {
+			return defaultChoice, fmt.Errorf("%w: weight of %s is negative", ErrInvalidWeights, option)
+		}
+		total += weight
+		cumulative[i] = total
+	}
+	if total <= 0 {
+		return defaultChoice, fmt.Errorf("%w: weights must add up to more than 0", ErrInvalidWeights)
+	}
+
+	draw := rand.Float64() * total
+	for i, upTo := range cumulative {
+		if draw < upTo {
+			return options[i], nil
+		}
+	}
+	// Rounding may leave the draw at the total, which the last option with a weight covers.
+	for i := len(options) - 1; ; i-- {
+		if cumulative[i] > 0 && (i == 0 || cumulative[i] > cumulative[i-1]) {
+			return options[i], nil
+		}
+	}
+}
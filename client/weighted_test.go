@@ -0,0 +1,50 @@
+package client
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestWeightedChoice(t *testing.T) {
+	client, repository := newMapClient(t, map[string]interface{}{
+		"backends": map[string]interface{}{"primary": 6, "secondary": 3, "canary": 1.0, "drained": 0},
+	})
+	const calls = 20000
+	counts := map[string]int{}
+	for i := 0; i < calls; i++ {
+		choice, err := client.WeightedChoice("backends", "default")
+		if err != nil {
+			t.Fatalf("Error choosing backend: %s", err.Error())
+		}
+		counts[choice]++
+	}
+	expected := map[string]float64{"primary": 0.6, "secondary": 0.3, "canary": 0.1, "drained": 0}
+	for option, share := range expected {
+		if got := float64(counts[option]) / calls; math.Abs(got-share) > 0.02 {
+			t.Errorf("Expected %s to be picked close to %f of the time, got %f", option, share, got)
+		}
+	}
+	if counts["drained"] != 0 {
+		t.Errorf("Expected an option of weight 0 never to be picked, got %d", counts["drained"])
+	}
+
+	// Invalid weights fall back to the default choice.
+	for _, weights := range []interface{}{
+		map[string]interface{}{"primary": 1, "secondary": -1},
+		map[string]interface{}{"primary": 0, "secondary": 0},
+		map[string]interface{}{},
+		map[string]interface{}{"primary": "heavy"},
+		"primary",
+	} {
+		repository.set("backends", weights)
+		choice, err := client.WeightedChoice("backends", "default")
+		if !errors.Is(err, ErrInvalidWeights) || choice != "default" {
+			t.Errorf("Expected ErrInvalidWeights and the default for %v, got %s (%v)", weights, choice, err)
+		}
+	}
+	choice, err := client.WeightedChoice("missing", "default")
+	if err == nil || choice != "default" {
+		t.Errorf("Expected an error and the default for a missing config, got %s (%v)", choice, err)
+	}
+}
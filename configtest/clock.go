@@ -0,0 +1,80 @@
+package configtest
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a client.TimerClock whose time only moves when the test calls
+// Advance. Passed to a Client with client.WithClock, it drives the refresh
+// loop: each wait of the loop ends once the clock is advanced past it, so a
+// test runs refreshes deterministically instead of sleeping. It is safe for
+// concurrent use. Create it with NewFakeClock.
+type FakeClock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond   // signaled when a wait is added
+	now    time.Time    // current time of the clock
+	timers []*fakeTimer // pending waits, in the order they were added
+}
+
+// fakeTimer is a wait of a FakeClock.
+type fakeTimer struct {
+	deadline time.Time
+	c        chan time.Time
+}
+
+// NewFakeClock creates a FakeClock telling the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	clock := &FakeClock{now: now}
+	clock.cond = sync.NewCond(&clock.mu)
+	return clock
+}
+
+// Now returns the current time of the clock.
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After returns a channel that receives the time once the clock has been
+// advanced by d.
+func (f *FakeClock) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	timer := &fakeTimer{deadline: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		timer.c <- f.now
+		return timer.c
+	}
+	f.timers = append(f.timers, timer)
+	f.cond.Broadcast()
+	return timer.c
+}
+
+// Advance moves the clock forward by d and ends the waits it passed.
+func (f *FakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	pending := f.timers[:0]
+	for _, timer := range f.timers {
+		if timer.deadline.After(f.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- f.now
+	}
+	f.timers = pending
+}
+
+// BlockUntil blocks until at least n waits are pending, such as the wait of
+// the refresh loop for its next refresh, so that a following Advance ends
+// them. Waits abandoned by their caller stay pending until they are passed.
+func (f *FakeClock) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.cond.Wait()
+	}
+}
@@ -0,0 +1,148 @@
+// Package configtest helps test code that reads its configuration through a
+// Client. MockRepository is a source.Repository whose data, refresh error and
+// refresh count the test controls, and NewClient builds a Client around one
+// without any real source.
+package configtest
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/client"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// MockRepository is a source.Repository held in memory for tests. Changes
+// made with Set, Delete and SetData are staged and served once the next
+// refresh succeeds, like the changes of a real source, and a refresh fails
+// with the error set with SetRefreshError, keeping the data it served. It is
+// safe for concurrent use.
+type MockRepository struct {
+	mu         sync.RWMutex
+	Name       string                 // Name of the configuration source
+	data       map[string]interface{} // data served by GetData
+	pending    map[string]interface{} // data served after the next successful refresh
+	refreshErr error                  // error of the next refreshes, nil for success
+	refreshes  int                    // number of Refresh calls
+}
+
+// NewMockRepository creates a MockRepository named "mock" serving data, a map
+// of configuration names to their values, such as strings, ints, float64s,
+// bools, []interface{} and map[string]interface{}.
+func NewMockRepository(data map[string]interface{}) *MockRepository {
+	return &MockRepository{
+		Name:    "mock",
+		data:    copyData(data),
+		pending: copyData(data),
+	}
+}
+
+// GetName returns the name of the configuration source.
+func (m *MockRepository) GetName() string {
+	return m.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (m *MockRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	config, isPresent = m.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, as the data has no serialized form.
+func (m *MockRepository) GetRawData() []byte {
+	return nil
+}
+
+// Keys returns the names of the served configurations.
+func (m *MockRepository) Keys() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]string, 0, len(m.data))
+	for key := range m.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Refresh counts the call and returns the error set with SetRefreshError.
+// Without an error it serves the staged changes.
+func (m *MockRepository) Refresh() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshes++
+	if m.refreshErr != nil {
+		return m.refreshErr
+	}
+	m.data = copyData(m.pending)
+	return nil
+}
+
+// Set stages the configuration with the given name to hold value.
+func (m *MockRepository) Set(name string, value interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending[name] = value
+}
+
+// Delete stages the removal of the configuration with the given name.
+func (m *MockRepository) Delete(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pending, name)
+}
+
+// SetData stages data to replace every configuration.
+func (m *MockRepository) SetData(data map[string]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending = copyData(data)
+}
+
+// SetRefreshError makes the next refreshes fail with err, or succeed again
+// when err is nil.
+func (m *MockRepository) SetRefreshError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshErr = err
+}
+
+// RefreshCount returns the number of times Refresh has been called.
+func (m *MockRepository) RefreshCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.refreshes
+}
+
+// NewClient creates a Client serving data from a MockRepository, which it
+// returns to let the test change the data or fail refreshes. The Client
+// refreshes only when ForceRefresh is called, and opts apply as with
+// client.NewClient. It fails the test if the Client cannot be created, and
+// the Client is closed and the previous default client restored when the
+// test ends.
+func NewClient(t testing.TB, data map[string]interface{}, opts ...client.Option) (*client.Client, *MockRepository) {
+	t.Helper()
+	repository := NewMockRepository(data)
+	previous := client.DefaultClient()
+	c, err := client.NewClient(context.Background(), repository, 24*time.Hour, opts...)
+	if err != nil {
+		t.Fatalf("configtest: creating client: %s", err.Error())
+	}
+	t.Cleanup(func() {
+		c.Close()
+		client.SetDefaultClient(previous)
+	})
+	return c, repository
+}
+
+// copyData returns a shallow copy of data.
+func copyData(data map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		copied[key] = value
+	}
+	return copied
+}
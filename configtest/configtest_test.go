@@ -0,0 +1,138 @@
+package configtest
+
+import (
+	"context"
+	"errors"
+	"github.com/divakarmanoj/go-remote-config/client"
+	"testing"
+	"time"
+)
+
+// retryLimit stands in for application code that reads its configuration
+// through the default client.
+func retryLimit() int {
+	limit, err := client.GetConfigInt("retries", 3)
+	if err != nil {
+		return 3
+	}
+	return limit
+}
+
+func TestNewClient(t *testing.T) {
+	c, repository := NewClient(t, map[string]interface{}{"retries": 5}, client.WithLogger(client.NewNopLogger()))
+	if limit := retryLimit(); limit != 5 {
+		t.Errorf("Expected the configured limit, got %d", limit)
+	}
+
+	repository.Delete("retries")
+	if err := c.ForceRefresh(context.Background()); err != nil {
+		t.Fatalf("Error refreshing client: %s", err.Error())
+	}
+	if limit := retryLimit(); limit != 3 {
+		t.Errorf("Expected the default limit once the key is removed, got %d", limit)
+	}
+
+	errUnavailable := errors.New("backend unavailable")
+	repository.SetRefreshError(errUnavailable)
+	repository.SetData(map[string]interface{}{"retries": 9})
+	if err := c.ForceRefresh(context.Background()); !errors.Is(err, errUnavailable) {
+		t.Errorf("Expected the refresh error, got %v", err)
+	}
+	if limit := retryLimit(); limit != 3 {
+		t.Errorf("Expected a failed refresh not to serve the staged data, got %d", limit)
+	}
+	if keys := c.Keys(); len(keys) != 0 {
+		t.Errorf("Expected no configurations, got %v", keys)
+	}
+	if count := repository.RefreshCount(); count != 3 {
+		t.Errorf("Expected 3 refreshes, got %d", count)
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	repository := NewMockRepository(map[string]interface{}{"retries": 1})
+	c, err := client.NewClient(context.Background(), repository, time.Minute, client.WithClock(clock), client.WithLogger(client.NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer c.Close()
+
+	// Each refresh interval passed on the clock runs one refresh.
+	for i := 2; i <= 4; i++ {
+		repository.Set("retries", i)
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		clock.BlockUntil(1) // The loop waits again once the refresh is done
+		if count := repository.RefreshCount(); count != i {
+			t.Errorf("Expected %d refreshes, got %d", i, count)
+		}
+		if retries, _ := c.GetConfigInt("retries", 0); retries != i {
+			t.Errorf("Expected the refreshed value %d, got %d", i, retries)
+		}
+	}
+
+	// Less than the refresh interval does not refresh.
+	clock.Advance(30 * time.Second)
+	if count := repository.RefreshCount(); count != 4 {
+		t.Errorf("Expected no refresh before the interval, got %d refreshes", count)
+	}
+}
+
+func TestFakeClockExpiry(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	repository := NewMockRepository(map[string]interface{}{
+		"token": map[string]interface{}{"_value": "abc", "_ttl": "90s"},
+	})
+	c, err := client.NewClient(context.Background(), repository, time.Hour, client.WithClock(clock), client.WithLogger(client.NewNopLogger()))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer c.Close()
+	expired := make(chan struct{}, 10)
+	cancel := c.OnExpire("token", func() {
+		expired <- struct{}{}
+	})
+	defer cancel()
+	expectExpiry := func(fired bool) {
+		t.Helper()
+		select {
+		case <-expired:
+			if !fired {
+				t.Errorf("Expected no expiry notification")
+			}
+		case <-time.After(100 * time.Millisecond):
+			if fired {
+				t.Errorf("Expected an expiry notification")
+			}
+		}
+	}
+
+	// The refresh loop and the expiry both wait on the clock.
+	clock.BlockUntil(2)
+	clock.Advance(time.Minute)
+	expectExpiry(false)
+	clock.Advance(30 * time.Second)
+	expectExpiry(true)
+	if token, _ := c.GetConfigString("token", "expired"); token != "expired" {
+		t.Errorf("Expected the token to have expired, got %s", token)
+	}
+
+	// The lapse is reported once, until a refresh renews the TTL.
+	clock.Advance(time.Minute)
+	expectExpiry(false)
+	if err := c.ForceRefresh(context.Background()); err != nil {
+		t.Fatalf("Error refreshing client: %s", err.Error())
+	}
+	clock.BlockUntil(2)
+	clock.Advance(90 * time.Second)
+	expectExpiry(true)
+
+	// Nothing is reported once the notifications are canceled.
+	cancel()
+	if err := c.ForceRefresh(context.Background()); err != nil {
+		t.Fatalf("Error refreshing client: %s", err.Error())
+	}
+	clock.Advance(90 * time.Second)
+	expectExpiry(false)
+}
@@ -0,0 +1,37 @@
+package configtest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/divakarmanoj/go-remote-config/client"
+	"github.com/divakarmanoj/go-remote-config/configtest"
+	"time"
+)
+
+func ExampleMockRepository() {
+	repository := configtest.NewMockRepository(map[string]interface{}{"timeout": 5})
+	c, err := client.NewClient(context.Background(), repository, time.Hour, client.WithLogger(client.NewNopLogger()))
+	if err != nil {
+		panic(err)
+	}
+	defer c.Close()
+
+	// A change is served once the Client refreshes.
+	repository.Set("timeout", 10)
+	_ = c.ForceRefresh(context.Background())
+	timeout, _ := c.GetConfigInt("timeout", 1)
+	fmt.Println(timeout)
+
+	// A failed refresh keeps the last good value.
+	repository.SetRefreshError(errors.New("backend unavailable"))
+	repository.Set("timeout", 20)
+	err = c.ForceRefresh(context.Background())
+	timeout, _ = c.GetConfigInt("timeout", 1)
+	fmt.Println(timeout, err)
+	fmt.Println(repository.RefreshCount())
+	// Output:
+	// 10
+	// 10 backend unavailable
+	// 3
+}
@@ -0,0 +1,222 @@
+// Package metrics instruments a Client with Prometheus metrics. It is a
+// separate package so that programs that do not use Prometheus do not link
+// its client library.
+package metrics
+
+import (
+	"errors"
+	"github.com/divakarmanoj/go-remote-config/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"math"
+	"time"
+)
+
+// DefaultNamespace is the namespace of the metrics when none is configured.
+const DefaultNamespace = "remote_config"
+
+// Option configures the metrics registered by WithPrometheus.
+type Option func(*config)
+
+// config is the configuration of the metrics of a Client.
+type config struct {
+	namespace   string            // Namespace of the metric names
+	subsystem   string            // Subsystem of the metric names, none when empty
+	constLabels prometheus.Labels // Labels added to every metric
+	now         func() time.Time  // Current time the staleness gauge measures up to
+	reads       bool              // Whether to count the reads of getters
+}
+
+// WithNamespace sets the namespace of the metric names, which defaults to
+// DefaultNamespace.
+func WithNamespace(namespace string) Option {
+	return func(c *config) {
+		c.namespace = namespace
+	}
+}
+
+// WithSubsystem sets the subsystem of the metric names, so that several
+// Clients of one process can register their metrics side by side.
+func WithSubsystem(subsystem string) Option {
+	return func(c *config) {
+		c.subsystem = subsystem
+	}
+}
+
+// WithConstLabels adds labels with fixed values to every metric, such as the
+// name of the configuration source, so that several Clients of one process
+// can register their metrics side by side.
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(c *config) {
+		if c.constLabels == nil {
+			c.constLabels = prometheus.Labels{}
+		}
+		for name, value := range labels {
+			c.constLabels[name] = value
+		}
+	}
+}
+
+// WithClock measures the time since the last successful refresh up to the
+// time returned by now instead of the current time. It should tell the same
+// time as the Clock of the Client.
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+// WithReadMetrics also counts the reads of getters by configuration name:
+//
+//   - reads_total counts the reads of each configuration.
+//   - read_fallbacks_total counts the reads that returned the default value,
+//     by configuration and reason: not_found, type_mismatch, closed or error.
+//
+// The counters are labeled with the names of the configurations read, so
+// only use it when those are a bounded set.
+func WithReadMetrics() Option {
+	return func(c *config) {
+		c.reads = true
+	}
+}
+
+// WithPrometheus registers metrics of the refreshes of the Client with
+// registerer:
+//
+//   - refreshes_total counts the refreshes, successful or not.
+//   - refresh_errors_total counts the failed refreshes.
+//   - refresh_duration_seconds is a histogram of the time refreshes take.
+//   - seconds_since_last_success is the time since the last successful
+//     refresh, +Inf until a refresh succeeds.
+//   - changes_total counts the changed configurations by the source they
+//     came from, the layer of a source.SourceResolver such as a ChainRepository.
+//
+// The names are prefixed with the namespace and subsystem. Clients that
+// register metrics with the same names and const labels share the counters
+// and the histogram instead of failing, while the gauge keeps reporting the
+// first of them. Metrics that cannot be registered are logged and left out.
+func WithPrometheus(registerer prometheus.Registerer, opts ...Option) client.Option {
+	cfg := &config{namespace: DefaultNamespace, now: time.Now}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return func(c *client.Client) {
+		refreshes := register(registerer, prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   cfg.namespace,
+			Subsystem:   cfg.subsystem,
+			Name:        "refreshes_total",
+			Help:        "Number of refreshes of the configuration, successful or not.",
+			ConstLabels: cfg.constLabels,
+		}))
+		refreshErrors := register(registerer, prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   cfg.namespace,
+			Subsystem:   cfg.subsystem,
+			Name:        "refresh_errors_total",
+			Help:        "Number of failed refreshes of the configuration.",
+			ConstLabels: cfg.constLabels,
+		}))
+		duration := register(registerer, prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   cfg.namespace,
+			Subsystem:   cfg.subsystem,
+			Name:        "refresh_duration_seconds",
+			Help:        "Time refreshes of the configuration take.",
+			ConstLabels: cfg.constLabels,
+			Buckets:     prometheus.DefBuckets,
+		}))
+		changes := register(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.namespace,
+			Subsystem:   cfg.subsystem,
+			Name:        "changes_total",
+			Help:        "Number of configurations changed by refreshes, by the source they came from.",
+			ConstLabels: cfg.constLabels,
+		}, []string{"source"}))
+		register(registerer, prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   cfg.namespace,
+			Subsystem:   cfg.subsystem,
+			Name:        "seconds_since_last_success",
+			Help:        "Time since the last successful refresh of the configuration.",
+			ConstLabels: cfg.constLabels,
+		}, func() float64 {
+			lastSuccess, _ := c.LastRefresh()
+			if lastSuccess.IsZero() {
+				return math.Inf(1)
+			}
+			return cfg.now().Sub(lastSuccess).Seconds()
+		}))
+
+		if cfg.reads {
+			registerReads(registerer, cfg)(c)
+		}
+
+		client.WithRefreshListener(func(result client.RefreshResult) {
+			if refreshes != nil {
+				refreshes.Inc()
+			}
+			if duration != nil {
+				duration.Observe(result.Duration.Seconds())
+			}
+			if result.Err != nil {
+				if refreshErrors != nil {
+					refreshErrors.Inc()
+				}
+				return
+			}
+			if changes == nil {
+				return
+			}
+			for _, key := range result.ChangedKeys {
+				source, ok := result.ChangeSources[key]
+				if !ok {
+					source = result.Source
+				}
+				changes.WithLabelValues(source).Inc()
+			}
+		})(c)
+	}
+}
+
+// registerReads registers the counters of WithReadMetrics with registerer and
+// returns the option that counts the reads of a Client with them.
+func registerReads(registerer prometheus.Registerer, cfg *config) client.Option {
+	reads := register(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   cfg.namespace,
+		Subsystem:   cfg.subsystem,
+		Name:        "reads_total",
+		Help:        "Number of reads of each configuration by getters.",
+		ConstLabels: cfg.constLabels,
+	}, []string{"key"}))
+	fallbacks := register(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   cfg.namespace,
+		Subsystem:   cfg.subsystem,
+		Name:        "read_fallbacks_total",
+		Help:        "Number of reads of each configuration that returned the default value, by reason.",
+		ConstLabels: cfg.constLabels,
+	}, []string{"key", "reason"}))
+	return client.WithReadListener(func(event client.ReadEvent) {
+		if reads != nil {
+			reads.WithLabelValues(event.Key).Inc()
+		}
+		if fallbacks != nil && event.Fallback != client.FallbackNone {
+			fallbacks.WithLabelValues(event.Key, string(event.Fallback)).Inc()
+		}
+	})
+}
+
+// register registers collector with registerer and returns it, or the
+// collector registered before it with the same metric. It returns the zero
+// value if collector cannot be registered.
+func register[T prometheus.Collector](registerer prometheus.Registerer, collector T) T {
+	err := registerer.Register(collector)
+	if err == nil {
+		return collector
+	}
+	var registered prometheus.AlreadyRegisteredError
+	if errors.As(err, &registered) {
+		if existing, ok := registered.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+	logrus.WithError(err).Warn("error registering metric")
+	var zero T
+	return zero
+}
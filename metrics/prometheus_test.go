@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"context"
+	"github.com/divakarmanoj/go-remote-config/client"
+	"github.com/divakarmanoj/go-remote-config/source"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock that only moves when advanced.
+type fakeClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.Lock()
+	defer f.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) advance(d time.Duration) {
+	f.Lock()
+	defer f.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+}
+
+func TestWithPrometheus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "name: John\n")
+	clock := &fakeClock{now: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)}
+	registry := prometheus.NewRegistry()
+	repository := &source.FileRepository{Name: "file", Path: path, ParseRetryDelay: time.Millisecond}
+	c, err := client.NewClient(context.Background(), repository, time.Hour,
+		client.WithClock(clock),
+		WithPrometheus(registry, WithSubsystem("flags"), WithConstLabels(prometheus.Labels{"client": "flags"}), WithClock(clock.Now)))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer c.Close()
+
+	writeFile(t, path, "name: Jane\n")
+	err = c.ForceRefresh(context.Background())
+	if err != nil {
+		t.Fatalf("Error refreshing: %s", err.Error())
+	}
+	clock.advance(time.Minute)
+	writeFile(t, path, "name: [unclosed\n")
+	if c.ForceRefresh(context.Background()) == nil {
+		t.Fatalf("Expected error refreshing a file that does not parse, got nil")
+	}
+
+	expected := `
+# HELP remote_config_flags_changes_total Number of configurations changed by refreshes, by the source they came from.
+# TYPE remote_config_flags_changes_total counter
+remote_config_flags_changes_total{client="flags",source="file"} 2
+# HELP remote_config_flags_refresh_errors_total Number of failed refreshes of the configuration.
+# TYPE remote_config_flags_refresh_errors_total counter
+remote_config_flags_refresh_errors_total{client="flags"} 1
+# HELP remote_config_flags_refreshes_total Number of refreshes of the configuration, successful or not.
+# TYPE remote_config_flags_refreshes_total counter
+remote_config_flags_refreshes_total{client="flags"} 3
+# HELP remote_config_flags_seconds_since_last_success Time since the last successful refresh of the configuration.
+# TYPE remote_config_flags_seconds_since_last_success gauge
+remote_config_flags_seconds_since_last_success{client="flags"} 60
+`
+	err = testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"remote_config_flags_changes_total",
+		"remote_config_flags_refresh_errors_total",
+		"remote_config_flags_refreshes_total",
+		"remote_config_flags_seconds_since_last_success")
+	if err != nil {
+		t.Errorf("Expected the metrics to match, got %s", err.Error())
+	}
+	count, err := testutil.GatherAndCount(registry, "remote_config_flags_refresh_duration_seconds")
+	if err != nil || count != 1 {
+		t.Errorf("Expected the refresh duration histogram, got %d (%v)", count, err)
+	}
+}
+
+func TestWithPrometheusNamespaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "name: John\n")
+	registry := prometheus.NewRegistry()
+	for _, namespace := range []string{"flags", "secrets"} {
+		repository := &source.FileRepository{Name: namespace, Path: path}
+		c, err := client.NewClient(context.Background(), repository, time.Hour, WithPrometheus(registry, WithNamespace(namespace)))
+		if err != nil {
+			t.Fatalf("Error creating client: %s", err.Error())
+		}
+		defer c.Close()
+	}
+	for _, name := range []string{"flags_refreshes_total", "secrets_refreshes_total"} {
+		count, err := testutil.GatherAndCount(registry, name)
+		if err != nil || count != 1 {
+			t.Errorf("Expected %s to be registered, got %d (%v)", name, count, err)
+		}
+	}
+
+	// Clients registering the same metrics share the counters.
+	repository := &source.FileRepository{Name: "flags", Path: path}
+	c, err := client.NewClient(context.Background(), repository, time.Hour, WithPrometheus(registry, WithNamespace("flags")))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer c.Close()
+	expected := `
+# HELP flags_refreshes_total Number of refreshes of the configuration, successful or not.
+# TYPE flags_refreshes_total counter
+flags_refreshes_total 2
+`
+	err = testutil.GatherAndCompare(registry, strings.NewReader(expected), "flags_refreshes_total")
+	if err != nil {
+		t.Errorf("Expected the refreshes to be shared, got %s", err.Error())
+	}
+}
+
+func TestWithReadMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "name: John\nage: thirty\n")
+	registry := prometheus.NewRegistry()
+	c, err := client.NewClient(context.Background(), &source.FileRepository{Name: "file", Path: path}, time.Hour,
+		WithPrometheus(registry, WithReadMetrics()))
+	if err != nil {
+		t.Fatalf("Error creating client: %s", err.Error())
+	}
+	defer c.Close()
+
+	_, _ = c.GetConfigString("name", "")
+	_, _ = c.GetConfigString("name", "")
+	_, _ = c.GetConfigInt("age", 0)
+	_, _ = c.GetConfigString("missing", "")
+
+	expected := `
+# HELP remote_config_read_fallbacks_total Number of reads of each configuration that returned the default value, by reason.
+# TYPE remote_config_read_fallbacks_total counter
+remote_config_read_fallbacks_total{key="age",reason="type_mismatch"} 1
+remote_config_read_fallbacks_total{key="missing",reason="not_found"} 1
+# HELP remote_config_reads_total Number of reads of each configuration by getters.
+# TYPE remote_config_reads_total counter
+remote_config_reads_total{key="age"} 1
+remote_config_reads_total{key="missing"} 1
+remote_config_reads_total{key="name"} 2
+`
+	err = testutil.GatherAndCompare(registry, strings.NewReader(expected), "remote_config_reads_total", "remote_config_read_fallbacks_total")
+	if err != nil {
+		t.Error(err)
+	}
+}
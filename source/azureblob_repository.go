@@ -0,0 +1,314 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ErrBlobNotModified is returned by an AzureBlobAPI when the blob still has
+// the ETag the download was made with.
+var ErrBlobNotModified = errors.New("blob not modified")
+
+// AzureBlob is a blob downloaded from Azure Blob Storage.
+type AzureBlob struct {
+	Data []byte // Content of the blob
+	ETag string // ETag of the blob
+}
+
+// AzureBlobAPI is the subset of Azure Blob Storage operations used by AzureBlobRepository.
+type AzureBlobAPI interface {
+	// DownloadBlob downloads the blob with the given name from container. When
+	// etag is not empty and the blob still has it, it returns ErrBlobNotModified.
+	DownloadBlob(ctx context.Context, container string, name string, etag string) (AzureBlob, error)
+}
+
+// AzureBlobRepository is a struct that implements the Repository interface
+// for handling configuration data stored in a blob of an Azure Storage
+// container. The blob is downloaded with the ETag of the loaded blob, so an
+// unchanged blob is neither downloaded nor reparsed. A failed refresh leaves
+// the current data in place, and errors of authentication or authorization
+// wrap ErrSourceUnauthorized.
+type AzureBlobRepository struct {
+	sync.RWMutex                            // RWMutex to synchronize access to data
+	Name             string                 // Name of the configuration source
+	Account          string                 // Name of the storage account
+	Container        string                 // Name of the container
+	Blob             string                 // Name of the blob holding the configuration file
+	ServiceURL       string                 // URL of the blob service, derived from Account when empty
+	ConnectionString string                 // Connection string of the storage account, used instead of Credential when set
+	Credential       azcore.TokenCredential // Credential of the client, the managed identity of the host when nil
+	Client           AzureBlobAPI           // Blob client, created by NewAzureBlobRepository
+	Codec            Codec                  // Codec used to parse the file, defaults to YAMLCodec
+	data             map[string]interface{} // Map to store the configuration data
+	rawData          []byte                 // Raw data of the configuration file
+	version          string                 // Version marker of the currently loaded data
+	etag             string                 // ETag of the currently loaded blob, sent as If-None-Match
+}
+
+// AzureBlobOption configures an AzureBlobRepository created with
+// NewAzureBlobRepository.
+type AzureBlobOption func(*AzureBlobRepository)
+
+// WithAzureBlobConnectionString authenticates with the connection string of
+// the storage account instead of a token credential. The connection string
+// holds the account key, so it is never logged.
+func WithAzureBlobConnectionString(connectionString string) AzureBlobOption {
+	return func(r *AzureBlobRepository) {
+		r.ConnectionString = connectionString
+	}
+}
+
+// WithAzureBlobCredential authenticates with credential, such as an
+// azidentity.DefaultAzureCredential, instead of the managed identity of the
+// host.
+func WithAzureBlobCredential(credential azcore.TokenCredential) AzureBlobOption {
+	return func(r *AzureBlobRepository) {
+		r.Credential = credential
+	}
+}
+
+// WithAzureBlobServiceURL sends requests to the given blob service, such as
+// an Azurite emulator or the endpoint of a sovereign cloud, instead of
+// https://<account>.blob.core.windows.net/.
+func WithAzureBlobServiceURL(serviceURL string) AzureBlobOption {
+	return func(r *AzureBlobRepository) {
+		r.ServiceURL = serviceURL
+	}
+}
+
+// WithAzureBlobCodec sets the codec used to parse the file.
+func WithAzureBlobCodec(codec Codec) AzureBlobOption {
+	return func(r *AzureBlobRepository) {
+		r.Codec = codec
+	}
+}
+
+// WithAzureBlobClient sets the client used to download the blob.
+func WithAzureBlobClient(client AzureBlobAPI) AzureBlobOption {
+	return func(r *AzureBlobRepository) {
+		r.Client = client
+	}
+}
+
+// NewAzureBlobRepository creates an AzureBlobRepository reading the blob with
+// the given name from container in the storage account. Unless
+// WithAzureBlobClient is given, the client authenticates with the connection
+// string of WithAzureBlobConnectionString, the credential of
+// WithAzureBlobCredential or else the managed identity of the host.
+func NewAzureBlobRepository(name string, account string, container string, blobName string, opts ...AzureBlobOption) (*AzureBlobRepository, error) {
+	repository := &AzureBlobRepository{
+		Name:      name,
+		Account:   account,
+		Container: container,
+		Blob:      blobName,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	if repository.Client != nil {
+		return repository, nil
+	}
+	if repository.ConnectionString != "" {
+		client, err := azblob.NewClientFromConnectionString(repository.ConnectionString, nil)
+		if err != nil {
+			// The error of the SDK does not include the connection string.
+			return nil, err
+		}
+		repository.Client = &azblobClient{client: client}
+		return repository, nil
+	}
+	if repository.ServiceURL == "" {
+		repository.ServiceURL = fmt.Sprintf("https://%s.blob.core.windows.net/", account)
+	}
+	if repository.Credential == nil {
+		credential, err := azidentity.NewManagedIdentityCredential(nil)
+		if err != nil {
+			return nil, err
+		}
+		repository.Credential = credential
+	}
+	client, err := azblob.NewClient(repository.ServiceURL, repository.Credential, nil)
+	if err != nil {
+		return nil, err
+	}
+	repository.Client = &azblobClient{client: client}
+	return repository, nil
+}
+
+// GetName returns the name of the configuration source.
+func (r *AzureBlobRepository) GetName() string {
+	return r.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (r *AzureBlobRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	r.RLock()
+	defer r.RUnlock()
+	config, isPresent = r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns the raw data of the configuration file.
+func (r *AzureBlobRepository) GetRawData() []byte {
+	r.RLock()
+	defer r.RUnlock()
+	return r.rawData
+}
+
+// Version returns the ETag of the currently loaded blob.
+func (r *AzureBlobRepository) Version() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.version
+}
+
+// Keys returns the names of the configurations in the currently loaded file.
+func (r *AzureBlobRepository) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.data)
+}
+
+// Refresh downloads the blob and replaces the data map with its
+// configurations, unless the blob has not changed.
+func (r *AzureBlobRepository) Refresh() error {
+	return r.RefreshContext(context.Background())
+}
+
+// RefreshContext is Refresh with the requests bound to ctx, so that they are
+// abandoned once ctx is done.
+func (r *AzureBlobRepository) RefreshContext(ctx context.Context) error {
+	r.RLock()
+	etag := r.etag
+	r.RUnlock()
+
+	// Download the blob, unless it has not changed.
+	downloaded, err := r.Client.DownloadBlob(ctx, r.Container, r.Blob, etag)
+	if errors.Is(err, ErrBlobNotModified) {
+		logrus.Debug("blob not modified, skipping reparse")
+		return nil
+	}
+	if err != nil {
+		logrus.Debug("error downloading blob")
+		return r.apiError(err)
+	}
+
+	// Skip reparsing when the version has not changed.
+	version := downloaded.ETag
+	if version == "" {
+		version = documentVersion(downloaded.Data)
+	}
+	r.RLock()
+	unchanged := version != "" && version == r.version
+	r.RUnlock()
+	if unchanged {
+		logrus.Debug("version unchanged, skipping reparse")
+		return nil
+	}
+
+	// Unmarshal the data into a new map with the codec, so a file that does
+	// not parse leaves the current data in place.
+	var parsed map[string]interface{}
+	err = codecOrDefault(r.Codec).Unmarshal(downloaded.Data, &parsed)
+	if err != nil {
+		logrus.Debug("error unmarshalling file")
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.data = parsed
+	r.rawData = downloaded.Data
+	r.version = version
+	r.etag = downloaded.ETag
+	return nil
+}
+
+// Load downloads the blob the way Refresh does and returns its
+// configurations, without replacing the loaded data. The blob is always
+// downloaded, even if it has not changed.
+func (r *AzureBlobRepository) Load() (map[string]interface{}, error) {
+	return r.LoadContext(context.Background())
+}
+
+// LoadContext is Load with the request bound to ctx.
+func (r *AzureBlobRepository) LoadContext(ctx context.Context) (map[string]interface{}, error) {
+	downloaded, err := r.Client.DownloadBlob(ctx, r.Container, r.Blob, "")
+	if err != nil {
+		logrus.Debug("error downloading blob")
+		return nil, r.apiError(err)
+	}
+	var parsed map[string]interface{}
+	err = codecOrDefault(r.Codec).Unmarshal(downloaded.Data, &parsed)
+	if err != nil {
+		logrus.Debug("error unmarshalling file")
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// apiError returns err of Azure with the reasons a blob cannot be read
+// spelled out, so that missing credentials or role assignments are easy to
+// tell apart from an outage.
+func (r *AzureBlobRepository) apiError(err error) error {
+	var authErr *azidentity.AuthenticationFailedError
+	if errors.As(err, &authErr) {
+		return fmt.Errorf("%w: authenticating to read blob %s/%s: %v", ErrSourceUnauthorized, r.Container, r.Blob, err)
+	}
+	var responseErr *azcore.ResponseError
+	if !errors.As(err, &responseErr) {
+		return err
+	}
+	switch responseErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: not allowed to read blob %s/%s, check the credentials and the role assignments of the identity: %v", ErrSourceUnauthorized, r.Container, r.Blob, err)
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: blob %s/%s: %v", ErrSourceNotFound, r.Container, r.Blob, err)
+	}
+	return err
+}
+
+// azblobClient implements AzureBlobAPI with the Azure SDK.
+type azblobClient struct {
+	client *azblob.Client
+}
+
+func (a *azblobClient) DownloadBlob(ctx context.Context, container string, name string, etag string) (AzureBlob, error) {
+	options := &azblob.DownloadStreamOptions{}
+	if etag != "" {
+		ifNoneMatch := azcore.ETag(etag)
+		options.AccessConditions = &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: &ifNoneMatch},
+		}
+	}
+	response, err := a.client.DownloadStream(ctx, container, name, options)
+	if err != nil {
+		var responseErr *azcore.ResponseError
+		if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotModified {
+			return AzureBlob{}, ErrBlobNotModified
+		}
+		return AzureBlob{}, err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return AzureBlob{}, err
+	}
+	if response.ContentLength != nil && int64(len(data)) != *response.ContentLength {
+		return AzureBlob{}, fmt.Errorf("%w: received %d of %d bytes", ErrTruncated, len(data), *response.ContentLength)
+	}
+	downloaded := AzureBlob{Data: data}
+	if response.ETag != nil {
+		downloaded.ETag = string(*response.ETag)
+	}
+	return downloaded, nil
+}
@@ -0,0 +1,163 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAzureBlob is an in-memory AzureBlobAPI serving one blob, or failing
+// with err when it is set.
+type fakeAzureBlob struct {
+	data      []byte
+	etag      string
+	err       error
+	downloads int      // number of downloads that returned the blob
+	etags     []string // ETags the downloads were made with
+	ctx       context.Context
+}
+
+func (f *fakeAzureBlob) DownloadBlob(ctx context.Context, container string, name string, etag string) (AzureBlob, error) {
+	f.ctx = ctx
+	f.etags = append(f.etags, etag)
+	if f.err != nil {
+		return AzureBlob{}, f.err
+	}
+	if container != "config" || name != "app.yaml" {
+		return AzureBlob{}, &azcore.ResponseError{StatusCode: http.StatusNotFound}
+	}
+	if etag != "" && etag == f.etag {
+		return AzureBlob{}, ErrBlobNotModified
+	}
+	f.downloads++
+	return AzureBlob{Data: f.data, ETag: f.etag}, nil
+}
+
+func TestAzureBlobRepository(t *testing.T) {
+	client := &fakeAzureBlob{data: []byte("name: John\nage: 30\n"), etag: `"0x1"`}
+	repository, err := NewAzureBlobRepository("azure", "account", "config", "app.yaml", WithAzureBlobClient(client))
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, ok := repository.GetData("name")
+	if !ok || name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+	if repository.Version() != `"0x1"` {
+		t.Errorf("Expected the version to be the ETag, got %s", repository.Version())
+	}
+
+	// An unchanged blob is requested with its ETag and not downloaded again.
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if client.downloads != 1 || client.etags[1] != `"0x1"` {
+		t.Errorf("Expected the second refresh to send the ETag and skip the download, got %d downloads with %v", client.downloads, client.etags)
+	}
+
+	// A changed blob is downloaded and parsed.
+	client.data = []byte("name: Jane\n")
+	client.etag = `"0x2"`
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected name to be Jane, got %v", name)
+	}
+
+	// Load downloads the blob without replacing the data.
+	client.data = []byte("name: Jill\n")
+	client.etag = `"0x3"`
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	if loaded["name"] != "Jill" {
+		t.Errorf("Expected loaded name to be Jill, got %v", loaded["name"])
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" || repository.Version() != `"0x2"` {
+		t.Errorf("Expected name to stay Jane at 0x2, got %v at %s", name, repository.Version())
+	}
+	client.data = []byte("name: Jane\n")
+	client.etag = `"0x2"`
+
+	// A transient failure keeps the last good data.
+	client.err = &azcore.ResponseError{StatusCode: http.StatusServiceUnavailable}
+	err = repository.Refresh()
+	if err == nil {
+		t.Fatalf("Expected the refresh to fail")
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected the last good name Jane, got %v", name)
+	}
+
+	// A blob that does not parse keeps the last good data too.
+	client.err = nil
+	client.data = []byte("name: [")
+	client.etag = `"0x3"`
+	if err := repository.Refresh(); err == nil {
+		t.Errorf("Expected an invalid blob to fail the refresh")
+	}
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected an invalid blob to fail the load")
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected the last good name Jane, got %v", name)
+	}
+}
+
+func TestAzureBlobRepositoryErrors(t *testing.T) {
+	client := &fakeAzureBlob{}
+	repository, err := NewAzureBlobRepository("azure", "account", "config", "app.yaml", WithAzureBlobClient(client))
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	for _, tc := range []struct {
+		status int
+		kind   error
+	}{
+		{http.StatusForbidden, ErrSourceUnauthorized},
+		{http.StatusUnauthorized, ErrSourceUnauthorized},
+		{http.StatusNotFound, ErrSourceNotFound},
+	} {
+		client.err = &azcore.ResponseError{StatusCode: tc.status}
+		err := repository.Refresh()
+		if !errors.Is(err, tc.kind) {
+			t.Errorf("Expected status %d to be reported as %v, got %v", tc.status, tc.kind, err)
+		}
+	}
+	client.err = &azcore.ResponseError{StatusCode: http.StatusForbidden}
+	if err := repository.Refresh(); !strings.Contains(err.Error(), "role assignments") {
+		t.Errorf("Expected a denied read to point at the role assignments, got %v", err)
+	}
+}
+
+func TestAzureBlobRepositoryContext(t *testing.T) {
+	client := &fakeAzureBlob{data: []byte("name: John\n"), etag: `"0x1"`}
+	repository, err := NewAzureBlobRepository("azure", "account", "config", "app.yaml", WithAzureBlobClient(client))
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := repository.RefreshContext(ctx); err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if client.ctx != ctx {
+		t.Errorf("Expected the download to be bound to the context of the refresh")
+	}
+}
@@ -0,0 +1,205 @@
+package source
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// CachedRepository is a struct that implements the Repository interface by
+// serving the data of another repository from a snapshot taken after each of
+// its successful refreshes. Reads never wait on the wrapped repository, even
+// while a slow refresh holds its lock: they see the previous snapshot until
+// the new one is swapped in whole. With MaxStaleness set, the snapshot is
+// withheld once the last successful refresh is older than it, so getters
+// return their defaults rather than data that is too old. A repository that
+// does not implement KeyLister cannot be copied, so reads of it go through to
+// it, still subject to MaxStaleness.
+type CachedRepository struct {
+	Repository   Repository                     // Repository the data is read from
+	MaxStaleness time.Duration                  // Age of the last successful refresh after which no data is served, none when 0
+	Now          func() time.Time               // Current time the age of the snapshot is measured with, defaults to time.Now
+	snapshot     atomic.Pointer[cachedSnapshot] // Snapshot of the last successful refresh, nil before the first one
+}
+
+// cachedSnapshot is the data of a repository after a successful refresh.
+type cachedSnapshot struct {
+	data        map[string]interface{} // Configurations of the repository, nil if it cannot list them
+	sources     map[string]string      // Repository each configuration came from, if the repository is a SourceResolver
+	keys        []string               // Sorted names of the configurations
+	rawData     []byte                 // Raw data of the repository
+	version     string                 // Version of the repository, if it is Versioned
+	refreshedAt time.Time              // Time the refresh succeeded
+}
+
+// CachedOption configures a CachedRepository created with NewCachedRepository.
+type CachedOption func(*CachedRepository)
+
+// WithMaxStaleness withholds the data once the last successful refresh is
+// older than maxStaleness.
+func WithMaxStaleness(maxStaleness time.Duration) CachedOption {
+	return func(r *CachedRepository) {
+		r.MaxStaleness = maxStaleness
+	}
+}
+
+// WithCacheClock measures the age of the snapshot with now, for example to
+// control time in tests.
+func WithCacheClock(now func() time.Time) CachedOption {
+	return func(r *CachedRepository) {
+		r.Now = now
+	}
+}
+
+// NewCachedRepository creates a CachedRepository serving the data of repository.
+func NewCachedRepository(repository Repository, opts ...CachedOption) *CachedRepository {
+	cached := &CachedRepository{Repository: repository}
+	for _, opt := range opts {
+		opt(cached)
+	}
+	return cached
+}
+
+// GetName returns the name of the wrapped repository.
+func (r *CachedRepository) GetName() string {
+	return r.Repository.GetName()
+}
+
+// GetData returns the configuration with the given name from the snapshot,
+// or reports it missing if the snapshot is too old.
+func (r *CachedRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	snapshot, ok := r.fresh()
+	if !ok {
+		return nil, false
+	}
+	if snapshot.data == nil {
+		return r.Repository.GetData(configName)
+	}
+	config, isPresent = snapshot.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns the raw data of the snapshot, or nil if it is too old.
+func (r *CachedRepository) GetRawData() []byte {
+	snapshot, ok := r.fresh()
+	if !ok {
+		return nil
+	}
+	return snapshot.rawData
+}
+
+// Refresh refreshes the wrapped repository and, if it succeeds, swaps in a
+// snapshot of its data. A failed refresh leaves the current snapshot in place
+// and does not make it any fresher.
+func (r *CachedRepository) Refresh() error {
+	err := r.Repository.Refresh()
+	if err != nil {
+		return err
+	}
+	snapshot := &cachedSnapshot{
+		rawData:     r.Repository.GetRawData(),
+		refreshedAt: r.now(),
+	}
+	if versioned, ok := r.Repository.(Versioned); ok {
+		snapshot.version = versioned.Version()
+	}
+	if lister, ok := r.Repository.(KeyLister); ok {
+		resolver, resolves := r.Repository.(SourceResolver)
+		snapshot.data = map[string]interface{}{}
+		snapshot.sources = map[string]string{}
+		for _, key := range lister.Keys() {
+			value, ok := r.Repository.GetData(key)
+			if !ok {
+				continue
+			}
+			snapshot.data[key] = value
+			if resolves {
+				if name, ok := resolver.SourceOf(key); ok {
+					snapshot.sources[key] = name
+				}
+			}
+		}
+		snapshot.keys = sortedKeys(snapshot.data)
+	}
+	r.snapshot.Store(snapshot)
+	return nil
+}
+
+// Load loads the wrapped repository if it is a Loader, without replacing the
+// snapshot or making it any fresher.
+func (r *CachedRepository) Load() (map[string]interface{}, error) {
+	loader, ok := r.Repository.(Loader)
+	if !ok {
+		return nil, ErrUnloadableRepository
+	}
+	return loader.Load()
+}
+
+// Version returns the version of the wrapped repository at the snapshot.
+func (r *CachedRepository) Version() string {
+	snapshot := r.snapshot.Load()
+	if snapshot == nil {
+		return ""
+	}
+	return snapshot.version
+}
+
+// Keys returns the names of the configurations in the snapshot, or none if
+// it is too old.
+func (r *CachedRepository) Keys() []string {
+	snapshot, ok := r.fresh()
+	if !ok || snapshot.data == nil {
+		return nil
+	}
+	return snapshot.keys
+}
+
+// SourceOf returns the repository the configuration with the given name came
+// from at the snapshot, if the wrapped repository is a SourceResolver.
+func (r *CachedRepository) SourceOf(configName string) (string, bool) {
+	snapshot := r.snapshot.Load()
+	if snapshot == nil {
+		return "", false
+	}
+	name, ok := snapshot.sources[configName]
+	return name, ok
+}
+
+// ReadsThrough reports whether the wrapped repository cannot be listed, so
+// that reads go through to it.
+func (r *CachedRepository) ReadsThrough() bool {
+	if _, ok := r.Repository.(KeyLister); ok {
+		return false
+	}
+	readThrough, ok := r.Repository.(ReadThrough)
+	return ok && readThrough.ReadsThrough()
+}
+
+// Close closes the wrapped repository if it can be closed.
+func (r *CachedRepository) Close() error {
+	if closer, ok := r.Repository.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// fresh returns the snapshot and whether it may be served, that is whether
+// there is one and it is not older than MaxStaleness.
+func (r *CachedRepository) fresh() (*cachedSnapshot, bool) {
+	snapshot := r.snapshot.Load()
+	if snapshot == nil {
+		return nil, false
+	}
+	if r.MaxStaleness > 0 && r.now().Sub(snapshot.refreshedAt) > r.MaxStaleness {
+		return nil, false
+	}
+	return snapshot, true
+}
+
+// now returns the current time of the clock.
+func (r *CachedRepository) now() time.Time {
+	if r.Now == nil {
+		return time.Now()
+	}
+	return r.Now()
+}
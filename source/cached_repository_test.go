@@ -0,0 +1,134 @@
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCachedRepository(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "name: John\n")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repository := NewCachedRepository(&FileRepository{Name: "file", Path: path}, WithMaxStaleness(time.Minute), WithCacheClock(func() time.Time {
+		return now
+	}))
+	if _, ok := repository.GetData("name"); ok {
+		t.Errorf("Expected no data before the first refresh")
+	}
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" || repository.GetName() != "file" {
+		t.Errorf("Expected name to be John from file, got %v from %s", name, repository.GetName())
+	}
+
+	// The snapshot is served up to the staleness threshold.
+	now = now.Add(time.Minute)
+	name, _ = repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected the snapshot at the threshold, got %v", name)
+	}
+
+	// A failed refresh keeps the snapshot but does not make it fresher.
+	err = os.Remove(path)
+	if err != nil {
+		t.Fatalf("Error removing file: %s", err.Error())
+	}
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a missing file, got nil")
+	}
+	now = now.Add(time.Nanosecond)
+	if name, ok := repository.GetData("name"); ok {
+		t.Errorf("Expected no data past the threshold, got %v", name)
+	}
+	if keys := repository.Keys(); len(keys) != 0 {
+		t.Errorf("Expected no keys past the threshold, got %v", keys)
+	}
+
+	// A successful refresh makes the data fresh again.
+	writeFile(t, path, "name: Jane\n")
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected name to be Jane, got %v", name)
+	}
+	if keys := repository.Keys(); len(keys) != 1 || keys[0] != "name" {
+		t.Errorf("Expected keys [name], got %v", keys)
+	}
+
+	// Load reads the wrapped repository without replacing the snapshot.
+	writeFile(t, path, "name: Jill\n")
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	if loaded["name"] != "Jill" {
+		t.Errorf("Expected loaded name to be Jill, got %v", loaded["name"])
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected name to stay Jane, got %v", name)
+	}
+}
+
+// blockingRepository is a FileRepository whose refreshes wait for release
+// while holding its lock.
+type blockingRepository struct {
+	FileRepository
+	refreshing chan struct{}
+	release    chan struct{}
+}
+
+func (b *blockingRepository) Refresh() error {
+	err := b.FileRepository.Refresh()
+	if b.release == nil {
+		return err
+	}
+	b.Lock()
+	defer b.Unlock()
+	close(b.refreshing)
+	<-b.release
+	return err
+}
+
+func TestCachedRepositoryDuringRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "name: John\n")
+	wrapped := &blockingRepository{FileRepository: FileRepository{Name: "file", Path: path}}
+	repository := NewCachedRepository(wrapped)
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// Reads are served from the snapshot while a refresh holds the lock of
+	// the wrapped repository.
+	wrapped.refreshing = make(chan struct{})
+	wrapped.release = make(chan struct{})
+	writeFile(t, path, "name: Jane\n")
+	refreshed := make(chan error)
+	go func() {
+		refreshed <- repository.Refresh()
+	}()
+	<-wrapped.refreshing
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected the previous snapshot during the refresh, got %v", name)
+	}
+	close(wrapped.release)
+	err = <-refreshed
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected the new snapshot after the refresh, got %v", name)
+	}
+}
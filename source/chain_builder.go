@@ -0,0 +1,115 @@
+package source
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"io/fs"
+)
+
+// ChainBuilder assembles a ChainRepository from a primary repository, any
+// number of fallbacks and an optional local override file.
+type ChainBuilder struct {
+	name          string
+	primary       Repository
+	fallbacks     []Repository
+	localOverride Repository
+	tolerate      bool
+	opts          []ChainOption
+}
+
+// NewChain returns an empty ChainBuilder.
+func NewChain() *ChainBuilder {
+	return &ChainBuilder{name: "chain", tolerate: true}
+}
+
+// Named sets the name of the built ChainRepository.
+func (b *ChainBuilder) Named(name string) *ChainBuilder {
+	b.name = name
+	return b
+}
+
+// Primary sets the main repository of the chain.
+func (b *ChainBuilder) Primary(repository Repository) *ChainBuilder {
+	b.primary = repository
+	return b
+}
+
+// Fallback adds a repository consulted when the primary does not have a key.
+// Fallbacks are consulted in the order they were added.
+func (b *ChainBuilder) Fallback(repository Repository) *ChainBuilder {
+	b.fallbacks = append(b.fallbacks, repository)
+	return b
+}
+
+// LocalOverride sets a local YAML file whose keys take precedence over every
+// other repository in the chain. The file is optional: while it does not exist
+// it is skipped, rather than counted as a failing repository.
+func (b *ChainBuilder) LocalOverride(path string) *ChainBuilder {
+	b.localOverride = &optionalFileRepository{FileRepository: &FileRepository{Name: "local-override", Path: path}}
+	return b
+}
+
+// TolerateFailures sets whether the built chain tolerates failing
+// repositories as long as one of them refreshes successfully. It defaults to true.
+func (b *ChainBuilder) TolerateFailures(tolerate bool) *ChainBuilder {
+	b.tolerate = tolerate
+	return b
+}
+
+// WithOptions adds options applied to the built ChainRepository.
+func (b *ChainBuilder) WithOptions(opts ...ChainOption) *ChainBuilder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Build creates the ChainRepository. The local override wins over the primary,
+// which wins over the fallbacks. Unless TolerateFailures(false) was called, the
+// chain tolerates failing repositories as long as one of them refreshes
+// successfully, so a down primary does not fail the whole refresh.
+func (b *ChainBuilder) Build() *ChainRepository {
+	var repositories []Repository
+	if b.localOverride != nil {
+		repositories = append(repositories, b.localOverride)
+	}
+	if b.primary != nil {
+		repositories = append(repositories, b.primary)
+	}
+	repositories = append(repositories, b.fallbacks...)
+	var opts []ChainOption
+	if b.tolerate {
+		opts = append(opts, WithTolerateFailures())
+	}
+	opts = append(opts, b.opts...)
+	return NewChainRepository(b.name, repositories, opts...)
+}
+
+// optionalFileRepository is a FileRepository that holds no data while its
+// file does not exist.
+type optionalFileRepository struct {
+	*FileRepository
+}
+
+// Refresh refreshes the file, clearing the data and returning
+// errRepositorySkipped if the file does not exist.
+func (o *optionalFileRepository) Refresh() error {
+	err := o.FileRepository.Refresh()
+	if errors.Is(err, fs.ErrNotExist) {
+		logrus.Debug("optional file does not exist, skipping")
+		o.Lock()
+		o.data = nil
+		o.rawData = nil
+		o.version = ""
+		o.Unlock()
+		return errRepositorySkipped
+	}
+	return err
+}
+
+// Load loads the file, returning errRepositorySkipped if it does not exist.
+func (o *optionalFileRepository) Load() (map[string]interface{}, error) {
+	data, err := o.FileRepository.Load()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errRepositorySkipped
+	}
+	return data, err
+}
@@ -0,0 +1,119 @@
+package source
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChainBuilder(t *testing.T) {
+	dir := t.TempDir()
+	primaryPath := filepath.Join(dir, "primary.yaml")
+	fallbackPath := filepath.Join(dir, "fallback.yaml")
+	overridePath := filepath.Join(dir, "override.yaml")
+	writeFile(t, primaryPath, "name: primary\nregion: us\n")
+	writeFile(t, fallbackPath, "name: fallback\nregion: eu\ntimeout: 5\n")
+	writeFile(t, overridePath, "name: override\n")
+
+	chain := NewChain().
+		Named("config").
+		Primary(&FileRepository{Name: "primary", Path: primaryPath}).
+		Fallback(&FileRepository{Name: "fallback", Path: fallbackPath}).
+		LocalOverride(overridePath).
+		Build()
+	if chain.GetName() != "config" {
+		t.Errorf("Expected name to be config, got %s", chain.GetName())
+	}
+	err := chain.Refresh()
+	if err != nil {
+		t.Errorf("Error refreshing chain: %s", err.Error())
+	}
+	expected := map[string]interface{}{"name": "override", "region": "us", "timeout": 5}
+	for key, want := range expected {
+		got, ok := chain.GetData(key)
+		if !ok || got != want {
+			t.Errorf("Expected %s to be %v, got %v", key, want, got)
+		}
+	}
+}
+
+func TestChainBuilderFailingPrimary(t *testing.T) {
+	dir := t.TempDir()
+	fallbackPath := filepath.Join(dir, "fallback.yaml")
+	writeFile(t, fallbackPath, "name: fallback\n")
+	unreachable, err := url.Parse("http://127.0.0.1:1/config.yaml")
+	if err != nil {
+		t.Fatalf("Error parsing url: %s", err.Error())
+	}
+
+	chain := NewChain().
+		Primary(&WebRepository{Name: "primary", URL: unreachable}).
+		Fallback(&FileRepository{Name: "fallback", Path: fallbackPath}).
+		LocalOverride(filepath.Join(dir, "missing.yaml")).
+		Build()
+	err = chain.Refresh()
+	if err != nil {
+		t.Errorf("Expected failing primary to be tolerated, got %s", err.Error())
+	}
+	name, ok := chain.GetData("name")
+	if !ok || name != "fallback" {
+		t.Errorf("Expected name to be fallback, got %v", name)
+	}
+
+	chain = NewChain().Primary(&WebRepository{Name: "primary", URL: unreachable}).Build()
+	if chain.Refresh() == nil {
+		t.Errorf("Expected error when every repository fails, got nil")
+	}
+}
+
+func TestChainBuilderIntolerant(t *testing.T) {
+	dir := t.TempDir()
+	fallbackPath := filepath.Join(dir, "fallback.yaml")
+	overridePath := filepath.Join(dir, "override.yaml")
+	writeFile(t, fallbackPath, "name: fallback\n")
+
+	chain := NewChain().
+		Primary(&FileRepository{Name: "primary", Path: filepath.Join(dir, "missing.yaml")}).
+		Fallback(&FileRepository{Name: "fallback", Path: fallbackPath}).
+		LocalOverride(overridePath).
+		TolerateFailures(false).
+		Build()
+	if chain.Refresh() == nil {
+		t.Errorf("Expected failing primary not to be tolerated, got nil")
+	}
+
+	// A missing override is skipped rather than failing the chain.
+	chain = NewChain().
+		Fallback(&FileRepository{Name: "fallback", Path: fallbackPath}).
+		LocalOverride(overridePath).
+		TolerateFailures(false).
+		Build()
+	err := chain.Refresh()
+	if err != nil {
+		t.Errorf("Expected missing override to be skipped, got %s", err.Error())
+	}
+
+	// The override applies once it appears and is dropped when it is removed.
+	writeFile(t, overridePath, "name: override\n")
+	err = chain.Refresh()
+	if err != nil {
+		t.Errorf("Error refreshing chain: %s", err.Error())
+	}
+	name, _ := chain.GetData("name")
+	if name != "override" {
+		t.Errorf("Expected name to be override, got %v", name)
+	}
+	err = os.Remove(overridePath)
+	if err != nil {
+		t.Fatalf("Error removing override: %s", err.Error())
+	}
+	err = chain.Refresh()
+	if err != nil {
+		t.Errorf("Expected removed override to be skipped, got %s", err.Error())
+	}
+	name, _ = chain.GetData("name")
+	if name != "fallback" {
+		t.Errorf("Expected name to be fallback, got %v", name)
+	}
+}
@@ -0,0 +1,348 @@
+package source
+
+import (
+	"errors"
+	"github.com/sirupsen/logrus"
+	"sync"
+)
+
+// DefaultRefreshConcurrencyLimit is the maximum number of sub-repositories a
+// ChainRepository refreshes at the same time when no limit has been configured.
+const DefaultRefreshConcurrencyLimit = 4
+
+// errRepositorySkipped is returned by the Refresh of an optional repository
+// that has nothing to load. The chain counts it as neither a success nor a failure.
+var errRepositorySkipped = errors.New("repository skipped")
+
+// ChainRepository is a struct that implements the Repository interface by
+// layering several repositories on top of each other. Repositories earlier in
+// the chain take precedence over later ones when looking up a configuration entry.
+type ChainRepository struct {
+	sync.RWMutex                                // RWMutex to synchronize access to the chain during refresh
+	Name                    string              // Name of the configuration source
+	Repositories            []Repository        // Ordered list of repositories, highest precedence first
+	RefreshConcurrencyLimit int                 // Maximum number of sub-repositories refreshed in parallel
+	TolerateFailures        bool                // Only fail a refresh when every sub-repository fails
+	OptionalFallbacks       bool                // Only fail a refresh when the first sub-repository fails
+	ForbiddenKeys           map[string][]string // Configurations ignored from the repositories of each name
+	notify                  func()              // Function registered with NotifyChanges, for the repositories added later
+}
+
+// ChainOption configures a ChainRepository created with NewChainRepository.
+type ChainOption func(*ChainRepository)
+
+// WithRefreshConcurrencyLimit bounds the number of sub-repositories that are
+// refreshed in parallel. A limit of 1 refreshes the chain serially.
+func WithRefreshConcurrencyLimit(limit int) ChainOption {
+	return func(c *ChainRepository) {
+		c.RefreshConcurrencyLimit = limit
+	}
+}
+
+// WithForbiddenKeys ignores the given configurations of the repositories
+// named source, so that a less trusted repository, such as one reading
+// environment variables, cannot set security-critical configurations. The
+// configurations are then looked up in the rest of the chain.
+func WithForbiddenKeys(source string, keys ...string) ChainOption {
+	return func(c *ChainRepository) {
+		if c.ForbiddenKeys == nil {
+			c.ForbiddenKeys = map[string][]string{}
+		}
+		c.ForbiddenKeys[source] = append(c.ForbiddenKeys[source], keys...)
+	}
+}
+
+// NewChainRepository creates a ChainRepository from an ordered list of repositories.
+func NewChainRepository(name string, repositories []Repository, opts ...ChainOption) *ChainRepository {
+	chain := &ChainRepository{
+		Name:         name,
+		Repositories: repositories,
+	}
+	for _, opt := range opts {
+		opt(chain)
+	}
+	return chain
+}
+
+// GetName returns the name of the configuration source.
+func (c *ChainRepository) GetName() string {
+	return c.Name
+}
+
+// GetData returns the configuration data from the first repository in the
+// chain that has it and is not forbidden from setting it.
+func (c *ChainRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	c.RLock()
+	defer c.RUnlock()
+	for _, repo := range c.Repositories {
+		if c.forbidden(repo, configName) {
+			continue
+		}
+		config, isPresent = repo.GetData(configName)
+		if isPresent {
+			return config, isPresent
+		}
+	}
+	return nil, false
+}
+
+// SourceOf returns the name of the repository that GetData takes the
+// configuration with the given name from. For a repository that combines
+// others itself, it is the name of the innermost one.
+func (c *ChainRepository) SourceOf(configName string) (string, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	for _, repo := range c.Repositories {
+		if c.forbidden(repo, configName) {
+			continue
+		}
+		if _, ok := repo.GetData(configName); !ok {
+			continue
+		}
+		if resolver, ok := repo.(SourceResolver); ok {
+			if name, ok := resolver.SourceOf(configName); ok {
+				return name, true
+			}
+		}
+		return repo.GetName(), true
+	}
+	return "", false
+}
+
+// GetRawData returns the raw data of the first repository in the chain that has any.
+func (c *ChainRepository) GetRawData() []byte {
+	c.RLock()
+	defer c.RUnlock()
+	for _, repo := range c.Repositories {
+		if rawData := repo.GetRawData(); rawData != nil {
+			return rawData
+		}
+	}
+	return nil
+}
+
+// Keys returns the names of the configurations held by any repository in the
+// chain that implements KeyLister, leaving out those it is forbidden from setting.
+func (c *ChainRepository) Keys() []string {
+	c.RLock()
+	defer c.RUnlock()
+	union := map[string]interface{}{}
+	for _, repo := range c.Repositories {
+		lister, ok := repo.(KeyLister)
+		if !ok {
+			continue
+		}
+		for _, key := range lister.Keys() {
+			if !c.forbidden(repo, key) {
+				union[key] = nil
+			}
+		}
+	}
+	return sortedKeys(union)
+}
+
+// ReadsThrough reports whether any repository in the chain reads through, so
+// that wrapping a repository in a chain keeps its GetData calls bounded.
+func (c *ChainRepository) ReadsThrough() bool {
+	c.RLock()
+	defer c.RUnlock()
+	for _, repo := range c.Repositories {
+		readThrough, ok := repo.(ReadThrough)
+		if ok && readThrough.ReadsThrough() {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifyChanges registers fn with every repository in the chain that is a
+// Notifier, including the ones added later with AddSource.
+func (c *ChainRepository) NotifyChanges(fn func()) {
+	c.Lock()
+	defer c.Unlock()
+	c.notify = fn
+	for _, repo := range c.Repositories {
+		if notifier, ok := repo.(Notifier); ok {
+			notifier.NotifyChanges(fn)
+		}
+	}
+}
+
+// forbidden reports whether repo is forbidden from setting the configuration
+// with the given name.
+func (c *ChainRepository) forbidden(repo Repository, configName string) bool {
+	keys, ok := c.ForbiddenKeys[repo.GetName()]
+	if !ok {
+		return false
+	}
+	for _, key := range keys {
+		if key == configName {
+			return true
+		}
+	}
+	return false
+}
+
+// logForbidden logs the configurations the repositories hold but are
+// forbidden from setting, once per refresh rather than on every lookup.
+func (c *ChainRepository) logForbidden() {
+	for _, repo := range c.Repositories {
+		for _, key := range c.ForbiddenKeys[repo.GetName()] {
+			if _, ok := repo.GetData(key); ok {
+				logrus.WithField("key", key).WithField("repository", repo.GetName()).Debug("ignoring forbidden key")
+			}
+		}
+	}
+}
+
+// AddSource refreshes repository and adds it to the chain with the highest
+// precedence, so its configurations override those of the existing
+// repositories. The repository is not added if its refresh fails. A Client
+// reading the chain sees the new configurations after its next refresh, or
+// right away after ForceRefresh.
+func (c *ChainRepository) AddSource(repository Repository) error {
+	// Refresh outside the lock so that lookups are not blocked meanwhile.
+	err := repository.Refresh()
+	if err != nil && !errors.Is(err, errRepositorySkipped) {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.Repositories = append([]Repository{repository}, c.Repositories...)
+	if notifier, ok := repository.(Notifier); ok && c.notify != nil {
+		notifier.NotifyChanges(c.notify)
+	}
+	return nil
+}
+
+// RemoveSource removes repository from the chain and reports whether it was
+// part of it. Like AddSource, the change reaches a Client on its next refresh.
+func (c *ChainRepository) RemoveSource(repository Repository) bool {
+	c.Lock()
+	defer c.Unlock()
+	for i, repo := range c.Repositories {
+		if repo == repository {
+			c.Repositories = append(c.Repositories[:i:i], c.Repositories[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// WithTolerateFailures makes a refresh succeed as long as at least one
+// sub-repository refreshed successfully.
+func WithTolerateFailures() ChainOption {
+	return func(c *ChainRepository) {
+		c.TolerateFailures = true
+	}
+}
+
+// WithOptionalFallbacks makes the failed refreshes of every sub-repository
+// but the first non-fatal, so that only the primary repository is required.
+// The failures of the fallbacks are logged and their last good data is kept.
+func WithOptionalFallbacks() ChainOption {
+	return func(c *ChainRepository) {
+		c.OptionalFallbacks = true
+	}
+}
+
+// Refresh refreshes every repository in the chain, running at most
+// RefreshConcurrencyLimit refreshes at a time. It returns the error of the
+// highest precedence repository that failed to refresh, unless TolerateFailures
+// is set and at least one repository refreshed successfully. With
+// OptionalFallbacks set, only the error of the first repository is returned.
+func (c *ChainRepository) Refresh() error {
+	c.RLock()
+	defer c.RUnlock()
+
+	limit := c.RefreshConcurrencyLimit
+	if limit <= 0 {
+		limit = DefaultRefreshConcurrencyLimit
+	}
+
+	// Use a buffered channel as a semaphore to bound the number of workers.
+	semaphore := make(chan struct{}, limit)
+	errs := make([]error, len(c.Repositories))
+	var wg sync.WaitGroup
+	for i, repo := range c.Repositories {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, repo Repository) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			errs[i] = repo.Refresh()
+		}(i, repo)
+	}
+	wg.Wait()
+	c.logForbidden()
+	return c.result(errs, "refreshing")
+}
+
+// Load loads every repository in the chain the way Refresh refreshes them and
+// returns their configurations layered by precedence, leaving out those a
+// repository is forbidden from setting, without replacing the data of any
+// repository. The errors of the repositories are handled as by Refresh, and
+// a repository that does not implement Loader fails with
+// ErrUnloadableRepository.
+func (c *ChainRepository) Load() (map[string]interface{}, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	loaded := make([]map[string]interface{}, len(c.Repositories))
+	errs := make([]error, len(c.Repositories))
+	for i, repo := range c.Repositories {
+		loader, ok := repo.(Loader)
+		if !ok {
+			errs[i] = ErrUnloadableRepository
+			continue
+		}
+		loaded[i], errs[i] = loader.Load()
+	}
+	err := c.result(errs, "loading")
+	if err != nil {
+		return nil, err
+	}
+
+	// Layer the repositories from the lowest precedence up, so that earlier
+	// ones override later ones as in GetData.
+	data := map[string]interface{}{}
+	for i := len(c.Repositories) - 1; i >= 0; i-- {
+		for key, value := range loaded[i] {
+			if !c.forbidden(c.Repositories[i], key) {
+				data[key] = value
+			}
+		}
+	}
+	return data, nil
+}
+
+// result returns the error of a refresh or load of the chain given the errors
+// of its repositories, in order, as described by Refresh. action names the
+// operation in the logs of the failures it tolerates.
+func (c *ChainRepository) result(errs []error, action string) error {
+	var firstErr error
+	succeeded := false
+	for i, err := range errs {
+		if err == nil {
+			succeeded = true
+			continue
+		}
+		if errors.Is(err, errRepositorySkipped) {
+			continue
+		}
+		if c.OptionalFallbacks && i > 0 {
+			logrus.WithField("error", RedactURLs(err.Error(), false)).WithField("repository", c.Repositories[i].GetName()).Warn("error " + action + " fallback repository in chain")
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if c.TolerateFailures {
+			logrus.WithField("error", RedactURLs(err.Error(), false)).WithField("repository", c.Repositories[i].GetName()).Warn("error " + action + " repository in chain")
+		}
+	}
+	if c.TolerateFailures && succeeded {
+		return nil
+	}
+	return firstErr
+}
@@ -0,0 +1,365 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyRepository is a Repository that records how many refreshes are in flight at once.
+type concurrencyRepository struct {
+	inFlight    *int32
+	maxInFlight *int32
+	mu          *sync.Mutex
+}
+
+func (r *concurrencyRepository) GetName() string {
+	return "concurrency"
+}
+
+func (r *concurrencyRepository) GetData(_ string) (interface{}, bool) {
+	return nil, false
+}
+
+func (r *concurrencyRepository) GetRawData() []byte {
+	return nil
+}
+
+func (r *concurrencyRepository) Refresh() error {
+	current := atomic.AddInt32(r.inFlight, 1)
+	r.mu.Lock()
+	if current > *r.maxInFlight {
+		*r.maxInFlight = current
+	}
+	r.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	atomic.AddInt32(r.inFlight, -1)
+	return nil
+}
+
+func TestChainRepositoryRefreshConcurrencyLimit(t *testing.T) {
+	testCases := []struct {
+		name  string
+		limit int
+		want  int32
+	}{
+		{name: "serial", limit: 1, want: 1},
+		{name: "bounded", limit: 3, want: 3},
+		{name: "default", limit: 0, want: DefaultRefreshConcurrencyLimit},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var inFlight, maxInFlight int32
+			mu := &sync.Mutex{}
+			var repositories []Repository
+			for i := 0; i < 10; i++ {
+				repositories = append(repositories, &concurrencyRepository{inFlight: &inFlight, maxInFlight: &maxInFlight, mu: mu})
+			}
+			chain := NewChainRepository("chain", repositories, WithRefreshConcurrencyLimit(tc.limit))
+			err := chain.Refresh()
+			if err != nil {
+				t.Errorf("Error refreshing chain: %s", err.Error())
+			}
+			if maxInFlight > tc.want {
+				t.Errorf("Expected at most %d concurrent refreshes, got %d", tc.want, maxInFlight)
+			}
+			if maxInFlight < 1 {
+				t.Errorf("Expected at least 1 refresh, got %d", maxInFlight)
+			}
+		})
+	}
+}
+
+func TestChainRepositoryGetData(t *testing.T) {
+	primary := &FileRepository{Name: "primary", Path: "../test.yaml"}
+	chain := NewChainRepository("chain", []Repository{primary})
+	err := chain.Refresh()
+	if err != nil {
+		t.Errorf("Error refreshing chain: %s", err.Error())
+	}
+	name, ok := chain.GetData("name")
+	if !ok || name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+	_, ok = chain.GetData("missing")
+	if ok {
+		t.Errorf("Expected missing to be absent")
+	}
+}
+
+func TestChainRepositoryKeys(t *testing.T) {
+	dir := t.TempDir()
+	primaryPath := filepath.Join(dir, "primary.yaml")
+	fallbackPath := filepath.Join(dir, "fallback.yaml")
+	writeFile(t, primaryPath, "name: primary\nregion: us\n")
+	writeFile(t, fallbackPath, "name: fallback\ntimeout: 5\n")
+	chain := NewChainRepository("chain", []Repository{
+		&FileRepository{Name: "primary", Path: primaryPath},
+		&FileRepository{Name: "fallback", Path: fallbackPath},
+		&concurrencyRepository{inFlight: new(int32), maxInFlight: new(int32), mu: &sync.Mutex{}},
+	})
+	err := chain.Refresh()
+	if err != nil {
+		t.Errorf("Error refreshing chain: %s", err.Error())
+	}
+	expected := []string{"name", "region", "timeout"}
+	if keys := chain.Keys(); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected keys to be %v, got %v", expected, keys)
+	}
+}
+
+func TestChainRepositoryAddRemoveSource(t *testing.T) {
+	dir := t.TempDir()
+	basePath := filepath.Join(dir, "base.yaml")
+	tenantPath := filepath.Join(dir, "tenant.yaml")
+	writeFile(t, basePath, "name: base\ntimeout: 5\n")
+	writeFile(t, tenantPath, "name: tenant\nquota: 10\n")
+	chain := NewChainRepository("chain", []Repository{&FileRepository{Name: "base", Path: basePath}})
+	err := chain.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing chain: %s", err.Error())
+	}
+
+	// Read the chain while it is changed.
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				chain.GetData("name")
+				_ = chain.Refresh()
+			}
+		}
+	}()
+
+	tenant := &FileRepository{Name: "tenant", Path: tenantPath}
+	err = chain.AddSource(tenant)
+	if err != nil {
+		t.Errorf("Error adding source: %s", err.Error())
+	}
+	quota, ok := chain.GetData("quota")
+	if !ok || quota != 10 {
+		t.Errorf("Expected the added source to be visible, got %v", quota)
+	}
+	name, _ := chain.GetData("name")
+	if name != "tenant" {
+		t.Errorf("Expected the added source to take precedence, got %v", name)
+	}
+
+	if !chain.RemoveSource(tenant) {
+		t.Errorf("Expected the source to be removed")
+	}
+	if chain.RemoveSource(tenant) {
+		t.Errorf("Expected a second removal to report false")
+	}
+	close(done)
+	wg.Wait()
+	if _, ok := chain.GetData("quota"); ok {
+		t.Errorf("Expected the removed source to be gone")
+	}
+
+	// A source that fails to refresh is not added.
+	err = chain.AddSource(&FileRepository{Name: "missing", Path: filepath.Join(dir, "missing.yaml")})
+	if err == nil {
+		t.Errorf("Expected error adding a failing source, got nil")
+	}
+	if len(chain.Repositories) != 1 {
+		t.Errorf("Expected the failing source not to be added, got %d repositories", len(chain.Repositories))
+	}
+}
+
+func TestChainRepositoryForbiddenKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "admin: false\nname: file\n")
+	t.Setenv("APP_ADMIN", "true")
+	t.Setenv("APP_NAME", "env")
+	t.Setenv("APP_TOKEN", "injected")
+	chain := NewChainRepository("chain", []Repository{
+		&EnvRepository{Name: "env", Prefix: "APP_"},
+		&FileRepository{Name: "file", Path: path},
+	}, WithForbiddenKeys("env", "admin"), WithForbiddenKeys("env", "token"))
+	var logs bytes.Buffer
+	logrus.SetOutput(&logs)
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.DebugLevel)
+	defer func() {
+		logrus.SetOutput(os.Stderr)
+		logrus.SetLevel(level)
+	}()
+	err := chain.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing chain: %s", err.Error())
+	}
+
+	// Forbidden keys are taken from the rest of the chain.
+	admin, _ := chain.GetData("admin")
+	if admin != false {
+		t.Errorf("Expected admin to be false from the file, got %v", admin)
+	}
+	if _, ok := chain.GetData("token"); ok {
+		t.Errorf("Expected the forbidden token to be absent")
+	}
+
+	// Allowed keys still apply.
+	name, _ := chain.GetData("name")
+	if name != "env" {
+		t.Errorf("Expected name to be env, got %v", name)
+	}
+	expected := []string{"admin", "name"}
+	if keys := chain.Keys(); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected keys to be %v, got %v", expected, keys)
+	}
+
+	// The forbidden keys are logged once per refresh, not on every lookup.
+	if count := strings.Count(logs.String(), "ignoring forbidden key"); count != 2 {
+		t.Errorf("Expected the 2 forbidden keys to be logged once, got %d logs", count)
+	}
+}
+
+func TestChainRepositorySourceOf(t *testing.T) {
+	dir := t.TempDir()
+	innerPath := filepath.Join(dir, "inner.yaml")
+	basePath := filepath.Join(dir, "base.yaml")
+	writeFile(t, innerPath, "name: inner\n")
+	writeFile(t, basePath, "name: base\nadmin: true\nport: 1\n")
+	t.Setenv("APP_ADMIN", "false")
+	inner := NewChainRepository("inner", []Repository{&FileRepository{Name: "inner-file", Path: innerPath}})
+	chain := NewChainRepository("chain", []Repository{
+		&EnvRepository{Name: "env", Prefix: "APP_"},
+		inner,
+		&FileRepository{Name: "base", Path: basePath},
+	}, WithForbiddenKeys("env", "admin"))
+	err := chain.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing chain: %s", err.Error())
+	}
+	expected := map[string]string{"name": "inner-file", "admin": "base", "port": "base"}
+	for key, want := range expected {
+		if got, ok := chain.SourceOf(key); !ok || got != want {
+			t.Errorf("Expected %s to come from %s, got %s", key, want, got)
+		}
+	}
+	if _, ok := chain.SourceOf("missing"); ok {
+		t.Errorf("Expected no source for a missing key")
+	}
+}
+
+func TestChainRepositoryFallback(t *testing.T) {
+	dir := t.TempDir()
+	primaryPath := filepath.Join(dir, "primary.yaml")
+	fallbackPath := filepath.Join(dir, "fallback.yaml")
+	writeFile(t, primaryPath, "name: primary\n")
+	writeFile(t, fallbackPath, "name: fallback\ntimeout: 5\n")
+	primary := &FileRepository{Name: "primary", Path: primaryPath}
+	fallback := &FileRepository{Name: "fallback", Path: fallbackPath}
+
+	// Earlier repositories override later ones.
+	chain := NewChainRepository("chain", []Repository{primary, fallback})
+	err := chain.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing chain: %s", err.Error())
+	}
+	name, _ := chain.GetData("name")
+	if name != "primary" {
+		t.Errorf("Expected name to be primary, got %v", name)
+	}
+	timeout, _ := chain.GetData("timeout")
+	if timeout != 5 {
+		t.Errorf("Expected timeout to come from the fallback, got %v", timeout)
+	}
+
+	// The fallback serves the configurations when the primary cannot load.
+	missing := &FileRepository{Name: "primary", Path: filepath.Join(dir, "missing.yaml")}
+	chain = NewChainRepository("chain", []Repository{missing, fallback}, WithTolerateFailures())
+	err = chain.Refresh()
+	if err != nil {
+		t.Errorf("Expected the fallback to be enough, got %s", err.Error())
+	}
+	name, _ = chain.GetData("name")
+	if name != "fallback" {
+		t.Errorf("Expected name to be fallback, got %v", name)
+	}
+
+	// With optional fallbacks, only the primary is required.
+	broken := &FileRepository{Name: "fallback", Path: filepath.Join(dir, "missing.yaml")}
+	chain = NewChainRepository("chain", []Repository{primary, broken}, WithOptionalFallbacks())
+	err = chain.Refresh()
+	if err != nil {
+		t.Errorf("Expected a failing fallback to be non-fatal, got %s", err.Error())
+	}
+	chain = NewChainRepository("chain", []Repository{missing, fallback}, WithOptionalFallbacks())
+	if chain.Refresh() == nil {
+		t.Errorf("Expected a failing primary to fail the refresh, got nil")
+	}
+}
+
+func TestChainRepositoryLoad(t *testing.T) {
+	dir := t.TempDir()
+	primaryPath := filepath.Join(dir, "primary.yaml")
+	fallbackPath := filepath.Join(dir, "fallback.yaml")
+	writeFile(t, primaryPath, "name: primary\n")
+	writeFile(t, fallbackPath, "name: fallback\ntimeout: 5\n")
+	t.Setenv("APP_ADMIN", "true")
+	chain := NewChainRepository("chain", []Repository{
+		&EnvRepository{Name: "env", Prefix: "APP_"},
+		&FileRepository{Name: "primary", Path: primaryPath},
+		&FileRepository{Name: "fallback", Path: fallbackPath},
+	}, WithForbiddenKeys("env", "admin"))
+	err := chain.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing chain: %s", err.Error())
+	}
+
+	// The repositories are layered by precedence, without the forbidden keys,
+	// and the live data is left alone.
+	writeFile(t, primaryPath, "name: next\n")
+	writeFile(t, fallbackPath, "name: fallback\ntimeout: 10\nadmin: false\n")
+	loaded, err := chain.Load()
+	if err != nil {
+		t.Fatalf("Error loading chain: %s", err.Error())
+	}
+	expected := map[string]interface{}{"name": "next", "timeout": 10, "admin": false}
+	if !reflect.DeepEqual(loaded, expected) {
+		t.Errorf("Expected loaded data to be %v, got %v", expected, loaded)
+	}
+	name, _ := chain.GetData("name")
+	timeout, _ := chain.GetData("timeout")
+	if name != "primary" || timeout != 5 {
+		t.Errorf("Expected the live data to stay primary with timeout 5, got %v and %v", name, timeout)
+	}
+
+	// Failures are handled as by Refresh.
+	writeFile(t, primaryPath, "name: [unclosed\n")
+	if _, err := chain.Load(); err == nil {
+		t.Errorf("Expected error for a primary that does not parse, got nil")
+	}
+	chain.TolerateFailures = true
+	loaded, err = chain.Load()
+	if err != nil {
+		t.Fatalf("Expected the other repositories to be enough, got %s", err.Error())
+	}
+	if loaded["name"] != "fallback" {
+		t.Errorf("Expected name to be fallback, got %v", loaded["name"])
+	}
+
+	// A repository that cannot load fails the load.
+	var inFlight, maxInFlight int32
+	unloadable := &concurrencyRepository{inFlight: &inFlight, maxInFlight: &maxInFlight, mu: &sync.Mutex{}}
+	chain = NewChainRepository("chain", []Repository{unloadable})
+	if _, err := chain.Load(); !errors.Is(err, ErrUnloadableRepository) {
+		t.Errorf("Expected ErrUnloadableRepository, got %v", err)
+	}
+}
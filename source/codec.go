@@ -0,0 +1,306 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Codec is an interface that defines how a repository unmarshals its
+// configuration documents.
+type Codec interface {
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// MarshalCodec is a Codec that also marshals values into documents it
+// parses, as the codecs of this package do. A Client converts configuration
+// values with one, see client.WithCodec.
+type MarshalCodec interface {
+	Codec
+	Marshal(v interface{}) ([]byte, error)
+}
+
+// YAMLCodec parses YAML documents. Repositories use it when their Codec is nil.
+var YAMLCodec MarshalCodec = yamlCodec{}
+
+// JSON5Codec parses JSON5 documents, that is JSON with comments, trailing
+// commas, single-quoted strings and unquoted object keys. Numbers decode to
+// the same types as in YAML, so integers are read as int. It marshals plain JSON.
+var JSON5Codec MarshalCodec = json5Codec{}
+
+// JSONCodec parses JSON documents. Numbers decode to float64, as with
+// encoding/json, unless UseNumber is set with WithDecoderOptions.
+var JSONCodec MarshalCodec = jsonCodec{}
+
+// DecoderOptions tune how a codec decodes documents. Codecs ignore the
+// options they do not support.
+type DecoderOptions struct {
+	Strict    bool // Reject document fields that the struct decoded into does not have, in YAML and JSON
+	UseNumber bool // Decode JSON numbers exactly, integers to int rather than float64
+}
+
+// DecoderOption sets one of the DecoderOptions.
+type DecoderOption func(*DecoderOptions)
+
+// Strict rejects document fields that the struct decoded into does not have.
+func Strict() DecoderOption {
+	return func(o *DecoderOptions) {
+		o.Strict = true
+	}
+}
+
+// UseNumber decodes JSON numbers exactly, so that integers too large for a
+// float64 keep their precision and decode to int like they do in YAML.
+// Integers beyond the range of int64 decode to uint64 if they fit.
+func UseNumber() DecoderOption {
+	return func(o *DecoderOptions) {
+		o.UseNumber = true
+	}
+}
+
+// TunableCodec is a Codec whose decoding can be tuned with DecoderOptions.
+type TunableCodec interface {
+	Codec
+	// WithOptions returns a copy of the codec that decodes with options.
+	WithOptions(options DecoderOptions) Codec
+}
+
+// WithDecoderOptions returns codec tuned with opts, for use as the Codec of a
+// repository, as in WithDecoderOptions(JSONCodec, UseNumber()). A nil codec
+// is YAMLCodec, and a codec that is not a TunableCodec is returned as is. The
+// codecs of this package keep being MarshalCodecs when tuned.
+func WithDecoderOptions(codec Codec, opts ...DecoderOption) Codec {
+	tunable, ok := codecOrDefault(codec).(TunableCodec)
+	if !ok {
+		return codec
+	}
+	var options DecoderOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return tunable.WithOptions(options)
+}
+
+// codecOrDefault returns codec, or YAMLCodec if codec is nil.
+func codecOrDefault(codec Codec) Codec {
+	if codec == nil {
+		return YAMLCodec
+	}
+	return codec
+}
+
+type yamlCodec struct {
+	strict bool // reject unknown fields of structs
+}
+
+func (y yamlCodec) Unmarshal(data []byte, v interface{}) error {
+	if !y.strict {
+		return yaml.Unmarshal(data, v)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(v)
+	if errors.Is(err, io.EOF) {
+		// An empty document decodes to nothing, as with yaml.Unmarshal.
+		return nil
+	}
+	return err
+}
+
+func (yamlCodec) Marshal(v interface{}) ([]byte, error) {
+	return yaml.Marshal(v)
+}
+
+func (y yamlCodec) WithOptions(options DecoderOptions) Codec {
+	y.strict = options.Strict
+	return y
+}
+
+type jsonCodec struct {
+	options DecoderOptions
+}
+
+func (j jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if j.options.Strict {
+		decoder.DisallowUnknownFields()
+	}
+	if j.options.UseNumber {
+		decoder.UseNumber()
+	}
+	err := decoder.Decode(v)
+	if err != nil {
+		return err
+	}
+	if j.options.UseNumber {
+		switch target := v.(type) {
+		case *map[string]interface{}:
+			for key, value := range *target {
+				(*target)[key] = exactNumbers(value)
+			}
+		case *interface{}:
+			*target = exactNumbers(*target)
+		}
+	}
+	return nil
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (j jsonCodec) WithOptions(options DecoderOptions) Codec {
+	j.options = options
+	return j
+}
+
+// exactNumbers replaces the json.Number values in value with an int, or an
+// uint64 beyond the range of int64, for integers, and a float64 otherwise.
+func exactNumbers(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case json.Number:
+		if integer, err := strconv.ParseInt(typed.String(), 10, 0); err == nil {
+			return int(integer)
+		}
+		if integer, err := strconv.ParseUint(typed.String(), 10, 64); err == nil {
+			return integer
+		}
+		if float, err := typed.Float64(); err == nil {
+			return float
+		}
+		return typed.String()
+	case map[string]interface{}:
+		for key, item := range typed {
+			typed[key] = exactNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = exactNumbers(item)
+		}
+	}
+	return value
+}
+
+type json5Codec struct{}
+
+// Unmarshal converts the document to JSON, checks that it is valid JSON and
+// decodes it as YAML, of which JSON is a subset, so that numbers decode like
+// they do in YAML documents.
+func (json5Codec) Unmarshal(data []byte, v interface{}) error {
+	converted := json5ToJSON(data)
+	var raw json.RawMessage
+	if err := json.Unmarshal(converted, &raw); err != nil {
+		return err
+	}
+	return yaml.Unmarshal(converted, v)
+}
+
+func (json5Codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// json5ToJSON rewrites a JSON5 document into plain JSON by removing comments
+// and trailing commas, converting single-quoted strings to double-quoted ones
+// and quoting bare object keys. Malformed input is passed through so that
+// the decoder reports the error.
+func json5ToJSON(data []byte) []byte {
+	src := []rune(string(data))
+	var out strings.Builder
+	for i := 0; i < len(src); i++ {
+		r := src[i]
+		switch {
+		case r == '"' || r == '\'':
+			i = writeJSONString(&out, src, i)
+		case r == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			if i < len(src) {
+				out.WriteRune('\n')
+			}
+		case r == '/' && i+1 < len(src) && src[i+1] == '*':
+			i += 2
+			for i+1 < len(src) && !(src[i] == '*' && src[i+1] == '/') {
+				i++
+			}
+			i++
+		case r == ',':
+			// Drop the comma if only whitespace or comments separate it from a closing bracket.
+			if next := nextSignificant(src, i+1); next < len(src) && (src[next] == '}' || src[next] == ']') {
+				continue
+			}
+			out.WriteRune(r)
+		case unicode.IsLetter(r) || r == '_' || r == '$':
+			end := i
+			for end < len(src) && (unicode.IsLetter(src[end]) || unicode.IsDigit(src[end]) || src[end] == '_' || src[end] == '$') {
+				end++
+			}
+			word := string(src[i:end])
+			if next := nextSignificant(src, end); next < len(src) && src[next] == ':' {
+				out.WriteString(`"` + word + `"`)
+			} else {
+				out.WriteString(word)
+			}
+			i = end - 1
+		default:
+			out.WriteRune(r)
+		}
+	}
+	return []byte(out.String())
+}
+
+// writeJSONString writes the string literal starting at src[start] as a
+// double-quoted JSON string and returns the index of its closing quote.
+func writeJSONString(out *strings.Builder, src []rune, start int) int {
+	quote := src[start]
+	out.WriteRune('"')
+	i := start + 1
+	for ; i < len(src) && src[i] != quote; i++ {
+		switch {
+		case src[i] == '\\' && i+1 < len(src):
+			i++
+			if src[i] == '\'' {
+				out.WriteRune('\'')
+			} else {
+				out.WriteRune('\\')
+				out.WriteRune(src[i])
+			}
+		case src[i] == '"':
+			out.WriteString(`\"`)
+		default:
+			out.WriteRune(src[i])
+		}
+	}
+	out.WriteRune('"')
+	return i
+}
+
+// nextSignificant returns the index of the next rune at or after start that is
+// neither whitespace nor part of a comment.
+func nextSignificant(src []rune, start int) int {
+	i := start
+	for i < len(src) {
+		switch {
+		case unicode.IsSpace(src[i]):
+			i++
+		case src[i] == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case src[i] == '/' && i+1 < len(src) && src[i+1] == '*':
+			i += 2
+			for i+1 < len(src) && !(src[i] == '*' && src[i+1] == '/') {
+				i++
+			}
+			i += 2
+		default:
+			return i
+		}
+	}
+	return i
+}
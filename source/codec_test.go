@@ -0,0 +1,162 @@
+package source
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJSON5Codec(t *testing.T) {
+	document := `{
+  // line comment
+  name: 'John "Johnny" O\'Brien',
+  /* block comment, with a trailing comma inside */
+  "url": "https://example.com/a//b",
+  hobbies: ['Reading', 'Coding',],
+  address: {
+    city: 'New York', // trailing comment
+  },
+}`
+	var data map[string]interface{}
+	err := JSON5Codec.Unmarshal([]byte(document), &data)
+	if err != nil {
+		t.Fatalf("Error unmarshalling JSON5: %s", err.Error())
+	}
+	expected := map[string]interface{}{
+		"name":    `John "Johnny" O'Brien`,
+		"url":     "https://example.com/a//b",
+		"hobbies": []interface{}{"Reading", "Coding"},
+		"address": map[string]interface{}{"city": "New York"},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %v, got %v", expected, data)
+	}
+
+	// Numbers decode like YAML numbers, and JSON escapes and tabs are accepted.
+	data = nil
+	err = JSON5Codec.Unmarshal([]byte("{\n\tcount: 3,\n\tratio: 1.5,\n\tcity: \"Z\\u00fcrich\",\n}"), &data)
+	if err != nil {
+		t.Fatalf("Error unmarshalling JSON5: %s", err.Error())
+	}
+	expected = map[string]interface{}{"count": 3, "ratio": 1.5, "city": "Zürich"}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %v, got %v", expected, data)
+	}
+
+	err = JSON5Codec.Unmarshal([]byte("{name: }"), &data)
+	if err == nil {
+		t.Errorf("Expected error for malformed JSON5, got nil")
+	}
+}
+
+func TestFileRepositoryJSON5(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json5")
+	writeFile(t, path, "{\n  // enabled features\n  features: ['a', 'b',],\n}\n")
+	repository := &FileRepository{Name: "file", Path: path, Codec: JSON5Codec}
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	features, _ := repository.GetData("features")
+	if !reflect.DeepEqual(features, []interface{}{"a", "b"}) {
+		t.Errorf("Expected features to be [a b], got %v", features)
+	}
+}
+
+func TestJSONCodecUseNumber(t *testing.T) {
+	document := []byte(`{"id": 9007199254740993, "big": 18446744073709551615, "ratio": 0.5, "nested": {"ids": [1, 9007199254740993]}}`)
+
+	var data map[string]interface{}
+	err := JSONCodec.Unmarshal(document, &data)
+	if err != nil {
+		t.Fatalf("Error unmarshalling JSON: %s", err.Error())
+	}
+	if id, ok := data["id"].(float64); !ok || int64(id) == 9007199254740993 {
+		t.Errorf("Expected the default decoding to round the id to a float64, got %#v", data["id"])
+	}
+
+	data = nil
+	err = WithDecoderOptions(JSONCodec, UseNumber()).Unmarshal(document, &data)
+	if err != nil {
+		t.Fatalf("Error unmarshalling JSON: %s", err.Error())
+	}
+	expected := map[string]interface{}{
+		"id":     9007199254740993,
+		"big":    uint64(18446744073709551615),
+		"ratio":  0.5,
+		"nested": map[string]interface{}{"ids": []interface{}{1, 9007199254740993}},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, data)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, path, string(document))
+	repository := &FileRepository{Name: "file", Path: path, Codec: WithDecoderOptions(JSONCodec, UseNumber())}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if id, _ := repository.GetData("id"); id != 9007199254740993 {
+		t.Errorf("Expected the id to keep its precision, got %#v", id)
+	}
+}
+
+func TestDecoderOptionsStrict(t *testing.T) {
+	type target struct {
+		Name string `yaml:"name" json:"name"`
+	}
+	tests := []struct {
+		codec    Codec
+		document string
+	}{
+		{YAMLCodec, "name: John\nage: 30\n"},
+		{nil, "name: John\nage: 30\n"},
+		{JSONCodec, `{"name": "John", "age": 30}`},
+	}
+	for _, test := range tests {
+		var value target
+		err := codecOrDefault(test.codec).Unmarshal([]byte(test.document), &value)
+		if err != nil || value.Name != "John" {
+			t.Errorf("Expected unknown fields to be ignored by default, got %+v (%v)", value, err)
+		}
+		err = WithDecoderOptions(test.codec, Strict()).Unmarshal([]byte(test.document), &value)
+		if err == nil {
+			t.Errorf("Expected error for an unknown field in strict mode, got nil")
+		}
+	}
+
+	var empty map[string]interface{}
+	err := WithDecoderOptions(YAMLCodec, Strict()).Unmarshal([]byte(""), &empty)
+	if err != nil {
+		t.Errorf("Expected an empty document to decode in strict mode, got %v", err)
+	}
+	if WithDecoderOptions(JSON5Codec, Strict()) != JSON5Codec {
+		t.Errorf("Expected a codec without options to be returned as is")
+	}
+}
+
+func TestMarshalCodec(t *testing.T) {
+	value := map[string]interface{}{"name": "John", "port": 8080, "tags": []interface{}{"a"}}
+	for name, codec := range map[string]MarshalCodec{"yaml": YAMLCodec, "json": JSONCodec, "json5": JSON5Codec} {
+		document, err := codec.Marshal(value)
+		if err != nil {
+			t.Fatalf("Error marshalling with %s: %s", name, err.Error())
+		}
+		var decoded struct {
+			Name string   `yaml:"name" json:"name"`
+			Port int      `yaml:"port" json:"port"`
+			Tags []string `yaml:"tags" json:"tags"`
+		}
+		err = codec.Unmarshal(document, &decoded)
+		if err != nil {
+			t.Fatalf("Error unmarshalling with %s: %s", name, err.Error())
+		}
+		if decoded.Name != "John" || decoded.Port != 8080 || len(decoded.Tags) != 1 {
+			t.Errorf("Expected %s to round-trip the value, got %+v", name, decoded)
+		}
+	}
+	if _, ok := WithDecoderOptions(JSONCodec, Strict()).(MarshalCodec); !ok {
+		t.Errorf("Expected a tuned codec to still marshal")
+	}
+}
@@ -0,0 +1,312 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sync"
+)
+
+// ConfigMapRepository is a struct that implements the Repository interface
+// for handling configuration data stored in a Kubernetes ConfigMap, read
+// through the API server rather than mounted as a file. Without Key, every
+// data entry of the ConfigMap is a configuration named by the entry and its
+// value is parsed with Codec. With Key, only that entry is read, as a whole
+// document whose top-level keys are the configurations. With Watch set, the
+// repository also watches the ConfigMap after the first refresh and applies
+// changes as they are made; when the watch breaks, it is started again by the
+// next refresh. A failed refresh, including one denied by RBAC, leaves the
+// current data in place; with TolerateKeyErrors set, entries that do not
+// parse only keep their own previous values. Close, which Client.Close calls, stops the watch.
+type ConfigMapRepository struct {
+	sync.RWMutex                             // RWMutex to synchronize access to data
+	changeNotifier                           // Notifies the changes applied by the watch
+	Name              string                 // Name of the configuration source
+	Namespace         string                 // Namespace of the ConfigMap
+	ConfigMap         string                 // Name of the ConfigMap
+	Key               string                 // Data entry holding the configuration document, empty to read every entry
+	Watch             bool                   // Whether to watch the ConfigMap for changes between refreshes
+	Client            kubernetes.Interface   // Kubernetes client, created from the in-cluster config by NewConfigMapRepository
+	Codec             Codec                  // Codec used to parse the data, defaults to YAMLCodec, which also parses JSON
+	TolerateKeyErrors bool                   // Whether entries whose value does not parse keep their previous value instead of failing the refresh
+	data              map[string]interface{} // Map to store the configuration data
+	resourceVersion   string                 // Resource version of the ConfigMap the data was read from
+	watching          bool                   // Whether the watch loop is running
+	closed            bool                   // Whether Close has been called
+	cancel            func()                 // Stops the watch loop
+	done              chan struct{}          // Closed when the watch loop returns
+}
+
+// ConfigMapOption configures a ConfigMapRepository created with
+// NewConfigMapRepository.
+type ConfigMapOption func(*ConfigMapRepository)
+
+// WithConfigMapKey reads only the given data entry of the ConfigMap, such as
+// config.yaml, as a document of configurations.
+func WithConfigMapKey(key string) ConfigMapOption {
+	return func(r *ConfigMapRepository) {
+		r.Key = key
+	}
+}
+
+// WithConfigMapCodec sets the codec used to parse the data.
+func WithConfigMapCodec(codec Codec) ConfigMapOption {
+	return func(r *ConfigMapRepository) {
+		r.Codec = codec
+	}
+}
+
+// WithConfigMapTolerateKeyErrors applies the data entries that parse when
+// the values of others do not, instead of failing the whole refresh. The
+// entries that did not parse keep their previous values, and Refresh returns
+// a KeyErrors of them. It has no effect with WithConfigMapKey, whose entry is
+// a single document.
+func WithConfigMapTolerateKeyErrors() ConfigMapOption {
+	return func(r *ConfigMapRepository) {
+		r.TolerateKeyErrors = true
+	}
+}
+
+// WithConfigMapWatch watches the ConfigMap for changes between refreshes.
+func WithConfigMapWatch() ConfigMapOption {
+	return func(r *ConfigMapRepository) {
+		r.Watch = true
+	}
+}
+
+// WithConfigMapClient sets the Kubernetes client used to read the ConfigMap,
+// instead of one created from the in-cluster config.
+func WithConfigMapClient(client kubernetes.Interface) ConfigMapOption {
+	return func(r *ConfigMapRepository) {
+		r.Client = client
+	}
+}
+
+// NewConfigMapRepository creates a ConfigMapRepository reading the ConfigMap
+// with the given name in namespace. Unless WithConfigMapClient is given, the
+// client is created from the in-cluster config, which requires running in a
+// pod whose service account is allowed to get, and to watch with
+// WithConfigMapWatch, the ConfigMap.
+func NewConfigMapRepository(name string, namespace string, configMap string, opts ...ConfigMapOption) (*ConfigMapRepository, error) {
+	repository := &ConfigMapRepository{
+		Name:      name,
+		Namespace: namespace,
+		ConfigMap: configMap,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	if repository.Client == nil {
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, err
+		}
+		repository.Client, err = kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return repository, nil
+}
+
+// GetName returns the name of the configuration source.
+func (r *ConfigMapRepository) GetName() string {
+	return r.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (r *ConfigMapRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	r.RLock()
+	defer r.RUnlock()
+	config, isPresent = r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, as the ConfigMap is not a single document.
+func (r *ConfigMapRepository) GetRawData() []byte {
+	return nil
+}
+
+// Version returns the resource version of the ConfigMap the data was read from.
+func (r *ConfigMapRepository) Version() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.resourceVersion
+}
+
+// Keys returns the names of the loaded configurations.
+func (r *ConfigMapRepository) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.data)
+}
+
+// Refresh reads the ConfigMap and replaces the data map with its
+// configurations. With Watch set, it starts the watch if it is not running.
+func (r *ConfigMapRepository) Refresh() error {
+	return r.RefreshContext(context.Background())
+}
+
+// RefreshContext is Refresh with the request bound to ctx, so that it is
+// abandoned once ctx is done.
+func (r *ConfigMapRepository) RefreshContext(ctx context.Context) error {
+	configMap, err := r.Client.CoreV1().ConfigMaps(r.Namespace).Get(ctx, r.ConfigMap, metav1.GetOptions{})
+	if err != nil {
+		logrus.Debug("error getting configmap")
+		return r.apiError(err)
+	}
+	data, err := r.parse(configMap, r.TolerateKeyErrors)
+	var keyErrors *KeyErrors
+	if err != nil && !errors.As(err, &keyErrors) {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.data = data
+	r.resourceVersion = configMap.ResourceVersion
+	if r.Watch && !r.watching && !r.closed {
+		ctx, cancel := context.WithCancel(context.Background())
+		watcher, watchErr := r.Client.CoreV1().ConfigMaps(r.Namespace).Watch(ctx, metav1.ListOptions{
+			FieldSelector:   "metadata.name=" + r.ConfigMap,
+			ResourceVersion: r.resourceVersion,
+		})
+		if watchErr != nil {
+			// The data is current; the next refresh tries to watch again.
+			cancel()
+			logrus.WithError(r.apiError(watchErr)).Warn("error watching configmap, polling until the next refresh")
+			return err
+		}
+		r.watching = true
+		r.cancel = func() {
+			cancel()
+			watcher.Stop()
+		}
+		r.done = make(chan struct{})
+		go r.watch(watcher)
+	}
+	return err
+}
+
+// Load reads the ConfigMap the way Refresh does and returns its
+// configurations, without replacing the loaded data or starting the watch. A
+// value that does not parse fails Load, even with TolerateKeyErrors.
+func (r *ConfigMapRepository) Load() (map[string]interface{}, error) {
+	return r.LoadContext(context.Background())
+}
+
+// LoadContext is Load with the request bound to ctx.
+func (r *ConfigMapRepository) LoadContext(ctx context.Context) (map[string]interface{}, error) {
+	configMap, err := r.Client.CoreV1().ConfigMaps(r.Namespace).Get(ctx, r.ConfigMap, metav1.GetOptions{})
+	if err != nil {
+		logrus.Debug("error getting configmap")
+		return nil, r.apiError(err)
+	}
+	return r.parse(configMap, false)
+}
+
+// Close stops the watch and waits for it to return.
+func (r *ConfigMapRepository) Close() error {
+	r.Lock()
+	r.closed = true
+	cancel, done := r.cancel, r.done
+	r.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	return nil
+}
+
+// parse returns the configurations held by configMap. A value that does not
+// parse fails the refresh, leaving the current data in place, unless tolerate
+// is set, in which case the configurations are returned with a KeyErrors.
+func (r *ConfigMapRepository) parse(configMap *corev1.ConfigMap, tolerate bool) (map[string]interface{}, error) {
+	if r.Key != "" {
+		document, ok := configMap.Data[r.Key]
+		if !ok {
+			return nil, fmt.Errorf("%w: configmap %s/%s has no key %s", ErrSourceNotFound, r.Namespace, r.ConfigMap, r.Key)
+		}
+		data := map[string]interface{}{}
+		if err := codecOrDefault(r.Codec).Unmarshal([]byte(document), &data); err != nil {
+			logrus.WithField("key", r.Key).Debug("error unmarshalling configmap")
+			return nil, err
+		}
+		return data, nil
+	}
+	raw := make(map[string][]byte, len(configMap.Data))
+	for key, value := range configMap.Data {
+		raw[key] = []byte(value)
+	}
+	r.RLock()
+	previous := r.data
+	r.RUnlock()
+	return parseValues(r.Codec, raw, previous, tolerate)
+}
+
+// apiError returns err of the API server with the reasons a ConfigMap cannot
+// be read spelled out, so that a missing RBAC permission is easy to tell
+// apart from an outage.
+func (r *ConfigMapRepository) apiError(err error) error {
+	switch {
+	case apierrors.IsForbidden(err):
+		return fmt.Errorf("%w: not allowed to read configmap %s/%s, check the RBAC permissions of the service account: %v", ErrSourceUnauthorized, r.Namespace, r.ConfigMap, err)
+	case apierrors.IsUnauthorized(err):
+		return fmt.Errorf("%w: %v", ErrSourceUnauthorized, err)
+	case apierrors.IsNotFound(err):
+		return fmt.Errorf("%w: configmap %s/%s: %v", ErrSourceNotFound, r.Namespace, r.ConfigMap, err)
+	}
+	return err
+}
+
+// watch applies the changes of watcher until it ends. The next refresh then
+// starts a new watch.
+func (r *ConfigMapRepository) watch(watcher watch.Interface) {
+	defer close(r.done)
+	defer watcher.Stop()
+	for event := range watcher.ResultChan() {
+		configMap, ok := event.Object.(*corev1.ConfigMap)
+		if !ok || configMap.Name != r.ConfigMap {
+			continue
+		}
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			r.apply(configMap)
+		case watch.Deleted:
+			logrus.WithField("configmap", r.Namespace+"/"+r.ConfigMap).Warn("configmap deleted, keeping the current data")
+		}
+	}
+	r.Lock()
+	defer r.Unlock()
+	if !r.closed {
+		logrus.Warn("configmap watch broken, polling until the next refresh")
+	}
+	r.watching = false
+}
+
+// apply replaces the data map with the configurations of a ConfigMap
+// received from the watch. A ConfigMap that does not parse is skipped, and
+// reported by the next refresh.
+func (r *ConfigMapRepository) apply(configMap *corev1.ConfigMap) {
+	data, err := r.parse(configMap, r.TolerateKeyErrors)
+	var keyErrors *KeyErrors
+	if err != nil && !errors.As(err, &keyErrors) {
+		logrus.WithField("configmap", r.Namespace+"/"+r.ConfigMap).Warn("error parsing watched configmap")
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Warn("error parsing keys of watched configmap, keeping their previous values")
+	}
+	r.Lock()
+	r.data = data
+	r.resourceVersion = configMap.ResourceVersion
+	r.Unlock()
+	r.notifyChange()
+}
@@ -0,0 +1,147 @@
+package source
+
+import (
+	"context"
+	"errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newConfigMap(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Data:       data,
+	}
+}
+
+func TestConfigMapRepository(t *testing.T) {
+	client := fake.NewSimpleClientset(newConfigMap(map[string]string{
+		"name":     "John",
+		"database": "host: localhost\nport: 5432\n",
+	}))
+	repository, err := NewConfigMapRepository("configmap", "default", "app", WithConfigMapClient(client))
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	if err := repository.Refresh(); err != nil {
+		t.Fatalf("Error refreshing: %s", err.Error())
+	}
+	if value, _ := repository.GetData("name"); value != "John" {
+		t.Errorf("Expected John, got %v", value)
+	}
+	database, _ := repository.GetData("database")
+	if database.(map[string]interface{})["port"] != 5432 {
+		t.Errorf("Expected the entry to be parsed, got %v", database)
+	}
+
+	// Load reads the ConfigMap without replacing the data.
+	_, err = client.CoreV1().ConfigMaps("default").Update(context.Background(), newConfigMap(map[string]string{"name": "Jane"}), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Error updating configmap: %s", err.Error())
+	}
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading: %s", err.Error())
+	}
+	if loaded["name"] != "Jane" {
+		t.Errorf("Expected loaded name to be Jane, got %v", loaded["name"])
+	}
+	if value, _ := repository.GetData("name"); value != "John" {
+		t.Errorf("Expected name to stay John, got %v", value)
+	}
+
+	// An entry that does not parse keeps the current data.
+	_, err = client.CoreV1().ConfigMaps("default").Update(context.Background(), newConfigMap(map[string]string{"name": "[unclosed"}), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Error updating configmap: %s", err.Error())
+	}
+	if err := repository.Refresh(); err == nil {
+		t.Errorf("Expected an error for an entry that does not parse")
+	}
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected Load to fail for an entry that does not parse")
+	}
+	if value, _ := repository.GetData("name"); value != "John" {
+		t.Errorf("Expected the last good data to be kept, got %v", value)
+	}
+}
+
+func TestConfigMapRepositoryKey(t *testing.T) {
+	client := fake.NewSimpleClientset(newConfigMap(map[string]string{
+		"config.yaml": "name: John\nage: 30\n",
+		"other":       "ignored",
+	}))
+	repository, _ := NewConfigMapRepository("configmap", "default", "app", WithConfigMapClient(client), WithConfigMapKey("config.yaml"))
+	if err := repository.Refresh(); err != nil {
+		t.Fatalf("Error refreshing: %s", err.Error())
+	}
+	if keys := repository.Keys(); len(keys) != 2 || keys[0] != "age" || keys[1] != "name" {
+		t.Errorf("Expected the configurations of the document, got %v", keys)
+	}
+
+	missing, _ := NewConfigMapRepository("configmap", "default", "app", WithConfigMapClient(client), WithConfigMapKey("missing.yaml"))
+	if err := missing.Refresh(); !errors.Is(err, ErrSourceNotFound) {
+		t.Errorf("Expected ErrSourceNotFound for a missing key, got %v", err)
+	}
+}
+
+func TestConfigMapRepositoryErrors(t *testing.T) {
+	client := fake.NewSimpleClientset(newConfigMap(map[string]string{"name": "John"}))
+	repository, _ := NewConfigMapRepository("configmap", "default", "app", WithConfigMapClient(client))
+	if err := repository.Refresh(); err != nil {
+		t.Fatalf("Error refreshing: %s", err.Error())
+	}
+
+	client.PrependReactor("get", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "app", errors.New("RBAC: access denied"))
+	})
+	err := repository.Refresh()
+	if !errors.Is(err, ErrSourceUnauthorized) || !strings.Contains(err.Error(), "RBAC permissions") {
+		t.Errorf("Expected ErrSourceUnauthorized naming the RBAC permissions, got %v", err)
+	}
+	if value, _ := repository.GetData("name"); value != "John" {
+		t.Errorf("Expected the last good data to be kept, got %v", value)
+	}
+
+	missing, _ := NewConfigMapRepository("configmap", "default", "missing", WithConfigMapClient(fake.NewSimpleClientset()))
+	if err := missing.Refresh(); !errors.Is(err, ErrSourceNotFound) {
+		t.Errorf("Expected ErrSourceNotFound for a missing configmap, got %v", err)
+	}
+}
+
+func TestConfigMapRepositoryWatch(t *testing.T) {
+	client := fake.NewSimpleClientset(newConfigMap(map[string]string{"name": "John"}))
+	repository, _ := NewConfigMapRepository("configmap", "default", "app", WithConfigMapClient(client), WithConfigMapWatch())
+	notified := notifications(repository)
+	if err := repository.Refresh(); err != nil {
+		t.Fatalf("Error refreshing: %s", err.Error())
+	}
+
+	_, err := client.CoreV1().ConfigMaps("default").Update(context.Background(), newConfigMap(map[string]string{"name": "Jane"}), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Error updating configmap: %s", err.Error())
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if value, _ := repository.GetData("name"); value == "Jane" {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if value, _ := repository.GetData("name"); value != "Jane" {
+		t.Errorf("Expected the watch to apply the change, got %v", value)
+	}
+	waitForNotification(t, notified)
+
+	if err := repository.Close(); err != nil {
+		t.Errorf("Error closing: %s", err.Error())
+	}
+}
@@ -0,0 +1,267 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// DefaultConsulAddress is the address of the local Consul agent, which a
+// ConsulRepository connects to when it is given no address.
+const DefaultConsulAddress = "http://127.0.0.1:8500"
+
+// ConsulRepository is a struct that implements the Repository interface for
+// handling configuration data stored in the key/value store of Consul. Every
+// key under Prefix is read on refresh, and its value is parsed with Codec.
+// Keys are nested by "/", so the keys `app/db/host` and `app/db/port` under
+// the prefix `app/` form the configuration `db` with the fields host and
+// port. A failed refresh, for example while Consul is unavailable, leaves the
+// current data in place.
+type ConsulRepository struct {
+	sync.RWMutex                         // RWMutex to synchronize access to data during refresh
+	Name          string                 // Name of the configuration source
+	Address       string                 // Address of the Consul agent, defaults to DefaultConsulAddress
+	Prefix        string                 // Prefix of the keys to read, stripped from the configuration names
+	Token         string                 // ACL token sent with every request, if any
+	TokenProvider TokenProvider          // Provider of the ACL token, used instead of Token and called again when Consul answers 401 or 403
+	Codec         Codec                  // Codec used to parse each value, defaults to YAMLCodec, which also parses JSON
+	HTTPClient    *http.Client           // HTTP client used to reach Consul, defaults to http.DefaultClient
+	data          map[string]interface{} // Map to store the configuration data
+	version       string                 // Consul index of the currently loaded keys
+}
+
+// ConsulOption configures a ConsulRepository created with NewConsulRepository.
+type ConsulOption func(*ConsulRepository)
+
+// WithConsulToken sends the given ACL token with every request.
+func WithConsulToken(token string) ConsulOption {
+	return func(r *ConsulRepository) {
+		r.Token = token
+	}
+}
+
+// WithConsulTokenProvider asks provider for the ACL token on every refresh,
+// so that rotated tokens are picked up.
+func WithConsulTokenProvider(provider TokenProvider) ConsulOption {
+	return func(r *ConsulRepository) {
+		r.TokenProvider = provider
+	}
+}
+
+// WithConsulHTTPClient sets the HTTP client used to reach Consul, for example
+// one configured with TLS client certificates.
+func WithConsulHTTPClient(client *http.Client) ConsulOption {
+	return func(r *ConsulRepository) {
+		r.HTTPClient = client
+	}
+}
+
+// NewConsulRepository creates a ConsulRepository reading the keys under prefix
+// from the Consul agent at address, such as http://127.0.0.1:8500. An address
+// without a scheme is reached over http.
+func NewConsulRepository(name string, address string, prefix string, opts ...ConsulOption) *ConsulRepository {
+	repository := &ConsulRepository{
+		Name:    name,
+		Address: address,
+		Prefix:  prefix,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	return repository
+}
+
+// GetName returns the name of the configuration source.
+func (r *ConsulRepository) GetName() string {
+	return r.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (r *ConsulRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	r.RLock()
+	defer r.RUnlock()
+	config, isPresent = r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, as the configuration is read key by key.
+func (r *ConsulRepository) GetRawData() []byte {
+	return nil
+}
+
+// Version returns the Consul index of the currently loaded keys, which
+// changes whenever a key under the prefix does.
+func (r *ConsulRepository) Version() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.version
+}
+
+// Keys returns the names of the top-level configurations.
+func (r *ConsulRepository) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.data)
+}
+
+// consulPair is a key and its value as listed by the Consul KV API.
+type consulPair struct {
+	Key   string // Full key
+	Value []byte // Value of the key, nil for folders
+}
+
+// Refresh reads every key under the prefix from Consul and replaces the data
+// map with them. If the Consul index has not changed, the keys are not reparsed.
+func (r *ConsulRepository) Refresh() error {
+	r.Lock()
+	defer r.Unlock()
+
+	// List the keys, authenticating with a fresh token if there is a provider.
+	var pairs []consulPair
+	var version string
+	fetch := func(token string) error {
+		var err error
+		pairs, version, err = r.list(token)
+		return err
+	}
+	var err error
+	if r.TokenProvider != nil {
+		err = withToken(context.Background(), r.TokenProvider, fetch)
+	} else {
+		err = fetch(r.Token)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Skip reparsing when the index has not changed.
+	if version != "" && version == r.version {
+		logrus.Debug("version unchanged, skipping reparse")
+		return nil
+	}
+
+	// Parse the values into a new map, so that a value that does not parse
+	// leaves the current data in place.
+	data, err := r.nest(pairs)
+	if err != nil {
+		return err
+	}
+	r.data = data
+	r.version = version
+	return nil
+}
+
+// Load reads every key under the prefix from Consul and returns them nested
+// the way Refresh does, without replacing the loaded data.
+func (r *ConsulRepository) Load() (map[string]interface{}, error) {
+	var pairs []consulPair
+	fetch := func(token string) error {
+		var err error
+		pairs, _, err = r.list(token)
+		return err
+	}
+	var err error
+	if r.TokenProvider != nil {
+		err = withToken(context.Background(), r.TokenProvider, fetch)
+	} else {
+		err = fetch(r.Token)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.nest(pairs)
+}
+
+// requestURL returns the URL listing the keys under the prefix.
+func (r *ConsulRepository) requestURL() (*url.URL, error) {
+	address := r.Address
+	if address == "" {
+		address = DefaultConsulAddress
+	}
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	requestURL, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	requestURL.Path = strings.TrimSuffix(requestURL.Path, "/") + "/v1/kv/" + r.Prefix
+	requestURL.RawQuery = "recurse=true"
+	return requestURL, nil
+}
+
+// list requests the keys under the prefix with the given token, if any, and
+// returns them with the Consul index of the response. A prefix without
+// keys, which Consul answers with 404, lists no keys.
+func (r *ConsulRepository) list(token string) ([]consulPair, string, error) {
+	requestURL, err := r.requestURL()
+	if err != nil {
+		logrus.Debug("error parsing consul address")
+		return nil, "", err
+	}
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, requestURL.String(), nil)
+	if err != nil {
+		logrus.Debug("error creating request")
+		return nil, "", err
+	}
+	if token != "" {
+		request.Header.Set("X-Consul-Token", token)
+	}
+
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		logrus.Debug("error doing request")
+		return nil, "", err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			logrus.WithError(err).Debug("error closing response body")
+		}
+	}(resp.Body)
+
+	version := resp.Header.Get("X-Consul-Index")
+	if resp.StatusCode == http.StatusNotFound {
+		logrus.Debug("no keys under prefix")
+		return nil, version, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		logrus.Debug("unexpected status code")
+		return nil, "", newStatusError(resp, requestURL)
+	}
+
+	var pairs []consulPair
+	err = json.NewDecoder(resp.Body).Decode(&pairs)
+	if err != nil {
+		logrus.Debug("error decoding keys")
+		return nil, "", err
+	}
+	return pairs, version, nil
+}
+
+// nest parses the value of every key with the codec and nests the keys by "/".
+func (r *ConsulRepository) nest(pairs []consulPair) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for _, pair := range pairs {
+		name := strings.Trim(strings.TrimPrefix(pair.Key, r.Prefix), "/")
+		if name == "" || strings.HasSuffix(pair.Key, "/") {
+			continue
+		}
+		value, err := parseValue(r.Codec, pair.Value)
+		if err != nil {
+			logrus.WithField("key", pair.Key).Debug("error unmarshalling value")
+			return nil, err
+		}
+		values[name] = value
+	}
+	return nestValues(values), nil
+}
@@ -0,0 +1,197 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeConsul is an in-memory Consul KV API that requires the token "secret".
+type fakeConsul struct {
+	mu    sync.Mutex
+	pairs map[string]string
+	index int
+	down  bool
+}
+
+func (f *fakeConsul) set(key string, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pairs[key] = value
+	f.index++
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if r.Header.Get("X-Consul-Token") != "secret" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if r.URL.Query().Get("recurse") != "true" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	var pairs []consulPair
+	for key, value := range f.pairs {
+		if strings.HasPrefix(key, prefix) {
+			pair := consulPair{Key: key}
+			if !strings.HasSuffix(key, "/") {
+				pair.Value = []byte(value)
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	w.Header().Set("X-Consul-Index", strings.Repeat("1", f.index))
+	if len(pairs) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(pairs)
+}
+
+func TestConsulRepository(t *testing.T) {
+	consul := &fakeConsul{pairs: map[string]string{
+		"app/":         "",
+		"app/name":     "John",
+		"app/db/host":  "localhost",
+		"app/db/port":  "5432",
+		"app/features": `{"beta": true, "regions": ["eu", "us"]}`,
+		"other/name":   "Jane",
+	}}
+	server := httptest.NewServer(consul)
+	defer server.Close()
+
+	repository := NewConsulRepository("consul", strings.TrimPrefix(server.URL, "http://"), "app/", WithConsulToken("secret"))
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+	db, _ := repository.GetData("db")
+	dbMap, ok := db.(map[string]interface{})
+	if !ok || dbMap["host"] != "localhost" || dbMap["port"] != 5432 {
+		t.Errorf("Expected db to be nested with host and port, got %v", db)
+	}
+	features, _ := repository.GetData("features")
+	featuresMap, ok := features.(map[string]interface{})
+	if !ok || featuresMap["beta"] != true {
+		t.Errorf("Expected features to be decoded from JSON, got %v", features)
+	}
+	keys := repository.Keys()
+	if len(keys) != 3 || keys[0] != "db" || keys[1] != "features" || keys[2] != "name" {
+		t.Errorf("Expected keys [db features name], got %v", keys)
+	}
+
+	// Changes are picked up with the Consul index.
+	version := repository.Version()
+	consul.set("app/name", "Jack")
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jack" {
+		t.Errorf("Expected name to be Jack, got %v", name)
+	}
+	if repository.Version() == version {
+		t.Errorf("Expected the version to change with the index")
+	}
+
+	// The last good snapshot is kept while Consul is unavailable.
+	consul.mu.Lock()
+	consul.down = true
+	consul.mu.Unlock()
+	err = repository.Refresh()
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected a StatusError for an unavailable Consul, got %v", err)
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jack" {
+		t.Errorf("Expected the last good data to be kept, got %v", name)
+	}
+	consul.mu.Lock()
+	consul.down = false
+	consul.mu.Unlock()
+
+	// Load returns the keys without replacing the data.
+	consul.set("app/name", "Jill")
+	loaded, err := repository.Load()
+	if err != nil || loaded["name"] != "Jill" {
+		t.Errorf("Expected the loaded name to be Jill, got %v (%v)", loaded, err)
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jack" {
+		t.Errorf("Expected the live data to be untouched, got %v", name)
+	}
+
+	// A value that does not parse leaves the data in place.
+	consul.set("app/broken", "key: [unclosed")
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected Load to fail for a value that does not parse, got nil")
+	}
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a value that does not parse, got nil")
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jack" {
+		t.Errorf("Expected the last good data to be kept, got %v", name)
+	}
+}
+
+func TestConsulRepositoryToken(t *testing.T) {
+	consul := &fakeConsul{pairs: map[string]string{"app/name": "John"}}
+	server := httptest.NewServer(consul)
+	defer server.Close()
+
+	repository := NewConsulRepository("consul", server.URL, "app/")
+	err := repository.Refresh()
+	if !errors.Is(NormalizeError(err), ErrSourceUnauthorized) {
+		t.Errorf("Expected ErrSourceUnauthorized without a token, got %v", err)
+	}
+
+	// A rejected token is replaced by a fresh one.
+	var calls int
+	repository = NewConsulRepository("consul", server.URL, "app/", WithConsulTokenProvider(func(ctx context.Context) (string, error) {
+		calls++
+		if calls == 1 {
+			return "expired", nil
+		}
+		return "secret", nil
+	}))
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if calls != 2 {
+		t.Errorf("Expected the provider to be called twice, got %d", calls)
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+
+	// A prefix without keys loads no configuration.
+	repository = NewConsulRepository("consul", server.URL, "missing/", WithConsulToken("secret"))
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if len(repository.Keys()) != 0 {
+		t.Errorf("Expected no keys, got %v", repository.Keys())
+	}
+}
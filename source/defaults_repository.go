@@ -0,0 +1,161 @@
+package source
+
+import (
+	"io"
+	"sort"
+)
+
+// DefaultsKey is the top-level key under which a configuration document can
+// declare default values, as a map of configuration names to their values,
+// for a DefaultsRepository to serve when the configurations are absent.
+const DefaultsKey = "_defaults"
+
+// DefaultsRepository is a struct that implements the Repository interface by
+// reading every configuration from the wrapped repository first, and from the
+// DefaultsKey section of the wrapped repository only when it is absent, so
+// that the source rather than every call site declares the defaults:
+//
+//	_defaults:
+//	  timeout: 30
+//	  retries: 3
+//	timeout: 10
+//
+// serves a timeout of 10 and 3 retries. The precedence of a value is thus
+// the live value, then the default declared in the source, then the default
+// the caller passes to a getter. With a ChainRepository, the section comes
+// from the first repository that has one, like any other configuration. The
+// section itself is not served as a configuration.
+type DefaultsRepository struct {
+	Repository Repository // Repository the configurations and their defaults are read from
+}
+
+// NewDefaultsRepository creates a DefaultsRepository serving the defaults
+// declared in repository.
+func NewDefaultsRepository(repository Repository) *DefaultsRepository {
+	return &DefaultsRepository{Repository: repository}
+}
+
+// GetName returns the name of the wrapped repository.
+func (r *DefaultsRepository) GetName() string {
+	return r.Repository.GetName()
+}
+
+// NotifyChanges registers fn with the wrapped repository if it is a Notifier.
+func (r *DefaultsRepository) NotifyChanges(fn func()) {
+	if notifier, ok := r.Repository.(Notifier); ok {
+		notifier.NotifyChanges(fn)
+	}
+}
+
+// GetData returns the configuration with the given name from the wrapped
+// repository if it has it, and its declared default otherwise.
+func (r *DefaultsRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	if configName == DefaultsKey {
+		return nil, false
+	}
+	config, isPresent = r.Repository.GetData(configName)
+	if isPresent {
+		return config, true
+	}
+	config, isPresent = r.defaults()[configName]
+	return config, isPresent
+}
+
+// GetRawData returns the raw data of the wrapped repository.
+func (r *DefaultsRepository) GetRawData() []byte {
+	return r.Repository.GetRawData()
+}
+
+// Refresh refreshes the wrapped repository, and with it the declared defaults.
+func (r *DefaultsRepository) Refresh() error {
+	return r.Repository.Refresh()
+}
+
+// Load loads the wrapped repository if it is a Loader and returns its
+// configurations with the defaults it declares for the absent ones, without
+// the section itself.
+func (r *DefaultsRepository) Load() (map[string]interface{}, error) {
+	loader, ok := r.Repository.(Loader)
+	if !ok {
+		return nil, ErrUnloadableRepository
+	}
+	loaded, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{}, len(loaded))
+	for key, value := range declaredDefaults(loaded[DefaultsKey]) {
+		data[key] = value
+	}
+	for key, value := range loaded {
+		if key != DefaultsKey {
+			data[key] = value
+		}
+	}
+	return data, nil
+}
+
+// Version returns the version of the wrapped repository, if it is Versioned.
+func (r *DefaultsRepository) Version() string {
+	if versioned, ok := r.Repository.(Versioned); ok {
+		return versioned.Version()
+	}
+	return ""
+}
+
+// Keys returns the names of the configurations of the wrapped repository, if
+// it is a KeyLister, and of the declared defaults.
+func (r *DefaultsRepository) Keys() []string {
+	lister, ok := r.Repository.(KeyLister)
+	if !ok {
+		return nil
+	}
+	seen := map[string]bool{}
+	var keys []string
+	for _, key := range lister.Keys() {
+		if key != DefaultsKey {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for key := range r.defaults() {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Close closes the wrapped repository if it can be closed.
+func (r *DefaultsRepository) Close() error {
+	if closer, ok := r.Repository.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// defaults returns the defaults declared in the wrapped repository, which
+// are nil if it declares none or the section is not a map.
+func (r *DefaultsRepository) defaults() map[string]interface{} {
+	section, _ := r.Repository.GetData(DefaultsKey)
+	return declaredDefaults(section)
+}
+
+// declaredDefaults returns the defaults declared in section, the value of
+// DefaultsKey, which are nil if section is not a map.
+func declaredDefaults(section interface{}) map[string]interface{} {
+	switch defaults := section.(type) {
+	case map[string]interface{}:
+		return defaults
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(defaults))
+		for key, value := range defaults {
+			if key, ok := key.(string); ok {
+				converted[key] = value
+			}
+		}
+		return converted
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package source
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDefaultsRepository(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "_defaults:\n  timeout: 30\n  retries: 3\ntimeout: 10\nname: John\n")
+	repository := NewDefaultsRepository(&FileRepository{Name: "file", Path: path})
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// The live value takes precedence over the declared default.
+	timeout, ok := repository.GetData("timeout")
+	if !ok || timeout != 10 {
+		t.Errorf("Expected the live timeout of 10, got %v (%t)", timeout, ok)
+	}
+	// The declared default is served when the live value is absent.
+	retries, ok := repository.GetData("retries")
+	if !ok || retries != 3 {
+		t.Errorf("Expected the declared retries of 3, got %v (%t)", retries, ok)
+	}
+	// Without either, the configuration is absent.
+	if value, ok := repository.GetData("missing"); ok {
+		t.Errorf("Expected missing to be absent, got %v", value)
+	}
+	if section, ok := repository.GetData(DefaultsKey); ok {
+		t.Errorf("Expected the defaults section to be hidden, got %v", section)
+	}
+	if keys := repository.Keys(); !reflect.DeepEqual(keys, []string{"name", "retries", "timeout"}) {
+		t.Errorf("Expected the live and declared keys, got %v", keys)
+	}
+
+	// Load fills in the declared defaults and hides the section.
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	expected := map[string]interface{}{"timeout": 10, "retries": 3, "name": "John"}
+	if !reflect.DeepEqual(loaded, expected) {
+		t.Errorf("Expected loaded data to be %v, got %v", expected, loaded)
+	}
+
+	// A refresh picks up changed defaults.
+	writeFile(t, path, "_defaults:\n  retries: 5\n")
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	retries, _ = repository.GetData("retries")
+	if retries != 5 {
+		t.Errorf("Expected the new declared retries of 5, got %v", retries)
+	}
+}
@@ -0,0 +1,224 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDynamoDBValueAttribute is the attribute holding the value of a
+// configuration, which a DynamoDBRepository reads when it is given no other.
+const DefaultDynamoDBValueAttribute = "value"
+
+// DynamoDBAPI is the subset of the DynamoDB client used by DynamoDBRepository.
+type DynamoDBAPI interface {
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+}
+
+// DynamoDBRepository is a struct that implements the Repository interface for
+// handling configuration data stored as items of a DynamoDB table. Every item
+// is a configuration named by its partition key attribute, KeyAttribute, whose
+// value attribute is parsed with Codec when it is a string or binary, so the
+// item {"name": "db", "value": "host: localhost"} is the configuration `db`
+// with the field host. Number, bool and null values are read as such. With a
+// KeyPrefix only the items whose key starts with it are returned by the scan,
+// stored under their key without the prefix. A failed refresh, for example
+// while requests are throttled, leaves the current data in place.
+type DynamoDBRepository struct {
+	sync.RWMutex                          // RWMutex to synchronize access to data during refresh
+	Name           string                 // Name of the configuration source
+	Table          string                 // Name of the table holding the configurations
+	KeyAttribute   string                 // Partition key attribute holding the configuration names
+	ValueAttribute string                 // Attribute holding the configuration values, defaults to DefaultDynamoDBValueAttribute
+	KeyPrefix      string                 // Prefix of the keys to read, stripped from the configuration names
+	Codec          Codec                  // Codec used to parse each value, defaults to YAMLCodec, which also parses JSON
+	Timeout        time.Duration          // Bound of the requests of a refresh, none when 0
+	Client         DynamoDBAPI            // DynamoDB client, created from the AWS config by NewDynamoDBRepository
+	data           map[string]interface{} // Map to store the configuration data
+}
+
+// DynamoDBOption configures a DynamoDBRepository created with NewDynamoDBRepository.
+type DynamoDBOption func(*DynamoDBRepository)
+
+// WithDynamoDBClient sets the DynamoDB client used to scan the table.
+func WithDynamoDBClient(client DynamoDBAPI) DynamoDBOption {
+	return func(r *DynamoDBRepository) {
+		r.Client = client
+	}
+}
+
+// WithDynamoDBValueAttribute reads the configuration values from attribute
+// instead of DefaultDynamoDBValueAttribute.
+func WithDynamoDBValueAttribute(attribute string) DynamoDBOption {
+	return func(r *DynamoDBRepository) {
+		r.ValueAttribute = attribute
+	}
+}
+
+// WithDynamoDBKeyPrefix only reads the items whose key starts with prefix,
+// such as "prod/", and strips it from the configuration names. The table is
+// still scanned, but the other items are filtered out by DynamoDB.
+func WithDynamoDBKeyPrefix(prefix string) DynamoDBOption {
+	return func(r *DynamoDBRepository) {
+		r.KeyPrefix = prefix
+	}
+}
+
+// WithDynamoDBCodec parses the configuration values with codec.
+func WithDynamoDBCodec(codec Codec) DynamoDBOption {
+	return func(r *DynamoDBRepository) {
+		r.Codec = codec
+	}
+}
+
+// WithDynamoDBTimeout bounds the time the requests of a refresh may take.
+func WithDynamoDBTimeout(timeout time.Duration) DynamoDBOption {
+	return func(r *DynamoDBRepository) {
+		r.Timeout = timeout
+	}
+}
+
+// NewDynamoDBRepository creates a DynamoDBRepository reading the items of table,
+// named by the partition key attribute keyAttribute, with a DynamoDB client
+// created from cfg.
+func NewDynamoDBRepository(name string, table string, keyAttribute string, cfg aws.Config, opts ...DynamoDBOption) *DynamoDBRepository {
+	repository := &DynamoDBRepository{
+		Name:         name,
+		Table:        table,
+		KeyAttribute: keyAttribute,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	if repository.Client == nil {
+		repository.Client = dynamodb.NewFromConfig(cfg)
+	}
+	return repository
+}
+
+// GetName returns the name of the configuration source.
+func (r *DynamoDBRepository) GetName() string {
+	return r.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (r *DynamoDBRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	r.RLock()
+	defer r.RUnlock()
+	config, isPresent = r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, as the configuration is read item by item.
+func (r *DynamoDBRepository) GetRawData() []byte {
+	return nil
+}
+
+// Keys returns the names of the loaded configurations.
+func (r *DynamoDBRepository) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.data)
+}
+
+// Refresh scans the table and replaces the data map with its items.
+func (r *DynamoDBRepository) Refresh() error {
+	return r.RefreshContext(context.Background())
+}
+
+// RefreshContext is Refresh with the requests to DynamoDB bound to ctx, so
+// that they are abandoned once ctx is done.
+func (r *DynamoDBRepository) RefreshContext(ctx context.Context) error {
+	data, err := r.LoadContext(ctx)
+	if err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.data = data
+	return nil
+}
+
+// Load scans the table and returns its items the way Refresh stores them,
+// without replacing the loaded data.
+func (r *DynamoDBRepository) Load() (map[string]interface{}, error) {
+	return r.LoadContext(context.Background())
+}
+
+// LoadContext is Load with the requests to DynamoDB bound to ctx.
+func (r *DynamoDBRepository) LoadContext(ctx context.Context) (map[string]interface{}, error) {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	valueAttribute := r.ValueAttribute
+	if valueAttribute == "" {
+		valueAttribute = DefaultDynamoDBValueAttribute
+	}
+	input := &dynamodb.ScanInput{
+		TableName:                aws.String(r.Table),
+		ProjectionExpression:     aws.String("#key, #value"),
+		ExpressionAttributeNames: map[string]string{"#key": r.KeyAttribute, "#value": valueAttribute},
+	}
+	if r.KeyPrefix != "" {
+		input.FilterExpression = aws.String("begins_with(#key, :prefix)")
+		input.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: r.KeyPrefix},
+		}
+	}
+
+	data := map[string]interface{}{}
+	for {
+		output, err := r.Client.Scan(ctx, input)
+		if err != nil {
+			logrus.Debug("error scanning table")
+			return nil, err
+		}
+		for _, item := range output.Items {
+			key, ok := item[r.KeyAttribute].(*types.AttributeValueMemberS)
+			if !ok || !strings.HasPrefix(key.Value, r.KeyPrefix) {
+				continue
+			}
+			name := strings.TrimPrefix(key.Value, r.KeyPrefix)
+			if name == "" {
+				continue
+			}
+			value, err := r.parseAttribute(item[valueAttribute])
+			if err != nil {
+				logrus.WithField("key", key.Value).Debug("error unmarshalling value")
+				return nil, err
+			}
+			data[name] = value
+		}
+		if len(output.LastEvaluatedKey) == 0 {
+			break
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+	return data, nil
+}
+
+// parseAttribute returns the configuration value of a value attribute,
+// parsing strings and binaries with the codec. A missing attribute is nil.
+func (r *DynamoDBRepository) parseAttribute(attribute types.AttributeValue) (interface{}, error) {
+	switch value := attribute.(type) {
+	case nil, *types.AttributeValueMemberNULL:
+		return nil, nil
+	case *types.AttributeValueMemberS:
+		return parseValue(r.Codec, []byte(value.Value))
+	case *types.AttributeValueMemberB:
+		return parseValue(r.Codec, value.Value)
+	case *types.AttributeValueMemberN:
+		return parseValue(YAMLCodec, []byte(value.Value))
+	case *types.AttributeValueMemberBOOL:
+		return value.Value, nil
+	}
+	return nil, fmt.Errorf("unsupported attribute type %T", attribute)
+}
@@ -0,0 +1,162 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDynamoDB is an in-memory DynamoDB table returning one item per page. It
+// applies the begins_with filter of the scan to the key attribute.
+type fakeDynamoDB struct {
+	items []map[string]types.AttributeValue
+	calls int
+	err   error
+	ctx   context.Context
+}
+
+func (f *fakeDynamoDB) Scan(ctx context.Context, params *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	f.calls++
+	f.ctx = ctx
+	if f.err != nil {
+		return nil, f.err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key := params.ExpressionAttributeNames["#key"]
+	var matching []map[string]types.AttributeValue
+	for _, item := range f.items {
+		if params.FilterExpression != nil {
+			prefix := params.ExpressionAttributeValues[":prefix"].(*types.AttributeValueMemberS).Value
+			name, ok := item[key].(*types.AttributeValueMemberS)
+			if !ok || !strings.HasPrefix(name.Value, prefix) {
+				continue
+			}
+		}
+		matching = append(matching, item)
+	}
+	start := 0
+	if params.ExclusiveStartKey != nil {
+		start, _ = strconv.Atoi(params.ExclusiveStartKey["page"].(*types.AttributeValueMemberN).Value)
+	}
+	output := &dynamodb.ScanOutput{}
+	if start < len(matching) {
+		output.Items = matching[start : start+1]
+	}
+	if start+1 < len(matching) {
+		output.LastEvaluatedKey = map[string]types.AttributeValue{"page": &types.AttributeValueMemberN{Value: strconv.Itoa(start + 1)}}
+	}
+	return output, nil
+}
+
+func dynamoDBItem(name string, value types.AttributeValue) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"name": &types.AttributeValueMemberS{Value: name}, "value": value}
+}
+
+func TestDynamoDBRepository(t *testing.T) {
+	service := &fakeDynamoDB{items: []map[string]types.AttributeValue{
+		dynamoDBItem("db", &types.AttributeValueMemberS{Value: "host: localhost\nport: 5432\n"}),
+		dynamoDBItem("flags", &types.AttributeValueMemberB{Value: []byte(`{"beta": true}`)}),
+		dynamoDBItem("retries", &types.AttributeValueMemberN{Value: "3"}),
+		dynamoDBItem("enabled", &types.AttributeValueMemberBOOL{Value: true}),
+		dynamoDBItem("empty", &types.AttributeValueMemberNULL{Value: true}),
+	}}
+
+	repository := NewDynamoDBRepository("dynamodb", "config", "name", aws.Config{}, WithDynamoDBClient(service))
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if service.calls != 5 {
+		t.Errorf("Expected every page to be read, got %d calls", service.calls)
+	}
+	expected := map[string]interface{}{
+		"db":      map[string]interface{}{"host": "localhost", "port": 5432},
+		"flags":   map[string]interface{}{"beta": true},
+		"retries": 3,
+		"enabled": true,
+		"empty":   nil,
+	}
+	for name, value := range expected {
+		config, ok := repository.GetData(name)
+		if !ok || !reflect.DeepEqual(config, value) {
+			t.Errorf("Expected %s to be %v, got %v (%t)", name, value, config, ok)
+		}
+	}
+	if keys := repository.Keys(); !reflect.DeepEqual(keys, []string{"db", "empty", "enabled", "flags", "retries"}) {
+		t.Errorf("Expected the keys of every item, got %v", keys)
+	}
+}
+
+func TestDynamoDBRepositoryKeyPrefix(t *testing.T) {
+	service := &fakeDynamoDB{items: []map[string]types.AttributeValue{
+		dynamoDBItem("prod/db", &types.AttributeValueMemberS{Value: "prod-db"}),
+		dynamoDBItem("staging/db", &types.AttributeValueMemberS{Value: "staging-db"}),
+	}}
+
+	repository := NewDynamoDBRepository("dynamodb", "config", "name", aws.Config{}, WithDynamoDBClient(service), WithDynamoDBKeyPrefix("prod/"))
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if service.calls != 1 {
+		t.Errorf("Expected the other items to be filtered out by the scan, got %d calls", service.calls)
+	}
+	if keys := repository.Keys(); !reflect.DeepEqual(keys, []string{"db"}) {
+		t.Errorf("Expected only the prefixed item without its prefix, got %v", keys)
+	}
+}
+
+func TestDynamoDBRepositoryKeepsDataOnError(t *testing.T) {
+	service := &fakeDynamoDB{items: []map[string]types.AttributeValue{
+		dynamoDBItem("db", &types.AttributeValueMemberS{Value: "localhost"}),
+	}}
+	repository := NewDynamoDBRepository("dynamodb", "config", "name", aws.Config{}, WithDynamoDBClient(service))
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	service.err = &types.ProvisionedThroughputExceededException{}
+	err = repository.Refresh()
+	var throttled *types.ProvisionedThroughputExceededException
+	if !errors.As(err, &throttled) {
+		t.Errorf("Expected the throttling error, got %v", err)
+	}
+	service.err = nil
+	service.items = append(service.items, dynamoDBItem("broken", &types.AttributeValueMemberS{Value: "key: [unterminated"}))
+	if err := repository.Refresh(); err == nil {
+		t.Errorf("Expected an error for a value that does not parse")
+	}
+	if db, _ := repository.GetData("db"); db != "localhost" {
+		t.Errorf("Expected the last good data to be kept, got %v", db)
+	}
+	if _, ok := repository.GetData("broken"); ok {
+		t.Errorf("Expected the failed refresh not to load any item")
+	}
+}
+
+func TestDynamoDBRepositoryContext(t *testing.T) {
+	service := &fakeDynamoDB{}
+	repository := NewDynamoDBRepository("dynamodb", "config", "name", aws.Config{}, WithDynamoDBClient(service), WithDynamoDBTimeout(time.Minute))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := repository.RefreshContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the error of the canceled context, got %v", err)
+	}
+	if err := repository.Refresh(); err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if _, ok := service.ctx.Deadline(); !ok {
+		t.Errorf("Expected the scan to be bound by the timeout")
+	}
+}
@@ -0,0 +1,219 @@
+package source
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultEnvOverridePrefix is the prefix of the variables an
+// EnvOverrideRepository reads when none is set.
+const DefaultEnvOverridePrefix = "REMOTECONFIG_"
+
+// EnvOverrideRepository is a struct that implements the Repository interface
+// by reading every configuration from an environment variable first, and
+// from the wrapped repository only when the variable is not set, for local
+// testing and emergency overrides. The variable of a name is Prefix followed
+// by the name in upper case, with "." replaced by Separator and every other
+// character that is not a letter, digit or underscore replaced by "_", so
+// that REMOTECONFIG_DATABASE_HOST overrides database.host. The fields of a
+// map read from the wrapped repository are overridden the same way, so that
+// the variable also applies to the database configuration as a whole. Values
+// are parsed with Codec, so typed getters still work, and a value that does
+// not parse is used as a string. Variables are read on every lookup rather
+// than on refresh.
+type EnvOverrideRepository struct {
+	Repository Repository // Repository the configurations are read from when no variable overrides them
+	Prefix     string     // Prefix of the variables, defaults to DefaultEnvOverridePrefix
+	Separator  string     // Replaces "." between the segments of a name, defaults to DefaultEnvSeparator
+	Codec      Codec      // Codec used to parse the values of the variables, defaults to YAMLCodec
+}
+
+// EnvOverrideOption configures an EnvOverrideRepository created with NewEnvOverrideRepository.
+type EnvOverrideOption func(*EnvOverrideRepository)
+
+// WithEnvOverridePrefix reads the variables with the given prefix.
+func WithEnvOverridePrefix(prefix string) EnvOverrideOption {
+	return func(r *EnvOverrideRepository) {
+		r.Prefix = prefix
+	}
+}
+
+// WithEnvOverrideSeparator replaces "." between the segments of a name with separator.
+func WithEnvOverrideSeparator(separator string) EnvOverrideOption {
+	return func(r *EnvOverrideRepository) {
+		r.Separator = separator
+	}
+}
+
+// WithEnvOverrideCodec parses the values of the variables with codec.
+func WithEnvOverrideCodec(codec Codec) EnvOverrideOption {
+	return func(r *EnvOverrideRepository) {
+		r.Codec = codec
+	}
+}
+
+// NewEnvOverrideRepository creates an EnvOverrideRepository overriding the
+// configurations of repository with environment variables.
+func NewEnvOverrideRepository(repository Repository, opts ...EnvOverrideOption) *EnvOverrideRepository {
+	override := &EnvOverrideRepository{Repository: repository}
+	for _, opt := range opts {
+		opt(override)
+	}
+	return override
+}
+
+// GetName returns the name of the wrapped repository.
+func (r *EnvOverrideRepository) GetName() string {
+	return r.Repository.GetName()
+}
+
+// NotifyChanges registers fn with the wrapped repository if it is a Notifier.
+func (r *EnvOverrideRepository) NotifyChanges(fn func()) {
+	if notifier, ok := r.Repository.(Notifier); ok {
+		notifier.NotifyChanges(fn)
+	}
+}
+
+// GetData returns the configuration with the given name from its variable
+// if it is set, and from the wrapped repository otherwise.
+func (r *EnvOverrideRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	if value, ok := r.lookupEnv(configName); ok {
+		return value, true
+	}
+	config, isPresent = r.Repository.GetData(configName)
+	if !isPresent {
+		return nil, false
+	}
+	config, _ = r.overrideFields(configName, config)
+	return config, true
+}
+
+// GetRawData returns the raw data of the wrapped repository, without the overrides.
+func (r *EnvOverrideRepository) GetRawData() []byte {
+	return r.Repository.GetRawData()
+}
+
+// Refresh refreshes the wrapped repository.
+func (r *EnvOverrideRepository) Refresh() error {
+	return r.Repository.Refresh()
+}
+
+// Load loads the wrapped repository if it is a Loader and returns its
+// configurations overridden by the variables set, the way GetData serves them.
+func (r *EnvOverrideRepository) Load() (map[string]interface{}, error) {
+	loader, ok := r.Repository.(Loader)
+	if !ok {
+		return nil, ErrUnloadableRepository
+	}
+	loaded, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{}, len(loaded))
+	for key, value := range loaded {
+		if override, ok := r.lookupEnv(key); ok {
+			data[key] = override
+			continue
+		}
+		data[key], _ = r.overrideFields(key, value)
+	}
+	return data, nil
+}
+
+// Version returns the version of the wrapped repository, if it is Versioned.
+func (r *EnvOverrideRepository) Version() string {
+	if versioned, ok := r.Repository.(Versioned); ok {
+		return versioned.Version()
+	}
+	return ""
+}
+
+// Keys returns the names of the configurations of the wrapped repository, if
+// it is a KeyLister.
+func (r *EnvOverrideRepository) Keys() []string {
+	if lister, ok := r.Repository.(KeyLister); ok {
+		return lister.Keys()
+	}
+	return nil
+}
+
+// Close closes the wrapped repository if it can be closed.
+func (r *EnvOverrideRepository) Close() error {
+	if closer, ok := r.Repository.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// EnvName returns the name of the variable overriding the configuration with
+// the given name.
+func (r *EnvOverrideRepository) EnvName(configName string) string {
+	prefix := r.Prefix
+	if prefix == "" {
+		prefix = DefaultEnvOverridePrefix
+	}
+	separator := r.Separator
+	if separator == "" {
+		separator = DefaultEnvSeparator
+	}
+	segments := strings.Split(configName, ".")
+	for i, segment := range segments {
+		segments[i] = strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z':
+				return r - 'a' + 'A'
+			case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+				return r
+			}
+			return '_'
+		}, segment)
+	}
+	return prefix + strings.Join(segments, separator)
+}
+
+// lookupEnv returns the parsed value of the variable overriding the
+// configuration with the given name, if it is set.
+func (r *EnvOverrideRepository) lookupEnv(configName string) (interface{}, bool) {
+	raw, ok := os.LookupEnv(r.EnvName(configName))
+	if !ok {
+		return nil, false
+	}
+	value, err := parseValue(r.Codec, []byte(raw))
+	if err != nil || value == nil {
+		return raw, true
+	}
+	return value, true
+}
+
+// overrideFields returns config with the fields of its maps, at any depth,
+// replaced by the variables overriding them, and whether any is set. Maps are
+// copied rather than modified, as they are shared with the wrapped repository.
+func (r *EnvOverrideRepository) overrideFields(configName string, config interface{}) (interface{}, bool) {
+	configMap, ok := config.(map[string]interface{})
+	if !ok {
+		return config, false
+	}
+	var overridden map[string]interface{}
+	for key, value := range configMap {
+		name := configName + "." + key
+		override, ok := r.lookupEnv(name)
+		if !ok {
+			override, ok = r.overrideFields(name, value)
+		}
+		if !ok {
+			continue
+		}
+		if overridden == nil {
+			overridden = make(map[string]interface{}, len(configMap))
+			for key, value := range configMap {
+				overridden[key] = value
+			}
+		}
+		overridden[key] = override
+	}
+	if overridden == nil {
+		return config, false
+	}
+	return overridden, true
+}
@@ -0,0 +1,92 @@
+package source
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvOverrideRepository(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "name: John\nretries: 3\ndatabase:\n  host: db.internal\n  port: 5432\n  pool:\n    size: 10\n")
+	repository := NewEnvOverrideRepository(&FileRepository{Name: "file", Path: path})
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// Absent variables pass through to the wrapped repository.
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+
+	// Set variables take precedence and are parsed with the codec.
+	t.Setenv("REMOTECONFIG_RETRIES", "5")
+	t.Setenv("REMOTECONFIG_DATABASE_HOST", "localhost")
+	t.Setenv("REMOTECONFIG_DATABASE_POOL_SIZE", "2")
+	t.Setenv("REMOTECONFIG_FEATURE_FLAGS", "{beta: true}")
+	retries, _ := repository.GetData("retries")
+	if retries != 5 {
+		t.Errorf("Expected retries to be overridden with 5, got %v", retries)
+	}
+	host, _ := repository.GetData("database.host")
+	if host != "localhost" {
+		t.Errorf("Expected database.host to be overridden with localhost, got %v", host)
+	}
+	flags, ok := repository.GetData("feature.flags")
+	flagsMap, isMap := flags.(map[string]interface{})
+	if !ok || !isMap || flagsMap["beta"] != true {
+		t.Errorf("Expected feature.flags to be parsed from the variable, got %v", flags)
+	}
+
+	// The fields of a map are overridden without modifying the wrapped data.
+	database, _ := repository.GetData("database")
+	databaseMap, _ := database.(map[string]interface{})
+	pool, _ := databaseMap["pool"].(map[string]interface{})
+	if databaseMap["host"] != "localhost" || databaseMap["port"] != 5432 || pool["size"] != 2 {
+		t.Errorf("Expected the fields of database to be overridden, got %v", database)
+	}
+	original, _ := repository.Repository.GetData("database")
+	if original.(map[string]interface{})["host"] != "db.internal" {
+		t.Errorf("Expected the wrapped data to be left unchanged, got %v", original)
+	}
+
+	// Load applies the overrides the way GetData does.
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	loadedDatabase, _ := loaded["database"].(map[string]interface{})
+	if loaded["retries"] != 5 || loaded["name"] != "John" || loadedDatabase["host"] != "localhost" {
+		t.Errorf("Expected the loaded data to be overridden, got %v", loaded)
+	}
+
+	// A value that does not parse is used as a string.
+	t.Setenv("REMOTECONFIG_NAME", "[unclosed")
+	name, _ = repository.GetData("name")
+	if name != "[unclosed" {
+		t.Errorf("Expected the raw value for a value that does not parse, got %v", name)
+	}
+}
+
+func TestEnvOverrideRepositoryEnvName(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []EnvOverrideOption
+		configKey string
+		expected  string
+	}{
+		{name: "nested", configKey: "database.host", expected: "REMOTECONFIG_DATABASE_HOST"},
+		{name: "punctuation", configKey: "feature-flags.new/ui", expected: "REMOTECONFIG_FEATURE_FLAGS_NEW_UI"},
+		{name: "prefix", opts: []EnvOverrideOption{WithEnvOverridePrefix("APP_")}, configKey: "name", expected: "APP_NAME"},
+		{name: "separator", opts: []EnvOverrideOption{WithEnvOverrideSeparator("__")}, configKey: "db.max_open", expected: "REMOTECONFIG_DB__MAX_OPEN"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repository := NewEnvOverrideRepository(&EnvRepository{}, test.opts...)
+			if got := repository.EnvName(test.configKey); got != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, got)
+			}
+		})
+	}
+}
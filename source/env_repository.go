@@ -0,0 +1,115 @@
+package source
+
+import (
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultEnvSeparator is the separator EnvRepository nests keys by when none is set.
+const DefaultEnvSeparator = "_"
+
+// EnvRepository is a struct that implements the Repository interface for
+// configuration data read from environment variables. Every variable whose
+// name starts with Prefix is imported under its name without the prefix,
+// lowercased and nested by Separator, so that APP_DB_HOST with the prefix
+// APP_ becomes the host field of the db configuration. Values are parsed as
+// YAML scalars, so numbers and booleans keep their types.
+// A variable whose nested name is a prefix of another's is ignored.
+type EnvRepository struct {
+	sync.RWMutex                        // RWMutex to synchronize access to data during refresh
+	Name         string                 // Name of the configuration source
+	Prefix       string                 // Prefix of the imported variables, stripped from the keys
+	Separator    string                 // Separator of nested keys, defaults to DefaultEnvSeparator
+	data         map[string]interface{} // Map to store the configuration data
+}
+
+// GetName returns the name of the configuration source.
+func (e *EnvRepository) GetName() string {
+	return e.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (e *EnvRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	e.RLock()
+	defer e.RUnlock()
+	config, isPresent = e.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, as environment variables have no raw document.
+func (e *EnvRepository) GetRawData() []byte {
+	return nil
+}
+
+// Keys returns the names of the imported configurations.
+func (e *EnvRepository) Keys() []string {
+	e.RLock()
+	defer e.RUnlock()
+	return sortedKeys(e.data)
+}
+
+// Refresh imports the environment variables matching Prefix.
+func (e *EnvRepository) Refresh() error {
+	data, _ := e.Load()
+	e.Lock()
+	defer e.Unlock()
+	e.data = data
+	return nil
+}
+
+// Load imports the environment variables matching Prefix the way Refresh
+// does and returns them, without replacing the loaded data. It never fails.
+func (e *EnvRepository) Load() (map[string]interface{}, error) {
+	separator := e.Separator
+	if separator == "" {
+		separator = DefaultEnvSeparator
+	}
+	environ := os.Environ()
+	// Sort so that conflicting names resolve the same way on every refresh.
+	sort.Strings(environ)
+	data := map[string]interface{}{}
+	for _, variable := range environ {
+		name, value, _ := strings.Cut(variable, "=")
+		if !strings.HasPrefix(name, e.Prefix) || name == e.Prefix {
+			continue
+		}
+		path := strings.Split(strings.ToLower(strings.TrimPrefix(name, e.Prefix)), separator)
+		setNested(data, path, envValue(value))
+	}
+	return data, nil
+}
+
+// setNested sets value at path in data, creating the intermediate maps. A
+// value is not set where a map already is, and a map replaces a value.
+func setNested(data map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := data[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			data[key] = next
+		}
+		data = next
+	}
+	last := path[len(path)-1]
+	if _, ok := data[last].(map[string]interface{}); ok {
+		return
+	}
+	data[last] = value
+}
+
+// envValue parses value as a YAML scalar, or returns it as is if it is
+// anything else, so that values like "a: b" stay strings.
+func envValue(value string) interface{} {
+	var parsed interface{}
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil || parsed == nil {
+		return value
+	}
+	switch parsed.(type) {
+	case map[string]interface{}, []interface{}:
+		return value
+	}
+	return parsed
+}
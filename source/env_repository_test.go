@@ -0,0 +1,65 @@
+package source
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnvRepository(t *testing.T) {
+	t.Setenv("APP_DB_HOST", "db.internal")
+	t.Setenv("APP_DB_PORT", "5432")
+	t.Setenv("APP_DEBUG", "true")
+	t.Setenv("APP_GREETING", "a: b")
+	t.Setenv("OTHER_NAME", "ignored")
+
+	repository := &EnvRepository{Name: "env", Prefix: "APP_"}
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	db, _ := repository.GetData("db")
+	expected := map[string]interface{}{"host": "db.internal", "port": 5432}
+	if !reflect.DeepEqual(db, expected) {
+		t.Errorf("Expected db to be %v, got %v", expected, db)
+	}
+	debug, _ := repository.GetData("debug")
+	if debug != true {
+		t.Errorf("Expected debug to be true, got %v", debug)
+	}
+	greeting, _ := repository.GetData("greeting")
+	if greeting != "a: b" {
+		t.Errorf("Expected non-scalar values to stay strings, got %v", greeting)
+	}
+	if _, ok := repository.GetData("name"); ok {
+		t.Errorf("Expected variables without the prefix to be ignored")
+	}
+
+	// Load imports the variables without replacing the data.
+	t.Setenv("APP_DEBUG", "false")
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	if loaded["debug"] != false {
+		t.Errorf("Expected loaded debug to be false, got %v", loaded["debug"])
+	}
+	debug, _ = repository.GetData("debug")
+	if debug != true {
+		t.Errorf("Expected debug to stay true, got %v", debug)
+	}
+}
+
+func TestEnvRepositorySeparator(t *testing.T) {
+	t.Setenv("APP_DB_PRIMARY__HOST", "db.internal")
+
+	repository := &EnvRepository{Name: "env", Prefix: "APP_", Separator: "__"}
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	db, _ := repository.GetData("db_primary")
+	expected := map[string]interface{}{"host": "db.internal"}
+	if !reflect.DeepEqual(db, expected) {
+		t.Errorf("Expected db_primary to be %v, got %v", expected, db)
+	}
+}
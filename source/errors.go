@@ -0,0 +1,211 @@
+package source
+
+import (
+	"cloud.google.com/go/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error kinds that NormalizeError maps backend errors to, so callers can
+// branch on the class of a failure regardless of the repository.
+var (
+	// ErrSourceNotFound means the file, object, bucket or secret does not exist.
+	ErrSourceNotFound = errors.New("source not found")
+	// ErrSourceUnauthorized means the credentials were missing, invalid or not allowed to read the source.
+	ErrSourceUnauthorized = errors.New("source unauthorized")
+	// ErrSourceTimeout means the source did not respond in time.
+	ErrSourceTimeout = errors.New("source timeout")
+	// ErrSourceUnavailable means the source could not be reached or failed on its side.
+	ErrSourceUnavailable = errors.New("source unavailable")
+)
+
+// ErrTruncated is returned by repositories that fetch over the network when
+// the file received is shorter than its declared length or lacks its
+// trailing marker. The current data is kept.
+var ErrTruncated = errors.New("truncated download")
+
+// KeyErrors is returned by the Refresh of a repository that tolerates key
+// errors when the values of some keys do not parse. The keys that parsed are
+// applied anyway, while those that did not keep their previous values, or
+// are left out if they had none.
+type KeyErrors struct {
+	Errors map[string]error // Error of every key whose value did not parse
+}
+
+func (e *KeyErrors) Error() string {
+	keys := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	messages := make([]string, len(keys))
+	for i, key := range keys {
+		messages[i] = key + ": " + e.Errors[key].Error()
+	}
+	return fmt.Sprintf("%d keys did not parse: %s", len(keys), strings.Join(messages, "; "))
+}
+
+// StatusError is returned by repositories that fetch over HTTP when the
+// response has a status code outside 2xx.
+type StatusError struct {
+	StatusCode int           // Status code of the response
+	URL        string        // Redacted URL of the request
+	RetryAfter time.Duration // Delay the server asked to wait before retrying, from the Retry-After header of a 429 or 503 response
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d from %s", e.StatusCode, e.URL)
+}
+
+// HTTPStatusCode returns the status code of the response.
+func (e *StatusError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+// newStatusError returns the StatusError of resp, the response to a request
+// of u.
+func newStatusError(resp *http.Response, u *url.URL) *StatusError {
+	statusErr := &StatusError{StatusCode: resp.StatusCode, URL: RedactURL(u)}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		statusErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	return statusErr
+}
+
+// parseRetryAfter returns the delay of a Retry-After header at now, which
+// holds either a number of seconds or an HTTP date. It returns 0 for a
+// missing or invalid header, or a date that has passed.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	date, err := http.ParseTime(value)
+	if err != nil || !date.After(now) {
+		return 0
+	}
+	return date.Sub(now)
+}
+
+// RetryAfter returns the delay the source asked to wait before retrying the
+// request that failed with err, or 0 if it did not ask for one. Errors report
+// a delay as a StatusError or with a RetryAfter method.
+func RetryAfter(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	var retryErr interface{ RetryAfter() time.Duration }
+	if errors.As(err, &retryErr) {
+		return retryErr.RetryAfter()
+	}
+	return 0
+}
+
+// normalizedError is an error tagged with the kind it was classified as. It
+// keeps the message of the original error and unwraps to it.
+type normalizedError struct {
+	kind error
+	err  error
+}
+
+func (e *normalizedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *normalizedError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *normalizedError) Unwrap() error {
+	return e.err
+}
+
+// NormalizeError returns err tagged with the error kind it belongs to, so
+// that errors.Is(err, ErrSourceNotFound) and the like work for errors of
+// every repository. Errors that match no kind are returned unchanged.
+func NormalizeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	kind := classifyError(err)
+	if kind == nil {
+		return err
+	}
+	return &normalizedError{kind: kind, err: err}
+}
+
+// classifyError returns the error kind err belongs to, or nil.
+func classifyError(err error) error {
+	for _, kind := range []error{ErrSourceNotFound, ErrSourceUnauthorized, ErrSourceTimeout, ErrSourceUnavailable} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, ErrSecretNotFound),
+		errors.Is(err, storage.ErrObjectNotExist), errors.Is(err, storage.ErrBucketNotExist):
+		return ErrSourceNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return ErrSourceUnauthorized
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrSourceTimeout
+	case errors.Is(err, ErrTruncated), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrSourceUnavailable
+	}
+	if kind := classifyStatusCode(err); kind != nil {
+		return kind
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrSourceTimeout
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	var urlErr *url.Error
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) || errors.As(err, &urlErr) {
+		return ErrSourceUnavailable
+	}
+	return nil
+}
+
+// classifyStatusCode returns the error kind of the HTTP status code carried
+// by err, as reported by StatusError, the AWS SDK and the Azure SDK.
+func classifyStatusCode(err error) error {
+	statusCode := 0
+	var statusErr interface{ HTTPStatusCode() int }
+	var azureErr *azcore.ResponseError
+	switch {
+	case errors.As(err, &statusErr):
+		statusCode = statusErr.HTTPStatusCode()
+	case errors.As(err, &azureErr):
+		statusCode = azureErr.StatusCode
+	}
+	switch {
+	case statusCode == http.StatusNotFound:
+		return ErrSourceNotFound
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		return ErrSourceUnauthorized
+	case statusCode == http.StatusRequestTimeout, statusCode == http.StatusGatewayTimeout:
+		return ErrSourceTimeout
+	case statusCode >= 500:
+		return ErrSourceUnavailable
+	}
+	return nil
+}
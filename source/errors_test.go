@@ -0,0 +1,126 @@
+package source
+
+import (
+	"cloud.google.com/go/storage"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// timeoutError is a net.Error that reports a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestNormalizeError(t *testing.T) {
+	_, missingFile := os.ReadFile("does-not-exist.yaml")
+	azureNotFound := &azcore.ResponseError{StatusCode: http.StatusNotFound, ErrorCode: "SecretNotFound", RawResponse: &http.Response{
+		StatusCode: http.StatusNotFound,
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    &http.Request{Method: http.MethodGet, URL: &url.URL{Scheme: "https", Host: "example.vault.azure.net"}},
+	}}
+	awsForbidden := &smithyhttp.ResponseError{Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusForbidden}}, Err: errors.New("AccessDenied")}
+
+	testCases := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "missing file", err: missingFile, want: ErrSourceNotFound},
+		{name: "http 404", err: &StatusError{StatusCode: http.StatusNotFound}, want: ErrSourceNotFound},
+		{name: "http 403", err: &StatusError{StatusCode: http.StatusForbidden}, want: ErrSourceUnauthorized},
+		{name: "http 503", err: &StatusError{StatusCode: http.StatusServiceUnavailable}, want: ErrSourceUnavailable},
+		{name: "aws forbidden", err: fmt.Errorf("operation error S3: GetObject: %w", awsForbidden), want: ErrSourceUnauthorized},
+		{name: "azure not found", err: azureNotFound, want: ErrSourceNotFound},
+		{name: "gcs object", err: storage.ErrObjectNotExist, want: ErrSourceNotFound},
+		{name: "secret", err: ErrSecretNotFound, want: ErrSourceNotFound},
+		{name: "deadline", err: fmt.Errorf("refresh: %w", context.DeadlineExceeded), want: ErrSourceTimeout},
+		{name: "net timeout", err: &url.Error{Op: "Get", URL: "http://config", Err: timeoutError{}}, want: ErrSourceTimeout},
+		{name: "connection refused", err: &url.Error{Op: "Get", URL: "http://config", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, want: ErrSourceUnavailable},
+		{name: "unknown", err: errors.New("boom"), want: nil},
+	}
+	for _, tc := range testCases {
+		normalized := NormalizeError(tc.err)
+		if tc.want == nil {
+			if normalized != tc.err {
+				t.Errorf("Expected %s to be returned unchanged, got %v", tc.name, normalized)
+			}
+			continue
+		}
+		if !errors.Is(normalized, tc.want) {
+			t.Errorf("Expected %s to be normalized to %v, got %v", tc.name, tc.want, normalized)
+		}
+		if !errors.Is(normalized, tc.err) || normalized.Error() != tc.err.Error() {
+			t.Errorf("Expected %s to keep the original error, got %v", tc.name, normalized)
+		}
+	}
+	if NormalizeError(nil) != nil {
+		t.Errorf("Expected nil to stay nil")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"-1", 0},
+		{"soon", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+	}
+	for _, test := range tests {
+		if got := parseRetryAfter(test.value, now); got != test.expected {
+			t.Errorf("Expected %q to be %s, got %s", test.value, test.expected, got)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	status := http.StatusTooManyRequests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Error parsing url: %s", err.Error())
+	}
+	repository := &WebRepository{Name: "web", URL: u}
+
+	err = repository.Refresh()
+	if delay := RetryAfter(err); delay != 7*time.Second {
+		t.Errorf("Expected a delay of 7s, got %s (%v)", delay, err)
+	}
+	if delay := RetryAfter(fmt.Errorf("wrapped: %w", err)); delay != 7*time.Second {
+		t.Errorf("Expected the delay of a wrapped error to be 7s, got %s", delay)
+	}
+
+	// The header is only honored for 429 and 503 responses.
+	status = http.StatusInternalServerError
+	err = repository.Refresh()
+	if delay := RetryAfter(err); err == nil || delay != 0 {
+		t.Errorf("Expected no delay for a 500 response, got %s (%v)", delay, err)
+	}
+	if delay := RetryAfter(errors.New("other")); delay != 0 {
+		t.Errorf("Expected no delay for other errors, got %s", delay)
+	}
+}
@@ -0,0 +1,394 @@
+package source
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"github.com/sirupsen/logrus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultEtcdDialTimeout bounds the time an EtcdRepository waits for a
+// connection to the cluster.
+const DefaultEtcdDialTimeout = 5 * time.Second
+
+// EtcdKeyValue is a key of etcd and its value.
+type EtcdKeyValue struct {
+	Key   string // Full key
+	Value []byte // Value of the key
+}
+
+// EtcdEvent is a change of a key of etcd received from an EtcdWatch.
+type EtcdEvent struct {
+	Key      string // Full key
+	Value    []byte // New value of the key
+	Deleted  bool   // Whether the key was deleted
+	Revision int64  // Revision of the store after the change
+}
+
+// EtcdWatch is a stream of the changes of the keys under a prefix.
+type EtcdWatch interface {
+	// Recv blocks until the next change and returns an error once the watch has ended.
+	Recv() (EtcdEvent, error)
+}
+
+// EtcdAPI is the subset of etcd v3 operations used by EtcdRepository.
+type EtcdAPI interface {
+	// Get returns the keys under prefix and the revision of the store they were read at.
+	Get(ctx context.Context, prefix string) ([]EtcdKeyValue, int64, error)
+	// Watch streams the changes of the keys under prefix from the given
+	// revision on. The watch must end when ctx is canceled.
+	Watch(ctx context.Context, prefix string, revision int64) EtcdWatch
+}
+
+// EtcdRepository is a struct that implements the Repository interface for
+// handling configuration data stored in etcd v3. Every key under Prefix is
+// read on refresh with a ranged Get, and its value is parsed with Codec. Keys
+// are nested by "/", so the keys `app/db/host` and `app/db/port` under the
+// prefix `app/` form the configuration `db` with the fields host and port.
+// With Watch set, the repository also watches the prefix after the first
+// refresh and applies changes as they are made; when the watch breaks, it is
+// started again by the next refresh, so the data is still kept current by
+// polling in the meantime. A failed refresh leaves the current data in place,
+// unless TolerateKeyErrors is set, in which case only the keys whose values
+// do not parse do.
+// Close, which Client.Close calls, stops the watch.
+type EtcdRepository struct {
+	sync.RWMutex                             // RWMutex to synchronize access to data
+	changeNotifier                           // Notifies the changes applied by the watch
+	Name              string                 // Name of the configuration source
+	Endpoints         []string               // Endpoints of the etcd cluster
+	Prefix            string                 // Prefix of the keys to read, stripped from the configuration names
+	TLS               *tls.Config            // TLS configuration of the connection, if the cluster requires TLS
+	Username          string                 // User to authenticate as, if the cluster has authentication enabled
+	Password          string                 // Password of the user
+	Watch             bool                   // Whether to watch the prefix for changes between refreshes
+	Client            EtcdAPI                // etcd client, created from the endpoints by NewEtcdRepository
+	Codec             Codec                  // Codec used to parse each value, defaults to YAMLCodec, which also parses JSON
+	TolerateKeyErrors bool                   // Whether keys whose value does not parse keep their previous value instead of failing the refresh
+	data              map[string]interface{} // Map to store the configuration data
+	values            map[string]interface{} // Parsed value of every key, by name relative to the prefix
+	revision          int64                  // Revision of the store the data was read at
+	watching          bool                   // Whether the watch loop is running
+	closed            bool                   // Whether Close has been called
+	cancel            context.CancelFunc     // Cancels the watch loop
+	done              chan struct{}          // Closed when the watch loop returns
+}
+
+// EtcdOption configures an EtcdRepository created with NewEtcdRepository.
+type EtcdOption func(*EtcdRepository)
+
+// WithEtcdTLS connects to the cluster over TLS with the given configuration,
+// which may hold a client certificate.
+func WithEtcdTLS(config *tls.Config) EtcdOption {
+	return func(r *EtcdRepository) {
+		r.TLS = config
+	}
+}
+
+// WithEtcdAuth authenticates with the given user and password.
+func WithEtcdAuth(username string, password string) EtcdOption {
+	return func(r *EtcdRepository) {
+		r.Username = username
+		r.Password = password
+	}
+}
+
+// WithEtcdWatch watches the prefix for changes between refreshes.
+func WithEtcdWatch() EtcdOption {
+	return func(r *EtcdRepository) {
+		r.Watch = true
+	}
+}
+
+// WithEtcdTolerateKeyErrors applies the keys that parse when the values of
+// others do not, instead of failing the whole refresh. The keys that did not
+// parse keep their previous values, and Refresh returns a KeyErrors of them.
+func WithEtcdTolerateKeyErrors() EtcdOption {
+	return func(r *EtcdRepository) {
+		r.TolerateKeyErrors = true
+	}
+}
+
+// WithEtcdClient sets the client used to read the keys.
+func WithEtcdClient(client EtcdAPI) EtcdOption {
+	return func(r *EtcdRepository) {
+		r.Client = client
+	}
+}
+
+// NewEtcdRepository creates an EtcdRepository reading the keys under prefix
+// from the etcd cluster at endpoints.
+func NewEtcdRepository(name string, endpoints []string, prefix string, opts ...EtcdOption) (*EtcdRepository, error) {
+	repository := &EtcdRepository{
+		Name:      name,
+		Endpoints: endpoints,
+		Prefix:    prefix,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	if repository.Client == nil {
+		client, err := clientv3.New(clientv3.Config{
+			Endpoints:   endpoints,
+			TLS:         repository.TLS,
+			Username:    repository.Username,
+			Password:    repository.Password,
+			DialTimeout: DefaultEtcdDialTimeout,
+		})
+		if err != nil {
+			return nil, err
+		}
+		repository.Client = &etcdClient{client: client}
+	}
+	return repository, nil
+}
+
+// GetName returns the name of the configuration source.
+func (r *EtcdRepository) GetName() string {
+	return r.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (r *EtcdRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	r.RLock()
+	defer r.RUnlock()
+	config, isPresent = r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, as the configuration is read key by key.
+func (r *EtcdRepository) GetRawData() []byte {
+	return nil
+}
+
+// Version returns the revision of the store the data was read at.
+func (r *EtcdRepository) Version() string {
+	r.RLock()
+	defer r.RUnlock()
+	if r.revision == 0 {
+		return ""
+	}
+	return strconv.FormatInt(r.revision, 10)
+}
+
+// Keys returns the names of the top-level configurations.
+func (r *EtcdRepository) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.data)
+}
+
+// Refresh reads every key under the prefix and replaces the data map with
+// them. With Watch set, it starts the watch if it is not running. With
+// TolerateKeyErrors set, a value that does not parse is reported in a
+// KeyErrors after the other keys are applied.
+func (r *EtcdRepository) Refresh() error {
+	raw, revision, err := r.get()
+	if err != nil {
+		return err
+	}
+
+	// Parse the values into a new map, so that a value that does not parse
+	// leaves the current data in place.
+	r.RLock()
+	previous := r.values
+	r.RUnlock()
+	values, err := parseValues(r.Codec, raw, previous, r.TolerateKeyErrors)
+	var keyErrors *KeyErrors
+	if err != nil && !errors.As(err, &keyErrors) {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	// The watch may have applied changes made after the keys were read.
+	if revision >= r.revision {
+		r.values = values
+		r.data = nestValues(values)
+		r.revision = revision
+	}
+	if r.Watch && !r.watching && !r.closed {
+		ctx, cancel := context.WithCancel(context.Background())
+		r.watching = true
+		r.cancel = cancel
+		r.done = make(chan struct{})
+		go r.watch(ctx, r.Client.Watch(ctx, r.Prefix, r.revision+1))
+	}
+	return err
+}
+
+// Load reads every key under the prefix the way Refresh does and returns the
+// configurations they form, without replacing the loaded data or starting the
+// watch. A value that does not parse fails Load, even with TolerateKeyErrors.
+func (r *EtcdRepository) Load() (map[string]interface{}, error) {
+	raw, _, err := r.get()
+	if err != nil {
+		return nil, err
+	}
+	values, err := parseValues(r.Codec, raw, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return nestValues(values), nil
+}
+
+// get reads every key under the prefix and returns their values by
+// configuration name, with the revision of the store they were read at.
+func (r *EtcdRepository) get() (map[string][]byte, int64, error) {
+	kvs, revision, err := r.Client.Get(context.Background(), r.Prefix)
+	if err != nil {
+		logrus.Debug("error getting keys")
+		return nil, 0, err
+	}
+	raw := map[string][]byte{}
+	for _, kv := range kvs {
+		if name, ok := r.name(kv.Key); ok {
+			raw[name] = kv.Value
+		}
+	}
+	return raw, revision, nil
+}
+
+// Close stops the watch and waits for it to return, then closes the client
+// if it can be closed.
+func (r *EtcdRepository) Close() error {
+	r.Lock()
+	r.closed = true
+	cancel, done := r.cancel, r.done
+	r.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	if closer, ok := r.Client.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// name returns the configuration name of key, relative to the prefix, and
+// whether key names a configuration rather than the prefix itself.
+func (r *EtcdRepository) name(key string) (string, bool) {
+	name := strings.Trim(strings.TrimPrefix(key, r.Prefix), "/")
+	return name, name != "" && !strings.HasSuffix(key, "/")
+}
+
+// watch applies the changes of watch until it ends. The next refresh then
+// starts a new watch.
+func (r *EtcdRepository) watch(ctx context.Context, watch EtcdWatch) {
+	defer close(r.done)
+	for {
+		event, err := watch.Recv()
+		if err != nil {
+			if ctx.Err() == nil {
+				logrus.WithError(err).Warn("etcd watch broken, polling until the next refresh")
+			}
+			r.Lock()
+			r.watching = false
+			r.Unlock()
+			return
+		}
+		if r.apply(event) {
+			r.notifyChange()
+		}
+	}
+}
+
+// apply applies a change received from the watch to the data map, and
+// reports whether it did. A value that does not parse is skipped, and
+// reported by the next refresh.
+func (r *EtcdRepository) apply(event EtcdEvent) bool {
+	name, ok := r.name(event.Key)
+	if !ok {
+		return false
+	}
+	var value interface{}
+	if !event.Deleted {
+		var err error
+		value, err = parseValue(r.Codec, event.Value)
+		if err != nil {
+			logrus.WithField("key", event.Key).Warn("error unmarshalling watched value")
+			return false
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	// Skip the changes a refresh has loaded since. A transaction changes
+	// several keys at one revision, so changes at the loaded revision apply.
+	if event.Revision < r.revision {
+		return false
+	}
+	if event.Deleted {
+		delete(r.values, name)
+	} else {
+		r.values[name] = value
+	}
+	r.data = nestValues(r.values)
+	r.revision = event.Revision
+	return true
+}
+
+// etcdClient implements EtcdAPI with the etcd v3 client.
+type etcdClient struct {
+	client *clientv3.Client
+}
+
+func (e *etcdClient) Get(ctx context.Context, prefix string) ([]EtcdKeyValue, int64, error) {
+	response, err := e.client.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, 0, err
+	}
+	kvs := make([]EtcdKeyValue, 0, len(response.Kvs))
+	for _, kv := range response.Kvs {
+		kvs = append(kvs, EtcdKeyValue{Key: string(kv.Key), Value: kv.Value})
+	}
+	return kvs, response.Header.Revision, nil
+}
+
+func (e *etcdClient) Watch(ctx context.Context, prefix string, revision int64) EtcdWatch {
+	return &etcdWatch{channel: e.client.Watch(ctx, prefix, clientv3.WithPrefix(), clientv3.WithRev(revision))}
+}
+
+func (e *etcdClient) Close() error {
+	return e.client.Close()
+}
+
+// errWatchClosed is returned by an etcdWatch whose channel was closed.
+var errWatchClosed = errors.New("etcd watch closed")
+
+// etcdWatch implements EtcdWatch on the channel of a clientv3 watch,
+// returning the events of each response one at a time.
+type etcdWatch struct {
+	channel clientv3.WatchChan
+	pending []EtcdEvent
+}
+
+func (w *etcdWatch) Recv() (EtcdEvent, error) {
+	for len(w.pending) == 0 {
+		response, ok := <-w.channel
+		if !ok {
+			return EtcdEvent{}, errWatchClosed
+		}
+		if err := response.Err(); err != nil {
+			return EtcdEvent{}, err
+		}
+		for _, event := range response.Events {
+			w.pending = append(w.pending, EtcdEvent{
+				Key:      string(event.Kv.Key),
+				Value:    event.Kv.Value,
+				Deleted:  event.Type == clientv3.EventTypeDelete,
+				Revision: event.Kv.ModRevision,
+			})
+		}
+	}
+	event := w.pending[0]
+	w.pending = w.pending[1:]
+	return event, nil
+}
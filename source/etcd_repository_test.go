@@ -0,0 +1,273 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEtcd is an in-memory EtcdAPI. Every change bumps the revision and is
+// sent to the open watches.
+type fakeEtcd struct {
+	mu       sync.Mutex
+	kvs      map[string]string
+	revision int64
+	watches  []chan EtcdEvent
+	getErr   error
+}
+
+func newFakeEtcd(kvs map[string]string) *fakeEtcd {
+	return &fakeEtcd{kvs: kvs, revision: 1}
+}
+
+func (f *fakeEtcd) change(event EtcdEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revision++
+	event.Revision = f.revision
+	if event.Deleted {
+		delete(f.kvs, event.Key)
+	} else {
+		f.kvs[event.Key] = string(event.Value)
+	}
+	for _, watch := range f.watches {
+		watch <- event
+	}
+}
+
+// breakWatches ends every open watch, as a lost connection would.
+func (f *fakeEtcd) breakWatches() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, watch := range f.watches {
+		close(watch)
+	}
+	f.watches = nil
+}
+
+func (f *fakeEtcd) watchCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.watches)
+}
+
+func (f *fakeEtcd) Get(_ context.Context, _ string) ([]EtcdKeyValue, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.getErr != nil {
+		return nil, 0, f.getErr
+	}
+	var kvs []EtcdKeyValue
+	for key, value := range f.kvs {
+		kvs = append(kvs, EtcdKeyValue{Key: key, Value: []byte(value)})
+	}
+	return kvs, f.revision, nil
+}
+
+func (f *fakeEtcd) Watch(ctx context.Context, _ string, _ int64) EtcdWatch {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events := make(chan EtcdEvent, 10)
+	f.watches = append(f.watches, events)
+	return &fakeEtcdWatch{ctx: ctx, events: events}
+}
+
+type fakeEtcdWatch struct {
+	ctx    context.Context
+	events chan EtcdEvent
+}
+
+func (w *fakeEtcdWatch) Recv() (EtcdEvent, error) {
+	select {
+	case <-w.ctx.Done():
+		return EtcdEvent{}, w.ctx.Err()
+	case event, ok := <-w.events:
+		if !ok {
+			return EtcdEvent{}, errors.New("watch broken")
+		}
+		return event, nil
+	}
+}
+
+func TestEtcdRepository(t *testing.T) {
+	etcd := newFakeEtcd(map[string]string{
+		"app/name":     "John",
+		"app/db/host":  "localhost",
+		"app/db/port":  "5432",
+		"app/features": `{"beta": true}`,
+	})
+	repository, err := NewEtcdRepository("etcd", []string{"localhost:2379"}, "app/", WithEtcdClient(etcd))
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+	db, _ := repository.GetData("db")
+	dbMap, ok := db.(map[string]interface{})
+	if !ok || dbMap["host"] != "localhost" || dbMap["port"] != 5432 {
+		t.Errorf("Expected db to be nested with host and port, got %v", db)
+	}
+	features, _ := repository.GetData("features")
+	if featuresMap, ok := features.(map[string]interface{}); !ok || featuresMap["beta"] != true {
+		t.Errorf("Expected features to be decoded from JSON, got %v", features)
+	}
+	if repository.Version() != "1" {
+		t.Errorf("Expected version 1, got %s", repository.Version())
+	}
+
+	// Without a watch, changes are loaded by the next refresh.
+	etcd.change(EtcdEvent{Key: "app/name", Value: []byte("Jack")})
+	name, _ = repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John until the refresh, got %v", name)
+	}
+	_ = repository.Refresh()
+	name, _ = repository.GetData("name")
+	if name != "Jack" {
+		t.Errorf("Expected name to be Jack, got %v", name)
+	}
+
+	// Load reads the keys without replacing the data.
+	etcd.change(EtcdEvent{Key: "app/name", Value: []byte("Jill")})
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	if loaded["name"] != "Jill" {
+		t.Errorf("Expected loaded name to be Jill, got %v", loaded["name"])
+	}
+	if loadedDB, ok := loaded["db"].(map[string]interface{}); !ok || loadedDB["host"] != "localhost" {
+		t.Errorf("Expected loaded db to be nested, got %v", loaded["db"])
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jack" {
+		t.Errorf("Expected name to stay Jack, got %v", name)
+	}
+	etcd.change(EtcdEvent{Key: "app/name", Value: []byte("Jack")})
+
+	// The last good data is kept when a refresh fails.
+	etcd.mu.Lock()
+	etcd.getErr = errors.New("unavailable")
+	etcd.mu.Unlock()
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for an unavailable cluster, got nil")
+	}
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected Load to fail for an unavailable cluster, got nil")
+	}
+	etcd.mu.Lock()
+	etcd.getErr = nil
+	etcd.mu.Unlock()
+	etcd.change(EtcdEvent{Key: "app/broken", Value: []byte("key: [unclosed")})
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a value that does not parse, got nil")
+	}
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected Load to fail for a value that does not parse, got nil")
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jack" {
+		t.Errorf("Expected the last good data to be kept, got %v", name)
+	}
+	_ = repository.Close()
+}
+
+func TestEtcdRepositoryTolerateKeyErrors(t *testing.T) {
+	etcd := newFakeEtcd(map[string]string{"app/name": "John", "app/db/port": "5432"})
+	repository, _ := NewEtcdRepository("etcd", []string{"localhost:2379"}, "app/", WithEtcdClient(etcd), WithEtcdTolerateKeyErrors())
+	if err := repository.Refresh(); err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	etcd.change(EtcdEvent{Key: "app/name", Value: []byte("Jack")})
+	etcd.change(EtcdEvent{Key: "app/db/port", Value: []byte("[unclosed")})
+	err := repository.Refresh()
+	var keyErrors *KeyErrors
+	if !errors.As(err, &keyErrors) || len(keyErrors.Errors) != 1 || keyErrors.Errors["db/port"] == nil {
+		t.Fatalf("Expected the error of db/port, got %v", err)
+	}
+	if name, _ := repository.GetData("name"); name != "Jack" {
+		t.Errorf("Expected the key that parsed to be applied, got %v", name)
+	}
+	db, _ := repository.GetData("db")
+	if dbMap, ok := db.(map[string]interface{}); !ok || dbMap["port"] != 5432 {
+		t.Errorf("Expected db/port to keep its previous value, got %v", db)
+	}
+}
+
+func TestEtcdRepositoryWatch(t *testing.T) {
+	etcd := newFakeEtcd(map[string]string{"app/name": "John", "app/db/host": "localhost"})
+	repository, err := NewEtcdRepository("etcd", []string{"localhost:2379"}, "app/", WithEtcdClient(etcd), WithEtcdWatch())
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	defer repository.Close()
+	notified := notifications(repository)
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// Changes are applied without a refresh, and notified.
+	etcd.change(EtcdEvent{Key: "app/name", Value: []byte("Jack")})
+	waitForData(t, repository, "name", "Jack")
+	waitForNotification(t, notified)
+	etcd.change(EtcdEvent{Key: "app/db/host", Deleted: true})
+	deadline := time.Now().Add(2 * time.Second)
+	for _, ok := repository.GetData("db"); ok; _, ok = repository.GetData("db") {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected db to be deleted by the watch, timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if repository.Version() != "3" {
+		t.Errorf("Expected version 3, got %s", repository.Version())
+	}
+
+	// A broken watch falls back to polling and is started again by the next refresh.
+	etcd.breakWatches()
+	deadline = time.Now().Add(2 * time.Second)
+	for {
+		repository.RLock()
+		watching := repository.watching
+		repository.RUnlock()
+		if !watching {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the broken watch to stop, timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	etcd.change(EtcdEvent{Key: "app/name", Value: []byte("Jane")})
+	_ = repository.Refresh()
+	name, _ := repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected name to be polled as Jane, got %v", name)
+	}
+	if etcd.watchCount() != 1 {
+		t.Errorf("Expected the refresh to start a new watch, got %d watches", etcd.watchCount())
+	}
+	etcd.change(EtcdEvent{Key: "app/name", Value: []byte("Jill")})
+	waitForData(t, repository, "name", "Jill")
+
+	// Close stops the watch.
+	done := make(chan struct{})
+	go func() {
+		_ = repository.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected Close to stop the watch, timed out")
+	}
+}
@@ -0,0 +1,97 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ExtendsKey is the top-level key a configuration file can set to inherit
+// from one or more base files, given as a path or a list of paths relative to
+// the file. The bases are loaded first, in order, and the file is deep-merged
+// over them, so its values override theirs.
+const ExtendsKey = "extends"
+
+// ErrCircularExtends is returned when configuration files extend each other in a cycle.
+var ErrCircularExtends = errors.New("circular extends")
+
+// readFileFunc reads the file at path from the filesystem of a repository.
+type readFileFunc func(path string) ([]byte, error)
+
+// resolveExtends merges the base files that the file at path, with the parsed
+// data, extends beneath it. It returns data unchanged if the file extends
+// nothing. chain holds the files being resolved, to detect cycles.
+func resolveExtends(path string, data map[string]interface{}, codec Codec, readFile readFileFunc, chain []string) (map[string]interface{}, error) {
+	extends, ok := data[ExtendsKey]
+	if !ok {
+		return data, nil
+	}
+	var parents []string
+	switch extends := extends.(type) {
+	case string:
+		parents = []string{extends}
+	case []interface{}:
+		for _, parent := range extends {
+			parent, ok := parent.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s of %s must be a path or a list of paths", ExtendsKey, path)
+			}
+			parents = append(parents, parent)
+		}
+	default:
+		return nil, fmt.Errorf("%s of %s must be a path or a list of paths", ExtendsKey, path)
+	}
+
+	merged := map[string]interface{}{}
+	for _, parent := range parents {
+		if !filepath.IsAbs(parent) {
+			parent = filepath.Join(filepath.Dir(path), parent)
+		}
+		parentData, err := loadExtended(parent, codec, readFile, chain)
+		if err != nil {
+			return nil, err
+		}
+		merged = deepMerge(merged, parentData)
+	}
+	delete(data, ExtendsKey)
+	return deepMerge(merged, data), nil
+}
+
+// loadExtended reads and parses the file at path and resolves what it extends.
+func loadExtended(path string, codec Codec, readFile readFileFunc, chain []string) (map[string]interface{}, error) {
+	path = filepath.Clean(path)
+	for _, seen := range chain {
+		if seen == path {
+			return nil, fmt.Errorf("%w: %s", ErrCircularExtends, strings.Join(append(chain, path), " -> "))
+		}
+	}
+	raw, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]interface{}
+	_, err = unmarshalIncludes(path, raw, codec, readFile, &data)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return resolveExtends(path, data, codec, readFile, append(chain[:len(chain):len(chain)], path))
+}
+
+// deepMerge merges override into base and returns base. Nested maps are
+// merged recursively; any other value of override replaces the one of base.
+func deepMerge(base, override map[string]interface{}) map[string]interface{} {
+	for key, value := range override {
+		overrideMap, ok := value.(map[string]interface{})
+		baseMap, baseOK := base[key].(map[string]interface{})
+		if ok && baseOK {
+			base[key] = deepMerge(baseMap, overrideMap)
+			continue
+		}
+		base[key] = value
+	}
+	return base
+}
@@ -1,20 +1,41 @@
 package source
 
 import (
+	"github.com/fsnotify/fsnotify"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
 	"os"
+	"path/filepath"
 	"sync"
+	"time"
 )
 
+// DefaultParseRetryDelay is how long a FileRepository waits before rereading a
+// file that failed to parse or was empty, in case it was read while being
+// written or swapped.
+const DefaultParseRetryDelay = 100 * time.Millisecond
+
 // FileRepository is a struct that implements the Repository interface for
-// handling configuration data stored in a YAML file.
+// handling configuration data stored in a file. With Watch set, the first
+// refresh also starts watching the directory of the file, and the file is
+// reloaded as soon as it is written or replaced, as editors that save by
+// renaming a new file over the old one do. A reload that fails, for example
+// because the edited file does not parse, is logged and leaves the current
+// data in place. Close, which Client.Close calls, stops the watch.
 type FileRepository struct {
-	sync.RWMutex                        // RWMutex to synchronize access to data during refresh
-	Name         string                 // Name of the configuration source
-	Path         string                 // File path of the YAML configuration file
-	data         map[string]interface{} // Map to store the configuration data
-	rawData      []byte                 // Raw data of the YAML configuration file
+	sync.RWMutex                           // RWMutex to synchronize access to data during refresh
+	changeNotifier                         // Notifies the reloads of the watch
+	refreshMu       sync.Mutex             // Serializes refreshes, which read the file without holding the RWMutex
+	Name            string                 // Name of the configuration source
+	Path            string                 // File path of the configuration file
+	data            map[string]interface{} // Map to store the configuration data
+	rawData         []byte                 // Raw data of the configuration file
+	version         string                 // Version marker of the currently loaded data
+	Codec           Codec                  // Codec used to parse the file, defaults to YAMLCodec
+	ParseRetryDelay time.Duration          // Delay before rereading a file that failed to parse or was empty, defaults to DefaultParseRetryDelay
+	Watch           bool                   // Whether to reload the file as soon as it changes, in addition to on refresh
+	watcher         *fsnotify.Watcher      // Watcher of the directory of the file, nil until the watch is started
+	closed          bool                   // Whether Close has been called
+	done            chan struct{}          // Closed when the watch loop returns
 }
 
 // GetName returns the name of the configuration source.
@@ -30,34 +51,226 @@ func (f *FileRepository) GetData(configName string) (config interface{}, isPrese
 	return config, isPresent
 }
 
-// GetRawData returns the raw data of the YAML configuration file.
+// GetRawData returns the raw data of the configuration file.
 func (f *FileRepository) GetRawData() []byte {
 	f.RLock()
 	defer f.RUnlock()
 	return f.rawData
 }
 
-// Refresh reads the YAML file, unmarshal it into the data map.
+// Version returns the VersionKey of the currently loaded file.
+func (f *FileRepository) Version() string {
+	f.RLock()
+	defer f.RUnlock()
+	return f.version
+}
+
+// Refresh reads the configuration file, unmarshal it into the data map.
+// If the file declares a VersionKey that matches the loaded data, the file is not reparsed.
+// Files listed under ExtendsKey are loaded beneath the file, and files tagged
+// with IncludeTag are inlined. A file that fails to parse, or is empty, is
+// read once more after ParseRetryDelay before the refresh fails.
 func (f *FileRepository) Refresh() error {
+	// The file is read and parsed, and reread after ParseRetryDelay, without
+	// the lock, so that getters are not blocked meanwhile.
+	f.refreshMu.Lock()
+	defer f.refreshMu.Unlock()
 	f.Lock()
-	defer f.Unlock()
+	if f.Watch {
+		f.startWatch()
+	}
+	loadedVersion := f.version
+	f.Unlock()
 
-	// Read the YAML file
-	data, err := os.ReadFile(f.Path)
+	// Read the configuration file
+	data, err := f.readFile()
 	if err != nil {
 		logrus.Debug("error reading file")
 		return err
 	}
 
-	// Unmarshal the YAML data into the data map
-	err = yaml.Unmarshal(data, &f.data)
+	// Skip reparsing when the declared version has not changed
+	version := documentVersion(data)
+	if version != "" && version == loadedVersion {
+		logrus.Debug("version unchanged, skipping reparse")
+		return nil
+	}
+
+	parsed, data, version, err := f.parse(data)
 	if err != nil {
-		logrus.Debug("error unmarshalling file")
 		return err
 	}
 
-	// Store the raw data of the YAML file
+	// Store the data, raw data and version of the file
+	f.Lock()
+	defer f.Unlock()
+	f.data = parsed
 	f.rawData = data
+	f.version = version
 
 	return nil
 }
+
+// Load reads and parses the configuration file the way Refresh does and
+// returns its configurations, without replacing the loaded data.
+func (f *FileRepository) Load() (map[string]interface{}, error) {
+	data, err := f.readFile()
+	if err != nil {
+		logrus.Debug("error reading file")
+		return nil, err
+	}
+	parsed, _, _, err := f.parse(data)
+	return parsed, err
+}
+
+// parse unmarshals data, read from the configuration file, into a new map
+// and returns it with the data and version of the file it was parsed from,
+// which is reread once if it fails to parse.
+func (f *FileRepository) parse(data []byte) (map[string]interface{}, []byte, string, error) {
+	version := documentVersion(data)
+	// Unmarshal the data with the codec, inlining included files
+	path := filepath.Clean(f.Path)
+	var parsed map[string]interface{}
+	included, err := unmarshalIncludes(path, data, f.Codec, os.ReadFile, &parsed)
+	if err != nil || len(data) == 0 {
+		// The file may have been read while it was being written or swapped,
+		// truncated but not written yet when empty, so read it once more
+		// after a short delay before giving up.
+		logrus.Debug("error unmarshalling file, retrying")
+		time.Sleep(f.parseRetryDelay())
+		data, err = f.readFile()
+		if err != nil {
+			logrus.Debug("error reading file")
+			return nil, nil, "", err
+		}
+		version = documentVersion(data)
+		parsed = nil
+		included, err = unmarshalIncludes(path, data, f.Codec, os.ReadFile, &parsed)
+		if err != nil {
+			logrus.Debug("error unmarshalling file")
+			return nil, nil, "", err
+		}
+	}
+
+	// Merge the files it extends beneath it. Included and base files can
+	// change without the version of this file changing, so such files are
+	// always reparsed.
+	_, extended := parsed[ExtendsKey]
+	if extended {
+		parsed, err = resolveExtends(path, parsed, f.Codec, os.ReadFile, []string{path})
+		if err != nil {
+			logrus.Debug("error resolving extends")
+			return nil, nil, "", err
+		}
+	}
+	if included || extended {
+		version = ""
+	}
+
+	return parsed, data, version, nil
+}
+
+// readFile reads the configuration file. The path is resolved on every read, so
+// that a symlink swapped since the last refresh, as Kubernetes does for the
+// files of a projected volume, is followed to its current target.
+func (f *FileRepository) readFile() ([]byte, error) {
+	path, err := filepath.EvalSymlinks(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// parseRetryDelay returns the delay before rereading a file that failed to parse.
+func (f *FileRepository) parseRetryDelay() time.Duration {
+	if f.ParseRetryDelay > 0 {
+		return f.ParseRetryDelay
+	}
+	return DefaultParseRetryDelay
+}
+
+// Keys returns the names of the configurations in the currently loaded file.
+func (f *FileRepository) Keys() []string {
+	f.RLock()
+	defer f.RUnlock()
+	return sortedKeys(f.data)
+}
+
+// Close stops the watch, if any, and waits for it to return.
+func (f *FileRepository) Close() error {
+	f.Lock()
+	f.closed = true
+	watcher, done := f.watcher, f.done
+	f.Unlock()
+	if watcher == nil {
+		return nil
+	}
+	err := watcher.Close()
+	<-done
+	return err
+}
+
+// startWatch starts watching the directory of the file unless the watch is
+// already running. The directory rather than the file is watched, so that a
+// file replaced by a new one is still watched. A watch that cannot be started
+// is retried on the next refresh. It must be called with the lock held.
+func (f *FileRepository) startWatch() {
+	if f.watcher != nil || f.closed {
+		return
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logrus.WithError(err).Warn("error creating file watcher, polling until the next refresh")
+		return
+	}
+	path := filepath.Clean(f.Path)
+	err = watcher.Add(filepath.Dir(path))
+	if err != nil {
+		_ = watcher.Close()
+		logrus.WithError(err).Warn("error watching file, polling until the next refresh")
+		return
+	}
+	f.watcher = watcher
+	f.done = make(chan struct{})
+	go f.watch(watcher, path)
+}
+
+// watch reloads the file whenever watcher reports that it was written or
+// created, until the watcher is closed.
+func (f *FileRepository) watch(watcher *fsnotify.Watcher, path string) {
+	defer close(f.done)
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != path || !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+			err := f.reload()
+			if err != nil {
+				logrus.WithError(err).WithField("path", path).Warn("error reloading watched file, keeping the current data")
+				continue
+			}
+			f.notifyChange()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logrus.WithError(err).Warn("error watching file")
+		}
+	}
+}
+
+// reload refreshes the file on a change reported by the watch, unless the
+// repository has been closed since.
+func (f *FileRepository) reload() error {
+	f.RLock()
+	closed := f.closed
+	f.RUnlock()
+	if closed {
+		return nil
+	}
+	return f.Refresh()
+}
@@ -0,0 +1,381 @@
+package source
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+}
+
+func TestFileRepositoryVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	repository := &FileRepository{Name: "file", Path: path}
+
+	writeFile(t, path, "_version: \"1\"\nname: John\n")
+	err := repository.Refresh()
+	if err != nil {
+		t.Errorf("Error refreshing repository: %s", err.Error())
+	}
+	if repository.Version() != "1" {
+		t.Errorf("Expected version to be 1, got %s", repository.Version())
+	}
+
+	// An unchanged version short-circuits the reparse.
+	writeFile(t, path, "_version: \"1\"\nname: Jane\n")
+	err = repository.Refresh()
+	if err != nil {
+		t.Errorf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+
+	// A changed version triggers a full reload.
+	writeFile(t, path, "_version: 2 # bumped\nname: Jane\n")
+	err = repository.Refresh()
+	if err != nil {
+		t.Errorf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected name to be Jane, got %v", name)
+	}
+	if repository.Version() != "2" {
+		t.Errorf("Expected version to be 2, got %s", repository.Version())
+	}
+}
+
+func TestFileRepositoryExtends(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base.yaml"), "name: base\ndb:\n  host: localhost\n  port: 5432\ntags: [a, b]\n")
+	writeFile(t, filepath.Join(dir, "staging.yaml"), "extends: base.yaml\ndb:\n  host: staging\n")
+	writeFile(t, filepath.Join(dir, "app.yaml"), "extends: [staging.yaml]\nname: app\ntags: [c]\n")
+
+	// Single inheritance deep-merges the base beneath the file.
+	repository := &FileRepository{Name: "file", Path: filepath.Join(dir, "staging.yaml")}
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	db, _ := repository.GetData("db")
+	expected := map[string]interface{}{"host": "staging", "port": 5432}
+	if !reflect.DeepEqual(db, expected) {
+		t.Errorf("Expected db to be %v, got %v", expected, db)
+	}
+	if _, ok := repository.GetData(ExtendsKey); ok {
+		t.Errorf("Expected %s to be removed from the data", ExtendsKey)
+	}
+
+	// Chained inheritance applies every level, the file winning.
+	repository = &FileRepository{Name: "file", Path: filepath.Join(dir, "app.yaml")}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "app" {
+		t.Errorf("Expected name to be app, got %v", name)
+	}
+	db, _ = repository.GetData("db")
+	if !reflect.DeepEqual(db, expected) {
+		t.Errorf("Expected db to be %v, got %v", expected, db)
+	}
+	tags, _ := repository.GetData("tags")
+	if !reflect.DeepEqual(tags, []interface{}{"c"}) {
+		t.Errorf("Expected lists to be replaced, got %v", tags)
+	}
+
+	// A changed base is picked up even though the file is unchanged.
+	writeFile(t, filepath.Join(dir, "base.yaml"), "name: base\ndb:\n  port: 6543\n")
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	db, _ = repository.GetData("db")
+	if !reflect.DeepEqual(db, map[string]interface{}{"host": "staging", "port": 6543}) {
+		t.Errorf("Expected the changed base to be merged, got %v", db)
+	}
+}
+
+func TestFileRepositoryExtendsCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.yaml"), "extends: b.yaml\nname: a\n")
+	writeFile(t, filepath.Join(dir, "b.yaml"), "extends: a.yaml\nname: b\n")
+
+	repository := &FileRepository{Name: "file", Path: filepath.Join(dir, "a.yaml")}
+	err := repository.Refresh()
+	if !errors.Is(err, ErrCircularExtends) {
+		t.Errorf("Expected ErrCircularExtends, got %v", err)
+	}
+}
+
+func TestFileRepositoryInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "greeting.txt"), "hello\n")
+	err := os.Mkdir(filepath.Join(dir, "fragments"), 0o700)
+	if err != nil {
+		t.Fatalf("Error creating directory: %s", err.Error())
+	}
+	writeFile(t, filepath.Join(dir, "fragments", "db.yaml"), "host: localhost\nport: 5432\npassword: !include ../secret.yaml\n")
+	writeFile(t, filepath.Join(dir, "secret.yaml"), "hunter2\n")
+	writeFile(t, filepath.Join(dir, "app.yaml"), "greeting: !include greeting.txt\ndb: !include fragments/db.yaml\n")
+
+	repository := &FileRepository{Name: "file", Path: filepath.Join(dir, "app.yaml")}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	greeting, _ := repository.GetData("greeting")
+	if greeting != "hello" {
+		t.Errorf("Expected the scalar fragment to be inlined, got %v", greeting)
+	}
+	db, _ := repository.GetData("db")
+	expected := map[string]interface{}{"host": "localhost", "port": 5432, "password": "hunter2"}
+	if !reflect.DeepEqual(db, expected) {
+		t.Errorf("Expected the map fragment to be inlined, got %v", db)
+	}
+}
+
+func TestFileRepositoryIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.yaml"), "b: !include b.yaml\n")
+	writeFile(t, filepath.Join(dir, "b.yaml"), "a: !include a.yaml\n")
+
+	repository := &FileRepository{Name: "file", Path: filepath.Join(dir, "a.yaml")}
+	err := repository.Refresh()
+	if !errors.Is(err, ErrCircularInclude) {
+		t.Errorf("Expected ErrCircularInclude, got %v", err)
+	}
+}
+
+// swapProjectedVolume points the ..data symlink of a Kubernetes-style projected
+// volume at a new directory holding content, atomically like the kubelet does.
+func swapProjectedVolume(dir string, revision string, content string) error {
+	target := filepath.Join(dir, "..rev-"+revision)
+	err := os.Mkdir(target, 0o700)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(target, "config.yaml"), []byte(content), 0o600)
+	if err != nil {
+		return err
+	}
+	err = os.Symlink(filepath.Base(target), filepath.Join(dir, "..data_tmp"))
+	if err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data"))
+}
+
+func TestFileRepositoryProjectedVolume(t *testing.T) {
+	dir := t.TempDir()
+	if err := swapProjectedVolume(dir, "1", "name: John\n"); err != nil {
+		t.Fatalf("Error swapping volume: %s", err.Error())
+	}
+	path := filepath.Join(dir, "config.yaml")
+	err := os.Symlink(filepath.Join("..data", "config.yaml"), path)
+	if err != nil {
+		t.Fatalf("Error creating symlink: %s", err.Error())
+	}
+
+	repository := &FileRepository{Name: "file", Path: path, ParseRetryDelay: 200 * time.Millisecond}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// A swap between refreshes is followed to the new target.
+	if err := swapProjectedVolume(dir, "2", "name: Jane\n"); err != nil {
+		t.Fatalf("Error swapping volume: %s", err.Error())
+	}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected name to be Jane after the swap, got %v", name)
+	}
+
+	// A half-written file is retried without blocking getters, and by then
+	// the volume has been swapped.
+	if err := swapProjectedVolume(dir, "3", "name: [Ja"); err != nil {
+		t.Fatalf("Error swapping volume: %s", err.Error())
+	}
+	swapped := make(chan struct{})
+	go func() {
+		defer close(swapped)
+		time.Sleep(50 * time.Millisecond)
+		// The current data is served while the refresh waits to retry.
+		if name, _ := repository.GetData("name"); name != "Jane" {
+			t.Errorf("Expected name to still be Jane during the retry, got %v", name)
+		}
+		if err := swapProjectedVolume(dir, "4", "name: Joe\n"); err != nil {
+			t.Errorf("Error swapping volume: %s", err.Error())
+		}
+	}()
+	err = repository.Refresh()
+	<-swapped
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ = repository.GetData("name")
+	if name != "Joe" {
+		t.Errorf("Expected name to be Joe after the retry, got %v", name)
+	}
+
+	// A file that still fails to parse on the retry fails the refresh.
+	if err := swapProjectedVolume(dir, "5", "name: [Ja"); err != nil {
+		t.Fatalf("Error swapping volume: %s", err.Error())
+	}
+	repository.ParseRetryDelay = time.Millisecond
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a file that does not parse, got nil")
+	}
+}
+
+func TestFileRepositoryAnchors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "secret.yaml"), "hunter2\n")
+	document := `base: &base
+  host: localhost
+  port: 5432
+  tags: &tags [a, b]
+primary:
+  <<: *base
+  host: primary.internal
+replica:
+  <<: *base
+  password: !include secret.yaml
+tags: *tags
+`
+	for _, name := range []string{"plain.yaml", "include.yaml"} {
+		content := document
+		if name == "plain.yaml" {
+			content = strings.Replace(content, "!include secret.yaml", "hunter2", 1)
+		}
+		writeFile(t, filepath.Join(dir, name), content)
+		repository := &FileRepository{Name: "file", Path: filepath.Join(dir, name)}
+		err := repository.Refresh()
+		if err != nil {
+			t.Fatalf("Error refreshing repository: %s", err.Error())
+		}
+
+		primary, _ := repository.GetData("primary")
+		expected := map[string]interface{}{"host": "primary.internal", "port": 5432, "tags": []interface{}{"a", "b"}}
+		if !reflect.DeepEqual(primary, expected) {
+			t.Errorf("Expected the merge key to be expanded with overrides in %s, got %v", name, primary)
+		}
+		replica, _ := repository.GetData("replica")
+		expected = map[string]interface{}{"host": "localhost", "port": 5432, "tags": []interface{}{"a", "b"}, "password": "hunter2"}
+		if !reflect.DeepEqual(replica, expected) {
+			t.Errorf("Expected the merge key to be expanded in %s, got %v", name, replica)
+		}
+		tags, _ := repository.GetData("tags")
+		if !reflect.DeepEqual(tags, []interface{}{"a", "b"}) {
+			t.Errorf("Expected the alias to be resolved in %s, got %v", name, tags)
+		}
+
+		// Expanded aliases are copies, so changing one leaves the others intact.
+		primary.(map[string]interface{})["tags"].([]interface{})[0] = "z"
+		base, _ := repository.GetData("base")
+		if base.(map[string]interface{})["tags"].([]interface{})[0] != "a" {
+			t.Errorf("Expected aliases to be expanded into separate values in %s, got %v", name, base)
+		}
+	}
+}
+
+func TestFileRepositoryWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeFile(t, path, "name: John\n")
+	repository := &FileRepository{Name: "file", Path: path, Watch: true, ParseRetryDelay: time.Millisecond}
+	defer repository.Close()
+	notified := notifications(repository)
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// Edits are applied without a refresh, and notified.
+	writeFile(t, path, "name: Jane\n")
+	waitForData(t, repository, "name", "Jane")
+	waitForNotification(t, notified)
+
+	// An edit that does not parse keeps the current data.
+	writeFile(t, path, "name: [unclosed\n")
+	time.Sleep(50 * time.Millisecond)
+	name, _ := repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected the last good data to be kept, got %v", name)
+	}
+
+	// A file replaced by renaming a new one over it is still watched.
+	replacement := filepath.Join(dir, "config.yaml.tmp")
+	writeFile(t, replacement, "name: Jack\n")
+	err = os.Rename(replacement, path)
+	if err != nil {
+		t.Fatalf("Error replacing file: %s", err.Error())
+	}
+	waitForData(t, repository, "name", "Jack")
+	writeFile(t, path, "name: Jill\n")
+	waitForData(t, repository, "name", "Jill")
+
+	// Close stops the watch.
+	err = repository.Close()
+	if err != nil {
+		t.Fatalf("Error closing repository: %s", err.Error())
+	}
+	writeFile(t, path, "name: Joe\n")
+	time.Sleep(50 * time.Millisecond)
+	name, _ = repository.GetData("name")
+	if name != "Jill" {
+		t.Errorf("Expected no reload after Close, got %v", name)
+	}
+}
+
+func TestFileRepositoryLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "name: John\n")
+	repository := &FileRepository{Name: "file", Path: path, ParseRetryDelay: time.Millisecond}
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// A file that does not parse is reported without touching the data.
+	writeFile(t, path, "name: [unclosed\n")
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected error for a file that does not parse, got nil")
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected the live data to be untouched, got %v", name)
+	}
+
+	// A file that parses is returned without replacing the data.
+	writeFile(t, path, "name: Jane\n")
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading file: %s", err.Error())
+	}
+	if loaded["name"] != "Jane" {
+		t.Errorf("Expected the loaded name to be Jane, got %v", loaded["name"])
+	}
+	name, _ = repository.GetData("name")
+	if name != "John" || string(repository.GetRawData()) != "name: John\n" {
+		t.Errorf("Expected the live data to be untouched, got %v", name)
+	}
+}
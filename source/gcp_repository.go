@@ -4,66 +4,132 @@ import (
 	// ...
 	"cloud.google.com/go/storage"
 	"context"
-	"gopkg.in/yaml.v3"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/api/option"
 	"io"
+	"strconv"
 	"sync"
 	// ...
 )
 
 // GcpStorageRepository is a struct that implements the Repository interface for
-// handling configuration data stored in a YAML file within a GCS bucket.
+// handling configuration data stored in a file within a GCS bucket. The
+// object is only downloaded again when its generation changes, and a failed
+// refresh leaves the current data in place.
 type GcpStorageRepository struct {
-	sync.RWMutex                        // RWMutex to synchronize access to data during refresh
-	Name         string                 // Name of the configuration source
-	data         map[string]interface{} // Map to store the configuration data
-	BucketName   string                 // Name of the GCS bucket
-	ObjectName   string                 // Name of the YAML file within the GCS bucket
-	Client       *storage.Client        // GCS client instance
-	rawData      []byte                 // Raw data of the YAML configuration file
+	sync.RWMutex                           // RWMutex to synchronize access to data during refresh
+	Name            string                 // Name of the configuration source
+	data            map[string]interface{} // Map to store the configuration data
+	BucketName      string                 // Name of the GCS bucket
+	ObjectName      string                 // Name of the configuration file within the GCS bucket
+	Client          *storage.Client        // GCS client instance
+	CredentialsFile string                 // Path of the credentials file the client is created with, Application Default Credentials when empty
+	rawData         []byte                 // Raw data of the configuration file
+	version         string                 // Version marker of the currently loaded data
+	generation      int64                  // Generation of the currently loaded object
+	Codec           Codec                  // Codec used to parse the file, defaults to YAMLCodec
 }
 
-// Refresh reads the YAML file from the GCS bucket, unmarshal it into the data map.
+// GcpStorageOption configures a GcpStorageRepository created with
+// NewGcpStorageRepository.
+type GcpStorageOption func(*GcpStorageRepository)
+
+// WithCredentialsFile creates the GCS client with the service account or
+// user credentials in the JSON file at path, instead of the Application
+// Default Credentials.
+func WithCredentialsFile(path string) GcpStorageOption {
+	return func(g *GcpStorageRepository) {
+		g.CredentialsFile = path
+	}
+}
+
+// WithStorageClient sets the GCS client used to read the object.
+func WithStorageClient(client *storage.Client) GcpStorageOption {
+	return func(g *GcpStorageRepository) {
+		g.Client = client
+	}
+}
+
+// NewGcpStorageRepository creates a GcpStorageRepository reading the object
+// with the given name from bucket.
+func NewGcpStorageRepository(name string, bucket string, object string, opts ...GcpStorageOption) *GcpStorageRepository {
+	repository := &GcpStorageRepository{
+		Name:       name,
+		BucketName: bucket,
+		ObjectName: object,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	return repository
+}
+
+// Refresh reads the configuration file from the GCS bucket, unmarshal it into the data map.
+// The attributes of the object are read first, and the object is neither
+// downloaded nor reparsed if its generation has not changed.
 func (g *GcpStorageRepository) Refresh() error {
 	g.Lock()
 	defer g.Unlock()
 
-	// If the GCS client does not exist, create it.
-	if g.Client == nil {
-		ctx := context.Background()
-		client, err := storage.NewClient(ctx)
-		if err != nil {
-			return err
-		}
-		g.Client = client
+	client, err := g.storageClient()
+	if err != nil {
+		return err
 	}
 
-	// Open the YAML file from the GCS bucket.
+	// Skip the download when the generation of the object has not changed.
 	ctx := context.Background()
-	bucket := g.Client.Bucket(g.BucketName)
-	obj := bucket.Object(g.ObjectName)
-	reader, err := obj.NewReader(ctx)
+	obj := client.Bucket(g.BucketName).Object(g.ObjectName)
+	attrs, err := obj.Attrs(ctx)
 	if err != nil {
+		logrus.Debug("error getting object attributes")
 		return err
 	}
-	defer reader.Close()
+	if g.generation != 0 && attrs.Generation == g.generation {
+		logrus.Debug("generation unchanged, skipping download")
+		return nil
+	}
 
-	// Read the file content from the reader.
-	fileContent, err := io.ReadAll(reader)
+	// Read the generation of the configuration file the attributes describe.
+	fileContent, err := readObject(ctx, obj.Generation(attrs.Generation))
 	if err != nil {
 		return err
 	}
 
-	// Unmarshal the YAML data into the data map.
-	err = yaml.Unmarshal(fileContent, &g.data)
+	// Skip reparsing when the declared version has not changed.
+	version := documentVersion(fileContent)
+	if version == "" {
+		version = strconv.FormatInt(attrs.Generation, 10)
+	}
+	if version == g.version {
+		logrus.Debug("version unchanged, skipping reparse")
+		g.generation = attrs.Generation
+		return nil
+	}
+
+	// Unmarshal the data into a new map with the codec, so a file that does
+	// not parse leaves the current data in place.
+	var parsed map[string]interface{}
+	err = codecOrDefault(g.Codec).Unmarshal(fileContent, &parsed)
 	if err != nil {
 		return err
 	}
 
-	// Store the raw data of the YAML file.
+	// Store the data, raw data, version and generation of the file.
+	g.data = parsed
 	g.rawData = fileContent
+	g.version = version
+	g.generation = attrs.Generation
 	return nil
 }
 
+// Version returns the VersionKey of the currently loaded file, or the
+// generation of the object when the file declares none.
+func (g *GcpStorageRepository) Version() string {
+	g.RLock()
+	defer g.RUnlock()
+	return g.version
+}
+
 // GetName returns the name of the configuration source.
 func (g *GcpStorageRepository) GetName() string {
 	return g.Name
@@ -77,9 +143,68 @@ func (g *GcpStorageRepository) GetData(configName string) (config interface{}, i
 	return config, isPresent
 }
 
-// GetRawData returns the raw data of the YAML configuration file.
+// GetRawData returns the raw data of the configuration file.
 func (g *GcpStorageRepository) GetRawData() []byte {
 	g.RLock()
 	defer g.RUnlock()
 	return g.rawData
 }
+
+// Keys returns the names of the configurations in the currently loaded file.
+func (g *GcpStorageRepository) Keys() []string {
+	g.RLock()
+	defer g.RUnlock()
+	return sortedKeys(g.data)
+}
+
+// Load downloads and parses the configuration file the way Refresh does and
+// returns its configurations, without replacing the loaded data. The
+// repository is only locked to set up its client, so GetData and Refresh do
+// not wait for the download.
+func (g *GcpStorageRepository) Load() (map[string]interface{}, error) {
+	g.Lock()
+	client, err := g.storageClient()
+	codec := g.Codec
+	g.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	fileContent, err := readObject(context.Background(), client.Bucket(g.BucketName).Object(g.ObjectName))
+	if err != nil {
+		return nil, err
+	}
+	var parsed map[string]interface{}
+	err = codecOrDefault(codec).Unmarshal(fileContent, &parsed)
+	if err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// storageClient returns the GCS client, creating it first if there is none.
+// The caller must hold the lock.
+func (g *GcpStorageRepository) storageClient() (*storage.Client, error) {
+	if g.Client == nil {
+		ctx := context.Background()
+		var opts []option.ClientOption
+		if g.CredentialsFile != "" {
+			opts = append(opts, option.WithCredentialsFile(g.CredentialsFile))
+		}
+		client, err := storage.NewClient(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		g.Client = client
+	}
+	return g.Client, nil
+}
+
+// readObject reads the content of obj.
+func readObject(ctx context.Context, obj *storage.ObjectHandle) ([]byte, error) {
+	reader, err := obj.NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,126 @@
+package source
+
+import (
+	"cloud.google.com/go/storage"
+	"context"
+	"github.com/fullstorydev/emulators/storage/gcsemu"
+	"path/filepath"
+	"testing"
+)
+
+// newGCSEmulator starts an in-memory GCS server with the bucket "config" and
+// returns a client of it.
+func newGCSEmulator(t *testing.T) *storage.Client {
+	t.Helper()
+	server, err := gcsemu.NewServer("127.0.0.1:0", gcsemu.Options{})
+	if err != nil {
+		t.Fatalf("Error starting in-memory storage server: %s", err.Error())
+	}
+	t.Cleanup(server.Close)
+	t.Setenv("STORAGE_EMULATOR_HOST", "http://"+server.Addr)
+	client, err := storage.NewClient(context.Background())
+	if err != nil {
+		t.Fatalf("Error creating storage client: %s", err.Error())
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	err = client.Bucket("config").Create(context.Background(), "test-project", nil)
+	if err != nil {
+		t.Fatalf("Error creating bucket: %s", err.Error())
+	}
+	return client
+}
+
+// writeObject writes content to the object with the given name of the bucket "config".
+func writeObject(t *testing.T, client *storage.Client, name string, content string) {
+	t.Helper()
+	writer := client.Bucket("config").Object(name).NewWriter(context.Background())
+	_, err := writer.Write([]byte(content))
+	if err != nil {
+		t.Fatalf("Error writing object: %s", err.Error())
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatalf("Error closing object: %s", err.Error())
+	}
+}
+
+func TestGcpStorageRepository(t *testing.T) {
+	client := newGCSEmulator(t)
+	writeObject(t, client, "app.yaml", "name: John\n")
+
+	repository := NewGcpStorageRepository("gcs", "config", "app.yaml", WithStorageClient(client))
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+	version := repository.Version()
+	if version == "" {
+		t.Errorf("Expected the generation as version, got none")
+	}
+
+	// An unchanged object keeps its version.
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing unchanged object: %s", err.Error())
+	}
+	if repository.Version() != version {
+		t.Errorf("Expected the version to be stable, got %s and %s", version, repository.Version())
+	}
+
+	// A new generation is downloaded and parsed.
+	writeObject(t, client, "app.yaml", "name: Jane\n")
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing changed object: %s", err.Error())
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" || repository.Version() == version {
+		t.Errorf("Expected name Jane at a new version, got %v at %s", name, repository.Version())
+	}
+
+	// Load downloads and parses the object without replacing the data.
+	writeObject(t, client, "app.yaml", "name: Jack\n")
+	loaded, err := repository.Load()
+	if err != nil || loaded["name"] != "Jack" {
+		t.Errorf("Expected the loaded name to be Jack, got %v (%v)", loaded, err)
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected the live data to be untouched, got %v", name)
+	}
+
+	// The last good data is kept when the object does not parse or is gone.
+	writeObject(t, client, "app.yaml", "name: [unclosed\n")
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for an object that does not parse, got nil")
+	}
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected error loading an object that does not parse, got nil")
+	}
+	err = client.Bucket("config").Object("app.yaml").Delete(context.Background())
+	if err != nil {
+		t.Fatalf("Error deleting object: %s", err.Error())
+	}
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a missing object, got nil")
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected the last good data to be kept, got %v", name)
+	}
+}
+
+func TestGcpStorageRepositoryCredentialsFile(t *testing.T) {
+	t.Setenv("STORAGE_EMULATOR_HOST", "")
+	repository := NewGcpStorageRepository("gcs", "config", "app.yaml", WithCredentialsFile(filepath.Join(t.TempDir(), "missing.json")))
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a missing credentials file, got nil")
+	}
+	if repository.Client != nil {
+		t.Errorf("Expected no client without credentials")
+	}
+}
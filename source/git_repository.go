@@ -2,37 +2,103 @@ package source
 
 import (
 	"context"
-	"fmt"
-	"github.com/go-git/go-billy/v5"
-	"github.com/go-git/go-billy/v5/memfs"
+	"errors"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
-	"io"
 	"net/url"
-	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 )
 
+// gitLocalRef is the reference of the local clone the fetched commit is stored under.
+const gitLocalRef = "refs/remotes/origin/config"
+
 // GitRepository is a struct that implements the Repository interface for
-// handling configuration data stored in a YAML file within a Git repository.
-// Deprecated: This is Deprecated because it there is API limitation you make to github and gitlab. Which will get exhausted.
-// This is not a good way to handle the configuration is to use your CI to upload the configuration to a S3/GCS bucket and then use the S3/GCS  repository to fetch the configuration.
+// handling configuration data stored in a file within a Git repository. The
+// first refresh makes a shallow, in-memory clone of Branch, Ref or the
+// default branch, and later refreshes fetch only its latest commit into that
+// clone. The file is reparsed only when the commit changes, and Commit
+// returns the hash of the commit the data was read from. A failed fetch
+// leaves the current data in place. Every refresh contacts the Git host, so
+// keep the refresh interval within its rate limits; for frequent refreshes,
+// have CI upload the file to a bucket and use the S3 or GCS repository.
 type GitRepository struct {
 	sync.RWMutex                         // RWMutex to synchronize access to data during refresh
 	Name          string                 // Name of the configuration source
 	data          map[string]interface{} // Map to store the configuration data
 	URL           *url.URL               // URL representing the Git repository URL
-	Path          string                 // Path to the YAML file within the Git repository
+	Path          string                 // Path to the configuration file within the Git repository
 	gitRepository *git.Repository        // Go-Git repository instance for the in-memory clone
-	Branch        string                 // Branch to use when cloning the Git repository
-	Auth          *http.BasicAuth        // BasicAuth to use when cloning the Git repository
-	fs            billy.Filesystem       // Filesystem to store the in-memory clone of the repository
-	rawData       []byte                 // Raw data of the YAML configuration file
+	Branch        string                 // Branch to read the file from, defaults to the default branch of the repository
+	Ref           string                 // Full name of the reference to read the file from instead of Branch, such as refs/tags/v1.2.0
+	Auth          *http.BasicAuth        // BasicAuth to use when fetching over HTTPS
+	Token         string                 // Access token to use when fetching over HTTPS, if Auth is not set
+	SSHKey        []byte                 // PEM-encoded private key to use when fetching over SSH
+	SSHKeyPass    string                 // Passphrase of SSHKey, if it is encrypted
+	rawData       []byte                 // Raw data of the configuration file
+	commit        string                 // Hash of the commit the data was read from
+	Codec         Codec                  // Codec used to parse the file, defaults to YAMLCodec
+}
+
+// GitOption configures a GitRepository created with NewGitRepository.
+type GitOption func(*GitRepository)
+
+// WithGitBranch reads the file from the given branch.
+func WithGitBranch(branch string) GitOption {
+	return func(g *GitRepository) {
+		g.Branch = branch
+	}
+}
+
+// WithGitRef reads the file from the reference with the given full name,
+// such as refs/tags/v1.2.0.
+func WithGitRef(ref string) GitOption {
+	return func(g *GitRepository) {
+		g.Ref = ref
+	}
+}
+
+// WithGitToken authenticates over HTTPS with the given access token.
+func WithGitToken(token string) GitOption {
+	return func(g *GitRepository) {
+		g.Token = token
+	}
+}
+
+// WithGitSSHKey authenticates over SSH with the given PEM-encoded private key
+// and its passphrase, empty if the key is not encrypted.
+func WithGitSSHKey(key []byte, passphrase string) GitOption {
+	return func(g *GitRepository) {
+		g.SSHKey = key
+		g.SSHKeyPass = passphrase
+	}
+}
+
+// NewGitRepository creates a GitRepository reading the file at path within the
+// Git repository cloned from rawURL, such as
+// https://github.com/org/config.git or ssh://git@github.com/org/config.git.
+func NewGitRepository(name string, rawURL string, path string, opts ...GitOption) (*GitRepository, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	repository := &GitRepository{
+		Name: name,
+		URL:  parsed,
+		Path: path,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	return repository, nil
 }
 
 // GetName returns the configuration data as a map of configuration names to their respective models.
@@ -40,127 +106,222 @@ func (g *GitRepository) GetName() string {
 	return g.Name
 }
 
-// GetRawData returns the raw data of the YAML configuration file.
+// GetRawData returns the raw data of the configuration file.
 func (g *GitRepository) GetRawData() []byte {
 	g.RLock()
 	defer g.RUnlock()
 	return g.rawData
 }
 
-// Refresh reads the YAML file from the Git repository, unmarshal it into the data map.
+// Refresh fetches the latest commit of the reference and, if it differs from
+// the loaded one, reads the configuration file from it and unmarshal it into
+// the data map.
 func (g *GitRepository) Refresh() error {
 	g.Lock()
 	defer g.Unlock()
 
-	// If the in-memory clone of the Git repository does not exist, create it.
-	if g.fs == nil {
-		g.fs = memfs.New()
-		logrus.Debugf("Cloning %s into memory", g.URL.String())
-		// Clone the Git repository into the in-memory filesystem.
-		r, err := git.CloneContext(context.Background(), memory.NewStorage(), g.fs, &git.CloneOptions{
-			URL:  g.URL.String(),
-			Auth: g.Auth,
-		})
+	if g.gitRepository == nil {
+		r, err := g.clone()
 		if err != nil {
 			return err
 		}
+		g.gitRepository = r
+	}
+	hash, err := g.fetch(g.gitRepository)
+	if err != nil {
+		return err
+	}
 
-		if g.Branch != "" {
-			// Checkout the specified Branch.
-			w, err := r.Worktree()
-			if err != nil {
-				return err
-			}
-
-			err = r.Fetch(&git.FetchOptions{
-				RefSpecs: []config.RefSpec{"refs/*:refs/*", "HEAD:refs/heads/HEAD"},
-			})
-			if err != nil {
-				return err
-			}
-
-			err = w.Checkout(&git.CheckoutOptions{
-				Branch: plumbing.NewBranchReferenceName(g.Branch),
-				Force:  true,
-			})
-			if err != nil {
-				return err
-			}
-		}
+	// Skip reparsing when the commit has not changed.
+	if hash.String() == g.commit && g.data != nil {
+		logrus.Debug("commit unchanged, skipping reparse")
+		return nil
+	}
 
-		logrus.Debug("Cloned")
-		g.gitRepository = r
-	} else {
-		// Pull the latest changes from the Git repository.
-		w, err := g.gitRepository.Worktree()
-		if err != nil {
-			return err
-		}
-		logrus.Debug("Pulling")
+	parsed, fileContent, err := g.read(g.gitRepository, hash)
+	if err != nil {
+		return err
+	}
 
-		pullOptions := &git.PullOptions{
-			Auth: g.Auth,
-		}
-		if g.Branch != "" {
-			pullOptions = &git.PullOptions{
-				ReferenceName: plumbing.NewBranchReferenceName(g.Branch),
-				Force:         true,
-				SingleBranch:  true,
-				Auth:          g.Auth,
-			}
-		}
+	// Store the data, raw data and commit of the file.
+	g.data = parsed
+	g.rawData = fileContent
+	g.commit = hash.String()
 
-		err = w.PullContext(context.Background(), pullOptions)
+	return nil
+}
 
-		if err != nil && err != git.NoErrAlreadyUpToDate {
-			return err
-		}
-		if err == git.NoErrAlreadyUpToDate {
-			logrus.Debug("Already up to date")
-		} else {
-			logrus.Debug("Pulled")
-		}
+// Load fetches the latest commit of the reference and parses the
+// configuration file from it the way Refresh does, and returns its
+// configurations, without replacing the loaded data. The commit is fetched
+// into a clone of its own, so the clone of the repository is left untouched
+// and GetData and Refresh do not wait for the fetch.
+func (g *GitRepository) Load() (map[string]interface{}, error) {
+	r, err := g.clone()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := g.fetch(r)
+	if err != nil {
+		return nil, err
+	}
+	parsed, _, err := g.read(r, hash)
+	return parsed, err
+}
+
+// clone creates an empty in-memory clone with the URL as its remote.
+func (g *GitRepository) clone() (*git.Repository, error) {
+	r, err := git.Init(memory.NewStorage(), nil)
+	if err != nil {
+		return nil, err
 	}
+	_, err = r.CreateRemote(&config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{g.URL.String()}})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
 
-	// Open the YAML file from the in-memory filesystem.
-	file, err := g.fs.Open(g.Path)
+// fetch fetches the latest commit of the reference into the in-memory clone
+// r and returns its hash. Only that commit is fetched, not its history.
+func (g *GitRepository) fetch(r *git.Repository) (plumbing.Hash, error) {
+	auth, err := g.auth()
 	if err != nil {
-		fmt.Println("Error opening file:", err)
-		os.Exit(1)
+		return plumbing.ZeroHash, err
+	}
+	logrus.Debugf("Fetching %s from %s", g.remoteRef(), g.URL.Redacted())
+	err = r.FetchContext(context.Background(), &git.FetchOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   []config.RefSpec{config.RefSpec("+" + g.remoteRef() + ":" + gitLocalRef)},
+		Depth:      1,
+		Auth:       auth,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		logrus.Debug("error fetching repository")
+		return plumbing.ZeroHash, err
 	}
-	defer func(file billy.File) {
-		err := file.Close()
-		if err != nil {
-			logrus.WithError(err).Error("error closing file")
-		}
-	}(file)
 
-	// Read the file content from the reader.
-	fileContent, err := io.ReadAll(file)
+	ref, err := r.Reference(gitLocalRef, true)
 	if err != nil {
-		fmt.Println("Error reading file:", err)
-		os.Exit(1)
+		logrus.Debug("error resolving fetched reference")
+		return plumbing.ZeroHash, err
 	}
+	return ref.Hash(), nil
+}
 
-	// Unmarshal the YAML data into the data map.
-	err = yaml.Unmarshal(fileContent, &g.data)
+// read reads the configuration file from the commit with the given hash of
+// the clone r and unmarshals it into a new map, which it returns with the
+// content of the file.
+func (g *GitRepository) read(r *git.Repository, hash plumbing.Hash) (map[string]interface{}, []byte, error) {
+	commit, err := r.CommitObject(hash)
+	if err != nil {
+		logrus.Debug("error reading commit")
+		return nil, nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		logrus.Debug("error reading commit tree")
+		return nil, nil, err
+	}
+	readFile := func(path string) ([]byte, error) {
+		return readTreeFile(tree, path)
+	}
+	path := filepath.ToSlash(filepath.Clean(g.Path))
+	fileContent, err := readFile(path)
+	if err != nil {
+		logrus.WithField("path", g.Path).Debug("error reading file")
+		return nil, nil, err
+	}
+
+	// Unmarshal the data into a new map with the codec, inlining files
+	// included from the same commit, so that a file that does not parse
+	// leaves the current data in place.
+	var parsed map[string]interface{}
+	_, err = unmarshalIncludes(path, fileContent, g.Codec, readFile, &parsed)
 	if err != nil {
 		logrus.Debug("error unmarshalling file")
-		return err
+		return nil, nil, err
 	}
 
-	// Store the raw data of the YAML file.
-	g.rawData = fileContent
+	// Merge the files it extends beneath it.
+	if _, extended := parsed[ExtendsKey]; extended {
+		parsed, err = resolveExtends(path, parsed, g.Codec, readFile, []string{path})
+		if err != nil {
+			logrus.Debug("error resolving extends")
+			return nil, nil, err
+		}
+	}
+	return parsed, fileContent, nil
+}
 
-	return nil
+// remoteRef returns the name of the reference to fetch.
+func (g *GitRepository) remoteRef() string {
+	switch {
+	case g.Ref != "":
+		return g.Ref
+	case g.Branch != "":
+		return plumbing.NewBranchReferenceName(g.Branch).String()
+	}
+	return plumbing.HEAD.String()
+}
+
+// auth returns the authentication to fetch with: Auth if it is set, else the
+// token, else the SSH key, and none otherwise.
+func (g *GitRepository) auth() (transport.AuthMethod, error) {
+	switch {
+	case g.Auth != nil:
+		return g.Auth, nil
+	case g.Token != "":
+		// Hosts such as GitHub and GitLab accept a token as the password of any user.
+		return &http.BasicAuth{Username: "git", Password: g.Token}, nil
+	case len(g.SSHKey) > 0:
+		user := "git"
+		if g.URL.User != nil && g.URL.User.Username() != "" {
+			user = g.URL.User.Username()
+		}
+		return ssh.NewPublicKeys(user, g.SSHKey, g.SSHKeyPass)
+	}
+	return nil, nil
+}
+
+// readTreeFile returns the content of the file at path in tree.
+func readTreeFile(tree *object.Tree, path string) ([]byte, error) {
+	file, err := tree.File(strings.TrimPrefix(filepath.ToSlash(path), "/"))
+	if err != nil {
+		return nil, err
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// Commit returns the hash of the commit the current data was read from, or an
+// empty string before the first successful refresh.
+func (g *GitRepository) Commit() string {
+	g.RLock()
+	defer g.RUnlock()
+	return g.commit
+}
+
+// Version returns the hash of the commit the current data was read from.
+func (g *GitRepository) Version() string {
+	return g.Commit()
 }
 
 // GetData returns the configuration data as a map of configuration names to their respective models.
-// Deprecated: This is Deprecated because it there is API limitation you make to github and gitlab. Which will get exhausted.
-// This is not a good way to handle the configuration is to use your CI to upload the configuration to a S3/GCS bucket and then use the S3/GCS  repository to fetch the configuration.
 func (g *GitRepository) GetData(configName string) (config interface{}, isPresent bool) {
 	g.RLock()
 	defer g.RUnlock()
 	config, isPresent = g.data[configName]
 	return config, isPresent
 }
+
+// Keys returns the names of the configurations in the currently loaded file.
+func (g *GitRepository) Keys() []string {
+	g.RLock()
+	defer g.RUnlock()
+	return sortedKeys(g.data)
+}
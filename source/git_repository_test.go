@@ -0,0 +1,161 @@
+package source
+
+import (
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// commitFile writes content to the file at name in the worktree of repo at
+// dir and commits it, returning the hash of the commit.
+func commitFile(t *testing.T, repo *git.Repository, dir string, name string, content string) string {
+	t.Helper()
+	err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755)
+	if err != nil {
+		t.Fatalf("Error creating directory: %s", err.Error())
+	}
+	writeFile(t, filepath.Join(dir, name), content)
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Error opening worktree: %s", err.Error())
+	}
+	_, err = worktree.Add(name)
+	if err != nil {
+		t.Fatalf("Error adding file: %s", err.Error())
+	}
+	hash, err := worktree.Commit("update "+name, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("Error committing file: %s", err.Error())
+	}
+	return hash.String()
+}
+
+func TestGitRepository(t *testing.T) {
+	dir := t.TempDir()
+	origin, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	commit := commitFile(t, origin, dir, "config/app.yaml", "name: John\n")
+
+	repository, err := NewGitRepository("git", dir, "config/app.yaml")
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+	if repository.Commit() != commit {
+		t.Errorf("Expected commit %s, got %s", commit, repository.Commit())
+	}
+
+	// An unchanged commit is not fetched or parsed again.
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if repository.Commit() != commit {
+		t.Errorf("Expected commit %s, got %s", commit, repository.Commit())
+	}
+
+	// A new commit is picked up by the kept clone.
+	commit = commitFile(t, origin, dir, "config/app.yaml", "name: Jane\n")
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected name to be Jane, got %v", name)
+	}
+	if repository.Commit() != commit {
+		t.Errorf("Expected commit %s, got %s", commit, repository.Commit())
+	}
+
+	// A file that does not parse leaves the data in place.
+	commitFile(t, origin, dir, "config/app.yaml", "name: [unclosed\n")
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a file that does not parse, got nil")
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" || repository.Commit() != commit {
+		t.Errorf("Expected the last good data to be kept, got %v at %s", name, repository.Commit())
+	}
+
+	// Load fetches and parses the latest commit without replacing the data.
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected error loading a file that does not parse, got nil")
+	}
+	commitFile(t, origin, dir, "config/app.yaml", "name: Jack\n")
+	loaded, err := repository.Load()
+	if err != nil || loaded["name"] != "Jack" {
+		t.Errorf("Expected the loaded name to be Jack, got %v (%v)", loaded, err)
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" || repository.Commit() != commit {
+		t.Errorf("Expected the live data to be untouched, got %v at %s", name, repository.Commit())
+	}
+
+	// A failed fetch leaves the data in place.
+	err = os.RemoveAll(dir)
+	if err != nil {
+		t.Fatalf("Error removing repository: %s", err.Error())
+	}
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a repository that cannot be fetched, got nil")
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected the last good data to be kept, got %v", name)
+	}
+}
+
+func TestGitRepositoryBranch(t *testing.T) {
+	dir := t.TempDir()
+	origin, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	commitFile(t, origin, dir, "app.yaml", "name: John\n")
+	worktree, err := origin.Worktree()
+	if err != nil {
+		t.Fatalf("Error opening worktree: %s", err.Error())
+	}
+	err = worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("staging"), Create: true})
+	if err != nil {
+		t.Fatalf("Error creating branch: %s", err.Error())
+	}
+	commit := commitFile(t, origin, dir, "app.yaml", "name: Jane\n")
+
+	repository, err := NewGitRepository("git", dir, "app.yaml", WithGitBranch("staging"))
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "Jane" || repository.Version() != commit {
+		t.Errorf("Expected name to be Jane at %s, got %v at %s", commit, name, repository.Version())
+	}
+
+	repository, err = NewGitRepository("git", dir, "app.yaml", WithGitRef("refs/heads/missing"))
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a missing reference, got nil")
+	}
+}
@@ -0,0 +1,88 @@
+package source
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"path/filepath"
+	"strings"
+)
+
+// IncludeTag is the YAML tag that inlines another file at its position, as in
+// `db: !include db.yaml`. The path is relative to the including file, and the
+// included file may be a scalar, a sequence or a map, and include files itself.
+// Includes are only resolved in YAML files.
+const IncludeTag = "!include"
+
+// ErrCircularInclude is returned when configuration files include each other in a cycle.
+var ErrCircularInclude = errors.New("circular include")
+
+// unmarshalIncludes unmarshals the content of the file at path with codec
+// into v, inlining the files it includes. It reports whether the file
+// included any file.
+func unmarshalIncludes(path string, data []byte, codec Codec, readFile readFileFunc, v interface{}) (bool, error) {
+	if _, ok := codecOrDefault(codec).(yamlCodec); !ok || !bytes.Contains(data, []byte(IncludeTag)) {
+		return false, codecOrDefault(codec).Unmarshal(data, v)
+	}
+	var document yaml.Node
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		return false, err
+	}
+	included, err := resolveIncludes(path, &document, readFile, []string{path})
+	if err != nil {
+		return false, err
+	}
+	return included, document.Decode(v)
+}
+
+// resolveIncludes replaces every node tagged IncludeTag under node, which was
+// parsed from the file at path, with the content of the file it names. chain
+// holds the files being resolved, to detect cycles.
+func resolveIncludes(path string, node *yaml.Node, readFile readFileFunc, chain []string) (bool, error) {
+	if node.Tag != IncludeTag {
+		included := false
+		for _, child := range node.Content {
+			childIncluded, err := resolveIncludes(path, child, readFile, chain)
+			if err != nil {
+				return false, err
+			}
+			included = included || childIncluded
+		}
+		return included, nil
+	}
+	if node.Kind != yaml.ScalarNode {
+		return false, fmt.Errorf("%s in %s must name a file", IncludeTag, path)
+	}
+	target := node.Value
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	target = filepath.Clean(target)
+	for _, seen := range chain {
+		if seen == target {
+			return false, fmt.Errorf("%w: %s", ErrCircularInclude, strings.Join(append(chain, target), " -> "))
+		}
+	}
+	data, err := readFile(target)
+	if err != nil {
+		return false, err
+	}
+	var document yaml.Node
+	err = yaml.Unmarshal(data, &document)
+	if err != nil {
+		return false, err
+	}
+	_, err = resolveIncludes(target, &document, readFile, append(chain[:len(chain):len(chain)], target))
+	if err != nil {
+		return false, err
+	}
+	if len(document.Content) == 0 {
+		// An empty file includes a null value.
+		*node = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+		return true, nil
+	}
+	*node = *document.Content[0]
+	return true, nil
+}
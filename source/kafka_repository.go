@@ -0,0 +1,351 @@
+package source
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Default backoff bounds of the live consumer of a KafkaRepository.
+const (
+	DefaultKafkaMinBackoff = 100 * time.Millisecond
+	DefaultKafkaMaxBackoff = 30 * time.Second
+)
+
+// ErrNoKafkaMessage is returned by the Refresh of a KafkaRepository whose
+// partition holds no message yet.
+var ErrNoKafkaMessage = errors.New("no message in kafka partition")
+
+// ErrKafkaClosed is returned by the Refresh of a KafkaRepository after Close.
+var ErrKafkaClosed = errors.New("kafka repository is closed")
+
+// KafkaMessage is a message read from a Kafka partition.
+type KafkaMessage struct {
+	Offset int64  // Offset of the message in the partition
+	Value  []byte // Value of the message
+}
+
+// KafkaConsumer is the subset of the operations of a Kafka consumer of a
+// single partition used by KafkaRepository.
+type KafkaConsumer interface {
+	// LastOffset returns the offset of the last message of the partition, or
+	// -1 if the partition holds no message.
+	LastOffset(ctx context.Context) (int64, error)
+	// Read returns the first message at or after offset, blocking until there
+	// is one or ctx is canceled.
+	Read(ctx context.Context, offset int64) (KafkaMessage, error)
+	// Close closes the connections of the consumer.
+	Close() error
+}
+
+// KafkaConfig configures the consumer of a KafkaRepository.
+type KafkaConfig struct {
+	Partition int           // Partition of the topic holding the config snapshots
+	TLS       *tls.Config   // TLS configuration of the connections to the brokers, if they require TLS
+	Username  string        // User authenticated with SASL/PLAIN, if the brokers require SASL
+	Password  string        // Password of the user
+	Timeout   time.Duration // Timeout of connecting to a broker, none when 0
+	Live      bool          // Whether to keep consuming after the first refresh, applying new snapshots as they are published
+	Codec     Codec         // Codec used to parse each message, defaults to YAMLCodec
+	Consumer  KafkaConsumer // Consumer of the partition, created from the other fields when nil
+}
+
+// KafkaRepository is a struct that implements the Repository interface for
+// handling configuration data published as full config documents to a
+// partition of a Kafka topic, typically a compacted one. Refresh reads the
+// partition to its end and loads its last message. With Live set, the first
+// successful refresh also starts consuming the partition in the background,
+// and later messages are applied as they are published; later refreshes do
+// not contact Kafka and return the error of the last failed read while the
+// consumer is retrying. A message that does not parse leaves the current data
+// in place. Close, which Client.Close calls, stops consuming and tears down
+// the consumer.
+type KafkaRepository struct {
+	sync.RWMutex                          // RWMutex to synchronize access to data
+	changeNotifier                        // Notifies the messages applied by the live consumer
+	Name           string                 // Name of the configuration source
+	Brokers        []string               // Addresses of the Kafka brokers
+	Topic          string                 // Topic the config snapshots are published to
+	Partition      int                    // Partition of the topic holding the config snapshots
+	Live           bool                   // Whether to keep consuming after the first refresh
+	Codec          Codec                  // Codec used to parse each message, defaults to YAMLCodec
+	Consumer       KafkaConsumer          // Consumer of the partition
+	MinBackoff     time.Duration          // First delay before reading again after a failed read, defaults to DefaultKafkaMinBackoff
+	MaxBackoff     time.Duration          // Maximum delay between failed reads, defaults to DefaultKafkaMaxBackoff
+	data           map[string]interface{} // Map to store the configuration data
+	rawData        []byte                 // Raw data of the loaded message
+	offset         int64                  // Offset of the loaded message, -1 when none is loaded
+	err            error                  // Error of the last read of the live consumer, nil after a successful one
+	started        bool                   // Whether the live consumer has been started
+	closed         bool                   // Whether Close has been called
+	cancel         context.CancelFunc     // Cancels the live consumer
+	done           chan struct{}          // Closed when the live consumer returns
+}
+
+// NewKafkaRepository creates a KafkaRepository reading the config snapshots
+// published to topic on the given brokers, with the consumer configured by
+// config.
+func NewKafkaRepository(name string, brokers []string, topic string, config KafkaConfig) *KafkaRepository {
+	repository := &KafkaRepository{
+		Name:      name,
+		Brokers:   brokers,
+		Topic:     topic,
+		Partition: config.Partition,
+		Live:      config.Live,
+		Codec:     config.Codec,
+		Consumer:  config.Consumer,
+		offset:    -1,
+	}
+	if repository.Consumer == nil {
+		dialer := &kafka.Dialer{Timeout: config.Timeout, TLS: config.TLS}
+		if config.Username != "" {
+			dialer.SASLMechanism = plain.Mechanism{Username: config.Username, Password: config.Password}
+		}
+		repository.Consumer = &kafkaGoConsumer{dialer: dialer, brokers: brokers, topic: topic, partition: config.Partition}
+	}
+	return repository
+}
+
+// GetName returns the name of the configuration source.
+func (r *KafkaRepository) GetName() string {
+	return r.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (r *KafkaRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	r.RLock()
+	defer r.RUnlock()
+	config, isPresent = r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns the value of the loaded message.
+func (r *KafkaRepository) GetRawData() []byte {
+	r.RLock()
+	defer r.RUnlock()
+	return r.rawData
+}
+
+// Version returns the offset of the loaded message.
+func (r *KafkaRepository) Version() string {
+	r.RLock()
+	defer r.RUnlock()
+	if r.data == nil {
+		return ""
+	}
+	return strconv.FormatInt(r.offset, 10)
+}
+
+// Keys returns the names of the configurations in the loaded message.
+func (r *KafkaRepository) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.data)
+}
+
+// Refresh reads the partition to its end and loads its last message, unless
+// it is already loaded. With Live set, the first successful refresh starts
+// the live consumer, and later refreshes return its last error instead.
+func (r *KafkaRepository) Refresh() error {
+	r.Lock()
+	defer r.Unlock()
+	if r.closed {
+		return ErrKafkaClosed
+	}
+	if r.started {
+		return r.err
+	}
+
+	ctx := context.Background()
+	last, err := r.Consumer.LastOffset(ctx)
+	if err != nil {
+		logrus.Debug("error reading last offset")
+		return err
+	}
+	if last < 0 {
+		return ErrNoKafkaMessage
+	}
+	if last != r.offset {
+		message, err := r.Consumer.Read(ctx, last)
+		if err != nil {
+			logrus.Debug("error reading message")
+			return err
+		}
+		err = r.load(message)
+		if err != nil {
+			return err
+		}
+	} else {
+		logrus.Debug("offset unchanged, skipping reparse")
+	}
+
+	if r.Live {
+		ctx, cancel := context.WithCancel(context.Background())
+		r.started = true
+		r.cancel = cancel
+		r.done = make(chan struct{})
+		go r.consume(ctx, r.offset+1)
+	}
+	return nil
+}
+
+// Close stops the live consumer, waits for it to return and closes the consumer.
+func (r *KafkaRepository) Close() error {
+	r.Lock()
+	r.closed = true
+	cancel, done := r.cancel, r.done
+	r.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	return r.Consumer.Close()
+}
+
+// load parses message and replaces the data with it. It must be called with
+// the lock held.
+func (r *KafkaRepository) load(message KafkaMessage) error {
+	var parsed map[string]interface{}
+	err := codecOrDefault(r.Codec).Unmarshal(message.Value, &parsed)
+	if err != nil {
+		logrus.WithField("offset", message.Offset).Debug("error unmarshalling message")
+		return err
+	}
+	r.data = parsed
+	r.rawData = message.Value
+	r.offset = message.Offset
+	return nil
+}
+
+// consume applies the messages of the partition from offset on until ctx is
+// canceled, retrying failed reads with exponential backoff.
+func (r *KafkaRepository) consume(ctx context.Context, offset int64) {
+	defer close(r.done)
+	backoff := r.minBackoff()
+	for {
+		message, err := r.Consumer.Read(ctx, offset)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			logrus.WithError(err).Warn("error consuming kafka partition, retrying")
+			r.Lock()
+			r.err = err
+			r.Unlock()
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			backoff *= 2
+			if max := r.maxBackoff(); backoff > max {
+				backoff = max
+			}
+			continue
+		}
+		backoff = r.minBackoff()
+		offset = message.Offset + 1
+
+		r.Lock()
+		r.err = nil
+		err = r.load(message)
+		r.Unlock()
+		if err != nil {
+			logrus.WithError(err).WithField("offset", message.Offset).Warn("skipping kafka message that does not parse")
+			continue
+		}
+		r.notifyChange()
+	}
+}
+
+func (r *KafkaRepository) minBackoff() time.Duration {
+	if r.MinBackoff > 0 {
+		return r.MinBackoff
+	}
+	return DefaultKafkaMinBackoff
+}
+
+func (r *KafkaRepository) maxBackoff() time.Duration {
+	if r.MaxBackoff > 0 {
+		return r.MaxBackoff
+	}
+	return DefaultKafkaMaxBackoff
+}
+
+// kafkaGoConsumer implements KafkaConsumer with kafka-go.
+type kafkaGoConsumer struct {
+	dialer    *kafka.Dialer
+	brokers   []string
+	topic     string
+	partition int
+	reader    *kafka.Reader // Reader of the partition, created on the first read
+	next      int64         // Offset the reader reads next
+}
+
+func (k *kafkaGoConsumer) LastOffset(ctx context.Context) (int64, error) {
+	var err error
+	for _, broker := range k.brokers {
+		var conn *kafka.Conn
+		conn, err = k.dialer.DialLeader(ctx, "tcp", broker, k.topic, k.partition)
+		if err != nil {
+			logrus.WithField("broker", broker).Debug("error connecting to broker")
+			continue
+		}
+		var first, last int64
+		first, last, err = conn.ReadOffsets()
+		_ = conn.Close()
+		if err != nil {
+			continue
+		}
+		// The last offset is the one the next message will be written at.
+		if last <= first {
+			return -1, nil
+		}
+		return last - 1, nil
+	}
+	if err == nil {
+		err = errors.New("no kafka brokers configured")
+	}
+	return 0, err
+}
+
+func (k *kafkaGoConsumer) Read(ctx context.Context, offset int64) (KafkaMessage, error) {
+	if k.reader == nil {
+		k.reader = kafka.NewReader(kafka.ReaderConfig{
+			Brokers:   k.brokers,
+			Topic:     k.topic,
+			Partition: k.partition,
+			Dialer:    k.dialer,
+		})
+		k.next = -1
+	}
+	if offset != k.next {
+		err := k.reader.SetOffset(offset)
+		if err != nil {
+			return KafkaMessage{}, err
+		}
+	}
+	message, err := k.reader.ReadMessage(ctx)
+	if err != nil {
+		// Seek again on the next read, the reader may have moved.
+		k.next = -1
+		return KafkaMessage{}, err
+	}
+	k.next = message.Offset + 1
+	return KafkaMessage{Offset: message.Offset, Value: message.Value}, nil
+}
+
+func (k *kafkaGoConsumer) Close() error {
+	if k.reader == nil {
+		return nil
+	}
+	return k.reader.Close()
+}
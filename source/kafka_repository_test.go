@@ -0,0 +1,188 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeKafka is an in-memory KafkaConsumer of a single partition.
+type fakeKafka struct {
+	mu       sync.Mutex
+	messages []KafkaMessage
+	appended chan struct{} // Closed and replaced on every append
+	readErr  error
+	closed   bool
+}
+
+func newFakeKafka(values ...string) *fakeKafka {
+	f := &fakeKafka{appended: make(chan struct{})}
+	for _, value := range values {
+		f.publish(value)
+	}
+	return f
+}
+
+func (f *fakeKafka) publish(value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, KafkaMessage{Offset: int64(len(f.messages)), Value: []byte(value)})
+	close(f.appended)
+	f.appended = make(chan struct{})
+}
+
+func (f *fakeKafka) setReadErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.readErr = err
+	close(f.appended)
+	f.appended = make(chan struct{})
+}
+
+func (f *fakeKafka) LastOffset(_ context.Context) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return int64(len(f.messages)) - 1, nil
+}
+
+func (f *fakeKafka) Read(ctx context.Context, offset int64) (KafkaMessage, error) {
+	for {
+		f.mu.Lock()
+		if f.readErr != nil {
+			err := f.readErr
+			f.mu.Unlock()
+			return KafkaMessage{}, err
+		}
+		if offset < int64(len(f.messages)) {
+			message := f.messages[offset]
+			f.mu.Unlock()
+			return message, nil
+		}
+		appended := f.appended
+		f.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return KafkaMessage{}, ctx.Err()
+		case <-appended:
+		}
+	}
+}
+
+func (f *fakeKafka) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func TestKafkaRepository(t *testing.T) {
+	kafka := newFakeKafka()
+	repository := NewKafkaRepository("kafka", []string{"localhost:9092"}, "config", KafkaConfig{Consumer: kafka})
+	if !errors.Is(repository.Refresh(), ErrNoKafkaMessage) {
+		t.Errorf("Expected ErrNoKafkaMessage for an empty partition")
+	}
+
+	// The last message of the partition is loaded.
+	kafka.publish("name: John\n")
+	kafka.publish("name: Jane\n")
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "Jane" || repository.Version() != "1" {
+		t.Errorf("Expected name Jane at offset 1, got %v at %s", name, repository.Version())
+	}
+	if string(repository.GetRawData()) != "name: Jane\n" {
+		t.Errorf("Expected the raw data of the message, got %q", repository.GetRawData())
+	}
+
+	// Without live consumption, new messages are loaded by the next refresh.
+	kafka.publish("name: Jack\n")
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected name to be Jane until the refresh, got %v", name)
+	}
+	_ = repository.Refresh()
+	name, _ = repository.GetData("name")
+	if name != "Jack" {
+		t.Errorf("Expected name to be Jack, got %v", name)
+	}
+
+	// A message that does not parse leaves the data in place.
+	kafka.publish("name: [unclosed\n")
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a message that does not parse, got nil")
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jack" || repository.Version() != "2" {
+		t.Errorf("Expected the last good data to be kept, got %v at %s", name, repository.Version())
+	}
+
+	_ = repository.Close()
+	if !kafka.closed {
+		t.Errorf("Expected Close to close the consumer")
+	}
+	if !errors.Is(repository.Refresh(), ErrKafkaClosed) {
+		t.Errorf("Expected ErrKafkaClosed after Close")
+	}
+}
+
+func TestKafkaRepositoryLive(t *testing.T) {
+	kafka := newFakeKafka("name: John\n")
+	repository := NewKafkaRepository("kafka", []string{"localhost:9092"}, "config", KafkaConfig{Consumer: kafka, Live: true})
+	repository.MinBackoff = time.Millisecond
+	repository.MaxBackoff = 5 * time.Millisecond
+	notified := notifications(repository)
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// New messages are applied as they are published, and notified.
+	kafka.publish("name: Jane\n")
+	waitForData(t, repository, "name", "Jane")
+	waitForNotification(t, notified)
+
+	// A message that does not parse is skipped.
+	kafka.publish("name: [unclosed\n")
+	kafka.publish("name: Jack\n")
+	waitForData(t, repository, "name", "Jack")
+
+	// Refresh reports the errors of the live consumer while it retries.
+	kafka.setReadErr(errors.New("broker unavailable"))
+	deadline := time.Now().Add(2 * time.Second)
+	for repository.Refresh() == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected Refresh to report the read error, timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	kafka.setReadErr(nil)
+	kafka.publish("name: Jill\n")
+	waitForData(t, repository, "name", "Jill")
+	deadline = time.Now().Add(2 * time.Second)
+	for repository.Refresh() != nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected Refresh to succeed after recovery, timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Close stops the live consumer and tears down the consumer.
+	done := make(chan struct{})
+	go func() {
+		_ = repository.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected Close to stop the live consumer, timed out")
+	}
+	if !kafka.closed {
+		t.Errorf("Expected Close to close the consumer")
+	}
+}
@@ -0,0 +1,233 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrSecretNotFound is returned by a KeyVaultAPI when a secret or secret
+// version does not exist, for example because it has been soft-deleted.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// KeyVaultSecret is a secret read from an Azure Key Vault.
+type KeyVaultSecret struct {
+	Name    string // Name of the secret
+	Version string // Version of the secret
+	Value   string // Value of the secret
+}
+
+// KeyVaultAPI is the subset of Azure Key Vault operations used by KeyVaultRepository.
+type KeyVaultAPI interface {
+	// ListSecretNames returns the names of the enabled secrets of the vault.
+	ListSecretNames(ctx context.Context) ([]string, error)
+	// GetSecret returns the given version of a secret, or its latest version
+	// if version is empty. It returns ErrSecretNotFound if there is no such secret.
+	GetSecret(ctx context.Context, name string, version string) (KeyVaultSecret, error)
+}
+
+// KeyVaultRepository is a struct that implements the Repository interface for
+// handling configuration data stored as secrets in an Azure Key Vault. Each
+// secret is exposed under its name, without the prefix.
+// Secret values are never logged, and GetRawData returns nil so that they are
+// not served or included in raw snapshots.
+type KeyVaultRepository struct {
+	sync.RWMutex                        // RWMutex to synchronize access to data during refresh
+	Name         string                 // Name of the configuration source
+	VaultURL     string                 // URL of the Key Vault
+	Prefix       string                 // Only secrets whose name starts with Prefix are read
+	SecretNames  []string               // Explicit secrets to read, as "name" or "name/version", instead of listing the vault
+	Client       KeyVaultAPI            // Key Vault client
+	data         map[string]interface{} // Map to store the configuration data
+	version      string                 // Version marker derived from the versions of the loaded secrets
+}
+
+// KeyVaultOption configures a KeyVaultRepository created with NewKeyVaultRepository.
+type KeyVaultOption func(*KeyVaultRepository)
+
+// WithSecretPrefix only reads the secrets whose name starts with prefix.
+func WithSecretPrefix(prefix string) KeyVaultOption {
+	return func(k *KeyVaultRepository) {
+		k.Prefix = prefix
+	}
+}
+
+// WithSecretNames reads the given secrets instead of listing the vault. A name
+// may pin a version as "name/version"; otherwise the latest version is read.
+func WithSecretNames(names ...string) KeyVaultOption {
+	return func(k *KeyVaultRepository) {
+		k.SecretNames = append(k.SecretNames, names...)
+	}
+}
+
+// WithKeyVaultClient sets the client used to read the secrets.
+func WithKeyVaultClient(client KeyVaultAPI) KeyVaultOption {
+	return func(k *KeyVaultRepository) {
+		k.Client = client
+	}
+}
+
+// NewKeyVaultRepository creates a KeyVaultRepository reading secrets from the
+// Key Vault at vaultURL with the given credential.
+func NewKeyVaultRepository(name string, vaultURL string, credential azcore.TokenCredential, opts ...KeyVaultOption) (*KeyVaultRepository, error) {
+	repository := &KeyVaultRepository{
+		Name:     name,
+		VaultURL: vaultURL,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	if repository.Client == nil {
+		client, err := azsecrets.NewClient(vaultURL, credential, nil)
+		if err != nil {
+			return nil, err
+		}
+		repository.Client = &azsecretsClient{client: client}
+	}
+	return repository, nil
+}
+
+// GetName returns the name of the configuration source.
+func (k *KeyVaultRepository) GetName() string {
+	return k.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (k *KeyVaultRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	k.RLock()
+	defer k.RUnlock()
+	config, isPresent = k.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, so that secret values are never exposed as raw data.
+func (k *KeyVaultRepository) GetRawData() []byte {
+	return nil
+}
+
+// Version returns a marker that changes whenever the version of any loaded secret changes.
+func (k *KeyVaultRepository) Version() string {
+	k.RLock()
+	defer k.RUnlock()
+	return k.version
+}
+
+// Keys returns the names of the loaded secrets.
+func (k *KeyVaultRepository) Keys() []string {
+	k.RLock()
+	defer k.RUnlock()
+	return sortedKeys(k.data)
+}
+
+// Refresh reads the secrets from the Key Vault and replaces the data map with them.
+// Secrets that no longer exist, such as soft-deleted ones, are skipped.
+func (k *KeyVaultRepository) Refresh() error {
+	data, version, err := k.load(context.Background())
+	if err != nil {
+		return err
+	}
+	k.Lock()
+	defer k.Unlock()
+	k.data = data
+	k.version = version
+	return nil
+}
+
+// Load reads the secrets from the Key Vault the way Refresh does and returns
+// them, without replacing the loaded data.
+func (k *KeyVaultRepository) Load() (map[string]interface{}, error) {
+	data, _, err := k.load(context.Background())
+	return data, err
+}
+
+// load reads the secrets and returns them with a version derived from their
+// secret versions.
+func (k *KeyVaultRepository) load(ctx context.Context) (map[string]interface{}, string, error) {
+	names := k.SecretNames
+	if len(names) == 0 {
+		listed, err := k.Client.ListSecretNames(ctx)
+		if err != nil {
+			logrus.Debug("error listing secrets")
+			return nil, "", err
+		}
+		names = listed
+	}
+
+	data := map[string]interface{}{}
+	var versions []string
+	for _, name := range names {
+		name, version, _ := strings.Cut(name, "/")
+		if !strings.HasPrefix(name, k.Prefix) {
+			continue
+		}
+		secret, err := k.Client.GetSecret(ctx, name, version)
+		if errors.Is(err, ErrSecretNotFound) {
+			logrus.WithField("secret", name).Debug("secret not found, skipping")
+			continue
+		}
+		if err != nil {
+			logrus.WithField("secret", name).Debug("error getting secret")
+			return nil, "", err
+		}
+		data[strings.TrimPrefix(name, k.Prefix)] = secret.Value
+		versions = append(versions, name+"/"+secret.Version)
+	}
+
+	// Derive the version from the secret versions, never from their values.
+	sort.Strings(versions)
+	sum := sha256.Sum256([]byte(strings.Join(versions, "\n")))
+	return data, hex.EncodeToString(sum[:]), nil
+}
+
+// azsecretsClient implements KeyVaultAPI with the Azure SDK.
+type azsecretsClient struct {
+	client *azsecrets.Client
+}
+
+func (a *azsecretsClient) ListSecretNames(ctx context.Context) ([]string, error) {
+	var names []string
+	pager := a.client.NewListSecretPropertiesPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, properties := range page.Value {
+			if properties.ID == nil {
+				continue
+			}
+			if properties.Attributes != nil && properties.Attributes.Enabled != nil && !*properties.Attributes.Enabled {
+				continue
+			}
+			names = append(names, properties.ID.Name())
+		}
+	}
+	return names, nil
+}
+
+func (a *azsecretsClient) GetSecret(ctx context.Context, name string, version string) (KeyVaultSecret, error) {
+	response, err := a.client.GetSecret(ctx, name, version, nil)
+	if err != nil {
+		var responseErr *azcore.ResponseError
+		if errors.As(err, &responseErr) && responseErr.StatusCode == http.StatusNotFound {
+			return KeyVaultSecret{}, ErrSecretNotFound
+		}
+		return KeyVaultSecret{}, err
+	}
+	secret := KeyVaultSecret{Name: name}
+	if response.ID != nil {
+		secret.Version = response.ID.Version()
+	}
+	if response.Value != nil {
+		secret.Value = *response.Value
+	}
+	return secret, nil
+}
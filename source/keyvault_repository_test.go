@@ -0,0 +1,106 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"github.com/sirupsen/logrus"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fakeKeyVault is an in-memory KeyVaultAPI. Secrets are keyed by name and
+// then version, with the latest version stored under "".
+type fakeKeyVault struct {
+	secrets map[string]map[string]string
+	listed  []string
+}
+
+func (f *fakeKeyVault) ListSecretNames(_ context.Context) ([]string, error) {
+	return f.listed, nil
+}
+
+func (f *fakeKeyVault) GetSecret(_ context.Context, name string, version string) (KeyVaultSecret, error) {
+	versions, ok := f.secrets[name]
+	if !ok {
+		return KeyVaultSecret{}, ErrSecretNotFound
+	}
+	value, ok := versions[version]
+	if !ok {
+		return KeyVaultSecret{}, ErrSecretNotFound
+	}
+	if version == "" {
+		version = "latest-" + name
+	}
+	return KeyVaultSecret{Name: name, Version: version, Value: value}, nil
+}
+
+func TestKeyVaultRepository(t *testing.T) {
+	var logs bytes.Buffer
+	logrus.SetOutput(&logs)
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.DebugLevel)
+	defer func() {
+		logrus.SetOutput(os.Stderr)
+		logrus.SetLevel(level)
+	}()
+
+	vault := &fakeKeyVault{
+		secrets: map[string]map[string]string{
+			"app-db-password": {"": "hunter2", "v1": "old-password"},
+			"other-token":     {"": "unrelated"},
+		},
+		// app-deleted has been soft-deleted since it was listed.
+		listed: []string{"app-db-password", "app-deleted", "other-token"},
+	}
+	repository, err := NewKeyVaultRepository("vault", "https://example.vault.azure.net", nil, WithSecretPrefix("app-"), WithKeyVaultClient(vault))
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	password, ok := repository.GetData("db-password")
+	if !ok || password != "hunter2" {
+		t.Errorf("Expected db-password to be the latest version, got %v", password)
+	}
+	if keys := repository.Keys(); len(keys) != 1 {
+		t.Errorf("Expected only the prefixed secret to be loaded, got %v", keys)
+	}
+	if repository.GetRawData() != nil {
+		t.Errorf("Expected no raw data for secrets")
+	}
+	if strings.Contains(logs.String(), "hunter2") {
+		t.Errorf("Expected secret values to stay out of the logs")
+	}
+	latest := repository.Version()
+
+	// Load reads the secrets without replacing the data.
+	vault.secrets["app-db-password"][""] = "correct-horse"
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	if loaded["db-password"] != "correct-horse" {
+		t.Errorf("Expected loaded db-password to be correct-horse, got %v", loaded["db-password"])
+	}
+	password, _ = repository.GetData("db-password")
+	if password != "hunter2" {
+		t.Errorf("Expected db-password to stay hunter2, got %v", password)
+	}
+
+	// A pinned version is read instead of the latest one.
+	repository.SecretNames = []string{"app-db-password/v1"}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	password, _ = repository.GetData("db-password")
+	if password != "old-password" {
+		t.Errorf("Expected db-password to be the pinned version, got %v", password)
+	}
+	if repository.Version() == latest {
+		t.Errorf("Expected the version to change with the secret version")
+	}
+}
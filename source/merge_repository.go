@@ -0,0 +1,157 @@
+package source
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrUnlistableRepository is returned by the Refresh of a MergeRepository
+// holding a repository that does not implement KeyLister, whose
+// configurations cannot be merged.
+var ErrUnlistableRepository = errors.New("repository cannot list its configurations")
+
+// MergeRepository is a struct that implements the Repository interface by
+// deep-merging the configurations of several repositories, such as a base
+// file and an environment overlay, in order. Later repositories override
+// earlier ones configuration by configuration: maps are merged recursively,
+// and any other value, including a slice, replaces the earlier one, so the
+// result only depends on the order of the repositories. Every repository
+// must implement KeyLister. A refresh in which any repository fails leaves
+// the current merged data in place.
+type MergeRepository struct {
+	sync.RWMutex                        // RWMutex to synchronize access to data during refresh
+	Name         string                 // Name of the configuration source
+	Repositories []Repository           // Ordered list of repositories, lowest precedence first
+	data         map[string]interface{} // Merged configuration data
+	sources      map[string]string      // Name of the last repository that set each configuration
+}
+
+// NewMergeRepository creates a MergeRepository merging the given
+// repositories, later ones overriding earlier ones.
+func NewMergeRepository(name string, repositories ...Repository) *MergeRepository {
+	return &MergeRepository{
+		Name:         name,
+		Repositories: repositories,
+	}
+}
+
+// GetName returns the name of the configuration source.
+func (m *MergeRepository) GetName() string {
+	return m.Name
+}
+
+// GetData returns the merged configuration with the given name.
+func (m *MergeRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	m.RLock()
+	defer m.RUnlock()
+	config, isPresent = m.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, as the merged data has no raw document.
+func (m *MergeRepository) GetRawData() []byte {
+	return nil
+}
+
+// Keys returns the names of the merged configurations.
+func (m *MergeRepository) Keys() []string {
+	m.RLock()
+	defer m.RUnlock()
+	return sortedKeys(m.data)
+}
+
+// SourceOf returns the name of the last repository that sets the
+// configuration with the given name, which overrides the others.
+func (m *MergeRepository) SourceOf(configName string) (string, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	name, ok := m.sources[configName]
+	return name, ok
+}
+
+// Refresh refreshes every repository in order and replaces the data with
+// their merged configurations.
+func (m *MergeRepository) Refresh() error {
+	for _, repository := range m.Repositories {
+		if _, ok := repository.(KeyLister); !ok {
+			return ErrUnlistableRepository
+		}
+		err := repository.Refresh()
+		if err != nil {
+			return err
+		}
+	}
+
+	data := map[string]interface{}{}
+	sources := map[string]string{}
+	for _, repository := range m.Repositories {
+		for _, key := range repository.(KeyLister).Keys() {
+			value, ok := repository.GetData(key)
+			if !ok {
+				continue
+			}
+			data[key] = mergeValues(data[key], value)
+			sources[key] = repository.GetName()
+		}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.data = data
+	m.sources = sources
+	return nil
+}
+
+// Load loads every repository in order the way Refresh refreshes them and
+// returns their merged configurations, without replacing the merged data.
+// Every repository must implement Loader.
+func (m *MergeRepository) Load() (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	for _, repository := range m.Repositories {
+		loader, ok := repository.(Loader)
+		if !ok {
+			return nil, ErrUnloadableRepository
+		}
+		loaded, err := loader.Load()
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range loaded {
+			data[key] = mergeValues(data[key], value)
+		}
+	}
+	return data, nil
+}
+
+// Close closes every repository that can be closed and returns the first error.
+func (m *MergeRepository) Close() error {
+	var first error
+	for _, repository := range m.Repositories {
+		if closer, ok := repository.(io.Closer); ok {
+			if err := closer.Close(); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// mergeValues returns override merged over base. Maps are merged recursively
+// into new maps, so that neither is modified, as both are shared with the
+// repositories they came from; any other value of override replaces base.
+func mergeValues(base interface{}, override interface{}) interface{} {
+	overrideMap, ok := override.(map[string]interface{})
+	baseMap, baseOK := base.(map[string]interface{})
+	if !ok || !baseOK {
+		return override
+	}
+	merged := make(map[string]interface{}, len(baseMap)+len(overrideMap))
+	for key, value := range baseMap {
+		merged[key] = value
+	}
+	for key, value := range overrideMap {
+		merged[key] = mergeValues(merged[key], value)
+	}
+	return merged
+}
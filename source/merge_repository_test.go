@@ -0,0 +1,104 @@
+package source
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// unlistableRepository hides every method of a repository but those of Repository.
+type unlistableRepository struct {
+	Repository
+}
+
+func TestMergeRepository(t *testing.T) {
+	dir := t.TempDir()
+	basePath := filepath.Join(dir, "base.yaml")
+	overlayPath := filepath.Join(dir, "production.yaml")
+	writeFile(t, basePath, "name: John\nregions: [eu, us]\ndb:\n  host: localhost\n  port: 5432\n  pool:\n    size: 10\n    idle: 2\n")
+	writeFile(t, overlayPath, "name: Jane\nregions: [ap]\ndb:\n  host: db.internal\n  pool:\n    size: 50\nreplicas: 3\n")
+	base := &FileRepository{Name: "base", Path: basePath}
+	overlay := &FileRepository{Name: "production", Path: overlayPath}
+	repository := NewMergeRepository("merged", base, overlay)
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// Scalars of later documents override earlier ones.
+	name, _ := repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected name to be Jane, got %v", name)
+	}
+	if source, _ := repository.SourceOf("name"); source != "production" {
+		t.Errorf("Expected name to come from production, got %s", source)
+	}
+
+	// Slices are replaced rather than merged.
+	regions, _ := repository.GetData("regions")
+	if !reflect.DeepEqual(regions, []interface{}{"ap"}) {
+		t.Errorf("Expected regions to be replaced with [ap], got %v", regions)
+	}
+
+	// Maps are merged recursively, without modifying the documents.
+	db, _ := repository.GetData("db")
+	expected := map[string]interface{}{
+		"host": "db.internal",
+		"port": 5432,
+		"pool": map[string]interface{}{"size": 50, "idle": 2},
+	}
+	if !reflect.DeepEqual(db, expected) {
+		t.Errorf("Expected db to be %v, got %v", expected, db)
+	}
+	baseDB, _ := base.GetData("db")
+	if baseDB.(map[string]interface{})["host"] != "localhost" {
+		t.Errorf("Expected the base document to be left unchanged, got %v", baseDB)
+	}
+	keys := repository.Keys()
+	if !reflect.DeepEqual(keys, []string{"db", "name", "regions", "replicas"}) {
+		t.Errorf("Expected keys [db name regions replicas], got %v", keys)
+	}
+
+	// Load merges the documents without replacing the merged data.
+	writeFile(t, overlayPath, "name: Jill\ndb:\n  pool:\n    size: 20\n")
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	expectedDB := map[string]interface{}{
+		"host": "localhost",
+		"port": 5432,
+		"pool": map[string]interface{}{"size": 20, "idle": 2},
+	}
+	if loaded["name"] != "Jill" || !reflect.DeepEqual(loaded["db"], expectedDB) {
+		t.Errorf("Expected the loaded documents to be merged, got %v", loaded)
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected name to stay Jane, got %v", name)
+	}
+
+	// A document that fails to load leaves the merged data in place.
+	writeFile(t, overlayPath, "name: [unclosed\n")
+	overlay.ParseRetryDelay = 1
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a document that does not parse, got nil")
+	}
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected Load to fail for a document that does not parse, got nil")
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected the merged data to be kept, got %v", name)
+	}
+
+	// Repositories that cannot list their configurations cannot be merged.
+	repository = NewMergeRepository("merged", base, unlistableRepository{overlay})
+	if err := repository.Refresh(); !errors.Is(err, ErrUnlistableRepository) {
+		t.Errorf("Expected ErrUnlistableRepository, got %v", err)
+	}
+	if _, err := repository.Load(); !errors.Is(err, ErrUnloadableRepository) {
+		t.Errorf("Expected ErrUnloadableRepository, got %v", err)
+	}
+}
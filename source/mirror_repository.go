@@ -0,0 +1,369 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMirrorHealthTimeout bounds the time the default health check of a
+// MirrorRepository waits for a mirror.
+const DefaultMirrorHealthTimeout = 5 * time.Second
+
+// MirrorHealthCheck checks the health of the mirror at u, returning an error
+// if it is unhealthy.
+type MirrorHealthCheck func(ctx context.Context, u *url.URL) error
+
+// MirrorRepository is a struct that implements the Repository interface for
+// handling configuration data served by several HTTP mirrors of the same configuration file.
+// On each refresh the mirrors are tried in order until one of them succeeds.
+// With a HealthCheckInterval, the mirrors are health-checked in the background
+// and tried fastest healthy mirror first, then mirrors not checked yet, then
+// unhealthy mirrors, each group in the order of URLs. Close, which
+// Client.Close calls, stops the health checks.
+type MirrorRepository struct {
+	sync.RWMutex                                  // RWMutex to synchronize access to data during refresh
+	Name                string                    // Name of the configuration source
+	URLs                []*url.URL                // URLs of the mirrors, in the order they are tried without health checks
+	SocketPath          string                    // Unix domain socket to dial instead of the URL hosts
+	Codec               Codec                     // Codec used to parse the file, defaults to YAMLCodec
+	Timeout             time.Duration             // Timeout of each request to a mirror, so a stalled mirror fails over to the next; none when 0
+	Headers             http.Header               // Headers sent with every request to a mirror; a token from TokenProvider takes precedence
+	TokenProvider       TokenProvider             // Provider of the token sent with every request to a mirror, called again when a mirror answers 401 or 403
+	TokenHeader         string                    // Header the token is sent in as is, defaults to a bearer token in Authorization
+	HealthCheckInterval time.Duration             // Interval of the background health checks ordering the mirrors, none when 0
+	HealthCheck         MirrorHealthCheck         // Checks the health of a mirror, defaults to a HEAD request of its URL answered with 2xx
+	mirrors             []*WebRepository          // Web repositories fetching from each mirror
+	active              *WebRepository            // Mirror that served the current data
+	health              map[string]mirrorHealth   // Result of the last health check of each mirror, by URL
+	probes              map[string]*WebRepository // Web repositories holding the HTTP clients of the default health check, by URL
+	started             bool                      // Whether the health check loop has been started
+	closed              bool                      // Whether Close has been called
+	cancel              context.CancelFunc        // Cancels the health check loop
+	done                chan struct{}             // Closed when the health check loop returns
+}
+
+// mirrorHealth is the result of a health check of a mirror.
+type mirrorHealth struct {
+	healthy bool          // Whether the mirror passed the check
+	latency time.Duration // Time the check took
+}
+
+// GetName returns the name of the configuration source.
+func (m *MirrorRepository) GetName() string {
+	return m.Name
+}
+
+// GetData returns the configuration data served by the active mirror.
+func (m *MirrorRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	m.RLock()
+	defer m.RUnlock()
+	if m.active == nil {
+		return nil, false
+	}
+	return m.active.GetData(configName)
+}
+
+// GetRawData returns the raw data of the configuration file served by the active mirror.
+func (m *MirrorRepository) GetRawData() []byte {
+	m.RLock()
+	defer m.RUnlock()
+	if m.active == nil {
+		return nil
+	}
+	return m.active.GetRawData()
+}
+
+// Version returns the version marker of the data served by the active mirror.
+func (m *MirrorRepository) Version() string {
+	m.RLock()
+	defer m.RUnlock()
+	if m.active == nil {
+		return ""
+	}
+	return m.active.Version()
+}
+
+// Keys returns the names of the configurations served by the active mirror.
+func (m *MirrorRepository) Keys() []string {
+	m.RLock()
+	defer m.RUnlock()
+	if m.active == nil {
+		return nil
+	}
+	return m.active.Keys()
+}
+
+// ServedBy returns the URL of the mirror that served the current data, or nil
+// if no mirror has been refreshed successfully yet.
+func (m *MirrorRepository) ServedBy() *url.URL {
+	m.RLock()
+	defer m.RUnlock()
+	if m.active == nil {
+		return nil
+	}
+	return m.active.URL
+}
+
+// Refresh fetches the configuration file from the first mirror that responds successfully.
+// If every mirror fails, the previously served data is kept and an error
+// combining the errors of all mirrors is returned.
+func (m *MirrorRepository) Refresh() error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.syncMirrors()
+	if len(m.mirrors) == 0 {
+		return errors.New("no mirrors configured")
+	}
+
+	if m.HealthCheckInterval > 0 && !m.started && !m.closed {
+		ctx, cancel := context.WithCancel(context.Background())
+		m.started = true
+		m.cancel = cancel
+		m.done = make(chan struct{})
+		go m.checkHealthEvery(ctx, m.HealthCheckInterval)
+	}
+
+	var errs []error
+	for _, mirror := range m.ordered() {
+		err := mirror.Refresh()
+		if err != nil {
+			logrus.WithField("error", RedactURLs(err.Error(), false)).WithField("mirror", RedactURL(mirror.URL)).Debug("error refreshing mirror")
+			errs = append(errs, err)
+			continue
+		}
+		m.active = mirror
+		return nil
+	}
+	return &mirrorError{errs: errs}
+}
+
+// Load fetches the configuration file from the first mirror that responds
+// successfully, in the order Refresh tries them, and returns its
+// configurations without replacing the data served or the active mirror.
+func (m *MirrorRepository) Load() (map[string]interface{}, error) {
+	m.Lock()
+	m.syncMirrors()
+	mirrors := m.ordered()
+	m.Unlock()
+	if len(mirrors) == 0 {
+		return nil, errors.New("no mirrors configured")
+	}
+
+	var errs []error
+	for _, mirror := range mirrors {
+		data, err := mirror.Load()
+		if err != nil {
+			logrus.WithField("error", RedactURLs(err.Error(), false)).WithField("mirror", RedactURL(mirror.URL)).Debug("error loading mirror")
+			errs = append(errs, err)
+			continue
+		}
+		return data, nil
+	}
+	return nil, &mirrorError{errs: errs}
+}
+
+// Order returns the URLs of the mirrors in the order the next refresh tries
+// them, as ordered by the last health checks.
+func (m *MirrorRepository) Order() []*url.URL {
+	m.Lock()
+	defer m.Unlock()
+	m.syncMirrors()
+	mirrors := m.ordered()
+	order := make([]*url.URL, len(mirrors))
+	for i, mirror := range mirrors {
+		order[i] = mirror.URL
+	}
+	return order
+}
+
+// CheckHealth health-checks every mirror at once and reorders them by the
+// results. It is called every HealthCheckInterval in the background, and may
+// be called directly to reorder the mirrors without one.
+func (m *MirrorRepository) CheckHealth(ctx context.Context) {
+	m.Lock()
+	m.syncMirrors()
+	mirrors := m.mirrors
+	checks := make([]func(ctx context.Context) error, len(mirrors))
+	probes := make(map[string]*WebRepository, len(mirrors))
+	for i, mirror := range mirrors {
+		u := mirror.URL
+		if m.HealthCheck != nil {
+			check := m.HealthCheck
+			checks[i] = func(ctx context.Context) error {
+				return check(ctx, u)
+			}
+			continue
+		}
+		// Keep a probe per mirror, so that its HTTP client and connections are
+		// reused from one check to the next.
+		probe, ok := m.probes[u.String()]
+		if !ok || probe.SocketPath != m.SocketPath {
+			probe = &WebRepository{URL: u, SocketPath: m.SocketPath}
+		}
+		probe.Headers = m.Headers
+		probes[u.String()] = probe
+		client := probe.client()
+		checks[i] = func(ctx context.Context) error {
+			return headCheck(ctx, client, probe)
+		}
+	}
+	m.probes = probes
+	m.Unlock()
+
+	results := make([]mirrorHealth, len(mirrors))
+	var wg sync.WaitGroup
+	for i, mirror := range mirrors {
+		wg.Add(1)
+		go func(i int, u *url.URL) {
+			defer wg.Done()
+			start := time.Now()
+			err := checks[i](ctx)
+			results[i] = mirrorHealth{healthy: err == nil, latency: time.Since(start)}
+			if err != nil {
+				logrus.WithField("error", RedactURLs(err.Error(), false)).WithField("mirror", RedactURL(u)).Debug("mirror unhealthy")
+			}
+		}(i, mirror.URL)
+	}
+	wg.Wait()
+
+	health := make(map[string]mirrorHealth, len(mirrors))
+	for i, mirror := range mirrors {
+		health[mirror.URL.String()] = results[i]
+	}
+	m.Lock()
+	m.health = health
+	m.Unlock()
+}
+
+// Close stops the health check loop and waits for it to return.
+func (m *MirrorRepository) Close() error {
+	m.Lock()
+	m.closed = true
+	cancel, done := m.cancel, m.done
+	m.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	return nil
+}
+
+// checkHealthEvery runs CheckHealth every interval until ctx is canceled.
+func (m *MirrorRepository) checkHealthEvery(ctx context.Context, interval time.Duration) {
+	defer close(m.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.CheckHealth(ctx)
+		}
+	}
+}
+
+// headCheck is the default health check of a mirror. It sends a HEAD request
+// for the file of mirror with client, the HTTP client of mirror, and the
+// Headers of mirror, and expects a 2xx response within
+// DefaultMirrorHealthTimeout.
+func headCheck(ctx context.Context, client *http.Client, mirror *WebRepository) error {
+	ctx, cancel := context.WithTimeout(ctx, DefaultMirrorHealthTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, mirror.requestURL(), nil)
+	if err != nil {
+		return err
+	}
+	for name, values := range mirror.Headers {
+		request.Header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{StatusCode: resp.StatusCode, URL: RedactURL(mirror.URL)}
+	}
+	return nil
+}
+
+// ordered returns the mirrors in the order they are tried: healthy mirrors by
+// latency, then mirrors not checked yet, then unhealthy mirrors.
+func (m *MirrorRepository) ordered() []*WebRepository {
+	rank := func(mirror *WebRepository) (int, time.Duration) {
+		health, ok := m.health[mirror.URL.String()]
+		switch {
+		case !ok:
+			return 1, 0
+		case health.healthy:
+			return 0, health.latency
+		default:
+			return 2, 0
+		}
+	}
+	mirrors := append([]*WebRepository(nil), m.mirrors...)
+	sort.SliceStable(mirrors, func(i, j int) bool {
+		rankI, latencyI := rank(mirrors[i])
+		rankJ, latencyJ := rank(mirrors[j])
+		if rankI != rankJ {
+			return rankI < rankJ
+		}
+		return latencyI < latencyJ
+	})
+	return mirrors
+}
+
+// syncMirrors updates the web repositories of the mirrors to match URLs and
+// the settings of the requests, keeping the repositories of mirrors whose URL
+// did not change.
+func (m *MirrorRepository) syncMirrors() {
+	mirrors := make([]*WebRepository, len(m.URLs))
+	for i, u := range m.URLs {
+		mirror := &WebRepository{Name: m.Name, URL: u}
+		if i < len(m.mirrors) && m.mirrors[i].URL.String() == u.String() {
+			mirror = m.mirrors[i]
+		}
+		mirror.Lock()
+		mirror.SocketPath = m.SocketPath
+		mirror.Codec = m.Codec
+		mirror.Timeout = m.Timeout
+		mirror.Headers = m.Headers
+		mirror.TokenProvider = m.TokenProvider
+		mirror.TokenHeader = m.TokenHeader
+		mirror.Unlock()
+		mirrors[i] = mirror
+	}
+	m.mirrors = mirrors
+}
+
+// mirrorError is returned by Refresh when every mirror failed. errors.Is
+// matches the error of any mirror.
+type mirrorError struct {
+	errs []error
+}
+
+func (e *mirrorError) Error() string {
+	messages := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		messages[i] = err.Error()
+	}
+	return "every mirror failed: " + strings.Join(messages, "; ")
+}
+
+func (e *mirrorError) Is(target error) bool {
+	for _, err := range e.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
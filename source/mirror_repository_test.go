@@ -0,0 +1,293 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMirrorRepositoryFailover(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	var body atomic.Value
+	body.Store("name: John\n")
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body.Load().(string)))
+	}))
+	defer healthy.Close()
+
+	failingURL, _ := url.Parse(failing.URL)
+	healthyURL, _ := url.Parse(healthy.URL)
+	repository := &MirrorRepository{Name: "mirrors", URLs: []*url.URL{failingURL, healthyURL}}
+	if repository.ServedBy() != nil {
+		t.Errorf("Expected no mirror before the first refresh")
+	}
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, ok := repository.GetData("name")
+	if !ok || name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+	if repository.ServedBy() != healthyURL {
+		t.Errorf("Expected the second mirror to serve the data, got %v", repository.ServedBy())
+	}
+
+	// Load fails over the same way without replacing the data served.
+	body.Store("name: Jane\n")
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	if loaded["name"] != "Jane" {
+		t.Errorf("Expected loaded name to be Jane, got %v", loaded["name"])
+	}
+	name, _ = repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to stay John, got %v", name)
+	}
+
+	// If every mirror fails, the last good data is kept.
+	healthy.Close()
+	err = repository.Refresh()
+	if err == nil {
+		t.Errorf("Expected error when every mirror fails, got nil")
+	}
+	_, err = repository.Load()
+	if err == nil {
+		t.Errorf("Expected Load to fail when every mirror fails, got nil")
+	}
+	name, _ = repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to still be John, got %v", name)
+	}
+}
+
+func TestMirrorRepositoryStalledMirror(t *testing.T) {
+	stop := make(chan struct{})
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-stop
+	}))
+	defer stalled.Close()
+	defer close(stop)
+	var authorization, tenant string
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization, tenant = r.Header.Get("Authorization"), r.Header.Get("X-Tenant")
+		_, _ = w.Write([]byte("name: John\n"))
+	}))
+	defer healthy.Close()
+
+	stalledURL, _ := url.Parse(stalled.URL)
+	healthyURL, _ := url.Parse(healthy.URL)
+	repository := &MirrorRepository{
+		Name:    "mirrors",
+		URLs:    []*url.URL{stalledURL, healthyURL},
+		Timeout: 100 * time.Millisecond,
+		Headers: http.Header{"X-Tenant": []string{"acme"}},
+		TokenProvider: func(ctx context.Context) (string, error) {
+			return "token", nil
+		},
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- repository.Refresh()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Error refreshing repository: %s", err.Error())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the refresh to fail over past the stalled mirror")
+	}
+	if repository.ServedBy() != healthyURL {
+		t.Errorf("Expected the second mirror to serve the data, got %v", repository.ServedBy())
+	}
+	if authorization != "Bearer token" || tenant != "acme" {
+		t.Errorf("Expected the mirror to receive the token and headers, got %q and %q", authorization, tenant)
+	}
+}
+
+func TestMirrorRepositoryReconfigure(t *testing.T) {
+	first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("name: first\n"))
+	}))
+	defer first.Close()
+	second := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("name: second\n"))
+	}))
+	defer second.Close()
+
+	firstURL, _ := url.Parse(first.URL)
+	secondURL, _ := url.Parse(second.URL)
+	repository := &MirrorRepository{Name: "mirrors", URLs: []*url.URL{firstURL}}
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// Replacing a mirror with the same number of URLs takes effect.
+	repository.URLs = []*url.URL{secondURL}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "second" {
+		t.Errorf("Expected name to be second, got %v", name)
+	}
+}
+
+func TestMirrorRepositoryCombinesErrors(t *testing.T) {
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+	missing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer missing.Close()
+
+	unavailableURL, _ := url.Parse(unavailable.URL)
+	missingURL, _ := url.Parse(missing.URL)
+	repository := &MirrorRepository{Name: "mirrors", URLs: []*url.URL{unavailableURL, missingURL}}
+	err := repository.Refresh()
+	if err == nil {
+		t.Fatalf("Expected error when every mirror fails, got nil")
+	}
+	for _, code := range []string{"503", "404"} {
+		if !strings.Contains(err.Error(), code) {
+			t.Errorf("Expected error to mention status %s, got %s", code, err.Error())
+		}
+	}
+}
+
+// healthServer is a mirror serving name: <name>, answering after delay, or
+// with 503 while it is down.
+type healthServer struct {
+	*httptest.Server
+	url  *url.URL
+	down atomic.Bool
+}
+
+func newHealthServer(t *testing.T, name string, delay time.Duration) *healthServer {
+	t.Helper()
+	server := &healthServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		if server.down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("name: " + name + "\n"))
+	}))
+	t.Cleanup(server.Close)
+	server.url, _ = url.Parse(server.URL)
+	return server
+}
+
+func expectOrder(t *testing.T, repository *MirrorRepository, expected ...*url.URL) {
+	t.Helper()
+	order := repository.Order()
+	if len(order) != len(expected) {
+		t.Fatalf("Expected %d mirrors, got %v", len(expected), order)
+	}
+	for i := range order {
+		if order[i] != expected[i] {
+			t.Errorf("Expected mirror %d to be %v, got %v", i, expected[i], order[i])
+		}
+	}
+}
+
+func TestMirrorRepositoryHealthOrder(t *testing.T) {
+	slow := newHealthServer(t, "slow", 50*time.Millisecond)
+	fast := newHealthServer(t, "fast", 0)
+	flaky := newHealthServer(t, "flaky", 0)
+	flaky.down.Store(true)
+
+	repository := &MirrorRepository{Name: "mirrors", URLs: []*url.URL{slow.url, flaky.url, fast.url}}
+	defer repository.Close()
+
+	// Before any health check the mirrors are tried in the configured order.
+	expectOrder(t, repository, slow.url, flaky.url, fast.url)
+
+	// The fastest healthy mirror is tried first, unhealthy mirrors last.
+	repository.CheckHealth(context.Background())
+	expectOrder(t, repository, fast.url, slow.url, flaky.url)
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if repository.ServedBy() != fast.url {
+		t.Errorf("Expected the fastest mirror to serve the data, got %v", repository.ServedBy())
+	}
+
+	// The order adapts when the health of the mirrors changes.
+	fast.down.Store(true)
+	flaky.down.Store(false)
+	repository.CheckHealth(context.Background())
+	expectOrder(t, repository, flaky.url, slow.url, fast.url)
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "flaky" {
+		t.Errorf("Expected name to be flaky, got %v", name)
+	}
+
+	// Mirrors added since the last check are tried before unhealthy ones.
+	added := newHealthServer(t, "added", 0)
+	repository.Lock()
+	repository.URLs = []*url.URL{slow.url, flaky.url, fast.url, added.url}
+	repository.Unlock()
+	expectOrder(t, repository, flaky.url, slow.url, added.url, fast.url)
+}
+
+func TestMirrorRepositoryHealthCheckInterval(t *testing.T) {
+	first := newHealthServer(t, "first", 0)
+	second := newHealthServer(t, "second", 0)
+	var checks atomic.Int64
+	repository := &MirrorRepository{
+		Name:                "mirrors",
+		URLs:                []*url.URL{first.url, second.url},
+		HealthCheckInterval: 5 * time.Millisecond,
+		HealthCheck: func(ctx context.Context, u *url.URL) error {
+			checks.Add(1)
+			if u == first.url && first.down.Load() {
+				return http.ErrServerClosed
+			}
+			return nil
+		},
+	}
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// The background checks demote the mirror that became unhealthy.
+	first.down.Store(true)
+	deadline := time.Now().Add(2 * time.Second)
+	for repository.Order()[0] != second.url {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the unhealthy mirror to be demoted, timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Close stops the background checks.
+	_ = repository.Close()
+	stopped := checks.Load()
+	time.Sleep(20 * time.Millisecond)
+	if checks.Load() != stopped {
+		t.Errorf("Expected no health check after Close, got %d more", checks.Load()-stopped)
+	}
+}
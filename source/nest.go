@@ -0,0 +1,77 @@
+package source
+
+import (
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+// parseValue parses the value of a key of a key/value store with codec. An
+// empty value parses to nil.
+func parseValue(codec Codec, raw []byte) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var value interface{}
+	err := codecOrDefault(codec).Unmarshal(raw, &value)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// parseValues parses the raw value of every key with codec, like parseValue.
+// A value that does not parse fails the parse, unless tolerate is set: then
+// the key keeps its value in previous, if it had one, and the values are
+// returned with a KeyErrors of the keys that did not parse.
+func parseValues(codec Codec, raw map[string][]byte, previous map[string]interface{}, tolerate bool) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(raw))
+	var keyErrors *KeyErrors
+	for name, value := range raw {
+		parsed, err := parseValue(codec, value)
+		if err == nil {
+			values[name] = parsed
+			continue
+		}
+		logrus.WithField("key", name).Debug("error unmarshalling value")
+		if !tolerate {
+			return nil, err
+		}
+		if keyErrors == nil {
+			keyErrors = &KeyErrors{Errors: map[string]error{}}
+		}
+		keyErrors.Errors[name] = err
+		if old, ok := previous[name]; ok {
+			values[name] = old
+		}
+	}
+	if keyErrors != nil {
+		return values, keyErrors
+	}
+	return values, nil
+}
+
+// nestValues nests the values of the keys of a key/value store by "/", so that
+// the keys `db/host` and `db/port` form the configuration `db` with the fields
+// host and port. When a key is both a value and the folder of other keys, such
+// as `db` and `db/host`, the folder wins and the value is dropped.
+func nestValues(values map[string]interface{}) map[string]interface{} {
+	// Visit the keys in order so that folders always replace the values of their name.
+	data := map[string]interface{}{}
+	for _, name := range sortedKeys(values) {
+		parts := strings.Split(name, "/")
+		parent := data
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := parent[part].(map[string]interface{})
+			if !ok {
+				if _, isPresent := parent[part]; isPresent {
+					logrus.WithField("key", name).Debug("key shadows the value of its folder")
+				}
+				child = map[string]interface{}{}
+				parent[part] = child
+			}
+			parent = child
+		}
+		parent[parts[len(parts)-1]] = values[name]
+	}
+	return data
+}
@@ -0,0 +1,30 @@
+package source
+
+import (
+	"sync"
+)
+
+// changeNotifier implements Notifier for the repositories that embed it.
+type changeNotifier struct {
+	notifyMu sync.Mutex
+	notify   func() // Called after each change loaded between refreshes, nil for none
+}
+
+// NotifyChanges makes the repository call fn after each change it loads on
+// its own between refreshes.
+func (n *changeNotifier) NotifyChanges(fn func()) {
+	n.notifyMu.Lock()
+	defer n.notifyMu.Unlock()
+	n.notify = fn
+}
+
+// notifyChange calls the function registered with NotifyChanges, if any. It
+// must be called without the lock of the repository held.
+func (n *changeNotifier) notifyChange() {
+	n.notifyMu.Lock()
+	notify := n.notify
+	n.notifyMu.Unlock()
+	if notify != nil {
+		notify()
+	}
+}
@@ -0,0 +1,50 @@
+package source
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// notifications registers with repository and returns the channel its
+// notifications are delivered on.
+func notifications(repository Notifier) <-chan struct{} {
+	notified := make(chan struct{}, 100)
+	repository.NotifyChanges(func() {
+		select {
+		case notified <- struct{}{}:
+		default:
+		}
+	})
+	return notified
+}
+
+// waitForNotification waits until the repository notifies a change.
+func waitForNotification(t *testing.T, notified <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-notified:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected the change to be notified, timed out")
+	}
+}
+
+func TestChainRepositoryNotifyChanges(t *testing.T) {
+	watched := &FileRepository{Name: "watched"}
+	chain := NewChainRepository("chain", []Repository{&EnvRepository{Name: "env"}, NewDefaultsRepository(watched)})
+	notified := notifications(chain)
+
+	// Changes of the repositories in the chain, wrapped or not, are notified.
+	watched.notifyChange()
+	waitForNotification(t, notified)
+
+	// So are those of the repositories added later.
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "name: John\n")
+	added := &FileRepository{Name: "added", Path: path}
+	if err := chain.AddSource(added); err != nil {
+		t.Fatalf("Error adding source: %s", err.Error())
+	}
+	added.notifyChange()
+	waitForNotification(t, notified)
+}
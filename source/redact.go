@@ -0,0 +1,49 @@
+package source
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// redacted replaces sensitive parts of URLs.
+const redacted = "REDACTED"
+
+// urlPattern matches URLs embedded in messages.
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+
+// RedactURL returns u as a string with its credentials and the values of its
+// query parameters, which often carry tokens, replaced by REDACTED.
+func RedactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return redactURL(*u, false)
+}
+
+// RedactURLs returns message with the credentials and query parameter values
+// of every URL in it redacted. When hideHosts is true the host of each URL is
+// redacted as well.
+func RedactURLs(message string, hideHosts bool) string {
+	return urlPattern.ReplaceAllStringFunc(message, func(raw string) string {
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			return redacted
+		}
+		return redactURL(*parsed, hideHosts)
+	})
+}
+
+func redactURL(u url.URL, hideHosts bool) string {
+	u.User = nil
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key := range query {
+			query.Set(key, redacted)
+		}
+		u.RawQuery = query.Encode()
+	}
+	if hideHosts {
+		u.Host = redacted
+	}
+	return u.String()
+}
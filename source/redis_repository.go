@@ -0,0 +1,320 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// redisScanCount is the number of keys a RedisRepository asks for per SCAN.
+const redisScanCount = 100
+
+// RedisRepository is a struct that implements the Repository interface for
+// handling configuration data stored in Redis, either as the string keys
+// under Prefix or as the fields of the hash at HashKey. Each value is parsed
+// with Codec and named by its key without the prefix, or by its field. With
+// Notify set, the first refresh also subscribes to the keyspace notifications
+// of those keys and reloads them as soon as one changes, which requires the
+// server to publish them, for example with `notify-keyspace-events KA`. A
+// failed refresh, for example while Redis is unavailable, leaves the current
+// data in place; with TolerateKeyErrors set, values that do not parse only
+// keep their own previous values. Close, which Client.Close calls, stops the subscription and
+// closes the client.
+type RedisRepository struct {
+	sync.RWMutex                             // RWMutex to synchronize access to data during refresh
+	changeNotifier                           // Notifies the reloads on keyspace notifications
+	Name              string                 // Name of the configuration source
+	Address           string                 // Address of the Redis server, such as localhost:6379
+	Password          string                 // Password to authenticate with, if any
+	DB                int                    // Database to select
+	Prefix            string                 // Prefix of the keys to read, stripped from the configuration names
+	HashKey           string                 // Key of the hash to read the fields of instead of the keys under Prefix
+	Notify            bool                   // Whether to reload on keyspace notifications between refreshes
+	Codec             Codec                  // Codec used to parse each value, defaults to YAMLCodec, which also parses JSON
+	TolerateKeyErrors bool                   // Whether keys whose value does not parse keep their previous value instead of failing the refresh
+	Client            *redis.Client          // Redis client, created from the address by NewRedisRepository
+	data              map[string]interface{} // Map to store the configuration data
+	version           string                 // Digest of the keys and values of the currently loaded data
+	keyErrors         error                  // KeyErrors of the currently loaded data, nil if every value parsed
+	subscribed        bool                   // Whether the notification loop is running
+	closed            bool                   // Whether Close has been called
+	subscription      *redis.PubSub          // Subscription to the keyspace notifications
+	done              chan struct{}          // Closed when the notification loop returns
+}
+
+// RedisOption configures a RedisRepository created with NewRedisRepository.
+type RedisOption func(*RedisRepository)
+
+// WithRedisPassword authenticates with the given password.
+func WithRedisPassword(password string) RedisOption {
+	return func(r *RedisRepository) {
+		r.Password = password
+	}
+}
+
+// WithRedisDB selects the given database.
+func WithRedisDB(db int) RedisOption {
+	return func(r *RedisRepository) {
+		r.DB = db
+	}
+}
+
+// WithRedisHash reads the fields of the hash at key instead of the keys under the prefix.
+func WithRedisHash(key string) RedisOption {
+	return func(r *RedisRepository) {
+		r.HashKey = key
+	}
+}
+
+// WithRedisNotify reloads the data on keyspace notifications between refreshes.
+func WithRedisNotify() RedisOption {
+	return func(r *RedisRepository) {
+		r.Notify = true
+	}
+}
+
+// WithRedisTolerateKeyErrors applies the keys that parse when the values of
+// others do not, instead of failing the whole refresh. The keys that did not
+// parse keep their previous values, and Refresh returns a KeyErrors of them.
+func WithRedisTolerateKeyErrors() RedisOption {
+	return func(r *RedisRepository) {
+		r.TolerateKeyErrors = true
+	}
+}
+
+// WithRedisClient sets the client used to read the keys.
+func WithRedisClient(client *redis.Client) RedisOption {
+	return func(r *RedisRepository) {
+		r.Client = client
+	}
+}
+
+// NewRedisRepository creates a RedisRepository reading the keys under prefix
+// from the Redis server at address.
+func NewRedisRepository(name string, address string, prefix string, opts ...RedisOption) *RedisRepository {
+	repository := &RedisRepository{
+		Name:    name,
+		Address: address,
+		Prefix:  prefix,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	if repository.Client == nil {
+		repository.Client = redis.NewClient(&redis.Options{
+			Addr:     address,
+			Password: repository.Password,
+			DB:       repository.DB,
+		})
+	}
+	return repository
+}
+
+// GetName returns the name of the configuration source.
+func (r *RedisRepository) GetName() string {
+	return r.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (r *RedisRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	r.RLock()
+	defer r.RUnlock()
+	config, isPresent = r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, as the configuration is read key by key.
+func (r *RedisRepository) GetRawData() []byte {
+	return nil
+}
+
+// Version returns a digest of the keys and values of the currently loaded data.
+func (r *RedisRepository) Version() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.version
+}
+
+// Keys returns the names of the configurations.
+func (r *RedisRepository) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.data)
+}
+
+// Refresh reads the keys under the prefix, or the fields of the hash, and
+// replaces the data map with them. If no value has changed, they are not
+// reparsed. With Notify set, it subscribes to the keyspace notifications if
+// the subscription is not running.
+func (r *RedisRepository) Refresh() error {
+	r.Lock()
+	defer r.Unlock()
+	ctx := context.Background()
+
+	values, err := r.read(ctx)
+	if err != nil {
+		return err
+	}
+	version := redisVersion(values)
+	if version == r.version && r.data != nil {
+		logrus.Debug("version unchanged, skipping reparse")
+	} else {
+		// Parse the values into a new map, so that a value that does not
+		// parse leaves the current data in place.
+		raw := make(map[string][]byte, len(values))
+		for name, value := range values {
+			raw[name] = []byte(value)
+		}
+		data, err := parseValues(r.Codec, raw, r.data, r.TolerateKeyErrors)
+		var keyErrors *KeyErrors
+		if err != nil && !errors.As(err, &keyErrors) {
+			return err
+		}
+		r.data = data
+		r.version = version
+		r.keyErrors = err
+	}
+
+	if r.Notify && !r.subscribed && !r.closed {
+		r.subscription = r.Client.PSubscribe(ctx, r.notificationPattern())
+		r.subscribed = true
+		r.done = make(chan struct{})
+		go r.listen(r.subscription)
+	}
+	return r.keyErrors
+}
+
+// Load reads the keys under the prefix, or the fields of the hash, the way
+// Refresh does and returns the configurations they hold, without replacing
+// the loaded data or subscribing to notifications. A value that does not
+// parse fails Load, even with TolerateKeyErrors.
+func (r *RedisRepository) Load() (map[string]interface{}, error) {
+	values, err := r.read(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	raw := make(map[string][]byte, len(values))
+	for name, value := range values {
+		raw[name] = []byte(value)
+	}
+	return parseValues(r.Codec, raw, nil, false)
+}
+
+// Close stops the subscription, waits for it to return and closes the client.
+func (r *RedisRepository) Close() error {
+	r.Lock()
+	r.closed = true
+	subscription, done := r.subscription, r.done
+	r.Unlock()
+	if subscription != nil {
+		_ = subscription.Close()
+		<-done
+	}
+	return r.Client.Close()
+}
+
+// read returns the raw values of the keys under the prefix, or of the fields
+// of the hash, by configuration name.
+func (r *RedisRepository) read(ctx context.Context) (map[string]string, error) {
+	if r.HashKey != "" {
+		values, err := r.Client.HGetAll(ctx, r.HashKey).Result()
+		if err != nil {
+			logrus.Debug("error reading hash")
+			return nil, err
+		}
+		return values, nil
+	}
+
+	var keys []string
+	iterator := r.Client.Scan(ctx, 0, redisPattern(r.Prefix)+"*", redisScanCount).Iterator()
+	for iterator.Next(ctx) {
+		keys = append(keys, iterator.Val())
+	}
+	if err := iterator.Err(); err != nil {
+		logrus.Debug("error scanning keys")
+		return nil, err
+	}
+	values := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+	raw, err := r.Client.MGet(ctx, keys...).Result()
+	if err != nil {
+		logrus.Debug("error reading keys")
+		return nil, err
+	}
+	for i, key := range keys {
+		// Keys that are not strings or were deleted since the scan read as nil.
+		value, ok := raw[i].(string)
+		name := strings.TrimPrefix(key, r.Prefix)
+		if !ok || name == "" {
+			continue
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// notificationPattern returns the pattern of the keyspace notification
+// channels of the keys read.
+func (r *RedisRepository) notificationPattern() string {
+	channel := "__keyspace@" + strconv.Itoa(r.DB) + "__:"
+	if r.HashKey != "" {
+		return channel + redisPattern(r.HashKey)
+	}
+	return channel + redisPattern(r.Prefix) + "*"
+}
+
+// listen reloads the data on every notification of subscription until it is
+// closed. A reload that fails is logged and leaves the data in place.
+func (r *RedisRepository) listen(subscription *redis.PubSub) {
+	defer close(r.done)
+	for range subscription.Channel() {
+		err := r.reload()
+		if err != nil {
+			logrus.WithError(err).Warn("error reloading redis keys on notification, keeping the current data")
+			continue
+		}
+		r.notifyChange()
+	}
+}
+
+// reload refreshes the data on a notification, unless the repository has
+// been closed since.
+func (r *RedisRepository) reload() error {
+	r.RLock()
+	closed := r.closed
+	r.RUnlock()
+	if closed {
+		return nil
+	}
+	return r.Refresh()
+}
+
+// redisPattern escapes the characters of prefix that glob-style patterns match specially.
+func redisPattern(prefix string) string {
+	return redisPatternEscaper.Replace(prefix)
+}
+
+var redisPatternEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// redisVersion returns a digest of values.
+func redisVersion(values map[string]string) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	digest := sha256.New()
+	for _, name := range names {
+		digest.Write([]byte(strconv.Quote(name) + "=" + strconv.Quote(values[name]) + "\n"))
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
@@ -0,0 +1,130 @@
+package source
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"testing"
+	"time"
+)
+
+func TestRedisRepository(t *testing.T) {
+	server := miniredis.RunT(t)
+	_ = server.Set("app:name", "John")
+	_ = server.Set("app:db", `{"host": "localhost", "port": 5432}`)
+	_ = server.Set("other:name", "Jane")
+
+	repository := NewRedisRepository("redis", server.Addr(), "app:")
+	defer repository.Close()
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+	db, _ := repository.GetData("db")
+	dbMap, ok := db.(map[string]interface{})
+	if !ok || dbMap["host"] != "localhost" || dbMap["port"] != 5432 {
+		t.Errorf("Expected db to be decoded from JSON, got %v", db)
+	}
+	keys := repository.Keys()
+	if len(keys) != 2 || keys[0] != "db" || keys[1] != "name" {
+		t.Errorf("Expected keys [db name], got %v", keys)
+	}
+
+	// Changes are picked up, and the version changes with them.
+	version := repository.Version()
+	_ = server.Set("app:name", "Jack")
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jack" || repository.Version() == version {
+		t.Errorf("Expected name to be Jack at a new version, got %v at %s", name, repository.Version())
+	}
+
+	// Load reads the keys without replacing the data.
+	_ = server.Set("app:name", "Jill")
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	if loaded["name"] != "Jill" {
+		t.Errorf("Expected loaded name to be Jill, got %v", loaded["name"])
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jack" {
+		t.Errorf("Expected name to stay Jack, got %v", name)
+	}
+	_ = server.Set("app:name", "Jack")
+
+	// A value that does not parse leaves the data in place.
+	_ = server.Set("app:broken", "key: [unclosed")
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a value that does not parse, got nil")
+	}
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected Load to fail for a value that does not parse, got nil")
+	}
+	server.Del("app:broken")
+
+	// The last good snapshot is kept while Redis is unavailable.
+	server.Close()
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for an unavailable Redis, got nil")
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jack" {
+		t.Errorf("Expected the last good data to be kept, got %v", name)
+	}
+}
+
+func TestRedisRepositoryHash(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.HSet("config", "name", "John", "retries", "3")
+	server.Select(1)
+	server.HSet("config", "name", "Jane")
+
+	repository := NewRedisRepository("redis", server.Addr(), "", WithRedisHash("config"), WithRedisDB(1))
+	defer repository.Close()
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "Jane" || len(repository.Keys()) != 1 {
+		t.Errorf("Expected the hash of database 1, got %v", repository.Keys())
+	}
+}
+
+func TestRedisRepositoryNotify(t *testing.T) {
+	server := miniredis.RunT(t)
+	_ = server.Set("app:name", "John")
+
+	repository := NewRedisRepository("redis", server.Addr(), "app:", WithRedisNotify())
+	notified := notifications(repository)
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// miniredis does not publish keyspace notifications, so publish them the
+	// way Redis would until the subscription has received one.
+	_ = server.Set("app:name", "Jane")
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		server.Publish("__keyspace@0__:app:name", "set")
+		if name, _ := repository.GetData("name"); name == "Jane" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	waitForData(t, repository, "name", "Jane")
+	waitForNotification(t, notified)
+
+	err = repository.Close()
+	if err != nil {
+		t.Errorf("Error closing repository: %s", err.Error())
+	}
+}
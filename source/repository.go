@@ -1,8 +1,17 @@
 package source
 
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
 // Repository is an interface that defines the contract for a configuration data repository.
 // Any type implementing this interface must provide methods to retrieve the configuration data
 // and to refresh the data when required.
+// Implementations must be safe for concurrent use: the Client calls Refresh
+// from its refresh goroutine while application goroutines call GetData, so
+// the repositories of this package guard their data with a sync.RWMutex.
 type Repository interface {
 	GetName() string
 	// GetData returns the configuration data as a map of configuration names to their respective models.
@@ -18,3 +27,93 @@ type Repository interface {
 	// The caller of this method should handle the error appropriately.
 	Refresh() error
 }
+
+// VersionKey is the top-level key a configuration document can set to declare
+// its version. Repositories skip reparsing a document whose version is unchanged,
+// so edits that do not bump the version are ignored. The key is found by
+// scanning lines rather than parsing: it must be on a line of its own, at
+// column 0 in YAML or directly inside the outermost object in JSON and JSON5.
+const VersionKey = "_version"
+
+// Versioned is an optional interface implemented by repositories that can
+// report a cheap version marker (an ETag or the VersionKey of the document)
+// for the data they currently hold. The Client compares it across refreshes
+// and skips the work it derives from the data when it has not changed.
+type Versioned interface {
+	// Version returns the version marker of the current data, or an empty
+	// string if the source did not provide one.
+	Version() string
+}
+
+// KeyLister is an optional interface implemented by repositories that can
+// list the names of the configurations they currently hold.
+type KeyLister interface {
+	// Keys returns the names of the current configurations in sorted order.
+	Keys() []string
+}
+
+// Loader is an optional interface implemented by repositories that can fetch
+// and parse their source without replacing the data they serve, for example
+// to check a new configuration before it goes live. Every repository of this
+// package implements it but KafkaRepository, whose consumer keeps its
+// position in the partition and is shared with the live consumer, so it
+// cannot read the partition again on the side. The repositories combining or
+// wrapping others load them in turn, and fail with ErrUnloadableRepository
+// if one of them is not a Loader.
+type Loader interface {
+	// Load fetches and parses the source into a new map and returns it, or
+	// the error a refresh would fail with. GetData keeps serving the data of
+	// the last refresh.
+	Load() (map[string]interface{}, error)
+}
+
+// ErrUnloadableRepository is returned by the Load of a repository combining
+// or wrapping others when one of them does not implement Loader.
+var ErrUnloadableRepository = errors.New("repository cannot load its configurations")
+
+// SourceResolver is an optional interface implemented by repositories that
+// combine other repositories, such as ChainRepository, to tell which of them
+// provides a configuration.
+type SourceResolver interface {
+	// SourceOf returns the name of the repository that provides the
+	// configuration with the given name, or false if none does.
+	SourceOf(configName string) (string, bool)
+}
+
+// ReadThrough is an optional interface implemented by repositories whose
+// GetData may fetch from the backend, for example on a cache miss, rather than
+// only read the data loaded by Refresh. The Client bounds how many GetData
+// calls run at once for such repositories.
+type ReadThrough interface {
+	// ReadsThrough reports whether GetData may call the backend.
+	ReadsThrough() bool
+}
+
+// ContextRefresher is an optional interface implemented by repositories
+// whose refresh can be bound to a context, so that a Client refreshing with a
+// timeout can abandon a slow refresh instead of waiting for it.
+type ContextRefresher interface {
+	// RefreshContext is Refresh with the requests to the source bound to
+	// ctx, returning once ctx is done.
+	RefreshContext(ctx context.Context) error
+}
+
+// Notifier is an optional interface implemented by repositories that load
+// changes on their own between refreshes, such as from a watch or a stream.
+// The Client registers with it, so that such changes reach it right away
+// rather than on its next refresh.
+type Notifier interface {
+	// NotifyChanges makes the repository call fn after each change it loads
+	// on its own, replacing the function of an earlier call. fn must not block.
+	NotifyChanges(fn func())
+}
+
+// sortedKeys returns the keys of data in sorted order.
+func sortedKeys(data map[string]interface{}) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
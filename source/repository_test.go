@@ -0,0 +1,86 @@
+package source
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// hammer calls GetData, GetRawData and Keys from many goroutines while
+// Refresh runs in a loop, so that the race detector catches unsynchronized
+// access to the data of the repository. change is called before every
+// Refresh to change the source.
+func hammer(t *testing.T, repository Repository, change func(i int)) {
+	t.Helper()
+	var wg sync.WaitGroup
+	var stop atomic.Bool
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				repository.GetData("counter")
+				repository.GetRawData()
+				if lister, ok := repository.(KeyLister); ok {
+					lister.Keys()
+				}
+				// Let the goroutines Refresh starts run on few CPUs.
+				runtime.Gosched()
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		change(i)
+		if err := repository.Refresh(); err != nil {
+			t.Errorf("Error refreshing repository: %s", err.Error())
+		}
+	}
+	stop.Store(true)
+	wg.Wait()
+}
+
+func TestRepositoriesConcurrentAccess(t *testing.T) {
+	t.Run("FileRepository", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		writeFile(t, path, "counter: 0\n")
+		hammer(t, &FileRepository{Name: "file", Path: path}, func(i int) {
+			writeFile(t, path, fmt.Sprintf("counter: %d\nkey%d: true\n", i, i))
+		})
+	})
+
+	t.Run("WebRepository", func(t *testing.T) {
+		var counter atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, "counter: %d\n", counter.Load())
+		}))
+		defer server.Close()
+		urlParsed, err := url.Parse(server.URL)
+		if err != nil {
+			t.Fatalf("Error parsing url: %s", err.Error())
+		}
+		hammer(t, &WebRepository{Name: "web", URL: urlParsed}, func(i int) {
+			counter.Store(int64(i))
+		})
+	})
+
+	t.Run("EnvRepository", func(t *testing.T) {
+		hammer(t, &EnvRepository{Name: "env", Prefix: "HAMMER_"}, func(i int) {
+			t.Setenv("HAMMER_COUNTER", fmt.Sprint(i))
+		})
+	})
+
+	t.Run("ChainRepository", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		writeFile(t, path, "counter: 0\n")
+		chain := NewChainRepository("chain", []Repository{&FileRepository{Name: "file", Path: path}, &EnvRepository{Name: "env", Prefix: "HAMMER_"}})
+		hammer(t, chain, func(i int) {
+			writeFile(t, path, fmt.Sprintf("counter: %d\n", i))
+		})
+	})
+}
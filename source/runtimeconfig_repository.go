@@ -0,0 +1,380 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/runtimeconfig/v1beta1"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRuntimeConfigRetryDelay is the delay before a RuntimeConfigRepository
+// watches a variable again after a failed watch.
+const DefaultRuntimeConfigRetryDelay = time.Second
+
+// States of a RuntimeConfigVariable returned by RuntimeConfigAPI.WatchVariable.
+const (
+	RuntimeConfigUpdated = "UPDATED" // The variable was updated while watched
+	RuntimeConfigDeleted = "DELETED" // The variable was deleted while watched
+)
+
+// ErrWatchUnsupported is returned by a RuntimeConfigAPI whose service does not
+// support watching variables.
+var ErrWatchUnsupported = errors.New("variable watch not supported")
+
+// RuntimeConfigVariable is a variable read from a Runtime Configurator config.
+type RuntimeConfigVariable struct {
+	Name       string // Full name of the variable, as projects/P/configs/C/variables/NAME
+	Text       string // Text value of the variable, if it has one
+	Value      []byte // Binary value of the variable, if it has one
+	UpdateTime string // Time of the last update of the variable
+	State      string // State of the variable after a watch, RuntimeConfigUpdated or RuntimeConfigDeleted
+}
+
+// RuntimeConfigAPI is the subset of Runtime Configurator operations used by
+// RuntimeConfigRepository.
+type RuntimeConfigAPI interface {
+	// ListVariables returns the variables of the config parent, with their values.
+	ListVariables(ctx context.Context, parent string) ([]RuntimeConfigVariable, error)
+	// WatchVariable blocks until the variable with the given name is updated
+	// after newerThan, or is deleted, and returns it. It may return the
+	// variable unchanged, with an empty State, when the service times out.
+	WatchVariable(ctx context.Context, name string, newerThan string) (RuntimeConfigVariable, error)
+}
+
+// RuntimeConfigRepository is a struct that implements the Repository interface
+// for handling configuration data stored as variables of a Google Cloud Runtime
+// Configurator config. Each variable is exposed under its name within the
+// config, such as `db/host`, with a string value for text variables and a
+// []byte value for binary ones. With Watch set, every loaded variable is also
+// watched in the background, so that updates are applied as soon as they are
+// made instead of on the next refresh. Close, which Client.Close calls, stops
+// the watches.
+type RuntimeConfigRepository struct {
+	sync.RWMutex                                 // RWMutex to synchronize access to data
+	changeNotifier                               // Notifies the updates applied by the watches
+	Name           string                        // Name of the configuration source
+	Project        string                        // ID of the Google Cloud project
+	Config         string                        // Name of the Runtime Configurator config
+	Client         RuntimeConfigAPI              // Runtime Configurator client
+	Watch          bool                          // Whether to watch the loaded variables for updates between refreshes
+	RetryDelay     time.Duration                 // Delay before watching again after a failed watch, defaults to DefaultRuntimeConfigRetryDelay
+	data           map[string]interface{}        // Map to store the configuration data
+	updated        map[string]string             // Update time of each loaded variable, by configuration name
+	version        string                        // Version marker derived from the update times of the loaded variables
+	watchers       map[string]context.CancelFunc // Cancels the watch of each watched variable, by configuration name
+	closed         bool                          // Whether Close has been called
+	wg             sync.WaitGroup                // Tracks the running watches
+}
+
+// RuntimeConfigOption configures a RuntimeConfigRepository created with
+// NewRuntimeConfigRepository.
+type RuntimeConfigOption func(*RuntimeConfigRepository)
+
+// WithVariableWatch watches the loaded variables for updates between refreshes.
+func WithVariableWatch() RuntimeConfigOption {
+	return func(r *RuntimeConfigRepository) {
+		r.Watch = true
+	}
+}
+
+// WithRuntimeConfigClient sets the client used to read the variables.
+func WithRuntimeConfigClient(client RuntimeConfigAPI) RuntimeConfigOption {
+	return func(r *RuntimeConfigRepository) {
+		r.Client = client
+	}
+}
+
+// NewRuntimeConfigRepository creates a RuntimeConfigRepository reading the
+// variables of the given config of project. The service is authenticated with
+// credentials, such as option.WithCredentialsFile, or with the application
+// default credentials when credentials is nil.
+func NewRuntimeConfigRepository(name string, project string, config string, credentials option.ClientOption, opts ...RuntimeConfigOption) (*RuntimeConfigRepository, error) {
+	repository := &RuntimeConfigRepository{
+		Name:    name,
+		Project: project,
+		Config:  config,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	if repository.Client == nil {
+		var clientOptions []option.ClientOption
+		if credentials != nil {
+			clientOptions = append(clientOptions, credentials)
+		}
+		service, err := runtimeconfig.NewService(context.Background(), clientOptions...)
+		if err != nil {
+			return nil, err
+		}
+		repository.Client = &runtimeConfigService{service: service}
+	}
+	return repository, nil
+}
+
+// GetName returns the name of the configuration source.
+func (r *RuntimeConfigRepository) GetName() string {
+	return r.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (r *RuntimeConfigRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	r.RLock()
+	defer r.RUnlock()
+	config, isPresent = r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, as the configuration is read variable by variable.
+func (r *RuntimeConfigRepository) GetRawData() []byte {
+	return nil
+}
+
+// Version returns a marker that changes whenever any loaded variable is updated.
+func (r *RuntimeConfigRepository) Version() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.version
+}
+
+// Keys returns the names of the loaded variables.
+func (r *RuntimeConfigRepository) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.data)
+}
+
+// Refresh lists the variables of the config and replaces the data map with
+// them. With Watch set, it starts watching the new variables and stops
+// watching the removed ones.
+func (r *RuntimeConfigRepository) Refresh() error {
+	data, updated, err := r.list()
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.data = data
+	r.updated = updated
+	r.updateVersion()
+	if r.Watch && !r.closed {
+		r.syncWatchers()
+	}
+	return nil
+}
+
+// Load lists the variables of the config the way Refresh does and returns
+// them, without replacing the loaded data or starting any watch.
+func (r *RuntimeConfigRepository) Load() (map[string]interface{}, error) {
+	data, _, err := r.list()
+	return data, err
+}
+
+// list lists the variables of the config and returns their values and update
+// times by configuration name.
+func (r *RuntimeConfigRepository) list() (map[string]interface{}, map[string]string, error) {
+	parent := r.parent()
+	variables, err := r.Client.ListVariables(context.Background(), parent)
+	if err != nil {
+		logrus.Debug("error listing variables")
+		return nil, nil, err
+	}
+
+	data := map[string]interface{}{}
+	updated := map[string]string{}
+	for _, variable := range variables {
+		key := strings.TrimPrefix(variable.Name, parent+"/variables/")
+		data[key] = variable.value()
+		updated[key] = variable.UpdateTime
+	}
+	return data, updated, nil
+}
+
+// Close stops the watches and waits for them to return.
+func (r *RuntimeConfigRepository) Close() error {
+	r.Lock()
+	r.closed = true
+	for _, cancel := range r.watchers {
+		cancel()
+	}
+	r.watchers = nil
+	r.Unlock()
+	r.wg.Wait()
+	return nil
+}
+
+// parent returns the full name of the config.
+func (r *RuntimeConfigRepository) parent() string {
+	return "projects/" + r.Project + "/configs/" + r.Config
+}
+
+// updateVersion derives the version from the update times of the variables,
+// never from their values. It must be called with the lock held.
+func (r *RuntimeConfigRepository) updateVersion() {
+	lines := make([]string, 0, len(r.updated))
+	for key, updateTime := range r.updated {
+		lines = append(lines, key+"/"+updateTime)
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	r.version = hex.EncodeToString(sum[:])
+}
+
+// syncWatchers starts a watch for every loaded variable that has none, and
+// stops the watches of the variables that are no longer loaded. It must be
+// called with the lock held.
+func (r *RuntimeConfigRepository) syncWatchers() {
+	if r.watchers == nil {
+		r.watchers = map[string]context.CancelFunc{}
+	}
+	for key, cancel := range r.watchers {
+		if _, ok := r.data[key]; !ok {
+			cancel()
+			delete(r.watchers, key)
+		}
+	}
+	for key := range r.data {
+		if _, ok := r.watchers[key]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		r.watchers[key] = cancel
+		r.wg.Add(1)
+		go r.watch(ctx, key)
+	}
+}
+
+// watch applies the updates of the variable loaded under key until it is
+// deleted or ctx is canceled. A failed watch is retried after RetryDelay.
+func (r *RuntimeConfigRepository) watch(ctx context.Context, key string) {
+	defer r.wg.Done()
+	name := r.parent() + "/variables/" + key
+	for {
+		r.RLock()
+		newerThan := r.updated[key]
+		r.RUnlock()
+
+		variable, err := r.Client.WatchVariable(ctx, name, newerThan)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, ErrWatchUnsupported) {
+			logrus.WithField("variable", key).Debug("variable watch not supported")
+			return
+		}
+		if err != nil {
+			logrus.WithError(err).WithField("variable", key).Debug("error watching variable, retrying")
+			timer := time.NewTimer(r.retryDelay())
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			continue
+		}
+		if variable.State != RuntimeConfigUpdated && variable.State != RuntimeConfigDeleted {
+			continue
+		}
+
+		r.Lock()
+		// A refresh may have replaced this watch while it was waiting.
+		if ctx.Err() != nil {
+			r.Unlock()
+			return
+		}
+		if variable.State == RuntimeConfigDeleted {
+			delete(r.data, key)
+			delete(r.updated, key)
+			delete(r.watchers, key)
+			r.updateVersion()
+			r.Unlock()
+			r.notifyChange()
+			return
+		}
+		r.data[key] = variable.value()
+		r.updated[key] = variable.UpdateTime
+		r.updateVersion()
+		r.Unlock()
+		r.notifyChange()
+	}
+}
+
+func (r *RuntimeConfigRepository) retryDelay() time.Duration {
+	if r.RetryDelay > 0 {
+		return r.RetryDelay
+	}
+	return DefaultRuntimeConfigRetryDelay
+}
+
+// value returns the binary value of the variable if it has one, and its text otherwise.
+func (v RuntimeConfigVariable) value() interface{} {
+	if v.Value != nil {
+		return v.Value
+	}
+	return v.Text
+}
+
+// runtimeConfigService implements RuntimeConfigAPI with the Runtime Configurator API.
+type runtimeConfigService struct {
+	service *runtimeconfig.Service
+}
+
+func (s *runtimeConfigService) ListVariables(ctx context.Context, parent string) ([]RuntimeConfigVariable, error) {
+	var variables []RuntimeConfigVariable
+	err := s.service.Projects.Configs.Variables.List(parent).ReturnValues(true).Pages(ctx, func(page *runtimeconfig.ListVariablesResponse) error {
+		for _, variable := range page.Variables {
+			converted, err := convertVariable(variable)
+			if err != nil {
+				return err
+			}
+			variables = append(variables, converted)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
+
+func (s *runtimeConfigService) WatchVariable(ctx context.Context, name string, newerThan string) (RuntimeConfigVariable, error) {
+	request := &runtimeconfig.WatchVariableRequest{NewerThan: newerThan}
+	variable, err := s.service.Projects.Configs.Variables.Watch(name, request).Context(ctx).Do()
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotImplemented {
+			return RuntimeConfigVariable{}, ErrWatchUnsupported
+		}
+		return RuntimeConfigVariable{}, err
+	}
+	return convertVariable(variable)
+}
+
+// convertVariable converts a variable of the API, decoding its base64 value.
+func convertVariable(variable *runtimeconfig.Variable) (RuntimeConfigVariable, error) {
+	converted := RuntimeConfigVariable{
+		Name:       variable.Name,
+		Text:       variable.Text,
+		UpdateTime: variable.UpdateTime,
+		State:      variable.State,
+	}
+	if variable.Value != "" {
+		value, err := base64.StdEncoding.DecodeString(variable.Value)
+		if err != nil {
+			return RuntimeConfigVariable{}, err
+		}
+		converted.Value = value
+	}
+	return converted, nil
+}
@@ -0,0 +1,224 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testRuntimeConfig = "projects/app/configs/prod"
+
+// fakeRuntimeConfig is an in-memory RuntimeConfigAPI. Every change bumps the
+// update time of the variable and wakes up the watches.
+type fakeRuntimeConfig struct {
+	mu        sync.Mutex
+	variables map[string]RuntimeConfigVariable
+	clock     int
+	changed   chan struct{} // Closed and replaced on every change
+	watch     bool          // Whether WatchVariable is supported
+	listErr   error
+}
+
+func newFakeRuntimeConfig(watch bool) *fakeRuntimeConfig {
+	return &fakeRuntimeConfig{variables: map[string]RuntimeConfigVariable{}, changed: make(chan struct{}), watch: watch}
+}
+
+func (f *fakeRuntimeConfig) set(name string, variable RuntimeConfigVariable) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clock++
+	variable.Name = testRuntimeConfig + "/variables/" + name
+	variable.UpdateTime = fmt.Sprintf("2023-01-01T00:00:%02dZ", f.clock)
+	f.variables[variable.Name] = variable
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeRuntimeConfig) remove(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.variables, testRuntimeConfig+"/variables/"+name)
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeRuntimeConfig) ListVariables(_ context.Context, parent string) ([]RuntimeConfigVariable, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.listErr != nil {
+		return nil, f.listErr
+	}
+	var variables []RuntimeConfigVariable
+	for _, variable := range f.variables {
+		if parent == testRuntimeConfig {
+			variables = append(variables, variable)
+		}
+	}
+	return variables, nil
+}
+
+func (f *fakeRuntimeConfig) WatchVariable(ctx context.Context, name string, newerThan string) (RuntimeConfigVariable, error) {
+	if !f.watch {
+		return RuntimeConfigVariable{}, ErrWatchUnsupported
+	}
+	for {
+		f.mu.Lock()
+		variable, ok := f.variables[name]
+		changed := f.changed
+		f.mu.Unlock()
+		if !ok {
+			return RuntimeConfigVariable{Name: name, State: RuntimeConfigDeleted}, nil
+		}
+		if variable.UpdateTime != newerThan {
+			variable.State = RuntimeConfigUpdated
+			return variable, nil
+		}
+		select {
+		case <-ctx.Done():
+			return RuntimeConfigVariable{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func TestRuntimeConfigRepository(t *testing.T) {
+	service := newFakeRuntimeConfig(false)
+	service.set("db/host", RuntimeConfigVariable{Text: "localhost"})
+	service.set("certificate", RuntimeConfigVariable{Value: []byte{0x00, 0x01}})
+
+	repository, err := NewRuntimeConfigRepository("runtime", "app", "prod", nil, WithRuntimeConfigClient(service))
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	host, _ := repository.GetData("db/host")
+	if host != "localhost" {
+		t.Errorf("Expected db/host to be localhost, got %v", host)
+	}
+	certificate, _ := repository.GetData("certificate")
+	if value, ok := certificate.([]byte); !ok || len(value) != 2 || value[1] != 0x01 {
+		t.Errorf("Expected certificate to be its binary value, got %v", certificate)
+	}
+	keys := repository.Keys()
+	if len(keys) != 2 || keys[0] != "certificate" || keys[1] != "db/host" {
+		t.Errorf("Expected keys [certificate db/host], got %v", keys)
+	}
+	if repository.GetRawData() != nil {
+		t.Errorf("Expected no raw data, got %s", repository.GetRawData())
+	}
+
+	// The version only changes when a variable does.
+	version := repository.Version()
+	_ = repository.Refresh()
+	if repository.Version() != version {
+		t.Errorf("Expected the version to be stable, got %s and %s", version, repository.Version())
+	}
+	service.set("db/host", RuntimeConfigVariable{Text: "db.internal"})
+	service.remove("certificate")
+	_ = repository.Refresh()
+	if repository.Version() == version {
+		t.Errorf("Expected the version to change with the variables")
+	}
+	host, _ = repository.GetData("db/host")
+	if host != "db.internal" {
+		t.Errorf("Expected db/host to be db.internal, got %v", host)
+	}
+	if _, ok := repository.GetData("certificate"); ok {
+		t.Errorf("Expected the removed variable to be gone")
+	}
+
+	// Load lists the variables without replacing the data.
+	service.set("db/host", RuntimeConfigVariable{Text: "db.example"})
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	if loaded["db/host"] != "db.example" {
+		t.Errorf("Expected loaded db/host to be db.example, got %v", loaded["db/host"])
+	}
+	host, _ = repository.GetData("db/host")
+	if host != "db.internal" {
+		t.Errorf("Expected db/host to stay db.internal, got %v", host)
+	}
+
+	// A failed refresh keeps the loaded variables.
+	service.listErr = errors.New("unavailable")
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a failed listing, got nil")
+	}
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected Load to fail for a failed listing, got nil")
+	}
+	host, _ = repository.GetData("db/host")
+	if host != "db.internal" {
+		t.Errorf("Expected the last good data to be kept, got %v", host)
+	}
+	_ = repository.Close()
+}
+
+func TestRuntimeConfigRepositoryWatch(t *testing.T) {
+	service := newFakeRuntimeConfig(true)
+	service.set("db/host", RuntimeConfigVariable{Text: "localhost"})
+	service.set("feature", RuntimeConfigVariable{Text: "off"})
+
+	repository, err := NewRuntimeConfigRepository("runtime", "app", "prod", nil, WithRuntimeConfigClient(service), WithVariableWatch())
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	defer repository.Close()
+	notified := notifications(repository)
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// Updates are applied without a refresh, and notified.
+	version := repository.Version()
+	service.set("db/host", RuntimeConfigVariable{Text: "db.internal"})
+	waitForData(t, repository, "db/host", "db.internal")
+	waitForNotification(t, notified)
+	if repository.Version() == version {
+		t.Errorf("Expected the version to change with a watched update")
+	}
+
+	// Deletions are applied without a refresh, and end the watch.
+	service.remove("feature")
+	deadline := time.Now().Add(2 * time.Second)
+	for _, ok := repository.GetData("feature"); ok; _, ok = repository.GetData("feature") {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected feature to be deleted by the watch, timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Variables created later are watched after the next refresh.
+	service.set("timeout", RuntimeConfigVariable{Text: "5s"})
+	_ = repository.Refresh()
+	service.set("timeout", RuntimeConfigVariable{Text: "10s"})
+	waitForData(t, repository, "timeout", "10s")
+
+	// Close stops every watch.
+	done := make(chan struct{})
+	go func() {
+		_ = repository.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected Close to stop the watches, timed out")
+	}
+	_ = repository.Refresh()
+	service.set("db/host", RuntimeConfigVariable{Text: "other"})
+	time.Sleep(10 * time.Millisecond)
+	host, _ := repository.GetData("db/host")
+	if host != "db.internal" {
+		t.Errorf("Expected no watch after Close, got %v", host)
+	}
+}
@@ -0,0 +1,284 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// defaultS3Region is the region used to look up the region of a bucket when
+// no region is configured.
+const defaultS3Region = "us-east-1"
+
+// S3API is the subset of the S3 client used by S3Repository.
+type S3API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// S3Repository is a struct that implements the Repository interface for
+// handling configuration data stored in a file within an S3 bucket, or a
+// bucket of an S3-compatible store such as MinIO. Once a file is loaded, it
+// is only downloaded again when its ETag changes. A failed refresh leaves the
+// current data in place.
+type S3Repository struct {
+	sync.RWMutex                        // RWMutex to synchronize access to data during refresh
+	Name         string                 // Name of the configuration source
+	Bucket       string                 // Name of the S3 bucket
+	Key          string                 // Key of the configuration file within the bucket
+	Region       string                 // Region of the bucket, resolved from the bucket when empty
+	Endpoint     string                 // Custom endpoint of an S3-compatible store, addressed path-style
+	Client       S3API                  // S3 client, created from Config when nil
+	Config       *aws.Config            // AWS config the client is created from, loaded from the environment when nil
+	Codec        Codec                  // Codec used to parse the file, defaults to YAMLCodec
+	data         map[string]interface{} // Map to store the configuration data
+	rawData      []byte                 // Raw data of the configuration file
+	version      string                 // Version marker of the currently loaded data
+	etag         string                 // ETag of the currently loaded file, sent as If-None-Match
+}
+
+// S3Option configures an S3Repository created with NewS3Repository.
+type S3Option func(*S3Repository)
+
+// WithRegion sets the region of the bucket instead of resolving it.
+func WithRegion(region string) S3Option {
+	return func(r *S3Repository) {
+		r.Region = region
+	}
+}
+
+// WithEndpoint sends requests to a custom endpoint, such as a MinIO server,
+// instead of AWS. Buckets are addressed path-style on custom endpoints.
+func WithEndpoint(endpoint string) S3Option {
+	return func(r *S3Repository) {
+		r.Endpoint = endpoint
+	}
+}
+
+// WithAWSConfig creates the S3 client from cfg instead of the default AWS
+// config. The region of cfg is used unless WithRegion sets another one.
+func WithAWSConfig(cfg aws.Config) S3Option {
+	return func(r *S3Repository) {
+		r.Config = &cfg
+	}
+}
+
+// WithS3Client sets the S3 client used to fetch the file.
+func WithS3Client(client S3API) S3Option {
+	return func(r *S3Repository) {
+		r.Client = client
+	}
+}
+
+// NewS3Repository creates an S3Repository reading the file with the given key
+// from the given bucket.
+func NewS3Repository(name string, bucket string, key string, opts ...S3Option) *S3Repository {
+	repository := &S3Repository{
+		Name:   name,
+		Bucket: bucket,
+		Key:    key,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	return repository
+}
+
+// GetName returns the name of the configuration source.
+func (r *S3Repository) GetName() string {
+	return r.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (r *S3Repository) GetData(configName string) (config interface{}, isPresent bool) {
+	r.RLock()
+	defer r.RUnlock()
+	config, isPresent = r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns the raw data of the configuration file.
+func (r *S3Repository) GetRawData() []byte {
+	r.RLock()
+	defer r.RUnlock()
+	return r.rawData
+}
+
+// Version returns the ETag of the currently loaded file.
+func (r *S3Repository) Version() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.version
+}
+
+// Keys returns the names of the configurations in the currently loaded file.
+func (r *S3Repository) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.data)
+}
+
+// s3Client returns the S3 client, creating it from Config or the default AWS
+// config on first use. When neither Region nor Config sets a region, the
+// region of the bucket is looked up with a HeadBucket request, so the file is
+// always read from the bucket's region.
+func (r *S3Repository) s3Client(ctx context.Context) (S3API, error) {
+	if r.Client != nil {
+		return r.Client, nil
+	}
+	var cfg aws.Config
+	if r.Config != nil {
+		cfg = r.Config.Copy()
+		if r.Region == "" {
+			r.Region = cfg.Region
+		}
+	} else {
+		var err error
+		cfg, err = config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	endpoint := func(o *s3.Options) {
+		if r.Endpoint != "" {
+			o.EndpointResolver = s3.EndpointResolverFromURL(r.Endpoint)
+			o.UsePathStyle = true
+		}
+	}
+	if r.Region == "" {
+		probe := s3.NewFromConfig(cfg, endpoint, func(o *s3.Options) {
+			if o.Region == "" {
+				o.Region = defaultS3Region
+			}
+		})
+		region, err := manager.GetBucketRegion(ctx, probe, r.Bucket)
+		if err != nil {
+			logrus.Debug("error resolving bucket region")
+			return nil, err
+		}
+		r.Region = region
+	}
+	r.Client = s3.NewFromConfig(cfg, endpoint, func(o *s3.Options) {
+		o.Region = r.Region
+	})
+	return r.Client, nil
+}
+
+// Refresh reads the configuration file from the S3 bucket, unmarshal it into the data map.
+// The object is requested with the ETag of the loaded file as If-None-Match,
+// so an unchanged object is neither downloaded nor reparsed.
+func (r *S3Repository) Refresh() error {
+	r.Lock()
+	defer r.Unlock()
+
+	ctx := context.Background()
+	client, err := r.s3Client(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Fetch the configuration file from the bucket, unless it has not changed.
+	data, etag, err := r.getObject(ctx, client, r.etag)
+	if errors.Is(err, errNotModified) {
+		logrus.Debug("object not modified, skipping reparse")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Skip reparsing when the version has not changed.
+	version := etag
+	if version == "" {
+		version = documentVersion(data)
+	}
+	if version != "" && version == r.version {
+		logrus.Debug("version unchanged, skipping reparse")
+		return nil
+	}
+
+	// Unmarshal the data into a new map with the codec, so a file that does
+	// not parse leaves the current data in place.
+	var parsed map[string]interface{}
+	err = codecOrDefault(r.Codec).Unmarshal(data, &parsed)
+	if err != nil {
+		logrus.Debug("error unmarshalling file")
+		return err
+	}
+
+	// Store the data, raw data, version and ETag of the file.
+	r.data = parsed
+	r.rawData = data
+	r.version = version
+	r.etag = etag
+
+	return nil
+}
+
+// Load downloads and parses the configuration file the way Refresh does and
+// returns its configurations, without replacing the loaded data. The
+// repository is only locked to set up its client, so GetData and Refresh do
+// not wait for the download.
+func (r *S3Repository) Load() (map[string]interface{}, error) {
+	ctx := context.Background()
+	r.Lock()
+	client, err := r.s3Client(ctx)
+	codec := r.Codec
+	r.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := r.getObject(ctx, client, "")
+	if err != nil {
+		return nil, err
+	}
+	var parsed map[string]interface{}
+	err = codecOrDefault(codec).Unmarshal(data, &parsed)
+	if err != nil {
+		logrus.Debug("error unmarshalling file")
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// getObject downloads the configuration file with client and returns its
+// content and ETag. With an etag, the object is requested with it as
+// If-None-Match, and errNotModified is returned when it has not changed.
+func (r *S3Repository) getObject(ctx context.Context, client S3API, etag string) ([]byte, string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(r.Bucket),
+		Key:    aws.String(r.Key),
+	}
+	if etag != "" {
+		input.IfNoneMatch = aws.String(etag)
+	}
+	output, err := client.GetObject(ctx, input)
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) && statusErr.HTTPStatusCode() == http.StatusNotModified {
+		return nil, "", errNotModified
+	}
+	if err != nil {
+		logrus.Debug("error getting object")
+		return nil, "", err
+	}
+	defer output.Body.Close()
+
+	// Read the file content from the object body.
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		logrus.Debug("error reading file")
+		return nil, "", err
+	}
+	if output.ContentLength > 0 && int64(len(data)) != output.ContentLength {
+		logrus.Debug("object shorter than its content length")
+		return nil, "", fmt.Errorf("%w: received %d of %d bytes", ErrTruncated, len(data), output.ContentLength)
+	}
+	return data, aws.ToString(output.ETag), nil
+}
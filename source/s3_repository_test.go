@@ -0,0 +1,174 @@
+package source
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// setTestAWSEnv points the default AWS config at static test credentials.
+func setTestAWSEnv(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+}
+
+// newMinIOServer serves a bucket in bucketRegion the way MinIO does, with
+// path-style addressing. It records the region requests were signed for.
+func newMinIOServer(t *testing.T, bucketRegion string, signedRegions *[]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get("Authorization")
+		if parts := strings.Split(authorization, "/"); len(parts) > 2 {
+			*signedRegions = append(*signedRegions, parts[2])
+		}
+		switch {
+		case r.Method == http.MethodHead && r.URL.Path == "/config":
+			w.Header().Set("X-Amz-Bucket-Region", bucketRegion)
+		case r.Method == http.MethodGet && r.URL.Path == "/config/app.yaml":
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte("name: John\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestS3RepositoryResolvesRegion(t *testing.T) {
+	setTestAWSEnv(t)
+	var signedRegions []string
+	server := newMinIOServer(t, "eu-west-1", &signedRegions)
+
+	repository := NewS3Repository("s3", "config", "app.yaml", WithEndpoint(server.URL))
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, ok := repository.GetData("name")
+	if !ok || name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+	if repository.Region != "eu-west-1" {
+		t.Errorf("Expected region to be resolved to eu-west-1, got %s", repository.Region)
+	}
+	if len(signedRegions) == 0 || signedRegions[len(signedRegions)-1] != "eu-west-1" {
+		t.Errorf("Expected the object to be read in eu-west-1, got signatures for %v", signedRegions)
+	}
+	if repository.Version() != `"v1"` {
+		t.Errorf("Expected version to be the ETag, got %s", repository.Version())
+	}
+}
+
+func TestS3RepositoryExplicitRegion(t *testing.T) {
+	setTestAWSEnv(t)
+	var signedRegions []string
+	server := newMinIOServer(t, "eu-west-1", &signedRegions)
+
+	repository := NewS3Repository("s3", "config", "app.yaml", WithEndpoint(server.URL), WithRegion("ap-south-1"))
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if len(signedRegions) != 1 || signedRegions[0] != "ap-south-1" {
+		t.Errorf("Expected a single request signed for ap-south-1, got %v", signedRegions)
+	}
+
+	repository = NewS3Repository("s3", "config", "missing.yaml", WithEndpoint(server.URL), WithRegion("ap-south-1"))
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a missing object, got nil")
+	}
+}
+
+func TestS3RepositoryIfNoneMatch(t *testing.T) {
+	var mu sync.Mutex
+	etag, body := `"v1"`, "name: John\n"
+	var downloads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+	update := func(newETag string, newBody string) {
+		mu.Lock()
+		defer mu.Unlock()
+		etag, body = newETag, newBody
+	}
+
+	cfg := aws.Config{Region: "eu-west-1", Credentials: credentials.NewStaticCredentialsProvider("test", "test", "")}
+	repository := NewS3Repository("s3", "config", "app.yaml", WithEndpoint(server.URL), WithAWSConfig(cfg))
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if repository.Region != "eu-west-1" {
+		t.Errorf("Expected the region of the AWS config, got %s", repository.Region)
+	}
+
+	// An unchanged object is not downloaded again.
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing unchanged object: %s", err.Error())
+	}
+	if downloads != 1 {
+		t.Errorf("Expected the unchanged object not to be downloaded again, got %d downloads", downloads)
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+
+	// A changed object is downloaded and parsed.
+	update(`"v2"`, "name: Jane\n")
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing changed object: %s", err.Error())
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" || repository.Version() != `"v2"` {
+		t.Errorf("Expected name Jane at version v2, got %v at %s", name, repository.Version())
+	}
+
+	// An object that does not parse leaves the data in place.
+	update(`"v3"`, "name: [unclosed\n")
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for an object that does not parse, got nil")
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" || repository.Version() != `"v2"` {
+		t.Errorf("Expected the last good data to be kept, got %v at %s", name, repository.Version())
+	}
+
+	// Load downloads and parses the object without replacing the data.
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected error loading an object that does not parse, got nil")
+	}
+	update(`"v4"`, "name: Jack\n")
+	loaded, err := repository.Load()
+	if err != nil || loaded["name"] != "Jack" {
+		t.Errorf("Expected the loaded name to be Jack, got %v (%v)", loaded, err)
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" || repository.Version() != `"v2"` {
+		t.Errorf("Expected the live data to be untouched, got %v at %s", name, repository.Version())
+	}
+}
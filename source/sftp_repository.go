@@ -0,0 +1,312 @@
+package source
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pkg/sftp"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultSFTPPort is the port an SFTPRepository connects to when the host
+// has none.
+const DefaultSFTPPort = "22"
+
+// ErrNoHostKeyCallback is returned by NewSFTPRepository when no option
+// verifies the host key of the server.
+var ErrNoHostKeyCallback = errors.New("sftp repository needs a host key callback")
+
+// SFTPCredentials are the credentials an SFTPRepository logs in with. The
+// private key and the password are both tried when both are set.
+type SFTPCredentials struct {
+	User       string // User to log in as
+	Password   string // Password of the user, if any
+	PrivateKey []byte // PEM encoded private key of the user, if any
+	Passphrase []byte // Passphrase of the private key, if it is encrypted
+}
+
+// SFTPRepository is a struct that implements the Repository interface for
+// handling configuration data stored in a file on a remote host, read over
+// SFTP. The SSH connection is kept open across refreshes and is reopened once
+// when a refresh fails on it, for example because the server dropped it. A
+// failed refresh leaves the current data in place. Close, which Client.Close
+// calls, closes the connection.
+type SFTPRepository struct {
+	sync.RWMutex                        // RWMutex to synchronize access to data during refresh
+	Name         string                 // Name of the configuration source
+	Addr         string                 // Address of the SSH server, as host:port
+	Path         string                 // Path of the configuration file on the server
+	Config       *ssh.ClientConfig      // Configuration of the SSH connection, including the credentials
+	Codec        Codec                  // Codec used to parse the file, defaults to YAMLCodec
+	data         map[string]interface{} // Map to store the configuration data
+	rawData      []byte                 // Raw data of the configuration file
+	version      string                 // Version marker of the currently loaded data
+	sshClient    *ssh.Client            // Open SSH connection, nil when disconnected
+	sftpClient   *sftp.Client           // SFTP session on sshClient, nil when disconnected
+}
+
+// SFTPOption configures an SFTPRepository created with NewSFTPRepository.
+type SFTPOption func(*SFTPRepository)
+
+// WithHostKeyCallback verifies the host key of the server with callback, for
+// example one built from a known_hosts file with the knownhosts package.
+func WithHostKeyCallback(callback ssh.HostKeyCallback) SFTPOption {
+	return func(r *SFTPRepository) {
+		r.Config.HostKeyCallback = callback
+	}
+}
+
+// WithHostKey only accepts the server if it presents the given host key.
+func WithHostKey(key ssh.PublicKey) SFTPOption {
+	return WithHostKeyCallback(ssh.FixedHostKey(key))
+}
+
+// WithSFTPTimeout bounds the time it takes to open the SSH connection.
+func WithSFTPTimeout(timeout time.Duration) SFTPOption {
+	return func(r *SFTPRepository) {
+		r.Config.Timeout = timeout
+	}
+}
+
+// NewSFTPRepository creates an SFTPRepository reading the file at path on
+// host, which may include a port, logging in with the given credentials. The
+// host key of the server must be verified with WithHostKey or
+// WithHostKeyCallback.
+func NewSFTPRepository(name string, host string, path string, credentials SFTPCredentials, opts ...SFTPOption) (*SFTPRepository, error) {
+	var auth []ssh.AuthMethod
+	if len(credentials.PrivateKey) > 0 {
+		var signer ssh.Signer
+		var err error
+		if len(credentials.Passphrase) > 0 {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(credentials.PrivateKey, credentials.Passphrase)
+		} else {
+			signer, err = ssh.ParsePrivateKey(credentials.PrivateKey)
+		}
+		if err != nil {
+			return nil, err
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if credentials.Password != "" {
+		auth = append(auth, ssh.Password(credentials.Password))
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, DefaultSFTPPort)
+	}
+
+	repository := &SFTPRepository{
+		Name: name,
+		Addr: host,
+		Path: path,
+		Config: &ssh.ClientConfig{
+			User: credentials.User,
+			Auth: auth,
+		},
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	if repository.Config.HostKeyCallback == nil {
+		return nil, ErrNoHostKeyCallback
+	}
+	return repository, nil
+}
+
+// GetName returns the name of the configuration source.
+func (r *SFTPRepository) GetName() string {
+	return r.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (r *SFTPRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	r.RLock()
+	defer r.RUnlock()
+	config, isPresent = r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns the raw data of the configuration file.
+func (r *SFTPRepository) GetRawData() []byte {
+	r.RLock()
+	defer r.RUnlock()
+	return r.rawData
+}
+
+// Version returns the VersionKey of the currently loaded file.
+func (r *SFTPRepository) Version() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.version
+}
+
+// Keys returns the names of the configurations in the currently loaded file.
+func (r *SFTPRepository) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.data)
+}
+
+// Refresh reads the configuration file over SFTP, unmarshal it into the data map.
+// If the declared VersionKey matches the loaded data, the file is not reparsed.
+func (r *SFTPRepository) Refresh() error {
+	r.Lock()
+	defer r.Unlock()
+
+	// Read the file on the open connection, and reconnect once if that fails,
+	// as the server may have dropped the connection since the last refresh.
+	reused := r.sftpClient != nil
+	data, err := r.readFile()
+	if err != nil && reused {
+		logrus.WithError(err).Debug("error reading file, reconnecting")
+		data, err = r.readFile()
+	}
+	if err != nil {
+		return err
+	}
+
+	// Skip reparsing when the version has not changed.
+	version := documentVersion(data)
+	if version != "" && version == r.version {
+		logrus.Debug("version unchanged, skipping reparse")
+		return nil
+	}
+
+	// Unmarshal the data into a new map with the codec, so a file that does
+	// not parse leaves the current data in place.
+	var parsed map[string]interface{}
+	err = codecOrDefault(r.Codec).Unmarshal(data, &parsed)
+	if err != nil {
+		logrus.Debug("error unmarshalling file")
+		return err
+	}
+
+	// Store the data, raw data and version of the file.
+	r.data = parsed
+	r.rawData = data
+	r.version = version
+
+	return nil
+}
+
+// Load reads and parses the configuration file the way Refresh does and
+// returns its configurations, without replacing the loaded data. The file is
+// read on a connection of its own, closed again before Load returns, so the
+// connection of the repository is left untouched and GetData and Refresh do
+// not wait for the read.
+func (r *SFTPRepository) Load() (map[string]interface{}, error) {
+	sshClient, sftpClient, err := r.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = sftpClient.Close()
+		_ = sshClient.Close()
+	}()
+	data, err := readSFTPFile(sftpClient, r.Path)
+	if err != nil {
+		return nil, err
+	}
+	var parsed map[string]interface{}
+	err = codecOrDefault(r.Codec).Unmarshal(data, &parsed)
+	if err != nil {
+		logrus.Debug("error unmarshalling file")
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// readFile reads the configuration file, connecting first if needed. Any
+// error but a truncated read closes the connection, so the next read opens a
+// new one.
+func (r *SFTPRepository) readFile() ([]byte, error) {
+	if r.sftpClient == nil {
+		err := r.connect()
+		if err != nil {
+			return nil, err
+		}
+	}
+	data, err := readSFTPFile(r.sftpClient, r.Path)
+	if err != nil && !errors.Is(err, ErrTruncated) {
+		r.disconnect()
+	}
+	return data, err
+}
+
+// readSFTPFile reads the file at path in the SFTP session client.
+func readSFTPFile(client *sftp.Client, path string) ([]byte, error) {
+	file, err := client.Open(path)
+	if err != nil {
+		logrus.Debug("error opening file")
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		logrus.Debug("error reading file information")
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		logrus.Debug("error reading file")
+		return nil, err
+	}
+	if int64(len(data)) != info.Size() {
+		logrus.Debug("file shorter than its size")
+		return nil, fmt.Errorf("%w: received %d of %d bytes", ErrTruncated, len(data), info.Size())
+	}
+	return data, nil
+}
+
+// connect opens the SSH connection and the SFTP session on it.
+func (r *SFTPRepository) connect() error {
+	sshClient, sftpClient, err := r.dial()
+	if err != nil {
+		return err
+	}
+	r.sshClient = sshClient
+	r.sftpClient = sftpClient
+	return nil
+}
+
+// dial opens an SSH connection and an SFTP session on it.
+func (r *SFTPRepository) dial() (*ssh.Client, *sftp.Client, error) {
+	sshClient, err := ssh.Dial("tcp", r.Addr, r.Config)
+	if err != nil {
+		logrus.Debug("error connecting to ssh server")
+		return nil, nil, err
+	}
+	sftpClient, err := sftp.NewClient(sshClient)
+	if err != nil {
+		logrus.Debug("error starting sftp session")
+		_ = sshClient.Close()
+		return nil, nil, err
+	}
+	return sshClient, sftpClient, nil
+}
+
+// disconnect closes the SFTP session and the SSH connection, if open.
+func (r *SFTPRepository) disconnect() {
+	if r.sftpClient == nil {
+		return
+	}
+	if err := r.sftpClient.Close(); err != nil {
+		logrus.WithError(err).Debug("error closing sftp session")
+	}
+	if err := r.sshClient.Close(); err != nil {
+		logrus.WithError(err).Debug("error closing ssh connection")
+	}
+	r.sftpClient = nil
+	r.sshClient = nil
+}
+
+// Close closes the SSH connection. A later Refresh opens a new one.
+func (r *SFTPRepository) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	r.disconnect()
+	return nil
+}
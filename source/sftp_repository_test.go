@@ -0,0 +1,267 @@
+package source
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// sftpServer is an in-process SSH server serving the local filesystem over
+// SFTP to the user "config", who logs in with the password "secret" or the
+// key of clientKey.
+type sftpServer struct {
+	listener  net.Listener
+	hostKey   ssh.PublicKey
+	clientKey []byte // PEM encoded private key accepted by the server
+	mu        sync.Mutex
+	conns     []net.Conn
+	logins    int
+}
+
+func newSFTPServer(t *testing.T) *sftpServer {
+	t.Helper()
+	_, hostPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating host key: %s", err.Error())
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPrivate)
+	if err != nil {
+		t.Fatalf("Error creating host signer: %s", err.Error())
+	}
+	clientPublic, clientPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating client key: %s", err.Error())
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(clientPrivate)
+	if err != nil {
+		t.Fatalf("Error marshalling client key: %s", err.Error())
+	}
+	authorized, err := ssh.NewPublicKey(clientPublic)
+	if err != nil {
+		t.Fatalf("Error creating client public key: %s", err.Error())
+	}
+
+	server := &sftpServer{
+		hostKey:   hostSigner.PublicKey(),
+		clientKey: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if meta.User() == "config" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, errors.New("invalid password")
+		},
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == "config" && string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("invalid key")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	server.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Error listening: %s", err.Error())
+	}
+	t.Cleanup(server.stop)
+	go func() {
+		for {
+			conn, err := server.listener.Accept()
+			if err != nil {
+				return
+			}
+			server.mu.Lock()
+			server.conns = append(server.conns, conn)
+			server.mu.Unlock()
+			go server.serve(conn, config)
+		}
+	}()
+	return server
+}
+
+// serve runs the SSH handshake on conn and serves the sftp subsystem.
+func (s *sftpServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.logins++
+	s.mu.Unlock()
+	go ssh.DiscardRequests(requests)
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for request := range channelRequests {
+				isSFTP := request.Type == "subsystem" && len(request.Payload) > 4 && string(request.Payload[4:]) == "sftp"
+				_ = request.Reply(isSFTP, nil)
+				if isSFTP {
+					server, err := sftp.NewServer(channel)
+					if err != nil {
+						return
+					}
+					_ = server.Serve()
+					_ = channel.Close()
+				}
+			}
+		}()
+	}
+}
+
+// dropConnections closes every open connection, as a server restart would.
+func (s *sftpServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *sftpServer) stop() {
+	_ = s.listener.Close()
+	s.dropConnections()
+}
+
+func (s *sftpServer) loginCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+func TestSFTPRepository(t *testing.T) {
+	server := newSFTPServer(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "name: John\n")
+
+	credentials := []SFTPCredentials{
+		{User: "config", Password: "secret"},
+		{User: "config", PrivateKey: server.clientKey},
+	}
+	for _, credential := range credentials {
+		repository, err := NewSFTPRepository("sftp", server.listener.Addr().String(), path, credential, WithHostKey(server.hostKey))
+		if err != nil {
+			t.Fatalf("Error creating repository: %s", err.Error())
+		}
+		err = repository.Refresh()
+		if err != nil {
+			t.Fatalf("Error refreshing repository: %s", err.Error())
+		}
+		name, _ := repository.GetData("name")
+		if name != "John" {
+			t.Errorf("Expected name to be John, got %v", name)
+		}
+		_ = repository.Close()
+	}
+
+	repository, err := NewSFTPRepository("sftp", server.listener.Addr().String(), path, credentials[0], WithHostKey(server.hostKey))
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	defer repository.Close()
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	logins := server.loginCount()
+
+	// The connection is reused by later refreshes.
+	writeFile(t, path, "name: Jane\n")
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected name to be Jane, got %v", name)
+	}
+	if server.loginCount() != logins {
+		t.Errorf("Expected the connection to be reused, got %d logins", server.loginCount()-logins)
+	}
+
+	// A dropped connection is reopened.
+	server.dropConnections()
+	writeFile(t, path, "name: Jack\n")
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository after a dropped connection: %s", err.Error())
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jack" {
+		t.Errorf("Expected name to be Jack, got %v", name)
+	}
+
+	// Load reads on a connection of its own without replacing the data.
+	writeFile(t, path, "name: Jill\n")
+	logins = server.loginCount()
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	if loaded["name"] != "Jill" {
+		t.Errorf("Expected loaded name to be Jill, got %v", loaded["name"])
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jack" {
+		t.Errorf("Expected name to stay Jack, got %v", name)
+	}
+	if server.loginCount() != logins+1 {
+		t.Errorf("Expected Load to open one connection, got %d logins", server.loginCount()-logins)
+	}
+	writeFile(t, path, "name: Jack\n")
+
+	// The last good data is kept while the server is unreachable.
+	server.stop()
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for an unreachable server, got nil")
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jack" {
+		t.Errorf("Expected the last good data to be kept, got %v", name)
+	}
+}
+
+func TestSFTPRepositoryRejects(t *testing.T) {
+	server := newSFTPServer(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "name: John\n")
+
+	_, err := NewSFTPRepository("sftp", server.listener.Addr().String(), path, SFTPCredentials{User: "config", Password: "secret"})
+	if !errors.Is(err, ErrNoHostKeyCallback) {
+		t.Errorf("Expected ErrNoHostKeyCallback without a host key, got %v", err)
+	}
+
+	repository, err := NewSFTPRepository("sftp", server.listener.Addr().String(), path, SFTPCredentials{User: "config", Password: "wrong"}, WithHostKey(server.hostKey))
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a wrong password, got nil")
+	}
+
+	other := newSFTPServer(t)
+	repository, err = NewSFTPRepository("sftp", server.listener.Addr().String(), path, SFTPCredentials{User: "config", Password: "secret"}, WithHostKey(other.hostKey))
+	if err != nil {
+		t.Fatalf("Error creating repository: %s", err.Error())
+	}
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for an unknown host key, got nil")
+	}
+}
@@ -0,0 +1,239 @@
+package source
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// SQLRepository is a struct that implements the Repository interface for
+// handling configuration data stored as rows of a SQL table, such as the
+// table config(key, value) of a PostgreSQL database. Query returns one row per
+// configuration, its name and its value, which is parsed with Codec, so the
+// row ('db', 'host: localhost') is the configuration `db` with the field host.
+// A NULL value is nil. With a WatermarkQuery, such as
+// `SELECT max(updated_at) FROM config`, a refresh only reads the rows when
+// the watermark it returns has changed. A failed refresh, for example while
+// the database is unreachable, leaves the current data in place; with
+// TolerateKeyErrors set, values that do not parse only keep their own
+// previous values.
+type SQLRepository struct {
+	sync.RWMutex                             // RWMutex to synchronize access to data during refresh
+	Name              string                 // Name of the configuration source
+	DB                *sql.DB                // Database holding the configurations
+	Query             string                 // Query returning the name and the value of every configuration
+	WatermarkQuery    string                 // Query returning one value that changes whenever a row does, none when empty
+	Codec             Codec                  // Codec used to parse each value, defaults to YAMLCodec, which also parses JSON
+	Timeout           time.Duration          // Bound of the queries of a refresh, none when 0
+	TolerateKeyErrors bool                   // Whether rows whose value does not parse keep their previous value instead of failing the refresh
+	data              map[string]interface{} // Map to store the configuration data
+	watermark         string                 // Watermark of the loaded rows
+	keyErrors         error                  // KeyErrors of the loaded rows, nil if every value parsed
+	ownsDB            bool                   // Whether DB was opened by OpenSQLRepository and is closed by Close
+}
+
+// SQLOption configures a SQLRepository created with NewSQLRepository.
+type SQLOption func(*SQLRepository)
+
+// WithSQLQuery reads the configurations with query, which must return the
+// name and the value of every configuration as its first two columns, instead
+// of reading the columns key and value of the table.
+func WithSQLQuery(query string) SQLOption {
+	return func(r *SQLRepository) {
+		r.Query = query
+	}
+}
+
+// WithSQLWatermark skips reading the rows when query, such as
+// `SELECT max(updated_at) FROM config`, returns the same value as on the last
+// refresh, so that an unchanged table is not parsed again.
+func WithSQLWatermark(query string) SQLOption {
+	return func(r *SQLRepository) {
+		r.WatermarkQuery = query
+	}
+}
+
+// WithSQLTolerateKeyErrors applies the rows that parse when the values of
+// others do not, instead of failing the whole refresh. The rows that did not
+// parse keep their previous values, and Refresh returns a KeyErrors of them.
+func WithSQLTolerateKeyErrors() SQLOption {
+	return func(r *SQLRepository) {
+		r.TolerateKeyErrors = true
+	}
+}
+
+// WithSQLCodec parses the configuration values with codec.
+func WithSQLCodec(codec Codec) SQLOption {
+	return func(r *SQLRepository) {
+		r.Codec = codec
+	}
+}
+
+// WithSQLTimeout bounds the time the queries of a refresh may take.
+func WithSQLTimeout(timeout time.Duration) SQLOption {
+	return func(r *SQLRepository) {
+		r.Timeout = timeout
+	}
+}
+
+// NewSQLRepository creates a SQLRepository reading the columns key and value
+// of table from db. The table name is not quoted, so it must be trusted.
+func NewSQLRepository(name string, db *sql.DB, table string, opts ...SQLOption) *SQLRepository {
+	repository := &SQLRepository{
+		Name:  name,
+		DB:    db,
+		Query: "SELECT key, value FROM " + table,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	return repository
+}
+
+// OpenSQLRepository opens the database at dsn with the registered driver
+// driverName, such as "postgres", and creates a SQLRepository reading table
+// from it like NewSQLRepository. Close closes the database.
+func OpenSQLRepository(name string, driverName string, dsn string, table string, opts ...SQLOption) (*SQLRepository, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	repository := NewSQLRepository(name, db, table, opts...)
+	repository.ownsDB = true
+	return repository, nil
+}
+
+// GetName returns the name of the configuration source.
+func (r *SQLRepository) GetName() string {
+	return r.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (r *SQLRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	r.RLock()
+	defer r.RUnlock()
+	config, isPresent = r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, as the configuration is read row by row.
+func (r *SQLRepository) GetRawData() []byte {
+	return nil
+}
+
+// Keys returns the names of the loaded configurations.
+func (r *SQLRepository) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.data)
+}
+
+// Version returns the watermark of the loaded rows, or an empty string
+// without a WatermarkQuery.
+func (r *SQLRepository) Version() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.watermark
+}
+
+// Refresh queries the database and replaces the data map with its rows.
+func (r *SQLRepository) Refresh() error {
+	return r.RefreshContext(context.Background())
+}
+
+// RefreshContext is Refresh with the queries bound to ctx, so that they are
+// abandoned once ctx is done.
+func (r *SQLRepository) RefreshContext(ctx context.Context) error {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	var watermark string
+	if r.WatermarkQuery != "" {
+		var value sql.NullString
+		err := r.DB.QueryRowContext(ctx, r.WatermarkQuery).Scan(&value)
+		if err != nil {
+			logrus.Debug("error querying watermark")
+			return err
+		}
+		watermark = value.String
+		r.RLock()
+		unchanged := r.data != nil && value.Valid && watermark == r.watermark
+		r.RUnlock()
+		if unchanged {
+			logrus.Debug("watermark unchanged, skipping reparse")
+			r.RLock()
+			defer r.RUnlock()
+			return r.keyErrors
+		}
+	}
+	data, err := r.load(ctx)
+	var keyErrors *KeyErrors
+	if err != nil && !errors.As(err, &keyErrors) {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.data = data
+	r.watermark = watermark
+	r.keyErrors = err
+	return err
+}
+
+// Load queries the database and returns its rows the way Refresh stores
+// them, without replacing the loaded data.
+func (r *SQLRepository) Load() (map[string]interface{}, error) {
+	ctx := context.Background()
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	return r.load(ctx)
+}
+
+// Close closes the database if it was opened by OpenSQLRepository.
+func (r *SQLRepository) Close() error {
+	if !r.ownsDB {
+		return nil
+	}
+	return r.DB.Close()
+}
+
+// load runs Query and parses the value of every row.
+func (r *SQLRepository) load(ctx context.Context) (map[string]interface{}, error) {
+	rows, err := r.DB.QueryContext(ctx, r.Query)
+	if err != nil {
+		logrus.Debug("error querying configurations")
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+			logrus.WithError(err).Debug("error closing rows")
+		}
+	}(rows)
+
+	values := map[string][]byte{}
+	for rows.Next() {
+		var name string
+		var raw sql.NullString
+		if err := rows.Scan(&name, &raw); err != nil {
+			logrus.Debug("error scanning row")
+			return nil, err
+		}
+		values[name] = []byte(raw.String)
+	}
+	if err := rows.Err(); err != nil {
+		logrus.Debug("error reading rows")
+		return nil, err
+	}
+	r.RLock()
+	previous := r.data
+	r.RUnlock()
+	return parseValues(r.Codec, values, previous, r.TolerateKeyErrors)
+}
@@ -0,0 +1,177 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSQLRepository(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Error creating sqlmock: %s", err.Error())
+	}
+	defer db.Close()
+	mock.ExpectQuery("SELECT key, value FROM config").WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).
+		AddRow("db", "host: localhost\nport: 5432\n").
+		AddRow("flags", `{"beta": true}`).
+		AddRow("retries", "3").
+		AddRow("empty", nil))
+
+	repository := NewSQLRepository("sql", db, "config")
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	expected := map[string]interface{}{
+		"db":      map[string]interface{}{"host": "localhost", "port": 5432},
+		"flags":   map[string]interface{}{"beta": true},
+		"retries": 3,
+		"empty":   nil,
+	}
+	for name, value := range expected {
+		config, ok := repository.GetData(name)
+		if !ok || !reflect.DeepEqual(config, value) {
+			t.Errorf("Expected %s to be %v, got %v (%t)", name, value, config, ok)
+		}
+	}
+	if keys := repository.Keys(); !reflect.DeepEqual(keys, []string{"db", "empty", "flags", "retries"}) {
+		t.Errorf("Expected the keys of every row, got %v", keys)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err.Error())
+	}
+}
+
+func TestSQLRepositoryWatermark(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Error creating sqlmock: %s", err.Error())
+	}
+	defer db.Close()
+	watermark := "SELECT max(updated_at) FROM config"
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	query := "SELECT key, value FROM config WHERE env = 'prod'"
+
+	mock.ExpectQuery(watermark).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(updatedAt))
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).AddRow("name", "John"))
+	// The watermark is unchanged, so the rows are not read.
+	mock.ExpectQuery(watermark).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(updatedAt))
+	mock.ExpectQuery(watermark).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(updatedAt.Add(time.Second)))
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).AddRow("name", "Jane"))
+
+	repository := NewSQLRepository("sql", db, "config", WithSQLQuery(query), WithSQLWatermark(watermark))
+	for i := 0; i < 3; i++ {
+		err = repository.Refresh()
+		if err != nil {
+			t.Fatalf("Error refreshing repository: %s", err.Error())
+		}
+	}
+	if name, _ := repository.GetData("name"); name != "Jane" {
+		t.Errorf("Expected the rows of the new watermark, got %v", name)
+	}
+	if repository.Version() == "" {
+		t.Errorf("Expected the watermark as the version")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err.Error())
+	}
+}
+
+func TestSQLRepositoryKeepsDataOnError(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Error creating sqlmock: %s", err.Error())
+	}
+	defer db.Close()
+	mock.ExpectQuery("SELECT key, value FROM config").WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).AddRow("db", "localhost"))
+	errConnection := errors.New("connection refused")
+	mock.ExpectQuery("SELECT key, value FROM config").WillReturnError(errConnection)
+	mock.ExpectQuery("SELECT key, value FROM config").WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).
+		AddRow("db", "remotehost").
+		AddRow("broken", "key: [unterminated"))
+
+	repository := NewSQLRepository("sql", db, "config")
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if err := repository.Refresh(); !errors.Is(err, errConnection) {
+		t.Errorf("Expected the query error, got %v", err)
+	}
+	if err := repository.Refresh(); err == nil {
+		t.Errorf("Expected an error for a value that does not parse")
+	}
+	if db, _ := repository.GetData("db"); db != "localhost" {
+		t.Errorf("Expected the last good data to be kept, got %v", db)
+	}
+}
+
+func TestSQLRepositoryTolerateKeyErrors(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Error creating sqlmock: %s", err.Error())
+	}
+	defer db.Close()
+	watermark := "SELECT max(updated_at) FROM config"
+	updatedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(watermark).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(updatedAt))
+	mock.ExpectQuery("SELECT key, value FROM config").WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).
+		AddRow("db", "localhost").
+		AddRow("limits", "max: 3"))
+	mock.ExpectQuery(watermark).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(updatedAt.Add(time.Second)))
+	mock.ExpectQuery("SELECT key, value FROM config").WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).
+		AddRow("db", "remotehost").
+		AddRow("limits", "max: [unterminated").
+		AddRow("broken", "key: [unterminated"))
+	// The watermark is unchanged, so the errors of the loaded rows are reported again.
+	mock.ExpectQuery(watermark).WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(updatedAt.Add(time.Second)))
+
+	repository := NewSQLRepository("sql", db, "config", WithSQLWatermark(watermark), WithSQLTolerateKeyErrors())
+	if err := repository.Refresh(); err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	for i := 0; i < 2; i++ {
+		err = repository.Refresh()
+		var keyErrors *KeyErrors
+		if !errors.As(err, &keyErrors) || len(keyErrors.Errors) != 2 || keyErrors.Errors["limits"] == nil || keyErrors.Errors["broken"] == nil {
+			t.Fatalf("Expected the errors of limits and broken, got %v", err)
+		}
+	}
+	// The rows that parsed are applied, and the others keep their previous values.
+	if db, _ := repository.GetData("db"); db != "remotehost" {
+		t.Errorf("Expected the row that parsed to be applied, got %v", db)
+	}
+	if limits, _ := repository.GetData("limits"); !reflect.DeepEqual(limits, map[string]interface{}{"max": 3}) {
+		t.Errorf("Expected limits to keep its previous value, got %v", limits)
+	}
+	if _, ok := repository.GetData("broken"); ok {
+		t.Errorf("Expected broken to be left out, as it had no previous value")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err.Error())
+	}
+}
+
+func TestSQLRepositoryContext(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("Error creating sqlmock: %s", err.Error())
+	}
+	defer db.Close()
+	mock.ExpectQuery("SELECT key, value FROM config").WillDelayFor(time.Second).WillReturnRows(sqlmock.NewRows([]string{"key", "value"}))
+
+	repository := NewSQLRepository("sql", db, "config")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := repository.RefreshContext(ctx); err == nil {
+		t.Errorf("Expected the query to be canceled with the context")
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("Expected the query to be abandoned once the context expired, took %s", elapsed)
+	}
+}
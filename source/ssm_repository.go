@@ -0,0 +1,179 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/sirupsen/logrus"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SSMAPI is the subset of the SSM client used by SSMRepository.
+type SSMAPI interface {
+	GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error)
+}
+
+// SSMRepository is a struct that implements the Repository interface for
+// handling configuration data stored as parameters of the SSM Parameter
+// Store. Every parameter under Path is read on refresh, SecureString
+// parameters decrypted, and stored by its name relative to Path, so the
+// parameter `/app/prod/db/host` under the path `/app/prod` is the
+// configuration `db/host`. StringList parameters are stored as lists of
+// strings. Parameter values are never logged. A failed refresh leaves the
+// current data in place.
+type SSMRepository struct {
+	sync.RWMutex                        // RWMutex to synchronize access to data during refresh
+	Name         string                 // Name of the configuration source
+	Path         string                 // Path of the parameters to read, stripped from the configuration names
+	Client       SSMAPI                 // SSM client, created from the AWS config by NewSSMRepository
+	data         map[string]interface{} // Map to store the configuration data
+	version      string                 // Version marker of the currently loaded parameters
+}
+
+// SSMOption configures an SSMRepository created with NewSSMRepository.
+type SSMOption func(*SSMRepository)
+
+// WithSSMClient sets the SSM client used to read the parameters.
+func WithSSMClient(client SSMAPI) SSMOption {
+	return func(r *SSMRepository) {
+		r.Client = client
+	}
+}
+
+// NewSSMRepository creates an SSMRepository reading the parameters under path,
+// such as /app/prod, with an SSM client created from cfg.
+func NewSSMRepository(name string, path string, cfg aws.Config, opts ...SSMOption) *SSMRepository {
+	repository := &SSMRepository{
+		Name: name,
+		Path: path,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	if repository.Client == nil {
+		repository.Client = ssm.NewFromConfig(cfg)
+	}
+	return repository
+}
+
+// GetName returns the name of the configuration source.
+func (r *SSMRepository) GetName() string {
+	return r.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (r *SSMRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	r.RLock()
+	defer r.RUnlock()
+	config, isPresent = r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, so that decrypted parameters are never exposed as raw data.
+func (r *SSMRepository) GetRawData() []byte {
+	return nil
+}
+
+// Version returns a marker that changes whenever the version of any loaded parameter changes.
+func (r *SSMRepository) Version() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.version
+}
+
+// Keys returns the names of the loaded parameters.
+func (r *SSMRepository) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.data)
+}
+
+// Refresh reads every page of the parameters under the path and replaces the
+// data map with them.
+func (r *SSMRepository) Refresh() error {
+	data, version, err := r.load(context.Background())
+	if err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.data = data
+	r.version = version
+	return nil
+}
+
+// Load reads every page of the parameters under the path the way Refresh
+// does and returns them, without replacing the loaded data.
+func (r *SSMRepository) Load() (map[string]interface{}, error) {
+	data, _, err := r.load(context.Background())
+	return data, err
+}
+
+// load reads every page of the parameters under the path and returns them
+// with a version derived from their parameter versions.
+func (r *SSMRepository) load(ctx context.Context) (map[string]interface{}, string, error) {
+	input := &ssm.GetParametersByPathInput{
+		Path:           aws.String(r.Path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	}
+
+	data := map[string]interface{}{}
+	var versions []string
+	for {
+		output, err := r.Client.GetParametersByPath(ctx, input)
+		if err != nil {
+			logrus.Debug("error getting parameters")
+			return nil, "", err
+		}
+		for _, parameter := range output.Parameters {
+			name, ok := r.name(aws.ToString(parameter.Name))
+			if !ok {
+				continue
+			}
+			value := aws.ToString(parameter.Value)
+			if parameter.Type == types.ParameterTypeStringList {
+				data[name] = stringList(value)
+			} else {
+				data[name] = value
+			}
+			versions = append(versions, name+"/"+strconv.FormatInt(parameter.Version, 10))
+		}
+		if aws.ToString(output.NextToken) == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	// Derive the version from the parameter versions, never from their values.
+	sort.Strings(versions)
+	sum := sha256.Sum256([]byte(strings.Join(versions, "\n")))
+	return data, hex.EncodeToString(sum[:]), nil
+}
+
+// name returns the configuration name of the parameter, relative to the
+// path, and whether the parameter is under the path.
+func (r *SSMRepository) name(parameter string) (string, bool) {
+	path := strings.TrimSuffix(r.Path, "/") + "/"
+	if !strings.HasPrefix(parameter, path) {
+		return "", false
+	}
+	name := strings.Trim(strings.TrimPrefix(parameter, path), "/")
+	return name, name != ""
+}
+
+// stringList splits the comma-separated value of a StringList parameter.
+func stringList(value string) []interface{} {
+	items := strings.Split(value, ",")
+	list := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		list = append(list, item)
+	}
+	return list
+}
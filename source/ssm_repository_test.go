@@ -0,0 +1,135 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeSSM is an in-memory SSM Parameter Store returning one parameter per page.
+// SecureString parameters are only returned decrypted when decryption is asked for.
+type fakeSSM struct {
+	parameters []types.Parameter
+	calls      int
+	err        error
+}
+
+func (f *fakeSSM) GetParametersByPath(_ context.Context, params *ssm.GetParametersByPathInput, _ ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	var matching []types.Parameter
+	for _, parameter := range f.parameters {
+		if strings.HasPrefix(aws.ToString(parameter.Name), aws.ToString(params.Path)) {
+			if parameter.Type == types.ParameterTypeSecureString && !aws.ToBool(params.WithDecryption) {
+				parameter.Value = aws.String("AQICAHencrypted")
+			}
+			matching = append(matching, parameter)
+		}
+	}
+	start := 0
+	if params.NextToken != nil {
+		start, _ = strconv.Atoi(aws.ToString(params.NextToken))
+	}
+	output := &ssm.GetParametersByPathOutput{}
+	if start < len(matching) {
+		output.Parameters = matching[start : start+1]
+	}
+	if start+1 < len(matching) {
+		output.NextToken = aws.String(strconv.Itoa(start + 1))
+	}
+	return output, nil
+}
+
+func ssmParameter(name string, parameterType types.ParameterType, value string, version int64) types.Parameter {
+	return types.Parameter{Name: aws.String(name), Type: parameterType, Value: aws.String(value), Version: version}
+}
+
+func TestSSMRepository(t *testing.T) {
+	service := &fakeSSM{parameters: []types.Parameter{
+		ssmParameter("/app/prod/db/host", types.ParameterTypeString, "localhost", 1),
+		ssmParameter("/app/prod/db/password", types.ParameterTypeSecureString, "hunter2", 3),
+		ssmParameter("/app/prod/regions", types.ParameterTypeStringList, "eu,us", 1),
+		ssmParameter("/app/staging/db/host", types.ParameterTypeString, "staging", 1),
+	}}
+
+	repository := NewSSMRepository("ssm", "/app/prod", aws.Config{}, WithSSMClient(service))
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if service.calls != 3 {
+		t.Errorf("Expected every page to be read, got %d calls", service.calls)
+	}
+	host, _ := repository.GetData("db/host")
+	if host != "localhost" {
+		t.Errorf("Expected db/host to be localhost, got %v", host)
+	}
+	password, _ := repository.GetData("db/password")
+	if password != "hunter2" {
+		t.Errorf("Expected db/password to be decrypted, got %v", password)
+	}
+	regions, _ := repository.GetData("regions")
+	if list, ok := regions.([]interface{}); !ok || len(list) != 2 || list[0] != "eu" || list[1] != "us" {
+		t.Errorf("Expected regions to be a list, got %v", regions)
+	}
+	keys := repository.Keys()
+	if len(keys) != 3 || keys[0] != "db/host" || keys[1] != "db/password" || keys[2] != "regions" {
+		t.Errorf("Expected keys [db/host db/password regions], got %v", keys)
+	}
+	if repository.GetRawData() != nil {
+		t.Errorf("Expected no raw data, got %s", repository.GetRawData())
+	}
+
+	// The version only changes when a parameter does, and never holds a value.
+	version := repository.Version()
+	if strings.Contains(version, "hunter2") {
+		t.Errorf("Expected the version not to hold a secret value, got %s", version)
+	}
+	_ = repository.Refresh()
+	if repository.Version() != version {
+		t.Errorf("Expected the version to be stable, got %s and %s", version, repository.Version())
+	}
+	service.parameters[1] = ssmParameter("/app/prod/db/password", types.ParameterTypeSecureString, "correct-horse", 4)
+	_ = repository.Refresh()
+	if repository.Version() == version {
+		t.Errorf("Expected the version to change with the parameters")
+	}
+	password, _ = repository.GetData("db/password")
+	if password != "correct-horse" {
+		t.Errorf("Expected db/password to be correct-horse, got %v", password)
+	}
+
+	// Load reads the parameters without replacing the data.
+	service.parameters[0] = ssmParameter("/app/prod/db/host", types.ParameterTypeString, "db.internal", 2)
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	if loaded["db/host"] != "db.internal" {
+		t.Errorf("Expected loaded db/host to be db.internal, got %v", loaded["db/host"])
+	}
+	host, _ = repository.GetData("db/host")
+	if host != "localhost" {
+		t.Errorf("Expected db/host to stay localhost, got %v", host)
+	}
+
+	// A failed refresh keeps the loaded parameters.
+	service.err = errors.New("unavailable")
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error for a failed request, got nil")
+	}
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected Load to fail for a failed request, got nil")
+	}
+	password, _ = repository.GetData("db/password")
+	if password != "correct-horse" {
+		t.Errorf("Expected the last good data to be kept, got %v", password)
+	}
+}
@@ -0,0 +1,244 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// Default backoff bounds of the reconnect loop of a StreamRepository.
+const (
+	DefaultStreamMinBackoff = 100 * time.Millisecond
+	DefaultStreamMaxBackoff = 30 * time.Second
+)
+
+// ErrStreamClosed is returned by the Refresh of a StreamRepository after Close.
+var ErrStreamClosed = errors.New("stream repository is closed")
+
+// StreamUpdate is a change of one configuration received from a ConfigStream.
+type StreamUpdate struct {
+	Key     string      // Name of the configuration
+	Value   interface{} // New value of the configuration
+	Deleted bool        // Whether the configuration was deleted
+}
+
+// ConfigStream is a stream of configuration updates, such as the client
+// side of a gRPC server-streaming call.
+type ConfigStream interface {
+	// Recv blocks until the next update and returns an error once the stream is broken.
+	Recv() (StreamUpdate, error)
+}
+
+// StreamAPI is the subset of a streaming configuration service, such as a
+// gRPC service, used by StreamRepository.
+type StreamAPI interface {
+	// Snapshot returns every configuration.
+	Snapshot(ctx context.Context) (map[string]interface{}, error)
+	// Watch opens a stream of the updates made after it was opened. The stream
+	// must end when ctx is canceled.
+	Watch(ctx context.Context) (ConfigStream, error)
+}
+
+// StreamRepository is a struct that implements the Repository interface for
+// configuration data pushed by a streaming service. The first Refresh loads a
+// snapshot and starts watching the stream in the background; updates are
+// applied as they arrive. When the stream breaks, the repository reconnects
+// with exponential backoff and resyncs the full snapshot, so no update made
+// while disconnected is missed. Close, which Client.Close calls, stops the
+// reconnect loop.
+type StreamRepository struct {
+	sync.RWMutex                          // RWMutex to synchronize access to data
+	changeNotifier                        // Notifies the updates and resyncs of the stream
+	Name           string                 // Name of the configuration source
+	Client         StreamAPI              // Streaming service client
+	MinBackoff     time.Duration          // First delay before reconnecting, defaults to DefaultStreamMinBackoff
+	MaxBackoff     time.Duration          // Maximum delay between reconnects, defaults to DefaultStreamMaxBackoff
+	data           map[string]interface{} // Map to store the configuration data
+	err            error                  // Error of the last reconnect attempt, nil while connected
+	started        bool                   // Whether the watch loop has been started
+	closed         bool                   // Whether Close has been called
+	cancel         context.CancelFunc     // Cancels the watch loop
+	done           chan struct{}          // Closed when the watch loop returns
+}
+
+// GetName returns the name of the configuration source.
+func (s *StreamRepository) GetName() string {
+	return s.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (s *StreamRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	s.RLock()
+	defer s.RUnlock()
+	config, isPresent = s.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, as the configuration is streamed key by key.
+func (s *StreamRepository) GetRawData() []byte {
+	return nil
+}
+
+// Keys returns the names of the current configurations.
+func (s *StreamRepository) Keys() []string {
+	s.RLock()
+	defer s.RUnlock()
+	return sortedKeys(s.data)
+}
+
+// Refresh loads the snapshot and starts the watch loop on its first call.
+// Later calls do not contact the service, the stream keeps the data current;
+// they return the error of the last reconnect attempt while the stream is down.
+func (s *StreamRepository) Refresh() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.closed {
+		return ErrStreamClosed
+	}
+	if s.started {
+		return s.err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, data, err := s.sync(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+	s.data = data
+	s.started = true
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.watch(ctx, stream)
+	return nil
+}
+
+// Load reads the snapshot of the service the way the first Refresh does and
+// returns it, without replacing the loaded data or opening a stream.
+func (s *StreamRepository) Load() (map[string]interface{}, error) {
+	data, err := s.Client.Snapshot(context.Background())
+	if err != nil {
+		logrus.Debug("error loading snapshot")
+		return nil, err
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return data, nil
+}
+
+// Close stops the watch loop and waits for it to return.
+func (s *StreamRepository) Close() error {
+	s.Lock()
+	s.closed = true
+	cancel, done := s.cancel, s.done
+	s.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	return nil
+}
+
+// sync opens a stream and then loads the snapshot, so that updates made
+// while the snapshot is read are delivered by the stream.
+func (s *StreamRepository) sync(ctx context.Context) (ConfigStream, map[string]interface{}, error) {
+	stream, err := s.Client.Watch(ctx)
+	if err != nil {
+		logrus.Debug("error opening stream")
+		return nil, nil, err
+	}
+	data, err := s.Client.Snapshot(ctx)
+	if err != nil {
+		logrus.Debug("error loading snapshot")
+		return nil, nil, err
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return stream, data, nil
+}
+
+// watch applies the updates of stream until it breaks, then reconnects with
+// exponential backoff, until ctx is canceled.
+func (s *StreamRepository) watch(ctx context.Context, stream ConfigStream) {
+	defer close(s.done)
+	for {
+		err := s.consume(stream)
+		if ctx.Err() != nil {
+			return
+		}
+		logrus.WithError(err).Warn("config stream broken, reconnecting")
+		s.setErr(err)
+
+		backoff := s.minBackoff()
+		for {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			var data map[string]interface{}
+			stream, data, err = s.sync(ctx)
+			if err == nil {
+				s.Lock()
+				s.data = data
+				s.err = nil
+				s.Unlock()
+				s.notifyChange()
+				logrus.Debug("config stream reconnected")
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			s.setErr(err)
+			backoff *= 2
+			if max := s.maxBackoff(); backoff > max {
+				backoff = max
+			}
+		}
+	}
+}
+
+// consume applies the updates of stream and returns the error that ended it.
+func (s *StreamRepository) consume(stream ConfigStream) error {
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		s.Lock()
+		if update.Deleted {
+			delete(s.data, update.Key)
+		} else {
+			s.data[update.Key] = update.Value
+		}
+		s.Unlock()
+		s.notifyChange()
+	}
+}
+
+func (s *StreamRepository) setErr(err error) {
+	s.Lock()
+	defer s.Unlock()
+	s.err = err
+}
+
+func (s *StreamRepository) minBackoff() time.Duration {
+	if s.MinBackoff > 0 {
+		return s.MinBackoff
+	}
+	return DefaultStreamMinBackoff
+}
+
+func (s *StreamRepository) maxBackoff() time.Duration {
+	if s.MaxBackoff > 0 {
+		return s.MaxBackoff
+	}
+	return DefaultStreamMaxBackoff
+}
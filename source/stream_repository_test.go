@@ -0,0 +1,170 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStream is a ConfigStream fed by a channel. Closing kill breaks it.
+type fakeStream struct {
+	ctx     context.Context
+	updates chan StreamUpdate
+	kill    chan struct{}
+}
+
+func (f *fakeStream) Recv() (StreamUpdate, error) {
+	select {
+	case update := <-f.updates:
+		return update, nil
+	case <-f.kill:
+		return StreamUpdate{}, errors.New("stream reset")
+	case <-f.ctx.Done():
+		return StreamUpdate{}, f.ctx.Err()
+	}
+}
+
+// fakeStreamService is a StreamAPI serving data, whose Watch fails while down.
+type fakeStreamService struct {
+	sync.Mutex
+	data    map[string]interface{}
+	down    bool
+	watches int
+	streams chan *fakeStream
+}
+
+func (f *fakeStreamService) Snapshot(_ context.Context) (map[string]interface{}, error) {
+	f.Lock()
+	defer f.Unlock()
+	data := map[string]interface{}{}
+	for key, value := range f.data {
+		data[key] = value
+	}
+	return data, nil
+}
+
+func (f *fakeStreamService) Watch(ctx context.Context) (ConfigStream, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.watches++
+	if f.down {
+		return nil, errors.New("connection refused")
+	}
+	stream := &fakeStream{ctx: ctx, updates: make(chan StreamUpdate), kill: make(chan struct{})}
+	f.streams <- stream
+	return stream, nil
+}
+
+func (f *fakeStreamService) set(key string, value interface{}) {
+	f.Lock()
+	defer f.Unlock()
+	f.data[key] = value
+}
+
+func (f *fakeStreamService) setDown(down bool) {
+	f.Lock()
+	defer f.Unlock()
+	f.down = down
+}
+
+func (f *fakeStreamService) watchCount() int {
+	f.Lock()
+	defer f.Unlock()
+	return f.watches
+}
+
+// waitForData waits until the repository holds value for key.
+func waitForData(t *testing.T, repository Repository, key string, value interface{}) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, ok := repository.GetData(key); ok && got == value {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	got, _ := repository.GetData(key)
+	t.Fatalf("Expected %s to be %v, got %v", key, value, got)
+}
+
+func TestStreamRepositoryReconnects(t *testing.T) {
+	service := &fakeStreamService{data: map[string]interface{}{"name": "John"}, streams: make(chan *fakeStream, 10)}
+	repository := &StreamRepository{Name: "stream", Client: service, MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+	notified := notifications(repository)
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+
+	// Load reads a snapshot without replacing the data or opening a stream.
+	service.set("name", "Jill")
+	loaded, err := repository.Load()
+	if err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	if loaded["name"] != "Jill" {
+		t.Errorf("Expected loaded name to be Jill, got %v", loaded["name"])
+	}
+	name, _ = repository.GetData("name")
+	if name != "John" || service.watchCount() != 1 {
+		t.Errorf("Expected name to stay John on one stream, got %v on %d", name, service.watchCount())
+	}
+	service.set("name", "John")
+
+	// Updates are applied as they arrive, and notified.
+	stream := <-service.streams
+	stream.updates <- StreamUpdate{Key: "age", Value: 30}
+	waitForData(t, repository, "age", 30)
+	waitForNotification(t, notified)
+
+	// Kill the stream while the service is down; changes made during the gap
+	// are picked up by the resync once the service is back.
+	service.setDown(true)
+	close(stream.kill)
+	service.set("name", "Jane")
+	deadline := time.Now().Add(2 * time.Second)
+	for service.watchCount() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if repository.Refresh() == nil {
+		t.Errorf("Expected Refresh to report the stream is down")
+	}
+	service.setDown(false)
+	stream = <-service.streams
+	waitForData(t, repository, "name", "Jane")
+	if err := repository.Refresh(); err != nil {
+		t.Errorf("Expected no error once reconnected, got %s", err.Error())
+	}
+	stream.updates <- StreamUpdate{Key: "age", Deleted: true}
+	deadline = time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok := repository.GetData("age"); !ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, ok := repository.GetData("age"); ok {
+		t.Errorf("Expected age to be deleted")
+	}
+
+	// Close stops the reconnect loop.
+	service.setDown(true)
+	err = repository.Close()
+	if err != nil {
+		t.Errorf("Error closing repository: %s", err.Error())
+	}
+	watches := service.watchCount()
+	time.Sleep(20 * time.Millisecond)
+	if service.watchCount() != watches {
+		t.Errorf("Expected no reconnect after Close")
+	}
+	if !errors.Is(repository.Refresh(), ErrStreamClosed) {
+		t.Errorf("Expected ErrStreamClosed after Close")
+	}
+}
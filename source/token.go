@@ -0,0 +1,34 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+)
+
+// TokenProvider returns the token a repository authenticates with, such as
+// an ACL token of etcd or Consul. Repositories call it on every refresh, and
+// again when the backend rejects the token, so that rotated tokens are picked
+// up without restarting. It should return a token that is valid now.
+type TokenProvider func(ctx context.Context) (string, error)
+
+// withToken calls fetch with a token from provider. If the backend rejects
+// the token, it asks provider for a fresh one and calls fetch once more.
+func withToken(ctx context.Context, provider TokenProvider, fetch func(token string) error) error {
+	token, err := provider(ctx)
+	if err != nil {
+		logrus.Debug("error getting token")
+		return err
+	}
+	err = fetch(token)
+	if !errors.Is(NormalizeError(err), ErrSourceUnauthorized) {
+		return err
+	}
+	logrus.Debug("token rejected, re-authenticating")
+	token, err = provider(ctx)
+	if err != nil {
+		logrus.Debug("error getting token")
+		return err
+	}
+	return fetch(token)
+}
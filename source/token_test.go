@@ -0,0 +1,118 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// rotatingTokens issues numbered tokens, of which only the latest is valid.
+type rotatingTokens struct {
+	sync.Mutex
+	issued int
+}
+
+func (r *rotatingTokens) current(context.Context) (string, error) {
+	r.Lock()
+	defer r.Unlock()
+	return fmt.Sprintf("token-%d", r.issued), nil
+}
+
+// rotate expires the current token and issues a new one.
+func (r *rotatingTokens) rotate() {
+	r.Lock()
+	defer r.Unlock()
+	r.issued++
+}
+
+func TestWebRepositoryTokenRotation(t *testing.T) {
+	tokens := &rotatingTokens{}
+	expire := false
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-Consul-Token")
+		requests = append(requests, token)
+		if expire {
+			// The token expires while the request is in flight.
+			expire = false
+			tokens.rotate()
+		}
+		if current, _ := tokens.current(r.Context()); token != current {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte("name: John\n"))
+	}))
+	defer server.Close()
+	urlParsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Error parsing url: %s", err.Error())
+	}
+
+	repository := &WebRepository{Name: "web", URL: urlParsed, TokenProvider: tokens.current, TokenHeader: "X-Consul-Token"}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// The rejected request is retried with a fresh token.
+	expire = true
+	requests = nil
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository after rotation: %s", err.Error())
+	}
+	if len(requests) != 2 || requests[0] != "token-0" || requests[1] != "token-1" {
+		t.Errorf("Expected a rejected request and a retry with the new token, got %v", requests)
+	}
+
+	// Later refreshes ask for the current token up front.
+	tokens.rotate()
+	requests = nil
+	err = repository.Refresh()
+	if err != nil || len(requests) != 1 || requests[0] != "token-2" {
+		t.Errorf("Expected a single request with the rotated token, got %v (%v)", requests, err)
+	}
+}
+
+func TestWebRepositoryTokenRejected(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("name: John\n"))
+	}))
+	defer server.Close()
+	urlParsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Error parsing url: %s", err.Error())
+	}
+
+	provider := func(context.Context) (string, error) {
+		return "revoked", nil
+	}
+	repository := &WebRepository{Name: "web", URL: urlParsed, TokenProvider: provider}
+	err = repository.Refresh()
+	if !errors.Is(NormalizeError(err), ErrSourceUnauthorized) {
+		t.Errorf("Expected an unauthorized error, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected a single retry, got %d requests", requests)
+	}
+
+	providerErr := errors.New("token service down")
+	repository.TokenProvider = func(context.Context) (string, error) {
+		return "", providerErr
+	}
+	if err := repository.Refresh(); !errors.Is(err, providerErr) {
+		t.Errorf("Expected the provider error, got %v", err)
+	}
+}
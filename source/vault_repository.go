@@ -0,0 +1,290 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultVaultAddress is the address of a local Vault server, which a
+// VaultRepository connects to when it is given no address.
+const DefaultVaultAddress = "http://127.0.0.1:8200"
+
+// DefaultVaultMount is the mount path of the KV v2 secrets engine a
+// VaultRepository reads from when it is given no mount.
+const DefaultVaultMount = "secret"
+
+// VaultRepository is a struct that implements the Repository interface for
+// handling configuration data stored as a secret of the KV v2 secrets engine
+// of HashiCorp Vault. The latest version of the secret at Path is read on
+// refresh, and every field of the secret is a configuration, so the secret
+// {"username": "app", "password": "hunter2"} holds the configurations
+// username and password. When Vault grants the secret with a lease, the
+// secret is read again after two thirds of the lease, before it expires,
+// until Close is called. Secret values are never logged. A failed refresh,
+// for example while Vault is unreachable, leaves the current data in place.
+type VaultRepository struct {
+	sync.RWMutex                         // RWMutex to synchronize access to data during refresh
+	Name          string                 // Name of the configuration source
+	Address       string                 // Address of the Vault server, defaults to DefaultVaultAddress
+	Mount         string                 // Mount path of the KV v2 secrets engine, defaults to DefaultVaultMount
+	Path          string                 // Path of the secret within the mount
+	Token         string                 // Token sent with every request
+	TokenProvider TokenProvider          // Provider of the token, used instead of Token and called again when Vault answers 401 or 403
+	HTTPClient    *http.Client           // HTTP client used to reach Vault, defaults to http.DefaultClient
+	data          map[string]interface{} // Map to store the configuration data
+	version       string                 // Version of the currently loaded secret
+	renewal       *time.Timer            // Reads the secret again before its lease expires, nil without a lease
+	closed        bool                   // Whether Close has been called
+}
+
+// VaultOption configures a VaultRepository created with NewVaultRepository.
+type VaultOption func(*VaultRepository)
+
+// WithVaultToken authenticates every request with the given token.
+func WithVaultToken(token string) VaultOption {
+	return func(r *VaultRepository) {
+		r.Token = token
+	}
+}
+
+// WithVaultTokenProvider asks provider for the token on every refresh, so
+// that tokens obtained from an auth method, such as Kubernetes or AppRole,
+// can be renewed or replaced.
+func WithVaultTokenProvider(provider TokenProvider) VaultOption {
+	return func(r *VaultRepository) {
+		r.TokenProvider = provider
+	}
+}
+
+// WithVaultMount reads the secret from the KV v2 secrets engine mounted at mount.
+func WithVaultMount(mount string) VaultOption {
+	return func(r *VaultRepository) {
+		r.Mount = mount
+	}
+}
+
+// WithVaultHTTPClient sets the HTTP client used to reach Vault, for example
+// one configured with TLS client certificates.
+func WithVaultHTTPClient(client *http.Client) VaultOption {
+	return func(r *VaultRepository) {
+		r.HTTPClient = client
+	}
+}
+
+// NewVaultRepository creates a VaultRepository reading the secret at path,
+// such as app/prod/db, from the Vault server at address, such as
+// https://vault.internal:8200. An address without a scheme is reached over http.
+func NewVaultRepository(name string, address string, path string, opts ...VaultOption) *VaultRepository {
+	repository := &VaultRepository{
+		Name:    name,
+		Address: address,
+		Path:    path,
+	}
+	for _, opt := range opts {
+		opt(repository)
+	}
+	return repository
+}
+
+// GetName returns the name of the configuration source.
+func (r *VaultRepository) GetName() string {
+	return r.Name
+}
+
+// GetData returns the configuration data as a map of configuration names to their respective models.
+func (r *VaultRepository) GetData(configName string) (config interface{}, isPresent bool) {
+	r.RLock()
+	defer r.RUnlock()
+	config, isPresent = r.data[configName]
+	return config, isPresent
+}
+
+// GetRawData returns nil, so that secrets are never exposed as raw data.
+func (r *VaultRepository) GetRawData() []byte {
+	return nil
+}
+
+// Version returns the version of the currently loaded secret.
+func (r *VaultRepository) Version() string {
+	r.RLock()
+	defer r.RUnlock()
+	return r.version
+}
+
+// Keys returns the names of the fields of the loaded secret.
+func (r *VaultRepository) Keys() []string {
+	r.RLock()
+	defer r.RUnlock()
+	return sortedKeys(r.data)
+}
+
+// vaultSecret is the response of Vault to a read of a KV v2 secret.
+type vaultSecret struct {
+	LeaseDuration int `json:"lease_duration"` // Seconds the secret is leased for, 0 without a lease
+	Data          struct {
+		Data     map[string]interface{} `json:"data"` // Fields of the secret
+		Metadata struct {
+			Version int `json:"version"` // Version of the secret
+		} `json:"metadata"`
+	} `json:"data"`
+}
+
+// Refresh reads the latest version of the secret and replaces the data map
+// with its fields.
+func (r *VaultRepository) Refresh() error {
+	return r.RefreshContext(context.Background())
+}
+
+// RefreshContext is Refresh with the requests to Vault bound to ctx.
+func (r *VaultRepository) RefreshContext(ctx context.Context) error {
+	secret, err := r.readSecret(ctx)
+	if err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.data = secret.Data.Data
+	if r.data == nil {
+		r.data = map[string]interface{}{}
+	}
+	r.version = strconv.Itoa(secret.Data.Metadata.Version)
+	r.scheduleRenewal(time.Duration(secret.LeaseDuration) * time.Second)
+	return nil
+}
+
+// Load reads the latest version of the secret and returns its fields,
+// without replacing the loaded data.
+func (r *VaultRepository) Load() (map[string]interface{}, error) {
+	secret, err := r.readSecret(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if secret.Data.Data == nil {
+		return map[string]interface{}{}, nil
+	}
+	return secret.Data.Data, nil
+}
+
+// Close stops reading the secret again before its lease expires.
+func (r *VaultRepository) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	r.closed = true
+	if r.renewal != nil {
+		r.renewal.Stop()
+	}
+	return nil
+}
+
+// scheduleRenewal reads the secret again after two thirds of lease, replacing
+// any renewal scheduled before. The caller must hold the lock.
+func (r *VaultRepository) scheduleRenewal(lease time.Duration) {
+	if r.renewal != nil {
+		r.renewal.Stop()
+		r.renewal = nil
+	}
+	if lease <= 0 || r.closed {
+		return
+	}
+	r.renewal = time.AfterFunc(lease*2/3, func() {
+		if err := r.Refresh(); err != nil {
+			logrus.WithError(NormalizeError(err)).Debug("error renewing secret")
+		}
+	})
+}
+
+// readSecret reads the secret, authenticating with a fresh token if there is
+// a provider.
+func (r *VaultRepository) readSecret(ctx context.Context) (*vaultSecret, error) {
+	var secret *vaultSecret
+	fetch := func(token string) error {
+		var err error
+		secret, err = r.read(ctx, token)
+		return err
+	}
+	var err error
+	if r.TokenProvider != nil {
+		err = withToken(ctx, r.TokenProvider, fetch)
+	} else {
+		err = fetch(r.Token)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// requestURL returns the URL of the secret.
+func (r *VaultRepository) requestURL() (*url.URL, error) {
+	address := r.Address
+	if address == "" {
+		address = DefaultVaultAddress
+	}
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	requestURL, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	mount := r.Mount
+	if mount == "" {
+		mount = DefaultVaultMount
+	}
+	requestURL.Path = strings.TrimSuffix(requestURL.Path, "/") + "/v1/" + strings.Trim(mount, "/") + "/data/" + strings.Trim(r.Path, "/")
+	return requestURL, nil
+}
+
+// read requests the secret with the given token.
+func (r *VaultRepository) read(ctx context.Context, token string) (*vaultSecret, error) {
+	requestURL, err := r.requestURL()
+	if err != nil {
+		logrus.Debug("error parsing vault address")
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL.String(), nil)
+	if err != nil {
+		logrus.Debug("error creating request")
+		return nil, err
+	}
+	if token != "" {
+		request.Header.Set("X-Vault-Token", token)
+	}
+
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		logrus.Debug("error doing request")
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			logrus.WithError(err).Debug("error closing response body")
+		}
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		logrus.Debug("unexpected status code")
+		return nil, newStatusError(resp, requestURL)
+	}
+
+	secret := &vaultSecret{}
+	err = json.NewDecoder(resp.Body).Decode(secret)
+	if err != nil {
+		logrus.Debug("error decoding secret")
+		return nil, err
+	}
+	return secret, nil
+}
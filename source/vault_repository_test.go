@@ -0,0 +1,167 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeVault is an in-memory KV v2 secrets engine mounted at secret/ that
+// requires the token "root".
+type fakeVault struct {
+	mu      sync.Mutex
+	secrets map[string]map[string]interface{}
+	version int
+	lease   int
+	reads   int
+	down    bool
+}
+
+func (f *fakeVault) set(path string, fields map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.secrets[path] = fields
+	f.version++
+}
+
+func (f *fakeVault) readCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reads
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads++
+	if f.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if r.Header.Get("X-Vault-Token") != "root" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	fields, ok := f.secrets[strings.TrimPrefix(r.URL.Path, "/v1/secret/data/")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"lease_duration": f.lease,
+		"data": map[string]interface{}{
+			"data":     fields,
+			"metadata": map[string]interface{}{"version": f.version},
+		},
+	})
+}
+
+func TestVaultRepository(t *testing.T) {
+	vault := &fakeVault{secrets: map[string]map[string]interface{}{}}
+	vault.set("app/db", map[string]interface{}{"username": "app", "password": "hunter2", "port": 5432})
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logrus.SetOutput(&logs)
+	logrus.SetLevel(logrus.DebugLevel)
+	defer logrus.SetOutput(logrus.StandardLogger().Out)
+	defer logrus.SetLevel(logrus.InfoLevel)
+
+	repository := NewVaultRepository("vault", server.URL, "app/db", WithVaultToken("root"))
+	defer repository.Close()
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	password, _ := repository.GetData("password")
+	if password != "hunter2" {
+		t.Errorf("Expected password to be hunter2, got %v", password)
+	}
+	port, _ := repository.GetData("port")
+	if port != float64(5432) {
+		t.Errorf("Expected port to be 5432, got %v", port)
+	}
+	if version := repository.Version(); version != "1" {
+		t.Errorf("Expected version 1, got %s", version)
+	}
+
+	// An unreachable Vault keeps the last good secret.
+	vault.mu.Lock()
+	vault.down = true
+	vault.mu.Unlock()
+	err = repository.Refresh()
+	if !errors.Is(NormalizeError(err), ErrSourceUnavailable) {
+		t.Errorf("Expected the source to be unavailable, got %v", err)
+	}
+	password, _ = repository.GetData("password")
+	if password != "hunter2" {
+		t.Errorf("Expected the last good password to be kept, got %v", password)
+	}
+	if strings.Contains(logs.String(), "hunter2") || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("Expected the secret never to be logged")
+	}
+}
+
+func TestVaultRepositoryTokenProvider(t *testing.T) {
+	vault := &fakeVault{secrets: map[string]map[string]interface{}{}}
+	vault.set("app", map[string]interface{}{"key": "value"})
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	tokens := []string{"expired", "root"}
+	provider := func(ctx context.Context) (string, error) {
+		token := tokens[0]
+		tokens = tokens[1:]
+		return token, nil
+	}
+	repository := NewVaultRepository("vault", server.URL, "app", WithVaultTokenProvider(provider))
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Expected a rejected token to be replaced, got %s", err.Error())
+	}
+	if value, _ := repository.GetData("key"); value != "value" {
+		t.Errorf("Expected key to be value, got %v", value)
+	}
+}
+
+func TestVaultRepositoryLeaseRenewal(t *testing.T) {
+	vault := &fakeVault{secrets: map[string]map[string]interface{}{}, lease: 1}
+	vault.set("app", map[string]interface{}{"password": "old"})
+	server := httptest.NewServer(vault)
+	defer server.Close()
+
+	repository := NewVaultRepository("vault", server.URL, "app", WithVaultToken("root"))
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	vault.set("app", map[string]interface{}{"password": "new"})
+
+	// The secret is read again before its lease of a second expires.
+	deadline := time.Now().Add(2 * time.Second)
+	password, _ := repository.GetData("password")
+	for password != "new" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		password, _ = repository.GetData("password")
+	}
+	if password != "new" {
+		t.Errorf("Expected the secret to be read again before the lease expires, got %v", password)
+	}
+
+	// Close stops the renewals.
+	_ = repository.Close()
+	reads := vault.readCount()
+	time.Sleep(time.Second)
+	if vault.readCount() != reads {
+		t.Errorf("Expected no renewal after Close")
+	}
+}
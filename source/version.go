@@ -0,0 +1,61 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// documentVersion extracts the value of the top-level VersionKey from a
+// document by scanning its lines, without unmarshalling the whole document.
+// The key may be bare or quoted. A bare key only counts at column 0, as a YAML
+// top-level key. In a document that starts with '{' (JSON or JSON5) the key
+// may be indented as long as it sits directly inside the outermost object.
+// Nesting is tracked by counting braces per line, so braces inside string
+// values can confuse it, and the key must be on a line of its own.
+func documentVersion(data []byte) string {
+	isObject := bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+	depth := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimLeft(line, " \t")
+		atTopLevel := len(trimmed) == len(line)
+		if isObject {
+			atTopLevel = depth == 1
+		}
+		if atTopLevel {
+			if value, ok := versionValue(trimmed); ok {
+				return value
+			}
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+	}
+	return ""
+}
+
+// versionValue returns the value of line if it declares the VersionKey.
+func versionValue(line string) (string, bool) {
+	var rest string
+	found := false
+	for _, key := range []string{VersionKey, `"` + VersionKey + `"`, `'` + VersionKey + `'`} {
+		if strings.HasPrefix(line, key) {
+			rest = strings.TrimLeft(strings.TrimPrefix(line, key), " \t")
+			found = strings.HasPrefix(rest, ":")
+			if found {
+				break
+			}
+		}
+	}
+	if !found {
+		return "", false
+	}
+	value := strings.TrimPrefix(rest, ":")
+	for _, comment := range []string{" #", " //"} {
+		if idx := strings.Index(value, comment); idx >= 0 {
+			value = value[:idx]
+		}
+	}
+	value = strings.TrimSuffix(strings.TrimSpace(value), ",")
+	return strings.Trim(strings.TrimSpace(value), `"'`), true
+}
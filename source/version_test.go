@@ -0,0 +1,29 @@
+package source
+
+import (
+	"testing"
+)
+
+func TestDocumentVersion(t *testing.T) {
+	testCases := []struct {
+		name     string
+		document string
+		want     string
+	}{
+		{name: "yaml", document: "_version: 3\nname: John\n", want: "3"},
+		{name: "yaml comment", document: "_version: \"3\" # bumped\n", want: "3"},
+		{name: "yaml quoted key", document: "\"_version\": 3\n", want: "3"},
+		{name: "yaml nested", document: "nested:\n  _version: 3\n", want: ""},
+		{name: "json", document: "{\n  \"name\": \"John\",\n  \"_version\": \"3\",\n}\n", want: "3"},
+		{name: "json5", document: "{\n  // comment\n  _version: 3, // bumped\n}\n", want: "3"},
+		{name: "json nested", document: "{\n  \"nested\": {\n    \"_version\": 3\n  }\n}\n", want: ""},
+		{name: "prefix", document: "_versions: 3\n", want: ""},
+		{name: "missing", document: "name: John\n", want: ""},
+	}
+	for _, tc := range testCases {
+		got := documentVersion([]byte(tc.document))
+		if got != tc.want {
+			t.Errorf("Expected version of %s to be %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
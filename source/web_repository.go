@@ -1,25 +1,61 @@
 package source
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // WebRepository is a struct that implements the Repository interface for
 // handling configuration data fetched from a remote HTTP endpoint (web URL).
+// Once a file is loaded, it is requested conditionally with the ETag and
+// Last-Modified of the last response, so an unchanged file is neither
+// downloaded nor reparsed.
 type WebRepository struct {
-	sync.RWMutex                        // RWMutex to synchronize access to data during refresh
-	Name         string                 // Name of the configuration source
-	data         map[string]interface{} // Map to store the configuration data
-	URL          *url.URL               // URL representing the remote HTTP endpoint (web URL)
-	rawData      []byte                 // Raw data of the YAML configuration file
+	sync.RWMutex                         // RWMutex to synchronize access to data during refresh
+	Name          string                 // Name of the configuration source
+	data          map[string]interface{} // Map to store the configuration data
+	URL           *url.URL               // URL representing the remote HTTP endpoint (web URL)
+	SocketPath    string                 // Unix domain socket to dial instead of the URL host, the URL path is still requested
+	rawData       []byte                 // Raw data of the configuration file
+	version       string                 // Version marker of the currently loaded data
+	Codec         Codec                  // Codec used to parse the file, defaults to YAMLCodec
+	httpClient    *http.Client           // HTTP client used to fetch the configuration file
+	clientSocket  string                 // Unix domain socket httpClient was built for
+	TokenProvider TokenProvider          // Provider of the token sent with every request, called again when the endpoint answers 401 or 403
+	TokenHeader   string                 // Header the token is sent in as is, defaults to a bearer token in Authorization
+	EndMarker     string                 // Last line every complete file ends with, removed before parsing; no check when empty
+	RangeOffset   int64                  // Offset of the part of the file that changes, fetched alone with a Range request once the rest is loaded; 0 always fetches the whole file
+	acceptsRanges bool                   // Whether the endpoint advertised byte ranges in its last response
+	Timeout       time.Duration          // Timeout of each request, including reading the body; none when 0
+	Headers       http.Header            // Headers sent with every request, such as static auth tokens; a token from TokenProvider takes precedence
+	etag          string                 // ETag of the loaded file, sent as If-None-Match
+	lastModified  string                 // Last-Modified of the loaded file, sent as If-Modified-Since
 }
 
+// webResponse is a configuration file fetched by a WebRepository.
+type webResponse struct {
+	data          []byte // Whole content of the file
+	version       string // Version marker of the file
+	etag          string // ETag of the response
+	lastModified  string // Last-Modified of the response
+	acceptsRanges bool   // Whether the endpoint advertised byte ranges
+}
+
+// errNotModified is returned by fetchFrom when the endpoint answers a
+// conditional request with 304 Not Modified.
+var errNotModified = errors.New("not modified")
+
 // GetName returns the name of the configuration source.
 func (w *WebRepository) GetName() string {
 	return w.Name
@@ -33,32 +69,252 @@ func (w *WebRepository) GetData(configName string) (config interface{}, isPresen
 	return config, isPresent
 }
 
-// GetRawData returns the raw data of the YAML configuration file.
+// GetRawData returns the raw data of the configuration file.
 func (w *WebRepository) GetRawData() []byte {
 	w.RLock()
 	defer w.RUnlock()
 	return w.rawData
 }
 
-// Refresh fetches the YAML file from the remote HTTP endpoint (web URL),
+// Version returns the ETag of the last response, or the VersionKey of the
+// file when the endpoint does not send an ETag.
+func (w *WebRepository) Version() string {
+	w.RLock()
+	defer w.RUnlock()
+	return w.version
+}
+
+// unixSocketAndPath splits the path of a unix:// URL into the socket path and
+// the request path, which are separated by a colon as in
+// unix:///var/run/config.sock:/config.yaml. Without a colon the whole path
+// names the socket and the root of the server is requested.
+func unixSocketAndPath(path string) (string, string) {
+	if idx := strings.LastIndex(path, ":"); idx >= 0 {
+		return path[:idx], path[idx+1:]
+	}
+	return path, "/"
+}
+
+// socketPath returns the Unix domain socket to dial, either from SocketPath
+// or from the path of a unix:// URL.
+func (w *WebRepository) socketPath() string {
+	if w.SocketPath != "" {
+		return w.SocketPath
+	}
+	if w.URL.Scheme == "unix" {
+		socketPath, _ := unixSocketAndPath(w.URL.Path)
+		return socketPath
+	}
+	return ""
+}
+
+// requestURL returns the URL to request. For a unix:// URL it is built from
+// the request path after the socket path and the query of the URL.
+func (w *WebRepository) requestURL() string {
+	if w.URL.Scheme == "unix" {
+		_, path := unixSocketAndPath(w.URL.Path)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		requestURL := url.URL{Scheme: "http", Host: "unix", Path: path, RawQuery: w.URL.RawQuery}
+		return requestURL.String()
+	}
+	return w.URL.String()
+}
+
+// client returns the HTTP client used to fetch the configuration file, dialing the
+// Unix domain socket when one is configured. The client is rebuilt when the
+// socket changes.
+func (w *WebRepository) client() *http.Client {
+	socketPath := w.socketPath()
+	if w.httpClient != nil && w.clientSocket == socketPath {
+		return w.httpClient
+	}
+	w.clientSocket = socketPath
+	if socketPath == "" {
+		w.httpClient = http.DefaultClient
+		return w.httpClient
+	}
+	w.httpClient = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	return w.httpClient
+}
+
+// Refresh fetches the configuration file from the remote HTTP endpoint (web URL),
 // unmarshal it into the data map.
+// If the endpoint answers 304 Not Modified, or the ETag or declared VersionKey
+// matches the loaded data, the file is not reparsed.
+// A response with any other status code outside 2xx is an error and leaves the
+// current data in place; the body of such a response is never parsed.
 func (w *WebRepository) Refresh() error {
 	w.Lock()
 	defer w.Unlock()
 
-	// Create an HTTP request to fetch the YAML file from the remote web URL.
-	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, w.URL.String(), nil)
+	// Fetch the configuration file, authenticating with a fresh token if there is a provider.
+	var response webResponse
+	fetch := func(token string) error {
+		var err error
+		response, err = w.fetch(token)
+		return err
+	}
+	var err error
+	if w.TokenProvider != nil {
+		err = withToken(context.Background(), w.TokenProvider, fetch)
+	} else {
+		err = fetch("")
+	}
+	if errors.Is(err, errNotModified) {
+		logrus.Debug("file not modified, skipping reparse")
+		return nil
+	}
 	if err != nil {
-		logrus.Debug("error creating request")
 		return err
 	}
+	w.acceptsRanges = response.acceptsRanges
+
+	// Skip reparsing when the version has not changed.
+	if response.version != "" && response.version == w.version {
+		logrus.Debug("version unchanged, skipping reparse")
+		w.etag = response.etag
+		w.lastModified = response.lastModified
+		return nil
+	}
 
-	// Perform the HTTP request to get the YAML file content.
-	resp, err := http.DefaultClient.Do(request)
+	// Unmarshal the data into a new map with the codec, so a file that does
+	// not parse leaves the current data in place.
+	var parsed map[string]interface{}
+	err = codecOrDefault(w.Codec).Unmarshal(response.data, &parsed)
 	if err != nil {
-		logrus.Debug("error doing request")
+		logrus.Debug("error unmarshalling file")
+		return err
+	}
+
+	// Store the data, raw data, version and validators of the file.
+	w.data = parsed
+	w.rawData = response.data
+	w.version = response.version
+	w.etag = response.etag
+	w.lastModified = response.lastModified
+
+	return nil
+}
+
+// Load fetches and parses the whole configuration file the way Refresh does
+// and returns its configurations, without replacing the loaded data. The
+// repository is only locked to read its settings, so GetData and Refresh do
+// not wait for the request.
+func (w *WebRepository) Load() (map[string]interface{}, error) {
+	w.RLock()
+	probe := &WebRepository{
+		Name:          w.Name,
+		URL:           w.URL,
+		SocketPath:    w.SocketPath,
+		Codec:         w.Codec,
+		httpClient:    w.httpClient,
+		clientSocket:  w.clientSocket,
+		TokenProvider: w.TokenProvider,
+		TokenHeader:   w.TokenHeader,
+		EndMarker:     w.EndMarker,
+		Timeout:       w.Timeout,
+		Headers:       w.Headers,
+	}
+	w.RUnlock()
+
+	var response webResponse
+	fetch := func(token string) error {
+		var err error
+		response, err = probe.fetchFrom(token, 0, false)
 		return err
 	}
+	var err error
+	if probe.TokenProvider != nil {
+		err = withToken(context.Background(), probe.TokenProvider, fetch)
+	} else {
+		err = fetch("")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var parsed map[string]interface{}
+	err = codecOrDefault(probe.Codec).Unmarshal(response.data, &parsed)
+	if err != nil {
+		logrus.Debug("error unmarshalling file")
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// errRangeIgnored is returned by fetchFrom when the endpoint answers a Range
+// request with a part other than the one requested.
+var errRangeIgnored = errors.New("range request not honored")
+
+// fetch requests the configuration file with the given token, if any, and
+// returns its content and version marker. With a RangeOffset, once the file
+// is loaded and the endpoint advertises byte ranges, only the part of the file
+// from RangeOffset on is requested and appended to the loaded part before it.
+// Without a usable partial response the whole file is fetched instead.
+func (w *WebRepository) fetch(token string) (webResponse, error) {
+	if w.RangeOffset > 0 && w.acceptsRanges && int64(len(w.rawData)) >= w.RangeOffset {
+		response, err := w.fetchFrom(token, w.RangeOffset, true)
+		if !errors.Is(err, errRangeIgnored) {
+			return response, err
+		}
+		logrus.Debug("range request not honored, fetching the whole file")
+	}
+	return w.fetchFrom(token, 0, true)
+}
+
+// fetchFrom requests the configuration file from the given offset, or the
+// whole file when offset is 0, and returns the whole content and its version.
+// With conditional set, the whole file is requested conditionally once a file
+// is loaded, and errNotModified is returned when it has not changed.
+func (w *WebRepository) fetchFrom(token string, offset int64, conditional bool) (webResponse, error) {
+	ctx := context.Background()
+	if w.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.Timeout)
+		defer cancel()
+	}
+
+	// Create an HTTP request to fetch the configuration file from the remote web URL.
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, w.requestURL(), nil)
+	if err != nil {
+		logrus.Debug("error creating request")
+		return webResponse{}, err
+	}
+	for name, values := range w.Headers {
+		request.Header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+	}
+	if offset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	} else if conditional && w.data != nil {
+		if w.etag != "" {
+			request.Header.Set("If-None-Match", w.etag)
+		}
+		if w.lastModified != "" {
+			request.Header.Set("If-Modified-Since", w.lastModified)
+		}
+	}
+	if token != "" {
+		if w.TokenHeader != "" {
+			request.Header.Set(w.TokenHeader, token)
+		} else {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	// Perform the HTTP request to get the configuration file content.
+	resp, err := w.client().Do(request)
+	if err != nil {
+		logrus.Debug("error doing request")
+		return webResponse{}, err
+	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
@@ -66,22 +322,98 @@ func (w *WebRepository) Refresh() error {
 		}
 	}(resp.Body)
 
+	// Fall back to the whole file when the endpoint does not honor the range,
+	// for example because the file is now shorter than RangeOffset.
+	acceptsRanges := resp.Header.Get("Accept-Ranges") == "bytes" || resp.StatusCode == http.StatusPartialContent
+	if offset > 0 {
+		switch resp.StatusCode {
+		case http.StatusOK, http.StatusRequestedRangeNotSatisfiable:
+			return webResponse{}, errRangeIgnored
+		case http.StatusPartialContent:
+			if rangeStart(resp.Header.Get("Content-Range")) != offset {
+				return webResponse{}, errRangeIgnored
+			}
+		}
+	}
+	if resp.StatusCode == http.StatusNotModified && offset == 0 && conditional && w.data != nil {
+		return webResponse{}, errNotModified
+	}
+
+	// Treat non-2xx responses as failures so the current data is kept.
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		logrus.Debug("unexpected status code")
+		return webResponse{}, newStatusError(resp, w.URL)
+	}
+
 	// Read the file content from the response body.
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		logrus.Debug("error reading file")
-		return err
+		return webResponse{}, err
+	}
+
+	// Reject partial downloads, which may still parse and silently drop keys.
+	if resp.ContentLength >= 0 && int64(len(data)) != resp.ContentLength {
+		logrus.Debug("response shorter than its content length")
+		return webResponse{}, fmt.Errorf("%w: received %d of %d bytes", ErrTruncated, len(data), resp.ContentLength)
+	}
+	if offset > 0 {
+		// Complete the requested part with the loaded part before it.
+		data = append(w.rawData[:offset:offset], data...)
+	}
+	if w.EndMarker != "" {
+		data, err = trimEndMarker(data, w.EndMarker)
+		if err != nil {
+			logrus.Debug("response is missing its end marker")
+			return webResponse{}, err
+		}
+	}
+
+	// Use the ETag as the version, or the VersionKey of the file without one.
+	version := resp.Header.Get("ETag")
+	if version == "" {
+		version = documentVersion(data)
 	}
+	return webResponse{
+		data:          data,
+		version:       version,
+		etag:          resp.Header.Get("ETag"),
+		lastModified:  resp.Header.Get("Last-Modified"),
+		acceptsRanges: acceptsRanges,
+	}, nil
+}
 
-	// Unmarshal the YAML data into the data map.
-	err = yaml.Unmarshal(data, &w.data)
+// rangeStart returns the first byte of a Content-Range header such as
+// "bytes 100-199/200", or -1 if the header is not a byte range.
+func rangeStart(contentRange string) int64 {
+	if !strings.HasPrefix(contentRange, "bytes ") {
+		return -1
+	}
+	first, _, ok := strings.Cut(strings.TrimPrefix(contentRange, "bytes "), "-")
+	if !ok {
+		return -1
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
 	if err != nil {
-		logrus.Debug("error unmarshalling file")
-		return err
+		return -1
 	}
+	return start
+}
 
-	// Store the raw data of the YAML file.
-	w.rawData = data
+// trimEndMarker returns data without its last line if that line is marker,
+// ignoring surrounding whitespace, and ErrTruncated otherwise.
+func trimEndMarker(data []byte, marker string) ([]byte, error) {
+	trimmed := bytes.TrimRight(data, " \t\r\n")
+	idx := bytes.LastIndexByte(trimmed, '\n')
+	if string(bytes.TrimSpace(trimmed[idx+1:])) != marker {
+		return nil, fmt.Errorf("%w: missing end marker", ErrTruncated)
+	}
+	return trimmed[:idx+1], nil
+}
 
-	return nil
+// Keys returns the names of the configurations in the currently loaded file.
+func (w *WebRepository) Keys() []string {
+	w.RLock()
+	defer w.RUnlock()
+	return sortedKeys(w.data)
 }
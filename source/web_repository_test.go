@@ -0,0 +1,468 @@
+package source
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebRepositoryETagVersion(t *testing.T) {
+	etag := `"v1"`
+	body := "name: John\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	urlParsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Error parsing url: %s", err.Error())
+	}
+	repository := &WebRepository{Name: "web", URL: urlParsed}
+	err = repository.Refresh()
+	if err != nil {
+		t.Errorf("Error refreshing repository: %s", err.Error())
+	}
+	if repository.Version() != etag {
+		t.Errorf("Expected version to be %s, got %s", etag, repository.Version())
+	}
+
+	body = "name: Jane\n"
+	err = repository.Refresh()
+	if err != nil {
+		t.Errorf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+
+	etag = `"v2"`
+	err = repository.Refresh()
+	if err != nil {
+		t.Errorf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected name to be Jane, got %v", name)
+	}
+}
+
+func TestWebRepositoryUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Error listening on unix socket: %s", err.Error())
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/config.yaml" || r.URL.Path == "/" {
+			_, _ = w.Write([]byte("name: " + r.URL.Path + "\n"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	testCases := []struct {
+		name       string
+		url        string
+		socketPath string
+		want       string
+	}{
+		{name: "SocketPath", url: "http://agent/config.yaml", socketPath: socketPath, want: "/config.yaml"},
+		{name: "UnixURL", url: "unix://" + socketPath, want: "/"},
+		{name: "UnixURLWithPath", url: "unix://" + socketPath + ":/config.yaml?env=prod", want: "/config.yaml"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			urlParsed, err := url.Parse(tc.url)
+			if err != nil {
+				t.Fatalf("Error parsing url: %s", err.Error())
+			}
+			repository := &WebRepository{Name: "web", URL: urlParsed, SocketPath: tc.socketPath}
+			err = repository.Refresh()
+			if err != nil {
+				t.Fatalf("Error refreshing repository: %s", err.Error())
+			}
+			name, _ := repository.GetData("name")
+			if name != tc.want {
+				t.Errorf("Expected name to be %s, got %v", tc.want, name)
+			}
+		})
+	}
+
+	// Changing the socket after a refresh dials the new socket.
+	urlParsed, err := url.Parse("http://agent/config.yaml")
+	if err != nil {
+		t.Fatalf("Error parsing url: %s", err.Error())
+	}
+	repository := &WebRepository{Name: "web", URL: urlParsed, SocketPath: socketPath}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	repository.SocketPath = filepath.Join(t.TempDir(), "missing.sock")
+	if repository.Refresh() == nil {
+		t.Errorf("Expected error after switching to a missing socket, got nil")
+	}
+}
+
+func TestWebRepositoryTruncated(t *testing.T) {
+	truncate := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := "name: John\nage: 30\n"
+		if !truncate {
+			_, _ = w.Write([]byte(body))
+			return
+		}
+		// Declare the full length, send part of the body and drop the connection.
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(body[:8]))
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}))
+	defer server.Close()
+	urlParsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Error parsing url: %s", err.Error())
+	}
+	repository := &WebRepository{Name: "web", URL: urlParsed}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	truncate = true
+	err = repository.Refresh()
+	if err == nil {
+		t.Errorf("Expected a truncated download to be rejected")
+	}
+	if !errors.Is(NormalizeError(err), ErrSourceUnavailable) {
+		t.Errorf("Expected the error to be classified as unavailable, got %v", err)
+	}
+	age, ok := repository.GetData("age")
+	if !ok || age != 30 {
+		t.Errorf("Expected the last good data to be kept, got %v", age)
+	}
+}
+
+func TestWebRepositoryEndMarker(t *testing.T) {
+	body := "{\"name\": \"John\"}\n# end\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+	urlParsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Error parsing url: %s", err.Error())
+	}
+	// The marker is removed before parsing, so it need not be valid in the format.
+	repository := &WebRepository{Name: "web", URL: urlParsed, EndMarker: "# end", Codec: JSON5Codec}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+
+	// A body cut before its marker is rejected even though it parses.
+	body = "{\"name\": \"Jane\"}\n"
+	err = repository.Refresh()
+	if !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated, got %v", err)
+	}
+	name, _ = repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected the last good data to be kept, got %v", name)
+	}
+}
+
+func TestWebRepositoryRangeOffset(t *testing.T) {
+	head := "static:\n  region: eu\n  tier: gold\n"
+	body := head + "counter: 1\n"
+	honorRanges := true
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if !honorRanges {
+			_, _ = w.Write([]byte(body))
+			return
+		}
+		w.Header().Set("ETag", strconv.Quote(strconv.Itoa(len(ranges))))
+		http.ServeContent(w, r, "config.yaml", time.Time{}, strings.NewReader(body))
+	}))
+	defer server.Close()
+
+	urlParsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Error parsing url: %s", err.Error())
+	}
+	repository := &WebRepository{Name: "web", URL: urlParsed, RangeOffset: int64(len(head))}
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// Once loaded, only the tail is fetched and joined to the loaded head.
+	body = head + "counter: 2\nextra: true\n"
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	expectedRanges := []string{"", "bytes=" + strconv.Itoa(len(head)) + "-"}
+	if !reflect.DeepEqual(ranges, expectedRanges) {
+		t.Errorf("Expected requests for %q, got %q", expectedRanges, ranges)
+	}
+	counter, _ := repository.GetData("counter")
+	static, _ := repository.GetData("static")
+	extra, _ := repository.GetData("extra")
+	if counter != 2 || extra != true || !reflect.DeepEqual(static, map[string]interface{}{"region": "eu", "tier": "gold"}) {
+		t.Errorf("Expected the tail to be joined to the head, got %v, %v and %v", counter, extra, static)
+	}
+	if string(repository.GetRawData()) != body {
+		t.Errorf("Expected the raw data to be the whole file, got %q", repository.GetRawData())
+	}
+
+	// A file shorter than the offset is fetched whole.
+	body = "counter: 3\n"
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	if ranges[len(ranges)-1] != "" || string(repository.GetRawData()) != body {
+		t.Errorf("Expected a whole fetch after an unsatisfiable range, got %q", repository.GetRawData())
+	}
+
+	// An endpoint that does not advertise ranges is not sent Range requests.
+	honorRanges = false
+	ranges = nil
+	repository = &WebRepository{Name: "web", URL: urlParsed, RangeOffset: int64(len(head))}
+	for i := 0; i < 2; i++ {
+		err = repository.Refresh()
+		if err != nil {
+			t.Fatalf("Error refreshing repository: %s", err.Error())
+		}
+	}
+	if !reflect.DeepEqual(ranges, []string{"", ""}) {
+		t.Errorf("Expected only whole fetches, got %q", ranges)
+	}
+}
+
+func TestWebRepositoryConditionalRequest(t *testing.T) {
+	var mu sync.Mutex
+	etag := `"v1"`
+	lastModified := "Mon, 04 Nov 2024 10:00:00 GMT"
+	body := "name: John\n"
+	status := 0
+	var served, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		served++
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	urlParsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Error parsing url: %s", err.Error())
+	}
+	repository := &WebRepository{Name: "web", URL: urlParsed, Headers: http.Header{"X-Api-Key": {"secret"}}}
+	for i := 0; i < 3; i++ {
+		err = repository.Refresh()
+		if err != nil {
+			t.Fatalf("Error refreshing repository: %s", err.Error())
+		}
+	}
+	if served != 1 || notModified != 2 {
+		t.Errorf("Expected the file to be served once and not modified twice, got %d and %d", served, notModified)
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %v", name)
+	}
+
+	// A changed file is downloaded again.
+	mu.Lock()
+	etag, body = `"v2"`, "name: Jane\n"
+	mu.Unlock()
+	err = repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" || repository.Version() != `"v2"` {
+		t.Errorf("Expected name Jane at version v2, got %v at %s", name, repository.Version())
+	}
+
+	// Other status codes fail the refresh and keep the last good data.
+	mu.Lock()
+	status = http.StatusBadGateway
+	mu.Unlock()
+	err = repository.Refresh()
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected a StatusError for a bad gateway, got %v", err)
+	}
+	name, _ = repository.GetData("name")
+	if name != "Jane" {
+		t.Errorf("Expected the last good data to be kept, got %v", name)
+	}
+}
+
+func TestWebRepositoryTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	urlParsed, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Error parsing url: %s", err.Error())
+	}
+	repository := &WebRepository{Name: "web", URL: urlParsed, Timeout: 20 * time.Millisecond}
+	started := time.Now()
+	err = repository.Refresh()
+	if err == nil {
+		t.Errorf("Expected error for a request that times out, got nil")
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Expected the request to time out quickly, took %s", elapsed)
+	}
+}
+
+func TestWebRepositoryLoad(t *testing.T) {
+	var mu sync.Mutex
+	body := "name: John\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("If-None-Match") == `"v1"` && body == "name: John\n" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if body == "name: John\n" {
+			w.Header().Set("ETag", `"v1"`)
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	repository := &WebRepository{Name: "web", URL: u}
+	err := repository.Refresh()
+	if err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+
+	// The file is fetched in full even though it has not changed.
+	loaded, err := repository.Load()
+	if err != nil || loaded["name"] != "John" {
+		t.Errorf("Expected the loaded name to be John, got %v (%v)", loaded, err)
+	}
+
+	mu.Lock()
+	body = "name: [unclosed\n"
+	mu.Unlock()
+	if _, err := repository.Load(); err == nil {
+		t.Errorf("Expected error for a file that does not parse, got nil")
+	}
+	mu.Lock()
+	body = "name: Jane\n"
+	mu.Unlock()
+	loaded, err = repository.Load()
+	if err != nil || loaded["name"] != "Jane" {
+		t.Errorf("Expected the loaded name to be Jane, got %v (%v)", loaded, err)
+	}
+	name, _ := repository.GetData("name")
+	if name != "John" || repository.Version() != `"v1"` {
+		t.Errorf("Expected the live data to be untouched, got %v at %s", name, repository.Version())
+	}
+}
+
+func TestWebRepositoryLoadUnlocked(t *testing.T) {
+	var stalled sync.Once
+	requested := make(chan struct{})
+	release := make(chan struct{})
+	var loading atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if loading.Load() {
+			w.Header().Set("Accept-Ranges", "bytes")
+			stalled.Do(func() { close(requested) })
+			<-release
+		}
+		_, _ = w.Write([]byte("name: John\n"))
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	repository := &WebRepository{Name: "web", URL: u}
+	if err := repository.Refresh(); err != nil {
+		t.Fatalf("Error refreshing repository: %s", err.Error())
+	}
+	loading.Store(true)
+
+	loaded := make(chan error, 1)
+	go func() {
+		_, err := repository.Load()
+		loaded <- err
+	}()
+	<-requested
+	// GetData does not wait for the request of Load.
+	read := make(chan interface{}, 1)
+	go func() {
+		name, _ := repository.GetData("name")
+		read <- name
+	}()
+	select {
+	case name := <-read:
+		if name != "John" {
+			t.Errorf("Expected the live name to be John, got %v", name)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected GetData not to wait for Load")
+	}
+	close(release)
+	if err := <-loaded; err != nil {
+		t.Fatalf("Error loading repository: %s", err.Error())
+	}
+	if repository.acceptsRanges {
+		t.Errorf("Expected Load to leave the state of the repository untouched")
+	}
+}